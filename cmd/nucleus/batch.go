@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/batch"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/LambdaTest/synapse/pkg/task"
+	"github.com/LambdaTest/synapse/pkg/workspace"
+)
+
+// runBatch runs the sub-tasks of the batch payload in child processes until they all exited, the children are
+// interrupted on C-c and on the termination of the container
+func runBatch(ctx context.Context, cfg *config.NucleusConfig, ws *workspace.Manager, logger lumber.Logger) {
+	azureClient, err := storage.New(cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize blob storage: %v", err)
+	}
+	// the status of the interrupted sub-tasks is still reported
+	t, err := task.New(ctx, cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize task: %v", err)
+	}
+	runner, err := batch.New(cfg, azureClient, t, ws, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize batch runner: %v", err)
+	}
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := runner.Run(runCtx); err != nil {
+		logger.Errorf("batch failed: %v", err)
+		return
+	}
+	logger.Infof("batch completed")
+}
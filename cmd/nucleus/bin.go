@@ -9,32 +9,54 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/annotator"
 	"github.com/LambdaTest/synapse/pkg/api"
-	"github.com/LambdaTest/synapse/pkg/azure"
+	"github.com/LambdaTest/synapse/pkg/api/metrics"
+	"github.com/LambdaTest/synapse/pkg/api/results"
+	"github.com/LambdaTest/synapse/pkg/artifactmanager"
 	"github.com/LambdaTest/synapse/pkg/cachemanager"
+	"github.com/LambdaTest/synapse/pkg/checkpointmanager"
 	"github.com/LambdaTest/synapse/pkg/command"
+	"github.com/LambdaTest/synapse/pkg/compression"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/diffmanager"
+	"github.com/LambdaTest/synapse/pkg/discoverycache"
+	"github.com/LambdaTest/synapse/pkg/exporter"
+	"github.com/LambdaTest/synapse/pkg/failurereport"
 	"github.com/LambdaTest/synapse/pkg/gitmanager"
 	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/logstream"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 	"github.com/LambdaTest/synapse/pkg/payloadmanager"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/resultstore"
 	"github.com/LambdaTest/synapse/pkg/secret"
 	"github.com/LambdaTest/synapse/pkg/server"
+	"github.com/LambdaTest/synapse/pkg/service/autoconfig"
 	"github.com/LambdaTest/synapse/pkg/service/coverage"
+	"github.com/LambdaTest/synapse/pkg/service/depgraph"
+	"github.com/LambdaTest/synapse/pkg/service/dryrun"
 	"github.com/LambdaTest/synapse/pkg/service/parser"
+	"github.com/LambdaTest/synapse/pkg/service/services"
+	"github.com/LambdaTest/synapse/pkg/service/taskstatus"
 	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"github.com/LambdaTest/synapse/pkg/service/testtiming"
+	"github.com/LambdaTest/synapse/pkg/stageplugin"
+	"github.com/LambdaTest/synapse/pkg/storage"
 	"github.com/LambdaTest/synapse/pkg/tasconfigmanager"
 	"github.com/LambdaTest/synapse/pkg/task"
 	"github.com/LambdaTest/synapse/pkg/testblocklistservice"
 	"github.com/LambdaTest/synapse/pkg/testdiscoveryservice"
 	"github.com/LambdaTest/synapse/pkg/testexecutionservice"
-	"github.com/LambdaTest/synapse/pkg/zstd"
+	"github.com/LambdaTest/synapse/pkg/toolchainmanager"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/webhook"
+	"github.com/LambdaTest/synapse/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -49,6 +71,9 @@ func RootCommand() *cobra.Command {
 
 	// define flags used for this command
 	AttachCLIFlags(&rootCmd)
+	rootCmd.AddCommand(ValidateConfigCommand())
+	rootCmd.AddCommand(RunCommand())
+	rootCmd.AddCommand(ServeCommand())
 
 	return &rootCmd
 }
@@ -60,6 +85,8 @@ func run(cmd *cobra.Command, args []string) {
 
 	// timeout in seconds
 	const gracefulTimeout = 5000 * time.Millisecond
+	// the completed tests are uploaded on shutdown if checkpointing is enabled
+	const checkpointGracefulTimeout = 25 * time.Second
 
 	// a WaitGroup for the goroutines to tell us they've stopped
 	wg := sync.WaitGroup{}
@@ -81,16 +108,148 @@ func run(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatalf("Could not instantiate logger %s", err.Error())
 	}
+	// ship the buffered logs before exiting, they are the last ones of the task
+	defer closeLogger(logger)
 	logger.Debugf("Running on local: %t", cfg.LocalRunner)
 
-	if cfg.LocalRunner {
-		logger.Infof("Local runner detected , changing IP from: %s to: %s", global.NeuronHost, cfg.SynapseHost)
-		global.SetNeuronHost(strings.TrimSpace(cfg.SynapseHost))
+	// configure the proxy and TLS of the http clients before the services create them
+	if err := requestutils.Setup(&cfg.HTTP); err != nil {
+		logger.Fatalf("failed to configure the http clients: %v", err)
+	}
 
-		logger.Infof("change neuron host to %s", global.NeuronHost)
-	} else {
-		global.SetNeuronHost(global.NeuronRemoteHost)
+	shutdownTracing, err := tracing.Setup(ctx, cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize tracing: %v", err)
+	}
+	// flush pending spans before exiting
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %v", err)
+		}
+	}()
+
+	setNeuronHost(cfg, logger)
+	ws, err := workspace.New(cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize workspace: %v", err)
+	}
+	if err := ws.Setup(); err != nil {
+		logger.Fatalf("failed to set up workspace: %v", err)
+	}
+	if cfg.BatchAddress != "" {
+		runBatch(ctx, cfg, ws, logger)
+		return
+	}
+	app := newComponents(ctx, cfg, logger)
+	pl := app.pipeline
+
+	logger.Infof("LambdaTest Nucleus version: %s", global.NUCLEUS_BINARY_VERSION)
+
+	wg.Add(1)
+	go func() {
+		defer cancel()
+		defer wg.Done()
+		// starting pipeline
+		pl.Start(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer cancel()
+		defer wg.Done()
+		server.ListenAndServe(ctx, app.router, cfg, logger)
+	}()
+	if cfg.GRPCPort != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.ListenAndServeGRPC(ctx, results.NewGRPCServer(logger, app.testStats), cfg, logger)
+		}()
 	}
+	// listen for C-c and the termination of the container
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// the settings which can change at runtime are reloaded on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go reloadConfig(ctx, hup, logger, app.blocklist)
+	shutdownTimeout := gracefulTimeout
+	if cfg.Checkpoint {
+		shutdownTimeout = checkpointGracefulTimeout
+	}
+
+	// create channel to mark status of waitgroup
+	// this is required to brutally kill application in case of
+	// timeout
+	done := make(chan struct{})
+
+	// asynchronously wait for all the go routines
+	go func() {
+		// and wait for all go routines
+		wg.Wait()
+		logger.Debugf("main: all goroutines have finished.")
+		close(done)
+	}()
+
+	// wait for signal channel
+	select {
+	case <-c:
+		{
+			logger.Debugf("main: received C-c - attempting graceful shutdown ....")
+			// tell the goroutines to stop
+			logger.Debugf("main: telling goroutines to stop")
+			cancel()
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancelShutdown()
+			select {
+			case <-done:
+				logger.Debugf("Go routines exited within timeout")
+			case <-shutdownCtx.Done():
+				logger.Errorf("Graceful timeout exceeded. Brutally killing the application")
+			}
+			closeNotifier(shutdownCtx, app.notifier, logger)
+
+		}
+	case <-done:
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), notifierCloseTimeout)
+		closeNotifier(shutdownCtx, app.notifier, logger)
+		cancelShutdown()
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %v", err)
+		}
+		closeLogger(logger)
+		os.Exit(0)
+	}
+
+}
+
+// notifierCloseTimeout bounds the delivery of the queued webhook events once the pipeline is done
+const notifierCloseTimeout = 30 * time.Second
+
+// closeNotifier delivers the webhook events queued before the shutdown
+func closeNotifier(ctx context.Context, notifier *webhook.Notifier, logger lumber.Logger) {
+	if err := notifier.Close(ctx); err != nil {
+		logger.Errorf("failed to deliver the webhook events: %v", err)
+	}
+}
+
+// closeLogger flushes the logs buffered by the logger
+func closeLogger(logger lumber.Logger) {
+	if err := lumber.Close(logger); err != nil {
+		log.Printf("failed to flush the logs: %v", err)
+	}
+}
+
+// components are the pipeline and the services shared with the API servers
+type components struct {
+	pipeline  *core.Pipeline
+	router    api.Router
+	testStats *teststats.ProcStats
+	blocklist *testblocklistservice.TestBlockListService
+	notifier  *webhook.Notifier
+}
+
+// newComponents creates the services of the pipeline and attaches them to it
+func newComponents(ctx context.Context, cfg *config.NucleusConfig, logger lumber.Logger) *components {
 	pl, err := core.NewPipeline(cfg, logger)
 	if err != nil {
 		logger.Errorf("Unable to create the pipeline: %+v\n", err)
@@ -102,48 +261,83 @@ func run(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logger.Fatalf("failed to initialize test stats service: %v", err)
 	}
-	azureClient, err := azure.NewAzureBlobEnv(cfg, logger)
+	azureClient, err := storage.New(cfg, logger)
 	if err != nil {
-		logger.Fatalf("failed to initialize azure blob: %v", err)
-	}
-	if err != nil && !cfg.LocalRunner {
-		logger.Fatalf("failed to initialize azure blob: %v", err)
+		logger.Fatalf("failed to initialize blob storage: %v", err)
 	}
 
+	// redact the resolved secrets from the logs and uploaded command logs
+	redactor := logstream.NewRedactor()
+	lumber.SetRedactor(redactor)
+	secretParser := secret.New(cfg, redactor, logger)
 	// attach plugins to pipeline
-	pm := payloadmanager.NewPayloadManger(azureClient, logger, cfg)
-	secretParser := secret.New(logger)
+	pm := payloadmanager.NewPayloadManger(azureClient, secretParser, logger, cfg)
 	tcm := tasconfigmanager.NewTASConfigManager(logger)
-	gm := gitmanager.NewGitManager(logger)
+	gm := gitmanager.NewGitManager(secretParser, logger)
 	dm := diffmanager.NewDiffManager(cfg, logger)
-	execManager := command.NewExecutionManager(secretParser, azureClient, logger)
+	execManager, err := command.NewExecutionManager(cfg, secretParser, azureClient, redactor, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize execution manager: %v", err)
+	}
 	tds := testdiscoveryservice.NewTestDiscoveryService(execManager, logger)
-	tes := testexecutionservice.NewTestExecutionService(execManager, azureClient, ts, logger)
+	tes := testexecutionservice.NewTestExecutionService(cfg, execManager, azureClient, ts, testtiming.New(cfg, logger), logger)
 	tbs, err := testblocklistservice.NewTestBlockListService(cfg, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize test blocklist service: %v", err)
 	}
-	router := api.NewRouter(logger, ts)
+	dryRunReporter := dryrun.New(azureClient, logger)
+	configDetector := autoconfig.New(azureClient, logger)
+	resultStore, err := resultstore.New(cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize results store: %v", err)
+	}
+	discoveryCache := discoverycache.New(azureClient, logger)
+	// the coverage reports are only served by the local runner, they are hosted in the blob storage otherwise
+	coverageReportDir := ""
+	if cfg.LocalRunner {
+		coverageReportDir = global.CoverageReportDir
+	}
+	statusTracker := taskstatus.New(cfg)
+	go statusTracker.Watch(ctx, ts)
+	// the results received from the runners are emitted on the event bus of the pipeline
+	go pl.Events.Watch(ctx, ts)
+	pipelineMetrics := metrics.NewPipeline()
+	pl.Events.Subscribe(pipelineMetrics)
+	router := api.NewRouter(logger, ts, dryRunReporter, tbs, pl, resultStore, discoveryCache, statusTracker,
+		pl.Events, pipelineMetrics, coverageReportDir)
 
 	t, err := task.New(ctx, cfg, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize task: %v", err)
 	}
 
-	zstd, err := zstd.New(execManager, logger)
+	notifier, err := webhook.New(cfg, logger)
 	if err != nil {
-		logger.Fatalf("failed to initialize zstd compressor: %v", err)
+		logger.Fatalf("failed to initialize webhook notifier: %v", err)
 	}
-	cache, err := cachemanager.New(zstd, azureClient, logger)
+
+	resultExporter, err := exporter.New(cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize result exporter: %v", err)
+	}
+
+	compressor, err := compression.New(cfg, logger)
+	if err != nil {
+		logger.Fatalf("failed to initialize compressor: %v", err)
+	}
+	// the caches, the coverage and the artifacts are derived from the sources, they are encrypted if a key is configured
+	encryptedStore := storage.NewEncryptedStore(cfg.Encryption, azureClient, secretParser, logger)
+	cache, err := cachemanager.New(cfg, compressor, encryptedStore, pl.Events, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize cache manager: %v", err)
 	}
 
-	parserService, err := parser.New(ctx, tcm, logger)
+	parserService, err := parser.New(ctx, cfg, tcm, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize parser service: %v", err)
 	}
-	coverageService, err := coverage.New(execManager, azureClient, zstd, cfg, logger)
+	scmAnnotator := annotator.New(cfg, logger)
+	coverageService, err := coverage.New(execManager, encryptedStore, compressor, dm, scmAnnotator, resultStore, secretParser, cfg, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize coverage service: %v", err)
 	}
@@ -162,57 +356,32 @@ func run(cmd *cobra.Command, args []string) {
 	pl.Task = t
 	pl.CacheStore = cache
 	pl.SecretParser = secretParser
+	pl.DryRunReporter = dryRunReporter
+	pl.ConfigDetector = configDetector
+	pl.ImpactAnalyzer = depgraph.New(azureClient, logger)
+	pl.ServiceManager = services.New(secretParser, logger)
+	pl.ArtifactManager = artifactmanager.New(cfg, encryptedStore, compressor, logger)
+	pl.FailureReporter = failurereport.New(cfg, azureClient, logger)
+	pl.CheckpointManager = checkpointmanager.New(azureClient, compressor, logger)
+	pl.ToolchainManager = toolchainmanager.New(logger)
+	pl.ResultExporter = resultExporter
+	pl.Annotator = scmAnnotator
+	pl.ResultStore = resultStore
+	pl.DiscoveryCache = discoveryCache
+	pl.StageHooks = append(pl.StageHooks, statusTracker)
+	// the audit log is uploaded before the webhooks are notified of the completion of the task
+	pl.Events.Subscribe(execManager, core.EventTaskCompleted)
+	pl.Events.Subscribe(notifier)
 
-	logger.Infof("LambdaTest Nucleus version: %s", global.NUCLEUS_BINARY_VERSION)
-
-	wg.Add(1)
-	go func() {
-		defer cancel()
-		defer wg.Done()
-		// starting pipeline
-		pl.Start(ctx)
-	}()
-	wg.Add(1)
-	go func() {
-		defer cancel()
-		defer wg.Done()
-		server.ListenAndServe(ctx, router, cfg, logger)
-	}()
-	// listen for C-c
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-
-	// create channel to mark status of waitgroup
-	// this is required to brutally kill application in case of
-	// timeout
-	done := make(chan struct{})
-
-	// asynchronously wait for all the go routines
-	go func() {
-		// and wait for all go routines
-		wg.Wait()
-		logger.Debugf("main: all goroutines have finished.")
-		close(done)
-	}()
-
-	// wait for signal channel
-	select {
-	case <-c:
-		{
-			logger.Debugf("main: received C-c - attempting graceful shutdown ....")
-			// tell the goroutines to stop
-			logger.Debugf("main: telling goroutines to stop")
-			cancel()
-			select {
-			case <-done:
-				logger.Debugf("Go routines exited within timeout")
-			case <-time.After(gracefulTimeout):
-				logger.Errorf("Graceful timeout exceeded. Brutally killing the application")
-			}
-
+	stagePlugins, err := stageplugin.Load(cfg.StagePlugins, logger)
+	if err != nil {
+		logger.Fatalf("failed to load stage plugins: %v", err)
+	}
+	for _, plugin := range stagePlugins {
+		if err := pl.RegisterStage(plugin, plugin.Placement()); err != nil {
+			logger.Fatalf("failed to register stage plugin: %v", err)
 		}
-	case <-done:
-		os.Exit(0)
 	}
 
+	return &components{pipeline: pl, router: router, testStats: ts, blocklist: tbs, notifier: notifier}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/testblocklistservice"
+)
+
+// reloadConfig reloads the config each time a signal is received and applies the settings which can
+// change at runtime: the log level, the neuron host and the blocklist refresh interval.
+func reloadConfig(ctx context.Context, signals <-chan os.Signal, logger lumber.Logger, tbs *testblocklistservice.TestBlockListService) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+		cfg, err := config.ReloadNucleusConfig()
+		if err != nil {
+			logger.Errorf("failed to reload config, keeping the current settings: %v", err)
+			continue
+		}
+		level := cfg.LogConfig.ConsoleLevel
+		if cfg.Verbose {
+			level = lumber.Debug
+		}
+		if err := lumber.SetLevel(logger, level); err != nil {
+			logger.Errorf("failed to change log level to %s: %v", level, err)
+		}
+		setNeuronHost(cfg, logger)
+		tbs.SetRefreshInterval(time.Duration(cfg.BlocklistRefreshInterval) * time.Second)
+		logger.Infof("reloaded config, log level: %s, neuron host: %s, blocklist refresh interval: %ds",
+			level, global.NeuronHost(), cfg.BlocklistRefreshInterval)
+	}
+}
+
+// setNeuronHost sets the neuron host, the local runner uses the synapse host as proxy
+func setNeuronHost(cfg *config.NucleusConfig, logger lumber.Logger) {
+	host := global.NeuronRemoteHost
+	if cfg.LocalRunner {
+		host = strings.TrimSpace(cfg.SynapseHost)
+		logger.Infof("Local runner detected , changing IP from: %s to: %s", global.NeuronHost(), host)
+	} else if cfg.NeuronHost != "" {
+		host = strings.TrimSpace(cfg.NeuronHost)
+	}
+	global.SetNeuronHost(host)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/diffmanager"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/localrun"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/server"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/LambdaTest/synapse/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+// RunCommand returns the command which runs the pipeline against the working directory of a local repository
+func RunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run --local <repo-path>",
+		Short: "Run the tests impacted by the local changes of a repository",
+		Long: `run runs the pipeline against the working directory of a local repository, without downloading a payload
+or cloning the repository. The changes of the working directory since the base ref select the impacted tests,
+which are printed and executed. The results are printed and kept in the local results store, nothing is
+reported to neuron. The test runners are expected to be installed in the repository.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runLocal,
+		// the failures of the task are not usage errors
+		SilenceUsage: true,
+	}
+	cmd.Flags().String("base", "HEAD", "git ref the changes of the working directory are compared to")
+	cmd.Flags().String("tas-file", ".tas.yml", "path of the tas configuration file relative to the repository")
+	cmd.Flags().String("secrets", "", "path of the repo secrets file")
+	cmd.Flags().String("storage-dir", "", "directory of the caches and the results store, defaults to the user cache directory")
+	cmd.Flags().Bool("all", false, "run all the tests instead of the impacted tests")
+	return cmd
+}
+
+func runLocal(cmd *cobra.Command, args []string) error {
+	repoDir, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadNucleusConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	preview := cfg.DryRun
+	cfg.LocalRunner = true
+	cfg.Offline = true
+	cfg.ExecuteMode = true
+	cfg.DiscoverMode, cfg.CoverageMode, cfg.ParseMode, cfg.DryRun, cfg.Checkpoint = false, false, false, false, false
+	if err := setLocalStorage(cmd, cfg); err != nil {
+		return err
+	}
+	// the logs are only printed on the console, the output of the run is printed on stdout
+	cfg.LogConfig.EnableFile = false
+	if !cfg.Verbose {
+		cfg.LogConfig.ConsoleLevel = "error"
+	}
+	logger, err := lumber.NewLogger(cfg.LogConfig, cfg.Verbose, lumber.InstanceZapLogger)
+	if err != nil {
+		return fmt.Errorf("could not instantiate logger: %w", err)
+	}
+	defer closeLogger(logger)
+	if err := requestutils.Setup(&cfg.HTTP); err != nil {
+		return fmt.Errorf("failed to configure the http clients: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	base, _ := cmd.Flags().GetString("base")
+	tasFile, _ := cmd.Flags().GetString("tas-file")
+	payload, err := localrun.NewPayload(ctx, localrun.Options{RepoDir: repoDir, BaseRef: base, TasFileName: tasFile})
+	if err != nil {
+		return err
+	}
+	ws, err := workspace.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize workspace: %w", err)
+	}
+	if err := ws.Setup(); err != nil {
+		return fmt.Errorf("failed to set up workspace: %w", err)
+	}
+	// the services use the working directory of the repository as the clone directory
+	global.RepoDir = repoDir
+	if _, err := os.Stat(global.RunnersArchive); err != nil {
+		// the runners are installed in the repository
+		global.InstallRunnerCmd = []string{"true"}
+	}
+
+	app := newComponents(ctx, cfg, logger)
+	pl := app.pipeline
+	out := cmd.OutOrStdout()
+	task := localrun.NewTask(out)
+	secretsPath, _ := cmd.Flags().GetString("secrets")
+	all, _ := cmd.Flags().GetBool("all")
+	pl.PayloadManager = localrun.NewPayloadManager(payload)
+	pl.GitManager = localrun.NewGitManager()
+	pl.DiffManager = diffmanager.NewLocalDiffManager(repoDir, logger)
+	pl.SecretParser = localrun.NewSecretParser(pl.SecretParser, secretsPath)
+	pl.TestBlockListService = localrun.NewBlockListService()
+	pl.Task = task
+	if err := pl.RegisterStage(localrun.NewImpactStage(pl, out, all, preview), core.StagePlacement{Before: core.StageExecution}); err != nil {
+		return err
+	}
+	if err := pl.RegisterStage(localrun.NewReportStage(out), core.StagePlacement{After: core.StageExecution}); err != nil {
+		return err
+	}
+
+	// the runners post the results to the API server
+	serverCtx, stopServer := context.WithCancel(ctx)
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		if err := server.ListenAndServe(serverCtx, app.router, cfg, logger); err != nil {
+			logger.Errorf("failed to start the API server: %v", err)
+		}
+	}()
+	fmt.Fprintf(out, "Running %s at %s, changes since %s\n", repoDir, payload.TargetCommit, base)
+	runErr := pl.Start(ctx)
+	stopServer()
+	<-serverDone
+	notifierCtx, cancelNotifier := context.WithTimeout(context.Background(), notifierCloseTimeout)
+	closeNotifier(notifierCtx, app.notifier, logger)
+	cancelNotifier()
+
+	if status := task.Status(); status != core.Passed {
+		if runErr != nil {
+			return fmt.Errorf("task %s: %w", status, runErr)
+		}
+		return fmt.Errorf("task %s", status)
+	}
+	return nil
+}
+
+// setLocalStorage keeps the caches, the artifacts and the results store in the local storage directory
+func setLocalStorage(cmd *cobra.Command, cfg *config.NucleusConfig) error {
+	dir, _ := cmd.Flags().GetString("storage-dir")
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("failed to find the user cache directory, use --storage-dir: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "tas")
+	}
+	cfg.Storage.Provider = storage.ProviderLocal
+	cfg.Storage.LocalDir = dir
+	cfg.FailureReportPath = filepath.Join(dir, "reports", "failures.sarif")
+	cfg.AuditLogPath = filepath.Join(dir, "audit", "commands.jsonl")
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/api"
+	"github.com/LambdaTest/synapse/pkg/batch"
+	"github.com/LambdaTest/synapse/pkg/daemon"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/server"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/LambdaTest/synapse/pkg/task"
+	"github.com/LambdaTest/synapse/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+// ServeCommand returns the command which keeps nucleus resident and runs the tasks submitted over the HTTP API
+func ServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the tasks submitted over the HTTP API in a resident nucleus",
+		Long: `serve keeps nucleus running between the tasks of a reused container. The tasks are submitted with
+POST /tasks, with the same fields as the sub-tasks of a batch, and run one after another in the discovery or the
+execution mode of the flags. The checkout of the repository is fetched incrementally between the tasks of the
+same repository, the installed dependencies and the restored caches are kept. GET /tasks/:id returns the state
+of a task, whose status is reported to neuron as usual.`,
+		Args: cobra.NoArgs,
+		RunE: serve,
+		// the failures of the daemon are not usage errors
+		SilenceUsage: true,
+	}
+	cmd.Flags().Int("queue-size", 16, "number of tasks which can be queued while a task is running")
+	return cmd
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadNucleusConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.CoverageMode || cfg.ParseMode {
+		return errors.New("the coverage and parse modes are not supported by serve")
+	}
+	if cfg.LogFile != "" {
+		cfg.LogConfig.FileLocation = filepath.Join(cfg.LogFile, "nucleus.log")
+	}
+	logger, err := lumber.NewLogger(cfg.LogConfig, cfg.Verbose, lumber.InstanceZapLogger)
+	if err != nil {
+		return fmt.Errorf("could not instantiate logger: %w", err)
+	}
+	defer closeLogger(logger)
+	if err := requestutils.Setup(&cfg.HTTP); err != nil {
+		return fmt.Errorf("failed to configure the http clients: %w", err)
+	}
+	setNeuronHost(cfg, logger)
+	ws, err := workspace.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize workspace: %w", err)
+	}
+	if err := ws.Setup(); err != nil {
+		return fmt.Errorf("failed to set up workspace: %w", err)
+	}
+
+	azureClient, err := storage.New(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize blob storage: %w", err)
+	}
+	// the status of the tasks interrupted on shutdown is still reported
+	t, err := task.New(context.Background(), cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize task: %w", err)
+	}
+	runner, err := batch.New(cfg, azureClient, t, ws, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize task runner: %w", err)
+	}
+	queueSize, _ := cmd.Flags().GetInt("queue-size")
+	d := daemon.New(runner, ws, queueSize, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger.Infof("LambdaTest Nucleus version: %s, serving tasks", global.NUCLEUS_BINARY_VERSION)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.Run(ctx)
+	}()
+	err = server.ListenAndServe(ctx, api.NewDaemonRouter(logger, d), cfg, logger)
+	// the running task is interrupted along with the server
+	stop()
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/secret"
+	"github.com/LambdaTest/synapse/pkg/tasconfigmanager"
+	"github.com/spf13/cobra"
+)
+
+// validationResult is printed by the validate-config command
+type validationResult struct {
+	File        string                        `json:"file"`
+	Valid       bool                          `json:"valid"`
+	Diagnostics []tasconfigmanager.Diagnostic `json:"diagnostics"`
+}
+
+// ValidateConfigCommand returns the command which validates a tas configuration file locally
+func ValidateConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate-config <path>",
+		Short: "Validate a tas configuration file",
+		Long: `validate-config checks the tas configuration file for syntax errors, unknown keys,
+invalid glob patterns, missing commands and unresolved secret references.
+The included fragments are resolved relative to the file and the variables are substituted.
+The diagnostics are printed as JSON, the exit code is 1 if any error is found.`,
+		Args: cobra.ExactArgs(1),
+		RunE: validateConfig,
+	}
+	cmd.Flags().String("secrets", "", "path of the repo secrets file used to resolve the secret references")
+	return cmd
+}
+
+func validateConfig(cmd *cobra.Command, args []string) error {
+	// console logs are disabled to keep stdout machine readable, errors are returned instead
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{}, false, lumber.InstanceZapLogger)
+	if err != nil {
+		return err
+	}
+
+	var secrets map[string]string
+	if secretsPath, _ := cmd.Flags().GetString("secrets"); secretsPath != "" {
+		if _, err := os.Stat(secretsPath); err != nil {
+			return fmt.Errorf("failed to read repo secrets: %w", err)
+		}
+		secrets, err = secret.New(&config.NucleusConfig{}, nil, logger).GetRepoSecret(cmd.Context(), secretsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read repo secrets: %w", err)
+		}
+		if secrets == nil {
+			secrets = map[string]string{}
+		}
+	}
+
+	diagnostics, err := tasconfigmanager.NewTASConfigManager(logger).ValidateFile(args[0], secrets)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	result := validationResult{File: args[0], Valid: true, Diagnostics: diagnostics}
+	if result.Diagnostics == nil {
+		result.Diagnostics = []tasconfigmanager.Diagnostic{}
+	}
+	for _, d := range diagnostics {
+		if d.Severity == tasconfigmanager.SeverityError {
+			result.Valid = false
+		}
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	if !result.Valid {
+		os.Exit(1)
+	}
+	return nil
+}
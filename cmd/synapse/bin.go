@@ -13,10 +13,12 @@ import (
 	"time"
 
 	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 	"github.com/LambdaTest/synapse/pkg/proxyserver"
 	"github.com/LambdaTest/synapse/pkg/runner/docker"
+	"github.com/LambdaTest/synapse/pkg/runner/k8s"
 	"github.com/LambdaTest/synapse/pkg/secrets"
 	"github.com/LambdaTest/synapse/pkg/synapse"
 	"github.com/joho/godotenv"
@@ -80,9 +82,14 @@ func run(cmd *cobra.Command, args []string) {
 	}
 	secretsManager := secrets.New(cfg, logger)
 
-	runner, err := docker.New(secretsManager, logger, cfg)
+	var runner core.DockerRunner
+	if cfg.Executor == config.KubernetesExecutor {
+		runner, err = k8s.New(secretsManager, logger, cfg)
+	} else {
+		runner, err = docker.New(secretsManager, logger, cfg)
+	}
 	if err != nil {
-		logger.Fatalf("could not instantiate k8s runner %v", err)
+		logger.Fatalf("could not instantiate %s runner %v", cfg.Executor, err)
 	}
 
 	synapse := synapse.New(runner, logger, secretsManager)
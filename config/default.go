@@ -13,9 +13,35 @@ func setNucleusDefaultConfig() {
 	viper.SetDefault("LogConfig.FileJSONFormat", true)
 	viper.SetDefault("LogConfig.FileLevel", "debug")
 	viper.SetDefault("LogConfig.FileLocation", global.HomeDir+"/nucleus.log")
+	viper.SetDefault("LogConfig.EnableHTTP", false)
+	viper.SetDefault("LogConfig.HTTPLevel", "info")
+	viper.SetDefault("LogConfig.HTTPBatchSize", 100)
+	viper.SetDefault("LogConfig.HTTPFlushInterval", 5)
+	viper.SetDefault("LogConfig.ServiceName", "nucleus")
 	viper.SetDefault("Env", "prod")
 	viper.SetDefault("Port", "9876")
+	// the gRPC results server is unauthenticated, it is only started when the port is configured
+	viper.SetDefault("GRPCPort", "")
 	viper.SetDefault("Verbose", false)
+	viper.SetDefault("TRACING.SERVICE_NAME", "nucleus")
+	viper.SetDefault("STORAGE.LOCAL_DIR", global.HomeDir+"/storage")
+	viper.SetDefault("COMPRESSION.CODEC", "zstd")
+	viper.SetDefault("WEBHOOK.TIMEOUT", 10)
+	viper.SetDefault("EXPORT.DATADOG_SITE", "datadoghq.com")
+	viper.SetDefault("EXPORT.TIMEOUT", 30)
+	viper.SetDefault("ANNOTATION.NAME", "TAS")
+	viper.SetDefault("COVERAGE_REPORT", true)
+	viper.SetDefault("ZERO_CONFIG", true)
+	viper.SetDefault("FAILURE_REPORT_PATH", global.HomeDir+"/reports/failures.sarif")
+	viper.SetDefault("AUDIT_LOG_PATH", global.HomeDir+"/audit/commands.jsonl")
+	viper.SetDefault("HTTP.MAX_RETRIES", 3)
+	viper.SetDefault("HTTP.BREAKER_THRESHOLD", 5)
+	viper.SetDefault("HTTP.BREAKER_COOLDOWN", 30)
+	viper.SetDefault("TRANSFER.PARALLELISM", 4)
+	viper.SetDefault("TRANSFER.BLOCK_SIZE", 4)
+	viper.SetDefault("VAULT.AUTH_METHOD", "token")
+	viper.SetDefault("VAULT.JWT_PATH", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	viper.SetDefault("VAULT.CACHE_TTL", 300)
 }
 
 func setSynapseDefaultConfig() {
@@ -28,4 +54,6 @@ func setSynapseDefaultConfig() {
 	viper.SetDefault("LogConfig.FileLocation", "./mould.log")
 	viper.SetDefault("Env", "prod")
 	viper.SetDefault("Verbose", false)
+	viper.SetDefault("Executor", DockerExecutor)
+	viper.SetDefault("Kubernetes.Namespace", "default")
 }
@@ -22,7 +22,9 @@ type tempSecretReader struct {
 	RepoSecrets map[string]map[string]string `json:"RepoSecrets" yaml:"RepoSecrets"`
 }
 
-// LoadNucleusConfig loads config from command instance to predefined config variables
+// LoadNucleusConfig loads config from command instance to predefined config variables.
+// The values are layered, the flags take precedence over the environment, which takes precedence
+// over the config file and the defaults.
 func LoadNucleusConfig(cmd *cobra.Command) (*NucleusConfig, error) {
 	err := viper.BindPFlags(cmd.Flags())
 	if err != nil {
@@ -51,6 +53,17 @@ func LoadNucleusConfig(cmd *cobra.Command) (*NucleusConfig, error) {
 	return populateNucleusConfig(new(NucleusConfig))
 }
 
+// ReloadNucleusConfig reads the config file loaded by LoadNucleusConfig again and returns the config with
+// the same layering. Only the settings which can change at runtime are applied by the caller.
+func ReloadNucleusConfig() (*NucleusConfig, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+	}
+	return populateNucleusConfig(new(NucleusConfig))
+}
+
 // LoadSynapseConfig loads config from command instance to predefined config variables
 func LoadSynapseConfig(cmd *cobra.Command) (*SynapseConfig, error) {
 	err := viper.BindPFlags(cmd.Flags())
@@ -114,6 +127,9 @@ func ValidateCfg(cfg *SynapseConfig, logger lumber.Logger) error {
 	if cfg.ContainerRegistry.Mode == "" {
 		return errors.New("error finding ContainerRegistry Mode in configuration file")
 	}
+	if cfg.Executor != DockerExecutor && cfg.Executor != KubernetesExecutor {
+		return fmt.Errorf("invalid executor %s in configuration file", cfg.Executor)
+	}
 	if cfg.RepoSecrets == nil {
 		logger.Debugf("no RepoSecrets found in configuration file.")
 		return nil
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadNucleusConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "nucleus.json")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"LogConfig": {"ConsoleLevel": "info"}, "neuronHost": "http://file", "blocklistRefreshInterval": 60}`), 0644))
+	cmd := &cobra.Command{}
+	cmd.Flags().String("config", "", "")
+	assert.Nil(t, cmd.Flags().Set("config", file))
+	t.Setenv("NEURON_HOST", "http://env")
+
+	cfg, err := LoadNucleusConfig(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, "info", cfg.LogConfig.ConsoleLevel)
+	// the environment takes precedence over the file
+	assert.Equal(t, "http://env", cfg.NeuronHost)
+	assert.Equal(t, 60, cfg.BlocklistRefreshInterval)
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"LogConfig": {"ConsoleLevel": "warn"}}`), 0644))
+	cfg, err = ReloadNucleusConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "warn", cfg.LogConfig.ConsoleLevel)
+	assert.Equal(t, 0, cfg.BlocklistRefreshInterval)
+}
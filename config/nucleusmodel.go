@@ -8,6 +8,7 @@ import "github.com/LambdaTest/synapse/pkg/lumber"
 type NucleusConfig struct {
 	Config         string
 	Port           string
+	GRPCPort       string `json:"grpcPort" yaml:"grpcPort"`
 	PayloadAddress string `json:"payloadAddress" yaml:"payloadAddress"`
 	LogFile        string
 	LogConfig      lumber.LoggingConfig
@@ -15,17 +16,86 @@ type NucleusConfig struct {
 	ParseMode      bool   `json:"parser" yaml:"parseOnly"`
 	DiscoverMode   bool   `json:"discover" yaml:"discoverOnly"`
 	ExecuteMode    bool   `json:"execute" yaml:"executeOnly"`
+	DryRun         bool   `json:"dryRun" yaml:"dryRun" env:"dry-run"`
+	Checkpoint     bool   `json:"checkpoint" env:"CHECKPOINT"`
 	TaskID         string `json:"taskID" env:"TASK_ID"`
 	BuildID        string `json:"buildID" env:"BUILD_ID"`
 	TargetCommit   string `json:"targetCommit" env:"TARGET_COMMIT_ID"`
 	BaseCommit     string `json:"baseCommit" env:"BASE_COMMIT_ID"`
 	Locators       string `json:"locators"`
 	LocatorAddress string `json:"locatorAddress"`
+	RerunOf        string `json:"rerunOf" env:"RERUN_OF"`
 	Env            string
 	Verbose        bool
-	Azure          Azure  `env:"AZURE"`
-	LocalRunner    bool   `env:"local"`
-	SynapseHost    string `env:"synapsehost"`
+	Azure          Azure       `env:"AZURE"`
+	LocalRunner    bool        `env:"local"`
+	SynapseHost    string      `env:"synapsehost"`
+	NeuronHost     string      `json:"neuronHost" env:"NEURON_HOST"`
+	Tracing        Tracing     `env:"TRACING"`
+	Vault          Vault       `env:"VAULT"`
+	Storage        Storage     `env:"STORAGE"`
+	Compression    Compression `env:"COMPRESSION"`
+	Webhook        Webhook     `env:"WEBHOOK"`
+	Export         Export      `env:"EXPORT"`
+	Annotation     Annotation  `env:"ANNOTATION"`
+	HTTP           HTTP        `env:"HTTP"`
+	Transfer       Transfer    `env:"TRANSFER"`
+
+	// PayloadSigning verifies the signatures of the payloads downloaded from the blob storage
+	PayloadSigning PayloadSigning `env:"PAYLOAD_SIGNING"`
+
+	// Encryption encrypts the caches, the coverage and the artifacts before they are uploaded to the blob storage
+	Encryption Encryption `env:"ENCRYPTION"`
+
+	// FailureReportPath is the local path of the SARIF report of the failed tests, it is only uploaded if empty
+	FailureReportPath string `json:"failureReportPath" env:"FAILURE_REPORT_PATH"`
+
+	// AuditLogPath is the local path of the JSONL audit log of the spawned commands, it is uploaded next to the
+	// logs of the commands
+	AuditLogPath string `json:"auditLogPath" env:"AUDIT_LOG_PATH"`
+
+	// Offline runs the pipeline without reporting to neuron, it is set by the run command
+	Offline bool `json:"offline" env:"OFFLINE"`
+
+	// Shell runs the commands, one of bash, powershell, pwsh or cmd. Defaults to bash on linux and macOS and to
+	// PowerShell on windows
+	Shell string `json:"shell" env:"RUNNER_SHELL"`
+
+	// CoverageReport generates a browsable html report of the merged coverage of each commit in coverage mode,
+	// it is uploaded next to the coverage and served under /coverage/report by the local runner
+	CoverageReport bool `json:"coverageReport" env:"COVERAGE_REPORT"`
+
+	// DiscoveryCache skips the discovery if the tests were already discovered for the commit, the configuration
+	// and the changes, e.g. by another shard of the build
+	DiscoveryCache bool `json:"discoveryCache" env:"DISCOVERY_CACHE"`
+
+	// StagePlugins is the path of the manifest of the executables run as custom stages of the pipeline
+	StagePlugins string `json:"stagePlugins" env:"STAGE_PLUGINS"`
+
+	// BlocklistRefreshInterval in seconds at which the blocklist is fetched again while the task is running,
+	// the blocklist is only fetched once if 0
+	BlocklistRefreshInterval int `json:"blocklistRefreshInterval" env:"BLOCKLIST_REFRESH_INTERVAL"`
+
+	// StallTimeout in seconds after which the task is reported as stalled if no stage transitioned and no test
+	// completed, /healthz fails once the task stalled. The task is never reported as stalled if 0
+	StallTimeout int `json:"stallTimeout" env:"STALL_TIMEOUT"`
+
+	// RepoDir is the directory the repository is cloned into, each sub-task of a batch has its own
+	RepoDir string `json:"repoDir" env:"REPO_DIR"`
+
+	// BatchAddress is the address of a batch payload, the sub-tasks of the batch are run concurrently by child
+	// nucleus processes instead of running a single task
+	BatchAddress string `json:"batchAddress" env:"BATCH_ADDRESS"`
+
+	// Workspace lays out the directories of the tasks and cleans them up between the tasks
+	Workspace Workspace `env:"WORKSPACE"`
+
+	// Limits are the hard limits of the tasks, set by the admins to protect the capacity of shared runners
+	Limits Limits `env:"LIMITS"`
+
+	// ZeroConfig infers the configuration from the manifests of the repository if the configuration file does
+	// not exist, the inferred configuration is logged and uploaded for the review of the user
+	ZeroConfig bool `json:"zeroConfig" env:"ZERO_CONFIG"`
 }
 
 // Azure providers the storage configuration.
@@ -34,3 +104,189 @@ type Azure struct {
 	StorageAccountName string `env:"STORAGE_ACCOUNT"`
 	StorageAccessKey   string `env:"STORAGE_ACCESS_KEY"`
 }
+
+// Storage provides the blob storage configuration, used instead of azure for on-prem setups.
+type Storage struct {
+	// Provider is one of azure, local or s3. Defaults to local for local runner, else azure.
+	Provider  string `env:"PROVIDER"`
+	LocalDir  string `env:"LOCAL_DIR"`
+	Endpoint  string `env:"ENDPOINT"`
+	Bucket    string `env:"BUCKET"`
+	Region    string `env:"REGION"`
+	AccessKey string `env:"ACCESS_KEY"`
+	SecretKey string `env:"SECRET_KEY"`
+}
+
+// Compression provides the codec used for the cache and coverage archives.
+type Compression struct {
+	// Codec is one of zstd, gzip or lz4
+	Codec string `env:"CODEC"`
+	// Level of the codec, 0 selects the default level
+	Level int `env:"LEVEL"`
+}
+
+// Webhook provides the webhooks notified on the task lifecycle events.
+type Webhook struct {
+	// URLs is a comma separated list of webhook URLs. URLs prefixed with slack: or teams:
+	// receive the payload in the format of the Slack or Microsoft Teams incoming webhooks.
+	URLs string `env:"URLS"`
+	// Secret used to sign the payloads with HMAC-SHA256
+	Secret string `env:"SECRET"`
+	// Events is a comma separated list of events to send, the task lifecycle events are sent if empty.
+	// The pipeline events stage.finished and error are only sent if listed.
+	Events string `env:"EVENTS"`
+	// Timeout in seconds for each delivery
+	Timeout int `env:"TIMEOUT"`
+}
+
+// Export provides the external test analytics platforms the results are exported to once the tests completed.
+type Export struct {
+	// Sinks is a comma separated list of http, bigquery and datadog, the results are not exported if empty
+	Sinks string `env:"SINKS"`
+	// HTTPURL receives the results as JSON
+	HTTPURL string `env:"HTTP_URL"`
+	// HTTPToken is sent as bearer token to HTTPURL if set
+	HTTPToken       string `env:"HTTP_TOKEN"`
+	BigQueryProject string `env:"BIGQUERY_PROJECT"`
+	BigQueryDataset string `env:"BIGQUERY_DATASET"`
+	BigQueryTable   string `env:"BIGQUERY_TABLE"`
+	// BigQueryToken is an OAuth access token, the token of the service account of the instance
+	// is fetched from the GCE metadata server if empty
+	BigQueryToken string `env:"BIGQUERY_TOKEN"`
+	DatadogAPIKey string `env:"DATADOG_API_KEY"`
+	// DatadogSite is the Datadog site of the organization e.g. datadoghq.eu
+	DatadogSite string `env:"DATADOG_SITE"`
+	// DatadogService is the service of the tests, defaults to the repository slug
+	DatadogService string `env:"DATADOG_SERVICE"`
+	// Timeout in seconds for each request
+	Timeout int `env:"TIMEOUT"`
+}
+
+// Annotation provides the checks published on the commits in the git provider.
+type Annotation struct {
+	// Enabled publishes a check with the results of each task and the coverage of the build
+	Enabled bool `env:"ENABLED"`
+	// Name prefixes the names of the checks
+	Name string `env:"NAME"`
+	// DetailsURL links the checks to the results e.g. the dashboard, {build_id} and {task_id} are substituted
+	DetailsURL string `env:"DETAILS_URL"`
+}
+
+// HTTP provides the TLS configuration of the outbound requests, e.g. behind a proxy with a private CA,
+// and the retries of the requests to the neuron host and the blob storage.
+// The proxy is read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type HTTP struct {
+	// CABundle is the path of a PEM bundle trusted along with the system CAs
+	CABundle string `env:"CA_BUNDLE"`
+	// ClientCert and ClientKey are the paths of the PEM client certificate and key for mTLS
+	ClientCert string `env:"CLIENT_CERT"`
+	ClientKey  string `env:"CLIENT_KEY"`
+	// MaxRetries of the transient failures of the requests to the neuron host and the blob storage
+	MaxRetries int `env:"MAX_RETRIES"`
+	// BreakerThreshold is the number of consecutive failures of a host which open its circuit breaker,
+	// the requests to the host fail right away for BreakerCooldown seconds then
+	BreakerThreshold int `env:"BREAKER_THRESHOLD"`
+	BreakerCooldown  int `env:"BREAKER_COOLDOWN"`
+}
+
+// Transfer tunes the transfers of the blobs of the azure storage.
+type Transfer struct {
+	// Parallelism is the number of blocks of a blob uploaded or downloaded concurrently
+	Parallelism int `env:"PARALLELISM"`
+	// BlockSize of the transfers in MiB
+	BlockSize int `env:"BLOCK_SIZE"`
+	// UploadRateLimit and DownloadRateLimit cap the bandwidth used by all the transfers of the process
+	// in KiB per second, the bandwidth is not capped if 0
+	UploadRateLimit   int `env:"UPLOAD_RATE_LIMIT"`
+	DownloadRateLimit int `env:"DOWNLOAD_RATE_LIMIT"`
+}
+
+// Workspace configures the directories of the tasks. The clone, the cache archives and the scratch files are
+// kept under Root if set, the cache archives and the scratch files are kept in the temp directory otherwise.
+type Workspace struct {
+	Root string `env:"ROOT"`
+	// CacheDir holds the downloaded cache archives before they are extracted, it overrides the one of Root
+	CacheDir string `env:"CACHE_DIR"`
+	// ScratchDir holds the temporary files of the task, e.g. the archives of the artifacts and the checkpoints.
+	// It overrides the one of Root.
+	ScratchDir string `env:"SCRATCH_DIR"`
+	// BlocklistFile, CoverageDir and CoverageReportDir override the blocklisted tests file passed to the runners,
+	// the directory of the coverage files and the one of the coverage reports, each sub-task of a batch has its own
+	BlocklistFile     string `env:"BLOCKLIST_FILE"`
+	CoverageDir       string `env:"COVERAGE_DIR"`
+	CoverageReportDir string `env:"COVERAGE_REPORT_DIR"`
+	// CacheQuota and ScratchQuota in MiB, the least recently modified files are removed beyond them between the
+	// tasks. They are unlimited if 0
+	CacheQuota   int64 `env:"CACHE_QUOTA"`
+	ScratchQuota int64 `env:"SCRATCH_QUOTA"`
+	// Cleanup is the policy between the tasks: task removes the scratch files of the task, quota keeps them
+	// within ScratchQuota. Defaults to task
+	Cleanup string `env:"CLEANUP"`
+	// KeepTasks is the number of finished task workspaces kept by the batch and the resident nucleus, with their
+	// logs and reports. All are kept if 0
+	KeepTasks int `env:"KEEP_TASKS"`
+}
+
+// Limits caps the resources used by a task, a limit is disabled if 0. The limits can not be raised by the
+// configuration file of the repository.
+type Limits struct {
+	// MaxTests is the number of tests executed by a task, the tests beyond it are reported as limit exceeded.
+	// It is only enforced if the tests are selected by locators
+	MaxTests int `env:"MAX_TESTS"`
+	// MaxDuration in seconds of the task, the task is stopped once exceeded, before the task timeout of the
+	// configuration if it is longer
+	MaxDuration int `env:"MAX_DURATION"`
+	// MaxArtifactSize in MiB of the artifact archives uploaded by a task, the remaining archives are not uploaded
+	MaxArtifactSize int64 `env:"MAX_ARTIFACT_SIZE"`
+	// MaxCacheSize in MiB of a cache archive, the larger caches are not saved
+	MaxCacheSize int64 `env:"MAX_CACHE_SIZE"`
+}
+
+// PayloadSigning provides the key verifying the detached signatures of the payloads, stored next to each payload
+// with the .sig suffix as base64. The payloads are not verified if Key is empty.
+type PayloadSigning struct {
+	// Algorithm is hmac-sha256 or ed25519, defaults to hmac-sha256
+	Algorithm string `env:"ALGORITHM"`
+	// Key is the HMAC secret or the PEM encoded ed25519 public key, a ${{ secrets.NAME }} reference is
+	// resolved from the repo secrets, which include the secrets of Vault
+	Key string `env:"KEY"`
+}
+
+// Encryption provides the AES key encrypting the caches, the coverage and the artifacts uploaded by the tasks with
+// AES-GCM, they are decrypted when downloaded. The blobs are uploaded unencrypted if Key is empty.
+type Encryption struct {
+	// Key is the base64 encoded AES-128, AES-192 or AES-256 key, a ${{ secrets.NAME }} reference is resolved
+	// from the repo secrets, which include the secrets of Vault
+	Key string `env:"KEY"`
+	// AllowPlaintext reads the blobs uploaded before the encryption was enabled as is while they are migrated.
+	// The blobs which are not encrypted are rejected by default, as anyone able to write to the container could
+	// replace an encrypted blob with one which is not authenticated.
+	AllowPlaintext bool `env:"ALLOW_PLAINTEXT"`
+}
+
+// Tracing provides the OpenTelemetry exporter configuration.
+type Tracing struct {
+	Enabled     bool   `env:"ENABLED"`
+	Endpoint    string `env:"ENDPOINT"`
+	URLPath     string `env:"URL_PATH"`
+	Insecure    bool   `env:"INSECURE"`
+	ServiceName string `env:"SERVICE_NAME"`
+}
+
+// Vault provides the HashiCorp Vault configuration for resolving repo secrets.
+// Vault is disabled if Address is empty.
+type Vault struct {
+	Address    string `env:"ADDR"`
+	Namespace  string `env:"NAMESPACE"`
+	AuthMethod string `env:"AUTH_METHOD"`
+	AuthMount  string `env:"AUTH_MOUNT"`
+	Token      string `env:"TOKEN"`
+	RoleID     string `env:"ROLE_ID"`
+	SecretID   string `env:"SECRET_ID"`
+	Role       string `env:"ROLE"`
+	JWTPath    string `env:"JWT_PATH"`
+	// SecretPaths is a comma separated list of paths, keys of each path are exposed as secrets
+	SecretPaths string `env:"SECRET_PATHS"`
+	// CacheTTL in seconds for secrets without a lease
+	CacheTTL int `env:"CACHE_TTL"`
+}
@@ -15,6 +15,39 @@ type SynapseConfig struct {
 	Git               GitConfig
 	ContainerRegistry ContainerRegistryConfig
 	RepoSecrets       map[string]map[string]string
+	Executor          ExecutorType
+	Kubernetes        KubernetesConfig
+}
+
+// ExecutorType defines where the task containers are run
+type ExecutorType string
+
+// Values that ExecutorType can take
+const (
+	DockerExecutor     ExecutorType = "docker"
+	KubernetesExecutor ExecutorType = "kubernetes"
+)
+
+// KubernetesConfig contains the configuration of the kubernetes executor
+type KubernetesConfig struct {
+	// Kubeconfig is the path of the kubeconfig file, the in-cluster configuration is used if empty
+	Kubeconfig     string
+	Namespace      string
+	ServiceAccount string
+	NodeSelector   map[string]string
+	// Requests and Limits of the task pods, the specs of the task tier are used if not set
+	Requests ResourceConfig
+	Limits   ResourceConfig
+	// ImagePullSecrets are the names of the secrets used to pull private images
+	ImagePullSecrets []string
+	// CoverageClaim is the persistent volume claim shared by the task pods for the coverage data
+	CoverageClaim string
+}
+
+// ResourceConfig contains kubernetes resource quantities, e.g. 500m and 2Gi
+type ResourceConfig struct {
+	CPU    string
+	Memory string
 }
 
 // LambdatestConfig contains credentials for lambdatest
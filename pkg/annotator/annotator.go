@@ -0,0 +1,207 @@
+// Package annotator publishes the results as checks on the commits in the git provider.
+package annotator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+)
+
+const (
+	// maxListed limits the tests listed in the summary
+	maxListed = 20
+	// maxMessageLength limits the failure messages of the annotations
+	maxMessageLength = 1000
+)
+
+type annotator struct {
+	enabled    bool
+	name       string
+	detailsURL string
+	apiURLs    map[string]string
+	httpClient http.Client
+	logger     lumber.Logger
+}
+
+// New returns a new Annotator, nothing is published if the annotations are disabled
+func New(cfg *config.NucleusConfig, logger lumber.Logger) core.Annotator {
+	return &annotator{
+		enabled:    cfg.Annotation.Enabled,
+		name:       cfg.Annotation.Name,
+		detailsURL: cfg.Annotation.DetailsURL,
+		apiURLs:    global.APIHostURLMap,
+		httpClient: requestutils.NewClient(30 * time.Second),
+		logger:     logger,
+	}
+}
+
+// Publish creates the check of the target commit in the git provider of the repository
+func (a *annotator) Publish(ctx context.Context, payload *core.Payload, cloneToken string, annotation *core.Annotation) error {
+	if !a.enabled {
+		return nil
+	}
+	commitID := payload.BuildTargetCommit
+	if commitID == "" {
+		commitID = payload.TargetCommit
+	}
+	c := &check{
+		name:       a.name + " / " + annotation.Name,
+		commitID:   commitID,
+		title:      title(annotation),
+		summary:    summary(annotation),
+		detailsURL: strings.NewReplacer("{build_id}", payload.BuildID, "{task_id}", payload.TaskID).Replace(a.detailsURL),
+		annotation: annotation,
+	}
+	var err error
+	switch payload.GitProvider {
+	case core.GitHub:
+		err = a.publishGitHub(ctx, payload, cloneToken, c)
+	case core.GitLab:
+		err = a.publishGitLab(ctx, payload, cloneToken, c)
+	case core.Bitbucket:
+		err = a.publishBitbucket(ctx, payload, cloneToken, c)
+	default:
+		return errs.ErrUnsupportedGitProvider
+	}
+	if err != nil {
+		a.logger.Errorf("failed to publish check %s on commit %s, error: %v", c.name, commitID, err)
+		return err
+	}
+	a.logger.Debugf("published check %s on commit %s", c.name, commitID)
+	return nil
+}
+
+// check is the annotation rendered for the git providers
+type check struct {
+	name       string
+	commitID   string
+	title      string
+	summary    string
+	detailsURL string
+	annotation *core.Annotation
+}
+
+// title returns the headline of the check
+func title(annotation *core.Annotation) string {
+	parts := make([]string, 0, 4)
+	if total := annotation.Passed + annotation.Failed + annotation.Skipped; total > 0 {
+		parts = append(parts, fmt.Sprintf("%d passed", annotation.Passed), fmt.Sprintf("%d failed", annotation.Failed))
+		if annotation.Skipped > 0 {
+			parts = append(parts, fmt.Sprintf("%d skipped", annotation.Skipped))
+		}
+	}
+	if annotation.DiffCoverage != nil {
+		parts = append(parts, fmt.Sprintf("diff coverage %.2f%%", *annotation.DiffCoverage))
+	}
+	if annotation.CoverageDelta != nil {
+		parts = append(parts, fmt.Sprintf("coverage %+.1f%%", *annotation.CoverageDelta))
+	}
+	if len(annotation.Violations) > 0 {
+		parts = append(parts, "coverage thresholds not met")
+	}
+	if len(parts) == 0 {
+		return "No tests impacted"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summary returns the details of the check in markdown
+func summary(annotation *core.Annotation) string {
+	var b strings.Builder
+	if total := annotation.Passed + annotation.Failed + annotation.Skipped; total > 0 {
+		fmt.Fprintf(&b, "| Impacted tests | Passed | Failed | Skipped | Flaky |\n|---|---|---|---|---|\n| %d | %d | %d | %d | %d |\n",
+			annotation.ImpactedTests, annotation.Passed, annotation.Failed, annotation.Skipped, len(annotation.Flaky))
+	}
+	if annotation.DiffCoverage != nil {
+		fmt.Fprintf(&b, "\n**Diff coverage:** %.2f%%\n", *annotation.DiffCoverage)
+	}
+	if annotation.CoverageDelta != nil {
+		fmt.Fprintf(&b, "\n**Coverage change:** %+.1f%%\n", *annotation.CoverageDelta)
+	}
+	writeList(&b, "Coverage thresholds not met", annotation.Violations)
+	writeList(&b, "Files with regressed coverage", annotation.Regressed)
+	writeList(&b, "Files with improved coverage", annotation.Improved)
+	failed := make([]string, 0, len(annotation.Failures))
+	for _, failure := range annotation.Failures {
+		failed = append(failed, fmt.Sprintf("`%s` %s", failure.File, failure.Test))
+	}
+	writeList(&b, "Failed tests", failed)
+	writeList(&b, "Flaky tests", annotation.Flaky)
+	return b.String()
+}
+
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n### %s\n", heading)
+	for i, item := range items {
+		if i == maxListed {
+			fmt.Fprintf(b, "- and %d more\n", len(items)-maxListed)
+			break
+		}
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// message returns the message of the failure annotation
+func message(failure *core.AnnotationFailure) string {
+	msg := failure.Message
+	if msg == "" {
+		msg = "Test failed"
+	}
+	if len(msg) > maxMessageLength {
+		msg = msg[:maxMessageLength] + "..."
+	}
+	return msg
+}
+
+// line returns the line of the failure annotation, the first line if the line is unknown
+func line(failure *core.AnnotationFailure) int {
+	if failure.Line < 1 {
+		return 1
+	}
+	return failure.Line
+}
+
+// request sends the body as JSON with the token of the repository
+func (a *annotator) request(ctx context.Context, method, url, cloneToken string, body, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cloneToken))
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s %s returned status %d: %s", method, url, resp.StatusCode, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,138 @@
+package annotator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	method string
+	path   string
+	body   []byte
+}
+
+func newTestAnnotator(t *testing.T) (*annotator, *[]request, func()) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	var mu sync.Mutex
+	requests := make([]request, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		mu.Lock()
+		requests = append(requests, request{method: r.Method, path: r.URL.EscapedPath(), body: body})
+		mu.Unlock()
+		if r.Method == http.MethodPost && r.URL.Path == "/github/org/repo/check-runs" {
+			_, _ = w.Write([]byte(`{"id":7}`))
+		}
+	}))
+	cfg := &config.NucleusConfig{Annotation: config.Annotation{Enabled: true, Name: "TAS", DetailsURL: "https://tas.example.com/builds/{build_id}"}}
+	a := New(cfg, logger).(*annotator)
+	a.apiURLs = map[string]string{
+		core.GitHub:    server.URL + "/github",
+		core.GitLab:    server.URL + "/gitlab",
+		core.Bitbucket: server.URL + "/bitbucket",
+	}
+	return a, &requests, server.Close
+}
+
+func testAnnotation(failures int) *core.Annotation {
+	annotation := &core.Annotation{Name: "task", ImpactedTests: 3 + failures, Passed: 2, Skipped: 1, Failed: failures, Flaky: []string{"api retries"}}
+	for i := 0; i < failures; i++ {
+		annotation.Failures = append(annotation.Failures, core.AnnotationFailure{
+			File: "api.test.js", Line: i, Test: fmt.Sprintf("api test %d", i), Message: "expected 200",
+		})
+	}
+	return annotation
+}
+
+func TestPublishGitHub(t *testing.T) {
+	a, requests, closeServer := newTestAnnotator(t)
+	defer closeServer()
+	payload := &core.Payload{GitProvider: core.GitHub, RepoSlug: "org/repo", BuildTargetCommit: "abc", BuildID: "build"}
+
+	assert.Nil(t, a.Publish(context.Background(), payload, "token", testAnnotation(60)))
+	assert.Len(t, *requests, 2)
+	create := (*requests)[0]
+	assert.Equal(t, http.MethodPost, create.method)
+	run := githubCheckRun{}
+	assert.Nil(t, json.Unmarshal(create.body, &run))
+	assert.Equal(t, "TAS / task", run.Name)
+	assert.Equal(t, "abc", run.HeadSHA)
+	assert.Equal(t, "failure", run.Conclusion)
+	assert.Equal(t, "https://tas.example.com/builds/build", run.DetailsURL)
+	assert.Equal(t, "2 passed, 60 failed, 1 skipped", run.Output.Title)
+	assert.Contains(t, run.Output.Summary, "### Flaky tests\n- api retries\n")
+	assert.Contains(t, run.Output.Summary, "- and 40 more\n")
+	assert.Len(t, run.Output.Annotations, 50)
+	assert.Equal(t, githubAnnotation{Path: "api.test.js", StartLine: 1, EndLine: 1, AnnotationLevel: "failure", Title: "api test 0", Message: "expected 200"},
+		run.Output.Annotations[0])
+
+	update := (*requests)[1]
+	assert.Equal(t, http.MethodPatch, update.method)
+	assert.Equal(t, "/github/org/repo/check-runs/7", update.path)
+	run = githubCheckRun{}
+	assert.Nil(t, json.Unmarshal(update.body, &run))
+	assert.Len(t, run.Output.Annotations, 10)
+}
+
+func TestPublishGitLab(t *testing.T) {
+	a, requests, closeServer := newTestAnnotator(t)
+	defer closeServer()
+	payload := &core.Payload{GitProvider: core.GitLab, RepoSlug: "org/repo", BuildTargetCommit: "abc"}
+
+	assert.Nil(t, a.Publish(context.Background(), payload, "token", testAnnotation(1)))
+	assert.Len(t, *requests, 2)
+	assert.Equal(t, "/gitlab/org%2Frepo/statuses/abc", (*requests)[0].path)
+	status := gitlabStatus{}
+	assert.Nil(t, json.Unmarshal((*requests)[0].body, &status))
+	assert.Equal(t, "failed", status.State)
+	assert.Equal(t, "TAS / task", status.Name)
+	assert.Equal(t, "/gitlab/org%2Frepo/repository/commits/abc/comments", (*requests)[1].path)
+	comment := gitlabComment{}
+	assert.Nil(t, json.Unmarshal((*requests)[1].body, &comment))
+	assert.Equal(t, "api.test.js", comment.Path)
+	assert.Equal(t, 1, comment.Line)
+}
+
+func TestPublishBitbucket(t *testing.T) {
+	a, requests, closeServer := newTestAnnotator(t)
+	defer closeServer()
+	payload := &core.Payload{GitProvider: core.Bitbucket, RepoSlug: "org/repo", BuildTargetCommit: "abc"}
+	diffCoverage := 85.5
+	annotation := &core.Annotation{Name: "coverage", DiffCoverage: &diffCoverage}
+
+	assert.Nil(t, a.Publish(context.Background(), payload, "token", annotation))
+	assert.Len(t, *requests, 1)
+	assert.Equal(t, http.MethodPut, (*requests)[0].method)
+	assert.Equal(t, "/bitbucket/org/repo/commit/abc/reports/tas-coverage", (*requests)[0].path)
+	report := bitbucketReport{}
+	assert.Nil(t, json.Unmarshal((*requests)[0].body, &report))
+	assert.Equal(t, "PASSED", report.Result)
+	assert.Equal(t, "diff coverage 85.50%", report.Details)
+
+	delta := -1.5
+	annotation.CoverageDelta = &delta
+	annotation.Regressed = []string{"`src/a.js` 80.0% → 60.0% (-20.0%)"}
+	assert.Equal(t, "diff coverage 85.50%, coverage -1.5%", title(annotation))
+	assert.Contains(t, summary(annotation), "**Coverage change:** -1.5%\n\n### Files with regressed coverage\n- `src/a.js`")
+}
+
+func TestPublishDisabled(t *testing.T) {
+	a, requests, closeServer := newTestAnnotator(t)
+	defer closeServer()
+	a.enabled = false
+	assert.Nil(t, a.Publish(context.Background(), &core.Payload{GitProvider: core.GitHub}, "token", testAnnotation(1)))
+	assert.Empty(t, *requests)
+}
@@ -0,0 +1,99 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// bitbucketAnnotationBatch is the maximum number of annotations of a request
+const bitbucketAnnotationBatch = 100
+
+var reportIDRegex = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+type bitbucketReport struct {
+	Title      string            `json:"title"`
+	Details    string            `json:"details"`
+	ReportType string            `json:"report_type"`
+	Reporter   string            `json:"reporter"`
+	Link       string            `json:"link,omitempty"`
+	Result     string            `json:"result"`
+	Data       []bitbucketMetric `json:"data"`
+}
+
+type bitbucketMetric struct {
+	Title string      `json:"title"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type bitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Details        string `json:"details"`
+	Path           string `json:"path"`
+	Line           int    `json:"line"`
+	Severity       string `json:"severity"`
+	Result         string `json:"result"`
+}
+
+// publishBitbucket creates a Code Insights report of the commit with the failures as its annotations
+func (a *annotator) publishBitbucket(ctx context.Context, payload *core.Payload, cloneToken string, c *check) error {
+	reportID := strings.ToLower(strings.Trim(reportIDRegex.ReplaceAllString(c.name, "-"), "-"))
+	reportURL := fmt.Sprintf("%s/%s/commit/%s/reports/%s", a.apiURLs[core.Bitbucket], payload.RepoSlug, c.commitID, reportID)
+	result := "PASSED"
+	if c.annotation.Failing() {
+		result = "FAILED"
+	}
+	report := bitbucketReport{
+		Title:      c.name,
+		Details:    c.title,
+		ReportType: "TEST",
+		Reporter:   a.name,
+		Link:       c.detailsURL,
+		Result:     result,
+		Data: []bitbucketMetric{
+			{Title: "Impacted tests", Type: "NUMBER", Value: c.annotation.ImpactedTests},
+			{Title: "Passed", Type: "NUMBER", Value: c.annotation.Passed},
+			{Title: "Failed", Type: "NUMBER", Value: c.annotation.Failed},
+			{Title: "Skipped", Type: "NUMBER", Value: c.annotation.Skipped},
+			{Title: "Flaky", Type: "NUMBER", Value: len(c.annotation.Flaky)},
+		},
+	}
+	if c.annotation.DiffCoverage != nil {
+		report.Data = append(report.Data, bitbucketMetric{Title: "Diff coverage", Type: "PERCENTAGE", Value: *c.annotation.DiffCoverage})
+	}
+	// the report replaces the previous report of the commit along with its annotations
+	if err := a.request(ctx, http.MethodPut, reportURL, cloneToken, report, nil); err != nil {
+		return err
+	}
+	annotations := make([]bitbucketAnnotation, 0, len(c.annotation.Failures))
+	for i := range c.annotation.Failures {
+		failure := &c.annotation.Failures[i]
+		annotations = append(annotations, bitbucketAnnotation{
+			ExternalID:     fmt.Sprintf("%s-%d", reportID, i),
+			AnnotationType: "BUG",
+			Summary:        failure.Test,
+			Details:        message(failure),
+			Path:           failure.File,
+			Line:           line(failure),
+			Severity:       "HIGH",
+			Result:         "FAILED",
+		})
+	}
+	for start := 0; start < len(annotations); start += bitbucketAnnotationBatch {
+		end := start + bitbucketAnnotationBatch
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		if err := a.request(ctx, http.MethodPost, reportURL+"/annotations", cloneToken, annotations[start:end], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// githubAnnotationBatch is the maximum number of annotations of a check run request
+const githubAnnotationBatch = 50
+
+type githubCheckRun struct {
+	Name        string            `json:"name,omitempty"`
+	HeadSHA     string            `json:"head_sha,omitempty"`
+	DetailsURL  string            `json:"details_url,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Conclusion  string            `json:"conclusion,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	Output      githubCheckOutput `json:"output"`
+}
+
+type githubCheckOutput struct {
+	Title       string             `json:"title"`
+	Summary     string             `json:"summary"`
+	Annotations []githubAnnotation `json:"annotations,omitempty"`
+}
+
+type githubAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+// publishGitHub creates a check run, the annotations exceeding the limit of a request are
+// appended by updating the check run
+func (a *annotator) publishGitHub(ctx context.Context, payload *core.Payload, cloneToken string, c *check) error {
+	annotations := make([]githubAnnotation, 0, len(c.annotation.Failures))
+	for i := range c.annotation.Failures {
+		failure := &c.annotation.Failures[i]
+		annotations = append(annotations, githubAnnotation{
+			Path:            failure.File,
+			StartLine:       line(failure),
+			EndLine:         line(failure),
+			AnnotationLevel: "failure",
+			Title:           failure.Test,
+			Message:         message(failure),
+		})
+	}
+	conclusion := "success"
+	if c.annotation.Failing() {
+		conclusion = "failure"
+	}
+	completedAt := time.Now()
+	run := githubCheckRun{
+		Name:        c.name,
+		HeadSHA:     c.commitID,
+		DetailsURL:  c.detailsURL,
+		Status:      "completed",
+		Conclusion:  conclusion,
+		CompletedAt: &completedAt,
+		Output:      githubCheckOutput{Title: c.title, Summary: c.summary, Annotations: batch(annotations, 0)},
+	}
+	url := fmt.Sprintf("%s/%s/check-runs", a.apiURLs[core.GitHub], payload.RepoSlug)
+	created := struct {
+		ID int64 `json:"id"`
+	}{}
+	if err := a.request(ctx, http.MethodPost, url, cloneToken, run, &created); err != nil {
+		return err
+	}
+	for start := githubAnnotationBatch; start < len(annotations); start += githubAnnotationBatch {
+		update := githubCheckRun{Output: githubCheckOutput{Title: c.title, Summary: c.summary, Annotations: batch(annotations, start)}}
+		if err := a.request(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", url, created.ID), cloneToken, update, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func batch(annotations []githubAnnotation, start int) []githubAnnotation {
+	if start >= len(annotations) {
+		return nil
+	}
+	end := start + githubAnnotationBatch
+	if end > len(annotations) {
+		end = len(annotations)
+	}
+	return annotations[start:end]
+}
@@ -0,0 +1,61 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// maxDescriptionLength is the maximum length of the description of a commit status
+const maxDescriptionLength = 255
+
+type gitlabStatus struct {
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+type gitlabComment struct {
+	Note     string `json:"note"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	LineType string `json:"line_type"`
+}
+
+// publishGitLab sets the commit status and comments the failures on the lines of the test files,
+// as GitLab has no annotations of the commit statuses
+func (a *annotator) publishGitLab(ctx context.Context, payload *core.Payload, cloneToken string, c *check) error {
+	project := fmt.Sprintf("%s/%s", a.apiURLs[core.GitLab], url.QueryEscape(payload.RepoSlug))
+	state := "success"
+	if c.annotation.Failing() {
+		state = "failed"
+	}
+	description := c.title
+	if len(description) > maxDescriptionLength {
+		description = description[:maxDescriptionLength-3] + "..."
+	}
+	status := gitlabStatus{State: state, Name: c.name, Description: description, TargetURL: c.detailsURL}
+	if err := a.request(ctx, http.MethodPost, fmt.Sprintf("%s/statuses/%s", project, c.commitID), cloneToken, status, nil); err != nil {
+		return err
+	}
+	for i := range c.annotation.Failures {
+		if i == maxListed {
+			break
+		}
+		failure := &c.annotation.Failures[i]
+		comment := gitlabComment{
+			Note:     fmt.Sprintf("**%s** failed: %s\n\n```\n%s\n```", c.name, failure.Test, message(failure)),
+			Path:     failure.File,
+			Line:     line(failure),
+			LineType: "new",
+		}
+		if err := a.request(ctx, http.MethodPost, fmt.Sprintf("%s/repository/commits/%s/comments", project, c.commitID), cloneToken, comment, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package blocklist
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/testblocklistservice"
+	"github.com/gin-gonic/gin"
+)
+
+//ListHandler returns the blocklist entries of the yml, the api and the ones added at runtime
+func ListHandler(tbs *testblocklistservice.TestBlockListService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, tbs.Entries())
+	}
+}
+
+//AddHandler adds a blocklist entry, it applies to the tests run afterwards
+func AddHandler(logger lumber.Logger, tbs *testblocklistservice.TestBlockListService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := core.BlocklistEntry{}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Errorf("error while binding json %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if err := tbs.AddEntry(request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		logger.Infof("blocklist entry %s added at runtime", request.Value())
+		c.JSON(http.StatusCreated, request)
+	}
+}
+
+//RemoveHandler removes a blocklist entry added at runtime
+func RemoveHandler(logger lumber.Logger, tbs *testblocklistservice.TestBlockListService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := core.BlocklistEntry{}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Errorf("error while binding json %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		if err := tbs.RemoveEntry(request); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errs.ErrBlocklistEntryNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"message": err.Error()})
+			return
+		}
+		logger.Infof("blocklist entry %s removed at runtime", request.Value())
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/LambdaTest/synapse/pkg/api/health"
+	"github.com/LambdaTest/synapse/pkg/api/tasks"
+	"github.com/LambdaTest/synapse/pkg/daemon"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/gin-gonic/gin"
+)
+
+// DaemonRouter for the resident nucleus, the API of each task is served by the child running it
+type DaemonRouter struct {
+	logger lumber.Logger
+	daemon *daemon.Daemon
+}
+
+// NewDaemonRouter returns instance of DaemonRouter
+func NewDaemonRouter(logger lumber.Logger, d *daemon.Daemon) DaemonRouter {
+	return DaemonRouter{logger: logger, daemon: d}
+}
+
+// Handler function will perform all route operations
+func (r DaemonRouter) Handler() *gin.Engine {
+	r.logger.Infof("Setting up routes")
+	router := gin.Default()
+	router.GET("/health", health.Handler)
+	router.POST("/tasks", tasks.SubmitHandler(r.logger, r.daemon))
+	router.GET("/tasks/:id", tasks.GetHandler(r.daemon))
+	return router
+}
@@ -3,6 +3,7 @@ package health
 import (
 	"net/http"
 
+	"github.com/LambdaTest/synapse/pkg/service/taskstatus"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,3 +11,32 @@ import (
 func Handler(c *gin.Context) {
 	c.Data(http.StatusOK, gin.MIMEPlain, []byte(http.StatusText(http.StatusOK)))
 }
+
+// LivenessHandler fails once the task stalled, so that a hung nucleus is restarted
+func LivenessHandler(tracker *taskstatus.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status := tracker.Status(); status.Stalled {
+			c.JSON(http.StatusServiceUnavailable, status)
+			return
+		}
+		Handler(c)
+	}
+}
+
+// ReadinessHandler succeeds once the payload is loaded, until a stage fails
+func ReadinessHandler(tracker *taskstatus.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status := tracker.Status(); !status.Ready {
+			c.JSON(http.StatusServiceUnavailable, status)
+			return
+		}
+		Handler(c)
+	}
+}
+
+// StatusHandler returns the stage, the progress and the last error of the task
+func StatusHandler(tracker *taskstatus.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, tracker.Status())
+	}
+}
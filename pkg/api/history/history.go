@@ -0,0 +1,116 @@
+package history
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+// RunsHandler returns the latest runs of the repository given by the repo_id query parameter
+func RunsHandler(logger lumber.Logger, store core.ResultStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repoID, limit, ok := parseQuery(c, "limit")
+		if !ok {
+			return
+		}
+		runs, err := store.Runs(c.Request.Context(), repoID, limit)
+		respond(c, logger, runs, err)
+	}
+}
+
+// TestHandler returns the latest results of the test given by the test_id query parameter
+func TestHandler(logger lumber.Logger, store core.ResultStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repoID, limit, ok := parseQuery(c, "limit")
+		if !ok {
+			return
+		}
+		testID := c.Query("test_id")
+		if testID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "test_id is required"})
+			return
+		}
+		history, err := store.TestHistory(c.Request.Context(), repoID, testID, limit)
+		respond(c, logger, history, err)
+	}
+}
+
+// FailedHandler returns the locators of the failed tests of the task given by the task_id query parameter,
+// which are run again by a task re-running it
+func FailedHandler(logger lumber.Logger, store core.ResultStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repoID, taskID := c.Query("repo_id"), c.Query("task_id")
+		if repoID == "" || taskID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "repo_id and task_id are required"})
+			return
+		}
+		tests, err := store.FailedTests(c.Request.Context(), repoID, taskID)
+		respond(c, logger, tests, err)
+	}
+}
+
+// FlakyHandler returns the tests which both passed and failed in the number of latest runs given by the runs query parameter
+func FlakyHandler(logger lumber.Logger, store core.ResultStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repoID, runs, ok := parseQuery(c, "runs")
+		if !ok {
+			return
+		}
+		tests, err := store.FlakyTests(c.Request.Context(), repoID, runs)
+		respond(c, logger, tests, err)
+	}
+}
+
+// CoverageHandler returns the coverage of the latest commits of the repository
+func CoverageHandler(logger lumber.Logger, store core.ResultStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repoID, limit, ok := parseQuery(c, "limit")
+		if !ok {
+			return
+		}
+		trend, err := store.CoverageTrend(c.Request.Context(), repoID, limit)
+		respond(c, logger, trend, err)
+	}
+}
+
+// parseQuery returns the required repo_id and the count query parameters, the response is written if they are invalid
+func parseQuery(c *gin.Context, countParam string) (repoID string, count int, ok bool) {
+	repoID = c.Query("repo_id")
+	if repoID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "repo_id is required"})
+		return "", 0, false
+	}
+	count = defaultLimit
+	if value := c.Query(countParam); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": countParam + " must be between 1 and " + strconv.Itoa(maxLimit)})
+			return "", 0, false
+		}
+		count = n
+	}
+	return repoID, count, true
+}
+
+func respond(c *gin.Context, logger lumber.Logger, body interface{}, err error) {
+	if err != nil {
+		if errors.Is(err, errs.ErrResultStoreDisabled) || errors.Is(err, errs.ErrRunNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+			return
+		}
+		logger.Errorf("failed to query the results store, error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
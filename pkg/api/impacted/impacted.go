@@ -0,0 +1,29 @@
+package impacted
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/gin-gonic/gin"
+)
+
+//Handler returns the changed files and the impacted tests of the commit given by the sha query parameter,
+//compared to the base query parameter or the base commit of the task. No test is executed.
+func Handler(logger lumber.Logger, is core.ImpactService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := is.ImpactedTests(c.Request.Context(), c.Query("base"), c.Query("sha"))
+		if err != nil {
+			if errors.Is(err, errs.ErrTaskNotReady) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+				return
+			}
+			logger.Errorf("failed to get impacted tests of commit %s, error: %v", c.Query("sha"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPHandler returns the requests, retries and circuit breaker states of the calls to the neuron host and the blob storage
+func HTTPHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, requestutils.Stats())
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/gin-gonic/gin"
+)
+
+// StageStats are the runs of a stage
+type StageStats struct {
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	// Duration is the total duration of the runs in milliseconds
+	Duration int64 `json:"duration"`
+}
+
+// PipelineStats are the counts of the events of the pipeline since the nucleus started
+type PipelineStats struct {
+	// Events is the number of events by type, e.g. cache.hit and error
+	Events map[core.PipelineEventType]int `json:"events"`
+	Stages map[string]*StageStats         `json:"stages"`
+	// Tests is the number of tests by status, the status of a test is its last reported one
+	Tests map[string]int `json:"tests"`
+}
+
+// Pipeline collects the PipelineStats from the events of the pipeline, it is safe for concurrent use
+type Pipeline struct {
+	mu    sync.Mutex
+	stats PipelineStats
+	// taskID and tests are the task of the last result and the status of its tests, the partial results
+	// are published again with the complete result of the runner
+	taskID string
+	tests  map[string]string
+}
+
+// NewPipeline returns the collector of the pipeline metrics, it must be subscribed to the events of the pipeline
+func NewPipeline() *Pipeline {
+	return &Pipeline{
+		stats: PipelineStats{
+			Events: make(map[core.PipelineEventType]int),
+			Stages: make(map[string]*StageStats),
+			Tests:  make(map[string]int),
+		},
+		tests: make(map[string]string),
+	}
+}
+
+// HandleEvent counts the event
+func (p *Pipeline) HandleEvent(ctx context.Context, event *core.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Events[event.Type]++
+	switch event.Type {
+	case core.EventStageFinished:
+		stage, ok := p.stats.Stages[event.Stage]
+		if !ok {
+			stage = new(StageStats)
+			p.stats.Stages[event.Stage] = stage
+		}
+		stage.Runs++
+		if event.Err != nil {
+			stage.Failures++
+		}
+		stage.Duration += event.Duration.Milliseconds()
+	case core.EventTestResult:
+		p.addResult(event.Result)
+	}
+}
+
+func (p *Pipeline) addResult(result *core.ExecutionResult) {
+	if result.TaskID != p.taskID {
+		p.taskID = result.TaskID
+		p.tests = make(map[string]string)
+	}
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		if test.Status == core.TestStarted {
+			continue
+		}
+		previous, ok := p.tests[test.TestID]
+		if ok && previous == test.Status {
+			continue
+		}
+		if ok {
+			p.stats.Tests[previous]--
+		}
+		p.tests[test.TestID] = test.Status
+		p.stats.Tests[test.Status]++
+	}
+}
+
+// Stats returns a copy of the collected stats
+func (p *Pipeline) Stats() PipelineStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := PipelineStats{
+		Events: make(map[core.PipelineEventType]int, len(p.stats.Events)),
+		Stages: make(map[string]*StageStats, len(p.stats.Stages)),
+		Tests:  make(map[string]int, len(p.stats.Tests)),
+	}
+	for eventType, count := range p.stats.Events {
+		stats.Events[eventType] = count
+	}
+	for name, stage := range p.stats.Stages {
+		stage := *stage
+		stats.Stages[name] = &stage
+	}
+	for status, count := range p.stats.Tests {
+		if count > 0 {
+			stats.Tests[status] = count
+		}
+	}
+	return stats
+}
+
+// PipelineHandler returns the events, the stage durations and the test statuses counted by the pipeline metrics
+func PipelineHandler(p *Pipeline) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, p.Stats())
+	}
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline()
+	events := core.NewEventBus()
+	events.Subscribe(p)
+	ctx := context.Background()
+
+	events.Emit(ctx, &core.Event{Type: core.EventStageFinished, Stage: core.StageInstall, Duration: 2 * time.Second})
+	events.Emit(ctx, &core.Event{Type: core.EventStageFinished, Stage: core.StageInstall, Duration: time.Second, Err: errors.New("failed")})
+	events.Emit(ctx, &core.Event{Type: core.EventCacheHit, Cache: "node_modules"})
+	events.Emit(ctx, &core.Event{Type: core.EventTestResult, Result: &core.ExecutionResult{TaskID: "task", TestPayload: []core.TestPayload{
+		{TestID: "1", Status: core.TestStarted},
+		{TestID: "2", Status: core.TestFailed},
+	}}})
+	// the complete result of the runner republishes the partial results
+	events.Emit(ctx, &core.Event{Type: core.EventTestResult, Result: &core.ExecutionResult{TaskID: "task", TestPayload: []core.TestPayload{
+		{TestID: "1", Status: "passed"},
+		{TestID: "2", Status: core.TestFailed},
+	}}})
+	// the tests of another task are counted again
+	events.Emit(ctx, &core.Event{Type: core.EventTestResult, Result: &core.ExecutionResult{TaskID: "rerun", TestPayload: []core.TestPayload{
+		{TestID: "2", Status: "passed"},
+	}}})
+
+	stats := p.Stats()
+	assert.Equal(t, 2, stats.Events[core.EventStageFinished])
+	assert.Equal(t, 1, stats.Events[core.EventCacheHit])
+	assert.Equal(t, 3, stats.Events[core.EventTestResult])
+	assert.Equal(t, &StageStats{Runs: 2, Failures: 1, Duration: 3000}, stats.Stages[core.StageInstall])
+	assert.Equal(t, map[string]int{"passed": 2, core.TestFailed: 1}, stats.Tests)
+}
@@ -0,0 +1,143 @@
+package results
+
+import (
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/api/resultspb"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toExecutionResult(in *resultspb.ExecutionResult) core.ExecutionResult {
+	out := core.ExecutionResult{
+		TaskID:           in.TaskId,
+		BuildID:          in.BuildId,
+		RepoID:           in.RepoId,
+		OrgID:            in.OrgId,
+		CommitID:         in.CommitId,
+		TestPayload:      make([]core.TestPayload, 0, len(in.TestResults)),
+		TestSuitePayload: make([]core.TestSuitePayload, 0, len(in.TestSuiteResults)),
+	}
+	for _, t := range in.TestResults {
+		out.TestPayload = append(out.TestPayload, toTestPayload(t))
+	}
+	for _, s := range in.TestSuiteResults {
+		out.TestSuitePayload = append(out.TestSuitePayload, toTestSuitePayload(s))
+	}
+	return out
+}
+
+func toTestPayload(in *resultspb.TestResult) core.TestPayload {
+	return core.TestPayload{
+		TestID:          in.TestId,
+		Detail:          in.Detail,
+		SuiteID:         in.SuiteId,
+		Suites:          in.Suites,
+		Title:           in.Title,
+		FullTitle:       in.FullTitle,
+		Name:            in.Name,
+		Duration:        int(in.Duration),
+		FilePath:        in.File,
+		Line:            in.Line,
+		Col:             in.Col,
+		CurrentRetry:    int(in.CurrentRetry),
+		Status:          in.Status,
+		CommitID:        in.CommitId,
+		DAG:             in.DependsOn,
+		Filelocator:     in.Locator,
+		BlocklistSource: in.BlocklistSource,
+		Blocklisted:     in.Blocklisted,
+		StartTime:       toTime(in.StartTime),
+		EndTime:         toTime(in.EndTime),
+		Stats:           toProcessStats(in.Stats),
+	}
+}
+
+func toTestSuitePayload(in *resultspb.TestSuiteResult) core.TestSuitePayload {
+	return core.TestSuitePayload{
+		SuiteID:         in.SuiteId,
+		SuiteName:       in.SuiteName,
+		ParentSuiteID:   in.ParentSuiteId,
+		BlacklistSource: in.BlocklistSource,
+		Blacklisted:     in.Blocklisted,
+		StartTime:       toTime(in.StartTime),
+		EndTime:         toTime(in.EndTime),
+		Duration:        int(in.Duration),
+		Status:          in.Status,
+		Stats:           toProcessStats(in.Stats),
+	}
+}
+
+func toProcessStats(in []*resultspb.ProcessStats) []core.TestProcessStats {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]core.TestProcessStats, 0, len(in))
+	for _, s := range in {
+		out = append(out, core.TestProcessStats{Memory: s.Memory, CPU: s.Cpu, Storage: s.Storage, RecordTime: toTime(s.RecordTime)})
+	}
+	return out
+}
+
+func toTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func fromTestPayload(in *core.TestPayload) *resultspb.TestResult {
+	return &resultspb.TestResult{
+		TestId:          in.TestID,
+		Detail:          in.Detail,
+		SuiteId:         in.SuiteID,
+		Suites:          in.Suites,
+		Title:           in.Title,
+		FullTitle:       in.FullTitle,
+		Name:            in.Name,
+		Duration:        int64(in.Duration),
+		File:            in.FilePath,
+		Line:            in.Line,
+		Col:             in.Col,
+		CurrentRetry:    int32(in.CurrentRetry),
+		Status:          in.Status,
+		CommitId:        in.CommitID,
+		DependsOn:       in.DAG,
+		Locator:         in.Filelocator,
+		BlocklistSource: in.BlocklistSource,
+		Blocklisted:     in.Blocklisted,
+		StartTime:       fromTime(in.StartTime),
+		EndTime:         fromTime(in.EndTime),
+		Stats:           fromProcessStats(in.Stats),
+	}
+}
+
+func fromTestSuitePayload(in *core.TestSuitePayload) *resultspb.TestSuiteResult {
+	return &resultspb.TestSuiteResult{
+		SuiteId:         in.SuiteID,
+		SuiteName:       in.SuiteName,
+		ParentSuiteId:   in.ParentSuiteID,
+		BlocklistSource: in.BlacklistSource,
+		Blocklisted:     in.Blacklisted,
+		StartTime:       fromTime(in.StartTime),
+		EndTime:         fromTime(in.EndTime),
+		Duration:        int64(in.Duration),
+		Status:          in.Status,
+		Stats:           fromProcessStats(in.Stats),
+	}
+}
+
+func fromProcessStats(in []core.TestProcessStats) []*resultspb.ProcessStats {
+	out := make([]*resultspb.ProcessStats, 0, len(in))
+	for _, s := range in {
+		out = append(out, &resultspb.ProcessStats{Memory: s.Memory, Cpu: s.CPU, Storage: s.Storage, RecordTime: fromTime(s.RecordTime)})
+	}
+	return out
+}
+
+func fromTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,89 @@
+package results
+
+import (
+	"context"
+	"io"
+
+	"github.com/LambdaTest/synapse/pkg/api/resultspb"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"google.golang.org/grpc/metadata"
+)
+
+type grpcServer struct {
+	resultspb.UnimplementedResultsServer
+	logger lumber.Logger
+	ts     *teststats.ProcStats
+}
+
+// NewGRPCServer returns the gRPC results service, results are processed the same as the HTTP handler
+func NewGRPCServer(logger lumber.Logger, ts *teststats.ProcStats) resultspb.ResultsServer {
+	return &grpcServer{logger: logger, ts: ts}
+}
+
+func (s *grpcServer) SubmitResults(ctx context.Context, in *resultspb.ExecutionResult) (*resultspb.SubmitResponse, error) {
+	result := toExecutionResult(in)
+	s.ts.Publish(result)
+	received := s.ts.Submit(result)
+	return &resultspb.SubmitResponse{Received: int32(received)}, nil
+}
+
+func (s *grpcServer) StreamResults(stream resultspb.Results_StreamResultsServer) error {
+	merged := core.ExecutionResult{}
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.logger.Errorf("error while receiving results stream %v", err)
+			return err
+		}
+		result := toExecutionResult(in)
+		s.ts.Publish(result)
+		merged.TaskID, merged.BuildID, merged.RepoID = result.TaskID, result.BuildID, result.RepoID
+		merged.OrgID, merged.CommitID = result.OrgID, result.CommitID
+		merged.TestPayload = append(merged.TestPayload, result.TestPayload...)
+		merged.TestSuitePayload = append(merged.TestSuitePayload, result.TestSuitePayload...)
+	}
+	received := s.ts.Submit(merged)
+	return stream.SendAndClose(&resultspb.SubmitResponse{Received: int32(received)})
+}
+
+func (s *grpcServer) WatchResults(in *resultspb.WatchRequest, stream resultspb.Results_WatchResultsServer) error {
+	results, unsubscribe := s.ts.Subscribe()
+	defer unsubscribe()
+	// headers signal the client that the updates after this point are received
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case result := <-results:
+			if in.TaskId != "" && result.TaskID != in.TaskId {
+				continue
+			}
+			for i := range result.TestPayload {
+				update := &resultspb.ResultUpdate{
+					TaskId: result.TaskID,
+					Result: &resultspb.ResultUpdate_TestResult{TestResult: fromTestPayload(&result.TestPayload[i])},
+				}
+				if err := stream.Send(update); err != nil {
+					return err
+				}
+			}
+			for i := range result.TestSuitePayload {
+				update := &resultspb.ResultUpdate{
+					TaskId: result.TaskID,
+					Result: &resultspb.ResultUpdate_TestSuiteResult{TestSuiteResult: fromTestSuitePayload(&result.TestSuitePayload[i])},
+				}
+				if err := stream.Send(update); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package results
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/api/resultspb"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCStreamResults(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	ts, err := teststats.New(nil, logger)
+	assert.Nil(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	resultspb.RegisterResultsServer(srv, NewGRPCServer(logger, ts))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := resultspb.NewResultsClient(conn)
+
+	watch, err := client.WatchResults(ctx, &resultspb.WatchRequest{TaskId: "task"})
+	assert.Nil(t, err)
+	// headers are sent once the watcher is subscribed
+	_, err = watch.Header()
+	assert.Nil(t, err)
+
+	stream, err := client.StreamResults(ctx)
+	assert.Nil(t, err)
+	for _, status := range []string{"started", "passed"} {
+		err := stream.Send(&resultspb.ExecutionResult{TaskId: "task", TestResults: []*resultspb.TestResult{{TestId: "1", Status: status}}})
+		assert.Nil(t, err)
+	}
+	resp, err := stream.CloseAndRecv()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), resp.Received)
+
+	for _, status := range []string{"started", "passed"} {
+		update, err := watch.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, status, update.GetTestResult().Status)
+	}
+
+	// started updates are not submitted
+	merged := <-ts.ExecutionResultInputChannel
+	assert.Equal(t, "task", merged.TaskID)
+	assert.Len(t, merged.TestPayload, 1)
+}
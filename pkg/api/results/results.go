@@ -20,9 +20,8 @@ func Handler(logger lumber.Logger, ts *teststats.ProcStats) gin.HandlerFunc {
 			return
 		}
 
-		go func() {
-			ts.ExecutionResultInputChannel <- request
-		}()
+		ts.Publish(request)
+		ts.Submit(request)
 		c.Data(http.StatusOK, gin.MIMEPlain, []byte(http.StatusText(http.StatusOK)))
 	}
 }
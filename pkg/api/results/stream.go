@@ -0,0 +1,148 @@
+package results
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// keepAliveInterval is the interval of the comments sent to keep idle streams open through proxies
+	keepAliveInterval = 15 * time.Second
+	// streamBufferSize is the number of events buffered for each stream, the pipeline is never blocked by
+	// the streams and the events are dropped for the streams which are not keeping up
+	streamBufferSize = 256
+)
+
+// testEvent is the data of the `test` events
+type testEvent struct {
+	TaskID   string `json:"taskID"`
+	TestID   string `json:"testID"`
+	SuiteID  string `json:"suiteID"`
+	Title    string `json:"title"`
+	Locator  string `json:"locator"`
+	Status   string `json:"status"`
+	Duration int    `json:"duration"`
+}
+
+// suiteEvent is the data of the `suite` events
+type suiteEvent struct {
+	TaskID    string `json:"taskID"`
+	SuiteID   string `json:"suiteID"`
+	SuiteName string `json:"suiteName"`
+	Status    string `json:"status"`
+	Duration  int    `json:"duration"`
+}
+
+// stageEvent is the data of the `stage` events
+type stageEvent struct {
+	TaskID string `json:"taskID,omitempty"`
+	Stage  string `json:"stage"`
+	// Status is started, passed or failed
+	Status string `json:"status"`
+	// Duration in milliseconds, set once the stage finished
+	Duration int64 `json:"duration,omitempty"`
+}
+
+// StreamHandler streams the test and test suite results as server-sent events as they are
+// received from the runners, along with the stages of the pipeline. The optional taskID query
+// parameter filters the events of a task.
+func StreamHandler(logger lumber.Logger, events *core.EventBus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskID := c.Query("taskID")
+		stream := make(chan *core.Event, streamBufferSize)
+		unsubscribe := events.Subscribe(core.EventSubscriberFunc(func(ctx context.Context, event *core.Event) {
+			select {
+			case stream <- event:
+			default:
+				logger.Debugf("dropped %s event for slow result stream", event.Type)
+			}
+		}), core.EventTestResult, core.EventStageStarted, core.EventStageFinished)
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Header("Content-Type", "text/event-stream")
+		// headers are sent right away, so that clients know the stream is open
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-keepAlive.C:
+				if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+					logger.Debugf("result stream closed: %v", err)
+					return false
+				}
+				return true
+			case event := <-stream:
+				if taskID != "" && eventTaskID(event) != taskID {
+					return true
+				}
+				if event.Type == core.EventTestResult {
+					writeEvents(c, event.Result)
+				} else {
+					writeStageEvent(c, event)
+				}
+				return true
+			}
+		})
+	}
+}
+
+func eventTaskID(event *core.Event) string {
+	if event.Result != nil {
+		return event.Result.TaskID
+	}
+	if event.Task != nil {
+		return event.Task.TaskID
+	}
+	return ""
+}
+
+func writeStageEvent(c *gin.Context, event *core.Event) {
+	data := stageEvent{TaskID: eventTaskID(event), Stage: event.Stage, Status: "started"}
+	if event.Type == core.EventStageFinished {
+		data.Status = "passed"
+		if event.Err != nil {
+			data.Status = "failed"
+		}
+		data.Duration = event.Duration.Milliseconds()
+	}
+	c.SSEvent("stage", data)
+}
+
+func writeEvents(c *gin.Context, result *core.ExecutionResult) {
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		c.SSEvent("test", testEvent{
+			TaskID:   result.TaskID,
+			TestID:   test.TestID,
+			SuiteID:  test.SuiteID,
+			Title:    test.FullTitle,
+			Locator:  test.Filelocator,
+			Status:   test.Status,
+			Duration: test.Duration,
+		})
+	}
+	for i := range result.TestSuitePayload {
+		suite := &result.TestSuitePayload[i]
+		c.SSEvent("suite", suiteEvent{
+			TaskID:    result.TaskID,
+			SuiteID:   suite.SuiteID,
+			SuiteName: suite.SuiteName,
+			Status:    suite.Status,
+			Duration:  suite.Duration,
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package results
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamHandler(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	ts, err := teststats.New(nil, logger)
+	assert.Nil(t, err)
+	events := core.NewEventBus()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/results/stream", StreamHandler(logger, events))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go events.Watch(ctx, ts)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/results/stream?taskID=task", nil)
+	assert.Nil(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// results and stages are published till the handler subscribes, other tasks are filtered
+	go func() {
+		for ctx.Err() == nil {
+			ts.Publish(core.ExecutionResult{TaskID: "other", TestPayload: []core.TestPayload{{TestID: "2", Status: "passed"}}})
+			ts.Publish(core.ExecutionResult{TaskID: "task", TestPayload: []core.TestPayload{{TestID: "1", Status: "failed", Duration: 12}}})
+			events.Emit(ctx, &core.Event{Type: core.EventStageFinished, Task: &core.TaskPayload{TaskID: "other"}, Stage: core.StageClone})
+			events.Emit(ctx, &core.Event{
+				Type:     core.EventStageFinished,
+				Task:     &core.TaskPayload{TaskID: "task"},
+				Stage:    core.StageInstall,
+				Duration: 1500 * time.Millisecond,
+				Err:      errors.New("install failed"),
+			})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	data := make(map[string]string)
+	name := ""
+	for len(data) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			name = strings.TrimPrefix(line, "event:")
+		} else if strings.HasPrefix(line, "data:") {
+			data[name] = line
+		}
+	}
+	assert.Contains(t, data["test"], `"testID":"1"`)
+	assert.Contains(t, data["test"], `"status":"failed"`)
+	assert.Contains(t, data["test"], `"duration":12`)
+	assert.Contains(t, data["stage"], `"taskID":"task"`)
+	assert.Contains(t, data["stage"], `"stage":"install"`)
+	assert.Contains(t, data["stage"], `"status":"failed"`)
+	assert.Contains(t, data["stage"], `"duration":1500`)
+}
@@ -0,0 +1,4 @@
+// Package resultspb contains the protobuf definitions of the gRPC results API.
+package resultspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative results.proto
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: results.proto
+
+package resultspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ResultsClient is the client API for Results service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ResultsClient interface {
+	// SubmitResults submits the results of a runner process, same as POST /results.
+	SubmitResults(ctx context.Context, in *ExecutionResult, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// StreamResults submits the results of a runner process as the tests complete,
+	// the messages are merged and submitted as a single result when the stream is closed.
+	// Tests with the status `started` are only sent to the watchers.
+	StreamResults(ctx context.Context, opts ...grpc.CallOption) (Results_StreamResultsClient, error)
+	// WatchResults streams the results received from the runners.
+	WatchResults(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Results_WatchResultsClient, error)
+}
+
+type resultsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewResultsClient(cc grpc.ClientConnInterface) ResultsClient {
+	return &resultsClient{cc}
+}
+
+func (c *resultsClient) SubmitResults(ctx context.Context, in *ExecutionResult, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, "/tas.results.v1.Results/SubmitResults", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resultsClient) StreamResults(ctx context.Context, opts ...grpc.CallOption) (Results_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Results_ServiceDesc.Streams[0], "/tas.results.v1.Results/StreamResults", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &resultsStreamResultsClient{stream}
+	return x, nil
+}
+
+type Results_StreamResultsClient interface {
+	Send(*ExecutionResult) error
+	CloseAndRecv() (*SubmitResponse, error)
+	grpc.ClientStream
+}
+
+type resultsStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *resultsStreamResultsClient) Send(m *ExecutionResult) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *resultsStreamResultsClient) CloseAndRecv() (*SubmitResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SubmitResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *resultsClient) WatchResults(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Results_WatchResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Results_ServiceDesc.Streams[1], "/tas.results.v1.Results/WatchResults", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &resultsWatchResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Results_WatchResultsClient interface {
+	Recv() (*ResultUpdate, error)
+	grpc.ClientStream
+}
+
+type resultsWatchResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *resultsWatchResultsClient) Recv() (*ResultUpdate, error) {
+	m := new(ResultUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ResultsServer is the server API for Results service.
+// All implementations must embed UnimplementedResultsServer
+// for forward compatibility
+type ResultsServer interface {
+	// SubmitResults submits the results of a runner process, same as POST /results.
+	SubmitResults(context.Context, *ExecutionResult) (*SubmitResponse, error)
+	// StreamResults submits the results of a runner process as the tests complete,
+	// the messages are merged and submitted as a single result when the stream is closed.
+	// Tests with the status `started` are only sent to the watchers.
+	StreamResults(Results_StreamResultsServer) error
+	// WatchResults streams the results received from the runners.
+	WatchResults(*WatchRequest, Results_WatchResultsServer) error
+	mustEmbedUnimplementedResultsServer()
+}
+
+// UnimplementedResultsServer must be embedded to have forward compatible implementations.
+type UnimplementedResultsServer struct {
+}
+
+func (UnimplementedResultsServer) SubmitResults(context.Context, *ExecutionResult) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitResults not implemented")
+}
+func (UnimplementedResultsServer) StreamResults(Results_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedResultsServer) WatchResults(*WatchRequest, Results_WatchResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchResults not implemented")
+}
+func (UnimplementedResultsServer) mustEmbedUnimplementedResultsServer() {}
+
+// UnsafeResultsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ResultsServer will
+// result in compilation errors.
+type UnsafeResultsServer interface {
+	mustEmbedUnimplementedResultsServer()
+}
+
+func RegisterResultsServer(s grpc.ServiceRegistrar, srv ResultsServer) {
+	s.RegisterService(&Results_ServiceDesc, srv)
+}
+
+func _Results_SubmitResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecutionResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResultsServer).SubmitResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tas.results.v1.Results/SubmitResults",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResultsServer).SubmitResults(ctx, req.(*ExecutionResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Results_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ResultsServer).StreamResults(&resultsStreamResultsServer{stream})
+}
+
+type Results_StreamResultsServer interface {
+	SendAndClose(*SubmitResponse) error
+	Recv() (*ExecutionResult, error)
+	grpc.ServerStream
+}
+
+type resultsStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *resultsStreamResultsServer) SendAndClose(m *SubmitResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *resultsStreamResultsServer) Recv() (*ExecutionResult, error) {
+	m := new(ExecutionResult)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Results_WatchResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ResultsServer).WatchResults(m, &resultsWatchResultsServer{stream})
+}
+
+type Results_WatchResultsServer interface {
+	Send(*ResultUpdate) error
+	grpc.ServerStream
+}
+
+type resultsWatchResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *resultsWatchResultsServer) Send(m *ResultUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Results_ServiceDesc is the grpc.ServiceDesc for Results service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Results_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tas.results.v1.Results",
+	HandlerType: (*ResultsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitResults",
+			Handler:    _Results_SubmitResults_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _Results_StreamResults_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchResults",
+			Handler:       _Results_WatchResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "results.proto",
+}
@@ -1,10 +1,20 @@
 package api
 
 import (
+	"github.com/LambdaTest/synapse/pkg/api/blocklist"
 	"github.com/LambdaTest/synapse/pkg/api/health"
+	"github.com/LambdaTest/synapse/pkg/api/history"
+	"github.com/LambdaTest/synapse/pkg/api/impacted"
+	"github.com/LambdaTest/synapse/pkg/api/metrics"
 	"github.com/LambdaTest/synapse/pkg/api/results"
+	"github.com/LambdaTest/synapse/pkg/api/testlist"
+	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/dryrun"
+	"github.com/LambdaTest/synapse/pkg/service/taskstatus"
 	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"github.com/LambdaTest/synapse/pkg/testblocklistservice"
+	"github.com/LambdaTest/synapse/pkg/testexecutionservice"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,13 +22,42 @@ import (
 type Router struct {
 	logger           lumber.Logger
 	testStatsService *teststats.ProcStats
+	dryRunReporter   *dryrun.Reporter
+	blocklistService *testblocklistservice.TestBlockListService
+	impactService    core.ImpactService
+	resultStore      core.ResultStore
+	discoveryCache   core.DiscoveryCache
+	statusTracker    *taskstatus.Tracker
+	events           *core.EventBus
+	pipelineMetrics  *metrics.Pipeline
+	// coverageReportDir is served under /coverage/report if set
+	coverageReportDir string
 }
 
 // NewRouter returns instance of Router
-func NewRouter(logger lumber.Logger, ts *teststats.ProcStats) Router {
+func NewRouter(logger lumber.Logger,
+	ts *teststats.ProcStats,
+	dr *dryrun.Reporter,
+	tbs *testblocklistservice.TestBlockListService,
+	is core.ImpactService,
+	rs core.ResultStore,
+	dc core.DiscoveryCache,
+	st *taskstatus.Tracker,
+	events *core.EventBus,
+	pm *metrics.Pipeline,
+	coverageReportDir string) Router {
 	return Router{
-		logger:           logger,
-		testStatsService: ts,
+		logger:            logger,
+		testStatsService:  ts,
+		dryRunReporter:    dr,
+		blocklistService:  tbs,
+		impactService:     is,
+		resultStore:       rs,
+		discoveryCache:    dc,
+		statusTracker:     st,
+		events:            events,
+		pipelineMetrics:   pm,
+		coverageReportDir: coverageReportDir,
 	}
 }
 
@@ -32,7 +71,30 @@ func (r Router) Handler() *gin.Engine {
 	// corsConfig.AddAllowHeaders("authorization", "cache-control", "pragma")
 	// router.Use(cors.New(corsConfig))
 	router.GET("/health", health.Handler)
+	router.GET("/healthz", health.LivenessHandler(r.statusTracker))
+	router.GET("/readyz", health.ReadinessHandler(r.statusTracker))
+	router.GET("/task/status", health.StatusHandler(r.statusTracker))
+	router.GET("/metrics/http", metrics.HTTPHandler)
+	router.GET("/metrics/pipeline", metrics.PipelineHandler(r.pipelineMetrics))
 	router.POST("/results", results.Handler(r.logger, r.testStatsService))
+	router.GET("/results/stream", results.StreamHandler(r.logger, r.events))
+	// the console output of each test file is browsed at /results/logs/<path> where path is the path of the
+	// file log of the execution result
+	router.Static("/results/logs", testexecutionservice.FileLogsDir())
+	router.POST("/test-list", testlist.Handler(r.logger, r.dryRunReporter, r.discoveryCache))
+	router.GET("/blocklist", blocklist.ListHandler(r.blocklistService))
+	router.POST("/blocklist", blocklist.AddHandler(r.logger, r.blocklistService))
+	router.DELETE("/blocklist", blocklist.RemoveHandler(r.logger, r.blocklistService))
+	router.GET("/impacted-tests", impacted.Handler(r.logger, r.impactService))
+	router.GET("/history/runs", history.RunsHandler(r.logger, r.resultStore))
+	router.GET("/history/tests", history.TestHandler(r.logger, r.resultStore))
+	router.GET("/history/failed", history.FailedHandler(r.logger, r.resultStore))
+	router.GET("/history/flaky", history.FlakyHandler(r.logger, r.resultStore))
+	router.GET("/history/coverage", history.CoverageHandler(r.logger, r.resultStore))
+	if r.coverageReportDir != "" {
+		// the html reports are browsed at /coverage/report/<org>/<repo>/<commit>/index.html
+		router.Static("/coverage/report", r.coverageReportDir)
+	}
 
 	return router
 
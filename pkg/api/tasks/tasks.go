@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/batch"
+	"github.com/LambdaTest/synapse/pkg/daemon"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitHandler queues a task on the resident nucleus
+func SubmitHandler(logger lumber.Logger, d *daemon.Daemon) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := batch.SubTask{}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Errorf("error while binding json %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		task, err := d.Submit(request)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, daemon.ErrDuplicateTask):
+				status = http.StatusConflict
+			case errors.Is(err, daemon.ErrQueueFull):
+				status = http.StatusServiceUnavailable
+			}
+			c.JSON(status, gin.H{"message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, task)
+	}
+}
+
+// GetHandler returns the state of a task submitted to the resident nucleus
+func GetHandler(d *daemon.Daemon) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		task, ok := d.Task(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"message": "task not found"})
+			return
+		}
+		c.JSON(http.StatusOK, task)
+	}
+}
@@ -0,0 +1,26 @@
+package testlist
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/dryrun"
+	"github.com/gin-gonic/gin"
+)
+
+//Handler captures the test list discovered by the runners in dry run mode or if the discovery is cached
+func Handler(logger lumber.Logger, dr *dryrun.Reporter, dc core.DiscoveryCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request json.RawMessage
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Errorf("error while binding json %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		dr.AddDiscoveryResult(request)
+		dc.Record(request)
+		c.Data(http.StatusOK, gin.MIMEPlain, []byte(http.StatusText(http.StatusOK)))
+	}
+}
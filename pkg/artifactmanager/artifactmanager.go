@@ -0,0 +1,200 @@
+package artifactmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/utils"
+)
+
+const (
+	archiveMimeType = "application/octet-stream"
+	// taskArchiveName is the archive of the artifacts which are not linked to a test
+	taskArchiveName = "task"
+	archiveExt      = ".tzst"
+)
+
+// errSizeLimit is returned if the archive would exceed the maximum size of the artifacts of the task
+var errSizeLimit = errors.New("the artifacts exceed the size limit of the task")
+
+type manager struct {
+	azureClient core.AzureClient
+	compressor  core.Compressor
+	logger      lumber.Logger
+	repoDir     string
+	// maxSize in bytes of the archives uploaded by a task, unlimited if 0
+	maxSize int64
+}
+
+// New returns a new ArtifactManager for the repository cloned at global.RepoDir
+func New(cfg *config.NucleusConfig, azureClient core.AzureClient, compressor core.Compressor, logger lumber.Logger) core.ArtifactManager {
+	return &manager{azureClient: azureClient, compressor: compressor, logger: logger, repoDir: global.RepoDir,
+		maxSize: cfg.Limits.MaxArtifactSize << 20}
+}
+
+// Upload uploads an archive per test with the artifacts linked to it and an archive with the remaining artifacts.
+// Artifacts are only uploaded if a test failed, unless they are configured to be always uploaded. The archives
+// which would exceed the maximum size of the artifacts of the task are not uploaded.
+func (m *manager) Upload(ctx context.Context, payload *core.Payload, artifacts *core.Artifacts, result *core.ExecutionResult) error {
+	if artifacts == nil || len(artifacts.Paths) == 0 {
+		return nil
+	}
+	tests := make([]*core.TestPayload, 0, len(result.TestPayload))
+	for i := range result.TestPayload {
+		status := result.TestPayload[i].Status
+		if artifacts.When == core.ArtifactsAlways || status == core.TestFailed || status == core.TestTimedOut {
+			tests = append(tests, &result.TestPayload[i])
+		}
+	}
+	if artifacts.When != core.ArtifactsAlways && len(tests) == 0 {
+		m.logger.Debugf("no failed tests, skipping artifacts upload")
+		return nil
+	}
+
+	files := make(map[string]bool)
+	for _, pattern := range artifacts.Paths {
+		matches, err := utils.Glob(m.repoDir, pattern)
+		if err != nil {
+			return fmt.Errorf("failed to find artifacts matching %s: %v", pattern, err)
+		}
+		for _, file := range matches {
+			files[file] = true
+		}
+	}
+	if len(files) == 0 {
+		m.logger.Infof("no artifacts found matching %v", artifacts.Paths)
+		return nil
+	}
+	sorted := make([]string, 0, len(files))
+	for file := range files {
+		sorted = append(sorted, file)
+	}
+	sort.Strings(sorted)
+
+	linked, unlinked := link(sorted, tests)
+	prefix := fmt.Sprintf("artifacts/%s/%s/%s", payload.OrgID, payload.RepoID, payload.TaskID)
+	var uploaded int64
+	skipped := 0
+	for _, test := range tests {
+		if len(linked[test]) == 0 {
+			continue
+		}
+		blobPath, err := m.upload(ctx, path.Join(prefix, test.TestID+archiveExt), linked[test], &uploaded)
+		if errors.Is(err, errSizeLimit) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		test.Artifact = blobPath
+	}
+	if len(unlinked) > 0 {
+		blobPath, err := m.upload(ctx, path.Join(prefix, taskArchiveName+archiveExt), unlinked, &uploaded)
+		if errors.Is(err, errSizeLimit) {
+			skipped++
+		} else if err != nil {
+			return err
+		} else {
+			result.Artifacts = blobPath
+		}
+	}
+	if skipped > 0 {
+		m.logger.Warnf("%d artifact archives are not uploaded as they exceed the limit of %d MiB", skipped, m.maxSize>>20)
+		result.LimitsExceeded = append(result.LimitsExceeded, core.LimitMaxArtifactSize)
+	}
+	m.logger.Infof("uploaded %d artifacts to %s", len(sorted), prefix)
+	return nil
+}
+
+// upload compresses the files and uploads the archive, uploaded is the size of the archives uploaded by the task
+func (m *manager) upload(ctx context.Context, blobPath string, files []string, uploaded *int64) (string, error) {
+	archivePath := filepath.Join(global.ScratchDir, "artifacts-"+path.Base(blobPath))
+	defer os.Remove(archivePath)
+	if err := m.compressor.Compress(ctx, archivePath, true, m.repoDir, files...); err != nil {
+		return "", err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if m.maxSize > 0 && *uploaded+info.Size() > m.maxSize {
+		return "", errSizeLimit
+	}
+	*uploaded += info.Size()
+	sasURL, err := m.azureClient.GetSASURL(ctx, blobPath, core.ArtifactsContainer)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.azureClient.CreateUsingSASURL(ctx, sasURL, f, archiveMimeType); err != nil {
+		return "", err
+	}
+	return blobPath, nil
+}
+
+// link returns the artifacts of the tests and the artifacts not linked to any test. An artifact is linked to
+// the tests whose title its path contains, e.g. cypress/screenshots/login.spec.js/login -- fails (failed).png,
+// preferring the tests of the file it contains. Otherwise it is linked to all tests of the file its path
+// contains, e.g. cypress/videos/login.spec.js.mp4.
+func link(files []string, tests []*core.TestPayload) (map[*core.TestPayload][]string, []string) {
+	linked := make(map[*core.TestPayload][]string)
+	unlinked := make([]string, 0)
+	for _, file := range files {
+		name := normalize(file)
+		var byTitle, byFile, both []*core.TestPayload
+		for _, test := range tests {
+			titleMatch := test.Title != "" && strings.Contains(name, normalize(test.Title))
+			fileMatch := test.FilePath != "" && strings.Contains(name, normalize(path.Base(filepath.ToSlash(test.FilePath))))
+			if titleMatch {
+				byTitle = append(byTitle, test)
+			}
+			if fileMatch {
+				byFile = append(byFile, test)
+			}
+			if titleMatch && fileMatch {
+				both = append(both, test)
+			}
+		}
+		matched := both
+		if len(matched) == 0 {
+			matched = byTitle
+		}
+		if len(matched) == 0 {
+			matched = byFile
+		}
+		if len(matched) == 0 {
+			unlinked = append(unlinked, file)
+		}
+		for _, test := range matched {
+			linked[test] = append(linked[test], file)
+		}
+	}
+	return linked, unlinked
+}
+
+// normalize lower cases the letters and digits of s and removes the other characters,
+// as the test runners replace the special characters of the titles differently in file names
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,81 @@
+package artifactmanager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/compression"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLink(t *testing.T) {
+	fails := &core.TestPayload{Title: "shows an error", FilePath: "cypress/e2e/login.spec.js"}
+	passes := &core.TestPayload{Title: "logs in", FilePath: "cypress/e2e/login.spec.js"}
+	other := &core.TestPayload{Title: "shows an error", FilePath: "cypress/e2e/signup.spec.js"}
+	linked, unlinked := link([]string{
+		"cypress/screenshots/login.spec.js/Login -- shows an error (failed).png",
+		"cypress/videos/login.spec.js.mp4",
+		"logs/server.log",
+	}, []*core.TestPayload{fails, passes, other})
+	assert.Equal(t, []string{"cypress/screenshots/login.spec.js/Login -- shows an error (failed).png", "cypress/videos/login.spec.js.mp4"}, linked[fails])
+	assert.Equal(t, []string{"cypress/videos/login.spec.js.mp4"}, linked[passes])
+	assert.Empty(t, linked[other])
+	assert.Equal(t, []string{"logs/server.log"}, unlinked)
+}
+
+func TestUpload(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	blobDir := t.TempDir()
+	store, err := storage.NewLocalStore(blobDir, "artifacts", logger)
+	assert.Nil(t, err)
+	compressor, err := compression.New(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	repoDir := t.TempDir()
+	screenshot := filepath.Join(repoDir, "cypress", "screenshots", "a.spec.js", "fails (failed).png")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(screenshot), 0755))
+	assert.Nil(t, ioutil.WriteFile(screenshot, []byte("png"), 0644))
+
+	m := &manager{azureClient: store, compressor: compressor, logger: logger, repoDir: repoDir}
+	payload := &core.Payload{OrgID: "org", RepoID: "repo", TaskID: "task"}
+	artifacts := &core.Artifacts{Paths: []string{"cypress/screenshots/**"}}
+	result := &core.ExecutionResult{TestPayload: []core.TestPayload{
+		{TestID: "1", Title: "fails", FilePath: "a.spec.js", Status: "passed"},
+	}}
+	// artifacts are only uploaded on failure by default
+	assert.Nil(t, m.Upload(context.Background(), payload, artifacts, result))
+	assert.Empty(t, result.TestPayload[0].Artifact)
+
+	result.TestPayload[0].Status = "failed"
+	assert.Nil(t, m.Upload(context.Background(), payload, artifacts, result))
+	assert.Equal(t, "artifacts/org/repo/task/1.tzst", result.TestPayload[0].Artifact)
+	assert.Empty(t, result.Artifacts)
+	_, err = os.Stat(filepath.Join(blobDir, "artifacts", "artifacts", "org", "repo", "task", "1.tzst"))
+	assert.Nil(t, err)
+}
+
+func TestUploadSizeLimit(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	store, err := storage.NewLocalStore(t.TempDir(), "artifacts", logger)
+	assert.Nil(t, err)
+	compressor, err := compression.New(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	repoDir := t.TempDir()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(repoDir, "server.log"), []byte("log"), 0644))
+
+	m := &manager{azureClient: store, compressor: compressor, logger: logger, repoDir: repoDir, maxSize: 1}
+	payload := &core.Payload{OrgID: "org", RepoID: "repo", TaskID: "task"}
+	artifacts := &core.Artifacts{Paths: []string{"*.log"}, When: core.ArtifactsAlways}
+	result := &core.ExecutionResult{}
+	assert.Nil(t, m.Upload(context.Background(), payload, artifacts, result))
+	assert.Empty(t, result.Artifacts)
+	assert.Equal(t, []string{core.LimitMaxArtifactSize}, result.LimitsExceeded)
+}
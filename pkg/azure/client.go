@@ -18,11 +18,12 @@ import (
 	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/tracing"
 )
 
 var (
-	defaultBufferSize    = 3 * 1024 * 1024
-	defaultMaxBuffers    = 4
+	defaultBufferSize    = 4 * 1024 * 1024
 	defaultContainerName = "cache"
 )
 
@@ -34,6 +35,7 @@ type Store struct {
 	containerURL       *azblob.ContainerURL
 	azurePipeLine      *pipeline.Pipeline
 	httpClient         http.Client
+	transfer           *transfer
 	logger             lumber.Logger
 }
 
@@ -55,9 +57,8 @@ func NewAzureBlobEnv(cfg *config.NucleusConfig, logger lumber.Logger) (core.Azur
 		return &Store{
 			logger:        logger,
 			containerName: defaultContainerName,
-			httpClient: http.Client{
-				Timeout: global.DefaultHTTPTimeout,
-			},
+			httpClient:    requestutils.NewResilientClient(global.DefaultHTTPTimeout),
+			transfer:      newTransfer(cfg.Transfer),
 		}, nil
 	}
 	// FIXME: Hack for synapse
@@ -73,7 +74,7 @@ func NewAzureBlobEnv(cfg *config.NucleusConfig, logger lumber.Logger) (core.Azur
 		return nil, err
 	}
 
-	p := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	p := azblob.NewPipeline(credential, pipelineOptions())
 	URL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", cfg.Azure.StorageAccountName, cfg.Azure.ContainerName))
 	if err != nil {
 		return nil, err
@@ -85,22 +86,34 @@ func NewAzureBlobEnv(cfg *config.NucleusConfig, logger lumber.Logger) (core.Azur
 		storageAccessKey:   cfg.Azure.StorageAccessKey,
 		containerURL:       &containerURL,
 		azurePipeLine:      &p,
+		transfer:           newTransfer(cfg.Transfer),
 	}, nil
 }
 
+// pipelineOptions returns the options of the azure pipelines, which send the requests with the shared resilient
+// http client. The client retries the transient failures, so the retry policy of the pipeline makes a single try.
+func pipelineOptions() azblob.PipelineOptions {
+	client := requestutils.NewResilientClient(0)
+	sender := pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			resp, err := client.Do(request.WithContext(ctx))
+			if err != nil {
+				err = pipeline.NewError(err, "HTTP request failed")
+			}
+			return pipeline.NewHTTPResponse(resp), err
+		}
+	})
+	return azblob.PipelineOptions{HTTPSender: sender, Retry: azblob.RetryOptions{MaxTries: 1}}
+}
+
 // FindUsingSASUrl download object based on sasURL
 func (s *Store) FindUsingSASUrl(ctx context.Context, sasURL string) (io.ReadCloser, error) {
 	u, err := url.Parse(sasURL)
 	if err != nil {
 		return nil, err
 	}
-	blobURL := azblob.NewBlobURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{}))
-
-	out, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
-	if err != nil {
-		return nil, handleError(err)
-	}
-	return out.Body(azblob.RetryReaderOptions{MaxRetryRequests: 5}), nil
+	blobURL := azblob.NewBlobURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), pipelineOptions()))
+	return s.transfer.download(ctx, blobURL)
 }
 
 // CreateUsingSASURL creates object using sasURL
@@ -109,34 +122,22 @@ func (s *Store) CreateUsingSASURL(ctx context.Context, sasURL string, reader io.
 	if err != nil {
 		return "", err
 	}
-	blobURL := azblob.NewBlockBlobURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{}))
-	_, err = azblob.UploadStreamToBlockBlob(ctx, reader, blobURL, azblob.UploadStreamToBlockBlobOptions{
-		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: mimeType},
-		BufferSize:      defaultBufferSize,
-		MaxBuffers:      defaultMaxBuffers,
-	})
+	blobURL := azblob.NewBlockBlobURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), pipelineOptions()))
+	err = s.transfer.upload(ctx, blobURL, reader, mimeType)
 
 	return blobURL.String(), err
 }
 
 // Find function downloads blob based on URI
 func (s *Store) Find(ctx context.Context, path string) (io.ReadCloser, error) {
-	blobURL := s.containerURL.NewBlockBlobURL(path)
-	out, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
-	if err != nil {
-		return nil, handleError(err)
-	}
-	return out.Body(azblob.RetryReaderOptions{MaxRetryRequests: 5}), nil
+	blobURL := s.containerURL.NewBlobURL(path)
+	return s.transfer.download(ctx, blobURL)
 }
 
 // Create function ulploads blob to URI
 func (s *Store) Create(ctx context.Context, path string, reader io.Reader, mimeType string) (string, error) {
 	blobURL := s.containerURL.NewBlockBlobURL(path)
-	_, err := azblob.UploadStreamToBlockBlob(ctx, reader, blobURL, azblob.UploadStreamToBlockBlobOptions{
-		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: mimeType},
-		BufferSize:      defaultBufferSize,
-		MaxBuffers:      defaultMaxBuffers,
-	})
+	err := s.transfer.upload(ctx, blobURL, reader, mimeType)
 
 	return blobURL.String(), err
 }
@@ -152,11 +153,12 @@ func (s *Store) GetSASURL(ctx context.Context, containerPath string, containerTy
 		s.logger.Errorf("failed to marshal request body %v", err)
 		return "", err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", global.NeuronHost, "internal/sas-token"), bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", global.NeuronHost(), "internal/sas-token"), bytes.NewBuffer(reqBody))
 	if err != nil {
 		s.logger.Errorf("error while creating http request, error %v", err)
 		return "", err
 	}
+	tracing.InjectHeaders(ctx, req.Header)
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		s.logger.Errorf("error while getting SAS URL, error %v", err)
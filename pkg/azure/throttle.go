@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// limiter caps the bandwidth shared by concurrent transfers with a token bucket holding up to a second of
+// transfer. The bytes transferred beyond the bucket are a debt, which delays the next transfers.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter of bytesPerSecond, or nil if the bandwidth is not capped
+func newLimiter(bytesPerSecond int64) *limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &limiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// wait takes n bytes from the bucket, it blocks until the bytes are paid back or ctx is done
+func (l *limiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reader returns r throttled by the limiter
+func (l *limiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, l: l}
+}
+
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *limiter
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	// a read is limited to a second of transfer, so that the transfers are evenly paced
+	if max := int(tr.l.rate); len(p) > max {
+		p = p[:max]
+	}
+	n, err := tr.r.Read(p)
+	if waitErr := tr.l.wait(tr.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// throttledReadCloser closes the throttled body
+type throttledReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,263 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/LambdaTest/synapse/config"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultParallelism = 4
+	mib                = 1024 * 1024
+	kib                = 1024
+	maxRetryRequests   = 5
+)
+
+// transfer uploads and downloads the blobs in blocks transferred concurrently, the bandwidth caps are shared by
+// all the transfers of the store
+type transfer struct {
+	blockSize     int64
+	parallelism   int
+	uploadLimit   *limiter
+	downloadLimit *limiter
+}
+
+func newTransfer(cfg config.Transfer) *transfer {
+	t := &transfer{
+		blockSize:     int64(cfg.BlockSize) * mib,
+		parallelism:   cfg.Parallelism,
+		uploadLimit:   newLimiter(int64(cfg.UploadRateLimit) * kib),
+		downloadLimit: newLimiter(int64(cfg.DownloadRateLimit) * kib),
+	}
+	if t.blockSize <= 0 {
+		t.blockSize = int64(defaultBufferSize)
+	}
+	if t.parallelism <= 0 {
+		t.parallelism = defaultParallelism
+	}
+	return t
+}
+
+// upload uploads the reader to the block blob. A file larger than a block is uploaded resumably, other readers
+// are streamed.
+func (t *transfer) upload(ctx context.Context, blobURL azblob.BlockBlobURL, reader io.Reader, mimeType string) error {
+	if f, ok := reader.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		// the file is streamed from its current offset if it was partially read
+		if offset, err := f.Seek(0, io.SeekCurrent); err == nil && offset == 0 &&
+			info.Mode().IsRegular() && info.Size() > t.blockSize {
+			return t.uploadFile(ctx, blobURL, f, info.Size(), mimeType)
+		}
+	}
+	_, err := azblob.UploadStreamToBlockBlob(ctx, t.uploadLimit.reader(ctx, reader), blobURL, azblob.UploadStreamToBlockBlobOptions{
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: mimeType},
+		BufferSize:      int(t.blockSize),
+		MaxBuffers:      t.parallelism,
+	})
+	return err
+}
+
+// uploadFile stages the blocks of the file concurrently then commits them. The ids of the blocks are derived from
+// their index and content, the blocks staged by an interrupted upload of the same file are kept uncommitted by
+// azure for a week and are not uploaded again.
+func (t *transfer) uploadFile(ctx context.Context, blobURL azblob.BlockBlobURL, f *os.File, size int64, mimeType string) error {
+	staged := make(map[string]bool)
+	// the blob does not exist before the first upload, the blocks are all uploaded if they can't be listed
+	if list, err := blobURL.GetBlockList(ctx, azblob.BlockListUncommitted, azblob.LeaseAccessConditions{}); err == nil {
+		for _, block := range list.UncommittedBlocks {
+			staged[block.Name] = true
+		}
+	}
+
+	count := int((size + t.blockSize - 1) / t.blockSize)
+	ids := make([]string, count)
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, t.parallelism)
+	for i := 0; i < count; i++ {
+		i := i
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			if err := g.Wait(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			offset := int64(i) * t.blockSize
+			length := t.blockSize
+			if size-offset < length {
+				length = size - offset
+			}
+			data := make([]byte, length)
+			if _, err := f.ReadAt(data, offset); err != nil {
+				return err
+			}
+			ids[i] = blockID(i, data)
+			if staged[ids[i]] {
+				return nil
+			}
+			if err := t.uploadLimit.wait(gctx, len(data)); err != nil {
+				return err
+			}
+			_, err := blobURL.StageBlock(gctx, ids[i], bytes.NewReader(data), azblob.LeaseAccessConditions{}, nil, azblob.ClientProvidedKeyOptions{})
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	_, err := blobURL.CommitBlockList(ctx, ids, azblob.BlobHTTPHeaders{ContentType: mimeType}, azblob.Metadata{},
+		azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil, azblob.ClientProvidedKeyOptions{})
+	return err
+}
+
+// blockID returns the base64 id of the block, the ids of the blocks of a blob must have the same length
+func blockID(index int, data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%06d-%x", index, sum[:12])))
+}
+
+// download returns the body of the blob. A blob larger than a block is downloaded in blocks fetched concurrently
+// ahead of the reader, each block is resumed from where it failed if its connection breaks.
+func (t *transfer) download(ctx context.Context, blobURL azblob.BlobURL) (io.ReadCloser, error) {
+	count := int64(azblob.CountToEnd)
+	if t.parallelism > 1 {
+		count = t.blockSize
+	}
+	resp, err := blobURL.Download(ctx, 0, count, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		var serr azblob.StorageError
+		// the range of an empty blob is invalid
+		if count == azblob.CountToEnd || !errors.As(err, &serr) || serr.ServiceCode() != azblob.ServiceCodeInvalidRange {
+			return nil, handleError(err)
+		}
+		if resp, err = blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{}); err != nil {
+			return nil, handleError(err)
+		}
+	}
+	body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: maxRetryRequests})
+	size, ok := blobSize(resp.ContentRange())
+	if !ok || size <= t.blockSize {
+		return throttledReadCloser{Reader: t.downloadLimit.reader(ctx, body), Closer: body}, nil
+	}
+	return t.newBlockReader(ctx, blobURL, resp.ETag(), body, size), nil
+}
+
+// blobSize returns the size of the blob from the content range of a ranged download, e.g. bytes 0-1023/4096
+func blobSize(contentRange string) (int64, bool) {
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	return size, err == nil
+}
+
+type block struct {
+	data []byte
+	err  error
+}
+
+// blockReader reads the blocks of a blob in order, while the next blocks are fetched concurrently
+type blockReader struct {
+	cancel  context.CancelFunc
+	blocks  chan chan block
+	current *bytes.Reader
+	err     error
+}
+
+func (t *transfer) newBlockReader(ctx context.Context, blobURL azblob.BlobURL, etag azblob.ETag, first io.ReadCloser, size int64) *blockReader {
+	ctx, cancel := context.WithCancel(ctx)
+	br := &blockReader{cancel: cancel, blocks: make(chan chan block, t.parallelism-1)}
+	// the blocks are fetched from the same version of the blob as the first block
+	ac := azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfMatch: etag}}
+	go func() {
+		defer close(br.blocks)
+		for offset := int64(0); offset < size; offset += t.blockSize {
+			offset := offset
+			length := t.blockSize
+			if size-offset < length {
+				length = size - offset
+			}
+			result := make(chan block, 1)
+			select {
+			case br.blocks <- result:
+			case <-ctx.Done():
+				if offset == 0 {
+					first.Close()
+				}
+				return
+			}
+			go func() {
+				var b block
+				if offset == 0 {
+					b.data, b.err = t.readBlock(ctx, first, length)
+				} else {
+					b.data, b.err = t.fetchBlock(ctx, blobURL, ac, offset, length)
+				}
+				result <- b
+			}()
+		}
+	}()
+	return br
+}
+
+// fetchBlock downloads the block of the blob at offset
+func (t *transfer) fetchBlock(ctx context.Context, blobURL azblob.BlobURL, ac azblob.BlobAccessConditions, offset, length int64) ([]byte, error) {
+	resp, err := blobURL.Download(ctx, offset, length, ac, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, handleError(err)
+	}
+	return t.readBlock(ctx, resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: maxRetryRequests}), length)
+}
+
+func (t *transfer) readBlock(ctx context.Context, body io.ReadCloser, length int64) ([]byte, error) {
+	defer body.Close()
+	data := make([]byte, length)
+	if _, err := io.ReadFull(t.downloadLimit.reader(ctx, body), data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (br *blockReader) Read(p []byte) (int, error) {
+	for br.err == nil {
+		if br.current != nil && br.current.Len() > 0 {
+			return br.current.Read(p)
+		}
+		result, ok := <-br.blocks
+		if !ok {
+			br.err = io.EOF
+			break
+		}
+		b := <-result
+		if b.err != nil {
+			br.err = b.err
+			break
+		}
+		br.current = bytes.NewReader(b.data)
+	}
+	return 0, br.err
+}
+
+// Close stops fetching the blocks
+func (br *blockReader) Close() error {
+	br.cancel()
+	return nil
+}
@@ -0,0 +1,185 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/stretchr/testify/assert"
+)
+
+// blobServer serves a single block blob, with the ranged downloads and the staged blocks of the blob service
+type blobServer struct {
+	mu      sync.Mutex
+	content []byte
+	blocks  map[string][]byte
+	staged  int
+}
+
+type blockList struct {
+	Latest []string `xml:"Latest"`
+}
+
+func (s *blobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && query.Get("comp") == "blocklist":
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><BlockList><UncommittedBlocks>`)
+		for id, data := range s.blocks {
+			fmt.Fprintf(w, "<Block><Name>%s</Name><Size>%d</Size></Block>", id, len(data))
+		}
+		fmt.Fprint(w, `</UncommittedBlocks></BlockList>`)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		data, _ := ioutil.ReadAll(r.Body)
+		s.blocks[query.Get("blockid")] = data
+		s.staged++
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list blockList
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.content = nil
+		for _, id := range list.Latest {
+			s.content = append(s.content, s.blocks[id]...)
+		}
+		s.blocks = make(map[string][]byte)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet:
+		w.Header().Set("ETag", `"v1"`)
+		if match := r.Header.Get("If-Match"); match != "" && match != `"v1"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("x-ms-range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.WriteHeader(http.StatusOK)
+			w.Write(s.content) // nolint:errcheck
+			return
+		}
+		if start >= len(s.content) {
+			w.Header().Set("x-ms-error-code", string(azblob.ServiceCodeInvalidRange))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if end >= len(s.content) {
+			end = len(s.content) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.content)))
+		w.Header().Set("Content-Length", fmt.Sprint(end-start+1))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(s.content[start : end+1]) // nolint:errcheck
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newBlobServer(t *testing.T, content []byte) (*blobServer, azblob.BlockBlobURL) {
+	s := &blobServer{content: content, blocks: make(map[string][]byte)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL + "/cache/archive.tzst")
+	assert.NoError(t, err)
+	return s, azblob.NewBlockBlobURL(*u, azblob.NewPipeline(azblob.NewAnonymousCredential(), pipelineOptions()))
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data) // nolint:errcheck
+	return data
+}
+
+func TestDownload(t *testing.T) {
+	tr := &transfer{blockSize: 1024, parallelism: 3}
+	for _, size := range []int{0, 100, 1024, 10*1024 + 123} {
+		content := randomBytes(size)
+		_, blobURL := newBlobServer(t, content)
+		body, err := tr.download(context.Background(), blobURL.BlobURL)
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		assert.NoError(t, body.Close())
+		assert.Equal(t, content, data, "size %d", size)
+	}
+}
+
+func TestDownloadChanged(t *testing.T) {
+	tr := &transfer{blockSize: 1024, parallelism: 2}
+	s, blobURL := newBlobServer(t, randomBytes(4096))
+	body := tr.newBlockReader(context.Background(), blobURL.BlobURL, `"v0"`, ioutil.NopCloser(bytes.NewReader(s.content[:1024])), 4096)
+	_, err := ioutil.ReadAll(body)
+	assert.Error(t, err, "the blocks of another version of the blob are not mixed")
+	assert.NoError(t, body.Close())
+}
+
+func TestUploadFileResumes(t *testing.T) {
+	tr := &transfer{blockSize: 1024, parallelism: 2}
+	content := randomBytes(5*1024 + 10)
+	path := filepath.Join(t.TempDir(), "archive.tzst")
+	assert.NoError(t, ioutil.WriteFile(path, content, 0644))
+
+	s, blobURL := newBlobServer(t, nil)
+	// the first blocks were staged by an interrupted upload
+	s.blocks[blockID(0, content[:1024])] = content[:1024]
+	s.blocks[blockID(1, content[1024:2048])] = content[1024:2048]
+	// a block of another file is not reused
+	s.blocks[blockID(2, make([]byte, 1024))] = make([]byte, 1024)
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, tr.upload(context.Background(), blobURL, f, "application/octet-stream"))
+	assert.Equal(t, 4, s.staged)
+	assert.Equal(t, content, s.content)
+}
+
+func TestUploadStream(t *testing.T) {
+	tr := &transfer{blockSize: 1024, parallelism: 2}
+	content := randomBytes(3000)
+	s, blobURL := newBlobServer(t, nil)
+	assert.NoError(t, tr.upload(context.Background(), blobURL, bytes.NewReader(content), "application/octet-stream"))
+	assert.Equal(t, content, s.content)
+}
+
+func TestLimiter(t *testing.T) {
+	assert.Nil(t, newLimiter(0))
+
+	l := newLimiter(1000)
+	start := time.Now()
+	// a second of transfer is allowed right away, the rest waits
+	assert.NoError(t, l.wait(context.Background(), 1000))
+	assert.NoError(t, l.wait(context.Background(), 500))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(400*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, l.wait(ctx, 1000))
+
+	data, err := ioutil.ReadAll(newLimiter(1<<20).reader(context.Background(), bytes.NewReader(randomBytes(4096))))
+	assert.NoError(t, err)
+	assert.Len(t, data, 4096)
+}
+
+func TestBlobSize(t *testing.T) {
+	size, ok := blobSize("bytes 0-1023/4096")
+	assert.True(t, ok)
+	assert.Equal(t, int64(4096), size)
+	_, ok = blobSize("")
+	assert.False(t, ok)
+}
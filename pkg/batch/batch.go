@@ -0,0 +1,357 @@
+// Package batch runs the sub-tasks of a batch payload concurrently in a single nucleus instance, so that small
+// repositories do not each pay the cold start of a container. Each sub-task is run by a child nucleus process
+// with its own workspace and API server ports, the children share the configuration hence the cache store,
+// and each child reports the status and the results of its own task.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/workspace"
+)
+
+// Payload is the batch payload, the sub-tasks are run at most Parallelism at a time
+type Payload struct {
+	Tasks       []SubTask `json:"tasks"`
+	Parallelism int       `json:"parallelism"`
+}
+
+// SubTask is a task of the batch, its payload is fetched by the child running it
+type SubTask struct {
+	TaskID         string `json:"task_id"`
+	BuildID        string `json:"build_id"`
+	PayloadAddress string `json:"payload_address"`
+	TargetCommit   string `json:"target_commit_id"`
+	BaseCommit     string `json:"base_commit_id"`
+	Locators       string `json:"locators"`
+	LocatorAddress string `json:"locator_address"`
+	RerunOf        string `json:"rerun_of"`
+}
+
+// Runner runs the sub-tasks of a batch
+type Runner struct {
+	cfg         *config.NucleusConfig
+	azureClient core.AzureClient
+	task        core.Task
+	workspace   *workspace.Manager
+	logger      lumber.Logger
+	// executable is the nucleus binary run for each sub-task
+	executable string
+}
+
+// New returns a Runner running the sub-tasks with the nucleus binary of the current process, task reports the
+// status of the sub-tasks whose child failed before reporting it and ws cleans up after them
+func New(cfg *config.NucleusConfig,
+	azureClient core.AzureClient,
+	task core.Task,
+	ws *workspace.Manager,
+	logger lumber.Logger) (*Runner, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{cfg: cfg, azureClient: azureClient, task: task, workspace: ws, logger: logger, executable: executable}, nil
+}
+
+// Run fetches the batch payload and runs its sub-tasks, it returns an error if any child failed
+func (r *Runner) Run(ctx context.Context) error {
+	payload, err := r.fetchPayload(ctx, r.cfg.BatchAddress)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the batch payload: %w", err)
+	}
+	if err := validatePayload(payload); err != nil {
+		return err
+	}
+	parallelism := payload.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	r.logger.Infof("running %d sub-tasks, %d at a time", len(payload.Tasks), parallelism)
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for i := range payload.Tasks {
+		sub := &payload.Tasks[i]
+		sem <- struct{}{}
+		// the sub-tasks which are not started yet are not run on shutdown
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := r.runSubTask(ctx, sub); err != nil {
+				r.logger.Errorf("sub-task %s failed: %v", sub.TaskID, err)
+				mu.Lock()
+				failed = append(failed, sub.TaskID)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := r.workspace.PruneTasks(global.BatchDir); err != nil {
+		r.logger.Warnf("failed to prune the workspaces of the sub-tasks: %v", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("sub-tasks %s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (r *Runner) fetchPayload(ctx context.Context, address string) (*Payload, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	blobPath := strings.Replace(u.Path, fmt.Sprintf("/%s/", core.PayloadContainer), "", -1)
+	sasURL, err := r.azureClient.GetSASURL(ctx, blobPath, core.PayloadContainer)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := r.azureClient.FindUsingSASUrl(ctx, sasURL)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var p Payload
+	if err := json.NewDecoder(reader).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// validatePayload checks that each sub-task can be run, the task IDs name the workspaces hence must be unique
+func validatePayload(p *Payload) error {
+	if len(p.Tasks) == 0 {
+		return errs.ErrInvalidPayload("Missing tasks")
+	}
+	seen := make(map[string]bool, len(p.Tasks))
+	for i := range p.Tasks {
+		t := &p.Tasks[i]
+		if t.TaskID == "" {
+			return errs.ErrInvalidPayload(fmt.Sprintf("Missing task ID of task %d", i))
+		}
+		if seen[t.TaskID] {
+			return errs.ErrInvalidPayload(fmt.Sprintf("Duplicate task ID %s", t.TaskID))
+		}
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		seen[t.TaskID] = true
+	}
+	return nil
+}
+
+// Validate checks that the sub-task can be run, the task ID names its workspace
+func (t *SubTask) Validate() error {
+	switch {
+	case t.TaskID == "":
+		return errs.ErrInvalidPayload("Missing task ID")
+	case t.TaskID != filepath.Base(t.TaskID) || t.TaskID == "." || t.TaskID == "..":
+		return errs.ErrInvalidPayload(fmt.Sprintf("Invalid task ID %s", t.TaskID))
+	case t.PayloadAddress == "":
+		return errs.ErrInvalidPayload(fmt.Sprintf("Missing payload address of task %s", t.TaskID))
+	}
+	return nil
+}
+
+// runSubTask runs the sub-task in a child nucleus, the clone is removed once it exits while the logs are kept
+func (r *Runner) runSubTask(ctx context.Context, sub *SubTask) error {
+	workspace := filepath.Join(global.BatchDir, sub.TaskID)
+	repoDir := filepath.Join(workspace, "repo")
+	defer os.RemoveAll(repoDir)
+	return r.RunTask(ctx, sub, workspace, repoDir)
+}
+
+// RunTask runs the sub-task in a child nucleus cloning the repository into repoDir, the logs and the scratch
+// files of the child are written to workspace. The sub-task is reported as errored if the child exits before
+// reporting its status.
+func (r *Runner) RunTask(ctx context.Context, sub *SubTask, workspace, repoDir string) error {
+	startTime := time.Now()
+	if err := os.MkdirAll(workspace, global.DirectoryPermissions); err != nil {
+		return r.reportError(sub, startTime, err)
+	}
+	defer func() {
+		if err := r.workspace.CleanTask(workspace); err != nil {
+			r.logger.Warnf("failed to clean up the workspace of sub-task %s: %v", sub.TaskID, err)
+		}
+	}()
+
+	ports, err := freePorts(2)
+	if err != nil {
+		return r.reportError(sub, startTime, err)
+	}
+	cmd := exec.Command(r.executable, childArgs(r.cfg, sub, ports[0], ports[1])...)
+	cmd.Env = append(os.Environ(), childEnv(workspace, repoDir)...)
+	out, err := os.Create(filepath.Join(workspace, "nucleus.out"))
+	if err != nil {
+		return r.reportError(sub, startTime, err)
+	}
+	defer out.Close()
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	r.logger.Infof("starting sub-task %s in %s", sub.TaskID, workspace)
+	if err := cmd.Start(); err != nil {
+		return r.reportError(sub, startTime, err)
+	}
+	// the children shut down gracefully on interrupt, they are killed if interrupts are not supported
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				_ = cmd.Process.Kill()
+			}
+		case <-exited:
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == core.ExitCodePayloadSignature {
+			// the task of a tampered payload is not reported
+			return fmt.Errorf("the signature of the payload is rejected: %w", err)
+		}
+		// the status of the task is reported by the child, unless it exited before the end of the pipeline
+		return r.reportError(sub, startTime, err)
+	}
+	r.logger.Infof("sub-task %s completed in %s", sub.TaskID, time.Since(startTime).Round(time.Second))
+	return nil
+}
+
+// reportError reports the sub-task as errored and returns err
+func (r *Runner) reportError(sub *SubTask, startTime time.Time, err error) error {
+	// the task status is not reported in dry run mode, as no tests are executed
+	if r.cfg.DryRun {
+		return err
+	}
+	taskType := core.ExecutionTask
+	if r.cfg.DiscoverMode {
+		taskType = core.DiscoveryTask
+	}
+	payload := &core.TaskPayload{
+		TaskID:    sub.TaskID,
+		BuildID:   sub.BuildID,
+		CommitID:  sub.TargetCommit,
+		Status:    core.Error,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Remark:    errs.GenericUserFacingBEErrRemark,
+		Type:      taskType,
+	}
+	if reportErr := r.task.UpdateStatus(payload); reportErr != nil {
+		r.logger.Errorf("failed to report the status of sub-task %s: %v", sub.TaskID, reportErr)
+	}
+	return err
+}
+
+// childArgs returns the flags of the child running the sub-task, the mode of the batch applies to each sub-task
+func childArgs(cfg *config.NucleusConfig, sub *SubTask, port, grpcPort int) []string {
+	args := []string{
+		"--payloadAddress", sub.PayloadAddress,
+		"--taskID", sub.TaskID,
+		"--buildID", sub.BuildID,
+		"--port", strconv.Itoa(port),
+		"--grpcPort", strconv.Itoa(grpcPort),
+		"--env", cfg.Env,
+		// the address set in the environment or the config file must not be run again by the child
+		"--batchAddress=",
+	}
+	optional := []struct{ flag, value string }{
+		{"--config", cfg.Config},
+		{"--targetCommit", sub.TargetCommit},
+		{"--baseCommit", sub.BaseCommit},
+		{"--locators", sub.Locators},
+		{"--locatorAddress", sub.LocatorAddress},
+		{"--rerunOf", sub.RerunOf},
+	}
+	for _, o := range optional {
+		if o.value != "" {
+			args = append(args, o.flag, o.value)
+		}
+	}
+	modes := []struct {
+		flag    string
+		enabled bool
+	}{
+		{"--coverage", cfg.CoverageMode},
+		{"--parser", cfg.ParseMode},
+		{"--discover", cfg.DiscoverMode},
+		{"--execute", cfg.ExecuteMode},
+		{"--dry-run", cfg.DryRun},
+		{"--checkpoint", cfg.Checkpoint},
+		{"--verbose", cfg.Verbose},
+	}
+	for _, m := range modes {
+		if m.enabled {
+			args = append(args, m.flag)
+		}
+	}
+	return args
+}
+
+// childEnv returns the environment isolating the files of the child in its workspace
+func childEnv(taskDir, repoDir string) []string {
+	return []string{
+		"REPO_DIR=" + repoDir,
+		"LOGFILE=" + taskDir,
+		"FAILURE_REPORT_PATH=" + filepath.Join(taskDir, "reports", "failures.sarif"),
+		"AUDIT_LOG_PATH=" + filepath.Join(taskDir, "audit", "commands.jsonl"),
+		"WORKSPACE_SCRATCH_DIR=" + filepath.Join(taskDir, workspace.ScratchDirName),
+		"WORKSPACE_BLOCKLIST_FILE=" + filepath.Join(taskDir, "blocklist.json"),
+		"WORKSPACE_COVERAGE_DIR=" + filepath.Join(taskDir, "coverage"),
+		"WORKSPACE_COVERAGE_REPORT_DIR=" + filepath.Join(taskDir, "coverage-report"),
+	}
+}
+
+// freePorts returns n ports free on the host, they are released before being used by the child hence could
+// in theory be taken in between
+func freePorts(n int) ([]int, error) {
+	ports := make([]int, 0, n)
+	listeners := make([]net.Listener, 0, n)
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+		addr, ok := l.Addr().(*net.TCPAddr)
+		if !ok {
+			return nil, errors.New("unexpected listener address")
+		}
+		ports = append(ports, addr.Port)
+	}
+	return ports, nil
+}
@@ -0,0 +1,89 @@
+package batch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePayload(t *testing.T) {
+	valid := SubTask{TaskID: "task-1", PayloadAddress: "https://blob/payloads/task-1.json"}
+	tests := []struct {
+		name    string
+		tasks   []SubTask
+		wantErr bool
+	}{
+		{"valid", []SubTask{valid, {TaskID: "task-2", PayloadAddress: "https://blob/payloads/task-2.json"}}, false},
+		{"no tasks", nil, true},
+		{"missing task ID", []SubTask{{PayloadAddress: valid.PayloadAddress}}, true},
+		{"task ID escaping the workspace", []SubTask{{TaskID: "../repo", PayloadAddress: valid.PayloadAddress}}, true},
+		{"duplicate task ID", []SubTask{valid, valid}, true},
+		{"missing payload address", []SubTask{{TaskID: "task-1"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePayload(&Payload{Tasks: tt.tasks})
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestChildArgs(t *testing.T) {
+	cfg := &config.NucleusConfig{Env: "prod", ExecuteMode: true, Verbose: true}
+	sub := &SubTask{
+		TaskID:         "task-1",
+		BuildID:        "build-1",
+		PayloadAddress: "https://blob/payloads/task-1.json",
+		TargetCommit:   "abc",
+		LocatorAddress: "https://blob/locators/task-1",
+		RerunOf:        "task-0",
+	}
+	assert.Equal(t, []string{
+		"--payloadAddress", "https://blob/payloads/task-1.json",
+		"--taskID", "task-1",
+		"--buildID", "build-1",
+		"--port", "40001",
+		"--grpcPort", "40002",
+		"--env", "prod",
+		"--batchAddress=",
+		"--targetCommit", "abc",
+		"--locatorAddress", "https://blob/locators/task-1",
+		"--rerunOf", "task-0",
+		"--execute",
+		"--verbose",
+	}, childArgs(cfg, sub, 40001, 40002))
+}
+
+func TestChildEnv(t *testing.T) {
+	env := childEnv("/home/nucleus/batch/task-1", "/home/nucleus/batch/task-1/repo")
+	assert.Contains(t, env, "REPO_DIR=/home/nucleus/batch/task-1/repo")
+	assert.Contains(t, env, "LOGFILE=/home/nucleus/batch/task-1")
+	assert.Contains(t, env, "AUDIT_LOG_PATH=/home/nucleus/batch/task-1/audit/commands.jsonl")
+	assert.Contains(t, env, "WORKSPACE_SCRATCH_DIR=/home/nucleus/batch/task-1/scratch")
+
+	// the children running concurrently do not share their blocklist and coverage files
+	other := childEnv("/home/nucleus/batch/task-2", "/home/nucleus/batch/task-2/repo")
+	for _, name := range []string{"WORKSPACE_BLOCKLIST_FILE", "WORKSPACE_COVERAGE_DIR", "WORKSPACE_COVERAGE_REPORT_DIR"} {
+		assert.NotEqual(t, envValue(env, name), envValue(other, name), name)
+		assert.True(t, strings.HasPrefix(envValue(env, name), "/home/nucleus/batch/task-1/"), name)
+	}
+}
+
+// envValue returns the value of the variable name in env
+func envValue(env []string, name string) string {
+	for _, v := range env {
+		if strings.HasPrefix(v, name+"=") {
+			return strings.TrimPrefix(v, name+"=")
+		}
+	}
+	return ""
+}
+
+func TestFreePorts(t *testing.T) {
+	ports, err := freePorts(2)
+	assert.NoError(t, err)
+	assert.Len(t, ports, 2)
+	assert.NotEqual(t, ports[0], ports[1])
+}
@@ -7,13 +7,17 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/fileutils"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -30,25 +34,41 @@ type cache struct {
 	azureClient core.AzureClient
 	logger      lumber.Logger
 	once        sync.Once
-	zstd        core.ZstdCompressor
+	compressor  core.Compressor
 	skipUpload  bool
 	homeDir     string
+	events      *core.EventBus
+	// maxSize in bytes of a cache archive, the larger archives are not saved. Unlimited if 0
+	maxSize int64
+
+	// keys and hits of the named caches, set on restore
+	mu   sync.Mutex
+	keys map[string]string
+	hits map[string]bool
 }
 
 var cacheBlobURL string
 var apiErr error
 
 // New returns a new CacheStore
-func New(z core.ZstdCompressor, azureClient core.AzureClient, logger lumber.Logger) (core.CacheStore, error) {
+func New(cfg *config.NucleusConfig,
+	compressor core.Compressor,
+	azureClient core.AzureClient,
+	events *core.EventBus,
+	logger lumber.Logger) (core.CacheStore, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
 	return &cache{
 		azureClient: azureClient,
-		zstd:        z,
+		compressor:  compressor,
 		logger:      logger,
 		homeDir:     homeDir,
+		events:      events,
+		maxSize:     cfg.Limits.MaxCacheSize << 20,
+		keys:        make(map[string]string),
+		hits:        make(map[string]bool),
 	}, nil
 }
 
@@ -59,7 +79,36 @@ func (c *cache) getCacheSASURL(ctx context.Context, containerPath string) (strin
 	return cacheBlobURL, apiErr
 }
 
-func (c *cache) Download(ctx context.Context, cacheKey string) error {
+func (c *cache) Download(ctx context.Context, cacheKey string) (err error) {
+	cacheKey = platformCacheKey(cacheKey)
+	ctx, span := tracing.StartSpan(ctx, "cachemanager.Download", attribute.String("cache.key", cacheKey))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if isWarm(cacheKey) {
+		c.logger.Infof("Cache for key: %s already restored into the checkout", cacheKey)
+		c.skipUpload = true
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		c.emitHit(ctx, cacheKey)
+		return nil
+	}
+	namespace, err := chunksNamespace(cacheKey)
+	if err != nil {
+		return err
+	}
+	cachedFilePath := filepath.Join(global.CacheDir, defaultCompressedFileName)
+	err = c.downloadChunked(ctx, namespace, cacheKey, cachedFilePath)
+	if err == nil {
+		c.skipUpload = true
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		c.emitHit(ctx, cacheKey)
+		return c.decompressWarm(ctx, cachedFilePath, cacheKey)
+	}
+	if !errors.Is(err, errs.ErrNotFound) {
+		c.logger.Errorf("Error while downloading cache chunks for key: %s, error %v", cacheKey, err)
+		return err
+	}
+
+	// fallback to the caches uploaded as a single archive
 	containerPath := fmt.Sprintf("%s/%s", cacheKey, defaultCompressedFileName)
 	sasURL, err := c.getCacheSASURL(ctx, containerPath)
 	if err != nil {
@@ -76,9 +125,10 @@ func (c *cache) Download(ctx context.Context, cacheKey string) error {
 		return err
 	}
 	c.skipUpload = true
+	span.SetAttributes(attribute.Bool("cache.hit", true))
+	c.emitHit(ctx, cacheKey)
 	defer resp.Close()
 
-	cachedFilePath := filepath.Join(os.TempDir(), defaultCompressedFileName)
 	out, err := os.Create(cachedFilePath)
 	if err != nil {
 		return err
@@ -89,17 +139,40 @@ func (c *cache) Download(ctx context.Context, cacheKey string) error {
 		return err
 	}
 	//decompress
-	return c.zstd.Decompress(ctx, cachedFilePath, true, global.RepoDir)
+	return c.decompressWarm(ctx, cachedFilePath, cacheKey)
 
 }
 
-func (c *cache) Upload(ctx context.Context, cacheKey string, itemsToCompress ...string) error {
+// emitHit emits the cache.hit event of the restored cache
+func (c *cache) emitHit(ctx context.Context, cache string) {
+	c.events.Emit(ctx, &core.Event{Type: core.EventCacheHit, Cache: cache})
+}
+
+// decompressWarm decompresses the cache into the checkout and records its key as restored
+func (c *cache) decompressWarm(ctx context.Context, cachedFilePath, cacheKey string) error {
+	if err := c.compressor.Decompress(ctx, cachedFilePath, true, global.RepoDir); err != nil {
+		return err
+	}
+	if err := markWarm(cacheKey); err != nil {
+		c.logger.Warnf("failed to record the restored cache %s: %v", cacheKey, err)
+	}
+	return nil
+}
+
+func (c *cache) Upload(ctx context.Context, cacheKey string, itemsToCompress ...string) (err error) {
+	cacheKey = platformCacheKey(cacheKey)
+	ctx, span := tracing.StartSpan(ctx, "cachemanager.Upload", attribute.String("cache.key", cacheKey))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if c.skipUpload {
 		c.logger.Infof("Cache hit occurred on the key %s, not saving cache.", cacheKey)
 		return nil
 	}
+	namespace, err := chunksNamespace(cacheKey)
+	if err != nil {
+		return err
+	}
 
-	validatedItems := make([]string, 0, len(itemsToCompress))
 	if len(itemsToCompress) == 0 {
 		dir, err := c.getDefaultDirs()
 		if err != nil {
@@ -108,50 +181,64 @@ func (c *cache) Upload(ctx context.Context, cacheKey string, itemsToCompress ...
 		}
 		itemsToCompress = append(itemsToCompress, dir)
 	}
-	// validate the file or dir paths if it exists.
-	for _, item := range itemsToCompress {
-		exists, err := fileutils.CheckIfExists(item)
-		if err != nil {
-			return err
-		}
-		if exists {
-			validatedItems = append(validatedItems, item)
-		} else {
-			c.logger.Debugf("%s does not exist, skipping upload", item)
-		}
+	validatedItems, err := c.validateItems(itemsToCompress)
+	if err != nil {
+		return err
 	}
 	if len(validatedItems) == 0 {
 		c.logger.Debugf("No valid files/dirs found to cache")
 		return nil
 	}
 
-	err := c.zstd.Compress(ctx, defaultCompressedFileName, true, global.RepoDir, validatedItems...)
+	err = c.compressor.Compress(ctx, defaultCompressedFileName, true, global.RepoDir, validatedItems...)
 	if err != nil {
 		c.logger.Errorf("error while compressing files with key %s, error: %v", cacheKey, err)
 		return err
 	}
-
-	f, err := os.Open(filepath.Join(global.RepoDir, defaultCompressedFileName))
-	if err != nil {
-		c.logger.Errorf("error while opening compressed file with key %s, error: %v", cacheKey, err)
+	if exceeded, err := c.exceedsMaxSize(cacheKey, filepath.Join(global.RepoDir, defaultCompressedFileName)); err != nil || exceeded {
 		return err
 	}
 
-	defer f.Close()
-	containerPath := fmt.Sprintf("%s/%s", cacheKey, defaultCompressedFileName)
-	sasURL, err := c.getCacheSASURL(ctx, containerPath)
-	if err != nil {
-		c.logger.Errorf("Error while generating SAS Token, error %v", err)
-		return err
-	}
-	_, err = c.azureClient.CreateUsingSASURL(ctx, sasURL, f, "application/zstd")
-	if err != nil {
+	if err = c.uploadChunked(ctx, namespace, cacheKey, filepath.Join(global.RepoDir, defaultCompressedFileName)); err != nil {
 		c.logger.Errorf("error while uploading cached file %s with key %s, error: %v", defaultCompressedFileName, cacheKey, err)
 		return err
 	}
 	return nil
 }
 
+// exceedsMaxSize checks if the archive of the cache is larger than the maximum size of a cache
+func (c *cache) exceedsMaxSize(name, archivePath string) (bool, error) {
+	if c.maxSize == 0 {
+		return false, nil
+	}
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() <= c.maxSize {
+		return false, nil
+	}
+	c.logger.Warnf("cache %s of %d MiB exceeds the limit of %d MiB, not saving cache", name, info.Size()>>20, c.maxSize>>20)
+	return true, nil
+}
+
+// validateItems returns the file or dir paths which exist
+func (c *cache) validateItems(items []string) ([]string, error) {
+	validatedItems := make([]string, 0, len(items))
+	for _, item := range items {
+		exists, err := fileutils.CheckIfExists(item)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			validatedItems = append(validatedItems, item)
+		} else {
+			c.logger.Debugf("%s does not exist, skipping upload", item)
+		}
+	}
+	return validatedItems, nil
+}
+
 func (c *cache) getDefaultDirs() (string, error) {
 	f, err := os.Open(global.RepoDir)
 	if err != nil {
@@ -166,13 +253,49 @@ func (c *cache) getDefaultDirs() (string, error) {
 	for _, d := range dirs {
 		// if yarn.lock present cache yarn folder
 		if d.Name() == yarnLock {
-			return filepath.Join(c.homeDir, ".cache", "yarn"), nil
+			return c.yarnCacheDir(), nil
 		}
 		// if package-lock.json or npm-shrinkwrap.json cache .npm cache
 		if d.Name() == packageLock || d.Name() == npmShrinkwrap {
-			return filepath.Join(c.homeDir, ".npm"), nil
+			return c.npmCacheDir(), nil
 		}
 	}
 	// If none present cache node_modules
 	return nodeModules, nil
 }
+
+// yarnCacheDir returns the default cache directory of yarn on the OS
+func (c *cache) yarnCacheDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(c.localAppDataDir(), "Yarn", "Cache")
+	case "darwin":
+		return filepath.Join(c.homeDir, "Library", "Caches", "Yarn")
+	default:
+		return filepath.Join(c.homeDir, ".cache", "yarn")
+	}
+}
+
+// npmCacheDir returns the default cache directory of npm on the OS
+func (c *cache) npmCacheDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(c.localAppDataDir(), "npm-cache")
+	}
+	return filepath.Join(c.homeDir, ".npm")
+}
+
+func (c *cache) localAppDataDir() string {
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+		return dir
+	}
+	return filepath.Join(c.homeDir, "AppData", "Local")
+}
+
+// platformCacheKey scopes the caches of the windows and macOS agents by OS, as the archives contain absolute
+// paths and binaries of the OS. The caches of linux keep their key so that the existing caches are restored.
+func platformCacheKey(cacheKey string) string {
+	if runtime.GOOS == "linux" {
+		return cacheKey
+	}
+	return cacheKey + "-" + runtime.GOOS
+}
@@ -0,0 +1,75 @@
+package cachemanager
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+)
+
+// content defined chunking sizes, chosen for multi GB caches
+const (
+	minChunkSize = 1 << 20
+	avgChunkSize = 4 << 20
+	maxChunkSize = 16 << 20
+	// seed of the gear table, changing it invalidates all the stored chunks
+	gearSeed = 0x7461735f63646321
+)
+
+// masks used before and after the average chunk size (normalized chunking),
+// with more and less bits than log2(avgChunkSize) respectively
+const (
+	maskSmall uint64 = (1 << 24) - 1
+	maskLarge uint64 = (1 << 20) - 1
+)
+
+var gearTable [256]uint64
+
+func init() {
+	r := rand.New(rand.NewSource(gearSeed))
+	for i := range gearTable {
+		gearTable[i] = r.Uint64()
+	}
+}
+
+// chunker splits a stream into content defined chunks using the FastCDC gear hash,
+// so that an insertion or deletion only changes the chunks around it.
+type chunker struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: bufio.NewReaderSize(r, 1<<20), buf: make([]byte, 0, maxChunkSize)}
+}
+
+// Next returns the next chunk, which is only valid till the following call.
+// io.EOF is returned after the last chunk.
+func (c *chunker) Next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var fp uint64
+	for {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) == 0 {
+				return nil, io.EOF
+			}
+			return c.buf, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, b)
+		n := len(c.buf)
+		if n < minChunkSize {
+			continue
+		}
+		fp = (fp << 1) + gearTable[b]
+		mask := maskSmall
+		if n >= avgChunkSize {
+			mask = maskLarge
+		}
+		if fp&mask == 0 || n >= maxChunkSize {
+			return c.buf, nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package cachemanager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chunkHashes(t *testing.T, data []byte) []string {
+	hashes := make([]string, 0)
+	ch := newChunker(bytes.NewReader(data))
+	total := 0
+	for {
+		chunk, err := ch.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(chunk), maxChunkSize)
+		total += len(chunk)
+		sum := sha256.Sum256(chunk)
+		hashes = append(hashes, string(sum[:]))
+	}
+	assert.Equal(t, len(data), total)
+	return hashes
+}
+
+func TestChunkerInsertion(t *testing.T) {
+	data := make([]byte, 48<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	original := chunkHashes(t, data)
+	assert.Equal(t, original, chunkHashes(t, data))
+
+	// insert bytes in the middle, chunks before and after the insertion are retained
+	modified := append(append(append([]byte{}, data[:len(data)/2]...), []byte("inserted")...), data[len(data)/2:]...)
+	changed := chunkHashes(t, modified)
+	seen := make(map[string]bool)
+	for _, h := range original {
+		seen[h] = true
+	}
+	shared := 0
+	for _, h := range changed {
+		if seen[h] {
+			shared++
+		}
+	}
+	assert.GreaterOrEqual(t, shared, len(original)-2)
+}
@@ -0,0 +1,286 @@
+package cachemanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	chunksDir              = "chunks"
+	chunkIndexFileName     = "index.json"
+	cacheManifestName      = "manifest.json"
+	maxConcurrentTransfers = 8
+	chunkDownloadAttempts  = 3
+	// chunkIndexMaxAge is the age after which the chunks in the index are uploaded again, so that the index
+	// does not refer to the chunks removed by the retention of the container
+	chunkIndexMaxAge = 7 * 24 * time.Hour
+)
+
+// cacheManifest lists the chunks of a cache archive in order
+type cacheManifest struct {
+	Size   int64        `json:"size"`
+	Chunks []chunkEntry `json:"chunks"`
+}
+
+type chunkEntry struct {
+	Hash   string `json:"hash"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+}
+
+// chunkIndex maps the hashes of the uploaded chunks to the unix time they were last uploaded at
+type chunkIndex map[string]int64
+
+// chunksNamespace returns the directory where the chunks are stored, chunks are shared by all the cache
+// keys of the repository so that only new chunks are uploaded. The cache key is prefixed by the org and
+// repo ids, the chunks of the repositories are never shared.
+func chunksNamespace(cacheKey string) (string, error) {
+	parts := strings.SplitN(cacheKey, "/", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("cache key %s is not prefixed by the org and repo ids", cacheKey)
+	}
+	return path.Join(parts[0], parts[1], chunksDir), nil
+}
+
+// uploadChunked splits the archive into content defined chunks and uploads the chunks
+// which are not present in the chunk index of namespace, followed by the manifest of the cache key.
+func (c *cache) uploadChunked(ctx context.Context, namespace, cacheKey, archivePath string) error {
+	index, err := c.readChunkIndex(ctx, namespace)
+	if err != nil {
+		c.logger.Warnf("failed to read chunk index for %s, uploading all chunks: %v", namespace, err)
+		index = make(chunkIndex)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest := cacheManifest{}
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, maxConcurrentTransfers)
+	uploaded := make(map[string]bool)
+	var uploadedBytes int64
+	ch := newChunker(f)
+	for {
+		chunk, err := ch.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		manifest.Chunks = append(manifest.Chunks, chunkEntry{Hash: hash, Size: int64(len(chunk)), Offset: manifest.Size})
+		manifest.Size += int64(len(chunk))
+		if _, ok := index[hash]; ok || uploaded[hash] {
+			continue
+		}
+		uploaded[hash] = true
+		uploadedBytes += int64(len(chunk))
+		// chunk buffer is reused by the chunker
+		data := append([]byte(nil), chunk...)
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			return g.Wait()
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return c.putBlob(gctx, path.Join(namespace, hash), bytes.NewReader(data), "application/octet-stream")
+		})
+	}
+	if err := g.Wait(); err != nil {
+		c.logger.Errorf("failed to upload cache chunks for key %s, error: %v", cacheKey, err)
+		return err
+	}
+	c.logger.Infof("uploaded %d of %d chunks (%d of %d bytes) for cache key %s",
+		len(uploaded), len(manifest.Chunks), uploadedBytes, manifest.Size, cacheKey)
+
+	if err := c.putJSON(ctx, path.Join(cacheKey, cacheManifestName), manifest); err != nil {
+		return err
+	}
+
+	// concurrent builds may overwrite the index, missing entries only cause re-uploads
+	now := time.Now().Unix()
+	for hash := range uploaded {
+		index[hash] = now
+	}
+	return c.writeChunkIndex(ctx, namespace, index)
+}
+
+// downloadChunked restores the archive of the cache key from its chunks,
+// errs.ErrNotFound is returned if the cache key does not have a manifest.
+// The download is resumed from the chunks already in the archive if it is retried.
+func (c *cache) downloadChunked(ctx context.Context, namespace, cacheKey, archivePath string) error {
+	manifest := cacheManifest{}
+	if err := c.getJSON(ctx, path.Join(cacheKey, cacheManifestName), &manifest); err != nil {
+		return err
+	}
+	var err error
+	for attempt := 1; attempt <= chunkDownloadAttempts; attempt++ {
+		err = c.downloadChunks(ctx, namespace, manifest, archivePath)
+		if err == nil || ctx.Err() != nil || errors.Is(err, errs.ErrNotFound) {
+			break
+		}
+		c.logger.Warnf("failed to download cache chunks for key %s, attempt %d of %d, error: %v",
+			cacheKey, attempt, chunkDownloadAttempts, err)
+	}
+	if err != nil {
+		c.logger.Errorf("failed to download cache chunks for key %s, error: %v", cacheKey, err)
+		if errors.Is(err, errs.ErrNotFound) {
+			c.dropChunks(ctx, namespace, manifest)
+		}
+		return err
+	}
+	return nil
+}
+
+// dropChunks removes the chunks of the manifest from the index after one of them is found missing, the other
+// chunks of the manifest are likely missing as well. The chunks are uploaded again by the next upload.
+func (c *cache) dropChunks(ctx context.Context, namespace string, manifest cacheManifest) {
+	index, err := c.readChunkIndex(ctx, namespace)
+	if err != nil {
+		c.logger.Warnf("failed to read chunk index for %s, stale chunks are not dropped: %v", namespace, err)
+		return
+	}
+	for _, entry := range manifest.Chunks {
+		delete(index, entry.Hash)
+	}
+	if err := c.writeChunkIndex(ctx, namespace, index); err != nil {
+		c.logger.Warnf("failed to drop stale chunks from chunk index %s: %v", namespace, err)
+	}
+}
+
+// downloadChunks downloads the chunks of the manifest into the archive, the chunks already present in the
+// archive are kept
+func (c *cache) downloadChunks(ctx context.Context, namespace string, manifest cacheManifest, archivePath string) error {
+	out, err := os.OpenFile(archivePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := out.Truncate(manifest.Size); err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, maxConcurrentTransfers)
+	for _, entry := range manifest.Chunks {
+		entry := entry
+		if hasChunk(out, entry) {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			return g.Wait()
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return c.downloadChunk(gctx, path.Join(namespace, entry.Hash), entry, out)
+		})
+	}
+	return g.Wait()
+}
+
+// hasChunk checks whether the archive already holds the chunk at its offset
+func hasChunk(archive io.ReaderAt, entry chunkEntry) bool {
+	data := make([]byte, entry.Size)
+	if _, err := archive.ReadAt(data, entry.Offset); err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == entry.Hash
+}
+
+func (c *cache) downloadChunk(ctx context.Context, blobPath string, entry chunkEntry, out io.WriterAt) error {
+	body, err := c.getBlob(ctx, blobPath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(body, entry.Size+1))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != entry.Size || hex.EncodeToString(sum[:]) != entry.Hash {
+		return fmt.Errorf("checksum mismatch for cache chunk %s", entry.Hash)
+	}
+	_, err = out.WriteAt(data, entry.Offset)
+	return err
+}
+
+// readChunkIndex returns the index of the chunks of namespace, the entries older than chunkIndexMaxAge are dropped
+func (c *cache) readChunkIndex(ctx context.Context, namespace string) (chunkIndex, error) {
+	index := make(chunkIndex)
+	if err := c.getJSON(ctx, path.Join(namespace, chunkIndexFileName), &index); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return make(chunkIndex), nil
+		}
+		return nil, err
+	}
+	expired := time.Now().Add(-chunkIndexMaxAge).Unix()
+	for hash, uploadedAt := range index {
+		if uploadedAt < expired {
+			delete(index, hash)
+		}
+	}
+	return index, nil
+}
+
+func (c *cache) writeChunkIndex(ctx context.Context, namespace string, index chunkIndex) error {
+	return c.putJSON(ctx, path.Join(namespace, chunkIndexFileName), index)
+}
+
+func (c *cache) getJSON(ctx context.Context, blobPath string, v interface{}) error {
+	body, err := c.getBlob(ctx, blobPath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+func (c *cache) putJSON(ctx context.Context, blobPath string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.putBlob(ctx, blobPath, bytes.NewReader(raw), "application/json")
+}
+
+func (c *cache) getBlob(ctx context.Context, blobPath string) (io.ReadCloser, error) {
+	sasURL, err := c.azureClient.GetSASURL(ctx, blobPath, core.CacheContainer)
+	if err != nil {
+		return nil, err
+	}
+	return c.azureClient.FindUsingSASUrl(ctx, sasURL)
+}
+
+func (c *cache) putBlob(ctx context.Context, blobPath string, reader io.Reader, mimeType string) error {
+	sasURL, err := c.azureClient.GetSASURL(ctx, blobPath, core.CacheContainer)
+	if err != nil {
+		return err
+	}
+	_, err = c.azureClient.CreateUsingSASURL(ctx, sasURL, reader, mimeType)
+	return err
+}
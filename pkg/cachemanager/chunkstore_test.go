@@ -0,0 +1,84 @@
+package cachemanager
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunksNamespace(t *testing.T) {
+	namespace, err := chunksNamespace("org/repo/npm-deps")
+	assert.Nil(t, err)
+	assert.Equal(t, "org/repo/chunks", namespace)
+	namespace, err = chunksNamespace("org/repo/npm/deps")
+	assert.Nil(t, err)
+	assert.Equal(t, "org/repo/chunks", namespace)
+
+	_, err = chunksNamespace("npm-deps")
+	assert.NotNil(t, err)
+	_, err = chunksNamespace("/repo/npm-deps")
+	assert.NotNil(t, err)
+}
+
+func TestStaleChunks(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	root := t.TempDir()
+	store, err := storage.NewLocalStore(root, "coverage", logger)
+	assert.Nil(t, err)
+	c := &cache{azureClient: store, logger: logger}
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "cache.tzst")
+	data := make([]byte, 8<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	assert.Nil(t, ioutil.WriteFile(archivePath, data, 0644))
+	namespace, cacheKey := "org/repo/chunks", "org/repo/npm"
+	assert.Nil(t, c.uploadChunked(ctx, namespace, cacheKey, archivePath))
+
+	index, err := c.readChunkIndex(ctx, namespace)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, index)
+
+	// a chunk removed from the container fails the download and drops the chunks of the key from the index
+	manifest := cacheManifest{}
+	assert.Nil(t, c.getJSON(ctx, path.Join(cacheKey, cacheManifestName), &manifest))
+	removed := manifest.Chunks[0].Hash
+	assert.Nil(t, os.Remove(filepath.Join(root, string(core.CacheContainer), namespace, removed)))
+	restored := filepath.Join(dir, "restored.tzst")
+	err = c.downloadChunked(ctx, namespace, cacheKey, restored)
+	assert.True(t, errors.Is(err, errs.ErrNotFound))
+	index, err = c.readChunkIndex(ctx, namespace)
+	assert.Nil(t, err)
+	assert.Empty(t, index)
+
+	// the next upload uploads the missing chunk again
+	assert.Nil(t, c.uploadChunked(ctx, namespace, cacheKey, archivePath))
+	assert.Nil(t, c.downloadChunked(ctx, namespace, cacheKey, restored))
+	got, err := ioutil.ReadFile(restored)
+	assert.Nil(t, err)
+	assert.Equal(t, data, got)
+
+	// the entries older than the max age are dropped
+	index, err = c.readChunkIndex(ctx, namespace)
+	assert.Nil(t, err)
+	for hash := range index {
+		index[hash] = 0
+	}
+	assert.Nil(t, c.writeChunkIndex(ctx, namespace, index))
+	index, err = c.readChunkIndex(ctx, namespace)
+	assert.Nil(t, err)
+	assert.Empty(t, index)
+}
@@ -0,0 +1,309 @@
+package cachemanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	namedCachesDir = "caches"
+	cacheKeysDir   = "keys"
+	cacheRefsDir   = "refs"
+	// keyEnvPrefix is the prefix of the environment variables available to the cache key templates, the other
+	// variables may hold secrets which must not end up in the blob paths and the logs
+	keyEnvPrefix = "CACHE_"
+)
+
+// keyData is the data available to the cache key templates
+type keyData struct {
+	Branch string
+	OS     string
+	Arch   string
+}
+
+// cacheRef points a restore key to the latest key saved with the restore key as prefix
+type cacheRef struct {
+	Key string `json:"key"`
+}
+
+// Restore downloads the named caches in parallel. A cache restored using its key is
+// not saved again, the ones restored using a restore key or not found are saved by Save.
+func (c *cache) Restore(ctx context.Context, payload *core.Payload, caches []core.NamedCache) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "cachemanager.Restore", attribute.Int("cache.count", len(caches)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i := range caches {
+		namedCache := caches[i]
+		g.Go(func() error {
+			if err := c.restoreNamed(gctx, payload, namedCache); err != nil {
+				c.logger.Errorf("failed to restore cache %s, error: %v", namedCache.Name, err)
+				return err
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Save uploads the named caches in parallel
+func (c *cache) Save(ctx context.Context, payload *core.Payload, caches []core.NamedCache) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "cachemanager.Save", attribute.Int("cache.count", len(caches)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i := range caches {
+		namedCache := caches[i]
+		g.Go(func() error {
+			if err := c.saveNamed(gctx, payload, namedCache); err != nil {
+				c.logger.Errorf("failed to save cache %s, error: %v", namedCache.Name, err)
+				return err
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func (c *cache) restoreNamed(ctx context.Context, payload *core.Payload, namedCache core.NamedCache) error {
+	if err := validateCacheName(namedCache.Name); err != nil {
+		return err
+	}
+	key, err := c.renderKey(namedCache.Key, payload)
+	if err != nil {
+		return err
+	}
+	c.setKey(namedCache.Name, key)
+	warmKey := path.Join(namedCache.Name, key)
+	if isWarm(warmKey) {
+		c.logger.Infof("Cache %s already restored from key %s", namedCache.Name, key)
+		c.setHit(namedCache.Name)
+		c.emitHit(ctx, namedCache.Name)
+		return nil
+	}
+
+	root := namedCachePrefix(payload, namedCache.Name)
+	archivePath := namedArchivePath(namedCache.Name)
+	candidates := []string{key}
+	for _, restoreKey := range namedCache.RestoreKeys {
+		rendered, err := c.renderKey(restoreKey, payload)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, rendered)
+	}
+
+	for i, candidate := range candidates {
+		// restore keys are resolved to the latest key saved with them as prefix
+		if i > 0 {
+			ref := cacheRef{}
+			err := c.getJSON(ctx, path.Join(root, cacheRefsDir, refName(candidate)), &ref)
+			if err != nil && !errors.Is(err, errs.ErrNotFound) {
+				return err
+			}
+			if err == nil {
+				candidate = ref.Key
+			}
+		}
+		err := c.downloadChunked(ctx, path.Join(root, chunksDir), path.Join(root, cacheKeysDir, candidate), archivePath)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			c.setHit(namedCache.Name)
+		}
+		c.logger.Infof("Restoring cache %s from key %s", namedCache.Name, candidate)
+		c.emitHit(ctx, namedCache.Name)
+		defer os.Remove(archivePath)
+		if i > 0 {
+			return c.compressor.Decompress(ctx, archivePath, true, global.RepoDir)
+		}
+		return c.decompressWarm(ctx, archivePath, warmKey)
+	}
+	c.logger.Infof("Cache %s not found for key: %s", namedCache.Name, key)
+	return nil
+}
+
+func (c *cache) saveNamed(ctx context.Context, payload *core.Payload, namedCache core.NamedCache) error {
+	if err := validateCacheName(namedCache.Name); err != nil {
+		return err
+	}
+	key, hit := c.getKey(namedCache.Name)
+	if hit {
+		c.logger.Infof("Cache hit occurred on the key %s of cache %s, not saving cache.", key, namedCache.Name)
+		return nil
+	}
+	if key == "" {
+		rendered, err := c.renderKey(namedCache.Key, payload)
+		if err != nil {
+			return err
+		}
+		key = rendered
+	}
+
+	items := make([]string, 0, len(namedCache.Paths))
+	for _, item := range namedCache.Paths {
+		items = append(items, c.expandPath(item))
+	}
+	validatedItems, err := c.validateItems(items)
+	if err != nil {
+		return err
+	}
+	if len(validatedItems) == 0 {
+		c.logger.Debugf("No valid files/dirs found to cache for %s", namedCache.Name)
+		return nil
+	}
+
+	archivePath := namedArchivePath(namedCache.Name)
+	defer os.Remove(archivePath)
+	if err := c.compressor.Compress(ctx, archivePath, true, global.RepoDir, validatedItems...); err != nil {
+		return err
+	}
+	if exceeded, err := c.exceedsMaxSize(namedCache.Name, archivePath); err != nil || exceeded {
+		return err
+	}
+	root := namedCachePrefix(payload, namedCache.Name)
+	if err := c.uploadChunked(ctx, path.Join(root, chunksDir), path.Join(root, cacheKeysDir, key), archivePath); err != nil {
+		return err
+	}
+
+	for _, restoreKey := range namedCache.RestoreKeys {
+		rendered, err := c.renderKey(restoreKey, payload)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(key, rendered) {
+			continue
+		}
+		if err := c.putJSON(ctx, path.Join(root, cacheRefsDir, refName(rendered)), cacheRef{Key: key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderKey executes the cache key template. `checksum` returns the checksum of the given files
+// relative to the repository and `env` the value of an environment variable prefixed by CACHE_.
+func (c *cache) renderKey(keyTemplate string, payload *core.Payload) (string, error) {
+	funcs := template.FuncMap{
+		"checksum": checksumFiles,
+		"env":      keyEnv,
+	}
+	tmpl, err := template.New("key").Funcs(funcs).Parse(keyTemplate)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	data := keyData{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if payload != nil {
+		data.Branch = payload.BranchName
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(buf.String())
+	if key == "" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("cache key template %q rendered an invalid key %q", keyTemplate, key)
+	}
+	return key, nil
+}
+
+// expandPath resolves the paths relative to the home directory
+func (c *cache) expandPath(item string) string {
+	if item == "~" {
+		return c.homeDir
+	}
+	if strings.HasPrefix(item, "~/") {
+		return filepath.Join(c.homeDir, item[2:])
+	}
+	return item
+}
+
+func (c *cache) setKey(name, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys[name] = key
+}
+
+func (c *cache) setHit(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits[name] = true
+}
+
+func (c *cache) getKey(name string) (key string, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.keys[name], c.hits[name]
+}
+
+// keyEnv returns the value of the environment variable of a cache key template
+func keyEnv(name string) (string, error) {
+	if !strings.HasPrefix(name, keyEnvPrefix) {
+		return "", fmt.Errorf("environment variable %s is not available to the cache keys, only the ones prefixed by %s are",
+			name, keyEnvPrefix)
+	}
+	return os.Getenv(name), nil
+}
+
+// validateCacheName checks that the name of the cache is a single path segment, as it is part of the blob paths
+// and of the local archive path
+func validateCacheName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid cache name %q", name)
+	}
+	return nil
+}
+
+func checksumFiles(files ...string) (string, error) {
+	if len(files) == 0 {
+		return "", errors.New("checksum requires at least one file")
+	}
+	if len(files) == 1 {
+		return utils.ComputeChecksum(filepath.Join(global.RepoDir, files[0]))
+	}
+	hash := md5.New()
+	for _, file := range files {
+		checksum, err := utils.ComputeChecksum(filepath.Join(global.RepoDir, file))
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(checksum))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func namedCachePrefix(payload *core.Payload, name string) string {
+	return path.Join(payload.OrgID, payload.RepoID, namedCachesDir, name)
+}
+
+func namedArchivePath(name string) string {
+	return filepath.Join(global.CacheDir, fmt.Sprintf("cache-%s.tzst", name))
+}
+
+func refName(restoreKey string) string {
+	sum := sha256.Sum256([]byte(restoreKey))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
@@ -0,0 +1,57 @@
+package cachemanager
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderKey(t *testing.T) {
+	t.Setenv("CACHE_VERSION", "v2")
+	t.Setenv("NPM_TOKEN", "token")
+
+	c := &cache{homeDir: "/home/user"}
+	payload := &core.Payload{BranchName: "main"}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"literal", "npm", "npm", false},
+		{"data", "{{ .OS }}-{{ .Branch }}", runtime.GOOS + "-main", false},
+		{"env", `npm-{{ env "CACHE_VERSION" }}`, "npm-v2", false},
+		{"missing file", `{{ checksum "tas-missing.lock" }}`, "", true},
+		{"empty", `{{ env "CACHE_UNSET_VAR" }}`, "", true},
+		{"env without prefix", `npm-{{ env "NPM_TOKEN" }}`, "", true},
+		{"traversal", "../other", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.renderKey(tt.template, payload)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateCacheName(t *testing.T) {
+	assert.Nil(t, validateCacheName("npm"))
+	assert.Nil(t, validateCacheName("npm..deps"))
+	for _, name := range []string{"", ".", "..", "../npm", "npm/deps", `npm\deps`} {
+		assert.NotNil(t, validateCacheName(name), name)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	c := &cache{homeDir: "/home/user"}
+	assert.Equal(t, "/home/user/.m2", c.expandPath("~/.m2"))
+	assert.Equal(t, "/home/user", c.expandPath("~"))
+	assert.Equal(t, "node_modules", c.expandPath("node_modules"))
+}
@@ -0,0 +1,58 @@
+package cachemanager
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// warmKeysFile lists the keys of the caches restored into the checkout. A resident nucleus keeps the checkout
+// between the tasks of the repository, the caches restored with the same key are then not downloaded again.
+const warmKeysFile = "tas-warm-caches"
+
+var warmMu sync.Mutex
+
+// warmKeysPath returns the path of the list of the restored keys, it is removed along with the checkout
+func warmKeysPath() string {
+	return filepath.Join(global.RepoDir, ".git", warmKeysFile)
+}
+
+// isWarm reports whether the cache of the key was already restored into the checkout
+func isWarm(key string) bool {
+	warmMu.Lock()
+	defer warmMu.Unlock()
+	f, err := os.Open(warmKeysPath())
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if scanner.Text() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// markWarm records that the cache of the key was restored, only the git checkouts are reused
+func markWarm(key string) error {
+	warmMu.Lock()
+	defer warmMu.Unlock()
+	if _, err := os.Stat(filepath.Dir(warmKeysPath())); err != nil {
+		return nil
+	}
+	f, err := os.OpenFile(warmKeysPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, global.FilePermissions)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, key); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,27 @@
+package cachemanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmKeys(t *testing.T) {
+	repoDir := global.RepoDir
+	defer func() { global.RepoDir = repoDir }()
+	global.RepoDir = t.TempDir()
+
+	// the caches are only recorded in the git checkouts, which are reused by the next tasks
+	assert.NoError(t, markWarm("org/repo/key"))
+	assert.False(t, isWarm("org/repo/key"))
+
+	assert.NoError(t, os.Mkdir(filepath.Join(global.RepoDir, ".git"), global.DirectoryPermissions))
+	assert.NoError(t, markWarm("org/repo/key"))
+	assert.NoError(t, markWarm("deps/abc"))
+	assert.True(t, isWarm("org/repo/key"))
+	assert.True(t, isWarm("deps/abc"))
+	assert.False(t, isWarm("deps/def"))
+}
@@ -0,0 +1,194 @@
+package checkpointmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const (
+	checkpointMimeType = "application/json"
+	archiveMimeType    = "application/octet-stream"
+	checkpointFileName = "checkpoint.json"
+	coverageFileName   = "coverage.tzst"
+)
+
+type manager struct {
+	azureClient core.AzureClient
+	compressor  core.Compressor
+	logger      lumber.Logger
+}
+
+// New returns a new CheckpointManager storing the checkpoints in the blob storage
+func New(azureClient core.AzureClient, compressor core.Compressor, logger lumber.Logger) core.CheckpointManager {
+	return &manager{azureClient: azureClient, compressor: compressor, logger: logger}
+}
+
+// Load returns the checkpoint of the task. The checkpoint is ignored if the task has no test locators,
+// as the remaining tests can not be selected and all the tests are run again.
+func (m *manager) Load(ctx context.Context, payload *core.Payload, coverageDir string) (*core.Checkpoint, error) {
+	prefix := blobPrefix(payload)
+	reader, err := m.azureClient.Find(ctx, prefix+checkpointFileName)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+	checkpoint := &core.Checkpoint{}
+	if err := json.NewDecoder(reader).Decode(checkpoint); err != nil {
+		return nil, err
+	}
+	// cleared checkpoints are empty
+	if checkpoint.TaskID == "" {
+		return nil, nil
+	}
+
+	locators, err := m.getLocators(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(locators) == 0 {
+		m.logger.Warnf("task has no test locators, running all the tests of the interrupted task again")
+		return nil, nil
+	}
+	if checkpoint.Coverage && payload.CollectCoverage {
+		if err := m.restoreCoverage(ctx, prefix+coverageFileName, coverageDir); err != nil {
+			return nil, fmt.Errorf("failed to restore the coverage of the checkpoint: %v", err)
+		}
+	}
+	checkpoint.Remaining = checkpoint.RemainingLocators(locators)
+	payload.Locators = strings.Join(checkpoint.Remaining, global.TestLocatorsDelimiter)
+	payload.LocatorAddress = ""
+	m.logger.Infof("resuming task with %d completed tests, %d of %d test locators remaining",
+		len(checkpoint.TestPayload), len(checkpoint.Remaining), len(locators))
+	return checkpoint, nil
+}
+
+// Save uploads the checkpoint after the archive of the partial coverage, so that a stored checkpoint
+// always has its coverage.
+func (m *manager) Save(ctx context.Context, payload *core.Payload, checkpoint *core.Checkpoint, coverageDir string) error {
+	prefix := blobPrefix(payload)
+	if checkpoint.Coverage {
+		stored, err := m.storeCoverage(ctx, prefix+coverageFileName, coverageDir)
+		if err != nil {
+			return fmt.Errorf("failed to store the coverage of the checkpoint: %v", err)
+		}
+		checkpoint.Coverage = stored
+	}
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	_, err = m.azureClient.Create(ctx, prefix+checkpointFileName, bytes.NewReader(body), checkpointMimeType)
+	return err
+}
+
+// Clear overwrites the checkpoint with an empty one, as blobs can not be deleted using the storage client
+func (m *manager) Clear(ctx context.Context, payload *core.Payload) error {
+	_, err := m.azureClient.Create(ctx, blobPrefix(payload)+checkpointFileName, strings.NewReader("{}"), checkpointMimeType)
+	return err
+}
+
+// storeCoverage uploads the archive of the coverage directory, it returns false if there is no coverage
+func (m *manager) storeCoverage(ctx context.Context, blobPath, coverageDir string) (bool, error) {
+	entries, err := ioutil.ReadDir(coverageDir)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return false, nil
+	}
+	archivePath := filepath.Join(global.ScratchDir, "checkpoint-"+coverageFileName)
+	defer os.Remove(archivePath)
+	if err := m.compressor.Compress(ctx, archivePath, true, coverageDir, "."); err != nil {
+		return false, err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := m.azureClient.Create(ctx, blobPath, f, archiveMimeType); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *manager) restoreCoverage(ctx context.Context, blobPath, coverageDir string) error {
+	reader, err := m.azureClient.Find(ctx, blobPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	archivePath := filepath.Join(global.ScratchDir, "checkpoint-"+coverageFileName)
+	defer os.Remove(archivePath)
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, reader); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(coverageDir, global.DirectoryPermissions); err != nil {
+		return err
+	}
+	return m.compressor.Decompress(ctx, archivePath, true, coverageDir)
+}
+
+// getLocators returns the test locators of the locator file or else of the payload
+func (m *manager) getLocators(ctx context.Context, payload *core.Payload) ([]string, error) {
+	locators := payload.Locators
+	if payload.LocatorAddress != "" {
+		u, err := url.Parse(payload.LocatorAddress)
+		if err != nil {
+			return nil, err
+		}
+		blobPath := strings.Replace(u.Path, fmt.Sprintf("/%s/", core.PayloadContainer), "", -1)
+		sasURL, err := m.azureClient.GetSASURL(ctx, blobPath, core.PayloadContainer)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := m.azureClient.FindUsingSASUrl(ctx, sasURL)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		raw, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		locators = string(raw)
+	}
+	list := make([]string, 0)
+	for _, line := range strings.Split(locators, "\n") {
+		for _, locator := range strings.Split(line, global.TestLocatorsDelimiter) {
+			if locator = strings.TrimSpace(locator); locator != "" {
+				list = append(list, locator)
+			}
+		}
+	}
+	return list, nil
+}
+
+func blobPrefix(payload *core.Payload) string {
+	return fmt.Sprintf("checkpoints/%s/%s/%s/", payload.OrgID, payload.RepoID, payload.TaskID)
+}
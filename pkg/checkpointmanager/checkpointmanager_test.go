@@ -0,0 +1,57 @@
+package checkpointmanager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/compression"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	store, err := storage.NewLocalStore(t.TempDir(), "checkpoints", logger)
+	assert.Nil(t, err)
+	compressor, err := compression.New(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	m := New(store, compressor, logger)
+	ctx := context.Background()
+
+	payload := &core.Payload{OrgID: "org", RepoID: "repo", TaskID: "task", Locators: "a.test.js##works#TAS#a.test.js##fails#TAS#b.test.js", CollectCoverage: true}
+	checkpoint, err := m.Load(ctx, payload, t.TempDir())
+	assert.Nil(t, err)
+	assert.Nil(t, checkpoint)
+
+	coverageDir := t.TempDir()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(coverageDir, "coverage-final.json"), []byte("{}"), 0644))
+	assert.Nil(t, m.Save(ctx, payload, &core.Checkpoint{
+		TaskID:      "task",
+		TestPayload: []core.TestPayload{{TestID: "1", Filelocator: "a.test.js##works", Status: "passed"}},
+		Coverage:    true,
+	}, coverageDir))
+
+	restoreDir := filepath.Join(t.TempDir(), "coverage")
+	checkpoint, err = m.Load(ctx, payload, restoreDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.test.js##fails", "b.test.js"}, checkpoint.Remaining)
+	assert.Equal(t, "a.test.js##fails#TAS#b.test.js", payload.Locators)
+	_, err = os.Stat(filepath.Join(restoreDir, "coverage-final.json"))
+	assert.Nil(t, err)
+
+	result := &core.ExecutionResult{TestPayload: []core.TestPayload{{TestID: "2", Filelocator: "a.test.js##fails", Status: "failed"}}}
+	checkpoint.Merge(result)
+	assert.Len(t, result.TestPayload, 2)
+
+	assert.Nil(t, m.Clear(ctx, payload))
+	checkpoint, err = m.Load(ctx, payload, restoreDir)
+	assert.Nil(t, err)
+	assert.Nil(t, checkpoint)
+}
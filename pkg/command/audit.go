@@ -0,0 +1,145 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/logstream"
+)
+
+// auditLogName is the name of the blob of the audit log, next to the logs of the commands of the task
+const auditLogName = "audit.jsonl"
+
+// auditLog appends the records of the spawned commands to a JSONL file, it is safe for concurrent use
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// write appends the record to the log, the file is truncated when the first record of the process is written
+func (a *auditLog) write(record *core.CommandRecord) error {
+	if a.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+			return err
+		}
+		if a.file, err = os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644); err != nil {
+			return err
+		}
+	}
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// newCommandRecord returns the audit record of the exited command, the secrets are masked in the arguments
+func (m *manager) newCommandRecord(ctx context.Context,
+	commandType core.CommandType,
+	cmd *exec.Cmd,
+	startTime time.Time,
+	secretData map[string]string,
+	err error) *core.CommandRecord {
+	record := &core.CommandRecord{
+		Time:     startTime,
+		Stage:    core.StageFromContext(ctx),
+		Type:     commandType,
+		Command:  m.mask(cmd.Path, secretData),
+		Dir:      cmd.Dir,
+		ExitCode: -1,
+		Duration: time.Since(startTime).Milliseconds(),
+	}
+	if len(cmd.Args) > 1 {
+		record.Args = make([]string, 0, len(cmd.Args)-1)
+		for _, arg := range cmd.Args[1:] {
+			record.Args = append(record.Args, m.mask(arg, secretData))
+		}
+	}
+	if cmd.Process != nil {
+		record.PID = cmd.Process.Pid
+	}
+	var exitErr *exec.ExitError
+	if cmd.ProcessState != nil {
+		record.ExitCode = cmd.ProcessState.ExitCode()
+	} else if errors.As(err, &exitErr) {
+		record.ExitCode = exitErr.ExitCode()
+	}
+	if err != nil {
+		record.Error = m.mask(err.Error(), secretData)
+	}
+	return record
+}
+
+func (m *manager) mask(s string, secretData map[string]string) string {
+	s = logstream.Mask(s, secretData)
+	if m.redactor != nil {
+		s = m.redactor.Redact(s)
+	}
+	return s
+}
+
+// RecordCommand adds the exited command to the audit log, failures to write the log are only logged
+func (m *manager) RecordCommand(ctx context.Context,
+	commandType core.CommandType,
+	cmd *exec.Cmd,
+	startTime time.Time,
+	secretData map[string]string,
+	err error) {
+	record := m.newCommandRecord(ctx, commandType, cmd, startTime, secretData, err)
+	if writeErr := m.audit.write(record); writeErr != nil {
+		m.logger.Errorf("failed to write the audit record of command %s, error: %v", commandType, writeErr)
+	}
+}
+
+// HandleEvent uploads the audit log once the task completes, failures are only logged
+func (m *manager) HandleEvent(ctx context.Context, event *core.Event) {
+	if event.Type != core.EventTaskCompleted || event.Task == nil {
+		return
+	}
+	if err := m.uploadAuditLog(ctx, event.Task); err != nil {
+		m.logger.Errorf("failed to upload the audit log of the commands: %v", err)
+	}
+}
+
+// uploadAuditLog uploads the audit log of the commands next to the command logs of the task
+func (m *manager) uploadAuditLog(ctx context.Context, task *core.TaskPayload) error {
+	m.audit.mu.Lock()
+	defer m.audit.mu.Unlock()
+	if m.audit.file == nil {
+		m.logger.Debugf("no commands were audited, the audit log is not uploaded")
+		return nil
+	}
+	if err := m.audit.file.Sync(); err != nil {
+		return err
+	}
+	f, err := os.Open(m.audit.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	blobPath := fmt.Sprintf("%s/%s/%s/%s", task.OrgID, task.BuildID, os.Getenv("TASK_ID"), auditLogName)
+	sasURL, err := m.azureClient.GetSASURL(ctx, blobPath, core.LogsContainer)
+	if err != nil {
+		return err
+	}
+	if _, err := m.azureClient.CreateUsingSASURL(ctx, sasURL, f, "application/x-ndjson"); err != nil {
+		return err
+	}
+	m.logger.Debugf("uploaded the audit log of the commands to %s", blobPath)
+	return nil
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/logstream"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func readRecords(t *testing.T, path string) []core.CommandRecord {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	var records []core.CommandRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record core.CommandRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRecordCommand(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	if err != nil {
+		t.Fatalf("could not instantiate logger: %v", err)
+	}
+	redactor := logstream.NewRedactor()
+	redactor.Add("vault-token")
+	path := filepath.Join(t.TempDir(), "audit", "commands.jsonl")
+	m := &manager{logger: logger, redactor: redactor, audit: &auditLog{path: path}}
+	ctx := context.Background()
+
+	// the test binary exits successfully without running any test
+	cmd := exec.Command(os.Args[0], "-test.run=^$", "--", "--token=repo-secret", "--vault=vault-token")
+	dir := t.TempDir()
+	cmd.Dir = dir
+	startTime := time.Now()
+	err = cmd.Run()
+	m.RecordCommand(ctx, core.Execution, cmd, startTime, map[string]string{"TOKEN": "repo-secret"}, err)
+
+	cmd = exec.Command(filepath.Join(t.TempDir(), "missing"))
+	err = cmd.Start()
+	m.RecordCommand(ctx, core.Discovery, cmd, time.Now(), nil, err)
+
+	records := readRecords(t, path)
+	assert.Len(t, records, 2)
+	assert.Equal(t, core.Execution, records[0].Type)
+	assert.Equal(t, []string{"-test.run=^$", "--", "--token=****************", "--vault=****************"}, records[0].Args)
+	assert.Equal(t, 0, records[0].ExitCode)
+	assert.NotZero(t, records[0].PID)
+	assert.Equal(t, dir, records[0].Dir)
+	assert.Equal(t, -1, records[1].ExitCode)
+	assert.NotEmpty(t, records[1].Error)
+
+	// the log of a previous run is truncated
+	m = &manager{logger: logger, audit: &auditLog{path: path}}
+	m.RecordCommand(ctx, core.PreRun, exec.Command("true"), time.Now(), nil, nil)
+	assert.Len(t, readRecords(t, path), 1)
+}
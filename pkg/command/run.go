@@ -5,28 +5,49 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/logstream"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type manager struct {
 	logger       lumber.Logger
 	secretParser core.SecretParser
 	azureClient  core.AzureClient
+	redactor     *logstream.Redactor
+	shell        *shell
+	audit        *auditLog
 }
 
-// NewExecutionManager returns new instance of manger
-func NewExecutionManager(secretParser core.SecretParser,
+// NewExecutionManager returns new instance of manger, the commands are run by the configured shell or the
+// default shell of the OS. The spawned commands are recorded in the audit log of the configuration.
+func NewExecutionManager(cfg *config.NucleusConfig,
+	secretParser core.SecretParser,
 	azureClient core.AzureClient,
-	logger lumber.Logger) core.ExecutionManager {
+	redactor *logstream.Redactor,
+	logger lumber.Logger) (core.ExecutionManager, error) {
+	sh, err := newShell(cfg.Shell)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("running the commands with %s", sh.name)
 	return &manager{logger: logger,
 		secretParser: secretParser,
-		azureClient:  azureClient}
+		azureClient:  azureClient,
+		redactor:     redactor,
+		shell:        sh,
+		audit:        &auditLog{path: cfg.AuditLogPath}}, nil
 }
 
 // ExecuteUserCommands executes user commands
@@ -34,12 +55,11 @@ func (m *manager) ExecuteUserCommands(ctx context.Context,
 	commandType core.CommandType,
 	payload *core.Payload,
 	runConfig *core.Run,
-	secretData map[string]string) error {
-	script, err := m.createScript(runConfig.Commands, secretData)
-	if err != nil {
-		return err
-	}
-	envVars, err := m.GetEnvVariables(runConfig.EnvMap, secretData)
+	secretData map[string]string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "command.ExecuteUserCommands", attribute.String("command.type", string(commandType)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	script, env, err := m.createRunScript(runConfig, secretData)
 	if err != nil {
 		return err
 	}
@@ -47,7 +67,7 @@ func (m *manager) ExecuteUserCommands(ctx context.Context,
 	azureReader, azureWriter := io.Pipe()
 	defer azureWriter.Close()
 
-	blobPath := fmt.Sprintf("%s/%s/%s/%s.log", payload.OrgID, payload.BuildID, os.Getenv("TASK_ID"), commandType)
+	blobPath := fmt.Sprintf("%s/%s/%s/%s.log", payload.OrgID, payload.BuildID, os.Getenv("TASK_ID"), core.LogName(commandType, runConfig.Dir))
 	errChan := m.StoreCommandLogs(ctx, blobPath, azureReader)
 
 	logWriter := lumber.NewWriter(m.logger)
@@ -55,18 +75,23 @@ func (m *manager) ExecuteUserCommands(ctx context.Context,
 	multiWriter := io.MultiWriter(logWriter, azureWriter)
 	maskWriter := logstream.NewMasker(multiWriter, secretData)
 
-	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", script)
-	cmd.Dir = global.RepoDir
-	cmd.Env = envVars
+	cmd := m.shell.command(ctx, script)
+	cmd.Dir = filepath.Join(global.RepoDir, runConfig.Dir)
+	cmd.Env = env
 	cmd.Stdout = maskWriter
 	cmd.Stderr = maskWriter
+	utils.SetProcessGroup(cmd)
 
+	startTime := time.Now()
 	if startErr := cmd.Start(); startErr != nil {
+		m.RecordCommand(ctx, commandType, cmd, startTime, secretData, startErr)
 		m.logger.Errorf("failed to start command: %s, error: %v", commandType, startErr)
 		return startErr
 	}
 	m.logger.Debugf("command of type %s started with id %d", commandType, cmd.Process.Pid)
-	if execErr := cmd.Wait(); execErr != nil {
+	execErr := utils.WaitProcessGroup(ctx, cmd)
+	m.RecordCommand(ctx, commandType, cmd, startTime, secretData, execErr)
+	if execErr != nil {
 		m.logger.Errorf("command %s, exited with error: %v", commandType, execErr)
 		return execErr
 	}
@@ -83,9 +108,12 @@ func (m *manager) ExecuteInternalCommands(ctx context.Context,
 	commandType core.CommandType,
 	commands []string,
 	cwd string,
-	envMap, secretData map[string]string) error {
+	envMap, secretData map[string]string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "command.ExecuteInternalCommands", attribute.String("command.type", string(commandType)))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	argsString := strings.Join(commands, " ")
-	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", argsString)
+	cmd := m.shell.command(ctx, argsString)
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
@@ -93,30 +121,66 @@ func (m *manager) ExecuteInternalCommands(ctx context.Context,
 	defer logWriter.Close()
 	cmd.Stderr = logWriter
 	cmd.Stdout = logWriter
+	utils.SetProcessGroup(cmd)
 	m.logger.Debugf("Executing command: %s, of type %s", cmd.String(), commandType)
-	if err := cmd.Run(); err != nil {
+	startTime := time.Now()
+	if err = cmd.Start(); err == nil {
+		err = utils.WaitProcessGroup(ctx, cmd)
+	}
+	m.RecordCommand(ctx, commandType, cmd, startTime, secretData, err)
+	if err != nil {
 		m.logger.Errorf("command %s of type %s failed with error: %v", cmd.String(), commandType, err)
 		return err
 	}
 	return nil
 }
 
-// GetEnvVariables gives set environment variable
-func (m *manager) GetEnvVariables(envMap, secretData map[string]string) ([]string, error) {
+// GetEnvVariables returns the environment of the process merged with the env maps, the later maps override the
+// earlier ones. The variables of each map are applied in the order of their names, so that the environment is
+// the same in every run.
+func (m *manager) GetEnvVariables(secretData map[string]string, envMaps ...map[string]string) ([]string, error) {
 	envVars := os.Environ()
-	for k, v := range envMap {
-		val, err := m.secretParser.SubstituteSecret(v, secretData)
-		if err != nil {
-			return nil, err
+	index := make(map[string]int, len(envVars))
+	for i, envVar := range envVars {
+		index[envKey(strings.SplitN(envVar, "=", 2)[0])] = i
+	}
+	for _, envMap := range envMaps {
+		names := make([]string, 0, len(envMap))
+		for name := range envMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			val, err := m.secretParser.SubstituteSecret(envMap[name], secretData)
+			if err != nil {
+				return nil, err
+			}
+			envVar := fmt.Sprintf("%s=%s", name, val)
+			if i, ok := index[envKey(name)]; ok {
+				envVars[i] = envVar
+				continue
+			}
+			index[envKey(name)] = len(envVars)
+			envVars = append(envVars, envVar)
 		}
-		envVars = append(envVars, fmt.Sprintf("%s=%s", k, val))
 	}
 	return envVars, nil
 }
 
-// StoreCommandLogs stores the command logs to blob
+// envKey returns the name of the variable as compared by the OS, the names are case insensitive on windows
+func envKey(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// StoreCommandLogs stores the command logs to blob, secrets are masked before upload
 func (m *manager) StoreCommandLogs(ctx context.Context, blobPath string, reader io.Reader) <-chan error {
 	errChan := make(chan error, 1)
+	if m.redactor != nil {
+		reader = m.redactor.NewReader(reader)
+	}
 	go func() {
 		sasURL, err := m.azureClient.GetSASURL(ctx, blobPath, core.LogsContainer)
 		if err != nil {
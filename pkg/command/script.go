@@ -1,35 +1,61 @@
 package command
 
 import (
-	"bytes"
-	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
 )
 
-// CreateScript converts a slice of individual shell commands to
-// a shell script.
-func (m *manager) createScript(commands []string, secretData map[string]string) (string, error) {
-	buf := new(bytes.Buffer)
-	fmt.Fprintln(buf)
-	fmt.Fprint(buf, optionScript)
-	fmt.Fprintln(buf)
-	var err error
-	for _, command := range commands {
-		escaped := fmt.Sprintf("%q", command)
-		escaped = strings.Replace(escaped, "$", `\$`, -1)
-		if len(secretData) > 0 {
-			command, err = m.secretParser.SubstituteSecret(command, secretData)
+// createRunScript converts the commands of the run to a single script run with the env of the run, so that
+// the state of the shell, e.g. the working directory and the exported variables, is shared by the commands.
+// The env of a command is scoped to the command in the script.
+func (m *manager) createRunScript(runConfig *core.Run, secretData map[string]string) (script string, env []string, err error) {
+	runEnv := append(append([]map[string]string{}, runConfig.ParentEnv...), runConfig.EnvMap)
+	if env, err = m.GetEnvVariables(secretData, runEnv...); err != nil {
+		return "", nil, err
+	}
+	values := make(map[string]string, len(env))
+	for _, envVar := range env {
+		parts := strings.SplitN(envVar, "=", 2)
+		values[envKey(parts[0])] = parts[1]
+	}
+
+	lines := make([]scriptLine, 0, len(runConfig.Commands))
+	for _, command := range runConfig.Commands {
+		line, err := m.createLine(command.Run, secretData)
+		if err != nil {
+			return "", nil, err
+		}
+		names := make([]string, 0, len(command.Env))
+		for name := range command.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := m.secretParser.SubstituteSecret(command.Env[name], secretData)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
+			previous, defined := values[envKey(name)]
+			line.env = append(line.env, scopedEnv{name: name, value: value, previous: previous, defined: defined})
+		}
+		lines = append(lines, line)
+	}
+	return m.shell.script(lines), env, nil
+}
+
+// createLine returns the script line of the command, the command is traced before the secrets are substituted
+func (m *manager) createLine(command string, secretData map[string]string) (scriptLine, error) {
+	line := scriptLine{trace: command, command: command}
+	if len(secretData) > 0 {
+		var err error
+		line.command, err = m.secretParser.SubstituteSecret(command, secretData)
+		if err != nil {
+			return scriptLine{}, err
 		}
-		buf.WriteString(fmt.Sprintf(
-			traceScript,
-			escaped,
-			command,
-		))
 	}
-	return buf.String(), nil
+	return line, nil
 }
 
 // optionScript is a helper script this is added to the build
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// secretParser substitutes ${{ secrets.NAME }} with the value of NAME
+type secretParser struct {
+	core.SecretParser
+}
+
+func (secretParser) SubstituteSecret(command string, secretData map[string]string) (string, error) {
+	for name, value := range secretData {
+		command = strings.ReplaceAll(command, "${{ secrets."+name+" }}", value)
+	}
+	return command, nil
+}
+
+func newTestManager(t *testing.T) *manager {
+	sh, err := newShell(shellCmd)
+	assert.Nil(t, err)
+	return &manager{secretParser: secretParser{}, shell: sh}
+}
+
+func lookupEnv(env []string, name string) []string {
+	values := make([]string, 0, 1)
+	for _, envVar := range env {
+		if strings.HasPrefix(envVar, name+"=") {
+			values = append(values, strings.TrimPrefix(envVar, name+"="))
+		}
+	}
+	return values
+}
+
+func TestGetEnvVariables(t *testing.T) {
+	t.Setenv("TAS_TEST_SHARED", "os")
+	m := newTestManager(t)
+	env, err := m.GetEnvVariables(map[string]string{"TOKEN": "secret"},
+		map[string]string{"TAS_TEST_SHARED": "global", "TAS_TEST_GLOBAL": "global"},
+		nil,
+		map[string]string{"TAS_TEST_SHARED": "stage", "TAS_TEST_TOKEN": "${{ secrets.TOKEN }}"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stage"}, lookupEnv(env, "TAS_TEST_SHARED"))
+	assert.Equal(t, []string{"global"}, lookupEnv(env, "TAS_TEST_GLOBAL"))
+	assert.Equal(t, []string{"secret"}, lookupEnv(env, "TAS_TEST_TOKEN"))
+
+	again, err := m.GetEnvVariables(map[string]string{"TOKEN": "secret"},
+		map[string]string{"TAS_TEST_SHARED": "global", "TAS_TEST_GLOBAL": "global"},
+		nil,
+		map[string]string{"TAS_TEST_SHARED": "stage", "TAS_TEST_TOKEN": "${{ secrets.TOKEN }}"})
+	assert.Nil(t, err)
+	assert.Equal(t, env, again)
+}
+
+func TestCreateRunScript(t *testing.T) {
+	m := newTestManager(t)
+	run := &core.Run{
+		Commands: []core.Command{
+			{Run: "npm ci"},
+			{Run: "npm run lint"},
+			{Run: "npm run build", Env: map[string]string{"TAS_TEST_SCOPE": "command", "TAS_TEST_BUILD": "${{ secrets.TOKEN }}"}},
+			{Run: "npm run docs"},
+		},
+		EnvMap:    map[string]string{"TAS_TEST_SCOPE": "run"},
+		ParentEnv: []map[string]string{{"TAS_TEST_SCOPE": "global", "TAS_TEST_GLOBAL": "global"}},
+	}
+	// all the commands run in a single script, the env of a command is reset after it
+	script, env, err := m.createRunScript(run, map[string]string{"TOKEN": "secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, `(npm ci) && (npm run lint) && (set "TAS_TEST_BUILD=secret") && (set "TAS_TEST_SCOPE=command") && `+
+		`(npm run build) && (set "TAS_TEST_BUILD=" || ver >nul) && (set "TAS_TEST_SCOPE=run") && (npm run docs)`, script)
+	assert.Equal(t, []string{"run"}, lookupEnv(env, "TAS_TEST_SCOPE"))
+	assert.Equal(t, []string{"global"}, lookupEnv(env, "TAS_TEST_GLOBAL"))
+	assert.Empty(t, lookupEnv(env, "TAS_TEST_BUILD"))
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// names of the shells running the commands
+const (
+	shellBash       = "bash"
+	shellPowerShell = "powershell"
+	shellPwsh       = "pwsh"
+	shellCmd        = "cmd"
+)
+
+// scriptLine is a command of a script, trace is the command printed before it is run
+type scriptLine struct {
+	trace   string
+	command string
+	// env is set for the command only
+	env []scopedEnv
+}
+
+// scopedEnv is a variable of the env of a single command. previous is the value of the variable in the env of the
+// script, which cmd restores after the command as it can not scope the variable.
+type scopedEnv struct {
+	name     string
+	value    string
+	previous string
+	defined  bool
+}
+
+// shell runs the commands, the scripts of the user commands exit on the first failing command
+type shell struct {
+	name string
+	// path of the executable and the args preceding the script
+	path string
+	args []string
+	// script joins the lines of the user commands
+	script func(lines []scriptLine) string
+}
+
+// newShell returns the shell with the given name, the default shell of the OS is returned if name is empty:
+// bash on linux and macOS, PowerShell on windows or cmd if PowerShell is not installed
+func newShell(name string) (*shell, error) {
+	if name == "" {
+		name = defaultShell()
+	}
+	switch name {
+	case shellBash:
+		path := "/bin/bash"
+		if runtime.GOOS == "windows" {
+			path = "bash"
+		}
+		return &shell{name: name, path: path, args: []string{"-c"}, script: bashScript}, nil
+	case shellPowerShell, shellPwsh:
+		return &shell{name: name, path: name, args: []string{"-NoLogo", "-NoProfile", "-NonInteractive", "-Command"},
+			script: powerShellScript}, nil
+	case shellCmd:
+		return &shell{name: name, path: name, args: []string{"/d", "/s", "/c"}, script: cmdScript}, nil
+	default:
+		return nil, fmt.Errorf("unsupported shell %s, expected one of %s, %s, %s or %s",
+			name, shellBash, shellPowerShell, shellPwsh, shellCmd)
+	}
+}
+
+func defaultShell() string {
+	if runtime.GOOS != "windows" {
+		return shellBash
+	}
+	for _, name := range []string{shellPwsh, shellPowerShell} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return shellCmd
+}
+
+// command returns the command running the script in the shell
+func (s *shell) command(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, s.path, append(s.args, script)...)
+}
+
+// bashScript exits on error and traces each command, a command with an env is run in a subshell exporting the env
+func bashScript(lines []scriptLine) string {
+	buf := new(strings.Builder)
+	fmt.Fprintln(buf)
+	fmt.Fprint(buf, optionScript)
+	fmt.Fprintln(buf)
+	for _, line := range lines {
+		escaped := fmt.Sprintf("%q", line.trace)
+		escaped = strings.Replace(escaped, "$", `\$`, -1)
+		command := line.command
+		if len(line.env) > 0 {
+			scoped := new(strings.Builder)
+			fmt.Fprintln(scoped, "(")
+			for _, env := range line.env {
+				fmt.Fprintf(scoped, "export %s=%s\n", env.name, bashQuote(env.value))
+			}
+			fmt.Fprintf(scoped, "%s\n)", command)
+			command = scoped.String()
+		}
+		fmt.Fprintf(buf, traceScript, escaped, command)
+	}
+	return buf.String()
+}
+
+func bashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellScript stops on the errors of the cmdlets and on the non zero exit codes of the native commands,
+// which do not stop the script otherwise
+// The env of a command is restored to its previous values once the command completes.
+func powerShellScript(lines []scriptLine) string {
+	buf := new(strings.Builder)
+	fmt.Fprintln(buf, "$ErrorActionPreference = 'Stop'")
+	for _, line := range lines {
+		fmt.Fprintf(buf, "\nWrite-Output '+ %s'\n", powerShellQuote(line.trace))
+		if len(line.env) == 0 {
+			fmt.Fprintf(buf, "%s\nif ($LASTEXITCODE) { exit $LASTEXITCODE }\n", line.command)
+			continue
+		}
+		fmt.Fprintln(buf, "$tasScopedEnv = @{}")
+		for _, env := range line.env {
+			name := powerShellQuote(env.name)
+			fmt.Fprintf(buf, "$tasScopedEnv['%s'] = [Environment]::GetEnvironmentVariable('%s')\n", name, name)
+			fmt.Fprintf(buf, "[Environment]::SetEnvironmentVariable('%s', '%s')\n", name, powerShellQuote(env.value))
+		}
+		fmt.Fprintf(buf, "try {\n%s\nif ($LASTEXITCODE) { exit $LASTEXITCODE }\n} finally {\n", line.command)
+		fmt.Fprintln(buf, "foreach ($name in $tasScopedEnv.Keys) { [Environment]::SetEnvironmentVariable($name, $tasScopedEnv[$name]) }")
+		fmt.Fprintln(buf, "}")
+	}
+	return buf.String()
+}
+
+func powerShellQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// cmdScript chains the commands as cmd only runs a single line, the commands are not traced as echo would
+// require escaping the special characters of each command. The env of a command is set before the command
+// and reset to the env of the script after it.
+func cmdScript(lines []scriptLine) string {
+	commands := make([]string, 0, len(lines))
+	for _, line := range lines {
+		for _, env := range line.env {
+			commands = append(commands, fmt.Sprintf(`(set "%s=%s")`, env.name, env.value))
+		}
+		commands = append(commands, "("+line.command+")")
+		for _, env := range line.env {
+			if env.defined {
+				commands = append(commands, fmt.Sprintf(`(set "%s=%s")`, env.name, env.previous))
+				continue
+			}
+			// set fails if the variable to remove is not defined
+			commands = append(commands, fmt.Sprintf(`(set "%s=" || ver >nul)`, env.name))
+		}
+	}
+	return strings.Join(commands, " && ")
+}
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewShell(t *testing.T) {
+	sh, err := newShell("")
+	assert.Nil(t, err)
+	if runtime.GOOS == "windows" {
+		assert.Contains(t, []string{shellPwsh, shellPowerShell, shellCmd}, sh.name)
+	} else {
+		assert.Equal(t, shellBash, sh.name)
+	}
+
+	sh, err = newShell(shellPwsh)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pwsh", "-NoLogo", "-NoProfile", "-NonInteractive", "-Command", "exit 1"},
+		sh.command(context.Background(), "exit 1").Args)
+
+	_, err = newShell("zsh")
+	assert.NotNil(t, err)
+}
+
+func TestShellScripts(t *testing.T) {
+	lines := []scriptLine{{trace: "echo $HOME", command: "echo $HOME"}, {trace: "it's ${{ secrets.TOKEN }}", command: "it's x"}}
+
+	assert.Equal(t, "\n\nset -e\n\n\necho + \"echo \\$HOME\"\necho $HOME\n\necho + \"it's \\${{ secrets.TOKEN }}\"\nit's x\n",
+		bashScript(lines))
+	assert.Equal(t, `$ErrorActionPreference = 'Stop'
+
+Write-Output '+ echo $HOME'
+echo $HOME
+if ($LASTEXITCODE) { exit $LASTEXITCODE }
+
+Write-Output '+ it''s ${{ secrets.TOKEN }}'
+it's x
+if ($LASTEXITCODE) { exit $LASTEXITCODE }
+`, powerShellScript(lines))
+	assert.Equal(t, "(echo $HOME) && (it's x)", cmdScript(lines))
+}
+
+func TestScopedEnvScripts(t *testing.T) {
+	lines := []scriptLine{{trace: "npm run build", command: "npm run build", env: []scopedEnv{{name: "TOKEN", value: "it's"}}}}
+	assert.Equal(t, "\n\nset -e\n\n\necho + \"npm run build\"\n(\nexport TOKEN='it'\\''s'\nnpm run build\n)\n", bashScript(lines))
+	assert.Equal(t, `$ErrorActionPreference = 'Stop'
+
+Write-Output '+ npm run build'
+$tasScopedEnv = @{}
+$tasScopedEnv['TOKEN'] = [Environment]::GetEnvironmentVariable('TOKEN')
+[Environment]::SetEnvironmentVariable('TOKEN', 'it''s')
+try {
+npm run build
+if ($LASTEXITCODE) { exit $LASTEXITCODE }
+} finally {
+foreach ($name in $tasScopedEnv.Keys) { [Environment]::SetEnvironmentVariable($name, $tasScopedEnv[$name]) }
+}
+`, powerShellScript(lines))
+}
+
+func TestBashScriptState(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	sh, err := newShell(shellBash)
+	assert.Nil(t, err)
+	// the state of the shell is shared by the commands, the env of a command is scoped to it
+	out, err := sh.command(context.Background(), bashScript([]scriptLine{
+		{trace: "export A=one", command: "export A=one"},
+		{trace: "cd /", command: "cd /"},
+		{trace: "echo $A $B", command: "echo $A $B", env: []scopedEnv{{name: "B", value: "two"}}},
+		{trace: "echo $A ${B:-unset} $PWD", command: "echo $A ${B:-unset} $PWD"},
+	})).CombinedOutput()
+	assert.Nil(t, err)
+	assert.Equal(t, "+ export A=one\n+ cd /\n+ echo $A $B\none two\n+ echo $A ${B:-unset} $PWD\none unset /\n", string(out))
+}
+
+func TestBashScriptExitsOnError(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	sh, err := newShell(shellBash)
+	assert.Nil(t, err)
+	out, err := sh.command(context.Background(), bashScript([]scriptLine{
+		{trace: "echo one", command: "echo one"},
+		{trace: "false", command: "false"},
+		{trace: "echo two", command: "echo two"},
+	})).CombinedOutput()
+	assert.NotNil(t, err)
+	assert.Equal(t, "+ echo one\none\n+ false\n", string(out))
+}
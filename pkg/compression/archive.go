@@ -0,0 +1,293 @@
+package compression
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const bufferSize = 1 << 20
+
+type archiver struct {
+	codec  Codec
+	logger lumber.Logger
+}
+
+// New returns a compressor which creates tar archives using the configured codec.
+// Archives of any supported codec are decompressed irrespective of the configuration.
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (core.Compressor, error) {
+	codec, err := NewCodec(cfg.Compression.Codec, cfg.Compression.Level)
+	if err != nil {
+		logger.Errorf("failed to create compression codec, error: %v", err)
+		return nil, err
+	}
+	return &archiver{codec: codec, logger: logger}, nil
+}
+
+// Compress archives the files relative to workingDirectory into compressedFileName,
+// relative file names are resolved from the repository directory.
+// If preservePath is false the leading "/" and the drive of absolute paths are removed.
+// The names of the entries use forward slashes on all the OS.
+func (a *archiver) Compress(ctx context.Context, compressedFileName string, preservePath bool, workingDirectory string, filesToCompress ...string) error {
+	if !filepath.IsAbs(compressedFileName) {
+		compressedFileName = filepath.Join(global.RepoDir, compressedFileName)
+	}
+	start := time.Now()
+	if err := a.compress(ctx, compressedFileName, preservePath, workingDirectory, filesToCompress); err != nil {
+		a.logger.Errorf("error while %s compression %v", a.codec.Name(), err)
+		os.Remove(compressedFileName)
+		return err
+	}
+	a.logger.Debugf("compressed %d items to %s using %s in %s", len(filesToCompress), compressedFileName, a.codec.Name(), time.Since(start))
+	return nil
+}
+
+func (a *archiver) compress(ctx context.Context, compressedFileName string, preservePath bool, workingDirectory string, files []string) error {
+	f, err := os.Create(compressedFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriterSize(f, bufferSize)
+	fw, err := newFrameWriter(a.codec, bw)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(fw)
+	for _, file := range files {
+		if err := a.addToArchive(ctx, tw, workingDirectory, file, preservePath); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (a *archiver) addToArchive(ctx context.Context, tw *tar.Writer, workingDirectory, file string, preservePath bool) error {
+	src := file
+	if !filepath.IsAbs(file) {
+		src = filepath.Join(workingDirectory, file)
+	}
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// names are relative to the working directory as given, absolute paths are kept as is
+		name := p
+		if !filepath.IsAbs(file) {
+			rel, err := filepath.Rel(src, p)
+			if err != nil {
+				return err
+			}
+			name = filepath.Join(file, rel)
+		}
+		if !preservePath {
+			// the drive of the windows paths is removed as well
+			name = strings.TrimLeft(filepath.ToSlash(strings.TrimPrefix(name, filepath.VolumeName(name))), "/")
+		} else {
+			name = filepath.ToSlash(name)
+		}
+		return writeEntry(tw, p, name, info)
+	})
+}
+
+func writeEntry(tw *tar.Writer, p, name string, info os.FileInfo) error {
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
+		// sockets, devices and pipes are not archived
+		return nil
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	// access and change times differ in every run, which breaks deduplication of the archives
+	hdr.AccessTime = time.Time{}
+	hdr.ChangeTime = time.Time{}
+	hdr.Format = tar.FormatPAX
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Decompress extracts the archive at filePath into workingDirectory.
+// If preservePath is true, absolute paths are extracted as is.
+func (a *archiver) Decompress(ctx context.Context, filePath string, preservePath bool, workingDirectory string) error {
+	start := time.Now()
+	if err := a.decompress(ctx, filePath, preservePath, workingDirectory); err != nil {
+		a.logger.Errorf("error while decompression of %s %v", filePath, err)
+		return err
+	}
+	a.logger.Debugf("decompressed %s in %s", filePath, time.Since(start))
+	return nil
+}
+
+func (a *archiver) decompress(ctx context.Context, filePath string, preservePath bool, workingDirectory string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, bufferSize)
+	codec, err := detectCodec(br)
+	if err != nil {
+		return err
+	}
+	r, err := codec.NewReader(br)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	type dirMode struct {
+		path string
+		mode os.FileMode
+	}
+	// directory modes are applied at the end so that read only directories can be populated
+	dirs := make([]dirMode, 0)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		target, err := extractPath(workingDirectory, hdr.Name, preservePath)
+		if err != nil {
+			return err
+		}
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{path: target, mode: mode.Perm()})
+		case tar.TypeReg, tar.TypeRegA:
+			if err := prepareTarget(target); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, mode.Perm()); err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := prepareTarget(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			linkTarget, err := extractPath(workingDirectory, hdr.Linkname, preservePath)
+			if err != nil {
+				return err
+			}
+			if err := prepareTarget(target); err != nil {
+				return err
+			}
+			if err := os.Link(linkTarget, target); err != nil {
+				return err
+			}
+		default:
+			a.logger.Debugf("skipping unsupported tar entry %s of type %c", hdr.Name, hdr.Typeflag)
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractPath returns the path where the entry is extracted. Entries outside the
+// working directory are only allowed if preservePath is true.
+func extractPath(workingDirectory, name string, preservePath bool) (string, error) {
+	name = filepath.FromSlash(name)
+	if preservePath && filepath.IsAbs(name) {
+		return filepath.Clean(name), nil
+	}
+	target := filepath.Join(workingDirectory, name)
+	if !preservePath {
+		rel, err := filepath.Rel(workingDirectory, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("tar entry %s is outside the working directory", name)
+		}
+	}
+	return target, nil
+}
+
+// prepareTarget creates the parent directory and removes the existing file, like tar does
+func prepareTarget(target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), global.DirectoryPermissions); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+		return os.Remove(target)
+	}
+	return nil
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
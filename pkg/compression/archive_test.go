@@ -0,0 +1,124 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+// testData returns compressible data with enough content for multiple frames
+func testData(size int) []byte {
+	words := []string{"describe", "it", "expect", "toEqual", "function", "return", "const", "\n"}
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[r.Intn(len(words))])
+		buf.WriteByte(' ')
+	}
+	return buf.Bytes()[:size]
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	src := t.TempDir()
+	data := testData(2 << 20)
+	assert.Nil(t, os.MkdirAll(filepath.Join(src, "node_modules", "pkg"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(src, "node_modules", "pkg", "index.js"), data, 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(src, "node_modules", "pkg", "run.sh"), []byte("#!/bin/sh"), 0755))
+	assert.Nil(t, os.Symlink("pkg/index.js", filepath.Join(src, "node_modules", "link.js")))
+
+	for _, name := range []string{Zstd, Gzip, LZ4} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.NucleusConfig{Compression: config.Compression{Codec: name}}
+			a, err := New(cfg, logger)
+			assert.Nil(t, err)
+
+			archivePath := filepath.Join(t.TempDir(), "cache.tzst")
+			assert.Nil(t, a.Compress(context.Background(), archivePath, false, src, "node_modules"))
+
+			dst := t.TempDir()
+			assert.Nil(t, a.Decompress(context.Background(), archivePath, false, dst))
+			got, err := ioutil.ReadFile(filepath.Join(dst, "node_modules", "pkg", "index.js"))
+			assert.Nil(t, err)
+			assert.Equal(t, data, got)
+			info, err := os.Stat(filepath.Join(dst, "node_modules", "pkg", "run.sh"))
+			assert.Nil(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+			link, err := os.Readlink(filepath.Join(dst, "node_modules", "link.js"))
+			assert.Nil(t, err)
+			assert.Equal(t, "pkg/index.js", link)
+		})
+	}
+}
+
+func TestFrameWriterDeterministic(t *testing.T) {
+	data := testData(4 << 20)
+	compress := func(data []byte) []byte {
+		codec, err := NewCodec(Zstd, 0)
+		assert.Nil(t, err)
+		var buf bytes.Buffer
+		fw, err := newFrameWriter(codec, &buf)
+		assert.Nil(t, err)
+		_, err = fw.Write(data)
+		assert.Nil(t, err)
+		assert.Nil(t, fw.Close())
+		return buf.Bytes()
+	}
+	first := compress(data)
+	assert.Equal(t, first, compress(data))
+
+	// a change at the end only changes the last frames
+	changed := append(append([]byte{}, data...), []byte("appended")...)
+	second := compress(changed)
+	common := 0
+	for common < len(first) && common < len(second) && first[common] == second[common] {
+		common++
+	}
+	assert.Greater(t, common, len(first)/2)
+}
+
+func TestExtractPath(t *testing.T) {
+	_, err := extractPath("/repo", "../etc/passwd", false)
+	assert.NotNil(t, err)
+	target, err := extractPath("/repo", "/home/user/.npm", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/user/.npm", target)
+	target, err = extractPath("/repo", "/home/user/.npm", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "/repo/home/user/.npm", target)
+}
+
+func BenchmarkCodecs(b *testing.B) {
+	data := testData(16 << 20)
+	for _, name := range []string{Zstd, Gzip, LZ4} {
+		codec, err := NewCodec(name, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				fw, err := newFrameWriter(codec, ioutil.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := fw.Write(data); err != nil {
+					b.Fatal(err)
+				}
+				if err := fw.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package compression provides in-process tar archives compressed with pluggable codecs.
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// supported codecs
+const (
+	Zstd = "zstd"
+	Gzip = "gzip"
+	LZ4  = "lz4"
+)
+
+// defaultZstdLevel matches the level the archives were created with by `tar -I 'zstd -5'`
+const defaultZstdLevel = 5
+
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic = []byte{0x1f, 0x8b}
+	lz4Magic  = []byte{0x04, 0x22, 0x4d, 0x18}
+)
+
+// ResetWriter is a compressing writer which can be reused for a new frame after it is closed
+type ResetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Codec creates the compressing writers and decompressing readers of a format
+type Codec interface {
+	// Name returns the name of the codec
+	Name() string
+	// NewWriter returns a writer which compresses to w
+	NewWriter(w io.Writer) (ResetWriter, error)
+	// NewReader returns a reader which decompresses all the frames of r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// NewCodec returns the codec with name, level 0 selects the default level of the codec
+func NewCodec(name string, level int) (Codec, error) {
+	switch name {
+	case Zstd, "":
+		if level == 0 {
+			level = defaultZstdLevel
+		}
+		return &zstdCodec{level: zstd.EncoderLevelFromZstd(level)}, nil
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid gzip compression level %d", level)
+		}
+		return &gzipCodec{level: level}, nil
+	case LZ4:
+		if level < 0 || level > 9 {
+			return nil, fmt.Errorf("invalid lz4 compression level %d", level)
+		}
+		codec := &lz4Codec{level: lz4.Fast}
+		if level > 0 {
+			codec.level = lz4.CompressionLevel(1 << (8 + level))
+		}
+		return codec, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %s", name)
+	}
+}
+
+// detectCodec returns the codec of the compressed stream from its magic bytes
+func detectCodec(r *bufio.Reader) (Codec, error) {
+	magic, err := r.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		return &zstdCodec{}, nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		return &gzipCodec{}, nil
+	case bytes.HasPrefix(magic, lz4Magic):
+		return &lz4Codec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression format")
+	}
+}
+
+type zstdCodec struct {
+	level zstd.EncoderLevel
+}
+
+func (c *zstdCodec) Name() string { return Zstd }
+
+func (c *zstdCodec) NewWriter(w io.Writer) (ResetWriter, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(c.level))
+}
+
+func (c *zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (c *gzipCodec) Name() string { return Gzip }
+
+func (c *gzipCodec) NewWriter(w io.Writer) (ResetWriter, error) {
+	return gzip.NewWriterLevel(w, c.level)
+}
+
+func (c *gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	// concatenated members are read as a single stream by default
+	return gzip.NewReader(r)
+}
+
+type lz4Codec struct {
+	level lz4.CompressionLevel
+}
+
+func (c *lz4Codec) Name() string { return LZ4 }
+
+func (c *lz4Codec) NewWriter(w io.Writer) (ResetWriter, error) {
+	zw := lz4.NewWriter(w)
+	if err := zw.Apply(lz4.CompressionLevelOption(c.level)); err != nil {
+		return nil, err
+	}
+	return zw, nil
+}
+
+func (c *lz4Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	src := bufio.NewReader(r)
+	return &lz4Reader{src: src, r: lz4.NewReader(src)}, nil
+}
+
+// lz4Reader reads concatenated lz4 frames, which lz4.Reader stops at the end of the first one
+type lz4Reader struct {
+	src *bufio.Reader
+	r   *lz4.Reader
+}
+
+func (l *lz4Reader) Read(p []byte) (int, error) {
+	for {
+		n, err := l.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if _, err := l.src.Peek(1); err != nil {
+			return n, err
+		}
+		l.r.Reset(l.src)
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (l *lz4Reader) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package compression
+
+import (
+	"io"
+	"math/rand"
+)
+
+// frame boundaries are content defined with an average of 512KiB after the minimum size
+const (
+	minFrameSize        = 64 << 10
+	frameMask    uint64 = (1 << 19) - 1
+	frameSeed           = 0x7461735f66726d31
+)
+
+var frameGear [256]uint64
+
+func init() {
+	r := rand.New(rand.NewSource(frameSeed))
+	for i := range frameGear {
+		frameGear[i] = r.Uint64()
+	}
+}
+
+// frameWriter compresses the stream as independent frames ending at content defined boundaries,
+// similar to `zstd --rsyncable`. Unchanged regions of the input produce identical compressed
+// bytes, which keeps the content defined chunks of the cache archives deduplicated.
+type frameWriter struct {
+	enc ResetWriter
+	dst io.Writer
+	fp  uint64
+	n   int
+}
+
+func newFrameWriter(codec Codec, dst io.Writer) (*frameWriter, error) {
+	enc, err := codec.NewWriter(dst)
+	if err != nil {
+		return nil, err
+	}
+	return &frameWriter{enc: enc, dst: dst}, nil
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	written := 0
+	for i, b := range p {
+		fw.fp = (fw.fp << 1) + frameGear[b]
+		fw.n++
+		if fw.n < minFrameSize || fw.fp&frameMask != 0 {
+			continue
+		}
+		n, err := fw.enc.Write(p[written : i+1])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if err := fw.enc.Close(); err != nil {
+			return written, err
+		}
+		fw.enc.Reset(fw.dst)
+		fw.n = 0
+	}
+	n, err := fw.enc.Write(p[written:])
+	return written + n, err
+}
+
+// Close ends the last frame
+func (fw *frameWriter) Close() error {
+	return fw.enc.Close()
+}
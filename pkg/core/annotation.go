@@ -0,0 +1,72 @@
+package core
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Annotation is the summary of the results published as a check on the commit in the git provider
+type Annotation struct {
+	// Name identifies the check of the commit, a check with the same name is replaced
+	Name   string
+	Passed int
+	Failed int
+	// Skipped includes the blocklisted tests
+	Skipped int
+	// ImpactedTests is the number of tests selected for the commit
+	ImpactedTests int
+	// Flaky are the tests which passed after being retried
+	Flaky []string
+	// DiffCoverage is the coverage of the changed lines in percent, nil if not computed
+	DiffCoverage *float64
+	// CoverageDelta is the change of the line coverage against the base commit in percent, nil if not compared
+	CoverageDelta *float64
+	// Improved and Regressed are the files whose line coverage changed against the base commit
+	Improved  []string
+	Regressed []string
+	// Violations are the coverage thresholds which are not met
+	Violations []string
+	// Failures are annotated inline on the test files
+	Failures []AnnotationFailure
+}
+
+// AnnotationFailure is a failed test annotated on its file
+type AnnotationFailure struct {
+	File    string
+	Line    int
+	Test    string
+	Message string
+}
+
+// Failing reports whether the check fails
+func (a *Annotation) Failing() bool {
+	return a.Failed > 0 || len(a.Violations) > 0
+}
+
+// testsAnnotation returns the annotation of the test results of the task
+func testsAnnotation(taskID string, result *ExecutionResult) *Annotation {
+	annotation := &Annotation{Name: taskID, ImpactedTests: len(result.TestPayload)}
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		switch test.Status {
+		case string(Passed):
+			annotation.Passed++
+			if test.CurrentRetry > 0 {
+				annotation.Flaky = append(annotation.Flaky, test.FullTitle)
+			}
+		case TestFailed, TestTimedOut:
+			annotation.Failed++
+			line, _ := strconv.Atoi(test.Line)
+			annotation.Failures = append(annotation.Failures, AnnotationFailure{
+				File:    test.FilePath,
+				Line:    line,
+				Test:    test.FullTitle,
+				Message: test.Detail,
+			})
+		default:
+			annotation.Skipped++
+		}
+	}
+	sort.Strings(annotation.Flaky)
+	return annotation
+}
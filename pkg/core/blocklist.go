@@ -0,0 +1,77 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// BlocklistType is the way a blocklist entry matches the tests
+type BlocklistType string
+
+// BlocklistType values
+const (
+	// BlocklistLocator blocks the test locator and everything below it, i.e. a file, a suite or a test
+	BlocklistLocator BlocklistType = "locator"
+	// BlocklistGlob blocks the test locators matching the glob pattern, `**` matches any number of directories
+	BlocklistGlob BlocklistType = "glob"
+	// BlocklistRegex blocks the test locators matching the regular expression
+	BlocklistRegex BlocklistType = "regex"
+	// BlocklistTag blocks the tests having the tag in their title, e.g. @slow
+	BlocklistTag BlocklistType = "tag"
+)
+
+// BlocklistEntry is an entry of the blocklist in the .tas.yml, it sets exactly one of the locator,
+// glob, regex or tag. Entries given as a string are test locators.
+// The entry is ignored once the expiry date is passed, so that temporary blocks are lifted automatically.
+type BlocklistEntry struct {
+	Locator string     `yaml:"locator" json:"locator,omitempty"`
+	Glob    string     `yaml:"glob" json:"glob,omitempty"`
+	Regex   string     `yaml:"regex" json:"regex,omitempty"`
+	Tag     string     `yaml:"tag" json:"tag,omitempty"`
+	Expires *time.Time `yaml:"expires" json:"expires,omitempty"`
+}
+
+// UnmarshalYAML decodes both the locator strings and the blocklist entries
+func (b *BlocklistEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var locator string
+	if err := unmarshal(&locator); err == nil {
+		*b = BlocklistEntry{Locator: locator}
+		return nil
+	}
+	type entry BlocklistEntry
+	return unmarshal((*entry)(b))
+}
+
+// Type returns the type of the entry, or an error if it does not set exactly one of the locator, glob, regex or tag
+func (b *BlocklistEntry) Type() (BlocklistType, error) {
+	var types []BlocklistType
+	for t, value := range map[BlocklistType]string{
+		BlocklistLocator: b.Locator,
+		BlocklistGlob:    b.Glob,
+		BlocklistRegex:   b.Regex,
+		BlocklistTag:     b.Tag,
+	} {
+		if value != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) != 1 {
+		return "", errors.New("blocklist entry must set exactly one of `locator`, `glob`, `regex` or `tag`")
+	}
+	return types[0], nil
+}
+
+// Value returns the locator, pattern or tag of the entry
+func (b *BlocklistEntry) Value() string {
+	for _, value := range []string{b.Locator, b.Glob, b.Regex, b.Tag} {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Expired reports whether the expiry date of the entry is before now
+func (b *BlocklistEntry) Expired(now time.Time) bool {
+	return b.Expires != nil && b.Expires.Before(now)
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Checkpoint is the progress of an interrupted task, the completed tests are not run again when the task is resumed
+type Checkpoint struct {
+	TaskID           string             `json:"taskID"`
+	TestPayload      []TestPayload      `json:"testResults"`
+	TestSuitePayload []TestSuitePayload `json:"testSuiteResults"`
+	// Coverage is set if the partial coverage of the completed tests is stored with the checkpoint
+	Coverage bool `json:"coverage"`
+	// Remaining are the locators of the tests which did not complete, set when the checkpoint is loaded
+	Remaining []string  `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RemainingLocators returns the locators whose test did not complete. The locators of a file or a suite
+// are always remaining, as it is not known if all of their tests completed.
+func (c *Checkpoint) RemainingLocators(locators []string) []string {
+	completed := make(map[string]bool, len(c.TestPayload))
+	for i := range c.TestPayload {
+		if c.TestPayload[i].Filelocator != "" {
+			completed[c.TestPayload[i].Filelocator] = true
+		}
+	}
+	remaining := make([]string, 0, len(locators))
+	for _, locator := range locators {
+		if !completed[locator] {
+			remaining = append(remaining, locator)
+		}
+	}
+	return remaining
+}
+
+// Merge adds the results of the tests completed before the interruption to the result of the resumed run,
+// the results of the tests which were run again take precedence.
+func (c *Checkpoint) Merge(result *ExecutionResult) {
+	tests := make(map[string]bool, len(result.TestPayload))
+	for i := range result.TestPayload {
+		tests[result.TestPayload[i].TestID] = true
+	}
+	for i := range c.TestPayload {
+		if !tests[c.TestPayload[i].TestID] {
+			result.TestPayload = append(result.TestPayload, c.TestPayload[i])
+		}
+	}
+	suites := make(map[string]bool, len(result.TestSuitePayload))
+	for i := range result.TestSuitePayload {
+		suites[result.TestSuitePayload[i].SuiteID] = true
+	}
+	for i := range c.TestSuitePayload {
+		if !suites[c.TestSuitePayload[i].SuiteID] {
+			result.TestSuitePayload = append(result.TestSuitePayload, c.TestSuitePayload[i])
+		}
+	}
+}
+
+// resultRecorder collects the results of the completed tests as they are received from the runners
+type resultRecorder struct {
+	mu          sync.Mutex
+	tests       map[string]TestPayload
+	suites      map[string]TestSuitePayload
+	stop        chan struct{}
+	stopped     chan struct{}
+	unsubscribe func()
+}
+
+// recordResults starts collecting the results published by stats, the results of the checkpoint are
+// included so that the progress is kept if a resumed task is interrupted again.
+func recordResults(stats TestStats, checkpoint *Checkpoint) *resultRecorder {
+	results, unsubscribe := stats.Subscribe()
+	r := &resultRecorder{
+		tests:       make(map[string]TestPayload),
+		suites:      make(map[string]TestSuitePayload),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+		unsubscribe: unsubscribe,
+	}
+	if checkpoint != nil {
+		r.add(ExecutionResult{TestPayload: checkpoint.TestPayload, TestSuitePayload: checkpoint.TestSuitePayload})
+	}
+	go func() {
+		defer close(r.stopped)
+		for {
+			select {
+			case result := <-results:
+				r.add(result)
+			case <-r.stop:
+				// the results received before stopping are kept
+				for {
+					select {
+					case result := <-results:
+						r.add(result)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return r
+}
+
+func (r *resultRecorder) add(result ExecutionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range result.TestPayload {
+		if result.TestPayload[i].Status != TestStarted {
+			r.tests[result.TestPayload[i].TestID] = result.TestPayload[i]
+		}
+	}
+	for i := range result.TestSuitePayload {
+		r.suites[result.TestSuitePayload[i].SuiteID] = result.TestSuitePayload[i]
+	}
+}
+
+// checkpoint stops collecting the results and returns the checkpoint of the completed tests
+func (r *resultRecorder) checkpoint(taskID string) *Checkpoint {
+	select {
+	case <-r.stop:
+	default:
+		r.unsubscribe()
+		close(r.stop)
+	}
+	<-r.stopped
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	checkpoint := &Checkpoint{
+		TaskID:           taskID,
+		TestPayload:      make([]TestPayload, 0, len(r.tests)),
+		TestSuitePayload: make([]TestSuitePayload, 0, len(r.suites)),
+		CreatedAt:        time.Now(),
+	}
+	for _, test := range r.tests {
+		checkpoint.TestPayload = append(checkpoint.TestPayload, test)
+	}
+	for _, suite := range r.suites {
+		checkpoint.TestSuitePayload = append(checkpoint.TestSuitePayload, suite)
+	}
+	return checkpoint
+}
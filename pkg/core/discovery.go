@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+)
+
+// endpointNeuronTestList is resolved for each test list, as the neuron host can be reloaded
+const endpointNeuronTestList = "/test-list"
+
+// discoveryCacheKey identifies the inputs of the discovery besides the commit: the configuration, including the
+// packages and their patterns, the event selecting the patterns, the selection of the payload and the changes
+// selecting the tests
+func discoveryCacheKey(payload *Payload, tasConfig *TASConfig, diff map[string]int) (string, error) {
+	config, err := json.Marshal(tasConfig)
+	if err != nil {
+		return "", err
+	}
+	selection, err := json.Marshal(payload.Selection)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(config)
+	fmt.Fprintf(h, "\n%s\n%t\n%s\n", payload.EventType, payload.ParentCommitCoverageExists, selection)
+	files := make([]string, 0, len(diff))
+	for file := range diff {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		fmt.Fprintf(h, "%s:%d\n", file, diff[file])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replayDiscovery sends the cached test lists of the key to neuron, it returns false if they are not cached
+func (pl *Pipeline) replayDiscovery(ctx context.Context, payload *Payload, key string) (bool, error) {
+	lists, err := pl.DiscoveryCache.Load(ctx, payload, key)
+	if err != nil {
+		// the tests are discovered again
+		pl.Logger.Warnf("failed to load the cached test lists: %v", err)
+		return false, nil
+	}
+	if len(lists) == 0 {
+		return false, nil
+	}
+	pl.Logger.Infof("Skipping test discovery, using the %d test lists cached for key %s", len(lists), key)
+	for _, list := range lists {
+		if err := pl.postTestList(ctx, withTaskIDs(list, payload)); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// forwardDiscovery sends the test lists posted by the runners to neuron and caches them under the key
+func (pl *Pipeline) forwardDiscovery(ctx context.Context, payload *Payload, key string) error {
+	lists := pl.DiscoveryCache.Recorded()
+	for _, list := range lists {
+		if err := pl.postTestList(ctx, list); err != nil {
+			return err
+		}
+	}
+	if len(lists) == 0 {
+		return nil
+	}
+	// a failure only results in discovering the tests again
+	if err := pl.DiscoveryCache.Save(ctx, payload, key, lists); err != nil {
+		pl.Logger.Warnf("failed to cache the test lists: %v", err)
+	}
+	return nil
+}
+
+// withTaskIDs replaces the task and build of the task which discovered the cached test list with the current ones
+func withTaskIDs(list json.RawMessage, payload *Payload) json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(list, &fields); err != nil {
+		return list
+	}
+	for key, value := range map[string]string{"taskID": payload.TaskID, "buildID": payload.BuildID} {
+		if _, ok := fields[key]; ok {
+			fields[key], _ = json.Marshal(value)
+		}
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return list
+	}
+	return out
+}
+
+func (pl *Pipeline) postTestList(ctx context.Context, list json.RawMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, global.NeuronURL(endpointNeuronTestList), bytes.NewReader(list))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.InjectHeaders(ctx, req.Header)
+	resp, err := pl.HttpClient.Do(req)
+	if err != nil {
+		pl.Logger.Errorf("error while sending test list %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		pl.Logger.Errorf("error while sending test list, non 200 status: %d", resp.StatusCode)
+		return fmt.Errorf("non 200 status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package core
+
+// EnvStage is the stage of the pipeline whose commands share the env of StageEnv
+type EnvStage string
+
+// EnvStage values
+const (
+	// EnvStageInstall are the pre-run steps and the preInstall and postInstall hooks
+	EnvStageInstall EnvStage = "install"
+	// EnvStageDiscovery is the test discovery and the preDiscovery hook
+	EnvStageDiscovery EnvStage = "discovery"
+	// EnvStageExecution is the test execution, the post-run steps and the preRun, postRun and onFailure hooks
+	EnvStageExecution EnvStage = "execution"
+)
+
+// StageEnv are the environment variables of the commands of each stage
+type StageEnv struct {
+	Install   map[string]string `yaml:"install"`
+	Discovery map[string]string `yaml:"discovery"`
+	Execution map[string]string `yaml:"execution"`
+}
+
+// EnvScopes returns the env maps the commands of the stage inherit from the configuration, in increasing
+// precedence: the env of the configuration and the env of the stage. They are overridden by the env of the
+// preMerge or postMerge, of the pre and post runs or of the hooks, which is overridden by the env of a command.
+func (t *TASConfig) EnvScopes(stage EnvStage) []map[string]string {
+	scopes := []map[string]string{t.Env}
+	if t.StageEnv == nil {
+		return scopes
+	}
+	switch stage {
+	case EnvStageInstall:
+		scopes = append(scopes, t.StageEnv.Install)
+	case EnvStageDiscovery:
+		scopes = append(scopes, t.StageEnv.Discovery)
+	case EnvStageExecution:
+		scopes = append(scopes, t.StageEnv.Execution)
+	}
+	return scopes
+}
+
+// ScopedRun returns a copy of run inheriting the env of the configuration and of the stage, or nil if run is nil
+func (t *TASConfig) ScopedRun(stage EnvStage, run *Run) *Run {
+	if run == nil {
+		return nil
+	}
+	scoped := *run
+	scoped.ParentEnv = t.EnvScopes(stage)
+	return &scoped
+}
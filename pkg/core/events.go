@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PipelineEventType is the type of the events emitted by the pipeline
+type PipelineEventType string
+
+// task lifecycle events, they are delivered to the webhooks
+const (
+	EventTaskStarted   PipelineEventType = "task.started"
+	EventTestFailed    PipelineEventType = "test.failed"
+	EventBlocklistHit  PipelineEventType = "blocklist.hit"
+	EventTaskCompleted PipelineEventType = "task.completed"
+)
+
+// pipeline events
+const (
+	EventStageStarted  PipelineEventType = "stage.started"
+	EventStageFinished PipelineEventType = "stage.finished"
+	// EventTestResult is emitted for each partial or complete result received from the runners
+	EventTestResult PipelineEventType = "test.result"
+	EventCacheHit   PipelineEventType = "cache.hit"
+	// EventError is emitted when a stage fails
+	EventError PipelineEventType = "error"
+)
+
+// Event is emitted on the EventBus, the fields set depend on the type of the event
+type Event struct {
+	Type PipelineEventType
+	Time time.Time
+	// Task is a snapshot of the task status, it is not set for the events emitted outside of the task lifecycle
+	Task *TaskPayload
+	// Stage is the stage running when the event was emitted
+	Stage string
+	// Duration of the stage, set by stage.finished
+	Duration time.Duration
+	// Err is the error of the stage, set by stage.finished and error
+	Err error
+	// Result is set by test.result
+	Result *ExecutionResult
+	// Tests are set by test.failed and blocklist.hit
+	Tests []NotificationTest
+	// Cache is the key or the name of the restored cache, set by cache.hit
+	Cache string
+}
+
+// EventSubscriber receives the events of the EventBus
+type EventSubscriber interface {
+	// HandleEvent is called synchronously by Emit, the subscribers which can't keep up with the pipeline
+	// must hand the events off, e.g. to a buffered channel. The event must not be modified.
+	HandleEvent(ctx context.Context, event *Event)
+}
+
+// EventSubscriberFunc adapts a function to an EventSubscriber
+type EventSubscriberFunc func(ctx context.Context, event *Event)
+
+// HandleEvent calls f
+func (f EventSubscriberFunc) HandleEvent(ctx context.Context, event *Event) {
+	f(ctx, event)
+}
+
+type subscription struct {
+	subscriber EventSubscriber
+	// types are the types of the events delivered to the subscriber, all the events if empty
+	types map[PipelineEventType]bool
+}
+
+// EventBus delivers the events emitted by the pipeline to the subscribers, so that the services do not
+// call each other to e.g. notify the webhooks or upload the audit log
+type EventBus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+}
+
+// NewEventBus returns an EventBus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe delivers the events of the types to the subscriber, or all the events if no type is given.
+// The returned function must be called to unsubscribe.
+func (b *EventBus) Subscribe(subscriber EventSubscriber, types ...PipelineEventType) func() {
+	sub := &subscription{subscriber: subscriber, types: make(map[PipelineEventType]bool, len(types))}
+	for _, eventType := range types {
+		sub.types[eventType] = true
+	}
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, sub)
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subscriptions {
+			if s == sub {
+				b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit delivers the event to the subscribers in the order they subscribed and returns once they handled it.
+// The time and the stage of the event are set from ctx if missing. Emitting on a nil bus is a no-op.
+func (b *EventBus) Emit(ctx context.Context, event *Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Stage == "" {
+		event.Stage = StageFromContext(ctx)
+	}
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+	for _, sub := range subscriptions {
+		if len(sub.types) == 0 || sub.types[event.Type] {
+			sub.subscriber.HandleEvent(ctx, event)
+		}
+	}
+}
+
+// Watch emits the results received from the runners as test.result events till ctx is done
+func (b *EventBus) Watch(ctx context.Context, stats TestStats) {
+	results, unsubscribe := stats.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case result := <-results:
+			b.Emit(ctx, &Event{Type: EventTestResult, Result: &result})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// emitTaskEvent emits the event with a snapshot of the task, as the task status keeps changing
+func (pl *Pipeline) emitTaskEvent(ctx context.Context, eventType PipelineEventType, task *TaskPayload, tests []NotificationTest) {
+	snapshot := *task
+	pl.Events.Emit(ctx, &Event{Type: eventType, Task: &snapshot, Tests: tests})
+}
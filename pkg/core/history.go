@@ -0,0 +1,59 @@
+package core
+
+import "time"
+
+// RunSummary is a run of the tests of a task kept in the local results store
+type RunSummary struct {
+	TaskID      string    `json:"taskID"`
+	BuildID     string    `json:"buildID"`
+	RepoID      string    `json:"repoID"`
+	CommitID    string    `json:"commitID"`
+	Branch      string    `json:"branch,omitempty"`
+	Status      Status    `json:"status"`
+	Remark      string    `json:"remark,omitempty"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	Passed      int       `json:"passed"`
+	Failed      int       `json:"failed"`
+	Skipped     int       `json:"skipped"`
+	Blocklisted int       `json:"blocklisted"`
+	// Duration of the tests in milliseconds
+	Duration int64 `json:"duration"`
+	// RerunOf is the task whose failed tests were re-run by the run
+	RerunOf string `json:"rerunOf,omitempty"`
+}
+
+// TestHistoryEntry is the result of a test in a run
+type TestHistoryEntry struct {
+	TaskID   string    `json:"taskID"`
+	CommitID string    `json:"commitID"`
+	Status   string    `json:"status"`
+	Duration int       `json:"duration"`
+	Matrix   string    `json:"matrix,omitempty"`
+	RunAt    time.Time `json:"runAt"`
+}
+
+// FlakyTest is a test which both passed and failed in the recent runs of the repository
+type FlakyTest struct {
+	TestID string `json:"testID"`
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+	// FlakeRate is the share of the failed executions of the test
+	FlakeRate float64 `json:"flakeRate"`
+}
+
+// CoverageSummary is the total coverage percentages of a commit
+type CoverageSummary struct {
+	BuildID    string  `json:"buildID"`
+	RepoID     string  `json:"repoID"`
+	CommitID   string  `json:"commitID"`
+	Lines      float64 `json:"lines"`
+	Statements float64 `json:"statements"`
+	Functions  float64 `json:"functions"`
+	Branches   float64 `json:"branches"`
+	// DiffCoverage is only set for the gated commit if the diff coverage threshold is configured
+	DiffCoverage *float64  `json:"diffCoverage,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
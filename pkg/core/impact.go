@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+)
+
+// ChangeTypes are the names of the change types of the changed files
+var ChangeTypes = map[int]string{
+	FileAdded:    "added",
+	FileRemoved:  "removed",
+	FileModified: "modified",
+}
+
+// ImpactReport is the impact analysis of a commit, computed without executing any test
+type ImpactReport struct {
+	BaseCommit   string            `json:"baseCommit"`
+	TargetCommit string            `json:"targetCommit"`
+	ChangedFiles map[string]string `json:"changedFiles"`
+	// ImpactedFiles transitively import the changed files, they are only set if smart run is enabled
+	ImpactedFiles []string `json:"impactedFiles"`
+	// ImpactedTests are the changed or impacted files matching the test patterns of the configuration
+	ImpactedTests []string `json:"impactedTests"`
+}
+
+// impactState is the state of the task required for the impact analysis, it is set once the
+// configuration is loaded and read by the API handlers
+type impactState struct {
+	mu         sync.RWMutex
+	payload    *Payload
+	tasConfig  *TASConfig
+	cloneToken string
+}
+
+func (pl *Pipeline) setImpactState(payload *Payload, tasConfig *TASConfig, cloneToken string) {
+	pl.impact.mu.Lock()
+	defer pl.impact.mu.Unlock()
+	pl.impact.payload = payload
+	pl.impact.tasConfig = tasConfig
+	pl.impact.cloneToken = cloneToken
+}
+
+// ImpactedTests returns the changed files and the impacted tests of the target commit compared to the
+// base commit. The diff of the task is used if the target commit is empty, the base commit defaults to
+// the base commit of the task. The dependency graph of the checked out commit is used for the impacted files.
+func (pl *Pipeline) ImpactedTests(ctx context.Context, baseCommit, targetCommit string) (*ImpactReport, error) {
+	pl.impact.mu.RLock()
+	if pl.impact.payload == nil {
+		pl.impact.mu.RUnlock()
+		return nil, errs.ErrTaskNotReady
+	}
+	payload := *pl.impact.payload
+	tasConfig, cloneToken := pl.impact.tasConfig, pl.impact.cloneToken
+	pl.impact.mu.RUnlock()
+
+	// the test patterns are selected by the event of the task
+	eventType := payload.EventType
+	if targetCommit != "" {
+		// the diff of an explicit commit is always the commit diff, even for pull requests
+		payload.EventType = EventPush
+		payload.TargetCommit = targetCommit
+		if baseCommit != "" {
+			payload.BaseCommit = baseCommit
+		}
+	}
+	diff, err := pl.DiffManager.GetChangedFiles(ctx, &payload, cloneToken)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImpactReport{
+		BaseCommit:    payload.BaseCommit,
+		TargetCommit:  payload.TargetCommit,
+		ChangedFiles:  make(map[string]string, len(diff)),
+		ImpactedFiles: make([]string, 0),
+		ImpactedTests: make([]string, 0),
+	}
+	for file, changeType := range diff {
+		report.ChangedFiles[file] = ChangeTypes[changeType]
+	}
+	files := diff
+	if tasConfig.SmartRun && len(diff) > 0 {
+		impacted, err := pl.ImpactAnalyzer.ImpactedFiles(ctx, &payload, diff)
+		if err != nil {
+			return nil, err
+		}
+		files = make(map[string]int, len(diff)+len(impacted))
+		for file, changeType := range diff {
+			files[file] = changeType
+		}
+		for _, file := range impacted {
+			if _, ok := diff[file]; !ok {
+				report.ImpactedFiles = append(report.ImpactedFiles, file)
+				files[file] = FileModified
+			}
+		}
+		sort.Strings(report.ImpactedFiles)
+	}
+	for file, changeType := range files {
+		if changeType == FileRemoved {
+			continue
+		}
+		if isTest, err := matchesTestPatterns(tasConfig, eventType, file); err != nil {
+			return nil, err
+		} else if isTest {
+			report.ImpactedTests = append(report.ImpactedTests, file)
+		}
+	}
+	sort.Strings(report.ImpactedTests)
+	return report, nil
+}
+
+// matchesTestPatterns reports whether the file matches the test patterns of any of the configurations,
+// the patterns of the packages are already relative to the repository root
+func matchesTestPatterns(tasConfig *TASConfig, eventType EventType, file string) (bool, error) {
+	for _, target := range tasConfig.Targets() {
+		if ok, err := matchesAny(target.TestPatterns(eventType), file); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDiffManager returns the diff and records the payload it is called with
+type fakeDiffManager struct {
+	diff    map[string]int
+	payload *Payload
+}
+
+func (m *fakeDiffManager) GetChangedFiles(ctx context.Context, payload *Payload, cloneToken string) (map[string]int, error) {
+	m.payload = payload
+	return m.diff, nil
+}
+
+func (m *fakeDiffManager) GetChangedLines(ctx context.Context, payload *Payload, cloneToken string) (map[string][]int, error) {
+	return nil, nil
+}
+
+// fakeImpactAnalyzer returns the dependents of the changed files
+type fakeImpactAnalyzer struct {
+	dependents map[string][]string
+	err        error
+}
+
+func (a *fakeImpactAnalyzer) ImpactedFiles(ctx context.Context, payload *Payload, diff map[string]int) ([]string, error) {
+	files := make([]string, 0)
+	for file := range diff {
+		files = append(files, a.dependents[file]...)
+	}
+	return files, a.err
+}
+
+func newImpactPipeline(tasConfig *TASConfig, diff map[string]int, dependents map[string][]string) (*Pipeline, *fakeDiffManager) {
+	diffManager := &fakeDiffManager{diff: diff}
+	pl := &Pipeline{DiffManager: diffManager, ImpactAnalyzer: &fakeImpactAnalyzer{dependents: dependents}}
+	pl.setImpactState(&Payload{EventType: EventPullRequest, BaseCommit: "base", TargetCommit: "target"}, tasConfig, "token")
+	return pl, diffManager
+}
+
+func TestImpactedTests(t *testing.T) {
+	root := &TASConfig{
+		SmartRun: true,
+		Premerge: &Merge{Patterns: []string{"test/**/*.spec.ts"}},
+	}
+	// the patterns of the packages are made relative to the root when the configuration is loaded
+	packages := &TASConfig{
+		SmartRun: true,
+		SubConfigs: []*TASConfig{
+			{Dir: "packages/api", Premerge: &Merge{Patterns: []string{"packages/api/test/*.spec.ts"}}},
+			{Dir: "packages/web", Premerge: &Merge{Patterns: []string{"packages/web/src/**/*.test.ts"}}},
+		},
+	}
+	noSmartRun := *root
+	noSmartRun.SmartRun = false
+
+	tests := []struct {
+		name          string
+		tasConfig     *TASConfig
+		diff          map[string]int
+		dependents    map[string][]string
+		impactedFiles []string
+		impactedTests []string
+	}{
+		{
+			name:      "root",
+			tasConfig: root,
+			diff: map[string]int{
+				"src/math.ts":          FileModified,
+				"test/add.spec.ts":     FileAdded,
+				"test/old.spec.ts":     FileRemoved,
+				"test/api/get.spec.ts": FileModified,
+				"test/helper.ts":       FileModified,
+			},
+			impactedFiles: []string{},
+			impactedTests: []string{"test/add.spec.ts", "test/api/get.spec.ts"},
+		},
+		{
+			name:      "dependents",
+			tasConfig: root,
+			diff:      map[string]int{"src/math.ts": FileModified, "test/add.spec.ts": FileModified},
+			dependents: map[string][]string{
+				"src/math.ts":      {"src/stats.ts", "test/stats.spec.ts", "test/add.spec.ts"},
+				"test/add.spec.ts": {},
+			},
+			impactedFiles: []string{"src/stats.ts", "test/stats.spec.ts"},
+			impactedTests: []string{"test/add.spec.ts", "test/stats.spec.ts"},
+		},
+		{
+			name:          "dependents without smart run",
+			tasConfig:     &noSmartRun,
+			diff:          map[string]int{"src/math.ts": FileModified},
+			dependents:    map[string][]string{"src/math.ts": {"test/stats.spec.ts"}},
+			impactedFiles: []string{},
+			impactedTests: []string{},
+		},
+		{
+			name:      "packages",
+			tasConfig: packages,
+			diff: map[string]int{
+				"packages/api/test/get.spec.ts":       FileModified,
+				"packages/api/test/nested/a.spec.ts":  FileModified,
+				"packages/web/src/app/button.test.ts": FileAdded,
+				"test/get.spec.ts":                    FileModified,
+				"packages/web/test/get.spec.ts":       FileModified,
+			},
+			impactedFiles: []string{},
+			impactedTests: []string{"packages/api/test/get.spec.ts", "packages/web/src/app/button.test.ts"},
+		},
+		{
+			name:          "package dependents",
+			tasConfig:     packages,
+			diff:          map[string]int{"packages/shared/src/format.ts": FileModified},
+			dependents:    map[string][]string{"packages/shared/src/format.ts": {"packages/api/src/handler.ts", "packages/api/test/handler.spec.ts"}},
+			impactedFiles: []string{"packages/api/src/handler.ts", "packages/api/test/handler.spec.ts"},
+			impactedTests: []string{"packages/api/test/handler.spec.ts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, _ := newImpactPipeline(tt.tasConfig, tt.diff, tt.dependents)
+			report, err := pl.ImpactedTests(context.Background(), "", "")
+			assert.Nil(t, err)
+			assert.Equal(t, len(tt.diff), len(report.ChangedFiles))
+			assert.Equal(t, tt.impactedFiles, report.ImpactedFiles)
+			assert.Equal(t, tt.impactedTests, report.ImpactedTests)
+		})
+	}
+}
+
+func TestImpactedTestsCommits(t *testing.T) {
+	tasConfig := &TASConfig{
+		Premerge:  &Merge{Patterns: []string{"test/*.spec.ts"}},
+		Postmerge: &Merge{Patterns: []string{"e2e/*.spec.ts"}},
+	}
+	diff := map[string]int{"test/a.spec.ts": FileModified, "e2e/a.spec.ts": FileRemoved}
+	pl, diffManager := newImpactPipeline(tasConfig, diff, nil)
+
+	// the diff of the task
+	report, err := pl.ImpactedTests(context.Background(), "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, EventPullRequest, diffManager.payload.EventType)
+	assert.Equal(t, "base", report.BaseCommit)
+	assert.Equal(t, "target", report.TargetCommit)
+	assert.Equal(t, map[string]string{"test/a.spec.ts": "modified", "e2e/a.spec.ts": "removed"}, report.ChangedFiles)
+	assert.Equal(t, []string{"test/a.spec.ts"}, report.ImpactedTests)
+
+	// the commit diff of an explicit commit, the patterns are still selected by the event of the task
+	report, err = pl.ImpactedTests(context.Background(), "other-base", "other-target")
+	assert.Nil(t, err)
+	assert.Equal(t, EventPush, diffManager.payload.EventType)
+	assert.Equal(t, "other-base", report.BaseCommit)
+	assert.Equal(t, "other-target", report.TargetCommit)
+	assert.Equal(t, []string{"test/a.spec.ts"}, report.ImpactedTests)
+
+	// the base commit defaults to the one of the task
+	report, err = pl.ImpactedTests(context.Background(), "", "other-target")
+	assert.Nil(t, err)
+	assert.Equal(t, "base", report.BaseCommit)
+}
+
+func TestImpactedTestsErrors(t *testing.T) {
+	pl := &Pipeline{}
+	_, err := pl.ImpactedTests(context.Background(), "", "")
+	assert.True(t, errors.Is(err, errs.ErrTaskNotReady))
+
+	tasConfig := &TASConfig{SmartRun: true, Premerge: &Merge{Patterns: []string{"test/*.spec.ts"}}}
+	pl, _ = newImpactPipeline(tasConfig, map[string]int{"src/a.ts": FileModified}, nil)
+	pl.ImpactAnalyzer = &fakeImpactAnalyzer{err: errors.New("failed to build the dependency graph")}
+	_, err = pl.ImpactedTests(context.Background(), "", "")
+	assert.NotNil(t, err)
+
+	tasConfig = &TASConfig{Premerge: &Merge{Patterns: []string{"test/[a-.spec.ts"}}}
+	pl, _ = newImpactPipeline(tasConfig, map[string]int{"test/a.spec.ts": FileModified}, nil)
+	_, err = pl.ImpactedTests(context.Background(), "", "")
+	assert.NotNil(t, err)
+}
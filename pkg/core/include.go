@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+)
+
+var sha256Regex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Include is a shared configuration fragment the configuration file is based on, it sets either the path of a
+// fragment in the repository or the url of a remote fragment pinned by its checksum. Includes given as a string
+// are paths. The paths are relative to the directory of the including file.
+type Include struct {
+	Path string `yaml:"path" json:"path,omitempty"`
+	URL  string `yaml:"url" json:"url,omitempty"`
+	// SHA256 is the hex checksum of the remote fragment, the fragment is rejected if it does not match
+	SHA256 string `yaml:"sha256" json:"sha256,omitempty"`
+}
+
+// UnmarshalYAML decodes both the path strings and the includes
+func (i *Include) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var p string
+	if err := unmarshal(&p); err == nil {
+		*i = Include{Path: p}
+		return nil
+	}
+	type include Include
+	return unmarshal((*include)(i))
+}
+
+// Validate checks that the include sets either a path or an http url with its checksum
+func (i *Include) Validate() error {
+	if (i.Path == "") == (i.URL == "") {
+		return errors.New("include must set exactly one of `path` or `url`")
+	}
+	if i.Path != "" {
+		return nil
+	}
+	if u, err := url.Parse(i.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("include `url` must be an http or https url")
+	}
+	if !sha256Regex.MatchString(i.SHA256) {
+		return errors.New("include `url` requires the hex `sha256` checksum of the fragment")
+	}
+	return nil
+}
+
+// Source returns the path or the url of the fragment
+func (i *Include) Source() string {
+	if i.Path != "" {
+		return i.Path
+	}
+	return i.URL
+}
@@ -2,7 +2,10 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"io"
+	"os/exec"
+	"time"
 )
 
 // PayloadManager defines operations for payload
@@ -25,11 +28,15 @@ type GitManager interface {
 	Clone(ctx context.Context, payload *Payload, cloneToken string) error
 	// CloneYML  clones all .tas.yml for all  the commits
 	CloneYML(ctx context.Context, payload *Payload, cloneToken string) error
+	// SparseCheckout adds the directories to the checkout of a repository cloned with the sparse option
+	SparseCheckout(ctx context.Context, payload *Payload, cloneToken string, dirs []string) error
 }
 
 // DiffManager manages the diff findings for the given payload
 type DiffManager interface {
 	GetChangedFiles(ctx context.Context, payload *Payload, cloneToken string) (map[string]int, error)
+	// GetChangedLines returns the added or modified line numbers for each changed file
+	GetChangedLines(ctx context.Context, payload *Payload, cloneToken string) (map[string][]int, error)
 }
 
 // TestDiscoveryService services discovery of tests
@@ -38,6 +45,39 @@ type TestDiscoveryService interface {
 	Discover(ctx context.Context, tasConfig *TASConfig, payload *Payload, secretData map[string]string, diff map[string]int) error
 }
 
+// DiscoveryCache caches the test lists posted by the runners during the discovery, so that the discovery is
+// not run again for the same commit and configuration, e.g. when a task is run again or by each shard of a build
+type DiscoveryCache interface {
+	// Record collects a test list posted by a runner
+	Record(raw json.RawMessage)
+	// Recorded returns the test lists posted by the runners
+	Recorded() []json.RawMessage
+	// Load returns the cached test lists of the key or nil if they are not cached
+	Load(ctx context.Context, payload *Payload, key string) ([]json.RawMessage, error)
+	// Save stores the test lists under the key
+	Save(ctx context.Context, payload *Payload, key string, lists []json.RawMessage) error
+}
+
+// DryRunReporter reports the impacted tests discovered in dry run mode
+type DryRunReporter interface {
+	// Report emits the test lists posted by the runners along with the changed files
+	Report(ctx context.Context, payload *Payload, diff map[string]int) error
+}
+
+// ConfigDetector infers the configuration of the repositories without configuration file
+type ConfigDetector interface {
+	// Detect inspects the manifests of the repository in repoDir and returns the inferred configuration file
+	Detect(ctx context.Context, repoDir string) ([]byte, error)
+	// Publish logs the inferred configuration and uploads it for the review of the user
+	Publish(ctx context.Context, payload *Payload, config []byte) error
+}
+
+// ImpactAnalyzer finds the files impacted by the changes using the dependency graph of the repository
+type ImpactAnalyzer interface {
+	// ImpactedFiles returns the files which transitively import the changed files
+	ImpactedFiles(ctx context.Context, payload *Payload, diff map[string]int) ([]string, error)
+}
+
 // TestBlockListService is used for fetching blocklisted tests
 type TestBlockListService interface {
 	GetBlockListedTests(ctx context.Context, tasConfig *TASConfig, repo string) error
@@ -51,7 +91,9 @@ type TestExecutionService interface {
 
 // CoverageService services coverage of tests
 type CoverageService interface {
-	MergeAndUpload(ctx context.Context, payload *Payload) error
+	// MergeAndUpload merges, uploads and gates the coverage against the configured thresholds.
+	// cloneToken is used for fetching the diff for diff coverage, it can be empty for public repos.
+	MergeAndUpload(ctx context.Context, payload *Payload, cloneToken string) error
 }
 
 // YMLParserService services parsing of tas.yml
@@ -62,6 +104,13 @@ type YMLParserService interface {
 // TestStats is used for servicing stat collection
 type TestStats interface {
 	CaptureTestStats(pid int32) error
+	// RecordHookTiming records the duration of a lifecycle hook
+	RecordHookTiming(timing HookTiming)
+	// HookTimings returns the durations of the hooks run so far
+	HookTimings() []HookTiming
+	// Subscribe returns a channel which receives the results as they are received from the runners,
+	// the returned function must be called to unsubscribe.
+	Subscribe() (<-chan ExecutionResult, func())
 }
 
 // Task is a service to update task status at neuron
@@ -88,8 +137,78 @@ type AzureClient interface {
 	Exists(ctx context.Context, path string) (bool, error)
 }
 
-// ZstdCompressor performs zstd compression and decompression
-type ZstdCompressor interface {
+// ArtifactManager uploads the artifacts generated by the tests
+type ArtifactManager interface {
+	// Upload compresses and uploads the artifacts matching the configured paths and links them to the tests of the result
+	Upload(ctx context.Context, payload *Payload, artifacts *Artifacts, result *ExecutionResult) error
+}
+
+// FailureReporter writes a machine readable report of the failed tests
+type FailureReporter interface {
+	// Report writes the SARIF report of the failed tests of the result to the local file system and the blob storage
+	Report(ctx context.Context, payload *Payload, result *ExecutionResult) error
+}
+
+// ServiceManager manages the service containers used by the tests
+type ServiceManager interface {
+	// Start starts the services and waits until they are ready, it returns the environment variables exposing the services
+	Start(ctx context.Context, payload *Payload, services map[string]Service, secretData map[string]string) (map[string]string, error)
+	// Stop removes the started services
+	Stop(ctx context.Context) error
+}
+
+// CheckpointManager stores the progress of the interrupted tasks, so that only the remaining tests are run
+// when the task is started again
+type CheckpointManager interface {
+	// Load returns the checkpoint of the task or nil if the task was not interrupted. The partial coverage is
+	// restored into coverageDir and the locators of the payload are reduced to the remaining tests.
+	Load(ctx context.Context, payload *Payload, coverageDir string) (*Checkpoint, error)
+	// Save stores the checkpoint along with the partial coverage of coverageDir
+	Save(ctx context.Context, payload *Payload, checkpoint *Checkpoint, coverageDir string) error
+	// Clear removes the checkpoint once the resumed task completed
+	Clear(ctx context.Context, payload *Payload) error
+}
+
+// Annotator publishes the results as checks on the commits in the git provider
+type Annotator interface {
+	// Publish creates the check of the target commit with the annotation, nothing is published if disabled
+	Publish(ctx context.Context, payload *Payload, cloneToken string, annotation *Annotation) error
+}
+
+// ResultExporter exports the results of the tests to the external test analytics platforms
+type ResultExporter interface {
+	// Export sends the results to all the configured sinks, the results are sent to the remaining sinks if a sink fails
+	Export(ctx context.Context, payload *Payload, result *ExecutionResult) error
+}
+
+// ResultStore keeps the history of the runs and the coverage locally, it is only enabled in local runner mode
+// so that self-hosted users get the trends without the remote backend
+type ResultStore interface {
+	// SaveRun persists the task and the results of its tests, it is a no-op if the store is disabled
+	SaveRun(ctx context.Context, branch string, task *TaskPayload, result *ExecutionResult) error
+	// SaveCoverage persists the coverage of a commit, it is a no-op if the store is disabled
+	SaveCoverage(ctx context.Context, summary *CoverageSummary) error
+	// Runs returns the latest runs of the repository, most recent first
+	Runs(ctx context.Context, repoID string, limit int) ([]RunSummary, error)
+	// TestHistory returns the latest results of the test, most recent first
+	TestHistory(ctx context.Context, repoID, testID string, limit int) ([]TestHistoryEntry, error)
+	// FailedTests returns the locators of the tests which failed in the run of the task
+	FailedTests(ctx context.Context, repoID, taskID string) ([]string, error)
+	// FlakyTests returns the tests which both passed and failed in the latest runs of the repository
+	FlakyTests(ctx context.Context, repoID string, runs int) ([]FlakyTest, error)
+	// CoverageTrend returns the coverage of the latest commits of the repository, most recent first
+	CoverageTrend(ctx context.Context, repoID string, limit int) ([]CoverageSummary, error)
+}
+
+// ToolchainManager sets up the toolchains required by the tests before any command of the user is run
+type ToolchainManager interface {
+	// Setup installs the toolchains having a version manager in the container image and verifies the versions
+	// of the other toolchains, the PATH is updated with the installed toolchains
+	Setup(ctx context.Context, toolchains *Toolchains) error
+}
+
+// Compressor performs compression and decompression of archives
+type Compressor interface {
 	Compress(ctx context.Context, compressedFileName string, preservePath bool, workingDirectory string, filesToCompress ...string) error
 	Decompress(ctx context.Context, filePath string, preservePath bool, workingDirectory string) error
 }
@@ -100,12 +219,32 @@ type CacheStore interface {
 	Download(ctx context.Context, cacheKey string) error
 	// Upload creates, compresses and uploads cache at cacheKey
 	Upload(ctx context.Context, cacheKey string, itemsToCompress ...string) error
+	// Restore downloads the named caches in parallel
+	Restore(ctx context.Context, payload *Payload, caches []NamedCache) error
+	// Save uploads the named caches in parallel, skipping the ones restored using their key
+	Save(ctx context.Context, payload *Payload, caches []NamedCache) error
+}
+
+// ImpactService provides the impact analysis of a commit without executing the tests
+type ImpactService interface {
+	// ImpactedTests returns the changed files and the impacted tests of the target commit compared to the base commit
+	ImpactedTests(ctx context.Context, baseCommit, targetCommit string) (*ImpactReport, error)
+}
+
+// TestTimingStore provides the historical execution durations of the tests
+type TestTimingStore interface {
+	// GetTimings returns the durations in milliseconds keyed by test locator
+	GetTimings(ctx context.Context, repoID, branch string) (map[string]int, error)
+	// StoreTimings persists the durations of the executed tests
+	StoreTimings(ctx context.Context, repoID string, results []TestPayload) error
+	// GetFailures returns the locators of the tests which failed in their latest execution
+	GetFailures(ctx context.Context, repoID, branch string) ([]string, error)
 }
 
 // SecretParser defines operation for parsing the vault secrets in given path
 type SecretParser interface {
 	GetOauthSecret(filepath string) (*Oauth, error)
-	GetRepoSecret(string) (map[string]string, error)
+	GetRepoSecret(ctx context.Context, path string) (map[string]string, error)
 	SubstituteSecret(command string, secretData map[string]string) (string, error)
 }
 
@@ -115,8 +254,15 @@ type ExecutionManager interface {
 	ExecuteUserCommands(ctx context.Context, commandType CommandType, payload *Payload, runConfig *Run, secretData map[string]string) error
 	// ExecuteInternalCommands executes the commands like installing runners and test discovery.
 	ExecuteInternalCommands(ctx context.Context, commandType CommandType, commands []string, cwd string, envMap, secretData map[string]string) error
-	// GetEnvVariables get the environment variables from the env map given by user.
-	GetEnvVariables(envMap, secretData map[string]string) ([]string, error)
+	// GetEnvVariables returns the environment of the process merged with the env maps given by the user, the
+	// later maps override the earlier ones. The secrets in the values are substituted.
+	GetEnvVariables(secretData map[string]string, envMaps ...map[string]string) ([]string, error)
 	// StoreCommandLogs stores the command logs in the azure.
 	StoreCommandLogs(ctx context.Context, blobPath string, reader io.Reader) <-chan error
+	// RecordCommand adds the exited command to the audit log along with the stage of ctx, err is the error
+	// returned by waiting for the command. The secrets are masked in the arguments.
+	RecordCommand(ctx context.Context, commandType CommandType, cmd *exec.Cmd, startTime time.Time, secretData map[string]string, err error)
+	// EventSubscriber uploads the audit log of the commands next to the command logs of the task once the
+	// task.completed event is emitted
+	EventSubscriber
 }
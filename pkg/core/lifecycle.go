@@ -8,32 +8,44 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"strconv"
+	"runtime"
 	"time"
 
 	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/errs"
-	"github.com/LambdaTest/synapse/pkg/fileutils"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
-	endpointPostTestResults = "http://localhost:9876/results"
+	endpointPostTestResults = "/results"
+	// endpointLocalTestList captures the discovered tests locally instead of sending them to neuron,
+	// they are sent to neuron by nucleus if the discovery is cached
+	endpointLocalTestList = "/test-list"
+	// endpointNeuronReport is resolved for each report, as the neuron host can be reloaded
+	endpointNeuronReport = "/report"
+	// checkpointTimeout bounds saving the checkpoint within the graceful shutdown period
+	checkpointTimeout = 20 * time.Second
 )
 
 var endpointPostTestList string
-var endpointNeuronReport string
+
+// ExitCodePayloadSignature is the exit code of nucleus when the signature of the payload is rejected
+const ExitCodePayloadSignature = 3
 
 // NewPipeline creates and returns a new Pipeline instance
 func NewPipeline(cfg *config.NucleusConfig, logger lumber.Logger) (*Pipeline, error) {
 	return &Pipeline{
-		Cfg:    cfg,
-		Logger: logger,
-		HttpClient: http.Client{
-			Timeout: 45 * time.Second,
-		},
+		Cfg:        cfg,
+		Logger:     logger,
+		HttpClient: requestutils.NewResilientClient(45 * time.Second),
+		Events:     NewEventBus(),
 	}, nil
 }
 
@@ -42,48 +54,51 @@ func (pl *Pipeline) Start(ctx context.Context) (err error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	var errRemark string
+	ctx, span := tracing.StartSpan(ctx, "pipeline.Start")
+	defer func() { tracing.EndSpan(span, err) }()
+	// the task timeout is applied on top, the pipeline context is only cancelled on shutdown
+	pipelineCtx := ctx
+
 	startTime := time.Now()
 
 	pl.Logger.Debugf("Starting pipeline.....")
 	pl.Logger.Debugf("Fetching config")
 
-	endpointPostTestList = global.NeuronHost + "/test-list"
-	endpointNeuronReport = global.NeuronHost + "/report"
-	// fetch configuration
-	payload, err := pl.PayloadManager.FetchPayload(ctx, pl.Cfg.PayloadAddress)
-	if err != nil {
-		pl.Logger.Fatalf("error while fetching payload: %v", err)
-	}
-
-	err = pl.PayloadManager.ValidatePayload(ctx, payload)
-	if err != nil {
-		pl.Logger.Fatalf("error while validating payload %v", err)
+	endpointPostTestList = global.NeuronURL(endpointNeuronTestList)
+	if pl.Cfg.DryRun || pl.Cfg.DiscoveryCache {
+		endpointPostTestList = pl.localURL(endpointLocalTestList)
 	}
-
-	pl.Logger.Debugf("Payload for current task: %+v \n", *payload)
-
-	if pl.Cfg.CoverageMode {
-		if err := pl.CoverageService.MergeAndUpload(ctx, payload); err != nil {
-			pl.Logger.Fatalf("error while merge and upload coverage files %v", err)
+	state := &StageState{}
+	// fetch configuration
+	for _, stage := range pl.withCustomStages(newStage(StagePayload, pl.fetchPayload)) {
+		if err = pl.runStage(ctx, stage, state); err != nil {
+			if errors.Is(err, errs.ErrPayloadSignature) {
+				// the untrusted payload is not reported, the exit code tells the rejection apart
+				pl.Logger.Errorf("%s stage failed: %v", stage.Name(), err)
+				os.Exit(ExitCodePayloadSignature)
+			}
+			pl.Logger.Fatalf("%s stage failed: %v", stage.Name(), err)
 		}
-		os.Exit(0)
-	}
-
-	oauth, err := pl.SecretParser.GetOauthSecret(global.OauthSecretPath)
-	if err != nil {
-		pl.Logger.Fatalf("failed to get oauth secret %v", err)
 	}
-
-	// set payload on pipeline object
-	pl.Payload = payload
-	if pl.Cfg.ParseMode {
-		err = pl.GitManager.CloneYML(ctx, payload, oauth.Data.AccessToken)
-		if err != nil {
-			pl.Logger.Fatalf("failed to clone YML for build ID: %s, error: %v", payload.BuildID, err)
+	payload := state.Payload
+	span.SetAttributes(
+		attribute.String("tas.task_id", payload.TaskID),
+		attribute.String("tas.build_id", payload.BuildID),
+		attribute.String("tas.repo_id", payload.RepoID),
+		attribute.String("tas.org_id", payload.OrgID),
+		attribute.String("tas.commit_id", payload.TargetCommit),
+	)
+
+	// the coverage and parse modes exit once their stages completed, they do not report the task status
+	if pl.Cfg.CoverageMode || pl.Cfg.ParseMode {
+		modeStage := newStage(StageParse, pl.parse)
+		if pl.Cfg.CoverageMode {
+			modeStage = newStage(StageCoverage, pl.mergeCoverage)
 		}
-		if err = pl.ParserService.PerformParsing(payload); err != nil {
-			pl.Logger.Fatalf("error while parsing YML for build ID: %s, error: %v", payload.BuildID, err)
+		for _, stage := range pl.withCustomStages(modeStage) {
+			if err = pl.runStage(ctx, stage, state); err != nil {
+				pl.Logger.Fatalf("%s stage failed: %v", stage.Name(), err)
+			}
 		}
 		os.Exit(0)
 	}
@@ -99,18 +114,28 @@ func (pl *Pipeline) Start(ctx context.Context) (err error) {
 		GitProvider: payload.GitProvider,
 		StartTime:   startTime,
 		Status:      Running,
+		RerunOf:     payload.RerunOf,
 	}
-	if pl.Cfg.DiscoverMode {
+	if pl.Cfg.DiscoverMode || pl.Cfg.DryRun {
 		taskPayload.Type = DiscoveryTask
 	} else {
 		taskPayload.Type = ExecutionTask
 	}
+	state.Task = taskPayload
 
-	// marking task to running state
-	if err := pl.Task.UpdateStatus(taskPayload); err != nil {
-		pl.Logger.Fatalf("failed to update task status %v", err)
+	// the task status is not reported in dry run mode, as no tests are executed
+	if !pl.Cfg.DryRun {
+		// marking task to running state
+		if err := pl.Task.UpdateStatus(taskPayload); err != nil {
+			pl.Logger.Fatalf("failed to update task status %v", err)
+		}
+		pl.emitTaskEvent(ctx, EventTaskStarted, taskPayload, nil)
 	}
 
+	state.CoverageDir = filepath.Join(global.CodeCoveragParentDir, payload.OrgID, payload.RepoID, payload.TargetCommit)
+	// deadlineRemark is the remark of the task if the earliest of the duration limit and the task timeout is
+	// exceeded
+	deadlineRemark := ""
 	// update task status when pipeline exits
 	defer func() {
 		taskPayload.EndTime = time.Now()
@@ -119,211 +144,254 @@ func (pl *Pipeline) Start(ctx context.Context) (err error) {
 			taskPayload.Status = Error
 			taskPayload.Remark = errs.GenericUserFacingBEErrRemark
 		} else if err != nil {
-			if err == context.Canceled {
+			if deadlineRemark != "" && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				taskPayload.Status = Error
+				taskPayload.Remark = deadlineRemark
+			} else if state.recorder != nil && pipelineCtx.Err() == context.Canceled {
+				taskPayload.Status = Interrupted
+				taskPayload.Remark = "Task interrupted, the remaining tests are run when the task is resumed"
+				if checkpointErr := pl.saveCheckpoint(state.recorder.checkpoint(payload.TaskID), state.CoverageDir); checkpointErr != nil {
+					pl.Logger.Errorf("failed to save checkpoint: %v", checkpointErr)
+					taskPayload.Status = Aborted
+					taskPayload.Remark = "Task aborted"
+				}
+			} else if err == context.Canceled {
 				taskPayload.Status = Aborted
 				taskPayload.Remark = "Task aborted"
 			} else {
 				taskPayload.Status = Error
-				taskPayload.Remark = errRemark
+				taskPayload.Remark = state.ErrRemark
+			}
+		}
+		pl.completeHooks(state, taskPayload)
+		if pl.Cfg.DryRun {
+			return
+		}
+		if taskPayload.Type == ExecutionTask {
+			if saveErr := pl.ResultStore.SaveRun(context.Background(), payload.BranchName, taskPayload, state.Result); saveErr != nil {
+				pl.Logger.Errorf("failed to save the run to the results store: %v", saveErr)
 			}
 		}
+		// context of the pipeline is cancelled if the task is aborted, the subscribers e.g. upload the audit log
+		pl.emitTaskEvent(context.Background(), EventTaskCompleted, taskPayload, nil)
 		if err := pl.Task.UpdateStatus(taskPayload); err != nil {
 			pl.Logger.Fatalf("failed to update task status %v", err)
 		}
 	}()
-
-	coverageDir := filepath.Join(global.CodeCoveragParentDir, payload.OrgID, payload.RepoID, payload.TargetCommit)
-	pl.Logger.Infof("Cloning repo ...")
-	err = pl.GitManager.Clone(ctx, pl.Payload, oauth.Data.AccessToken)
-	if err != nil {
-		pl.Logger.Errorf("Unable to clone repo '%s': %s", payload.RepoLink, err)
-		errRemark = fmt.Sprintf("Unable to clone repo: %s", payload.RepoLink)
-		return err
-	}
-
-	// load tas yaml file
-	tasConfig, err := pl.TASConfigManager.LoadConfig(ctx, payload.TasFileName, payload.EventType, false)
-	if err != nil {
-		pl.Logger.Errorf("Unable to load tas yaml file, error: %v", err)
-		errRemark = err.Error()
-		return err
+	// e.g. the services are stopped before the task status is updated
+	defer state.cleanup()
+
+	// the duration limit of the runner applies from the start of the task
+	if maxDuration := time.Duration(pl.Cfg.Limits.MaxDuration) * time.Second; maxDuration > 0 {
+		var cancelLimit context.CancelFunc
+		ctx, cancelLimit = context.WithDeadline(ctx, startTime.Add(maxDuration))
+		defer cancelLimit()
+		deadlineRemark = fmt.Sprintf("Task exceeded the duration limit of %s of the runner", maxDuration)
 	}
-
-	pl.Logger.Infof("Tas yaml: %+v", tasConfig)
-
-	// set testing taskID, orgID and buildID as environment variable
-	os.Setenv("TASK_ID", payload.TaskID)
-	os.Setenv("ORG_ID", payload.OrgID)
-	os.Setenv("BUILD_ID", payload.BuildID)
-	//set commit_id as environment variable
-	os.Setenv("COMMIT_ID", payload.TargetCommit)
-	//set repo_id as environment variable
-	os.Setenv("REPO_ID", payload.RepoID)
-	//set coverage_dir as environment variable
-	os.Setenv("CODE_COVERAGE_DIR", coverageDir)
-	os.Setenv("BRANCH_NAME", payload.BranchName)
-	os.Setenv("ENV", pl.Cfg.Env)
-	os.Setenv("TAS_PARALLELISM", strconv.Itoa(tasConfig.Parallelism))
-	os.Setenv("ENDPOINT_POST_TEST_LIST", endpointPostTestList)
-	os.Setenv("ENDPOINT_POST_TEST_RESULTS", endpointPostTestResults)
-	os.Setenv("REPO_ROOT", global.RepoDir)
-	os.Setenv("BLOCKLISTED_TESTS_FILE", global.BlocklistedFileLocation)
-
-	if tasConfig.NodeVersion != nil {
-		nodeVersion := tasConfig.NodeVersion.String()
-		// Running the `source` command in a directory where .nvmrc is present, exits with exitCode 3
-		// https://github.com/nvm-sh/nvm/issues/1985
-		// TODO [good-to-have]: Auto-read and install from .nvmrc file, if present
-		command := []string{"source", "/home/nucleus/.nvm/nvm.sh",
-			"&&", "nvm", "install", nodeVersion}
-		pl.Logger.Infof("Using user-defined node version: %v", nodeVersion)
-		err = pl.ExecutionManager.ExecuteInternalCommands(ctx, InstallNodeVer, command, "", nil, nil)
-		if err != nil {
-			pl.Logger.Errorf("Unable to install user-defined nodeversion %v", err)
-			errRemark = errs.GenericUserFacingBEErrRemark
+	timeoutSet := false
+	for _, stage := range pl.withCustomStages(pl.taskStages()...) {
+		if err = pl.runStage(ctx, stage, state); err != nil {
 			return err
 		}
-		origPath := os.Getenv("PATH")
-		os.Setenv("PATH", fmt.Sprintf("/home/nucleus/.nvm/versions/node/v%s/bin:%s", nodeVersion, origPath))
-	}
-
-	if payload.CollectCoverage {
-		if err = fileutils.CreateIfNotExists(coverageDir, true); err != nil {
-			pl.Logger.Errorf("failed to create coverage directory %v", err)
-			errRemark = errs.GenericUserFacingBEErrRemark
-			return err
+		if state.Done {
+			return nil
+		}
+		// the task timeout applies from the stage loading the configuration onwards
+		if !timeoutSet && state.TASConfig != nil && state.TASConfig.Timeouts.Task > 0 {
+			// the running commands are killed with their process group when the task times out
+			timeout := time.Duration(state.TASConfig.Timeouts.Task) * time.Second
+			if deadline, ok := ctx.Deadline(); !ok || time.Now().Add(timeout).Before(deadline) {
+				deadlineRemark = fmt.Sprintf("Task timed out after %s", timeout)
+			}
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+			defer cancelTimeout()
+			timeoutSet = true
 		}
 	}
+	pl.Logger.Debugf("Completed pipeline")
+
+	return nil
+}
 
-	err = pl.TestBlockListService.GetBlockListedTests(ctx, tasConfig, payload.RepoID)
+func (pl *Pipeline) sendStats(ctx context.Context, payload ExecutionResult) error {
+	reqBody, err := json.Marshal(payload)
 	if err != nil {
-		pl.Logger.Errorf("Unable to fetch blocklisted tests: %v", err)
-		errRemark = errs.GenericUserFacingBEErrRemark
+		pl.Logger.Errorf("failed to marshal request body %v", err)
 		return err
 	}
 
-	// read secrets
-	secretMap, err := pl.SecretParser.GetRepoSecret(global.RepoSecretPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, global.NeuronURL(endpointNeuronReport), bytes.NewBuffer(reqBody))
 	if err != nil {
-		pl.Logger.Errorf("Error in fetching Repo secrets %v", err)
-		errRemark = errs.GenericUserFacingBEErrRemark
+		pl.Logger.Errorf("failed to create new request %v", err)
 		return err
 	}
+	tracing.InjectHeaders(ctx, req.Header)
 
-	cacheKey := fmt.Sprintf("%s/%s/%s", payload.OrgID, payload.RepoID, tasConfig.Cache.Key)
-	// TODO:  download from cdn
-	if err = pl.CacheStore.Download(ctx, cacheKey); err != nil {
-		pl.Logger.Errorf("Unable to download cache: %v", err)
-		errRemark = errs.GenericUserFacingBEErrRemark
+	resp, err := pl.HttpClient.Do(req)
+
+	if err != nil {
+		pl.Logger.Errorf("error while sending reports %v", err)
 		return err
 	}
 
-	if tasConfig.Prerun != nil {
-		pl.Logger.Infof("Running pre-run steps")
-		err = pl.ExecutionManager.ExecuteUserCommands(ctx, PreRun, payload, tasConfig.Prerun, secretMap)
-		if err != nil {
-			pl.Logger.Errorf("Unable to run pre-run steps %v", err)
-			errRemark = "Error occurred in pre-run steps"
-			return err
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		pl.Logger.Errorf("error while sending reports, non 200 status")
+		return errors.New("non 200 status")
+	}
+	return nil
+}
+
+// localURL returns the URL of the path of the API server of nucleus, the port differs for each sub-task of a batch
+func (pl *Pipeline) localURL(path string) string {
+	return "http://localhost:" + pl.Cfg.Port + path
+}
+
+// withImpactedFiles returns the diff with the files transitively importing the changed files marked as
+// modified, as the runners only select the tests depending on the changed files directly.
+func (pl *Pipeline) withImpactedFiles(ctx context.Context, diff map[string]int) map[string]int {
+	if len(diff) == 0 {
+		return diff
+	}
+	impactedFiles, err := pl.ImpactAnalyzer.ImpactedFiles(ctx, pl.Payload, diff)
+	if err != nil {
+		pl.Logger.Warnf("failed to analyze the impacted files, only the changed files are used: %v", err)
+		return diff
+	}
+	result := make(map[string]int, len(diff)+len(impactedFiles))
+	for file, changeType := range diff {
+		result[file] = changeType
+	}
+	for _, file := range impactedFiles {
+		if _, ok := result[file]; !ok {
+			result[file] = FileModified
 		}
 	}
-	err = pl.ExecutionManager.ExecuteInternalCommands(ctx, InstallRunners, global.InstallRunnerCmd, global.RepoDir, nil, nil)
+	return result
+}
+
+// checkoutSparseDirs adds the directories referenced by the configuration and the directories of the changed
+// files to the sparse checkout: the package directories, the static prefixes of the test patterns and the
+// directories of the config files. The patterns and config files of the packages are relative to the root.
+func (pl *Pipeline) checkoutSparseDirs(ctx context.Context, tasConfig *TASConfig, cloneToken string) error {
+	diff, err := pl.DiffManager.GetChangedFiles(ctx, pl.Payload, cloneToken)
 	if err != nil {
-		pl.Logger.Errorf("Unable to install custom runners %v", err)
-		errRemark = errs.GenericUserFacingBEErrRemark
 		return err
 	}
-
-	if pl.Cfg.DiscoverMode {
-		pl.Logger.Infof("Identifying changed files ...")
-		diff, err := pl.DiffManager.GetChangedFiles(ctx, payload, oauth.Data.AccessToken)
-		if err != nil {
-			pl.Logger.Errorf("Unable to identify changed files %s", err)
-			errRemark = "Error occurred in fetching diff from GitHub"
-			return err
+	dirs := make([]string, 0, len(diff))
+	for file, changeType := range diff {
+		if changeType != FileRemoved {
+			dirs = append(dirs, path.Dir(file))
 		}
-
-		// discover test cases
-		err = pl.TestDiscoveryService.Discover(ctx, tasConfig, pl.Payload, secretMap, diff)
-		if err != nil {
-			pl.Logger.Errorf("Unable to perform test discovery: %+v", err)
-			errRemark = "Error occurred in discovering tests"
-			return err
+	}
+	for _, target := range append([]*TASConfig{tasConfig}, tasConfig.SubConfigs...) {
+		dirs = append(dirs, target.Dir)
+		for _, merge := range []*Merge{target.Premerge, target.Postmerge} {
+			if merge == nil {
+				continue
+			}
+			for _, pattern := range merge.Patterns {
+				dirs = append(dirs, utils.GlobBase(pattern))
+			}
 		}
-		// mark status as passed
-		taskPayload.Status = Passed
+		if target.ConfigFile != "" {
+			dirs = append(dirs, path.Dir(target.ConfigFile))
+		}
+	}
+	return pl.GitManager.SparseCheckout(ctx, pl.Payload, cloneToken, dirs)
+}
 
+// runHook runs the commands of the hook, if configured, and records the duration of the hook in the test stats
+func (pl *Pipeline) runHook(ctx context.Context, hookType CommandType, hook *Run, secretMap map[string]string) error {
+	if hook == nil {
+		return nil
 	}
+	pl.Logger.Infof("Running %s hook", hookType)
+	start := time.Now()
+	err := pl.ExecutionManager.ExecuteUserCommands(ctx, hookType, pl.Payload, hook, secretMap)
+	timing := HookTiming{Hook: hookType, Duration: time.Since(start).Milliseconds(), Status: Passed}
+	if err != nil {
+		pl.Logger.Errorf("Unable to run %s hook %v", hookType, err)
+		timing.Status = Failed
+	}
+	pl.TestStats.RecordHookTiming(timing)
+	return err
+}
 
-	if pl.Cfg.ExecuteMode {
-		// execute test cases
-		executionResult, err := pl.TestExecutionService.Run(ctx, tasConfig, pl.Payload, coverageDir, secretMap)
-		if err != nil {
-			pl.Logger.Infof("Unable to perform test execution: %v", err)
-			errRemark = "Error occurred in executing tests"
-			return err
-		}
+// completeHooks runs the onFailure hook if the task failed and sets the durations of the hooks run by the task
+func (pl *Pipeline) completeHooks(state *StageState, taskPayload *TaskPayload) {
+	if state.TASConfig != nil && (taskPayload.Status == Error || taskPayload.Status == Failed) {
+		// failures of the hook are only logged, the task has already failed
+		onFailure := state.TASConfig.ScopedRun(EnvStageExecution, state.TASConfig.Hooks.OnFailure)
+		_ = pl.runHook(context.Background(), HookOnFailure, onFailure, state.SecretMap)
+	}
+	taskPayload.Hooks = pl.TestStats.HookTimings()
+}
 
-		if err = pl.sendStats(*executionResult); err != nil {
-			pl.Logger.Errorf("error while sending test reports %v", err)
-			errRemark = errs.GenericUserFacingBEErrRemark
-			return err
+// runUserCommands runs the pre or post run commands of the root configuration and then of each package
+func (pl *Pipeline) runUserCommands(ctx context.Context, commandType CommandType, tasConfig *TASConfig, secretMap map[string]string) error {
+	configs := []*TASConfig{tasConfig}
+	configs = append(configs, tasConfig.SubConfigs...)
+	for _, c := range configs {
+		runConfig := c.ScopedRun(EnvStageInstall, c.Prerun)
+		if commandType == PostRun {
+			runConfig = c.ScopedRun(EnvStageExecution, c.Postrun)
 		}
-		taskPayload.Status = Passed
-		for i := 0; i < len(executionResult.TestPayload); i++ {
-			testResult := &executionResult.TestPayload[i]
-			if testResult.Status == "failed" {
-				taskPayload.Status = Failed
-				break
-			}
+		if runConfig == nil {
+			continue
 		}
-
-		if tasConfig.Postrun != nil {
-			pl.Logger.Infof("Running post-run steps")
-			err = pl.ExecutionManager.ExecuteUserCommands(ctx, PostRun, payload, tasConfig.Postrun, secretMap)
-			if err != nil {
-				pl.Logger.Errorf("Unable to run post-run steps %v", err)
-				errRemark = "Error occurred in pre-run steps"
-				return err
-			}
+		pl.Logger.Infof("Running %s steps of %s", commandType, c.File)
+		if err := pl.ExecutionManager.ExecuteUserCommands(ctx, commandType, pl.Payload, runConfig, secretMap); err != nil {
+			pl.Logger.Errorf("Unable to run %s steps of %s %v", commandType, c.File, err)
+			return err
 		}
 	}
-	if err = pl.CacheStore.Upload(ctx, cacheKey, tasConfig.Cache.Paths...); err != nil {
-		pl.Logger.Errorf("Unable to upload cache: %v", err)
-		errRemark = errs.GenericUserFacingBEErrRemark
-		return err
-	}
-	pl.Logger.Debugf("Cache uploaded successfully")
-	pl.Logger.Debugf("Completed pipeline")
-
 	return nil
 }
 
-func (pl *Pipeline) sendStats(payload ExecutionResult) error {
-	reqBody, err := json.Marshal(payload)
-	if err != nil {
-		pl.Logger.Errorf("failed to marshal request body %v", err)
-		return err
+func newNotificationTest(test *TestPayload) NotificationTest {
+	name := test.FullTitle
+	if name == "" {
+		name = test.Title
 	}
-
-	req, err := http.NewRequest(http.MethodPost, endpointNeuronReport, bytes.NewBuffer(reqBody))
-	if err != nil {
-		pl.Logger.Errorf("failed to create new request %v", err)
-		return err
+	return NotificationTest{
+		TestID:          test.TestID,
+		Name:            name,
+		Locator:         test.Filelocator,
+		Status:          test.Status,
+		BlocklistSource: test.BlocklistSource,
 	}
+}
 
-	resp, err := pl.HttpClient.Do(req)
-
-	if err != nil {
-		pl.Logger.Errorf("error while sending reports %v", err)
+// saveCheckpoint stores the completed tests of the interrupted task, the pipeline context is already cancelled
+func (pl *Pipeline) saveCheckpoint(checkpoint *Checkpoint, coverageDir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+	checkpoint.Coverage = pl.Payload.CollectCoverage
+	if err := pl.CheckpointManager.Save(ctx, pl.Payload, checkpoint, coverageDir); err != nil {
 		return err
 	}
+	pl.Logger.Infof("saved checkpoint with %d completed tests", len(checkpoint.TestPayload))
+	return nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		pl.Logger.Errorf("error while sending reports, non 200 status")
-		return errors.New("non 200 status")
+// useNodeVersion installs the node version with nvm and prepends its binaries to the given PATH
+func (pl *Pipeline) useNodeVersion(ctx context.Context, nodeVersion, path string) error {
+	if runtime.GOOS == "windows" {
+		// nvm is a bash script, the node version is installed on the windows agents beforehand
+		return fmt.Errorf("installing node %s with nvm is not supported on windows", nodeVersion)
 	}
-	return nil
+	// Running the `source` command in a directory where .nvmrc is present, exits with exitCode 3
+	// https://github.com/nvm-sh/nvm/issues/1985
+	// TODO [good-to-have]: Auto-read and install from .nvmrc file, if present
+	command := []string{"source", "/home/nucleus/.nvm/nvm.sh",
+		"&&", "nvm", "install", nodeVersion}
+	pl.Logger.Infof("Using user-defined node version: %v", nodeVersion)
+	if err := pl.ExecutionManager.ExecuteInternalCommands(ctx, InstallNodeVer, command, "", nil, nil); err != nil {
+		pl.Logger.Errorf("Unable to install user-defined nodeversion %v", err)
+		return err
+	}
+	return os.Setenv("PATH", fmt.Sprintf("/home/nucleus/.nvm/versions/node/v%s/bin%c%s", nodeVersion, os.PathListSeparator, path))
 }
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutionManager records the types of the commands it runs, the commands of the types in fail fail
+type fakeExecutionManager struct {
+	commands []CommandType
+	fail     map[CommandType]bool
+}
+
+func (m *fakeExecutionManager) ExecuteUserCommands(ctx context.Context, commandType CommandType, payload *Payload,
+	runConfig *Run, secretData map[string]string) error {
+	return m.run(commandType)
+}
+
+func (m *fakeExecutionManager) ExecuteInternalCommands(ctx context.Context, commandType CommandType, commands []string,
+	cwd string, envMap, secretData map[string]string) error {
+	return m.run(commandType)
+}
+
+func (m *fakeExecutionManager) run(commandType CommandType) error {
+	m.commands = append(m.commands, commandType)
+	if m.fail[commandType] {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func (m *fakeExecutionManager) GetEnvVariables(secretData map[string]string, envMaps ...map[string]string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *fakeExecutionManager) StoreCommandLogs(ctx context.Context, blobPath string, reader io.Reader) <-chan error {
+	errChan := make(chan error, 1)
+	errChan <- nil
+	return errChan
+}
+
+func (m *fakeExecutionManager) RecordCommand(ctx context.Context, commandType CommandType, cmd *exec.Cmd,
+	startTime time.Time, secretData map[string]string, err error) {
+}
+
+func (m *fakeExecutionManager) HandleEvent(ctx context.Context, event *Event) {}
+
+// fakeTestStats records the hook timings
+type fakeTestStats struct {
+	timings []HookTiming
+}
+
+func (s *fakeTestStats) CaptureTestStats(pid int32) error {
+	return nil
+}
+
+func (s *fakeTestStats) RecordHookTiming(timing HookTiming) {
+	s.timings = append(s.timings, timing)
+}
+
+func (s *fakeTestStats) HookTimings() []HookTiming {
+	return s.timings
+}
+
+func (s *fakeTestStats) Subscribe() (<-chan ExecutionResult, func()) {
+	return make(chan ExecutionResult), func() {}
+}
+
+func newTestPipeline(t *testing.T, fail ...CommandType) (*Pipeline, *fakeExecutionManager) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	execManager := &fakeExecutionManager{fail: make(map[CommandType]bool)}
+	for _, commandType := range fail {
+		execManager.fail[commandType] = true
+	}
+	pl := &Pipeline{
+		Cfg:              &config.NucleusConfig{},
+		Logger:           logger,
+		Payload:          &Payload{},
+		ExecutionManager: execManager,
+		TestStats:        &fakeTestStats{},
+		Events:           NewEventBus(),
+	}
+	return pl, execManager
+}
+
+func newHookRun(command string) *Run {
+	return &Run{Commands: []Command{{Run: command}}}
+}
+
+func hookStatuses(timings []HookTiming) map[CommandType]Status {
+	statuses := make(map[CommandType]Status, len(timings))
+	for _, timing := range timings {
+		statuses[timing.Hook] = timing.Status
+	}
+	return statuses
+}
+
+func TestHookOrder(t *testing.T) {
+	pl, execManager := newTestPipeline(t)
+	state := &StageState{TASConfig: &TASConfig{
+		Prerun: newHookRun("npm ci"),
+		Hooks: Hooks{
+			PreInstall:  newHookRun("echo preInstall"),
+			PostInstall: newHookRun("echo postInstall"),
+			OnFailure:   newHookRun("echo onFailure"),
+		},
+	}}
+	assert.Nil(t, pl.install(context.Background(), state))
+
+	taskPayload := &TaskPayload{Status: Failed}
+	pl.completeHooks(state, taskPayload)
+	assert.Equal(t, []CommandType{HookPreInstall, PreRun, InstallRunners, HookPostInstall, HookOnFailure}, execManager.commands)
+	hooks := make([]CommandType, 0, len(taskPayload.Hooks))
+	for _, timing := range taskPayload.Hooks {
+		hooks = append(hooks, timing.Hook)
+	}
+	assert.Equal(t, []CommandType{HookPreInstall, HookPostInstall, HookOnFailure}, hooks)
+}
+
+func TestRunHook(t *testing.T) {
+	pl, execManager := newTestPipeline(t, HookPreRun)
+
+	// hooks which are not configured are neither run nor timed
+	assert.Nil(t, pl.runHook(context.Background(), HookPostRun, nil, nil))
+	assert.Empty(t, execManager.commands)
+	assert.Empty(t, pl.TestStats.HookTimings())
+
+	assert.Nil(t, pl.runHook(context.Background(), HookPreDiscovery, newHookRun("echo ok"), nil))
+	assert.NotNil(t, pl.runHook(context.Background(), HookPreRun, newHookRun("exit 1"), nil))
+	assert.Equal(t, map[CommandType]Status{HookPreDiscovery: Passed, HookPreRun: Failed}, hookStatuses(pl.TestStats.HookTimings()))
+}
+
+func TestInstallHookFailure(t *testing.T) {
+	pl, execManager := newTestPipeline(t, HookPreInstall)
+	state := &StageState{TASConfig: &TASConfig{Hooks: Hooks{
+		PreInstall:  newHookRun("exit 1"),
+		PostInstall: newHookRun("echo postInstall"),
+	}}}
+
+	assert.NotNil(t, pl.install(context.Background(), state))
+	assert.Equal(t, "Error occurred in preInstall hook", state.ErrRemark)
+	// the stage stops at the failed hook
+	assert.Equal(t, []CommandType{HookPreInstall}, execManager.commands)
+	assert.Equal(t, map[CommandType]Status{HookPreInstall: Failed}, hookStatuses(pl.TestStats.HookTimings()))
+}
+
+func TestCompleteHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    Status
+		onFailure bool
+	}{
+		{"passed", Passed, false},
+		{"failed", Failed, true},
+		{"error", Error, true},
+		{"aborted", Aborted, false},
+		{"interrupted", Interrupted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, execManager := newTestPipeline(t, HookOnFailure)
+			pl.TestStats.RecordHookTiming(HookTiming{Hook: HookPreRun, Duration: 10, Status: Passed})
+			state := &StageState{TASConfig: &TASConfig{Hooks: Hooks{OnFailure: newHookRun("echo onFailure")}}}
+			taskPayload := &TaskPayload{Status: tt.status}
+
+			pl.completeHooks(state, taskPayload)
+			expected := map[CommandType]Status{HookPreRun: Passed}
+			if tt.onFailure {
+				assert.Equal(t, []CommandType{HookOnFailure}, execManager.commands)
+				// a failed onFailure hook does not change the status of the task
+				expected[HookOnFailure] = Failed
+			} else {
+				assert.Empty(t, execManager.commands)
+			}
+			assert.Equal(t, tt.status, taskPayload.Status)
+			assert.Equal(t, expected, hookStatuses(taskPayload.Hooks))
+		})
+	}
+
+	// the hooks are not run if the task failed before the configuration was loaded
+	pl, execManager := newTestPipeline(t)
+	taskPayload := &TaskPayload{Status: Error}
+	pl.completeHooks(&StageState{}, taskPayload)
+	assert.Empty(t, execManager.commands)
+	assert.Empty(t, taskPayload.Hooks)
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// MatrixNodeVersion is the dimension of the node versions in the matrix combinations
+const MatrixNodeVersion = "nodeVersion"
+
+// Matrix runs the tests for each combination of the node versions and the values of the env variables
+type Matrix struct {
+	NodeVersions []*semver.Version `yaml:"nodeVersion" validate:"omitempty,dive,required"`
+	// Env are the values of each env variable
+	Env map[string][]string `yaml:"env" validate:"omitempty,dive,keys,required,endkeys,required,dive,required"`
+	// Parallel runs each combination in a separate task, else the combinations are run one after another
+	Parallel bool `yaml:"parallel"`
+}
+
+// MatrixCombination is a value of each dimension of the matrix, keyed by MatrixNodeVersion or the env variable
+type MatrixCombination map[string]string
+
+// MatrixSubTask is a task of a parallel matrix build, neuron sets the matrix of the payload of the task
+type MatrixSubTask struct {
+	// Name tells the sub-tasks of a build apart, it is the name of the combination
+	Name   string            `json:"name"`
+	Matrix MatrixCombination `json:"matrix"`
+}
+
+// Combinations returns all the combinations of the matrix, nil if the matrix has no dimension
+func (m *Matrix) Combinations() []MatrixCombination {
+	dims := make([]string, 0, len(m.Env)+1)
+	values := make(map[string][]string, len(m.Env)+1)
+	if len(m.NodeVersions) > 0 {
+		dims = append(dims, MatrixNodeVersion)
+		for _, version := range m.NodeVersions {
+			values[MatrixNodeVersion] = append(values[MatrixNodeVersion], version.String())
+		}
+	}
+	envNames := make([]string, 0, len(m.Env))
+	for name := range m.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		dims = append(dims, name)
+		values[name] = m.Env[name]
+	}
+	if len(dims) == 0 {
+		return nil
+	}
+
+	combinations := []MatrixCombination{{}}
+	for _, dim := range dims {
+		next := make([]MatrixCombination, 0, len(combinations)*len(values[dim]))
+		for _, combination := range combinations {
+			for _, value := range values[dim] {
+				c := make(MatrixCombination, len(combination)+1)
+				for k, v := range combination {
+					c[k] = v
+				}
+				c[dim] = value
+				next = append(next, c)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// SubTasks expands the matrix into a sub-task for each combination if the matrix is parallel. The combinations
+// of a matrix which is not parallel are executed one after another by the task, nil is returned.
+func (m *Matrix) SubTasks() []MatrixSubTask {
+	if !m.Parallel {
+		return nil
+	}
+	combinations := m.Combinations()
+	if len(combinations) == 0 {
+		return nil
+	}
+	subTasks := make([]MatrixSubTask, 0, len(combinations))
+	for _, combination := range combinations {
+		subTasks = append(subTasks, MatrixSubTask{Name: combination.Name(), Matrix: combination})
+	}
+	return subTasks
+}
+
+// Name returns the values of the combination ordered by their dimension, usable in blob paths
+func (c MatrixCombination) Name() string {
+	dims := make([]string, 0, len(c))
+	for dim := range c {
+		dims = append(dims, dim)
+	}
+	sort.Strings(dims)
+	values := make([]string, 0, len(dims))
+	replacer := strings.NewReplacer("/", "-", " ", "-")
+	for _, dim := range dims {
+		values = append(values, replacer.Replace(c[dim]))
+	}
+	return strings.Join(values, "-")
+}
+
+// Env returns the env variables of the combination
+func (c MatrixCombination) Env() map[string]string {
+	env := make(map[string]string, len(c))
+	for dim, value := range c {
+		if dim != MatrixNodeVersion {
+			env[dim] = value
+		}
+	}
+	return env
+}
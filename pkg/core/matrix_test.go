@@ -0,0 +1,113 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrixCombinations(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix *Matrix
+		want   []MatrixCombination
+	}{
+		{
+			name:   "empty",
+			matrix: &Matrix{},
+			want:   nil,
+		},
+		{
+			name:   "node versions",
+			matrix: &Matrix{NodeVersions: []*semver.Version{semver.New("14.17.2"), semver.New("16.13.0")}},
+			want: []MatrixCombination{
+				{MatrixNodeVersion: "14.17.2"},
+				{MatrixNodeVersion: "16.13.0"},
+			},
+		},
+		{
+			name:   "env",
+			matrix: &Matrix{Env: map[string][]string{"TZ": {"UTC", "Asia/Kolkata"}, "DB": {"postgres"}}},
+			want: []MatrixCombination{
+				{"DB": "postgres", "TZ": "UTC"},
+				{"DB": "postgres", "TZ": "Asia/Kolkata"},
+			},
+		},
+		{
+			name: "node versions and env",
+			matrix: &Matrix{
+				NodeVersions: []*semver.Version{semver.New("14.17.2"), semver.New("16.13.0")},
+				Env:          map[string][]string{"TZ": {"UTC", "Asia/Kolkata"}},
+			},
+			want: []MatrixCombination{
+				{MatrixNodeVersion: "14.17.2", "TZ": "UTC"},
+				{MatrixNodeVersion: "14.17.2", "TZ": "Asia/Kolkata"},
+				{MatrixNodeVersion: "16.13.0", "TZ": "UTC"},
+				{MatrixNodeVersion: "16.13.0", "TZ": "Asia/Kolkata"},
+			},
+		},
+		{
+			name:   "dimension without values",
+			matrix: &Matrix{Env: map[string][]string{"TZ": {"UTC"}, "DB": {}}},
+			want:   []MatrixCombination{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.matrix.Combinations())
+		})
+	}
+}
+
+func TestMatrixSubTasks(t *testing.T) {
+	matrix := &Matrix{
+		NodeVersions: []*semver.Version{semver.New("14.17.2"), semver.New("16.13.0")},
+		Env:          map[string][]string{"TZ": {"UTC"}},
+	}
+	// the combinations are executed by the task
+	assert.Nil(t, matrix.SubTasks())
+
+	matrix.Parallel = true
+	assert.Equal(t, []MatrixSubTask{
+		{Name: "UTC-14.17.2", Matrix: MatrixCombination{MatrixNodeVersion: "14.17.2", "TZ": "UTC"}},
+		{Name: "UTC-16.13.0", Matrix: MatrixCombination{MatrixNodeVersion: "16.13.0", "TZ": "UTC"}},
+	}, matrix.SubTasks())
+	assert.Nil(t, (&Matrix{Parallel: true}).SubTasks())
+}
+
+func TestMatrixCombinationName(t *testing.T) {
+	tests := []struct {
+		name        string
+		combination MatrixCombination
+		want        string
+	}{
+		{"empty", MatrixCombination{}, ""},
+		{"node version", MatrixCombination{MatrixNodeVersion: "16.13.0"}, "16.13.0"},
+		// the values are ordered by dimension, the node version sorts after the upper case env variables
+		{"ordered", MatrixCombination{MatrixNodeVersion: "16.13.0", "TZ": "UTC", "DB": "postgres"}, "postgres-UTC-16.13.0"},
+		{"path separators and spaces", MatrixCombination{"TZ": "Asia/Kolkata", "NAME": "a b"}, "a-b-Asia-Kolkata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.combination.Name())
+		})
+	}
+}
+
+func TestMatrixCombinationEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		combination MatrixCombination
+		want        map[string]string
+	}{
+		{"nil", nil, map[string]string{}},
+		{"node version only", MatrixCombination{MatrixNodeVersion: "16.13.0"}, map[string]string{}},
+		{"env", MatrixCombination{MatrixNodeVersion: "16.13.0", "TZ": "UTC", "DB": "postgres"}, map[string]string{"TZ": "UTC", "DB": "postgres"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.combination.Env())
+		})
+	}
+}
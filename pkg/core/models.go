@@ -5,6 +5,7 @@ package core
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/LambdaTest/synapse/config"
@@ -54,11 +55,22 @@ const (
 	InstallNodeVer CommandType = "installnodeversion"
 )
 
+// Types of lifecycle hooks
+const (
+	HookPreInstall   CommandType = "hook-preinstall"
+	HookPostInstall  CommandType = "hook-postinstall"
+	HookPreDiscovery CommandType = "hook-prediscovery"
+	HookPreRun       CommandType = "hook-prerun"
+	HookPostRun      CommandType = "hook-postrun"
+	HookOnFailure    CommandType = "hook-onfailure"
+)
+
 // Types of containers
 const (
-	CacheContainer   ContainerType = "cache"
-	LogsContainer    ContainerType = "logs"
-	PayloadContainer ContainerType = "container-payload"
+	CacheContainer     ContainerType = "cache"
+	LogsContainer      ContainerType = "logs"
+	PayloadContainer   ContainerType = "container-payload"
+	ArtifactsContainer ContainerType = "artifacts"
 )
 
 // EventType represents the webhook event
@@ -69,8 +81,18 @@ const (
 	EventPush EventType = "push"
 	// EventPullRequest represents the pull request event.
 	EventPullRequest EventType = "pull-request"
+	// EventCron represents the scheduled builds, all the tests are run without a diff.
+	EventCron EventType = "cron"
+	// EventCommitRange represents the builds of an explicit commit range, the diff is computed between
+	// the build base and target commits of the payload.
+	EventCommitRange EventType = "commit-range"
 )
 
+// IsPremerge returns true if the tests of the event are configured by preMerge, else by postMerge
+func (e EventType) IsPremerge() bool {
+	return e == EventPullRequest
+}
+
 // CommitChangeList defines  information related to commits
 type CommitChangeList struct {
 	Sha      string   `json:"Sha"`
@@ -106,6 +128,16 @@ type Payload struct {
 	ParentCommitCoverageExists bool               `json:"parent_commit_coverage_exists"`
 	LicenseTier                Tier               `json:"license_tier"`
 	CollectCoverage            bool               `json:"collect_coverage"`
+	// Clone is set by neuron from the clone configuration reported when parsing the yml,
+	// the archive of the commit is downloaded if not set
+	Clone *CloneConfig `json:"clone"`
+	// Matrix is set by neuron for each sub-task of a parallel matrix build, see ParserStatus.SubTasks
+	Matrix MatrixCombination `json:"matrix"`
+	// Selection selects the tests of the task, see SelectionStrategy for the default
+	Selection *TestSelection `json:"selection"`
+	// RerunOf is the task whose failed tests are re-run, the selection is resolved from its results and
+	// the results are reported as a re-run of the task
+	RerunOf string `json:"rerun_of"`
 }
 
 // Pipeline defines all attributes of Pipeline
@@ -127,7 +159,24 @@ type Pipeline struct {
 	TestStats            TestStats
 	Task                 Task
 	SecretParser         SecretParser
+	Events               *EventBus
+	DryRunReporter       DryRunReporter
+	ConfigDetector       ConfigDetector
+	ServiceManager       ServiceManager
+	ArtifactManager      ArtifactManager
+	FailureReporter      FailureReporter
+	ImpactAnalyzer       ImpactAnalyzer
+	CheckpointManager    CheckpointManager
+	ToolchainManager     ToolchainManager
+	ResultExporter       ResultExporter
+	Annotator            Annotator
+	ResultStore          ResultStore
+	DiscoveryCache       DiscoveryCache
 	HttpClient           http.Client
+	// StageHooks are called before and after each stage
+	StageHooks   []StageHook
+	impact       impactState
+	customStages []customStage
 }
 
 // ExecutionResult represents the request body for test and test suite execution
@@ -139,6 +188,67 @@ type ExecutionResult struct {
 	CommitID         string             `json:"commitID"`
 	TestPayload      []TestPayload      `json:"testResults"`
 	TestSuitePayload []TestSuitePayload `json:"testSuiteResults"`
+	// Artifacts is the blob path of the archive with the artifacts not linked to a test
+	Artifacts string `json:"artifacts,omitempty"`
+	// ResourceUsage of the test process tree during the run
+	ResourceUsage *ResourceUsage `json:"resourceUsage,omitempty"`
+	// FileResourceUsage is only set for the test files which did not run concurrently with other files
+	FileResourceUsage []FileResourceUsage `json:"fileResourceUsage,omitempty"`
+	// FileLogs are the logs of the console output of each test file
+	FileLogs []FileLog `json:"fileLogs,omitempty"`
+	// LimitsExceeded are the limits of the task which truncated the run, e.g. LimitMaxTests
+	LimitsExceeded []string `json:"limitsExceeded,omitempty"`
+}
+
+// ResourceUsage summarizes the resource usage samples of the test processes,
+// memory is in bytes and cpu is in percentage of a core
+type ResourceUsage struct {
+	PeakCPU    float64 `json:"peak_cpu_percentage"`
+	AvgCPU     float64 `json:"avg_cpu_percentage"`
+	PeakMemory uint64  `json:"peak_memory_consumed"`
+	AvgMemory  uint64  `json:"avg_memory_consumed"`
+	DiskRead   uint64  `json:"disk_read_bytes"`
+	DiskWrite  uint64  `json:"disk_write_bytes"`
+	Samples    int     `json:"samples"`
+}
+
+// Merge adds the usage of a run which did not overlap in time with the usage
+func (u *ResourceUsage) Merge(other *ResourceUsage) *ResourceUsage {
+	if u == nil {
+		return other
+	}
+	if other == nil || other.Samples == 0 {
+		return u
+	}
+	merged := *u
+	if other.PeakCPU > merged.PeakCPU {
+		merged.PeakCPU = other.PeakCPU
+	}
+	if other.PeakMemory > merged.PeakMemory {
+		merged.PeakMemory = other.PeakMemory
+	}
+	merged.Samples = u.Samples + other.Samples
+	merged.AvgCPU = (u.AvgCPU*float64(u.Samples) + other.AvgCPU*float64(other.Samples)) / float64(merged.Samples)
+	merged.AvgMemory = (u.AvgMemory*uint64(u.Samples) + other.AvgMemory*uint64(other.Samples)) / uint64(merged.Samples)
+	merged.DiskRead += other.DiskRead
+	merged.DiskWrite += other.DiskWrite
+	return &merged
+}
+
+// FileResourceUsage is the resource usage while the tests of a file were run
+type FileResourceUsage struct {
+	File string `json:"file"`
+	ResourceUsage
+}
+
+// FileLog is the console output of a test file, captured separately from the output of the other files
+type FileLog struct {
+	File string `json:"file"`
+	// Path of the log served under /results/logs
+	Path string `json:"path"`
+	// Blob is the blob path of the uploaded log, empty if the upload failed
+	Blob string `json:"blob,omitempty"`
+	Size int    `json:"size"`
 }
 
 // TestPayload represents the request body for test execution
@@ -164,6 +274,10 @@ type TestPayload struct {
 	StartTime       time.Time          `json:"start_time"`
 	EndTime         time.Time          `json:"end_time"`
 	Stats           []TestProcessStats `json:"stats"`
+	// Artifact is the blob path of the archive with the artifacts of the test, e.g. screenshots
+	Artifact string `json:"artifact,omitempty"`
+	// Matrix is the matrix combination the test was executed with
+	Matrix MatrixCombination `json:"matrix,omitempty"`
 }
 
 // TestSuitePayload represents the request body for test suite execution
@@ -178,6 +292,8 @@ type TestSuitePayload struct {
 	Duration        int                `json:"duration"`
 	Status          string             `json:"status"`
 	Stats           []TestProcessStats `json:"stats"`
+	// Matrix is the matrix combination the suite was executed with
+	Matrix MatrixCombination `json:"matrix,omitempty"`
 }
 
 // TestProcessStats process stats associated with each test
@@ -193,12 +309,40 @@ type Status string
 
 // Const related to task status
 const (
-	Initiating Status = "initiating"
-	Running    Status = "running"
-	Failed     Status = "failed"
-	Aborted    Status = "aborted"
-	Passed     Status = "passed"
-	Error      Status = "error"
+	Initiating  Status = "initiating"
+	Running     Status = "running"
+	Failed      Status = "failed"
+	Aborted     Status = "aborted"
+	Passed      Status = "passed"
+	Error       Status = "error"
+	Interrupted Status = "interrupted"
+)
+
+// Status values of the test results
+const (
+	TestFailed = "failed"
+	// TestStarted is the status of the updates streamed by the runners when a test starts
+	TestStarted = "started"
+	// TestTimedOut is the status of the tests which exceeded their timeout or whose file exceeded the file timeout
+	TestTimedOut = "timeout"
+	// TestSkipped is the status of the skipped tests, including the tests not run after the fail fast limit is reached
+	TestSkipped = "skipped"
+	// TestLimitExceeded is the status of the tests not run as the task exceeded the maximum number of tests
+	TestLimitExceeded = "limit_exceeded"
+)
+
+// Names of the limits of the tasks reported in ExecutionResult.LimitsExceeded
+const (
+	LimitMaxTests        = "maxTests"
+	LimitMaxArtifactSize = "maxArtifactSize"
+)
+
+// Values of TASConfig.Order
+const (
+	OrderDefault = "default"
+	// OrderSmart runs the tests which failed recently first, followed by the tests without history
+	// i.e. the newly added tests, to give fail fast feedback
+	OrderSmart = "smart"
 )
 
 // ParserStatus repersent information related to each parsing
@@ -209,6 +353,14 @@ type ParserStatus struct {
 	Message        string `json:"message"`
 	Tier           Tier   `json:"tier"`
 	ContainerImage string `json:"container_image"`
+	// Clone is the clone configuration of the repository, passed back in the payload of the tasks
+	Clone *CloneConfig `json:"clone,omitempty"`
+	// SubTasks are the tasks neuron creates for the combinations of a parallel matrix build, the combinations
+	// of a matrix which is not parallel are executed one after another in the task
+	SubTasks []MatrixSubTask `json:"sub_tasks,omitempty"`
+	// Inferred is set if the configuration file does not exist, the configuration is inferred by the tasks
+	// from the manifests of the repository
+	Inferred bool `json:"inferred,omitempty"`
 }
 
 // ParserResponse repersent response of nucleus when runs on parsing mode
@@ -236,6 +388,57 @@ type TaskPayload struct {
 	EndTime     time.Time `json:"end_time,omitempty"`
 	Remark      string    `json:"remark,omitempty"`
 	Type        TaskType  `json:"type"`
+	// Hooks are the durations of the lifecycle hooks run by the task
+	Hooks []HookTiming `json:"hooks,omitempty"`
+	// RerunOf is the task whose failed tests are re-run by the task
+	RerunOf string `json:"rerun_of,omitempty"`
+}
+
+// HookTiming is the duration of a lifecycle hook
+type HookTiming struct {
+	Hook CommandType `json:"hook"`
+	// Duration in milliseconds
+	Duration int64  `json:"duration"`
+	Status   Status `json:"status"`
+}
+
+// CommandRecord is the audit record of a command spawned by nucleus
+type CommandRecord struct {
+	Time time.Time `json:"time"`
+	// Stage is the pipeline stage which requested the command, it is empty outside of the stages
+	Stage   string      `json:"stage,omitempty"`
+	Type    CommandType `json:"type"`
+	Command string      `json:"command"`
+	// Args are the arguments of the command with the secrets masked
+	Args []string `json:"args,omitempty"`
+	Dir  string   `json:"cwd"`
+	PID  int      `json:"pid,omitempty"`
+	// ExitCode is -1 if the command did not start or was killed by a signal
+	ExitCode int `json:"exit_code"`
+	// Duration in milliseconds
+	Duration int64  `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// NotificationEventType is the type of the event sent to the webhooks
+type NotificationEventType = PipelineEventType
+
+// NotificationEvent represents the payload sent to the webhooks
+type NotificationEvent struct {
+	Type      NotificationEventType `json:"type"`
+	Timestamp time.Time             `json:"timestamp"`
+	Task      TaskPayload           `json:"task"`
+	Stage     string                `json:"stage,omitempty"`
+	Tests     []NotificationTest    `json:"tests,omitempty"`
+}
+
+// NotificationTest represents the test of a test.failed or blocklist.hit event
+type NotificationTest struct {
+	TestID          string `json:"test_id"`
+	Name            string `json:"name"`
+	Locator         string `json:"locator"`
+	Status          string `json:"status"`
+	BlocklistSource string `json:"blocklist_source,omitempty"`
 }
 
 //CoverageMainfest for post processing coverage job
@@ -243,6 +446,8 @@ type CoverageMainfest struct {
 	Removedfiles      []string           `json:"removed_files"`
 	AllFilesExecuted  bool               `json:"all_files_executed"`
 	CoverageThreshold *CoverageThreshold `json:"coverage_threshold,omitempty"`
+	CoverageUpload    *CoverageUpload    `json:"coverage_upload,omitempty"`
+	CoverageCompare   bool               `json:"coverage_compare,omitempty"`
 }
 
 const (
@@ -259,6 +464,8 @@ const (
 	GitHub string = "github"
 	// GitLab as git provider
 	GitLab string = "gitlab"
+	// Bitbucket as git provider
+	Bitbucket string = "bitbucket"
 )
 
 // Oauth repersents the sructure of Oauth
@@ -274,19 +481,82 @@ type Oauth struct {
 type TASConfig struct {
 	SmartRun          bool               `yaml:"smartRun"`
 	Framework         string             `yaml:"framework" validate:"required,oneof=jest mocha jasmine"`
-	Blocklist         []string           `yaml:"blocklist"`
+	Blocklist         []BlocklistEntry   `yaml:"blocklist"`
 	Postmerge         *Merge             `yaml:"postMerge" validate:"omitempty"`
 	Premerge          *Merge             `yaml:"preMerge" validate:"omitempty"`
 	Cache             *Cache             `yaml:"cache" validate:"omitempty"`
+	Caches            []NamedCache       `yaml:"caches" validate:"omitempty,unique=Name,dive"`
 	Prerun            *Run               `yaml:"preRun" validate:"omitempty"`
 	Postrun           *Run               `yaml:"postRun" validate:"omitempty"`
 	Parallelism       int                `yaml:"parallelism"`
 	SkipCache         bool               `yaml:"skipCache"`
 	ConfigFile        string             `yaml:"configFile" validate:"omitempty"`
 	CoverageThreshold *CoverageThreshold `yaml:"coverageThreshold" validate:"omitempty"`
+	CoverageUpload    *CoverageUpload    `yaml:"coverageUpload" validate:"omitempty"`
+	CoverageCompare   bool               `yaml:"coverageCompare"`
 	Tier              Tier               `yaml:"tier" validate:"oneof=xsmall small medium large xlarge"`
 	NodeVersion       *semver.Version    `yaml:"nodeVersion"`
 	ContainerImage    string             `yaml:"containerImage"`
+	Hooks             Hooks              `yaml:"hooks"`
+	Timeouts          Timeouts           `yaml:"timeouts"`
+	Clone             *CloneConfig       `yaml:"clone" validate:"omitempty"`
+	Matrix            *Matrix            `yaml:"matrix" validate:"omitempty"`
+	Toolchains        *Toolchains        `yaml:"toolchains" validate:"omitempty"`
+	// Env is set in the environment of all the commands, see EnvScopes for the precedence of the env maps
+	Env map[string]string `yaml:"env"`
+	// StageEnv is set in the environment of the commands of a stage
+	StageEnv *StageEnv `yaml:"stageEnv" validate:"omitempty"`
+	// Order is the order in which the test locators are executed, see OrderSmart
+	Order string `yaml:"order" validate:"omitempty,oneof=default smart"`
+	// FailFast aborts the remaining tests after the given number of failed tests, disabled if 0
+	FailFast int `yaml:"failFast" validate:"min=0"`
+	// Workers is the number of runner processes the tests of the container are split across, unlike Parallelism
+	// which is the number of containers of the task. The tests run in a single runner if it is less than 2.
+	Workers int `yaml:"workers" validate:"min=0,max=32"`
+	// Services are keyed by their name, which is the hostname of the service in the tests
+	Services  map[string]Service `yaml:"services" validate:"omitempty,dive,keys,hostname_rfc1123,endkeys,required"`
+	Artifacts *Artifacts         `yaml:"artifacts" validate:"omitempty"`
+	// Include are the shared fragments the configuration is based on, the keys of the file override the ones of
+	// the fragments which override each other in order
+	Include []Include `yaml:"include" validate:"omitempty,dive"`
+	// Variables are substituted for the ${{ vars.NAME }} references of the file and of its fragments, the
+	// variables of the file override the ones of the fragments
+	Variables map[string]string `yaml:"variables"`
+	// Packages are glob patterns of the monorepo package directories having their own configuration file
+	Packages []string `yaml:"packages" validate:"omitempty,dive,required"`
+	// Dir is the package directory relative to the repository root, empty for the root configuration
+	Dir string `yaml:"-"`
+	// File is the path of the configuration file relative to the repository root
+	File string `yaml:"-"`
+	// SubConfigs are the configurations of the packages, which inherit the values of the root configuration
+	SubConfigs []*TASConfig `yaml:"-"`
+}
+
+// Targets returns the configurations for which the tests are discovered and executed,
+// the root configuration is only used as the base of the packages if any are configured.
+func (t *TASConfig) Targets() []*TASConfig {
+	if len(t.SubConfigs) > 0 {
+		return t.SubConfigs
+	}
+	return []*TASConfig{t}
+}
+
+// LogName returns the name of the logs of the command run in dir, the logs of each package are stored separately
+func LogName(commandType CommandType, dir string) string {
+	if dir == "" {
+		return string(commandType)
+	}
+	return string(commandType) + "-" + strings.ReplaceAll(dir, "/", "-")
+}
+
+// Toolchains are the versions of the toolchains required by the tests. A version matches the
+// versions it is a prefix of, e.g. 3.9 matches 3.9.7
+type Toolchains struct {
+	// Node is installed with nvm, it overrides nodeVersion
+	Node   string `yaml:"node"`
+	Python string `yaml:"python"`
+	Go     string `yaml:"go"`
+	Java   string `yaml:"java"`
 }
 
 //CoverageThreshold reprents the code coverage threshold
@@ -295,7 +565,26 @@ type CoverageThreshold struct {
 	Lines      float64 `yaml:"lines" json:"lines" validate:"number,min=0,max=100"`
 	Functions  float64 `yaml:"functions" json:"functions" validate:"number,min=0,max=100"`
 	Statements float64 `yaml:"statements" json:"statements" validate:"number,min=0,max=100"`
-	PerFile    bool    `yaml:"perFile" json:"perFile"`
+	// Diff is the minimum percentage of changed lines which must be covered
+	Diff    float64 `yaml:"diff" json:"diff" validate:"number,min=0,max=100"`
+	PerFile bool    `yaml:"perFile" json:"perFile"`
+}
+
+// CoverageUpload uploads the merged coverage of the build target commit to the coverage services
+type CoverageUpload struct {
+	Codecov   *CoverageUploadTarget `yaml:"codecov" json:"codecov,omitempty" validate:"omitempty"`
+	Coveralls *CoverageUploadTarget `yaml:"coveralls" json:"coveralls,omitempty" validate:"omitempty"`
+}
+
+// CoverageUploadTarget is a coverage service the coverage is uploaded to
+type CoverageUploadTarget struct {
+	// Token is the upload token of the repository, usually a secret e.g. ${{ secrets.CODECOV_TOKEN }}.
+	// The secret is substituted when the coverage is uploaded, it is not written to the coverage manifest.
+	Token string `yaml:"token" json:"token" validate:"required"`
+	// URL of a self hosted instance of the service, the public service is used by default
+	URL string `yaml:"url" json:"url,omitempty" validate:"omitempty,url"`
+	// Flags group the uploaded coverage in codecov, e.g. unit or integration
+	Flags []string `yaml:"flags" json:"flags,omitempty"`
 }
 
 // Cache represents the user's cached directories
@@ -304,6 +593,17 @@ type Cache struct {
 	Paths []string `yaml:"paths" validate:"required"`
 }
 
+// NamedCache represents a cache entry which is restored and saved independently of the others.
+// Key and RestoreKeys are templates, e.g. `npm-{{ checksum "package-lock.json" }}`.
+type NamedCache struct {
+	Name string `yaml:"name" validate:"required,excludesall=/\\,ne=.,ne=.."`
+	Key  string `yaml:"key" validate:"required"`
+	// RestoreKeys are tried in order if Key is not found, a restore key matches
+	// the latest cache saved with a key having the restore key as the prefix.
+	RestoreKeys []string `yaml:"restoreKeys" validate:"omitempty,dive,required"`
+	Paths       []string `yaml:"paths" validate:"required,gt=0"`
+}
+
 // Modifier defines struct for modifier
 type Modifier struct {
 	Type   string
@@ -313,8 +613,125 @@ type Modifier struct {
 
 // Run repersents  pre and post runs
 type Run struct {
-	Commands []string          `yaml:"command" validate:"omitempty,gt=0"`
+	Commands []Command         `yaml:"command" validate:"omitempty,gt=0"`
 	EnvMap   map[string]string `yaml:"env" validate:"omitempty,gt=0"`
+	// Dir is the directory relative to the repository root in which the commands are run
+	Dir string `yaml:"-"`
+	// ParentEnv are the env maps of the configuration and of the stage inherited by the commands, set when the
+	// commands are run
+	ParentEnv []map[string]string `yaml:"-"`
+}
+
+// Command is a command of the pre and post runs and of the hooks, the commands given as a string have no env.
+// A command with an env is run in its own shell, so that the env does not apply to the other commands.
+type Command struct {
+	Run string            `yaml:"run" json:"run"`
+	Env map[string]string `yaml:"env" json:"env,omitempty"`
+}
+
+// UnmarshalYAML decodes both the command strings and the commands with an env
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var run string
+	if err := unmarshal(&run); err == nil {
+		*c = Command{Run: run}
+		return nil
+	}
+	type command Command
+	return unmarshal((*command)(c))
+}
+
+// Hooks are the commands run at the stages of the pipeline. preInstall and postInstall are run
+// before and after the pre-run steps and the installation of the runners, preDiscovery before
+// the test discovery, preRun and postRun before and after the test execution and onFailure
+// when the task fails.
+type Hooks struct {
+	PreInstall   *Run `yaml:"preInstall" validate:"omitempty"`
+	PostInstall  *Run `yaml:"postInstall" validate:"omitempty"`
+	PreDiscovery *Run `yaml:"preDiscovery" validate:"omitempty"`
+	PreRun       *Run `yaml:"preRun" validate:"omitempty"`
+	PostRun      *Run `yaml:"postRun" validate:"omitempty"`
+	OnFailure    *Run `yaml:"onFailure" validate:"omitempty"`
+}
+
+// Timeouts of the task, the test files and the tests in seconds, a timeout is disabled if 0.
+// The processes of a timed out task or test file are killed and the timed out tests are reported
+// with the TestTimedOut status.
+type Timeouts struct {
+	Task int `yaml:"task" validate:"min=0"`
+	// File is enforced by running the files of the test locators in separate runner processes
+	File int `yaml:"file" validate:"min=0"`
+	// Test is passed to the runners in milliseconds as the TAS_TEST_TIMEOUT environment variable
+	Test int `yaml:"test" validate:"min=0"`
+}
+
+// CloneConfig configures cloning the repository using git instead of downloading the archive of the
+// commit, which reduces the clone time of large repositories
+type CloneConfig struct {
+	// Depth is the number of commits fetched, the full history is fetched if 0
+	Depth int `yaml:"depth" json:"depth" validate:"min=0"`
+	// Blobless fetches the contents of the files only when they are checked out (--filter=blob:none)
+	Blobless bool `yaml:"blobless" json:"blobless"`
+	// Sparse checks out only the files in the root directory, the directories referenced by the
+	// configuration and the directories of the changed files
+	Sparse bool `yaml:"sparse" json:"sparse"`
+	// LFS pulls the Git LFS objects of the checked out files
+	LFS        bool        `yaml:"lfs" json:"lfs"`
+	Submodules *Submodules `yaml:"submodules" json:"submodules" validate:"omitempty"`
+}
+
+// Submodules configures the initialization of the submodules, the submodules referenced using ssh
+// urls are fetched using https from the host of the repository
+type Submodules struct {
+	// Recursive initializes the submodules of the submodules
+	Recursive bool `yaml:"recursive" json:"recursive"`
+	// Auth are the credentials of the submodules which can not be fetched using the clone token
+	Auth []SubmoduleAuth `yaml:"auth" json:"auth" validate:"omitempty,dive"`
+}
+
+// SubmoduleAuth is the credential used for fetching the submodules matching URL. The path of URL is matched
+// by segments, e.g. https://github.com/org/ matches all the repositories of org.
+type SubmoduleAuth struct {
+	URL string `yaml:"url" json:"url" validate:"required,url"`
+	// User is x-access-token by default, the user of a personal access token can be anything
+	User string `yaml:"user" json:"user"`
+	// Token is usually a secret, e.g. ${{ secrets.LIB_TOKEN }}
+	Token string `yaml:"token" json:"token" validate:"required"`
+}
+
+// Values of Artifacts.When
+const (
+	ArtifactsAlways    = "always"
+	ArtifactsOnFailure = "on-failure"
+)
+
+// Artifacts are the files generated by the tests which are uploaded after the test execution,
+// e.g. screenshots and videos of UI tests. Artifacts are linked to the tests whose file name
+// or title they contain.
+type Artifacts struct {
+	// Paths are glob patterns relative to the repository root, `**` matches any number of directories
+	Paths []string `yaml:"paths" validate:"required,dive,required"`
+	// When is on-failure by default, only artifacts of failed tests are uploaded
+	When string `yaml:"when" validate:"omitempty,oneof=always on-failure"`
+}
+
+// Service is a container started before the tests and removed on task completion, e.g. a database
+// used by the integration tests. The host and port of the service are exposed to the tests as the
+// <NAME>_HOST and <NAME>_PORT environment variables.
+type Service struct {
+	Image   string            `yaml:"image" validate:"required"`
+	Env     map[string]string `yaml:"env"`
+	Command []string          `yaml:"command"`
+	// Port is checked for accepting connections if no health check is configured
+	Port        int                 `yaml:"port" validate:"omitempty,min=1,max=65535"`
+	HealthCheck *ServiceHealthCheck `yaml:"healthCheck" validate:"omitempty"`
+}
+
+// ServiceHealthCheck is run in the service container until it succeeds, durations are in seconds
+type ServiceHealthCheck struct {
+	Command  string `yaml:"command" validate:"required"`
+	Interval int    `yaml:"interval" validate:"omitempty,min=1"`
+	Timeout  int    `yaml:"timeout" validate:"omitempty,min=1"`
+	Retries  int    `yaml:"retries" validate:"omitempty,min=1"`
 }
 
 // Merge represents pre and post merge
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+)
+
+// endpointNeuronTaskFailures returns the locators of the failed tests of a task
+const endpointNeuronTaskFailures = "/task-failures"
+
+type taskFailuresResponse struct {
+	Failures []string `json:"failures"`
+}
+
+// selectRerun selects the failed tests of the task re-run by the payload. The tests are named in the selection,
+// so that each package and sub-task only runs its own failed tests.
+func (pl *Pipeline) selectRerun(ctx context.Context, payload *Payload) error {
+	failed, err := pl.failedTests(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to get the failed tests of task %s: %w", payload.RerunOf, err)
+	}
+	if len(failed) == 0 {
+		pl.Logger.Infof("task %s has no failed tests, no tests are re-run", payload.RerunOf)
+	} else {
+		pl.Logger.Infof("re-running %d failed tests of task %s", len(failed), payload.RerunOf)
+	}
+	payload.Selection = &TestSelection{Strategy: SelectionNamed, Tests: failed}
+	return nil
+}
+
+// failedTests returns the failed tests of the task from the results store in local runner mode, and from neuron otherwise
+func (pl *Pipeline) failedTests(ctx context.Context, payload *Payload) ([]string, error) {
+	if pl.Cfg.LocalRunner {
+		return pl.ResultStore.FailedTests(ctx, payload.RepoID, payload.RerunOf)
+	}
+	u, err := url.Parse(global.NeuronURL(endpointNeuronTaskFailures))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("repoID", payload.RepoID)
+	q.Set("taskID", payload.RerunOf)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	tracing.InjectHeaders(ctx, req.Header)
+	resp, err := pl.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non 200 status %d", resp.StatusCode)
+	}
+	failures := taskFailuresResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&failures); err != nil {
+		return nil, err
+	}
+	return failures.Failures, nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/utils"
+)
+
+// SelectionStrategy selects the tests run by a task
+type SelectionStrategy string
+
+// SelectionStrategy values.
+const (
+	// SelectionFull runs all the tests
+	SelectionFull SelectionStrategy = "full"
+	// SelectionImpacted runs the tests impacted by the changed files, including the files importing them
+	SelectionImpacted SelectionStrategy = "impacted"
+	// SelectionChanged only runs the changed test files
+	SelectionChanged SelectionStrategy = "changed"
+	// SelectionNamed runs the tests named by the payload, e.g. to re-run the failed tests of a build
+	SelectionNamed SelectionStrategy = "named"
+)
+
+// locatorSeparator separates the file of a test locator from its suites and name
+const locatorSeparator = "##"
+
+// TestSelection selects the tests of the task
+type TestSelection struct {
+	Strategy SelectionStrategy `json:"strategy"`
+	// Tests are the locators of the tests run by the named strategy, e.g. src/app.test.js##App##renders
+	Tests []string `json:"tests"`
+	// Patterns are the globs of the test files run by the named strategy, relative to the repository root.
+	// They are exclusive with Tests.
+	Patterns []string `json:"patterns"`
+}
+
+// Validate checks the strategy of the selection and that the named strategy names either tests or patterns
+func (s *TestSelection) Validate() error {
+	switch s.Strategy {
+	case "", SelectionFull, SelectionImpacted, SelectionChanged:
+		return nil
+	case SelectionNamed:
+		// the runner can't select the union of a list of tests and of patterns
+		if (len(s.Tests) == 0) == (len(s.Patterns) == 0) {
+			return fmt.Errorf("the %s selection requires either tests or patterns", SelectionNamed)
+		}
+		for _, pattern := range s.Patterns {
+			if err := utils.ValidateGlob(pattern); err != nil {
+				return fmt.Errorf("invalid test pattern %s: %w", pattern, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown test selection strategy %s", s.Strategy)
+	}
+}
+
+// SelectionStrategy returns the strategy selecting the tests of the task. Unless the payload sets a strategy,
+// the impacted tests are selected if smart run is enabled, and all the tests otherwise or for the scheduled builds.
+func (p *Payload) SelectionStrategy(tasConfig *TASConfig) SelectionStrategy {
+	if p.Selection != nil && p.Selection.Strategy != "" {
+		return p.Selection.Strategy
+	}
+	if !tasConfig.SmartRun || p.EventType == EventCron {
+		return SelectionFull
+	}
+	return SelectionImpacted
+}
+
+// TestPatterns returns the test patterns of the configuration for the event of the task
+func (t *TASConfig) TestPatterns(eventType EventType) []string {
+	merge := t.Postmerge
+	if eventType.IsPremerge() {
+		merge = t.Premerge
+	}
+	if merge == nil {
+		return nil
+	}
+	return merge.Patterns
+}
+
+// ChangedTests returns the added or modified files of the diff which match the test patterns of the configuration
+func ChangedTests(tasConfig *TASConfig, eventType EventType, diff map[string]int) ([]string, error) {
+	tests := make([]string, 0)
+	for file, changeType := range diff {
+		if changeType == FileRemoved {
+			continue
+		}
+		if ok, err := matchesAny(tasConfig.TestPatterns(eventType), file); err != nil {
+			return nil, err
+		} else if ok {
+			tests = append(tests, file)
+		}
+	}
+	return tests, nil
+}
+
+// NamedTests returns the named tests whose file matches the test patterns of the configuration, so that each
+// package only runs its own tests
+func (s *TestSelection) NamedTests(tasConfig *TASConfig, eventType EventType) ([]string, error) {
+	tests := make([]string, 0, len(s.Tests))
+	for _, test := range s.Tests {
+		if ok, err := matchesAny(tasConfig.TestPatterns(eventType), LocatorFile(test)); err != nil {
+			return nil, err
+		} else if ok {
+			tests = append(tests, test)
+		}
+	}
+	return tests, nil
+}
+
+// NamedPatterns returns the named patterns of the test files in the directory of the configuration, so that each
+// package only runs its own tests
+func (s *TestSelection) NamedPatterns(tasConfig *TASConfig) []string {
+	if tasConfig.Dir == "" {
+		return s.Patterns
+	}
+	patterns := make([]string, 0, len(s.Patterns))
+	for _, pattern := range s.Patterns {
+		if base := utils.GlobBase(pattern); base == tasConfig.Dir || strings.HasPrefix(base, tasConfig.Dir+"/") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// LocatorFile returns the test file of the test locator
+func LocatorFile(locator string) string {
+	return strings.SplitN(locator, locatorSeparator, 2)[0]
+}
+
+func matchesAny(patterns []string, file string) (bool, error) {
+	for _, pattern := range patterns {
+		if ok, err := utils.MatchGlob(pattern, file); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
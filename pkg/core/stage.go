@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+)
+
+// names of the built-in stages of the pipeline, in the order they run
+const (
+	StagePayload      = "payload"
+	StageCoverage     = "coverage"
+	StageParse        = "parse"
+	StageClone        = "clone"
+	StageSetup        = "setup"
+	StageBlocklist    = "blocklist"
+	StageCacheRestore = "cache-restore"
+	StageServices     = "services"
+	StageInstall      = "install"
+	StageDiff         = "diff"
+	StageDiscovery    = "discovery"
+	StageExecution    = "execution"
+	StageCacheSave    = "cache-save"
+)
+
+// Stages are the names of the built-in stages, the stages of each mode are a subset
+var Stages = []string{StagePayload, StageCoverage, StageParse, StageClone, StageSetup, StageBlocklist,
+	StageCacheRestore, StageServices, StageInstall, StageDiff, StageDiscovery, StageExecution, StageCacheSave}
+
+// Stage is a step of the pipeline
+type Stage interface {
+	// Name identifies the stage in the logs, the traces and the placement of the custom stages
+	Name() string
+	// Run runs the stage, the stage sets StageState.ErrRemark to the user facing reason of its failure
+	Run(ctx context.Context, state *StageState) error
+}
+
+// StageHook is called before and after each stage of the pipeline
+type StageHook interface {
+	// BeforeStage fails the stage without running it if it returns an error
+	BeforeStage(ctx context.Context, stage string, state *StageState) error
+	// AfterStage receives the error of the stage, an error fails the stage if it succeeded
+	AfterStage(ctx context.Context, stage string, state *StageState, err error) error
+}
+
+// StagePlacement places a custom stage right before or after a built-in stage. The custom stage
+// does not run in the modes which do not run the built-in stage.
+type StagePlacement struct {
+	Before string `yaml:"before" json:"before,omitempty"`
+	After  string `yaml:"after" json:"after,omitempty"`
+}
+
+// StageState is the state shared by the stages of a pipeline run, the fields are set
+// by the stages as the pipeline progresses
+type StageState struct {
+	Payload    *Payload
+	CloneToken string
+	// Task is the status reported for the task, it is set once the payload is loaded
+	Task        *TaskPayload
+	TASConfig   *TASConfig
+	SecretMap   map[string]string
+	CoverageDir string
+	// ChangedFiles is the diff of the commits with the change type by file, set by the diff stage.
+	// The discovery stage selects the tests based on it.
+	ChangedFiles map[string]int
+	Result       *ExecutionResult
+	// ErrRemark is the user facing reason of the failure of the task
+	ErrRemark string
+	// Done stops the pipeline successfully after the current stage
+	Done bool
+
+	cacheKey string
+	// recorder is set while executing the tests if checkpointing is enabled
+	recorder *resultRecorder
+	// cleanups run in reverse order once the stages completed
+	cleanups []func()
+}
+
+// AddCleanup registers a function which runs once all the stages completed, even if a stage failed
+func (s *StageState) AddCleanup(cleanup func()) {
+	s.cleanups = append(s.cleanups, cleanup)
+}
+
+func (s *StageState) cleanup() {
+	for i := len(s.cleanups) - 1; i >= 0; i-- {
+		s.cleanups[i]()
+	}
+	s.cleanups = nil
+}
+
+type customStage struct {
+	stage     Stage
+	placement StagePlacement
+}
+
+// RegisterStage adds a custom stage to the pipeline, e.g. license scanning after the clone or a custom test
+// selection after the diff. The custom stages placed at the same built-in stage run in the order they are registered.
+func (pl *Pipeline) RegisterStage(stage Stage, placement StagePlacement) error {
+	anchor := placement.Before
+	if (placement.Before == "") == (placement.After == "") {
+		return fmt.Errorf("stage %s must be placed either before or after a stage", stage.Name())
+	}
+	if anchor == "" {
+		anchor = placement.After
+	}
+	for _, name := range Stages {
+		if name == stage.Name() {
+			return fmt.Errorf("stage %s conflicts with the built-in stage", stage.Name())
+		}
+	}
+	for _, name := range Stages {
+		if name == anchor {
+			pl.customStages = append(pl.customStages, customStage{stage: stage, placement: placement})
+			return nil
+		}
+	}
+	return fmt.Errorf("stage %s is placed at unknown stage %s", stage.Name(), anchor)
+}
+
+// withCustomStages returns the built-in stages with the registered custom stages inserted at their placement
+func (pl *Pipeline) withCustomStages(stages ...Stage) []Stage {
+	result := make([]Stage, 0, len(stages)+len(pl.customStages))
+	for _, stage := range stages {
+		for _, custom := range pl.customStages {
+			if custom.placement.Before == stage.Name() {
+				result = append(result, custom.stage)
+			}
+		}
+		result = append(result, stage)
+		for _, custom := range pl.customStages {
+			if custom.placement.After == stage.Name() {
+				result = append(result, custom.stage)
+			}
+		}
+	}
+	return result
+}
+
+type stageKey struct{}
+
+// StageFromContext returns the name of the stage running in ctx, or an empty string outside of the stages
+func StageFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(stageKey{}).(string)
+	return name
+}
+
+// runStage runs the stage between the hooks in its own span, the stage.started and stage.finished events
+// are emitted around the hooks
+func (pl *Pipeline) runStage(ctx context.Context, stage Stage, state *StageState) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "pipeline."+stage.Name())
+	ctx = context.WithValue(ctx, stageKey{}, stage.Name())
+	defer func() { tracing.EndSpan(span, err) }()
+
+	pl.Logger.Debugf("Running %s stage", stage.Name())
+	startTime := time.Now()
+	pl.emitStageEvent(ctx, EventStageStarted, state, 0, nil)
+	defer func() {
+		if err != nil {
+			pl.emitStageEvent(ctx, EventError, state, 0, err)
+		}
+		pl.emitStageEvent(ctx, EventStageFinished, state, time.Since(startTime), err)
+	}()
+	for _, hook := range pl.StageHooks {
+		if err = hook.BeforeStage(ctx, stage.Name(), state); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = stage.Run(ctx, state)
+	}
+	for _, hook := range pl.StageHooks {
+		if hookErr := hook.AfterStage(ctx, stage.Name(), state, err); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	if err != nil && state.ErrRemark == "" {
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+	}
+	return err
+}
+
+// emitStageEvent emits the event of the stage running in ctx with a snapshot of the task if it is loaded
+func (pl *Pipeline) emitStageEvent(ctx context.Context, eventType PipelineEventType, state *StageState, duration time.Duration, err error) {
+	event := &Event{Type: eventType, Duration: duration, Err: err}
+	if state.Task != nil {
+		task := *state.Task
+		event.Task = &task
+	}
+	pl.Events.Emit(ctx, event)
+}
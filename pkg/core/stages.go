@@ -0,0 +1,581 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/fileutils"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// stageFunc is a built-in stage of the pipeline
+type stageFunc struct {
+	name string
+	run  func(ctx context.Context, state *StageState) error
+}
+
+func (s *stageFunc) Name() string {
+	return s.name
+}
+
+func (s *stageFunc) Run(ctx context.Context, state *StageState) error {
+	return s.run(ctx, state)
+}
+
+func newStage(name string, run func(ctx context.Context, state *StageState) error) Stage {
+	return &stageFunc{name: name, run: run}
+}
+
+// taskStages returns the built-in stages of the task in the current mode
+func (pl *Pipeline) taskStages() []Stage {
+	stages := []Stage{
+		newStage(StageClone, pl.clone),
+		newStage(StageSetup, pl.setup),
+		newStage(StageBlocklist, pl.fetchBlocklist),
+		newStage(StageCacheRestore, pl.restoreCache),
+	}
+	if pl.Cfg.ExecuteMode {
+		stages = append(stages, newStage(StageServices, pl.startServices))
+	}
+	stages = append(stages, newStage(StageInstall, pl.install))
+	if pl.Cfg.DiscoverMode || pl.Cfg.DryRun {
+		stages = append(stages, newStage(StageDiff, pl.fetchDiff), newStage(StageDiscovery, pl.discover))
+	}
+	if pl.Cfg.ExecuteMode {
+		stages = append(stages, newStage(StageExecution, pl.execute))
+	}
+	return append(stages, newStage(StageCacheSave, pl.saveCache))
+}
+
+// fetchPayload fetches and validates the payload of the task, along with the clone token
+func (pl *Pipeline) fetchPayload(ctx context.Context, state *StageState) error {
+	payload, err := pl.PayloadManager.FetchPayload(ctx, pl.Cfg.PayloadAddress)
+	if err != nil {
+		pl.Logger.Errorf("error while fetching payload: %v", err)
+		return err
+	}
+	if err = pl.PayloadManager.ValidatePayload(ctx, payload); err != nil {
+		pl.Logger.Errorf("error while validating payload %v", err)
+		return err
+	}
+	pl.Logger.Debugf("Payload for current task: %+v \n", *payload)
+	// set payload on pipeline object
+	pl.Payload = payload
+	state.Payload = payload
+	if pl.Cfg.CoverageMode {
+		return nil
+	}
+	if payload.RerunOf != "" && !pl.Cfg.ParseMode {
+		if err := pl.selectRerun(ctx, payload); err != nil {
+			pl.Logger.Errorf("error while selecting the tests of the re-run: %v", err)
+			return err
+		}
+	}
+	oauth, err := pl.SecretParser.GetOauthSecret(global.OauthSecretPath)
+	if err != nil {
+		pl.Logger.Errorf("failed to get oauth secret %v", err)
+		return err
+	}
+	state.CloneToken = oauth.Data.AccessToken
+	return nil
+}
+
+// mergeCoverage merges, uploads and gates the coverage of the build
+func (pl *Pipeline) mergeCoverage(ctx context.Context, state *StageState) error {
+	// clone token is only required for diff coverage, hence not mandatory
+	if oauth, err := pl.SecretParser.GetOauthSecret(global.OauthSecretPath); err != nil {
+		pl.Logger.Warnf("failed to get oauth secret, diff coverage will not be available: %v", err)
+	} else {
+		state.CloneToken = oauth.Data.AccessToken
+	}
+	if err := pl.CoverageService.MergeAndUpload(ctx, state.Payload, state.CloneToken); err != nil {
+		pl.Logger.Errorf("error while merge and upload coverage files %v", err)
+		return err
+	}
+	return nil
+}
+
+// parse clones and parses the configuration of the build
+func (pl *Pipeline) parse(ctx context.Context, state *StageState) error {
+	if err := pl.GitManager.CloneYML(ctx, state.Payload, state.CloneToken); err != nil {
+		// the parser reports the configuration as inferred if the configuration file does not exist
+		if !pl.Cfg.ZeroConfig || !errors.Is(err, errs.ErrFileNotFound) {
+			pl.Logger.Errorf("failed to clone YML for build ID: %s, error: %v", state.Payload.BuildID, err)
+			return err
+		}
+	}
+	if err := pl.ParserService.PerformParsing(state.Payload); err != nil {
+		pl.Logger.Errorf("error while parsing YML for build ID: %s, error: %v", state.Payload.BuildID, err)
+		return err
+	}
+	return nil
+}
+
+// clone clones the repository and loads its configuration
+func (pl *Pipeline) clone(ctx context.Context, state *StageState) error {
+	payload := state.Payload
+	pl.Logger.Infof("Cloning repo ...")
+	if err := pl.GitManager.Clone(ctx, payload, state.CloneToken); err != nil {
+		pl.Logger.Errorf("Unable to clone repo '%s': %s", payload.RepoLink, err)
+		state.ErrRemark = fmt.Sprintf("Unable to clone repo: %s", payload.RepoLink)
+		return err
+	}
+
+	configPath, err := pl.configPath(ctx, payload)
+	if err != nil {
+		pl.Logger.Errorf("Unable to infer the configuration, error: %v", err)
+		state.ErrRemark = err.Error()
+		return err
+	}
+	// load tas yaml file
+	tasConfig, err := pl.TASConfigManager.LoadConfig(ctx, configPath, payload.EventType, false)
+	if err != nil {
+		pl.Logger.Errorf("Unable to load tas yaml file, error: %v", err)
+		state.ErrRemark = err.Error()
+		return err
+	}
+	state.TASConfig = tasConfig
+
+	pl.Logger.Infof("Tas yaml: %+v", tasConfig)
+	if payload.Clone != nil && payload.Clone.Sparse {
+		if err = pl.checkoutSparseDirs(ctx, tasConfig, state.CloneToken); err != nil {
+			pl.Logger.Errorf("Unable to check out the directories of the repository: %v", err)
+			state.ErrRemark = fmt.Sprintf("Unable to clone repo: %s", payload.RepoLink)
+			return err
+		}
+	}
+	pl.setImpactState(payload, tasConfig, state.CloneToken)
+	return nil
+}
+
+// configPath returns the path of the configuration file relative to the repository. If the file does not exist
+// and zero config is enabled, the configuration is inferred from the manifests of the repository and written
+// to the scratch directory
+func (pl *Pipeline) configPath(ctx context.Context, payload *Payload) (string, error) {
+	if !pl.Cfg.ZeroConfig || pl.ConfigDetector == nil {
+		return payload.TasFileName, nil
+	}
+	if _, err := os.Stat(filepath.Join(global.RepoDir, payload.TasFileName)); !errors.Is(err, os.ErrNotExist) {
+		return payload.TasFileName, nil
+	}
+	pl.Logger.Infof("Configuration file %s not found, inferring the configuration from the repository",
+		payload.TasFileName)
+	config, err := pl.ConfigDetector.Detect(ctx, global.RepoDir)
+	if err != nil {
+		return "", err
+	}
+	if err := pl.ConfigDetector.Publish(ctx, payload, config); err != nil {
+		pl.Logger.Warnf("Unable to publish the inferred configuration: %v", err)
+	}
+	inferredPath := filepath.Join(global.ScratchDir, fmt.Sprintf("inferred-%s.yml", payload.TaskID))
+	if err := ioutil.WriteFile(inferredPath, config, global.FilePermissions); err != nil {
+		return "", err
+	}
+	return filepath.Rel(global.RepoDir, inferredPath)
+}
+
+// setup sets the environment of the commands, the toolchains and reads the secrets of the repository
+func (pl *Pipeline) setup(ctx context.Context, state *StageState) error {
+	payload, tasConfig := state.Payload, state.TASConfig
+	// set testing taskID, orgID and buildID as environment variable
+	os.Setenv("TASK_ID", payload.TaskID)
+	os.Setenv("ORG_ID", payload.OrgID)
+	os.Setenv("BUILD_ID", payload.BuildID)
+	//set commit_id as environment variable
+	os.Setenv("COMMIT_ID", payload.TargetCommit)
+	//set repo_id as environment variable
+	os.Setenv("REPO_ID", payload.RepoID)
+	//set coverage_dir as environment variable
+	os.Setenv("CODE_COVERAGE_DIR", state.CoverageDir)
+	os.Setenv("BRANCH_NAME", payload.BranchName)
+	os.Setenv("ENV", pl.Cfg.Env)
+	os.Setenv("TAS_PARALLELISM", strconv.Itoa(tasConfig.Parallelism))
+	os.Setenv("ENDPOINT_POST_TEST_LIST", endpointPostTestList)
+	os.Setenv("ENDPOINT_POST_TEST_RESULTS", pl.localURL(endpointPostTestResults))
+	os.Setenv("REPO_ROOT", global.RepoDir)
+	os.Setenv("BLOCKLISTED_TESTS_FILE", global.BlocklistedFileLocation)
+
+	if tasConfig.NodeVersion != nil {
+		if err := pl.useNodeVersion(ctx, tasConfig.NodeVersion.String(), os.Getenv("PATH")); err != nil {
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+	// the toolchains are set up before any command of the user, which would fail with a less clear error
+	if tasConfig.Toolchains != nil {
+		if err := pl.ToolchainManager.Setup(ctx, tasConfig.Toolchains); err != nil {
+			pl.Logger.Errorf("Unable to set up the toolchains: %v", err)
+			state.ErrRemark = err.Error()
+			return err
+		}
+	}
+
+	if payload.CollectCoverage {
+		if err := fileutils.CreateIfNotExists(state.CoverageDir, true); err != nil {
+			pl.Logger.Errorf("failed to create coverage directory %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+
+	// read secrets
+	secretMap, err := pl.SecretParser.GetRepoSecret(ctx, global.RepoSecretPath)
+	if err != nil {
+		pl.Logger.Errorf("Error in fetching Repo secrets %v", err)
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+		return err
+	}
+	state.SecretMap = secretMap
+	return nil
+}
+
+// fetchBlocklist fetches the blocklisted tests of the repository
+func (pl *Pipeline) fetchBlocklist(ctx context.Context, state *StageState) error {
+	if err := pl.TestBlockListService.GetBlockListedTests(ctx, state.TASConfig, state.Payload.RepoID); err != nil {
+		pl.Logger.Errorf("Unable to fetch blocklisted tests: %v", err)
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+		return err
+	}
+	return nil
+}
+
+// restoreCache downloads the cache of the repository and restores the caches of the configuration
+func (pl *Pipeline) restoreCache(ctx context.Context, state *StageState) error {
+	payload, tasConfig := state.Payload, state.TASConfig
+	if tasConfig.Cache != nil {
+		state.cacheKey = fmt.Sprintf("%s/%s/%s", payload.OrgID, payload.RepoID, tasConfig.Cache.Key)
+		// TODO:  download from cdn
+		if err := pl.CacheStore.Download(ctx, state.cacheKey); err != nil {
+			pl.Logger.Errorf("Unable to download cache: %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+	if err := pl.CacheStore.Restore(ctx, payload, tasConfig.Caches); err != nil {
+		pl.Logger.Errorf("Unable to restore caches: %v", err)
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+		return err
+	}
+	return nil
+}
+
+// startServices starts the service containers of the configuration and exposes their addresses in the environment
+func (pl *Pipeline) startServices(ctx context.Context, state *StageState) error {
+	services := state.TASConfig.Services
+	if len(services) == 0 {
+		return nil
+	}
+	pl.Logger.Infof("Starting %d services ...", len(services))
+	// services are removed even if the task context is cancelled
+	state.AddCleanup(func() {
+		if stopErr := pl.ServiceManager.Stop(context.Background()); stopErr != nil {
+			pl.Logger.Errorf("Unable to stop services: %v", stopErr)
+		}
+	})
+	serviceEnv, err := pl.ServiceManager.Start(ctx, state.Payload, services, state.SecretMap)
+	if err != nil {
+		pl.Logger.Errorf("Unable to start services: %v", err)
+		state.ErrRemark = fmt.Sprintf("Error occurred in starting services: %v", err)
+		return err
+	}
+	for k, v := range serviceEnv {
+		if err = os.Setenv(k, v); err != nil {
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+	return nil
+}
+
+// install runs the install hooks and the pre-run steps, and installs the custom runners
+func (pl *Pipeline) install(ctx context.Context, state *StageState) error {
+	tasConfig, secretMap := state.TASConfig, state.SecretMap
+	if err := pl.runHook(ctx, HookPreInstall, tasConfig.ScopedRun(EnvStageInstall, tasConfig.Hooks.PreInstall), secretMap); err != nil {
+		state.ErrRemark = "Error occurred in preInstall hook"
+		return err
+	}
+	if err := pl.runUserCommands(ctx, PreRun, tasConfig, secretMap); err != nil {
+		state.ErrRemark = "Error occurred in pre-run steps"
+		return err
+	}
+	if err := pl.ExecutionManager.ExecuteInternalCommands(ctx, InstallRunners, global.InstallRunnerCmd, global.RepoDir, nil, nil); err != nil {
+		pl.Logger.Errorf("Unable to install custom runners %v", err)
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+		return err
+	}
+	if err := pl.runHook(ctx, HookPostInstall, tasConfig.ScopedRun(EnvStageInstall, tasConfig.Hooks.PostInstall), secretMap); err != nil {
+		state.ErrRemark = "Error occurred in postInstall hook"
+		return err
+	}
+	return nil
+}
+
+// fetchDiff identifies the files changed by the commits of the task
+func (pl *Pipeline) fetchDiff(ctx context.Context, state *StageState) error {
+	pl.Logger.Infof("Identifying changed files ...")
+	diff, err := pl.DiffManager.GetChangedFiles(ctx, state.Payload, state.CloneToken)
+	if err != nil {
+		pl.Logger.Errorf("Unable to identify changed files %s", err)
+		state.ErrRemark = "Error occurred in fetching diff from GitHub"
+		return err
+	}
+	state.ChangedFiles = diff
+	return nil
+}
+
+// discover discovers the tests selected by the selection strategy of the task, the dry run reports them and
+// stops the pipeline
+func (pl *Pipeline) discover(ctx context.Context, state *StageState) error {
+	tasConfig, secretMap := state.TASConfig, state.SecretMap
+	// only the impacted strategy runs the impact analysis, the runners select the tests of the changed files
+	discoveryDiff := state.ChangedFiles
+	if state.Payload.SelectionStrategy(tasConfig) == SelectionImpacted {
+		discoveryDiff = pl.withImpactedFiles(ctx, state.ChangedFiles)
+	}
+	// the dry run always discovers the tests, its test lists are reported instead of being sent to neuron
+	cacheKey := ""
+	if pl.Cfg.DiscoveryCache && !pl.Cfg.DryRun {
+		key, err := discoveryCacheKey(state.Payload, tasConfig, discoveryDiff)
+		if err != nil {
+			pl.Logger.Warnf("failed to compute the discovery cache key, the discovery is not cached: %v", err)
+		}
+		cacheKey = key
+	}
+	if cacheKey != "" {
+		cached, err := pl.replayDiscovery(ctx, state.Payload, cacheKey)
+		if err != nil {
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+		if cached {
+			state.Task.Status = Passed
+			return nil
+		}
+	}
+	if err := pl.runHook(ctx, HookPreDiscovery, tasConfig.ScopedRun(EnvStageDiscovery, tasConfig.Hooks.PreDiscovery), secretMap); err != nil {
+		state.ErrRemark = "Error occurred in preDiscovery hook"
+		return err
+	}
+	// discover test cases of each package
+	for _, target := range tasConfig.Targets() {
+		if err := pl.TestDiscoveryService.Discover(ctx, target, state.Payload, secretMap, discoveryDiff); err != nil {
+			pl.Logger.Errorf("Unable to perform test discovery of %s: %+v", target.File, err)
+			state.ErrRemark = "Error occurred in discovering tests"
+			return err
+		}
+	}
+	if cacheKey != "" {
+		if err := pl.forwardDiscovery(ctx, state.Payload, cacheKey); err != nil {
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+	if pl.Cfg.DryRun {
+		// caches are not saved as the dry run does not execute the tests
+		if err := pl.DryRunReporter.Report(ctx, state.Payload, state.ChangedFiles); err != nil {
+			pl.Logger.Errorf("Unable to report impacted tests: %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+		pl.Logger.Debugf("Completed dry run")
+		state.Done = true
+		return nil
+	}
+	// mark status as passed
+	state.Task.Status = Passed
+	return nil
+}
+
+// execute executes the tests of each matrix combination, reports the results and runs the post-run steps
+func (pl *Pipeline) execute(ctx context.Context, state *StageState) error {
+	payload, tasConfig, secretMap, taskPayload := state.Payload, state.TASConfig, state.SecretMap, state.Task
+	if err := pl.runHook(ctx, HookPreRun, tasConfig.ScopedRun(EnvStageExecution, tasConfig.Hooks.PreRun), secretMap); err != nil {
+		state.ErrRemark = "Error occurred in preRun hook"
+		return err
+	}
+	var checkpoint *Checkpoint
+	if pl.Cfg.Checkpoint {
+		var err error
+		if checkpoint, err = pl.CheckpointManager.Load(ctx, payload, state.CoverageDir); err != nil {
+			pl.Logger.Errorf("Unable to load checkpoint: %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+		state.recorder = recordResults(pl.TestStats, checkpoint)
+	}
+	// execute test cases of each package, only the remaining tests are run when resuming
+	executionResult := &ExecutionResult{
+		OrgID:    payload.OrgID,
+		RepoID:   payload.RepoID,
+		BuildID:  payload.BuildID,
+		TaskID:   payload.TaskID,
+		CommitID: payload.TargetCommit,
+	}
+	targets := tasConfig.Targets()
+	if checkpoint != nil && len(checkpoint.Remaining) == 0 {
+		pl.Logger.Infof("all tests completed before the task was interrupted")
+		targets = nil
+	}
+	// the tests are executed once for each matrix combination, the sub-task of a parallel matrix
+	// build only executes the combination of its payload
+	combinations := []MatrixCombination{payload.Matrix}
+	if len(payload.Matrix) == 0 && tasConfig.Matrix != nil {
+		if tasConfig.Matrix.Parallel {
+			pl.Logger.Warnf("matrix combination not set in the payload, executing the combinations one after another")
+		}
+		if all := tasConfig.Matrix.Combinations(); len(all) > 0 {
+			combinations = all
+		}
+	}
+	// the node version of a combination only applies to its tests, the env of the combination is passed
+	// to the test runners along with the env of the configuration
+	basePath := os.Getenv("PATH")
+	defer func() {
+		if err := os.Setenv("PATH", basePath); err != nil {
+			pl.Logger.Errorf("Unable to restore the PATH after the matrix combinations %v", err)
+		}
+	}()
+	first := true
+	for _, combination := range combinations {
+		if nodeVersion, ok := combination[MatrixNodeVersion]; ok {
+			if err := pl.useNodeVersion(ctx, nodeVersion, basePath); err != nil {
+				state.ErrRemark = errs.GenericUserFacingBEErrRemark
+				return err
+			}
+		}
+		combinationPayload := *payload
+		combinationPayload.Matrix = combination
+		if len(combination) > 0 {
+			pl.Logger.Infof("Executing tests with matrix combination %v", combination)
+		}
+		for _, target := range targets {
+			result, err := pl.TestExecutionService.Run(ctx, target, &combinationPayload, state.CoverageDir, secretMap)
+			if err != nil {
+				pl.Logger.Infof("Unable to perform test execution of %s: %v", target.File, err)
+				state.ErrRemark = "Error occurred in executing tests"
+				return err
+			}
+			if first {
+				executionResult = result
+				first = false
+				continue
+			}
+			executionResult.TestPayload = append(executionResult.TestPayload, result.TestPayload...)
+			executionResult.TestSuitePayload = append(executionResult.TestSuitePayload, result.TestSuitePayload...)
+			executionResult.ResourceUsage = executionResult.ResourceUsage.Merge(result.ResourceUsage)
+			executionResult.FileResourceUsage = append(executionResult.FileResourceUsage, result.FileResourceUsage...)
+			executionResult.FileLogs = append(executionResult.FileLogs, result.FileLogs...)
+			executionResult.LimitsExceeded = appendLimits(executionResult.LimitsExceeded, result.LimitsExceeded...)
+		}
+	}
+	if checkpoint != nil {
+		checkpoint.Merge(executionResult)
+	}
+	state.Result = executionResult
+	// artifacts help debugging the failures, the task does not fail if they can not be uploaded
+	if artifactErr := pl.ArtifactManager.Upload(ctx, payload, tasConfig.Artifacts, executionResult); artifactErr != nil {
+		pl.Logger.Errorf("Unable to upload artifacts: %v", artifactErr)
+	}
+	// the report links the artifacts uploaded above
+	if reportErr := pl.FailureReporter.Report(ctx, payload, executionResult); reportErr != nil {
+		pl.Logger.Errorf("Unable to write the failure report: %v", reportErr)
+	}
+
+	if pl.Cfg.Offline {
+		pl.Logger.Debugf("Offline run, the test reports are not sent")
+	} else if err := pl.sendStats(ctx, *executionResult); err != nil {
+		// in local runner mode the results are kept in the local results store once the task completes
+		if !pl.Cfg.LocalRunner {
+			pl.Logger.Errorf("error while sending test reports %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+		pl.Logger.Warnf("failed to send test reports, the results are only kept in the local results store: %v", err)
+	}
+	// the results are already reported to neuron, the task does not fail if they can not be exported
+	if exportErr := pl.ResultExporter.Export(ctx, payload, executionResult); exportErr != nil {
+		pl.Logger.Errorf("Unable to export test results: %v", exportErr)
+	}
+	if annotateErr := pl.Annotator.Publish(ctx, payload, state.CloneToken, testsAnnotation(payload.TaskID, executionResult)); annotateErr != nil {
+		pl.Logger.Errorf("Unable to publish the check of the test results: %v", annotateErr)
+	}
+	if checkpoint != nil {
+		// a failure only results in running the tests again if the task is started once more
+		if clearErr := pl.CheckpointManager.Clear(ctx, payload); clearErr != nil {
+			pl.Logger.Warnf("failed to clear checkpoint: %v", clearErr)
+		}
+	}
+	taskPayload.Status = Passed
+	failedTests := make([]NotificationTest, 0)
+	blocklistedTests := make([]NotificationTest, 0)
+	for i := 0; i < len(executionResult.TestPayload); i++ {
+		testResult := &executionResult.TestPayload[i]
+		if testResult.Status == TestFailed || testResult.Status == TestTimedOut {
+			taskPayload.Status = Failed
+			failedTests = append(failedTests, newNotificationTest(testResult))
+		}
+		if testResult.Blocklisted {
+			blocklistedTests = append(blocklistedTests, newNotificationTest(testResult))
+		}
+	}
+	if len(executionResult.LimitsExceeded) > 0 {
+		taskPayload.Remark = fmt.Sprintf("The run was truncated by the limits of the runner: %s",
+			strings.Join(executionResult.LimitsExceeded, ", "))
+	}
+	if len(failedTests) > 0 {
+		pl.emitTaskEvent(ctx, EventTestFailed, taskPayload, failedTests)
+	}
+	if len(blocklistedTests) > 0 {
+		pl.emitTaskEvent(ctx, EventBlocklistHit, taskPayload, blocklistedTests)
+	}
+
+	if err := pl.runHook(ctx, HookPostRun, tasConfig.ScopedRun(EnvStageExecution, tasConfig.Hooks.PostRun), secretMap); err != nil {
+		state.ErrRemark = "Error occurred in postRun hook"
+		return err
+	}
+	if err := pl.runUserCommands(ctx, PostRun, tasConfig, secretMap); err != nil {
+		state.ErrRemark = "Error occurred in post-run steps"
+		return err
+	}
+	return nil
+}
+
+// appendLimits appends the exceeded limits which are not in limits yet
+func appendLimits(limits []string, exceeded ...string) []string {
+	for _, limit := range exceeded {
+		found := false
+		for _, l := range limits {
+			found = found || l == limit
+		}
+		if !found {
+			limits = append(limits, limit)
+		}
+	}
+	return limits
+}
+
+// saveCache uploads the cache of the repository and saves the caches of the configuration
+func (pl *Pipeline) saveCache(ctx context.Context, state *StageState) error {
+	tasConfig := state.TASConfig
+	if tasConfig.Cache != nil {
+		if err := pl.CacheStore.Upload(ctx, state.cacheKey, tasConfig.Cache.Paths...); err != nil {
+			pl.Logger.Errorf("Unable to upload cache: %v", err)
+			state.ErrRemark = errs.GenericUserFacingBEErrRemark
+			return err
+		}
+	}
+	if err := pl.CacheStore.Save(ctx, state.Payload, tasConfig.Caches); err != nil {
+		pl.Logger.Errorf("Unable to save caches: %v", err)
+		state.ErrRemark = errs.GenericUserFacingBEErrRemark
+		return err
+	}
+	pl.Logger.Debugf("Cache uploaded successfully")
+	return nil
+}
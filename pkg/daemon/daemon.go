@@ -0,0 +1,176 @@
+// Package daemon keeps nucleus resident between the tasks of a reused container. The task payloads are submitted
+// over the HTTP API and run one after another by child nucleus processes sharing the checkout of the
+// repository, so that the clone is fetched incrementally and the installed dependencies and the restored
+// caches are kept warm for the next task.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/batch"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/workspace"
+)
+
+// maxFinishedTasks is the number of finished tasks whose state is kept
+const maxFinishedTasks = 100
+
+// ErrQueueFull is returned when a task is submitted while the queue is full
+var ErrQueueFull = errors.New("the queue of tasks is full")
+
+// ErrDuplicateTask is returned when a task is submitted while a task with the same ID is queued or running
+var ErrDuplicateTask = errors.New("the task is already queued")
+
+// State of a submitted task
+type State string
+
+// States of a submitted task
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Completed State = "completed"
+	Failed    State = "failed"
+)
+
+// Task is a submitted task, the status of the task itself is reported to neuron by the child running it
+type Task struct {
+	batch.SubTask
+	State     State     `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	QueuedAt  time.Time `json:"queued_at"`
+	StartTime time.Time `json:"start_time,omitempty"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+}
+
+// taskRunner runs a task in a child nucleus
+type taskRunner interface {
+	RunTask(ctx context.Context, sub *batch.SubTask, workspace, repoDir string) error
+}
+
+// workspacePruner removes the workspaces of the oldest finished tasks
+type workspacePruner interface {
+	PruneTasks(dir string) error
+}
+
+// Daemon runs the submitted tasks one after another
+type Daemon struct {
+	runner taskRunner
+	pruner workspacePruner
+	logger lumber.Logger
+	queue  chan *Task
+
+	mu       sync.Mutex
+	tasks    map[string]*Task
+	finished []string
+}
+
+// New returns a Daemon queuing up to queueSize tasks, the workspaces of the finished tasks are pruned by ws
+func New(runner *batch.Runner, ws *workspace.Manager, queueSize int, logger lumber.Logger) *Daemon {
+	return newDaemon(runner, ws, queueSize, logger)
+}
+
+func newDaemon(runner taskRunner, pruner workspacePruner, queueSize int, logger lumber.Logger) *Daemon {
+	return &Daemon{
+		runner: runner,
+		pruner: pruner,
+		logger: logger,
+		queue:  make(chan *Task, queueSize),
+		tasks:  make(map[string]*Task),
+	}
+}
+
+// Submit queues the task
+func (d *Daemon) Submit(sub batch.SubTask) (Task, error) {
+	if err := sub.Validate(); err != nil {
+		return Task{}, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.tasks[sub.TaskID]; ok && (t.State == Queued || t.State == Running) {
+		return Task{}, ErrDuplicateTask
+	}
+	t := &Task{SubTask: sub, State: Queued, QueuedAt: time.Now()}
+	select {
+	case d.queue <- t:
+	default:
+		return Task{}, ErrQueueFull
+	}
+	d.removeFinished(sub.TaskID)
+	d.tasks[sub.TaskID] = t
+	d.logger.Infof("task %s queued", sub.TaskID)
+	return *t, nil
+}
+
+// Task returns the state of the task
+func (d *Daemon) Task(taskID string) (Task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.tasks[taskID]
+	if !ok {
+		return Task{}, false
+	}
+	return *t, true
+}
+
+// Run runs the queued tasks until ctx is done, the tasks are run in the checkout of global.RepoDir
+func (d *Daemon) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-d.queue:
+			d.run(ctx, t)
+		}
+	}
+}
+
+func (d *Daemon) run(ctx context.Context, t *Task) {
+	d.locked(func() {
+		t.State = Running
+		t.StartTime = time.Now()
+	})
+	workspace := filepath.Join(global.DaemonDir, t.TaskID)
+	err := d.runner.RunTask(ctx, &t.SubTask, workspace, global.RepoDir)
+	if pruneErr := d.pruner.PruneTasks(global.DaemonDir); pruneErr != nil {
+		d.logger.Warnf("failed to prune the workspaces of the finished tasks: %v", pruneErr)
+	}
+	d.locked(func() {
+		t.EndTime = time.Now()
+		t.State = Completed
+		if err != nil {
+			t.State = Failed
+			t.Error = err.Error()
+		}
+		d.finished = append(d.finished, t.TaskID)
+		if len(d.finished) > maxFinishedTasks {
+			delete(d.tasks, d.finished[0])
+			d.finished = d.finished[1:]
+		}
+	})
+	if err != nil {
+		d.logger.Errorf("task %s failed: %v", t.TaskID, err)
+		return
+	}
+	d.logger.Infof("task %s completed in %s", t.TaskID, t.EndTime.Sub(t.StartTime).Round(time.Second))
+}
+
+func (d *Daemon) locked(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn()
+}
+
+// removeFinished forgets the finished task submitted again with the same ID
+func (d *Daemon) removeFinished(taskID string) {
+	for i, id := range d.finished {
+		if id == taskID {
+			d.finished = append(d.finished[:i], d.finished[i+1:]...)
+			return
+		}
+	}
+}
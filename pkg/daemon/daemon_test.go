@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/batch"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+type runnerStub struct {
+	release chan struct{}
+	err     error
+	repoDir chan string
+}
+
+func (r *runnerStub) RunTask(ctx context.Context, sub *batch.SubTask, workspace, repoDir string) error {
+	r.repoDir <- repoDir
+	<-r.release
+	return r.err
+}
+
+type prunerStub struct{}
+
+func (prunerStub) PruneTasks(dir string) error {
+	return nil
+}
+
+func newTestDaemon(t *testing.T, runner taskRunner, queueSize int) *Daemon {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	if err != nil {
+		t.Fatalf("could not instantiate logger: %v", err)
+	}
+	return newDaemon(runner, prunerStub{}, queueSize, logger)
+}
+
+func subTask(id string) batch.SubTask {
+	return batch.SubTask{TaskID: id, PayloadAddress: "https://blob/payloads/" + id + ".json"}
+}
+
+func waitState(t *testing.T, d *Daemon, id string, state State) Task {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if task, ok := d.Task(id); ok && task.State == state {
+			return task
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not reach state %s", id, state)
+	return Task{}
+}
+
+func TestSubmit(t *testing.T) {
+	d := newTestDaemon(t, &runnerStub{}, 1)
+
+	_, err := d.Submit(batch.SubTask{TaskID: "task-1"})
+	assert.Error(t, err, "the payload address is required")
+
+	task, err := d.Submit(subTask("task-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, Queued, task.State)
+
+	_, err = d.Submit(subTask("task-1"))
+	assert.True(t, errors.Is(err, ErrDuplicateTask))
+
+	_, err = d.Submit(subTask("task-2"))
+	assert.True(t, errors.Is(err, ErrQueueFull))
+
+	_, ok := d.Task("task-2")
+	assert.False(t, ok, "the rejected task is not kept")
+}
+
+func TestRun(t *testing.T) {
+	runner := &runnerStub{release: make(chan struct{}), repoDir: make(chan string, 2)}
+	d := newTestDaemon(t, runner, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	_, err := d.Submit(subTask("task-1"))
+	assert.NoError(t, err)
+	_, err = d.Submit(subTask("task-2"))
+	assert.NoError(t, err)
+
+	// the tasks run one after another in the same checkout
+	waitState(t, d, "task-1", Running)
+	task, _ := d.Task("task-2")
+	assert.Equal(t, Queued, task.State)
+	runner.release <- struct{}{}
+	waitState(t, d, "task-1", Completed)
+	waitState(t, d, "task-2", Running)
+	runner.err = errors.New("exit status 1")
+	runner.release <- struct{}{}
+	task = waitState(t, d, "task-2", Failed)
+	assert.Equal(t, "exit status 1", task.Error)
+	assert.Equal(t, <-runner.repoDir, <-runner.repoDir)
+
+	// a finished task can be submitted again
+	_, err = d.Submit(subTask("task-1"))
+	assert.NoError(t, err)
+}
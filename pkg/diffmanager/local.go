@@ -0,0 +1,88 @@
+package diffmanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+type localDiffManager struct {
+	repoDir string
+	logger  lumber.Logger
+}
+
+// NewLocalDiffManager returns a DiffManager computing the diff with git in the working directory of a local
+// repository instead of fetching it from the git provider. The diff is taken between the base commit and the
+// working directory, so that the uncommitted changes and the untracked files are included.
+func NewLocalDiffManager(repoDir string, logger lumber.Logger) core.DiffManager {
+	return &localDiffManager{repoDir: repoDir, logger: logger}
+}
+
+// GetChangedFiles returns the files changed in the working directory since the base commit
+func (dm *localDiffManager) GetChangedFiles(ctx context.Context, payload *core.Payload, cloneToken string) (map[string]int, error) {
+	out, err := dm.git(ctx, "diff", "--name-status", "--no-renames", payload.BaseCommit)
+	if err != nil {
+		return nil, err
+	}
+	m := parseNameStatus(out)
+	untracked, err := dm.git(ctx, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range strings.Split(strings.TrimSpace(untracked), "\n") {
+		if file != "" {
+			m[file] = core.FileAdded
+		}
+	}
+	return m, nil
+}
+
+// GetChangedLines returns the added or modified lines of the files changed in the working directory since
+// the base commit, the lines of the untracked files are not included
+func (dm *localDiffManager) GetChangedLines(ctx context.Context, payload *core.Payload, cloneToken string) (map[string][]int, error) {
+	out, err := dm.git(ctx, "diff", "--unified=0", "--no-renames", payload.BaseCommit)
+	if err != nil {
+		return nil, err
+	}
+	return parseUnifiedDiffLines(out), nil
+}
+
+func (dm *localDiffManager) git(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dm.repoDir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		dm.logger.Errorf("git %s failed: %v, %s", args[0], err, stderr.String())
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// parseNameStatus parses the output of git diff --name-status
+func parseNameStatus(diff string) map[string]int {
+	m := make(map[string]int)
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+		switch fields[0][0] {
+		case 'A':
+			m[fields[1]] = core.FileAdded
+		case 'D':
+			m[fields[1]] = core.FileRemoved
+		default:
+			m[fields[1]] = core.FileModified
+		}
+	}
+	return m
+}
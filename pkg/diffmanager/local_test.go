@@ -0,0 +1,64 @@
+package diffmanager
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	diff := "A\tsrc/new.js\nD\tsrc/old.js\nM\tsrc/sum.js\nT\tlink.js\n"
+	assert.Equal(t, map[string]int{
+		"src/new.js": core.FileAdded,
+		"src/old.js": core.FileRemoved,
+		"src/sum.js": core.FileModified,
+		"link.js":    core.FileModified,
+	}, parseNameStatus(diff))
+}
+
+func TestLocalDiffManager(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.Nil(t, err, string(out))
+	}
+	write := func(name, content string) {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	git("init", "-q")
+	write("sum.js", "a\nb\n")
+	write("old.js", "gone\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	write("sum.js", "a\nc\nd\n")
+	write("new.test.js", "test\n")
+	git("rm", "-q", "old.js")
+
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	dm := NewLocalDiffManager(dir, logger)
+	payload := &core.Payload{BaseCommit: "HEAD"}
+
+	files, err := dm.GetChangedFiles(context.Background(), payload, "")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"sum.js": core.FileModified, "old.js": core.FileRemoved, "new.test.js": core.FileAdded}, files)
+
+	lines, err := dm.GetChangedLines(context.Background(), payload, "")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]int{"sum.js": {2, 3}}, lines)
+
+	_, err = dm.GetChangedFiles(context.Background(), &core.Payload{BaseCommit: "unknown"}, "")
+	assert.NotNil(t, err)
+}
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 	"github.com/LambdaTest/synapse/pkg/urlmanager"
 )
 
@@ -38,6 +40,7 @@ type gitLabDiff struct {
 	NewFile     bool   `json:"new_file"`
 	RenamedFile bool   `json:"renamed_file"`
 	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
 }
 
 // NewDiffManager Instantiate DiffManager
@@ -45,12 +48,7 @@ func NewDiffManager(cfg *config.NucleusConfig, logger lumber.Logger) *diffManage
 	return &diffManager{
 		cfg:    cfg,
 		logger: logger,
-		client: http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				DisableKeepAlives: true,
-			},
-		},
+		client: requestutils.NewClientWithoutKeepAlive(30 * time.Second),
 	}
 }
 
@@ -167,7 +165,7 @@ func (dm *diffManager) parseGitLabDiff(eventType core.EventType, diff []byte) (m
 		return nil, err
 	}
 	diffs := diffList.PRDiff
-	if eventType == core.EventPush {
+	if !eventType.IsPremerge() {
 		diffs = diffList.CommitDiff
 	}
 	for _, diff := range diffs {
@@ -196,14 +194,15 @@ func (dm *diffManager) parseGitDiff(gitprovider string, eventType core.EventType
 	}
 }
 
-// GetChangedFiles Figure out changed files
-func (dm *diffManager) GetChangedFiles(ctx context.Context, payload *core.Payload, cloneToken string) (map[string]int, error) {
-	// map to store file and type of change (added, removed, modified)
-	var m map[string]int
-
+// fetchDiff fetches the raw PR or commit diff from the git provider,
+// returns nil diff if commit diff is not found or for the scheduled builds
+func (dm *diffManager) fetchDiff(payload *core.Payload, cloneToken string) ([]byte, error) {
+	if payload.EventType == core.EventCron {
+		return nil, nil
+	}
 	var diff []byte
 	var err error
-	if payload.EventType == core.EventPullRequest {
+	if payload.EventType.IsPremerge() {
 		diff, err = dm.getPRDiff(payload.GitProvider, payload.RepoLink, payload.PullRequestNumber, cloneToken)
 		if err != nil {
 			dm.logger.Errorf("failed to parse pr diff for gitprovider: %s error: %v", payload.GitProvider, err)
@@ -220,11 +219,101 @@ func (dm *diffManager) GetChangedFiles(ctx context.Context, payload *core.Payloa
 			return nil, err
 		}
 	}
+	return diff, nil
+}
 
-	m, err = dm.parseGitDiff(payload.GitProvider, payload.EventType, diff)
+// GetChangedFiles Figure out changed files
+func (dm *diffManager) GetChangedFiles(ctx context.Context, payload *core.Payload, cloneToken string) (map[string]int, error) {
+	diff, err := dm.fetchDiff(payload, cloneToken)
+	if err != nil || diff == nil {
+		return nil, err
+	}
+
+	// map to store file and type of change (added, removed, modified)
+	m, err := dm.parseGitDiff(payload.GitProvider, payload.EventType, diff)
 	if err != nil {
 		dm.logger.Errorf("failed to parse gitdiff for gitprovider: %s error: %v", payload.GitProvider, err)
 		return nil, err
 	}
 	return m, nil
 }
+
+// GetChangedLines Figure out added or modified lines of each changed file
+func (dm *diffManager) GetChangedLines(ctx context.Context, payload *core.Payload, cloneToken string) (map[string][]int, error) {
+	diff, err := dm.fetchDiff(payload, cloneToken)
+	if err != nil || diff == nil {
+		return nil, err
+	}
+
+	switch payload.GitProvider {
+	case core.GitHub:
+		return parseUnifiedDiffLines(string(diff)), nil
+	case core.GitLab:
+		var diffList gitLabDiffList
+		if err := json.Unmarshal(diff, &diffList); err != nil {
+			dm.logger.Errorf("failed to unmarshall diff %v error %v", string(diff), err)
+			return nil, err
+		}
+		diffs := diffList.PRDiff
+		if !payload.EventType.IsPremerge() {
+			diffs = diffList.CommitDiff
+		}
+		m := make(map[string][]int)
+		for _, d := range diffs {
+			if d.DeletedFile {
+				continue
+			}
+			// gitlab diffs only contain the hunks, so add the file header
+			for path, lines := range parseUnifiedDiffLines("+++ b/" + d.NewPath + "\n" + d.Diff) {
+				m[path] = append(m[path], lines...)
+			}
+		}
+		return m, nil
+	default:
+		return nil, errs.ErrUnsupportedGitProvider
+	}
+}
+
+// parseUnifiedDiffLines returns the line numbers in the new version of each file
+// which were added or modified in the unified diff
+func parseUnifiedDiffLines(diff string) map[string][]int {
+	m := make(map[string][]int)
+	var file string
+	var line int
+	// file headers are only expected outside of hunks, else `+++ ` can be an added line
+	inHunk := false
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "diff "):
+			inHunk = false
+		case !inHunk && strings.HasPrefix(text, "+++ "):
+			file = ""
+			if strings.HasPrefix(text, "+++ b/") {
+				file = text[6:]
+			}
+		case !inHunk && (strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "index ")):
+			continue
+		case strings.HasPrefix(text, "@@"):
+			inHunk = true
+			// hunk header of the form @@ -l,s +l,s @@
+			line = 0
+			if i := strings.Index(text, " +"); i >= 0 {
+				newRange := strings.SplitN(strings.Fields(text[i+2:])[0], ",", 2)[0]
+				if n, err := strconv.Atoi(newRange); err == nil {
+					line = n
+				}
+			}
+		case file == "" || line == 0:
+			continue
+		case strings.HasPrefix(text, "+"):
+			m[file] = append(m[file], line)
+			line++
+		case strings.HasPrefix(text, " "), text == "":
+			line++
+		}
+	}
+	return m
+}
@@ -0,0 +1,34 @@
+package diffmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnifiedDiffLines(t *testing.T) {
+	diff := `diff --git a/src/sum.js b/src/sum.js
+index 3b18e51..a1f2c3d 100644
+--- a/src/sum.js
++++ b/src/sum.js
+@@ -1,4 +1,5 @@
+ function sum(a, b) {
+-  return a + b;
++  const s = a + b;
+++++ counter;
+   return s;
+ }
+@@ -10 +11,2 @@ module.exports
+-- removed
++module.exports = sum;
++
+diff --git a/old.js b/old.js
+deleted file mode 100644
+--- a/old.js
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`
+	lines := parseUnifiedDiffLines(diff)
+	assert.Equal(t, map[string][]int{"src/sum.js": {2, 3, 11, 12}}, lines)
+}
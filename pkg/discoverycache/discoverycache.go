@@ -0,0 +1,77 @@
+// Package discoverycache caches the test lists discovered by the runners in the blob storage, so that the
+// discovery is skipped when it already ran for the same commit and configuration.
+package discoverycache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const cacheMimeType = "application/json"
+
+type cache struct {
+	azureClient core.AzureClient
+	logger      lumber.Logger
+	mu          sync.Mutex
+	recorded    []json.RawMessage
+}
+
+// New returns a new DiscoveryCache storing the test lists in the blob storage
+func New(azureClient core.AzureClient, logger lumber.Logger) core.DiscoveryCache {
+	return &cache{azureClient: azureClient, logger: logger}
+}
+
+// Record collects a test list posted by a runner
+func (c *cache) Record(raw json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorded = append(c.recorded, raw)
+}
+
+// Recorded returns the test lists posted by the runners
+func (c *cache) Recorded() []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]json.RawMessage(nil), c.recorded...)
+}
+
+// Load returns the cached test lists of the key or nil if they are not cached
+func (c *cache) Load(ctx context.Context, payload *core.Payload, key string) ([]json.RawMessage, error) {
+	reader, err := c.azureClient.Find(ctx, blobPath(payload, key))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+	var lists []json.RawMessage
+	if err := json.NewDecoder(reader).Decode(&lists); err != nil {
+		return nil, err
+	}
+	c.logger.Debugf("loaded %d cached test lists for key %s", len(lists), key)
+	return lists, nil
+}
+
+// Save stores the test lists under the key
+func (c *cache) Save(ctx context.Context, payload *core.Payload, key string, lists []json.RawMessage) error {
+	body, err := json.Marshal(lists)
+	if err != nil {
+		return err
+	}
+	_, err = c.azureClient.Create(ctx, blobPath(payload, key), bytes.NewReader(body), cacheMimeType)
+	return err
+}
+
+// blobPath scopes the cache to the commit, the key identifies the configuration and the changes
+func blobPath(payload *core.Payload, key string) string {
+	return fmt.Sprintf("discovery/%s/%s/%s/%s.json", payload.OrgID, payload.RepoID, payload.TargetCommit, key)
+}
@@ -0,0 +1,41 @@
+package discoverycache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	store, err := storage.NewLocalStore(t.TempDir(), "discovery", logger)
+	assert.Nil(t, err)
+	c := New(store, logger)
+	ctx := context.Background()
+	payload := &core.Payload{OrgID: "org", RepoID: "repo", TargetCommit: "sha"}
+
+	lists, err := c.Load(ctx, payload, "key")
+	assert.Nil(t, err)
+	assert.Nil(t, lists)
+
+	c.Record(json.RawMessage(`{"taskID":"task","tests":[]}`))
+	c.Record(json.RawMessage(`{"taskID":"task","tests":[{"testID":"1"}]}`))
+	recorded := c.Recorded()
+	assert.Len(t, recorded, 2)
+	assert.Nil(t, c.Save(ctx, payload, "key", recorded))
+
+	lists, err = c.Load(ctx, payload, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, recorded, lists)
+
+	// the cache is scoped to the commit
+	lists, err = c.Load(ctx, &core.Payload{OrgID: "org", RepoID: "repo", TargetCommit: "other"}, "key")
+	assert.Nil(t, err)
+	assert.Nil(t, lists)
+}
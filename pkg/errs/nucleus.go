@@ -2,6 +2,7 @@ package errs
 
 import (
 	"fmt"
+	"strings"
 )
 
 // GenericUserFacingBEErrRemark returns a generic error message for user facing errors.
@@ -38,11 +39,73 @@ func ErrInvalidPayload(errMsg string) error {
 	return New(errMsg)
 }
 
+// ErrPayloadSignature is returned when the signature of the payload is missing or does not match, the payload
+// may have been tampered with
+var ErrPayloadSignature = Err{
+	Code:    "ERR::PAYLOAD::SIGNATURE",
+	Message: "Payload signature verification failed"}
+
+// ErrBlobDecryption is returned when an encrypted blob can't be decrypted with the key of the runner, the blob
+// was encrypted with another key or may have been tampered with
+var ErrBlobDecryption = Err{
+	Code:    "ERR::BLOB::DECRYPTION",
+	Message: "Blob decryption failed"}
+
 // ErrSecretNotFound represents the error when a secret is not found in map.
 func ErrSecretNotFound(secret string) error {
 	return New(fmt.Sprintf("secret with name %s not found", secret))
 }
 
+// ThresholdViolation represents a coverage metric below the configured threshold
+type ThresholdViolation struct {
+	Metric    string  `json:"metric"`
+	File      string  `json:"file,omitempty"`
+	Threshold float64 `json:"threshold"`
+	Actual    float64 `json:"actual"`
+}
+
+func (v ThresholdViolation) String() string {
+	if v.File != "" {
+		return fmt.Sprintf("%s coverage (%.2f%%) does not meet threshold (%.2f%%) for %s", v.Metric, v.Actual, v.Threshold, v.File)
+	}
+	return fmt.Sprintf("%s coverage (%.2f%%) does not meet threshold (%.2f%%)", v.Metric, v.Actual, v.Threshold)
+}
+
+// CoverageThresholdError is returned when the coverage does not meet the configured thresholds
+type CoverageThresholdError struct {
+	CommitID   string               `json:"commit_id"`
+	Violations []ThresholdViolation `json:"violations"`
+}
+
+func (e *CoverageThresholdError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return fmt.Sprintf("coverage threshold not met for commit %s: %s", e.CommitID, strings.Join(msgs, "; "))
+}
+
+// ToolchainError is returned when a toolchain required by the configuration is not available
+type ToolchainError struct {
+	Toolchain string `json:"toolchain"`
+	Required  string `json:"required"`
+	// Installed is the version found in the container image, empty if the toolchain is not installed
+	Installed string `json:"installed"`
+	// Reason is set if the version could not be installed or determined
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e *ToolchainError) Error() string {
+	switch {
+	case e.Reason != "":
+		return fmt.Sprintf("%s %s is required by the configuration: %s", e.Toolchain, e.Required, e.Reason)
+	case e.Installed == "":
+		return fmt.Sprintf("%s %s is required by the configuration but %s is not installed in the container image", e.Toolchain, e.Required, e.Toolchain)
+	default:
+		return fmt.Sprintf("%s %s is required by the configuration but %s %s is installed in the container image", e.Toolchain, e.Required, e.Toolchain, e.Installed)
+	}
+}
+
 var (
 	// ErrParseVariableName represents the error when unable to parse a
 	// variable name within a substitution.
@@ -55,6 +118,8 @@ var (
 	ErrSASToken = New("azure client requires SAS Token")
 	// ErrAzureCredentials is returned when the azure credentials are invalid.
 	ErrAzureCredentials = New("azure client requires credentials")
+	// ErrFileNotFound is returned when the file is not found in the repository by the git provider.
+	ErrFileNotFound = New("file not found in the repository")
 	// ErrApiStatus is returned when the api status is not 200.
 	ErrApiStatus = New("non OK status")
 	// ErrInvalidLoggerInstance is returned when logger instance is not supported.
@@ -63,4 +128,18 @@ var (
 	ErrUnsupportedGitProvider = New("unsupported gitprovider")
 	// ErrGitDiffNotFound is returned when basecommit is null or git provider returns empty diff
 	ErrGitDiffNotFound = New("diff not found")
+	// ErrUnsupportedVaultAuth is returned when the vault auth method is not supported
+	ErrUnsupportedVaultAuth = New("unsupported vault auth method")
+	// ErrVaultAuth is returned when vault does not return a client token on login
+	ErrVaultAuth = New("vault login did not return a client token")
+	// ErrBlocklistEntryNotFound is returned when removing a blocklist entry which was not added at runtime
+	ErrBlocklistEntryNotFound = New("blocklist entry not found")
+	// ErrTaskNotReady is returned when the payload or the configuration of the task is not loaded yet
+	ErrTaskNotReady = New("task is not ready")
+	// ErrCircuitOpen is returned without sending the request while the circuit breaker of the host is open
+	ErrCircuitOpen = New("circuit breaker is open")
+	// ErrResultStoreDisabled is returned by the queries of the results store outside of local runner mode
+	ErrResultStoreDisabled = New("results store is only available in local runner mode")
+	// ErrRunNotFound is returned by the results store if the run of the task was not saved
+	ErrRunNotFound = New("run not found in the results store")
 )
@@ -231,3 +231,17 @@ var ERR_SNK_RD_WRT_MSM = Err{
 var CR_AUTH_NF = Err{
 	Code:    "CR::AUTH:NF",
 	Message: "Contianer registry auth are not present for private repo"}
+
+// ERR_K8S_CRT function returns error with code ERR::K8S::CRT
+func ERR_K8S_CRT(err string) Err {
+	return Err{
+		Code:    "ERR::K8S::CRT",
+		Message: fmt.Sprintf("Kubernetes job create failed with error:  \n%s", err)}
+}
+
+// ERR_K8S_RUN function returns error with code ERR::K8S::RUN
+func ERR_K8S_RUN(err string) Err {
+	return Err{
+		Code:    "ERR::K8S::RUN",
+		Message: fmt.Sprintf("Kubernetes job run failed with error:  \n%s", err)}
+}
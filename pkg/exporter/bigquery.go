@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+const (
+	bigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	gceTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// bigQueryBatchSize is the recommended maximum number of rows of a streaming insert
+	bigQueryBatchSize = 500
+)
+
+// bigQuerySink streams the results into a table using the insertAll API,
+// the columns of the table are the fields of testRecord
+type bigQuerySink struct {
+	insertURL  string
+	tokenURL   string
+	token      string
+	httpClient http.Client
+}
+
+type bigQueryRow struct {
+	// InsertID deduplicates the rows if the task is retried
+	InsertID string     `json:"insertId"`
+	JSON     testRecord `json:"json"`
+}
+
+type bigQueryInsertRequest struct {
+	Rows []bigQueryRow `json:"rows"`
+}
+
+type bigQueryInsertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+func newBigQuerySink(cfg *config.Export, httpClient http.Client) *bigQuerySink {
+	return &bigQuerySink{
+		insertURL: fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigQueryEndpoint,
+			url.PathEscape(cfg.BigQueryProject), url.PathEscape(cfg.BigQueryDataset), url.PathEscape(cfg.BigQueryTable)),
+		tokenURL:   gceTokenURL,
+		token:      cfg.BigQueryToken,
+		httpClient: httpClient,
+	}
+}
+
+func (s *bigQuerySink) name() string {
+	return sinkBigQuery
+}
+
+func (s *bigQuerySink) export(ctx context.Context, payload *core.Payload, tests []testRecord) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	for _, batch := range batches(len(tests), bigQueryBatchSize) {
+		rows := make([]bigQueryRow, 0, batch[1]-batch[0])
+		for _, test := range tests[batch[0]:batch[1]] {
+			rows = append(rows, bigQueryRow{InsertID: test.TaskID + "/" + test.TestID, JSON: test})
+		}
+		resp := bigQueryInsertResponse{}
+		if err := postJSON(ctx, &s.httpClient, s.insertURL, headers, bigQueryInsertRequest{Rows: rows}, &resp); err != nil {
+			return err
+		}
+		// the valid rows are not inserted either if any row is invalid
+		if len(resp.InsertErrors) > 0 {
+			first := resp.InsertErrors[0]
+			msg := ""
+			if len(first.Errors) > 0 {
+				msg = first.Errors[0].Message
+			}
+			return fmt.Errorf("%d rows failed to insert, row %d: %s", len(resp.InsertErrors), batch[0]+first.Index, msg)
+		}
+	}
+	return nil
+}
+
+// accessToken returns the configured token, else the token of the service account of the instance
+func (s *bigQuerySink) accessToken(ctx context.Context) (string, error) {
+	if s.token != "" {
+		return s.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned status %d", resp.StatusCode)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+const (
+	datadogAPIKeyHeader = "DD-API-KEY"
+	// datadogBatchSize keeps the payloads below the size limit of the intake
+	datadogBatchSize = 1000
+)
+
+// datadogSink sends the results as test events to the agentless intake of Datadog CI Visibility
+type datadogSink struct {
+	url        string
+	apiKey     string
+	service    string
+	httpClient http.Client
+}
+
+type datadogPayload struct {
+	Version  int                          `json:"version"`
+	Metadata map[string]map[string]string `json:"metadata"`
+	Events   []datadogEvent               `json:"events"`
+}
+
+type datadogEvent struct {
+	Type    string      `json:"type"`
+	Version int         `json:"version"`
+	Content datadogSpan `json:"content"`
+}
+
+type datadogSpan struct {
+	TraceID  uint64             `json:"trace_id"`
+	SpanID   uint64             `json:"span_id"`
+	ParentID uint64             `json:"parent_id"`
+	Name     string             `json:"name"`
+	Resource string             `json:"resource"`
+	Service  string             `json:"service"`
+	Type     string             `json:"type"`
+	Start    int64              `json:"start"`
+	Duration int64              `json:"duration"`
+	Error    int                `json:"error"`
+	Meta     map[string]string  `json:"meta"`
+	Metrics  map[string]float64 `json:"metrics"`
+}
+
+func newDatadogSink(cfg *config.Export, httpClient http.Client) *datadogSink {
+	return &datadogSink{
+		url:        "https://citestcycle-intake." + cfg.DatadogSite + "/api/v2/citestcycle",
+		apiKey:     cfg.DatadogAPIKey,
+		service:    cfg.DatadogService,
+		httpClient: httpClient,
+	}
+}
+
+func (s *datadogSink) name() string {
+	return sinkDatadog
+}
+
+func (s *datadogSink) export(ctx context.Context, payload *core.Payload, tests []testRecord) error {
+	service := s.service
+	if service == "" {
+		service = payload.RepoSlug
+	}
+	headers := map[string]string{datadogAPIKeyHeader: s.apiKey}
+	for _, batch := range batches(len(tests), datadogBatchSize) {
+		events := make([]datadogEvent, 0, batch[1]-batch[0])
+		for i := range tests[batch[0]:batch[1]] {
+			events = append(events, datadogEvent{Type: "test", Version: 2, Content: testSpan(payload, &tests[batch[0]+i], service)})
+		}
+		body := datadogPayload{
+			Version:  1,
+			Metadata: map[string]map[string]string{"*": {"env": "ci"}},
+			Events:   events,
+		}
+		if err := postJSON(ctx, &s.httpClient, s.url, headers, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testSpan returns the span of the test, the ids are derived from the task and the test so that
+// the events of a retried export are deduplicated
+func testSpan(payload *core.Payload, test *testRecord, service string) datadogSpan {
+	status, isError := "skip", 0
+	switch test.Status {
+	case string(core.Passed):
+		status = "pass"
+	case core.TestFailed, core.TestTimedOut:
+		status, isError = "fail", 1
+	}
+	meta := map[string]string{
+		"span.kind":          "test",
+		"test.type":          "test",
+		"test.name":          test.Name,
+		"test.suite":         test.Suite,
+		"test.status":        status,
+		"test.source.file":   test.File,
+		"git.repository_url": payload.RepoLink,
+		"git.commit.sha":     test.CommitID,
+		"git.branch":         test.Branch,
+		"ci.provider.name":   "tas",
+		"ci.pipeline.id":     test.BuildID,
+		"ci.job.name":        test.TaskID,
+	}
+	if test.Matrix != "" {
+		meta["tas.matrix"] = test.Matrix
+	}
+	return datadogSpan{
+		TraceID:  hashID(test.TaskID, test.TestID, "trace"),
+		SpanID:   hashID(test.TaskID, test.TestID, "span"),
+		Name:     "tas.test",
+		Resource: test.FullName,
+		Service:  service,
+		Type:     "test",
+		Start:    test.StartTime.UnixNano(),
+		Duration: int64(test.DurationMS) * 1e6,
+		Error:    isError,
+		Meta:     meta,
+		Metrics:  map[string]float64{"_dd.top_level": 1, "tas.retries": float64(test.Retries)},
+	}
+}
+
+func hashID(parts ...string) uint64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	// the ids are positive 63 bit integers
+	return h.Sum64() >> 1
+}
@@ -0,0 +1,210 @@
+// Package exporter exports the test results to external test analytics platforms.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+)
+
+// names of the sinks in config.Export.Sinks
+const (
+	sinkHTTP     = "http"
+	sinkBigQuery = "bigquery"
+	sinkDatadog  = "datadog"
+)
+
+// sink exports the results to a single platform
+type sink interface {
+	name() string
+	export(ctx context.Context, payload *core.Payload, tests []testRecord) error
+}
+
+type exporter struct {
+	sinks  []sink
+	logger lumber.Logger
+}
+
+// New returns a new ResultExporter sending the results to the sinks of the configuration,
+// the results are discarded if no sink is configured
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (core.ResultExporter, error) {
+	exportCfg := cfg.Export
+	httpClient := requestutils.NewClient(time.Duration(exportCfg.Timeout) * time.Second)
+	e := &exporter{logger: logger}
+	for _, name := range strings.Split(exportCfg.Sinks, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+			continue
+		case sinkHTTP:
+			if exportCfg.HTTPURL == "" {
+				return nil, errors.New("export to http requires the url")
+			}
+			e.sinks = append(e.sinks, &httpSink{url: exportCfg.HTTPURL, token: exportCfg.HTTPToken, httpClient: httpClient})
+		case sinkBigQuery:
+			if exportCfg.BigQueryProject == "" || exportCfg.BigQueryDataset == "" || exportCfg.BigQueryTable == "" {
+				return nil, errors.New("export to bigquery requires the project, dataset and table")
+			}
+			e.sinks = append(e.sinks, newBigQuerySink(&exportCfg, httpClient))
+		case sinkDatadog:
+			if exportCfg.DatadogAPIKey == "" {
+				return nil, errors.New("export to datadog requires the api key")
+			}
+			e.sinks = append(e.sinks, newDatadogSink(&exportCfg, httpClient))
+		default:
+			return nil, fmt.Errorf("unsupported export sink %s", name)
+		}
+	}
+	return e, nil
+}
+
+// Export sends the results to each sink, the errors of the sinks are combined
+func (e *exporter) Export(ctx context.Context, payload *core.Payload, result *core.ExecutionResult) error {
+	if len(e.sinks) == 0 || len(result.TestPayload) == 0 {
+		return nil
+	}
+	tests := records(payload, result)
+	msgs := make([]string, 0)
+	for _, s := range e.sinks {
+		if err := s.export(ctx, payload, tests); err != nil {
+			e.logger.Errorf("failed to export %d test results to %s, error: %v", len(tests), s.name(), err)
+			msgs = append(msgs, fmt.Sprintf("%s: %v", s.name(), err))
+			continue
+		}
+		e.logger.Debugf("exported %d test results to %s", len(tests), s.name())
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("failed to export test results to %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// testRecord is the result of a test exported to the sinks, the fields are flat so that
+// they map to the columns of a table
+type testRecord struct {
+	OrgID       string    `json:"org_id"`
+	RepoID      string    `json:"repo_id"`
+	RepoSlug    string    `json:"repo_slug"`
+	BuildID     string    `json:"build_id"`
+	TaskID      string    `json:"task_id"`
+	CommitID    string    `json:"commit_id"`
+	Branch      string    `json:"branch"`
+	EventType   string    `json:"event_type"`
+	TestID      string    `json:"test_id"`
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	SuiteID     string    `json:"suite_id"`
+	Suite       string    `json:"suite"`
+	File        string    `json:"file"`
+	Status      string    `json:"status"`
+	DurationMS  int       `json:"duration_ms"`
+	Retries     int       `json:"retries"`
+	Blocklisted bool      `json:"blocklisted"`
+	StartTime   time.Time `json:"start_time"`
+	// Matrix is the matrix combination formatted as sorted key=value pairs
+	Matrix string `json:"matrix,omitempty"`
+}
+
+func records(payload *core.Payload, result *core.ExecutionResult) []testRecord {
+	tests := make([]testRecord, 0, len(result.TestPayload))
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		// the full title is the title prefixed with the titles of the parent suites
+		suite := strings.TrimSpace(strings.TrimSuffix(test.FullTitle, test.Title))
+		tests = append(tests, testRecord{
+			OrgID:       payload.OrgID,
+			RepoID:      payload.RepoID,
+			RepoSlug:    payload.RepoSlug,
+			BuildID:     payload.BuildID,
+			TaskID:      payload.TaskID,
+			CommitID:    payload.TargetCommit,
+			Branch:      payload.BranchName,
+			EventType:   string(payload.EventType),
+			TestID:      test.TestID,
+			Name:        test.Title,
+			FullName:    test.FullTitle,
+			SuiteID:     test.SuiteID,
+			Suite:       suite,
+			File:        test.FilePath,
+			Status:      test.Status,
+			DurationMS:  test.Duration,
+			Retries:     test.CurrentRetry,
+			Blocklisted: test.Blocklisted,
+			StartTime:   test.StartTime,
+			Matrix:      formatMatrix(test.Matrix),
+		})
+	}
+	return tests
+}
+
+func formatMatrix(matrix core.MatrixCombination) string {
+	pairs := make([]string, 0, len(matrix))
+	for k, v := range matrix {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// postJSON sends the body as JSON with the headers and decodes the response into out if not nil
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("non OK status %d: %s", resp.StatusCode, truncate(respBody, 512))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func truncate(body []byte, n int) string {
+	if len(body) <= n {
+		return string(body)
+	}
+	return string(body[:n]) + "..."
+}
+
+// batches splits the n records into batches of at most size records
+func batches(n, size int) [][2]int {
+	ranges := make([][2]int, 0, n/size+1)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
@@ -0,0 +1,133 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	e, err := New(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	assert.Empty(t, e.(*exporter).sinks)
+
+	_, err = New(&config.NucleusConfig{Export: config.Export{Sinks: "http"}}, logger)
+	assert.NotNil(t, err)
+	_, err = New(&config.NucleusConfig{Export: config.Export{Sinks: "splunk"}}, logger)
+	assert.NotNil(t, err)
+
+	e, err = New(&config.NucleusConfig{Export: config.Export{
+		Sinks:           "http, bigquery,datadog",
+		HTTPURL:         "http://localhost/results",
+		BigQueryProject: "project",
+		BigQueryDataset: "tas",
+		BigQueryTable:   "tests",
+		DatadogAPIKey:   "key",
+		DatadogSite:     "datadoghq.eu",
+	}}, logger)
+	assert.Nil(t, err)
+	sinks := e.(*exporter).sinks
+	assert.Len(t, sinks, 3)
+	assert.Equal(t, "https://bigquery.googleapis.com/bigquery/v2/projects/project/datasets/tas/tables/tests/insertAll", sinks[1].(*bigQuerySink).insertURL)
+	assert.Equal(t, "https://citestcycle-intake.datadoghq.eu/api/v2/citestcycle", sinks[2].(*datadogSink).url)
+}
+
+func TestExport(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	bodies := make(map[string][]byte)
+	headers := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies[r.URL.Path] = body
+		headers[r.URL.Path] = r.Header
+		switch r.URL.Path {
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token":"gce-token","expires_in":3599,"token_type":"Bearer"}`))
+		case "/insertAll":
+			_, _ = w.Write([]byte(`{"kind":"bigquery#tableDataInsertAllResponse"}`))
+		case "/fails":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	start := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	payload := &core.Payload{
+		OrgID: "org", RepoID: "repo", RepoSlug: "org/repo", RepoLink: "https://github.com/org/repo",
+		BuildID: "build", TaskID: "task", TargetCommit: "abc", BranchName: "main", EventType: core.EventPush,
+	}
+	result := &core.ExecutionResult{TestPayload: []core.TestPayload{
+		{TestID: "1", Title: "returns 200", FullTitle: "api returns 200", FilePath: "api.test.js", Status: "passed", Duration: 12, StartTime: start},
+		{TestID: "2", Title: "returns 404", FullTitle: "api returns 404", FilePath: "api.test.js", Status: "failed", Duration: 3, StartTime: start,
+			Matrix: core.MatrixCombination{"nodeVersion": "16.13.0", "TZ": "UTC"}},
+	}}
+	e := &exporter{logger: logger, sinks: []sink{
+		&httpSink{url: server.URL + "/http", token: "secret"},
+		&bigQuerySink{insertURL: server.URL + "/insertAll", tokenURL: server.URL + "/token"},
+		&datadogSink{url: server.URL + "/datadog", apiKey: "key"},
+	}}
+	assert.Nil(t, e.Export(context.Background(), payload, result))
+
+	report := httpReport{}
+	assert.Nil(t, json.Unmarshal(bodies["/http"], &report))
+	assert.Equal(t, "Bearer secret", headers["/http"].Get("Authorization"))
+	assert.Equal(t, []testRecord{
+		{OrgID: "org", RepoID: "repo", RepoSlug: "org/repo", BuildID: "build", TaskID: "task", CommitID: "abc", Branch: "main",
+			EventType: "push", TestID: "1", Name: "returns 200", FullName: "api returns 200", Suite: "api", File: "api.test.js",
+			Status: "passed", DurationMS: 12, StartTime: start},
+		{OrgID: "org", RepoID: "repo", RepoSlug: "org/repo", BuildID: "build", TaskID: "task", CommitID: "abc", Branch: "main",
+			EventType: "push", TestID: "2", Name: "returns 404", FullName: "api returns 404", Suite: "api", File: "api.test.js",
+			Status: "failed", DurationMS: 3, StartTime: start, Matrix: "TZ=UTC,nodeVersion=16.13.0"},
+	}, report.Tests)
+
+	insert := bigQueryInsertRequest{}
+	assert.Nil(t, json.Unmarshal(bodies["/insertAll"], &insert))
+	assert.Equal(t, "Google", headers["/token"].Get("Metadata-Flavor"))
+	assert.Equal(t, "Bearer gce-token", headers["/insertAll"].Get("Authorization"))
+	assert.Len(t, insert.Rows, 2)
+	assert.Equal(t, "task/2", insert.Rows[1].InsertID)
+
+	events := datadogPayload{}
+	assert.Nil(t, json.Unmarshal(bodies["/datadog"], &events))
+	assert.Equal(t, "key", headers["/datadog"].Get(datadogAPIKeyHeader))
+	assert.Len(t, events.Events, 2)
+	span := events.Events[1].Content
+	assert.Equal(t, "org/repo", span.Service)
+	assert.Equal(t, 1, span.Error)
+	assert.Equal(t, "fail", span.Meta["test.status"])
+	assert.Equal(t, "api", span.Meta["test.suite"])
+	assert.Equal(t, start.UnixNano(), span.Start)
+	assert.Equal(t, int64(3e6), span.Duration)
+	assert.Equal(t, "pass", events.Events[0].Content.Meta["test.status"])
+
+	// the remaining sinks are exported to if a sink fails
+	delete(bodies, "/datadog")
+	e.sinks[0] = &httpSink{url: server.URL + "/fails"}
+	err = e.Export(context.Background(), payload, result)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "http: non OK status 403")
+	assert.NotNil(t, bodies["/datadog"])
+}
+
+func TestBatches(t *testing.T) {
+	assert.Equal(t, [][2]int{}, batches(0, 500))
+	assert.Equal(t, [][2]int{{0, 500}, {500, 1000}, {1000, 1001}}, batches(1001, 500))
+}
@@ -0,0 +1,31 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// httpSink posts the results of the task as a single JSON document
+type httpSink struct {
+	url        string
+	token      string
+	httpClient http.Client
+}
+
+type httpReport struct {
+	Tests []testRecord `json:"tests"`
+}
+
+func (s *httpSink) name() string {
+	return sinkHTTP
+}
+
+func (s *httpSink) export(ctx context.Context, payload *core.Payload, tests []testRecord) error {
+	headers := map[string]string{}
+	if s.token != "" {
+		headers["Authorization"] = "Bearer " + s.token
+	}
+	return postJSON(ctx, &s.httpClient, s.url, headers, httpReport{Tests: tests}, nil)
+}
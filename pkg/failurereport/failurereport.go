@@ -0,0 +1,266 @@
+// Package failurereport writes the failed tests as a SARIF report, which CI systems render natively.
+package failurereport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifMimeType = "application/sarif+json"
+	reportName    = "failures.sarif"
+)
+
+// ids of the rules of the results
+const (
+	ruleFailed   = "test-failed"
+	ruleTimedOut = "test-timeout"
+)
+
+// stackFrameRegex matches the frames of the node stack traces, e.g. "at Object.<anonymous> (/repo/a.test.js:10:5)"
+var stackFrameRegex = regexp.MustCompile(`^\s*at (?:(.+?) \()?(.+?):(\d+):(\d+)\)?$`)
+
+type reporter struct {
+	azureClient core.AzureClient
+	localPath   string
+	repoDir     string
+	logger      lumber.Logger
+}
+
+// New returns a new FailureReporter writing the report to the configured path and the blob storage
+func New(cfg *config.NucleusConfig, azureClient core.AzureClient, logger lumber.Logger) core.FailureReporter {
+	return &reporter{azureClient: azureClient, localPath: cfg.FailureReportPath, repoDir: global.RepoDir, logger: logger}
+}
+
+// Report writes the SARIF report of the failed and timed out tests of the result, the report
+// of a task without failures has no results
+func (r *reporter) Report(ctx context.Context, payload *core.Payload, result *core.ExecutionResult) error {
+	report := r.build(payload, result)
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if r.localPath != "" {
+		if err := os.MkdirAll(filepath.Dir(r.localPath), global.DirectoryPermissions); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(r.localPath, body, 0644); err != nil {
+			return err
+		}
+	}
+	blobPath := fmt.Sprintf("reports/%s/%s/%s/%s", payload.OrgID, payload.RepoID, payload.TaskID, reportName)
+	if _, err := r.azureClient.Create(ctx, blobPath, bytes.NewReader(body), sarifMimeType); err != nil {
+		return err
+	}
+	r.logger.Debugf("uploaded failure report with %d results to %s", len(report.Runs[0].Results), blobPath)
+	return nil
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool                     sarifTool                 `json:"tool"`
+	AutomationDetails        sarifAutomationDetails    `json:"automationDetails"`
+	VersionControlProvenance []sarifVersionControlInfo `json:"versionControlProvenance,omitempty"`
+	Results                  []sarifResult             `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifVersionControlInfo struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations,omitempty"`
+	Stacks     []sarifStack    `json:"stacks,omitempty"`
+	Properties resultProperty  `json:"properties"`
+}
+
+// resultProperty are the details of the test which have no SARIF equivalent
+type resultProperty struct {
+	TestID   string `json:"testId"`
+	Test     string `json:"test"`
+	Duration int    `json:"durationMs"`
+	// Artifact is the blob path of the archive with the artifacts of the test
+	Artifact string `json:"artifact,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifStack struct {
+	Frames []sarifFrame `json:"frames"`
+}
+
+type sarifFrame struct {
+	Location sarifFrameLocation `json:"location"`
+}
+
+type sarifFrameLocation struct {
+	Message          *sarifMessage         `json:"message,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+func (r *reporter) build(payload *core.Payload, result *core.ExecutionResult) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "TAS", Rules: []sarifRule{
+			{ID: ruleFailed, ShortDescription: sarifMessage{Text: "Test failed"}},
+			{ID: ruleTimedOut, ShortDescription: sarifMessage{Text: "Test timed out"}},
+		}}},
+		AutomationDetails: sarifAutomationDetails{ID: fmt.Sprintf("tas/%s/%s", payload.BuildID, payload.TaskID)},
+		Results:           make([]sarifResult, 0),
+	}
+	if payload.RepoLink != "" {
+		run.VersionControlProvenance = []sarifVersionControlInfo{{
+			RepositoryURI: payload.RepoLink,
+			RevisionID:    payload.TargetCommit,
+			Branch:        payload.BranchName,
+		}}
+	}
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		ruleID := ruleFailed
+		switch test.Status {
+		case core.TestFailed:
+		case core.TestTimedOut:
+			ruleID = ruleTimedOut
+		default:
+			continue
+		}
+		run.Results = append(run.Results, r.result(ruleID, test))
+	}
+	return &sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// result returns the result of the failed test, located at the innermost stack frame in the test
+// file or at the line of the test if the stack trace has no such frame
+func (r *reporter) result(ruleID string, test *core.TestPayload) sarifResult {
+	message := strings.TrimSpace(test.Detail)
+	if idx := strings.Index(message, "\n"); idx >= 0 {
+		message = strings.TrimSpace(message[:idx])
+	}
+	if message == "" {
+		message = "Test failed"
+		if ruleID == ruleTimedOut {
+			message = "Test timed out"
+		}
+	}
+	res := sarifResult{
+		RuleID:     ruleID,
+		Level:      "error",
+		Message:    sarifMessage{Text: fmt.Sprintf("%s: %s", test.FullTitle, message)},
+		Properties: resultProperty{TestID: test.TestID, Test: test.FullTitle, Duration: test.Duration, Artifact: test.Artifact},
+	}
+	location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: test.FilePath}}
+	if line, err := strconv.Atoi(test.Line); err == nil && line > 0 {
+		location.Region = &sarifRegion{StartLine: line}
+	}
+	frames := r.frames(test.Detail)
+	for _, frame := range frames {
+		if frame.Location.PhysicalLocation.ArtifactLocation.URI == test.FilePath {
+			location = frame.Location.PhysicalLocation
+			break
+		}
+	}
+	if test.FilePath != "" {
+		res.Locations = []sarifLocation{{PhysicalLocation: location}}
+	}
+	if len(frames) > 0 {
+		res.Stacks = []sarifStack{{Frames: frames}}
+	}
+	return res
+}
+
+// frames returns the frames of the stack trace in the detail, the paths in the repository are
+// relative to its root and the frames of the dependencies are skipped
+func (r *reporter) frames(detail string) []sarifFrame {
+	frames := make([]sarifFrame, 0)
+	for _, line := range strings.Split(detail, "\n") {
+		match := stackFrameRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		file := strings.TrimPrefix(match[2], "file://")
+		if filepath.IsAbs(file) {
+			rel, err := filepath.Rel(r.repoDir, file)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			file = rel
+		}
+		if strings.Contains(file, "node_modules/") || strings.HasPrefix(file, "node:") || strings.HasPrefix(file, "internal/") {
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(match[3])
+		column, _ := strconv.Atoi(match[4])
+		frame := sarifFrame{Location: sarifFrameLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(file)},
+			Region:           &sarifRegion{StartLine: lineNumber, StartColumn: column},
+		}}}
+		if match[1] != "" {
+			frame.Location.Message = &sarifMessage{Text: match[1]}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
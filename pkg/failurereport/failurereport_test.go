@@ -0,0 +1,77 @@
+package failurereport
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+const stackTrace = `Error: expect(received).toBe(expected)
+Expected: 200
+Received: 404
+    at Object.<anonymous> (/home/nucleus/repo/src/api.test.js:12:23)
+    at Promise.then.completed (/home/nucleus/repo/node_modules/jest-circus/build/utils.js:333:28)
+    at processTicksAndRejections (node:internal/process/task_queues:96:5)
+    at /home/nucleus/repo/src/helpers.js:4:9`
+
+func TestReport(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	blobDir := t.TempDir()
+	store, err := storage.NewLocalStore(blobDir, "reports", logger)
+	assert.Nil(t, err)
+	localPath := filepath.Join(t.TempDir(), "reports", "failures.sarif")
+
+	r := &reporter{azureClient: store, localPath: localPath, repoDir: "/home/nucleus/repo", logger: logger}
+	payload := &core.Payload{OrgID: "org", RepoID: "repo", BuildID: "build", TaskID: "task", TargetCommit: "abc",
+		RepoLink: "https://github.com/org/repo", BranchName: "main"}
+	result := &core.ExecutionResult{TestPayload: []core.TestPayload{
+		{TestID: "1", FullTitle: "api returns 200", FilePath: "src/api.test.js", Line: "10", Status: core.TestFailed,
+			Detail: stackTrace, Artifact: "artifacts/org/repo/task/1.tzst"},
+		{TestID: "2", FullTitle: "api is fast", FilePath: "src/api.test.js", Line: "20", Status: core.TestTimedOut},
+		{TestID: "3", FullTitle: "api returns 404", FilePath: "src/api.test.js", Status: "passed"},
+	}}
+	assert.Nil(t, r.Report(context.Background(), payload, result))
+
+	body, err := ioutil.ReadFile(localPath)
+	assert.Nil(t, err)
+	report := sarifLog{}
+	assert.Nil(t, json.Unmarshal(body, &report))
+	assert.Equal(t, "2.1.0", report.Version)
+	run := report.Runs[0]
+	assert.Equal(t, "tas/build/task", run.AutomationDetails.ID)
+	assert.Equal(t, "abc", run.VersionControlProvenance[0].RevisionID)
+	assert.Len(t, run.Results, 2)
+
+	failed := run.Results[0]
+	assert.Equal(t, ruleFailed, failed.RuleID)
+	assert.Equal(t, "api returns 200: Error: expect(received).toBe(expected)", failed.Message.Text)
+	assert.Equal(t, sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: "src/api.test.js"},
+		Region:           &sarifRegion{StartLine: 12, StartColumn: 23},
+	}, failed.Locations[0].PhysicalLocation)
+	assert.Len(t, failed.Stacks[0].Frames, 2)
+	assert.Equal(t, "src/helpers.js", failed.Stacks[0].Frames[1].Location.PhysicalLocation.ArtifactLocation.URI)
+	assert.Nil(t, failed.Stacks[0].Frames[1].Location.Message)
+	assert.Equal(t, "artifacts/org/repo/task/1.tzst", failed.Properties.Artifact)
+
+	timedOut := run.Results[1]
+	assert.Equal(t, ruleTimedOut, timedOut.RuleID)
+	assert.Equal(t, "api is fast: Test timed out", timedOut.Message.Text)
+	assert.Equal(t, 20, timedOut.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Empty(t, timedOut.Stacks)
+
+	uploaded, err := store.Find(context.Background(), "reports/org/repo/task/failures.sarif")
+	assert.Nil(t, err)
+	defer uploaded.Close()
+	uploadedBody, err := ioutil.ReadAll(uploaded)
+	assert.Nil(t, err)
+	assert.Equal(t, body, uploadedBody)
+}
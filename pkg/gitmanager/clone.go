@@ -0,0 +1,282 @@
+package gitmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// cloneGit fetches the target commit into global.RepoDir using git with the clone configuration of the payload,
+// the checkout of the repository left by a previous task is reused.
+// With the sparse option only the files in the root directory, the directory of the configuration file and the
+// package directories and the directories of the included fragments of the configuration are checked out, so that
+// the configuration can be loaded.
+// The LFS objects and the submodules are fetched once the commit is checked out.
+func (gm *gitManager) cloneGit(ctx context.Context, payload *core.Payload, cloneToken string) error {
+	cfg := payload.Clone
+	env, err := gm.gitEnv(ctx, payload, cloneToken)
+	if err != nil {
+		return err
+	}
+	warm, err := gm.reuseCheckout(ctx, payload)
+	if err != nil {
+		return err
+	}
+	if !warm {
+		if err := os.MkdirAll(global.RepoDir, global.DirectoryPermissions); err != nil {
+			return err
+		}
+		if err := gm.git(ctx, env, "init", "--quiet"); err != nil {
+			return err
+		}
+		if err := gm.git(ctx, env, "remote", "add", "origin", payload.RepoLink); err != nil {
+			return err
+		}
+	} else if !cfg.Sparse {
+		if err := gm.git(ctx, env, "sparse-checkout", "disable"); err != nil {
+			return err
+		}
+	}
+	if cfg.Sparse {
+		if err := gm.git(ctx, env, "sparse-checkout", "init", "--cone"); err != nil {
+			return err
+		}
+		if dir := path.Dir(payload.TasFileName); dir != "." {
+			if err := gm.git(ctx, env, "sparse-checkout", "add", dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if cfg.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(cfg.Depth))
+	}
+	if cfg.Blobless {
+		args = append(args, "--filter=blob:none")
+	}
+	args = append(args, "origin", payload.TargetCommit)
+	gm.logger.Debugf("fetching commit %s with depth %d, blobless %t and sparse %t", payload.TargetCommit, cfg.Depth, cfg.Blobless, cfg.Sparse)
+	if err := gm.git(ctx, env, args...); err != nil {
+		return err
+	}
+	if err := gm.git(ctx, env, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	if warm {
+		// the ignored files e.g. the installed dependencies are kept warm for the next tasks
+		if err := gm.git(ctx, env, "clean", "--quiet", "-ffd"); err != nil {
+			return err
+		}
+	}
+	if cfg.LFS {
+		// the smudge filter is installed after the checkout, the files added to the sparse checkout are smudged
+		if err := gm.git(ctx, env, "lfs", "install", "--local"); err != nil {
+			return err
+		}
+		if err := gm.git(ctx, env, "lfs", "pull"); err != nil {
+			return err
+		}
+	}
+	if cfg.Submodules != nil {
+		if err := gm.updateSubmodules(ctx, env, cfg); err != nil {
+			return err
+		}
+	}
+	if !cfg.Sparse {
+		return nil
+	}
+	dirs, err := readSparseDirs(global.RepoDir, payload.TasFileName)
+	if err != nil {
+		gm.logger.Errorf("failed to read the sparse directories of %s, error %v", payload.TasFileName, err)
+		return err
+	}
+	return gm.SparseCheckout(ctx, payload, cloneToken, dirs)
+}
+
+// reuseCheckout reports whether global.RepoDir holds a checkout of the repository of the payload left by a
+// previous task of a resident nucleus, in which case the target commit is fetched into it. The checkout of
+// another repository is removed.
+func (gm *gitManager) reuseCheckout(ctx context.Context, payload *core.Payload) (bool, error) {
+	if _, err := os.Stat(filepath.Join(global.RepoDir, ".git")); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = global.RepoDir
+	out, err := cmd.Output()
+	if err == nil && strings.TrimSpace(string(out)) == payload.RepoLink {
+		gm.logger.Infof("reusing the checkout of %s", payload.RepoLink)
+		return true, nil
+	}
+	gm.logger.Infof("removing the checkout of another repository")
+	return false, os.RemoveAll(global.RepoDir)
+}
+
+// SparseCheckout adds the directories to the checkout of a repository cloned with the sparse option
+func (gm *gitManager) SparseCheckout(ctx context.Context, payload *core.Payload, cloneToken string, dirs []string) error {
+	if payload.Clone == nil || !payload.Clone.Sparse {
+		return nil
+	}
+	unique := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if dir = path.Clean(strings.TrimPrefix(filepath.ToSlash(dir), "./")); dir != "." && !strings.HasPrefix(dir, "..") {
+			unique[dir] = true
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	sorted := make([]string, 0, len(unique))
+	for dir := range unique {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	env, err := gm.gitEnv(ctx, payload, cloneToken)
+	if err != nil {
+		return err
+	}
+	gm.logger.Debugf("adding %d directories to the sparse checkout", len(sorted))
+	return gm.git(ctx, env, append([]string{"sparse-checkout", "add"}, sorted...)...)
+}
+
+// updateSubmodules initializes and checks out the submodules, with the same depth as the repository
+func (gm *gitManager) updateSubmodules(ctx context.Context, env []string, cfg *core.CloneConfig) error {
+	for _, args := range submoduleCommands(cfg) {
+		if err := gm.git(ctx, env, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submoduleCommands returns the arguments of the git commands updating the submodules, the LFS objects of
+// the submodules are pulled once they are checked out
+func submoduleCommands(cfg *core.CloneConfig) [][]string {
+	args := []string{"submodule", "update", "--init"}
+	if cfg.Submodules.Recursive {
+		args = append(args, "--recursive")
+	}
+	if cfg.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(cfg.Depth))
+	}
+	commands := [][]string{args}
+	if !cfg.LFS {
+		return commands
+	}
+	args = []string{"submodule", "foreach", "--quiet"}
+	if cfg.Submodules.Recursive {
+		args = append(args, "--recursive")
+	}
+	return append(commands, append(args, "git lfs install --local && git lfs pull"))
+}
+
+// gitEnv returns the environment of the git commands. The credentials are passed in the environment as
+// http headers, so that they are neither stored in the remote urls nor visible in the process arguments.
+// The clone token is only sent to the host of the repository, the credentials of the submodules replace
+// it for their urls.
+func (gm *gitManager) gitEnv(ctx context.Context, payload *core.Payload, cloneToken string) ([]string, error) {
+	u, err := url.Parse(payload.RepoLink)
+	if err != nil {
+		return nil, err
+	}
+	host := fmt.Sprintf("%s://%s/", u.Scheme, u.Host)
+	configs := make([][2]string, 0)
+	if cloneToken != "" {
+		user := "x-access-token"
+		if payload.GitProvider == core.GitLab {
+			user = "oauth2"
+		}
+		configs = append(configs, [2]string{"http." + host + ".extraHeader", authHeader(user, cloneToken)})
+	}
+	// the clone configuration is not set for the repositories fetched from the archive of the commit
+	if payload.Clone != nil && payload.Clone.Submodules != nil {
+		submodules := payload.Clone.Submodules
+		configs = append(configs, [2]string{"url." + host + ".insteadOf", "git@" + u.Host + ":"})
+		var secrets map[string]string
+		if len(submodules.Auth) > 0 {
+			if secrets, err = gm.secretParser.GetRepoSecret(ctx, global.RepoSecretPath); err != nil {
+				gm.logger.Errorf("failed to get the repo secrets for the submodule credentials, error %v", err)
+				return nil, err
+			}
+		}
+		for _, auth := range submodules.Auth {
+			token, err := gm.secretParser.SubstituteSecret(auth.Token, secrets)
+			if err != nil {
+				return nil, err
+			}
+			user := auth.User
+			if user == "" {
+				user = "x-access-token"
+			}
+			// an empty value resets the headers of the less specific urls, i.e. the clone token
+			configs = append(configs,
+				[2]string{"http." + auth.URL + ".extraHeader", ""},
+				[2]string{"http." + auth.URL + ".extraHeader", authHeader(user, token)})
+		}
+	}
+	env := append([]string{"GIT_TERMINAL_PROMPT=0", fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(configs))}, requestutils.GitEnv()...)
+	for i, config := range configs {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, config[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, config[1]))
+	}
+	return env, nil
+}
+
+func authHeader(user, token string) string {
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+token))
+}
+
+// git runs the git command in global.RepoDir with the environment returned by gitEnv
+func (gm *gitManager) git(ctx context.Context, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = global.RepoDir
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		gm.logger.Errorf("git %s failed, error %v, output: %s", args[0], err, out)
+		return fmt.Errorf("git %s failed: %v", args[0], err)
+	}
+	return nil
+}
+
+// readSparseDirs returns the directories of the packages and of the fragments in the repository included by the
+// configuration file, which are checked out along with the configuration
+func readSparseDirs(repoDir, tasFileName string) ([]string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(repoDir, tasFileName))
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Packages []string       `yaml:"packages"`
+		Include  []core.Include `yaml:"include"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(config.Packages)+len(config.Include))
+	for _, pattern := range config.Packages {
+		dirs = append(dirs, utils.GlobBase(path.Join(pattern, "*")))
+	}
+	for _, include := range config.Include {
+		if include.Path != "" {
+			dirs = append(dirs, path.Dir(path.Join(path.Dir(tasFileName), include.Path)))
+		}
+	}
+	return dirs, nil
+}
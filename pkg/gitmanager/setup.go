@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -14,23 +16,34 @@ import (
 	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/tracing"
 	"github.com/LambdaTest/synapse/pkg/urlmanager"
 	"github.com/mholt/archiver/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v2"
 )
 
 type gitManager struct {
-	logger     lumber.Logger
-	httpClient http.Client
+	logger       lumber.Logger
+	secretParser core.SecretParser
+	httpClient   http.Client
 }
 
-// NewGitManager returns a new GitManager
-func NewGitManager(logger lumber.Logger) core.GitManager {
-	return &gitManager{logger: logger, httpClient: http.Client{
-		Timeout: global.DefaultHTTPTimeout,
-	}}
+// NewGitManager returns a new GitManager, the secret parser resolves the secrets of the submodule credentials
+func NewGitManager(secretParser core.SecretParser, logger lumber.Logger) core.GitManager {
+	return &gitManager{logger: logger, secretParser: secretParser, httpClient: requestutils.NewClient(global.DefaultHTTPTimeout)}
 }
 
-func (gm *gitManager) Clone(ctx context.Context, payload *core.Payload, cloneToken string) error {
+func (gm *gitManager) Clone(ctx context.Context, payload *core.Payload, cloneToken string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "gitmanager.Clone",
+		attribute.String("git.provider", payload.GitProvider),
+		attribute.String("git.commit_id", payload.TargetCommit))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if payload.Clone != nil {
+		return gm.cloneGit(ctx, payload, cloneToken)
+	}
 	repoLink := payload.RepoLink
 	repoItems := strings.Split(repoLink, "/")
 	repoName := repoItems[len(repoItems)-1]
@@ -47,6 +60,11 @@ func (gm *gitManager) Clone(ctx context.Context, payload *core.Payload, cloneTok
 		return err
 	}
 
+	// the archives are not fetched incrementally, the checkout left by a previous task is replaced
+	if err = os.RemoveAll(global.RepoDir); err != nil {
+		gm.logger.Errorf("failed to remove dir, error %v", err)
+		return err
+	}
 	if err = os.Rename(repoName+"-"+commitID, global.RepoDir); err != nil {
 		gm.logger.Errorf("failed to rename dir, error %v", err)
 		return err
@@ -55,7 +73,12 @@ func (gm *gitManager) Clone(ctx context.Context, payload *core.Payload, cloneTok
 	return nil
 }
 
-func (gm *gitManager) CloneYML(ctx context.Context, payload *core.Payload, cloneToken string) error {
+func (gm *gitManager) CloneYML(ctx context.Context, payload *core.Payload, cloneToken string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "gitmanager.CloneYML",
+		attribute.String("git.provider", payload.GitProvider),
+		attribute.String("git.commit_id", payload.BuildTargetCommit))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if err := os.Mkdir(global.RepoDir, os.ModePerm); err != nil {
 		gm.logger.Errorf("failed to create dir %s, error: %v", global.RepoDir, err)
 		return err
@@ -77,6 +100,44 @@ func (gm *gitManager) CloneYML(ctx context.Context, payload *core.Payload, clone
 		gm.logger.Errorf("failed to move dir commitID %s, error: %v", err)
 		return err
 	}
+	return gm.cloneFragments(ctx, payload, tasConfigFilePath, cloneToken)
+}
+
+// cloneFragments downloads the fragments in the repository included by the configuration file, they are stored
+// relative to the downloaded configuration as they are in the repository
+func (gm *gitManager) cloneFragments(ctx context.Context, payload *core.Payload, tasConfigFilePath, cloneToken string) error {
+	content, err := ioutil.ReadFile(filepath.Join(global.RepoDir, tasConfigFilePath))
+	if err != nil {
+		return err
+	}
+	config := struct {
+		Include []core.Include `yaml:"include"`
+	}{}
+	// the format errors are reported when parsing the configuration
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil
+	}
+	for _, include := range config.Include {
+		if include.Path == "" {
+			continue
+		}
+		repoPath := path.Join(path.Dir(payload.TasFileName), include.Path)
+		if repoPath == ".." || strings.HasPrefix(repoPath, "../") {
+			continue
+		}
+		fragmentURL, err := urlmanager.GetDownloadURL(payload.GitProvider, payload.RepoSlug, payload.BuildTargetCommit, repoPath)
+		if err != nil {
+			return err
+		}
+		localPath := filepath.Join(global.RepoDir, filepath.FromSlash(path.Join(path.Dir(tasConfigFilePath), include.Path)))
+		if err := os.MkdirAll(filepath.Dir(localPath), global.DirectoryPermissions); err != nil {
+			return err
+		}
+		if err := gm.downloadFile(ctx, fragmentURL, localPath, cloneToken); err != nil {
+			gm.logger.Errorf("error while cloning the fragment %s, error: %v", repoPath, err)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -96,6 +157,9 @@ func (gm *gitManager) downloadFile(ctx context.Context, archiveURL, fileName, cl
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return errs.ErrFileNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
 		gm.logger.Errorf("non 200 status while cloning from endpoint %s, status %d ", archiveURL, resp.StatusCode)
 		return errs.ErrApiStatus
@@ -1,22 +1,25 @@
 package global
 
-import "time"
+import (
+	"os"
+	"sync"
+	"time"
+)
 
 // All constant related to nucleus
 const (
-	CodeCoveragParentDir     = "/coverage"
 	CoverageManifestFileName = "manifest.json"
 	HomeDir                  = "/home/nucleus"
-	RepoDir                  = HomeDir + "/repo"
 	DefaultHTTPTimeout       = 45 * time.Second
 	SamplingTime             = 5 * time.Millisecond
 	RepoSecretPath           = "/vault/secrets/reposecrets"
 	OauthSecretPath          = "/vault/secrets/oauth"
 	NeuronRemoteHost         = "http://neuron-service.phoenix"
-	BlocklistedFileLocation  = "/scripts/blocklist.json"
 	SecretRegex              = `\${{\s*secrets\.(.*?)\s*}}`
 	ExecutionResultChunkSize = 50
 	TestLocatorsDelimiter    = "#TAS#"
+	// RunnersArchive is the archive of the custom runners in the container image
+	RunnersArchive = "/custom-runners/custom-runners.tgz"
 )
 
 // FrameworkRunnerMap is map of framework with there respective runner location
@@ -33,17 +36,57 @@ var RawContentURLMap = map[string]string{
 
 // APIHostURLMap is map of git provider with there api url
 var APIHostURLMap = map[string]string{
-	"github": "https://api.github.com/repos",
-	"gitlab": "https://gitlab.com/api/v4/projects",
+	"github":    "https://api.github.com/repos",
+	"gitlab":    "https://gitlab.com/api/v4/projects",
+	"bitbucket": "https://api.bitbucket.org/2.0/repositories",
 }
 
+// RepoDir is the directory the repository is cloned into, it is the working directory of the repository
+// when the pipeline is run locally and the workspace of the sub-task when run as part of a batch
+var RepoDir = HomeDir + "/repo"
+
+// The directories of the workspace, they are laid out by the workspace manager from the configuration
+var (
+	// CacheDir holds the downloaded cache archives before they are extracted
+	CacheDir = os.TempDir()
+	// ScratchDir holds the temporary files of the task, e.g. the archives of the artifacts and the checkpoints
+	ScratchDir = os.TempDir()
+	// BatchDir holds a workspace per sub-task of a batch
+	BatchDir = HomeDir + "/batch"
+	// DaemonDir holds the logs of each task run by the resident nucleus
+	DaemonDir = HomeDir + "/daemon"
+	// BlocklistedFileLocation is the file of the blocklisted tests read by the runners
+	BlocklistedFileLocation = "/scripts/blocklist.json"
+	// CodeCoveragParentDir holds the coverage files of the commits, it is mounted unless run as part of a batch
+	CodeCoveragParentDir = "/coverage"
+	// CoverageReportDir holds the html coverage reports served by the local runner
+	CoverageReportDir = HomeDir + "/coverage-report"
+)
+
 // InstallRunnerCmd  are list of command used to install custom runner
-var InstallRunnerCmd = []string{"tar", "-xzf", "/custom-runners/custom-runners.tgz"}
+var InstallRunnerCmd = []string{"tar", "-xzf", RunnersArchive}
 
-// NeuronHost is neuron host end point
-var NeuronHost string
+var (
+	neuronHostMu sync.RWMutex
+	neuronHost   string
+)
+
+// NeuronHost returns the neuron host end point, it can change when the configuration is reloaded
+func NeuronHost() string {
+	neuronHostMu.RLock()
+	defer neuronHostMu.RUnlock()
+	return neuronHost
+}
 
 // SetNeuronHost is setter for NeuronHost
 func SetNeuronHost(host string) {
-	NeuronHost = host
+	neuronHostMu.Lock()
+	defer neuronHostMu.Unlock()
+	neuronHost = host
+}
+
+// NeuronURL returns the URL of the path of the neuron API, it is resolved for each request so that a
+// reloaded neuron host is used right away
+func NeuronURL(path string) string {
+	return NeuronHost() + path
 }
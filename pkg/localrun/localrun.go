@@ -0,0 +1,173 @@
+// Package localrun adapts the pipeline to run against the working directory of a local repository: the payload
+// is built from the repository instead of being downloaded, nothing is cloned and the status is printed to the
+// terminal instead of being reported to neuron.
+package localrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// Options of the local run
+type Options struct {
+	// RepoDir is the absolute path of the working directory of the repository
+	RepoDir string
+	// BaseRef is the git ref the changes of the working directory are compared to
+	BaseRef string
+	// TasFileName is the path of the tas configuration relative to RepoDir
+	TasFileName string
+}
+
+// NewPayload returns the payload of the local run, the target commit is the checked out commit
+func NewPayload(ctx context.Context, opts Options) (*core.Payload, error) {
+	target, err := revParse(ctx, opts.RepoDir, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	base, err := revParse(ctx, opts.RepoDir, opts.BaseRef)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := revParse(ctx, opts.RepoDir, "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(opts.RepoDir)
+	return &core.Payload{
+		TaskID:       fmt.Sprintf("local-%d", time.Now().Unix()),
+		BuildID:      "local",
+		OrgID:        "local",
+		RepoID:       name,
+		RepoSlug:     name,
+		RepoLink:     opts.RepoDir,
+		BranchName:   branch,
+		EventType:    core.EventPush,
+		BaseCommit:   base,
+		TargetCommit: target,
+		TasFileName:  opts.TasFileName,
+		// the tests are selected from the changes unless the configuration disables smart run
+		ParentCommitCoverageExists: true,
+	}, nil
+}
+
+func revParse(ctx context.Context, repoDir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"rev-parse"}, args...)...)
+	cmd.Dir = repoDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s in %s: %v: %s", args[len(args)-1], repoDir, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type payloadManager struct {
+	payload *core.Payload
+}
+
+// NewPayloadManager returns a PayloadManager returning the payload of the local run
+func NewPayloadManager(payload *core.Payload) core.PayloadManager {
+	return &payloadManager{payload: payload}
+}
+
+func (pm *payloadManager) FetchPayload(ctx context.Context, payloadAddress string) (*core.Payload, error) {
+	return pm.payload, nil
+}
+
+func (pm *payloadManager) ValidatePayload(ctx context.Context, payload *core.Payload) error {
+	return nil
+}
+
+type gitManager struct{}
+
+// NewGitManager returns a GitManager using the working directory as is
+func NewGitManager() core.GitManager {
+	return gitManager{}
+}
+
+func (gitManager) Clone(ctx context.Context, payload *core.Payload, cloneToken string) error {
+	return nil
+}
+
+func (gitManager) CloneYML(ctx context.Context, payload *core.Payload, cloneToken string) error {
+	return nil
+}
+
+func (gitManager) SparseCheckout(ctx context.Context, payload *core.Payload, cloneToken string, dirs []string) error {
+	return nil
+}
+
+type secretParser struct {
+	core.SecretParser
+	secretsPath string
+}
+
+// NewSecretParser returns a SecretParser reading the repo secrets from secretsPath, there is no oauth secret
+// as nothing is cloned
+func NewSecretParser(parser core.SecretParser, secretsPath string) core.SecretParser {
+	return &secretParser{SecretParser: parser, secretsPath: secretsPath}
+}
+
+func (s *secretParser) GetOauthSecret(filepath string) (*core.Oauth, error) {
+	return &core.Oauth{}, nil
+}
+
+func (s *secretParser) GetRepoSecret(ctx context.Context, path string) (map[string]string, error) {
+	if s.secretsPath == "" {
+		return nil, nil
+	}
+	return s.SecretParser.GetRepoSecret(ctx, s.secretsPath)
+}
+
+type blockListService struct{}
+
+// NewBlockListService returns a TestBlockListService which blocklists no test, as the blocklists are managed by neuron
+func NewBlockListService() core.TestBlockListService {
+	return blockListService{}
+}
+
+func (blockListService) GetBlockListedTests(ctx context.Context, tasConfig *core.TASConfig, repo string) error {
+	return nil
+}
+
+// Task prints the status of the task instead of reporting it to neuron
+type Task struct {
+	out    io.Writer
+	mu     sync.Mutex
+	status core.Status
+}
+
+// NewTask returns a Task printing the final status to out
+func NewTask(out io.Writer) *Task {
+	return &Task{out: out}
+}
+
+// UpdateStatus prints the final status of the task
+func (t *Task) UpdateStatus(payload *core.TaskPayload) error {
+	t.mu.Lock()
+	t.status = payload.Status
+	t.mu.Unlock()
+	if payload.Status == core.Running {
+		return nil
+	}
+	fmt.Fprintf(t.out, "\nTask %s in %s", payload.Status, payload.EndTime.Sub(payload.StartTime).Round(time.Second))
+	if payload.Remark != "" {
+		fmt.Fprintf(t.out, ": %s", payload.Remark)
+	}
+	fmt.Fprintln(t.out)
+	return nil
+}
+
+// Status returns the latest status of the task
+func (t *Task) Status() core.Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
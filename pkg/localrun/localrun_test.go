@@ -0,0 +1,41 @@
+package localrun
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask(t *testing.T) {
+	var out bytes.Buffer
+	task := NewTask(&out)
+	start := time.Now()
+	assert.Nil(t, task.UpdateStatus(&core.TaskPayload{Status: core.Running, StartTime: start}))
+	assert.Equal(t, core.Running, task.Status())
+	assert.Empty(t, out.String())
+
+	assert.Nil(t, task.UpdateStatus(&core.TaskPayload{Status: core.Error, Remark: "Error occurred in pre-run steps",
+		StartTime: start, EndTime: start.Add(3 * time.Second)}))
+	assert.Equal(t, core.Error, task.Status())
+	assert.Equal(t, "\nTask error in 3s: Error occurred in pre-run steps\n", out.String())
+}
+
+func TestReportStage(t *testing.T) {
+	var out bytes.Buffer
+	state := &core.StageState{Result: &core.ExecutionResult{TestPayload: []core.TestPayload{
+		{FilePath: "a.test.js", Title: "adds", Status: "passed", Duration: 120},
+		{FilePath: "a.test.js", FullTitle: "sum subtracts", Status: core.TestFailed, Duration: 30},
+		{FilePath: "b.test.js", Title: "waits", Status: core.TestTimedOut, Duration: 1000},
+		{FilePath: "b.test.js", Title: "skips", Status: core.TestSkipped},
+	}}}
+	assert.Nil(t, NewReportStage(&out).Run(context.Background(), state))
+	assert.Equal(t, `
+  FAILED  a.test.js > sum subtracts
+  TIMEOUT b.test.js > waits
+Tests: 1 passed, 2 failed, 1 skipped, 4 total in 1.15s
+`, out.String())
+}
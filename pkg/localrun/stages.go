@@ -0,0 +1,152 @@
+package localrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// names of the stages of the local run
+const (
+	StageImpact = "impact"
+	StageReport = "report"
+)
+
+type impactStage struct {
+	pl  *core.Pipeline
+	out io.Writer
+	// all runs all the tests instead of the impacted tests
+	all bool
+	// preview stops the pipeline once the impacted tests are printed
+	preview bool
+}
+
+// NewImpactStage returns the stage printing the tests impacted by the changes of the working directory and
+// selecting them for the execution, it is placed before the execution
+func NewImpactStage(pl *core.Pipeline, out io.Writer, all, preview bool) core.Stage {
+	return &impactStage{pl: pl, out: out, all: all, preview: preview}
+}
+
+func (s *impactStage) Name() string {
+	return StageImpact
+}
+
+func (s *impactStage) Run(ctx context.Context, state *core.StageState) error {
+	all := s.all
+	if !all && !state.TASConfig.SmartRun {
+		fmt.Fprintln(s.out, "Smart run is disabled in the configuration")
+		all = true
+	}
+	if !all {
+		report, err := s.pl.ImpactedTests(ctx, "", "")
+		if err != nil {
+			state.ErrRemark = "Unable to identify the impacted tests"
+			return err
+		}
+		printReport(s.out, report)
+		if _, ok := report.ChangedFiles[state.Payload.TasFileName]; ok {
+			fmt.Fprintln(s.out, "The configuration changed")
+			all = true
+		} else {
+			state.Payload.Locators = strings.Join(report.ImpactedTests, global.TestLocatorsDelimiter)
+			if len(report.ImpactedTests) == 0 {
+				fmt.Fprintln(s.out, "No test is impacted by the changes")
+				state.Task.Status = core.Passed
+				state.Done = true
+				return nil
+			}
+		}
+	}
+	if all {
+		fmt.Fprintln(s.out, "All the tests are run")
+		state.Payload.Locators = ""
+	}
+	if s.preview {
+		state.Task.Status = core.Passed
+		state.Done = true
+	}
+	return nil
+}
+
+func printReport(out io.Writer, report *core.ImpactReport) {
+	changed := make([]string, 0, len(report.ChangedFiles))
+	for file := range report.ChangedFiles {
+		changed = append(changed, file)
+	}
+	sort.Strings(changed)
+	fmt.Fprintf(out, "Changed files since %s (%d):\n", shortSHA(report.BaseCommit), len(changed))
+	for _, file := range changed {
+		fmt.Fprintf(out, "  %-8s %s\n", report.ChangedFiles[file], file)
+	}
+	if len(report.ImpactedFiles) > 0 {
+		fmt.Fprintf(out, "Files importing the changed files (%d):\n", len(report.ImpactedFiles))
+		for _, file := range report.ImpactedFiles {
+			fmt.Fprintf(out, "  %s\n", file)
+		}
+	}
+	fmt.Fprintf(out, "Impacted tests (%d):\n", len(report.ImpactedTests))
+	for _, file := range report.ImpactedTests {
+		fmt.Fprintf(out, "  %s\n", file)
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+type reportStage struct {
+	out io.Writer
+}
+
+// NewReportStage returns the stage printing the results of the tests, it is placed after the execution
+func NewReportStage(out io.Writer) core.Stage {
+	return &reportStage{out: out}
+}
+
+func (s *reportStage) Name() string {
+	return StageReport
+}
+
+func (s *reportStage) Run(ctx context.Context, state *core.StageState) error {
+	if state.Result == nil {
+		return nil
+	}
+	passed, failed, skipped := 0, 0, 0
+	var duration time.Duration
+	fmt.Fprintln(s.out)
+	for i := range state.Result.TestPayload {
+		t := &state.Result.TestPayload[i]
+		duration += time.Duration(t.Duration) * time.Millisecond
+		switch t.Status {
+		case core.TestFailed, core.TestTimedOut:
+			failed++
+			fmt.Fprintf(s.out, "  %-7s %s > %s\n", strings.ToUpper(t.Status), t.FilePath, title(t))
+		case core.TestSkipped, core.TestLimitExceeded:
+			skipped++
+		default:
+			passed++
+		}
+	}
+	fmt.Fprintf(s.out, "Tests: %d passed, %d failed, %d skipped, %d total in %s\n",
+		passed, failed, skipped, len(state.Result.TestPayload), duration.Round(time.Millisecond))
+	return nil
+}
+
+func title(t *core.TestPayload) string {
+	if t.FullTitle != "" {
+		return t.FullTitle
+	}
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Name
+}
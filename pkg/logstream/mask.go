@@ -17,27 +17,35 @@ type masker struct {
 
 // NewMasker returns a masker that wraps io.Writer w.
 func NewMasker(w io.Writer, secretData map[string]string) io.Writer {
+	r := newMaskReplacer(secretData)
+	if r == nil {
+		return w
+	}
+	return &masker{
+		w: w,
+		r: r,
+	}
+}
+
+// Mask returns s with the values of secretData masked.
+func Mask(s string, secretData map[string]string) string {
+	if r := newMaskReplacer(secretData); r != nil {
+		return r.Replace(s)
+	}
+	return s
+}
+
+func newMaskReplacer(secretData map[string]string) *strings.Replacer {
 	var oldnew []string
 	for _, secret := range secretData {
-		if secret == "" {
-			continue
-		}
-		for _, part := range strings.Split(secret, "\n") {
-			part = strings.TrimSpace(part)
-			// avoid masking empty or single character strings.
-			if len(part) < 2 {
-				continue
-			}
+		for _, part := range secretParts(secret) {
 			oldnew = append(oldnew, part, maskedStr)
 		}
 	}
 	if len(oldnew) == 0 {
-		return w
-	}
-	return &masker{
-		w: w,
-		r: strings.NewReplacer(oldnew...),
+		return nil
 	}
+	return strings.NewReplacer(oldnew...)
 }
 
 // Write writes p to the base writer. The method scans for any
@@ -0,0 +1,117 @@
+package logstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redactor masks all the registered secret values, it is safe for concurrent use.
+// Unlike the masker, secrets are registered as they are resolved, so a single
+// redactor can be shared by the loggers and the log uploads of the process.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// NewRedactor returns a new Redactor without any secrets.
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: make(map[string]struct{})}
+}
+
+// Add registers the secret values to be masked.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := false
+	for _, secret := range secrets {
+		for _, part := range secretParts(secret) {
+			// the secrets in the json encoded logs and payloads are escaped
+			for _, form := range []string{part, jsonEscaped(part)} {
+				if _, ok := r.secrets[form]; !ok {
+					r.secrets[form] = struct{}{}
+					added = true
+				}
+			}
+		}
+	}
+	if !added {
+		return
+	}
+	sorted := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		sorted = append(sorted, secret)
+	}
+	// replacer prefers earlier pairs, longer secrets are masked first if they overlap
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	oldnew := make([]string, 0, 2*len(sorted))
+	for _, secret := range sorted {
+		oldnew = append(oldnew, secret, maskedStr)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+}
+
+// Redact returns s with all the registered secrets masked.
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// NewReader returns a reader which masks the secrets in rd. The input is redacted
+// line by line so that secrets split across reads are also masked.
+func (r *Redactor) NewReader(rd io.Reader) io.Reader {
+	return &redactReader{src: bufio.NewReader(rd), redactor: r}
+}
+
+type redactReader struct {
+	src      *bufio.Reader
+	redactor *Redactor
+	buf      []byte
+	err      error
+}
+
+func (rr *redactReader) Read(p []byte) (int, error) {
+	for len(rr.buf) == 0 {
+		if rr.err != nil {
+			return 0, rr.err
+		}
+		var line string
+		line, rr.err = rr.src.ReadString('\n')
+		rr.buf = []byte(rr.redactor.Redact(line))
+	}
+	n := copy(p, rr.buf)
+	rr.buf = rr.buf[n:]
+	return n, nil
+}
+
+// secretParts splits a multiline secret into lines, skipping empty or
+// single character strings to avoid masking everything.
+func secretParts(secret string) []string {
+	var parts []string
+	for _, part := range strings.Split(secret, "\n") {
+		part = strings.TrimSpace(part)
+		if len(part) < 2 {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// jsonEscaped returns the secret as it is escaped in a json string by json.Marshal
+func jsonEscaped(secret string) string {
+	encoded, err := json.Marshal(secret)
+	if err != nil {
+		return secret
+	}
+	return string(encoded[1 : len(encoded)-1])
+}
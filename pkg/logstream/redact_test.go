@@ -0,0 +1,50 @@
+package logstream
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRedact(t *testing.T) {
+	r := NewRedactor()
+	if got, want := r.Redact("token abc"), "token abc"; got != want {
+		t.Errorf("Want string %s without secrets, got %s", want, got)
+	}
+	r.Add("abc", "abcdef", "x", "")
+	if got, want := r.Redact("token abcdef abc x"), "token **************** **************** x"; got != want {
+		t.Errorf("Want masked string %s, got %s", want, got)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	r := NewRedactor()
+	secret := `p"a\ss<&>`
+	r.Add(secret)
+	encoded, err := json.Marshal(map[string]string{"remark": "failed with " + secret})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := r.Redact(string(encoded)), `{"remark":"failed with ****************"}`; got != want {
+		t.Errorf("Want masked json %s, got %s", want, got)
+	}
+	if got, want := r.Redact(secret), "****************"; got != want {
+		t.Errorf("Want masked string %s, got %s", want, got)
+	}
+}
+
+func TestRedactReader(t *testing.T) {
+	r := NewRedactor()
+	r.Add("lazy dog")
+	// one byte reads split the secret across reads
+	reader := r.NewReader(iotest.OneByteReader(strings.NewReader("The quick brown fox\njumps over the lazy dog")))
+	out, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := string(out), "The quick brown fox\njumps over the ****************"; got != want {
+		t.Errorf("Want masked string %s, got %s", want, got)
+	}
+}
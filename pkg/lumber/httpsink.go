@@ -0,0 +1,157 @@
+package lumber
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPBatchSize     = 100
+	defaultHTTPFlushInterval = 5 * time.Second
+	httpSinkTimeout          = 10 * time.Second
+)
+
+var (
+	transportMu   sync.RWMutex
+	httpTransport http.RoundTripper
+)
+
+// SetHTTPTransport sets the transport of the http sinks, so that the proxy, the CA bundle and the client
+// certificate of the outbound requests also apply to the shipped logs. It is set by requestutils, which can
+// not be imported here as it depends on config which depends on lumber. The default transport is used if nil.
+func SetHTTPTransport(transport http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	httpTransport = transport
+}
+
+func sinkTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return httpTransport
+}
+
+// httpSink buffers JSON encoded log entries and ships them in batches
+// to a HTTP endpoint (eg. fluentd in_http, logstash http input, datadog intake).
+// Each batch is POSTed as a JSON array.
+type httpSink struct {
+	endpoint  string
+	batchSize int
+	mu        sync.Mutex
+	entries   [][]byte
+	// syncMu allows a single batch in flight, so that a slow endpoint does not pile up the batches
+	syncMu sync.Mutex
+
+	// flush wakes up the flusher once the batch size is reached
+	flush chan struct{}
+	// stop stops the flusher, done is closed once it stopped
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newHTTPSink(endpoint string, batchSize int, flushInterval time.Duration) *httpSink {
+	if batchSize <= 0 {
+		batchSize = defaultHTTPBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultHTTPFlushInterval
+	}
+	s := &httpSink{
+		endpoint:  endpoint,
+		batchSize: batchSize,
+		flush:     make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	// the entries are only shipped by this goroutine, on a timer or once a batch is full
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// errors are already reported on stderr
+				_ = s.Sync()
+			case <-s.flush:
+				_ = s.Sync()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Write buffers a single encoded log entry, flushing when batch size is reached.
+func (s *httpSink) Write(p []byte) (int, error) {
+	entry := bytes.TrimSpace(p)
+	if len(entry) == 0 {
+		return len(p), nil
+	}
+	// the encoder reuses its buffer, so the entry needs to be copied
+	entryCopy := make([]byte, len(entry))
+	copy(entryCopy, entry)
+
+	s.mu.Lock()
+	s.entries = append(s.entries, entryCopy)
+	full := len(s.entries) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		// the flusher is already woken up if the signal is pending
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Close stops the periodic flush and ships the buffered entries, so that the last logs of the process
+// are not lost. It is safe to call more than once.
+func (s *httpSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	return s.Sync()
+}
+
+// Sync ships all the buffered entries to the endpoint.
+func (s *httpSink) Sync() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	body := make([]byte, 0, len(entries)*128)
+	body = append(body, '[')
+	body = append(body, bytes.Join(entries, []byte(","))...)
+	body = append(body, ']')
+
+	client := http.Client{Timeout: httpSinkTimeout, Transport: sinkTransport()}
+	resp, err := client.Post(s.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// logging through the logger itself would recurse into the sink
+		fmt.Fprintf(os.Stderr, "lumber: failed to ship %d log entries to %s, error: %v\n", len(entries), s.endpoint, err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		err = fmt.Errorf("non 2xx status %d", resp.StatusCode)
+		fmt.Fprintf(os.Stderr, "lumber: failed to ship %d log entries to %s, error: %v\n", len(entries), s.endpoint, err)
+		return err
+	}
+	return nil
+}
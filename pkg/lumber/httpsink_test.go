@@ -0,0 +1,153 @@
+package lumber
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSinkSync(t *testing.T) {
+	received := make(chan []map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading request body: %v", err)
+		}
+		var entries []map[string]interface{}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			t.Errorf("error in unmarshaling shipped logs: %v", err)
+		}
+		received <- entries
+	}))
+	defer server.Close()
+
+	sink := newHTTPSink(server.URL, 10, time.Hour)
+	sink.Write([]byte(`{"msg":"first"}` + "\n"))  // nolint:errcheck
+	sink.Write([]byte(`{"msg":"second"}` + "\n")) // nolint:errcheck
+	assert.Nil(t, sink.Sync())
+
+	entries := <-received
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "first", entries[0]["msg"])
+	assert.Equal(t, "second", entries[1]["msg"])
+	// nothing is shipped when buffer is empty
+	assert.Nil(t, sink.Sync())
+}
+
+func TestHTTPSinkClose(t *testing.T) {
+	received := make(chan []map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entries []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			t.Errorf("error in unmarshaling shipped logs: %v", err)
+		}
+		received <- entries
+	}))
+	defer server.Close()
+
+	sink := newHTTPSink(server.URL, 10, time.Hour)
+	sink.Write([]byte(`{"msg":"last"}` + "\n")) // nolint:errcheck
+	assert.Nil(t, sink.Close())
+
+	// the entries buffered before closing are shipped without waiting for the ticker
+	entries := <-received
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "last", entries[0]["msg"])
+	// the ticker is stopped
+	select {
+	case <-sink.done:
+	default:
+		t.Errorf("flush ticker not stopped")
+	}
+	assert.Nil(t, sink.Close())
+}
+
+func TestClose(t *testing.T) {
+	received := make(chan []map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entries []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			t.Errorf("error in unmarshaling shipped logs: %v", err)
+		}
+		received <- entries
+	}))
+	defer server.Close()
+
+	config := LoggingConfig{ConsoleLevel: "info", EnableHTTP: true, HTTPEndpoint: server.URL, HTTPLevel: "info", HTTPBatchSize: 10, HTTPFlushInterval: 3600}
+	for _, instance := range []int{InstanceZapLogger, InstanceLogrusLogger} {
+		logger, err := NewLogger(config, false, instance)
+		assert.Nil(t, err)
+		logger.WithFields(Fields{"task": "1"}).Infof("task failed")
+		assert.Nil(t, Close(logger))
+
+		entries := <-received
+		assert.Len(t, entries, 1)
+		assert.Contains(t, entries[0], "task")
+	}
+}
+
+func TestHTTPSinkSingleFlush(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, batches := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		batches++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		// a slow endpoint
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink := newHTTPSink(server.URL, 2, time.Hour)
+	for i := 0; i < 50; i++ {
+		sink.Write([]byte(`{"msg":"entry"}` + "\n")) // nolint:errcheck
+	}
+	assert.Nil(t, sink.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, maxInFlight)
+	// the entries buffered while a batch is in flight are shipped together
+	assert.Less(t, batches, 25)
+}
+
+// countingTransport counts the requests sent through the default transport
+type countingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPSinkTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the transport set after the sink is created is used by the next batch
+	sink := newHTTPSink(server.URL, 10, time.Hour)
+	defer sink.Close()
+	transport := &countingTransport{}
+	SetHTTPTransport(transport)
+	defer SetHTTPTransport(nil)
+	sink.Write([]byte(`{"msg":"first"}` + "\n")) // nolint:errcheck
+	assert.Nil(t, sink.Sync())
+	assert.Equal(t, 1, transport.requests)
+}
@@ -3,17 +3,42 @@ package lumber
 import (
 	"io"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 type logrusLogEntry struct {
-	entry *logrus.Entry
+	entry    *logrus.Entry
+	httpSink *httpSink
 }
 
 type logrusLogger struct {
 	logger *logrus.Logger
+	// httpSink is nil if the logs are not shipped
+	httpSink *httpSink
+}
+
+// httpSinkHook ships JSON formatted entries to the http sink,
+// independently of the formatter used for console and file output
+type httpSinkHook struct {
+	sink      *httpSink
+	formatter logrus.Formatter
+	levels    []logrus.Level
+}
+
+func (h *httpSinkHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *httpSinkHook) Fire(entry *logrus.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.sink.Write(redact(b))
+	return err
 }
 
 func getFormatter(isJSON bool) logrus.Formatter {
@@ -64,10 +89,64 @@ func newLogrusLogger(config LoggingConfig, verbose bool) (Logger, error) {
 		lLogger.SetFormatter(getFormatter(config.FileJSONFormat))
 	}
 
-	lLogger.SetOutput(io.MultiWriter(multiWriter...))
-	return &logrusLogger{
-		logger: lLogger,
-	}, nil
+	lLogger.SetOutput(&redactWriter{w: io.MultiWriter(multiWriter...)})
+
+	var sink *httpSink
+	if config.EnableHTTP && config.HTTPEndpoint != "" {
+		httpLevel, err := logrus.ParseLevel(config.HTTPLevel)
+		if err != nil {
+			return nil, err
+		}
+		sink = newHTTPSink(config.HTTPEndpoint, config.HTTPBatchSize, time.Duration(config.HTTPFlushInterval)*time.Second)
+		lLogger.AddHook(&httpSinkHook{
+			sink:      sink,
+			formatter: &logrus.JSONFormatter{},
+			levels:    logrus.AllLevels[:httpLevel+1],
+		})
+		// the entries buffered before a fatal log are shipped before exiting
+		lLogger.ExitFunc = func(code int) {
+			_ = sink.Close()
+			os.Exit(code)
+		}
+	}
+
+	var logger Logger = &logrusLogger{logger: lLogger, httpSink: sink}
+	if config.ServiceName != "" {
+		logger = logger.WithFields(Fields{"service": config.ServiceName})
+	}
+	return logger, nil
+}
+
+func (l *logrusLogger) close() error {
+	if l.httpSink == nil {
+		return nil
+	}
+	return l.httpSink.Close()
+}
+
+func (l *logrusLogEntry) close() error {
+	if l.httpSink == nil {
+		return nil
+	}
+	return l.httpSink.Close()
+}
+
+func (l *logrusLogger) setLevel(level string) error {
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.logger.SetLevel(logLevel)
+	return nil
+}
+
+func (l *logrusLogEntry) setLevel(level string) error {
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.entry.Logger.SetLevel(logLevel)
+	return nil
 }
 
 func (l *logrusLogger) Debugf(format string, args ...interface{}) {
@@ -96,7 +175,8 @@ func (l *logrusLogger) Panicf(format string, args ...interface{}) {
 
 func (l *logrusLogger) WithFields(fields Fields) Logger {
 	return &logrusLogEntry{
-		entry: l.logger.WithFields(convertToLogrusFields(fields)),
+		entry:    l.logger.WithFields(convertToLogrusFields(fields)),
+		httpSink: l.httpSink,
 	}
 }
 
@@ -126,7 +206,8 @@ func (l *logrusLogEntry) Panicf(format string, args ...interface{}) {
 
 func (l *logrusLogEntry) WithFields(fields Fields) Logger {
 	return &logrusLogEntry{
-		entry: l.entry.WithFields(convertToLogrusFields(fields)),
+		entry:    l.entry.WithFields(convertToLogrusFields(fields)),
+		httpSink: l.httpSink,
 	}
 }
 
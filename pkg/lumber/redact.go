@@ -0,0 +1,60 @@
+package lumber
+
+import (
+	"io"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Redactor masks sensitive values in log entries
+type Redactor interface {
+	Redact(s string) string
+}
+
+var (
+	redactorMu sync.RWMutex
+	redactor   Redactor
+)
+
+// SetRedactor sets the redactor applied to the output of all the loggers
+// before it is written to console, file or shipped over http.
+func SetRedactor(r Redactor) {
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	redactor = r
+}
+
+func redact(p []byte) []byte {
+	redactorMu.RLock()
+	r := redactor
+	redactorMu.RUnlock()
+	if r == nil {
+		return p
+	}
+	return []byte(r.Redact(string(p)))
+}
+
+// redactWriter redacts the entries written to the underlying writer
+type redactWriter struct {
+	w io.Writer
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write(redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactWriteSyncer redacts the entries written to the underlying zap WriteSyncer
+type redactWriteSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (rw redactWriteSyncer) Write(p []byte) (int, error) {
+	if _, err := rw.WriteSyncer.Write(redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
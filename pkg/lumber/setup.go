@@ -14,6 +14,15 @@ type LoggingConfig struct {
 	FileJSONFormat    bool
 	FileLevel         string
 	FileLocation      string
+	// EnableHTTP ships JSON encoded logs in batches to HTTPEndpoint (eg. fluentd, logstash, datadog)
+	EnableHTTP    bool
+	HTTPLevel     string
+	HTTPEndpoint  string
+	HTTPBatchSize int
+	// HTTPFlushInterval is the max interval in seconds after which buffered logs are shipped
+	HTTPFlushInterval int
+	// ServiceName is added as `service` field on all JSON encoded logs
+	ServiceName string
 }
 
 // Fields Type to pass when we want to call WithFields for structured logging
@@ -59,6 +68,36 @@ type Logger interface {
 	WithFields(keyValues Fields) Logger
 }
 
+// levelSetter is implemented by the loggers whose level can be changed at runtime
+type levelSetter interface {
+	setLevel(level string) error
+}
+
+// SetLevel changes the console level of the logger at runtime, for the loggers with a single level
+// across writers the level of all writers is changed
+func SetLevel(logger Logger, level string) error {
+	setter, ok := logger.(levelSetter)
+	if !ok {
+		return errs.ErrInvalidLoggerInstance
+	}
+	return setter.setLevel(level)
+}
+
+// closer is implemented by the loggers buffering the entries of a writer
+type closer interface {
+	close() error
+}
+
+// Close flushes the entries buffered by the logger, e.g. the logs not yet shipped over http. It must be
+// called before the process exits, the logger can still be used but its entries may not be shipped.
+func Close(logger Logger) error {
+	c, ok := logger.(closer)
+	if !ok {
+		return errs.ErrInvalidLoggerInstance
+	}
+	return c.close()
+}
+
 // NewLogger returns an instance of logger
 func NewLogger(config LoggingConfig, verbose bool, loggerInstance int) (Logger, error) {
 	switch loggerInstance {
@@ -2,6 +2,7 @@ package lumber
 
 import (
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,16 +11,24 @@ import (
 
 type zapLogger struct {
 	sugaredLogger *zap.SugaredLogger
+	// consoleLevel is shared by the loggers created using WithFields
+	consoleLevel zap.AtomicLevel
+	// httpSink is shared by the loggers created using WithFields, nil if the logs are not shipped
+	httpSink *httpSink
 }
 
 const callDepth = 2
 
-func getEncoder(isJSON bool) zapcore.Encoder {
+func getEncoder(isJSON bool, serviceName string) zapcore.Encoder {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	encoderConfig.TimeKey = "time" // This will change the key from ts to time
 	if isJSON {
-		return zapcore.NewJSONEncoder(encoderConfig)
+		encoder := zapcore.NewJSONEncoder(encoderConfig)
+		if serviceName != "" {
+			encoder.AddString("service", serviceName)
+		}
+		return encoder
 	}
 	// customize console logger
 	encoderConfig.LevelKey = ""
@@ -47,26 +56,37 @@ func getZapLevel(level string) zapcore.Level {
 
 func newZapLogger(config LoggingConfig, verbose bool) Logger {
 	cores := []zapcore.Core{}
+	level := getZapLevel(config.ConsoleLevel)
+	// command line args take highest precedence
+	if verbose {
+		level = getZapLevel("debug")
+	}
+	consoleLevel := zap.NewAtomicLevelAt(level)
 	if config.EnableConsole {
-		level := getZapLevel(config.ConsoleLevel)
-		// command line args take highest precedence
-		if verbose {
-			level = getZapLevel("debug")
-		}
-		writer := zapcore.Lock(os.Stdout)
-		core := zapcore.NewCore(getEncoder(config.ConsoleJSONFormat), writer, level)
+		writer := redactWriteSyncer{zapcore.Lock(os.Stdout)}
+		core := zapcore.NewCore(getEncoder(config.ConsoleJSONFormat, config.ServiceName), writer, consoleLevel)
 		cores = append(cores, core)
 	}
 
 	if config.EnableFile {
 		level := getZapLevel(config.FileLevel)
-		writer := zapcore.AddSync(&lumberjack.Logger{
+		writer := redactWriteSyncer{zapcore.AddSync(&lumberjack.Logger{
 			Filename: config.FileLocation,
 			MaxSize:  100,
 			Compress: true,
 			MaxAge:   28,
-		})
-		core := zapcore.NewCore(getEncoder(config.FileJSONFormat), writer, level)
+		})}
+		core := zapcore.NewCore(getEncoder(config.FileJSONFormat, config.ServiceName), writer, level)
+		cores = append(cores, core)
+	}
+
+	var sink *httpSink
+	if config.EnableHTTP && config.HTTPEndpoint != "" {
+		level := getZapLevel(config.HTTPLevel)
+		sink = newHTTPSink(config.HTTPEndpoint, config.HTTPBatchSize, time.Duration(config.HTTPFlushInterval)*time.Second)
+		writer := redactWriteSyncer{sink}
+		// logs shipped over http are always JSON encoded
+		core := zapcore.NewCore(getEncoder(true, config.ServiceName), writer, level)
 		cores = append(cores, core)
 	}
 
@@ -81,7 +101,25 @@ func newZapLogger(config LoggingConfig, verbose bool) Logger {
 
 	return &zapLogger{
 		sugaredLogger: logger,
+		consoleLevel:  consoleLevel,
+		httpSink:      sink,
+	}
+}
+
+func (l *zapLogger) close() error {
+	if l.httpSink == nil {
+		return nil
+	}
+	return l.httpSink.Close()
+}
+
+func (l *zapLogger) setLevel(level string) error {
+	var zapLevel zapcore.Level
+	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+		return err
 	}
+	l.consoleLevel.SetLevel(zapLevel)
+	return nil
 }
 
 func (l *zapLogger) Debugf(format string, args ...interface{}) {
@@ -114,5 +152,5 @@ func (l *zapLogger) WithFields(fields Fields) Logger {
 		f = append(f, k, v)
 	}
 	newLogger := l.sugaredLogger.With(f...)
-	return &zapLogger{newLogger}
+	return &zapLogger{sugaredLogger: newLogger, consoleLevel: l.consoleLevel, httpSink: l.httpSink}
 }
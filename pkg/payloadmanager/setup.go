@@ -6,10 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"io/ioutil"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
@@ -19,22 +18,21 @@ import (
 
 // PayloadManager represents the payload for nucleus
 type payloadManager struct {
-	logger      lumber.Logger
-	httpClient  http.Client
-	azureClient core.AzureClient
-	cfg         *config.NucleusConfig
+	logger       lumber.Logger
+	azureClient  core.AzureClient
+	secretParser core.SecretParser
+	cfg          *config.NucleusConfig
 }
 
-// NewPayloadManger creates and returns a new PayloadManager instance
-func NewPayloadManger(azureClient core.AzureClient,
+// NewPayloadManger creates and returns a new PayloadManager instance, the secret parser resolves the payload
+// signing key
+func NewPayloadManger(azureClient core.AzureClient, secretParser core.SecretParser,
 	logger lumber.Logger, cfg *config.NucleusConfig) core.PayloadManager {
 	pm := payloadManager{
-		azureClient: azureClient,
-		logger:      logger,
-		httpClient: http.Client{
-			Timeout: 30 * time.Second,
-		},
-		cfg: cfg,
+		azureClient:  azureClient,
+		secretParser: secretParser,
+		logger:       logger,
+		cfg:          cfg,
 	}
 
 	return &pm
@@ -52,23 +50,29 @@ func (pm *payloadManager) FetchPayload(ctx context.Context, payloadAddress strin
 	// string the container name to get blob path
 	blobPath := strings.Replace(u.Path, fmt.Sprintf("/%s/", core.PayloadContainer), "", -1)
 
-	sasURL, err := pm.azureClient.GetSASURL(ctx, blobPath, core.PayloadContainer)
+	v, err := newVerifier(ctx, pm.cfg.PayloadSigning, pm.secretParser)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sasURL, nil)
+	raw, err := pm.readBlob(ctx, blobPath)
 	if err != nil {
 		return nil, err
 	}
-
-	r, err := pm.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if v != nil {
+		signature, err := pm.readBlob(ctx, blobPath+signatureSuffix)
+		if err != nil {
+			if errors.Is(err, errs.ErrNotFound) {
+				return nil, fmt.Errorf("%w: the payload is not signed", errs.ErrPayloadSignature)
+			}
+			return nil, err
+		}
+		if err := verifySignature(v, raw, signature); err != nil {
+			return nil, err
+		}
+		pm.logger.Debugf("verified the signature of the payload")
 	}
-	defer r.Body.Close()
 	var p core.Payload
-	err = json.NewDecoder(r.Body).Decode(&p)
+	err = json.Unmarshal(raw, &p)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +80,21 @@ func (pm *payloadManager) FetchPayload(ctx context.Context, payloadAddress strin
 
 }
 
+// readBlob reads the blob of the payload container
+func (pm *payloadManager) readBlob(ctx context.Context, blobPath string) ([]byte, error) {
+	sasURL, err := pm.azureClient.GetSASURL(ctx, blobPath, core.PayloadContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := pm.azureClient.FindUsingSASUrl(ctx, sasURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
 func (pm *payloadManager) ValidatePayload(ctx context.Context, payload *core.Payload) error {
 	if payload.RepoLink == "" {
 		return errs.ErrInvalidPayload("Missing repo link")
@@ -115,30 +134,52 @@ func (pm *payloadManager) ValidatePayload(ctx context.Context, payload *core.Pay
 	if pm.cfg.LocatorAddress != "" {
 		payload.LocatorAddress = pm.cfg.LocatorAddress
 	}
+	if pm.cfg.RerunOf != "" {
+		payload.RerunOf = pm.cfg.RerunOf
+	}
 	if payload.BuildTargetCommit == "" {
 		return errs.ErrInvalidPayload("Missing build target commit")
 	}
+	if payload.Selection != nil {
+		if err := payload.Selection.Validate(); err != nil {
+			return errs.ErrInvalidPayload(err.Error())
+		}
+	}
+	// the tests of a re-run are selected from the failures of the task
+	if payload.RerunOf != "" && payload.Selection != nil {
+		return errs.ErrInvalidPayload("The selection of a re-run is resolved from the failed tests")
+	}
 	// some checks are removed in case of coverage mode or parsing mode
 	if !(pm.cfg.CoverageMode || pm.cfg.ParseMode) {
-		if pm.cfg.TargetCommit == "" {
-			return errs.ErrInvalidPayload("Missing targetCommit in config")
-		}
 		payload.TargetCommit = pm.cfg.TargetCommit
-
 		payload.BaseCommit = pm.cfg.BaseCommit
+		// the commits of a commit range are supplied by the payload unless overridden by the config
+		if payload.EventType == core.EventCommitRange && payload.TargetCommit == "" {
+			payload.TargetCommit = payload.BuildTargetCommit
+			payload.BaseCommit = payload.BuildBaseCommit
+		}
+		if payload.TargetCommit == "" {
+			return errs.ErrInvalidPayload("Missing targetCommit in config")
+		}
 		if pm.cfg.TaskID == "" {
 			return errs.ErrInvalidPayload("Missing taskID in config")
 		}
 		payload.TaskID = pm.cfg.TaskID
 	}
 
-	if payload.EventType != core.EventPush && payload.EventType != core.EventPullRequest {
+	switch payload.EventType {
+	case core.EventPush:
+		if len(payload.Commits) == 0 {
+			return errs.ErrInvalidPayload("Missing commits error")
+		}
+	case core.EventCommitRange:
+		if payload.BuildBaseCommit == "" {
+			return errs.ErrInvalidPayload("Missing build base commit")
+		}
+	case core.EventPullRequest, core.EventCron:
+	default:
 		return errs.ErrInvalidPayload("Invalid event type")
 	}
 
-	if payload.EventType == core.EventPush && len(payload.Commits) == 0 {
-		return errs.ErrInvalidPayload("Missing commits error")
-	}
-
 	return nil
 }
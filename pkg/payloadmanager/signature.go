@@ -0,0 +1,105 @@
+package payloadmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// algorithms of the payload signatures
+const (
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	AlgorithmEd25519    = "ed25519"
+)
+
+// signatureSuffix is appended to the blob path of the payload to get the blob of its detached signature
+const signatureSuffix = ".sig"
+
+// verifier verifies the detached signatures of the payloads
+type verifier interface {
+	verify(payload, signature []byte) bool
+}
+
+type hmacVerifier struct {
+	key []byte
+}
+
+func (v hmacVerifier) verify(payload, signature []byte) bool {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), signature)
+}
+
+type ed25519Verifier struct {
+	key ed25519.PublicKey
+}
+
+func (v ed25519Verifier) verify(payload, signature []byte) bool {
+	return ed25519.Verify(v.key, payload, signature)
+}
+
+// newVerifier returns the verifier of the signing configuration, or nil if the payloads are not signed.
+// A ${{ secrets.NAME }} key is resolved from the repo secrets, which include the secrets of Vault.
+func newVerifier(ctx context.Context, cfg config.PayloadSigning, secretParser core.SecretParser) (verifier, error) {
+	key := cfg.Key
+	if key == "" {
+		return nil, nil
+	}
+	if strings.Contains(key, "${{") {
+		secrets, err := secretParser.GetRepoSecret(ctx, global.RepoSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the secrets of the payload signing key: %w", err)
+		}
+		if key, err = secretParser.SubstituteSecret(key, secrets); err != nil {
+			return nil, err
+		}
+		if strings.Contains(key, "${{") {
+			return nil, errors.New("the secret of the payload signing key is not found")
+		}
+	}
+	switch cfg.Algorithm {
+	case AlgorithmHMACSHA256, "":
+		return hmacVerifier{key: []byte(key)}, nil
+	case AlgorithmEd25519:
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return nil, errors.New("the payload signing key is not a PEM encoded public key")
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the payload signing key: %w", err)
+		}
+		edKey, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("the payload signing key is not an ed25519 public key")
+		}
+		return ed25519Verifier{key: edKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload signing algorithm %s, expected %s or %s",
+			cfg.Algorithm, AlgorithmHMACSHA256, AlgorithmEd25519)
+	}
+}
+
+// verifySignature checks the base64 encoded detached signature of the payload
+func verifySignature(v verifier, payload, encodedSignature []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("%w: the signature is not base64 encoded", errs.ErrPayloadSignature)
+	}
+	if !v.verify(payload, signature) {
+		return fmt.Errorf("%w: the signature does not match the payload", errs.ErrPayloadSignature)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package payloadmanager
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretParserStub struct {
+	core.SecretParser
+	secrets map[string]string
+}
+
+func (s secretParserStub) GetRepoSecret(ctx context.Context, path string) (map[string]string, error) {
+	return s.secrets, nil
+}
+
+func (s secretParserStub) SubstituteSecret(command string, secretData map[string]string) (string, error) {
+	for name, value := range secretData {
+		command = strings.ReplaceAll(command, "${{ secrets."+name+" }}", value)
+	}
+	return command, nil
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	payload := []byte(`{"repo_link":"https://github.com/org/repo"}`)
+	mac := hmac.New(sha256.New, []byte("signing-secret"))
+	mac.Write(payload)
+	signature := []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)) + "\n")
+
+	parser := secretParserStub{secrets: map[string]string{"PAYLOAD_KEY": "signing-secret"}}
+	v, err := newVerifier(context.Background(), config.PayloadSigning{Key: "${{ secrets.PAYLOAD_KEY }}"}, parser)
+	assert.NoError(t, err)
+	assert.NoError(t, verifySignature(v, payload, signature))
+
+	tampered := []byte(`{"repo_link":"https://github.com/attacker/repo"}`)
+	err = verifySignature(v, tampered, signature)
+	assert.True(t, errors.Is(err, errs.ErrPayloadSignature), "error: %v", err)
+
+	err = verifySignature(v, payload, []byte("not base64!"))
+	assert.True(t, errors.Is(err, errs.ErrPayloadSignature), "error: %v", err)
+
+	_, err = newVerifier(context.Background(), config.PayloadSigning{Key: "${{ secrets.MISSING }}"}, parser)
+	assert.Error(t, err)
+}
+
+func TestVerifyEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	payload := []byte(`{"repo_link":"https://github.com/org/repo"}`)
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)))
+
+	v, err := newVerifier(context.Background(), config.PayloadSigning{Algorithm: AlgorithmEd25519, Key: key}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, verifySignature(v, payload, signature))
+	err = verifySignature(v, append(payload, ' '), signature)
+	assert.True(t, errors.Is(err, errs.ErrPayloadSignature), "error: %v", err)
+
+	_, err = newVerifier(context.Background(), config.PayloadSigning{Algorithm: AlgorithmEd25519, Key: "not a key"}, nil)
+	assert.Error(t, err)
+}
+
+func TestNewVerifierDisabled(t *testing.T) {
+	v, err := newVerifier(context.Background(), config.PayloadSigning{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = newVerifier(context.Background(), config.PayloadSigning{Algorithm: "md5", Key: "secret"}, nil)
+	assert.Error(t, err)
+}
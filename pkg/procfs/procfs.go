@@ -1,6 +1,4 @@
-//go:build linux
-// +build linux
-
+// Package procfs samples the resource usage of a process tree, the PSS memory is only supported on linux
 package procfs
 
 import (
@@ -11,12 +9,16 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 )
 
-// Proc represents the process for which we want to find stats
+// Proc represents the process for which we want to find stats, the stats include the descendants of the process
 type Proc struct {
 	totalMem     uint64
 	process      *process.Process
 	samplingTime time.Duration
 	usePss       bool
+	// descendants of the process found in the last sample, they are kept to compute the cpu usage between samples
+	children map[int32]*process.Process
+	// io counters of the processes of the tree, including the exited ones
+	io map[int32]*process.IOCountersStat
 }
 
 // Stats represents the process stats
@@ -26,7 +28,11 @@ type Stats struct {
 	MemShared     uint64
 	MemSwapped    uint64
 	MemConsumed   uint64
-	RecordTime    time.Time
+	// DiskRead and DiskWrite are the bytes read and written by the process tree since it started
+	DiskRead   uint64
+	DiskWrite  uint64
+	Processes  int
+	RecordTime time.Time
 }
 
 //New  Returns new Proc struct
@@ -40,50 +46,117 @@ func New(pid int32, samplingInterval time.Duration, usePss bool) (*Proc, error)
 		return nil, err
 	}
 
-	return &Proc{process: p, samplingTime: samplingInterval, usePss: usePss, totalMem: machineMemory.Total}, nil
+	return &Proc{
+		process:      p,
+		samplingTime: samplingInterval,
+		usePss:       usePss,
+		totalMem:     machineMemory.Total,
+		children:     make(map[int32]*process.Process),
+		io:           make(map[int32]*process.IOCountersStat),
+	}, nil
 }
 
-// GetStats returns process stats
+// GetStats returns the stats of the process tree, it fails if the process exited
 func (ps *Proc) GetStats() (stat *Stats, err error) {
 
 	s := Stats{}
 	s.RecordTime = time.Now()
-	s.CPUPercentage, err = ps.process.CPUPercent()
+	// the cpu percentage is the usage since the previous sample, it is 0 for the first sample of a process
+	s.CPUPercentage, err = ps.process.Percent(0)
 	if err != nil {
 		return nil, err
 	}
-
-	memInfo, err := ps.process.MemoryInfo()
-	if err != nil {
+	if err := ps.addMemory(&s, ps.process); err != nil {
 		return nil, err
 	}
+	ps.addIO(ps.process)
+	s.Processes = 1
+
+	for _, child := range ps.descendants() {
+		// the children may exit between the samples
+		if err := ps.addMemory(&s, child); err != nil {
+			continue
+		}
+		if cpu, err := child.Percent(0); err == nil {
+			s.CPUPercentage += cpu
+		}
+		ps.addIO(child)
+		s.Processes++
+	}
+	for _, counters := range ps.io {
+		s.DiskRead += counters.ReadBytes
+		s.DiskWrite += counters.WriteBytes
+	}
+	s.MemPercentage = (100 * float64(s.MemConsumed) / float64(ps.totalMem))
+	return &s, nil
 
+}
+
+func (ps *Proc) addMemory(s *Stats, p *process.Process) error {
 	if !ps.usePss {
-		s.MemConsumed = memInfo.RSS
-		s.MemSwapped = memInfo.Swap
-		s.MemPercentage = (100 * float64(s.MemConsumed) / float64(ps.totalMem))
-		return &s, nil
+		memInfo, err := p.MemoryInfo()
+		if err != nil {
+			return err
+		}
+		s.MemConsumed += memInfo.RSS
+		s.MemSwapped += memInfo.Swap
+		return nil
 	}
+	return addPss(s, p)
+}
 
-	//NOTE: parsing maps is inefficient, can use smaps_rollup instead to find Pss, Ref #https://www.kernel.org/doc/Documentation/ABI/testing/procfs-smaps_rollup
-	// TODO: smaps_rollup parser
+// addIO records the io counters of the process, reading them requires the same user as the process
+func (ps *Proc) addIO(p *process.Process) {
+	if counters, err := p.IOCounters(); err == nil {
+		ps.io[p.Pid] = counters
+	}
+}
 
-	//why use pss instead of rss, Ref #https://stackoverflow.com/questions/1420426/how-to-calculate-the-cpu-usage-of-a-process-by-pid-in-linux-from-c/1424556
-	maps, err := ps.process.MemoryMaps(false)
+// descendants returns the running descendants of the process, found from the parent pids of the running processes
+func (ps *Proc) descendants() []*process.Process {
+	pids, err := process.Pids()
 	if err != nil {
-		return nil, err
+		return nil
+	}
+	children := make(map[int32][]int32)
+	for _, pid := range pids {
+		if pid == ps.process.Pid {
+			continue
+		}
+		p, ok := ps.children[pid]
+		if !ok {
+			if p, err = process.NewProcess(pid); err != nil {
+				continue
+			}
+		}
+		ppid, err := p.Ppid()
+		if err != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], pid)
 	}
 
-	var pss float64
-	for _, m := range *maps {
-		// add 0.5KiB as this avg error due to truncation, Ref #https://github.com/pixelb/ps_mem
-		pss += float64(m.Pss) + 0.5
-		s.MemSwapped += m.Swap
+	found := make(map[int32]*process.Process)
+	queue := children[ps.process.Pid]
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		p, ok := ps.children[pid]
+		if !ok {
+			if p, err = process.NewProcess(pid); err != nil {
+				continue
+			}
+		}
+		found[pid] = p
+		queue = append(queue, children[pid]...)
 	}
-	s.MemConsumed = uint64(pss)
-	s.MemPercentage = (100 * float64(s.MemConsumed) / float64(ps.totalMem))
-	return &s, nil
+	ps.children = found
 
+	result := make([]*process.Process, 0, len(found))
+	for _, p := range found {
+		result = append(result, p)
+	}
+	return result
 }
 
 // GetStatsInInterval returns process stats after every interval
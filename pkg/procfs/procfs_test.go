@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package procfs
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStatsIncludesChildren(t *testing.T) {
+	cmd := exec.Command("sleep", "5")
+	assert.Nil(t, cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	ps, err := New(int32(os.Getpid()), time.Second, false)
+	assert.Nil(t, err)
+	stats, err := ps.GetStats()
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, stats.Processes, 2)
+	assert.Contains(t, ps.children, int32(cmd.Process.Pid))
+	assert.NotZero(t, stats.MemConsumed)
+}
@@ -0,0 +1,24 @@
+package procfs
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// addPss adds the proportional set size of the process to the stats
+func addPss(s *Stats, p *process.Process) error {
+	//NOTE: parsing maps is inefficient, can use smaps_rollup instead to find Pss, Ref #https://www.kernel.org/doc/Documentation/ABI/testing/procfs-smaps_rollup
+	// TODO: smaps_rollup parser
+
+	//why use pss instead of rss, Ref #https://stackoverflow.com/questions/1420426/how-to-calculate-the-cpu-usage-of-a-process-by-pid-in-linux-from-c/1424556
+	maps, err := p.MemoryMaps(false)
+	if err != nil {
+		return err
+	}
+
+	var pss float64
+	for _, m := range *maps {
+		// add 0.5KiB as this avg error due to truncation, Ref #https://github.com/pixelb/ps_mem
+		pss += float64(m.Pss) + 0.5
+		s.MemSwapped += m.Swap
+	}
+	s.MemConsumed += uint64(pss)
+	return nil
+}
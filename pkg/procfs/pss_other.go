@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package procfs
+
+import (
+	"errors"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// addPss fails as the memory maps of the processes are only read on linux
+func addPss(s *Stats, p *process.Process) error {
+	return errors.New("pss is only supported on linux")
+}
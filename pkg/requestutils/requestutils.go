@@ -0,0 +1,143 @@
+// Package requestutils builds the http clients of the outbound requests, so that the proxy,
+// the custom CA bundle and the client certificate apply to all of them.
+package requestutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+// systemBundles are the locations of the system CA bundle on the common distributions
+var systemBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+var (
+	mu                   sync.RWMutex
+	transport            = newTransport(nil, false)
+	noKeepAliveTransport = newTransport(nil, true)
+	gitEnv               []string
+)
+
+// Setup configures the transport of the clients returned by NewClient with the CA bundle and the client
+// certificate of the config, and the retries of the clients returned by NewResilientClient. The proxy is read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables regardless of the config. Setup must be called before the clients are created.
+func Setup(cfg *config.HTTP) error {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	env := make([]string, 0)
+	if cfg.CABundle != "" {
+		bundle, err := ioutil.ReadFile(cfg.CABundle)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return fmt.Errorf("no certificates found in the CA bundle %s", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+		// git replaces the system CAs with the configured file, so it gets the system CAs along with the bundle
+		gitBundle, err := writeGitBundle(bundle)
+		if err != nil {
+			return err
+		}
+		env = append(env, "GIT_SSL_CAINFO="+gitBundle)
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		if cfg.ClientCert == "" || cfg.ClientKey == "" {
+			return errors.New("both the client certificate and the client key are required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		env = append(env, "GIT_SSL_CERT="+cfg.ClientCert, "GIT_SSL_KEY="+cfg.ClientKey)
+	}
+
+	hostMu.Lock()
+	policy = retryPolicy{
+		maxRetries:       cfg.MaxRetries,
+		breakerThreshold: cfg.BreakerThreshold,
+		breakerCooldown:  time.Duration(cfg.BreakerCooldown) * time.Second,
+	}
+	hostMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	transport = newTransport(tlsConfig, false)
+	noKeepAliveTransport = newTransport(tlsConfig, true)
+	gitEnv = env
+	// the loggers are created before the setup, their http sinks get the transport when they ship the logs
+	lumber.SetHTTPTransport(transport)
+	return nil
+}
+
+// NewClient returns a client with the shared transport and the timeout, no timeout is set if 0
+func NewClient(timeout time.Duration) http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return http.Client{Timeout: timeout, Transport: transport}
+}
+
+// NewClientWithoutKeepAlive returns a client as NewClient which closes the connection after each request
+func NewClientWithoutKeepAlive(timeout time.Duration) http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return http.Client{Timeout: timeout, Transport: noKeepAliveTransport}
+}
+
+// GitEnv returns the environment variables which configure git with the CA bundle and the client certificate,
+// git reads the proxy from the environment itself
+func GitEnv() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string(nil), gitEnv...)
+}
+
+func newTransport(tlsConfig *tls.Config, disableKeepAlives bool) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	t.DisableKeepAlives = disableKeepAlives
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig.Clone()
+	}
+	return t
+}
+
+// writeGitBundle writes the system CA bundle followed by the custom bundle to a temporary file
+func writeGitBundle(bundle []byte) (string, error) {
+	combined := make([]byte, 0)
+	for _, path := range append([]string{os.Getenv("SSL_CERT_FILE")}, systemBundles...) {
+		if path == "" {
+			continue
+		}
+		if system, err := ioutil.ReadFile(path); err == nil {
+			combined = append(combined, system...)
+			combined = append(combined, '\n')
+			break
+		}
+	}
+	combined = append(combined, bundle...)
+	path := filepath.Join(os.TempDir(), "tas-ca-bundle.pem")
+	if err := ioutil.WriteFile(path, combined, 0644); err != nil {
+		return "", fmt.Errorf("failed to write the CA bundle of git: %w", err)
+	}
+	return path, nil
+}
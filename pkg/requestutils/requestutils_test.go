@@ -0,0 +1,95 @@
+package requestutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeClientCert writes a self signed client certificate and its key to the directory
+func writeClientCert(t *testing.T, dir string) (certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nucleus"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certPath = filepath.Join(dir, "client.crt")
+	keyPath = filepath.Join(dir, "client.key")
+	assert.Nil(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certPath, keyPath
+}
+
+func TestSetup(t *testing.T) {
+	defer func() { _ = Setup(&config.HTTP{}) }()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caBundle := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	certPath, keyPath := writeClientCert(t, dir)
+
+	client := NewClient(time.Second)
+	_, err := client.Get(server.URL)
+	assert.NotNil(t, err, "the certificate of the server is not trusted without the CA bundle")
+
+	assert.Nil(t, Setup(&config.HTTP{CABundle: caBundle}))
+	client = NewClient(time.Second)
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(t, GitEnv(), 1)
+
+	assert.Nil(t, Setup(&config.HTTP{CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath}))
+	client = NewClientWithoutKeepAlive(time.Second)
+	resp, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "nucleus", string(body))
+	assert.Equal(t, []string{"GIT_SSL_CERT=" + certPath, "GIT_SSL_KEY=" + keyPath}, GitEnv()[1:])
+}
+
+func TestSetupInvalid(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.pem")
+	assert.Nil(t, ioutil.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	assert.NotNil(t, Setup(&config.HTTP{CABundle: filepath.Join(dir, "missing.pem")}))
+	assert.NotNil(t, Setup(&config.HTTP{CABundle: invalid}))
+	assert.NotNil(t, Setup(&config.HTTP{ClientCert: invalid}))
+	assert.Empty(t, GitEnv())
+}
@@ -0,0 +1,242 @@
+package requestutils
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+)
+
+const (
+	maxRetryDelay = 10 * time.Second
+	// maxRetryAfter is the longest Retry-After of a response which is waited for, the response is returned otherwise
+	maxRetryAfter = time.Minute
+	// each request adds budgetRatio to the retry budget of its host and each retry withdraws 1, so that at most
+	// 1 in 1/budgetRatio requests is retried while the host is failing, the budget is capped at budgetCap
+	budgetRatio = 0.2
+	budgetCap   = 10
+)
+
+// circuit breaker states
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// retryPolicy configures the retries and the circuit breaker of the resilient clients
+type retryPolicy struct {
+	maxRetries       int
+	breakerThreshold int
+	breakerCooldown  time.Duration
+}
+
+// HostStats are the counters of the requests of the resilient clients to a host
+type HostStats struct {
+	Host     string `json:"host"`
+	Requests int64  `json:"requests"`
+	Retries  int64  `json:"retries"`
+	Failures int64  `json:"failures"`
+	// Rejected is the number of requests failed by the open circuit breaker without being sent
+	Rejected int64  `json:"rejected"`
+	Breaker  string `json:"breaker"`
+}
+
+type hostState struct {
+	stats               HostStats
+	budget              float64
+	consecutiveFailures int
+	openUntil           time.Time
+	probing             bool
+}
+
+var (
+	policy = retryPolicy{maxRetries: 3, breakerThreshold: 5, breakerCooldown: 30 * time.Second}
+	hostMu sync.Mutex
+	hosts  = make(map[string]*hostState)
+	// now and baseRetryDelay are replaced in the tests
+	now            = time.Now
+	baseRetryDelay = 500 * time.Millisecond
+)
+
+// resilientTransport retries the transient failures with exponential backoff and jitter, within the retry budget
+// of the host, and fails the requests right away while the circuit breaker of the host is open
+type resilientTransport struct {
+	next http.RoundTripper
+}
+
+// NewResilientClient returns a client as NewClient which retries the transient failures, it is meant for the
+// requests to the neuron host and the blob storage. Requests are only retried if their body can be rewound.
+func NewResilientClient(timeout time.Duration) http.Client {
+	return Resilient(NewClient(timeout))
+}
+
+// Resilient returns the client with the retries and the circuit breaker of NewResilientClient
+func Resilient(client http.Client) http.Client {
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &resilientTransport{next: next}
+	return client
+}
+
+// Stats returns the counters of the resilient clients by host, sorted by host
+func Stats() []HostStats {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	stats := make([]HostStats, 0, len(hosts))
+	for _, h := range hosts {
+		stats = append(stats, h.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hostMu.Lock()
+	p := policy
+	hostMu.Unlock()
+	host := req.URL.Host
+	if err := allow(host); err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+		resp, err := t.next.RoundTrip(r)
+		transient := isTransient(req, resp, err)
+		record(host, p, transient)
+		if !transient || attempt >= p.maxRetries || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+		delay, ok := retryDelay(attempt, resp)
+		if !ok || !withdraw(host) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isTransient reports whether the request failed with a network error, a 5xx or a 429 status
+func isTransient(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the delay before the retry, the Retry-After of the response takes precedence
+// over the exponential backoff with full jitter
+func retryDelay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil {
+				delay := time.Duration(seconds) * time.Second
+				return delay, delay <= maxRetryAfter
+			}
+		}
+	}
+	backoff := baseRetryDelay << attempt
+	if backoff > maxRetryDelay || backoff <= 0 {
+		backoff = maxRetryDelay
+	}
+	// #nosec G404 the jitter does not need a cryptographic source
+	return time.Duration(rand.Int63n(int64(backoff)) + 1), true
+}
+
+// state returns the state of the host, hostMu must be held
+func state(host string) *hostState {
+	h, ok := hosts[host]
+	if !ok {
+		h = &hostState{stats: HostStats{Host: host, Breaker: breakerClosed}, budget: budgetCap}
+		hosts[host] = h
+	}
+	return h
+}
+
+// allow returns ErrCircuitOpen while the breaker of the host is open, a single request probes the host
+// once the cooldown has elapsed
+func allow(host string) error {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	h := state(host)
+	h.stats.Requests++
+	if h.budget += budgetRatio; h.budget > budgetCap {
+		h.budget = budgetCap
+	}
+	if h.stats.Breaker == breakerClosed {
+		return nil
+	}
+	if h.probing || now().Before(h.openUntil) {
+		h.stats.Rejected++
+		return errs.ErrCircuitOpen
+	}
+	h.probing = true
+	h.stats.Breaker = breakerHalfOpen
+	return nil
+}
+
+// record updates the breaker of the host with the outcome of an attempt
+func record(host string, p retryPolicy, failed bool) {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	h := state(host)
+	if !failed {
+		h.consecutiveFailures = 0
+		h.probing = false
+		h.stats.Breaker = breakerClosed
+		return
+	}
+	h.stats.Failures++
+	h.consecutiveFailures++
+	if h.stats.Breaker == breakerHalfOpen || (p.breakerThreshold > 0 && h.consecutiveFailures >= p.breakerThreshold) {
+		h.probing = false
+		h.stats.Breaker = breakerOpen
+		h.openUntil = now().Add(p.breakerCooldown)
+	}
+}
+
+// withdraw takes a retry from the budget of the host, it returns false if the budget is exhausted
+// or the breaker has opened meanwhile
+func withdraw(host string) bool {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	h := state(host)
+	if h.stats.Breaker == breakerOpen || h.budget < 1 {
+		return false
+	}
+	h.budget--
+	h.stats.Retries++
+	return true
+}
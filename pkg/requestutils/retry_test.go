@@ -0,0 +1,130 @@
+package requestutils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func setPolicy(t *testing.T, p retryPolicy) {
+	hostMu.Lock()
+	previous := policy
+	policy = p
+	hostMu.Unlock()
+	previousDelay := baseRetryDelay
+	baseRetryDelay = time.Millisecond
+	t.Cleanup(func() {
+		hostMu.Lock()
+		policy = previous
+		hostMu.Unlock()
+		baseRetryDelay = previousDelay
+		now = time.Now
+	})
+}
+
+func hostStats(host string) HostStats {
+	for _, s := range Stats() {
+		if s.Host == host {
+			return s
+		}
+	}
+	return HostStats{}
+}
+
+func TestResilientClientRetries(t *testing.T) {
+	setPolicy(t, retryPolicy{maxRetries: 3, breakerThreshold: 5, breakerCooldown: time.Minute})
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "payload", string(body))
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewResilientClient(5 * time.Second)
+	resp, err := client.Post(server.URL, "text/plain", bytes.NewBufferString("payload"))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	stats := hostStats(strings.TrimPrefix(server.URL, "http://"))
+	assert.Equal(t, int64(1), stats.Requests)
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(2), stats.Failures)
+	assert.Equal(t, breakerClosed, stats.Breaker)
+}
+
+func TestResilientClientNoRetry(t *testing.T) {
+	setPolicy(t, retryPolicy{maxRetries: 3, breakerThreshold: 5, breakerCooldown: time.Minute})
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewResilientClient(5 * time.Second)
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "client errors are not retried")
+
+	resp, err = client.Get(server.URL + "/limited")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "a Retry-After above the limit is not waited for")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestResilientClientCircuitBreaker(t *testing.T) {
+	setPolicy(t, retryPolicy{maxRetries: 0, breakerThreshold: 2, breakerCooldown: time.Minute})
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	client := NewResilientClient(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, breakerOpen, hostStats(host).Breaker)
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, errs.ErrCircuitOpen))
+	assert.Equal(t, int64(1), hostStats(host).Rejected)
+
+	// the request after the cooldown probes the host and closes the breaker
+	atomic.StoreInt32(&healthy, 1)
+	now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, breakerClosed, hostStats(host).Breaker)
+}
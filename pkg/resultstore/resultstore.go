@@ -0,0 +1,379 @@
+// Package resultstore keeps the history of the test runs and the coverage in an embedded SQLite database
+// so that the trends are available in local runner mode without the remote backend.
+package resultstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	// registers the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// databaseFile is the name of the database in the local storage directory
+const databaseFile = "results.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	task_id     TEXT PRIMARY KEY,
+	build_id    TEXT NOT NULL,
+	repo_id     TEXT NOT NULL,
+	commit_id   TEXT NOT NULL,
+	branch      TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	remark      TEXT NOT NULL,
+	start_time  INTEGER NOT NULL,
+	end_time    INTEGER NOT NULL,
+	passed      INTEGER NOT NULL,
+	failed      INTEGER NOT NULL,
+	skipped     INTEGER NOT NULL,
+	blocklisted INTEGER NOT NULL,
+	duration    INTEGER NOT NULL,
+	rerun_of    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS runs_repo ON runs (repo_id, end_time);
+CREATE TABLE IF NOT EXISTS test_results (
+	task_id  TEXT NOT NULL,
+	test_id  TEXT NOT NULL,
+	name     TEXT NOT NULL,
+	file     TEXT NOT NULL,
+	status   TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	matrix   TEXT NOT NULL,
+	locator  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS test_results_task ON test_results (task_id);
+CREATE INDEX IF NOT EXISTS test_results_test ON test_results (test_id);
+CREATE TABLE IF NOT EXISTS coverage (
+	repo_id       TEXT NOT NULL,
+	commit_id     TEXT NOT NULL,
+	build_id      TEXT NOT NULL,
+	lines         REAL NOT NULL,
+	statements    REAL NOT NULL,
+	functions     REAL NOT NULL,
+	branches      REAL NOT NULL,
+	diff_coverage REAL,
+	created_at    INTEGER NOT NULL,
+	PRIMARY KEY (repo_id, commit_id)
+);
+`
+
+// migrations add the columns of the later versions to the databases created before them
+var migrations = []struct{ table, column, definition string }{
+	{"runs", "rerun_of", "TEXT NOT NULL DEFAULT ''"},
+	{"test_results", "locator", "TEXT NOT NULL DEFAULT ''"},
+}
+
+type store struct {
+	db     *sql.DB
+	logger lumber.Logger
+}
+
+// New returns a new ResultStore. The database is only opened in local runner mode, the results
+// are recorded by neuron otherwise and the store is disabled.
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (core.ResultStore, error) {
+	if !cfg.LocalRunner {
+		return &store{logger: logger}, nil
+	}
+	return open(filepath.Join(cfg.Storage.LocalDir, databaseFile), logger)
+}
+
+func open(path string, logger lumber.Logger) (*store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// the nucleus of the following tasks may read the database while it is written
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows a single writer, the queries are serialized rather than failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &store{db: db, logger: logger}, nil
+}
+
+// migrate adds the missing columns, sqlite can't add a column only if it does not exist
+func migrate(db *sql.DB) error {
+	for _, m := range migrations {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, m.table, m.column).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// SaveRun persists the task and the results of its tests, the results of a task run again are replaced
+func (s *store) SaveRun(ctx context.Context, branch string, task *core.TaskPayload, result *core.ExecutionResult) error {
+	if s.db == nil {
+		return nil
+	}
+	run := summarize(task, result)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO runs
+		(task_id, build_id, repo_id, commit_id, branch, status, remark, start_time, end_time,
+		passed, failed, skipped, blocklisted, duration, rerun_of) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.TaskID, run.BuildID, run.RepoID, run.CommitID, branch, string(run.Status), run.Remark,
+		run.StartTime.UnixMilli(), run.EndTime.UnixMilli(),
+		run.Passed, run.Failed, run.Skipped, run.Blocklisted, run.Duration, run.RerunOf); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM test_results WHERE task_id = ?`, task.TaskID); err != nil {
+		return err
+	}
+	if result != nil {
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO test_results
+			(task_id, test_id, name, file, status, duration, matrix, locator) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i := range result.TestPayload {
+			t := &result.TestPayload[i]
+			if _, err := stmt.ExecContext(ctx, task.TaskID, t.TestID, t.Name, t.FilePath, t.Status, t.Duration,
+				t.Matrix.Name(), t.Filelocator); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Debugf("saved run of task %s with %d tests to the results store", task.TaskID, run.Passed+run.Failed+run.Skipped)
+	return nil
+}
+
+// summarize counts the results of the tests by status
+func summarize(task *core.TaskPayload, result *core.ExecutionResult) core.RunSummary {
+	run := core.RunSummary{
+		TaskID:    task.TaskID,
+		BuildID:   task.BuildID,
+		RepoID:    task.RepoID,
+		CommitID:  task.CommitID,
+		Status:    task.Status,
+		Remark:    task.Remark,
+		StartTime: task.StartTime,
+		EndTime:   task.EndTime,
+		RerunOf:   task.RerunOf,
+	}
+	if result == nil {
+		return run
+	}
+	for i := range result.TestPayload {
+		t := &result.TestPayload[i]
+		switch t.Status {
+		case core.TestFailed, core.TestTimedOut:
+			run.Failed++
+		case core.TestSkipped, core.TestLimitExceeded:
+			run.Skipped++
+		default:
+			run.Passed++
+		}
+		if t.Blocklisted {
+			run.Blocklisted++
+		}
+		run.Duration += int64(t.Duration)
+	}
+	return run
+}
+
+// SaveCoverage persists the coverage of a commit, the coverage of a commit merged again is replaced
+func (s *store) SaveCoverage(ctx context.Context, summary *core.CoverageSummary) error {
+	if s.db == nil {
+		return nil
+	}
+	createdAt := summary.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO coverage
+		(repo_id, commit_id, build_id, lines, statements, functions, branches, diff_coverage, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		summary.RepoID, summary.CommitID, summary.BuildID, summary.Lines, summary.Statements,
+		summary.Functions, summary.Branches, summary.DiffCoverage, createdAt.UnixMilli())
+	return err
+}
+
+// Runs returns the latest runs of the repository, most recent first
+func (s *store) Runs(ctx context.Context, repoID string, limit int) ([]core.RunSummary, error) {
+	if s.db == nil {
+		return nil, errs.ErrResultStoreDisabled
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT task_id, build_id, repo_id, commit_id, branch, status, remark,
+		start_time, end_time, passed, failed, skipped, blocklisted, duration, rerun_of
+		FROM runs WHERE repo_id = ? ORDER BY end_time DESC LIMIT ?`, repoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]core.RunSummary, 0)
+	for rows.Next() {
+		var run core.RunSummary
+		var startTime, endTime int64
+		if err := rows.Scan(&run.TaskID, &run.BuildID, &run.RepoID, &run.CommitID, &run.Branch, &run.Status, &run.Remark,
+			&startTime, &endTime, &run.Passed, &run.Failed, &run.Skipped, &run.Blocklisted, &run.Duration, &run.RerunOf); err != nil {
+			return nil, err
+		}
+		run.StartTime = time.UnixMilli(startTime)
+		run.EndTime = time.UnixMilli(endTime)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// TestHistory returns the latest results of the test, most recent first
+func (s *store) TestHistory(ctx context.Context, repoID, testID string, limit int) ([]core.TestHistoryEntry, error) {
+	if s.db == nil {
+		return nil, errs.ErrResultStoreDisabled
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT r.task_id, r.commit_id, t.status, t.duration, t.matrix, r.end_time
+		FROM test_results t JOIN runs r ON r.task_id = t.task_id
+		WHERE r.repo_id = ? AND t.test_id = ? ORDER BY r.end_time DESC LIMIT ?`, repoID, testID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]core.TestHistoryEntry, 0)
+	for rows.Next() {
+		var entry core.TestHistoryEntry
+		var runAt int64
+		if err := rows.Scan(&entry.TaskID, &entry.CommitID, &entry.Status, &entry.Duration, &entry.Matrix, &runAt); err != nil {
+			return nil, err
+		}
+		entry.RunAt = time.UnixMilli(runAt)
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// FailedTests returns the locators of the tests which failed or timed out in the run of the task. The file of
+// the test is returned for the results saved without a locator, so that the whole file is run again.
+func (s *store) FailedTests(ctx context.Context, repoID, taskID string) ([]string, error) {
+	if s.db == nil {
+		return nil, errs.ErrResultStoreDisabled
+	}
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM runs WHERE repo_id = ? AND task_id = ?`,
+		repoID, taskID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errs.ErrRunNotFound
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT CASE WHEN locator = '' THEN file ELSE locator END AS test
+		FROM test_results WHERE task_id = ? AND status IN (?, ?) ORDER BY test`,
+		taskID, core.TestFailed, core.TestTimedOut)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tests := make([]string, 0)
+	for rows.Next() {
+		var test string
+		if err := rows.Scan(&test); err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+	return tests, rows.Err()
+}
+
+// FlakyTests returns the tests which both passed and failed in the latest runs of the repository,
+// the tests failing most often first
+func (s *store) FlakyTests(ctx context.Context, repoID string, runs int) ([]core.FlakyTest, error) {
+	if s.db == nil {
+		return nil, errs.ErrResultStoreDisabled
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT t.test_id, MAX(t.name), MAX(t.file),
+		SUM(CASE WHEN t.status IN (?, ?, ?, ?) THEN 0 ELSE 1 END) AS passed,
+		SUM(CASE WHEN t.status IN (?, ?) THEN 1 ELSE 0 END) AS failed
+		FROM test_results t
+		JOIN (SELECT task_id FROM runs WHERE repo_id = ? ORDER BY end_time DESC LIMIT ?) r ON r.task_id = t.task_id
+		GROUP BY t.test_id HAVING passed > 0 AND failed > 0
+		ORDER BY failed DESC, t.test_id`,
+		core.TestFailed, core.TestTimedOut, core.TestSkipped, core.TestLimitExceeded, core.TestFailed, core.TestTimedOut,
+		repoID, runs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tests := make([]core.FlakyTest, 0)
+	for rows.Next() {
+		var test core.FlakyTest
+		if err := rows.Scan(&test.TestID, &test.Name, &test.File, &test.Passed, &test.Failed); err != nil {
+			return nil, err
+		}
+		test.FlakeRate = float64(test.Failed) / float64(test.Passed+test.Failed)
+		tests = append(tests, test)
+	}
+	return tests, rows.Err()
+}
+
+// CoverageTrend returns the coverage of the latest commits of the repository, most recent first
+func (s *store) CoverageTrend(ctx context.Context, repoID string, limit int) ([]core.CoverageSummary, error) {
+	if s.db == nil {
+		return nil, errs.ErrResultStoreDisabled
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT repo_id, commit_id, build_id, lines, statements, functions, branches,
+		diff_coverage, created_at FROM coverage WHERE repo_id = ? ORDER BY created_at DESC LIMIT ?`, repoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trend := make([]core.CoverageSummary, 0)
+	for rows.Next() {
+		var summary core.CoverageSummary
+		var diffCoverage sql.NullFloat64
+		var createdAt int64
+		if err := rows.Scan(&summary.RepoID, &summary.CommitID, &summary.BuildID, &summary.Lines, &summary.Statements,
+			&summary.Functions, &summary.Branches, &diffCoverage, &createdAt); err != nil {
+			return nil, err
+		}
+		if diffCoverage.Valid {
+			summary.DiffCoverage = &diffCoverage.Float64
+		}
+		summary.CreatedAt = time.UnixMilli(createdAt)
+		trend = append(trend, summary)
+	}
+	return trend, rows.Err()
+}
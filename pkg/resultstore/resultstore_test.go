@@ -0,0 +1,164 @@
+package resultstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStore(t *testing.T) *store {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	s, err := open(filepath.Join(t.TempDir(), databaseFile), logger)
+	assert.Nil(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func saveRun(t *testing.T, s *store, taskID string, end time.Time, statuses map[string]string) {
+	task := &core.TaskPayload{TaskID: taskID, BuildID: "build", RepoID: "repo", CommitID: "sha-" + taskID,
+		Status: core.Passed, StartTime: end.Add(-time.Minute), EndTime: end}
+	result := &core.ExecutionResult{TaskID: taskID}
+	for testID, status := range statuses {
+		result.TestPayload = append(result.TestPayload, core.TestPayload{TestID: testID, Name: testID, FilePath: "a.test.js",
+			Status: status, Duration: 100})
+	}
+	assert.Nil(t, s.SaveRun(context.Background(), "main", task, result))
+}
+
+func TestRuns(t *testing.T) {
+	s := newStore(t)
+	now := time.Now()
+	saveRun(t, s, "task-1", now.Add(-time.Hour), map[string]string{"t1": "passed", "t2": core.TestFailed, "t3": core.TestSkipped})
+	saveRun(t, s, "task-2", now, map[string]string{"t1": "passed"})
+	// the results of a task run again are replaced
+	saveRun(t, s, "task-2", now, map[string]string{"t1": "passed", "t2": "passed"})
+
+	runs, err := s.Runs(context.Background(), "repo", 10)
+	assert.Nil(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, "task-2", runs[0].TaskID)
+	assert.Equal(t, 2, runs[0].Passed)
+	assert.Equal(t, "main", runs[0].Branch)
+	assert.Equal(t, int64(200), runs[0].Duration)
+	assert.Equal(t, now.UnixMilli(), runs[0].EndTime.UnixMilli())
+	assert.Equal(t, core.RunSummary{TaskID: "task-1", BuildID: "build", RepoID: "repo", CommitID: "sha-task-1", Branch: "main",
+		Status: core.Passed, StartTime: runs[1].StartTime, EndTime: runs[1].EndTime, Passed: 1, Failed: 1, Skipped: 1, Duration: 300}, runs[1])
+
+	runs, err = s.Runs(context.Background(), "repo", 1)
+	assert.Nil(t, err)
+	assert.Len(t, runs, 1)
+
+	history, err := s.TestHistory(context.Background(), "repo", "t2", 10)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "passed", history[0].Status)
+	assert.Equal(t, core.TestFailed, history[1].Status)
+	assert.Equal(t, "sha-task-1", history[1].CommitID)
+}
+
+func TestFailedTests(t *testing.T) {
+	s := newStore(t)
+	task := &core.TaskPayload{TaskID: "task-1", RepoID: "repo", Status: core.Failed, EndTime: time.Now()}
+	result := &core.ExecutionResult{TaskID: "task-1", TestPayload: []core.TestPayload{
+		{TestID: "t1", FilePath: "a.test.js", Filelocator: "a.test.js##suite##t1", Status: core.TestFailed},
+		{TestID: "t2", FilePath: "a.test.js", Filelocator: "a.test.js##suite##t2", Status: "passed"},
+		{TestID: "t3", FilePath: "b.test.js", Filelocator: "b.test.js##t3", Status: core.TestTimedOut},
+		// the whole file is run again without a locator
+		{TestID: "t4", FilePath: "c.test.js", Status: core.TestFailed},
+	}}
+	assert.Nil(t, s.SaveRun(context.Background(), "main", task, result))
+	rerun := &core.TaskPayload{TaskID: "task-2", RepoID: "repo", Status: core.Passed, EndTime: time.Now(), RerunOf: "task-1"}
+	assert.Nil(t, s.SaveRun(context.Background(), "main", rerun, &core.ExecutionResult{TaskID: "task-2"}))
+
+	tests, err := s.FailedTests(context.Background(), "repo", "task-1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.test.js##suite##t1", "b.test.js##t3", "c.test.js"}, tests)
+
+	tests, err = s.FailedTests(context.Background(), "repo", "task-2")
+	assert.Nil(t, err)
+	assert.Empty(t, tests)
+
+	_, err = s.FailedTests(context.Background(), "other", "task-1")
+	assert.ErrorIs(t, err, errs.ErrRunNotFound)
+
+	runs, err := s.Runs(context.Background(), "repo", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "task-1", runs[0].RerunOf)
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), databaseFile)
+	db, err := sql.Open("sqlite3", path)
+	assert.Nil(t, err)
+	// the tables created before the re-runs were recorded
+	_, err = db.Exec(`CREATE TABLE runs (task_id TEXT PRIMARY KEY, build_id TEXT NOT NULL, repo_id TEXT NOT NULL,
+		commit_id TEXT NOT NULL, branch TEXT NOT NULL, status TEXT NOT NULL, remark TEXT NOT NULL,
+		start_time INTEGER NOT NULL, end_time INTEGER NOT NULL, passed INTEGER NOT NULL, failed INTEGER NOT NULL,
+		skipped INTEGER NOT NULL, blocklisted INTEGER NOT NULL, duration INTEGER NOT NULL);
+		CREATE TABLE test_results (task_id TEXT NOT NULL, test_id TEXT NOT NULL, name TEXT NOT NULL, file TEXT NOT NULL,
+		status TEXT NOT NULL, duration INTEGER NOT NULL, matrix TEXT NOT NULL);`)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	for i := 0; i < 2; i++ {
+		s, err := open(path, nil)
+		assert.Nil(t, err)
+		_, err = s.db.Exec(`INSERT INTO test_results (task_id, test_id, name, file, status, duration, matrix, locator)
+			VALUES ('task', 'test', 'test', 'a.test.js', 'failed', 1, '', 'a.test.js##test')`)
+		assert.Nil(t, err)
+		assert.Nil(t, s.Close())
+	}
+}
+
+func TestFlakyTests(t *testing.T) {
+	s := newStore(t)
+	now := time.Now()
+	saveRun(t, s, "task-1", now.Add(-3*time.Hour), map[string]string{"t1": core.TestFailed, "t2": core.TestFailed})
+	saveRun(t, s, "task-2", now.Add(-2*time.Hour), map[string]string{"t1": "passed", "t2": core.TestFailed})
+	saveRun(t, s, "task-3", now.Add(-time.Hour), map[string]string{"t1": core.TestTimedOut, "t2": core.TestFailed})
+	saveRun(t, s, "task-4", now, map[string]string{"t1": "passed", "t2": core.TestSkipped})
+
+	tests, err := s.FlakyTests(context.Background(), "repo", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []core.FlakyTest{{TestID: "t1", Name: "t1", File: "a.test.js", Passed: 2, Failed: 2, FlakeRate: 0.5}}, tests)
+
+	// t1 only passed in the latest run
+	tests, err = s.FlakyTests(context.Background(), "repo", 1)
+	assert.Nil(t, err)
+	assert.Empty(t, tests)
+}
+
+func TestCoverageTrend(t *testing.T) {
+	s := newStore(t)
+	now := time.Now()
+	diff := 75.5
+	assert.Nil(t, s.SaveCoverage(context.Background(), &core.CoverageSummary{RepoID: "repo", CommitID: "sha-1", Lines: 80,
+		CreatedAt: now.Add(-time.Hour)}))
+	assert.Nil(t, s.SaveCoverage(context.Background(), &core.CoverageSummary{RepoID: "repo", CommitID: "sha-2", Lines: 82.5,
+		DiffCoverage: &diff, CreatedAt: now}))
+
+	trend, err := s.CoverageTrend(context.Background(), "repo", 10)
+	assert.Nil(t, err)
+	assert.Len(t, trend, 2)
+	assert.Equal(t, "sha-2", trend[0].CommitID)
+	assert.Equal(t, 82.5, trend[0].Lines)
+	assert.Equal(t, &diff, trend[0].DiffCoverage)
+	assert.Nil(t, trend[1].DiffCoverage)
+}
+
+func TestDisabled(t *testing.T) {
+	s, err := New(&config.NucleusConfig{}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, s.SaveRun(context.Background(), "main", &core.TaskPayload{TaskID: "task"}, nil))
+	_, err = s.Runs(context.Background(), "repo", 10)
+	assert.ErrorIs(t, err, errs.ErrResultStoreDisabled)
+}
@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultContainerVolumePath = "/coverage"
+	defaultVaultPath           = "/vault/secrets"
+	coverageVolume             = "coverage"
+	secretsVolume              = "secrets"
+	containerName              = "task"
+	// jobNameLabel is set by the job controller on the pods of the job
+	jobNameLabel = "job-name"
+	podTypeLabel = "tas.lambdatest.com/pod-type"
+	// maxNameLength is the maximum length of a kubernetes resource name which is used as label value
+	maxNameLength = 63
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func (k *k8s) getJob(r *core.RunnerOptions) (*batchv1.Job, error) {
+	resources, err := getResources(r.Tier, k.cfg.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+	name := jobName(r)
+	env := make([]corev1.EnvVar, 0, len(r.Env))
+	for _, e := range r.Env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: parts[0], Value: parts[1]})
+	}
+	args := append(append([]string{}, r.ContainerArgs...), "--local", "true", "--synapsehost", utils.GetOutboundIP())
+
+	pullPolicy := corev1.PullAlways
+	if k.cfg.ContainerRegistry.PullPolicy == config.PullNever && r.PodType == core.NucleusPod {
+		pullPolicy = corev1.PullNever
+	}
+	pullSecrets := make([]corev1.LocalObjectReference, 0, len(k.cfg.Kubernetes.ImagePullSecrets))
+	for _, secret := range k.cfg.Kubernetes.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	// the coverage data is only shared between the pods if a volume claim is configured
+	coverageSource := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	if claim := k.cfg.Kubernetes.CoverageClaim; claim != "" {
+		coverageSource = corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim}}
+	}
+
+	var backoffLimit int32
+	podLabels := map[string]string{podTypeLabel: string(r.PodType)}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: podLabels},
+		Spec: batchv1.JobSpec{
+			// the task is retried by neuron, not by the job controller
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: k.cfg.Kubernetes.ServiceAccount,
+					NodeSelector:       k.cfg.Kubernetes.NodeSelector,
+					ImagePullSecrets:   pullSecrets,
+					Containers: []corev1.Container{{
+						Name:            containerName,
+						Image:           r.DockerImage,
+						Args:            args,
+						Env:             env,
+						ImagePullPolicy: pullPolicy,
+						Resources:       resources,
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: coverageVolume, MountPath: defaultContainerVolumePath},
+							{Name: secretsVolume, MountPath: defaultVaultPath, ReadOnly: true},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: coverageVolume, VolumeSource: coverageSource},
+						{Name: secretsVolume, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: name}}},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// getSecret returns the secret with the files written by the secrets manager to the host volume path
+func (k *k8s) getSecret(r *core.RunnerOptions, job *batchv1.Job) (*corev1.Secret, error) {
+	data := make(map[string][]byte)
+	if r.HostVolumePath != "" {
+		files, err := ioutil.ReadDir(r.HostVolumePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			content, err := ioutil.ReadFile(filepath.Join(r.HostVolumePath, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			data[file.Name()] = content
+		}
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: job.Name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1.SchemeGroupVersion.String(),
+				Kind:       "Job",
+				Name:       job.Name,
+				UID:        job.UID,
+			}},
+		},
+		Data: data,
+	}, nil
+}
+
+// getResources returns the configured requests and limits, defaulting to the specs of the tier
+func getResources(tier core.Tier, cfg config.KubernetesConfig) (corev1.ResourceRequirements, error) {
+	specs, ok := core.TierOpts[tier]
+	if !ok {
+		specs = core.TierOpts[core.Small]
+	}
+	defaults := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(specs.CPU*1000), resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(specs.RAM*mb, resource.BinarySI),
+	}
+	requests, err := resourceList(cfg.Requests, defaults)
+	if err != nil {
+		return corev1.ResourceRequirements{}, fmt.Errorf("invalid kubernetes requests: %v", err)
+	}
+	limits, err := resourceList(cfg.Limits, defaults)
+	if err != nil {
+		return corev1.ResourceRequirements{}, fmt.Errorf("invalid kubernetes limits: %v", err)
+	}
+	return corev1.ResourceRequirements{Requests: requests, Limits: limits}, nil
+}
+
+func resourceList(cfg config.ResourceConfig, defaults corev1.ResourceList) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{corev1.ResourceCPU: cfg.CPU, corev1.ResourceMemory: cfg.Memory} {
+		if value == "" {
+			list[name] = defaults[name]
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", name, value, err)
+		}
+		list[name] = quantity
+	}
+	return list, nil
+}
+
+// jobName returns the container name as a valid kubernetes resource name
+func jobName(r *core.RunnerOptions) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s", r.ContainerName, r.PodType)), "-")
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return strings.Trim(name, "-")
+}
@@ -0,0 +1,278 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	mb = 1048576
+	// pollInterval is the interval between the status checks of the jobs
+	pollInterval = 2 * time.Second
+)
+
+type k8s struct {
+	client         kubernetes.Interface
+	logger         lumber.Logger
+	cfg            *config.SynapseConfig
+	secretsManager core.SecretsManager
+	cpu            float32
+	ram            int64
+
+	mu          sync.Mutex
+	runningJobs map[string]*core.RunnerOptions
+}
+
+// New initialize a new kubernetes runner which runs the task containers as jobs
+func New(secretsManager core.SecretsManager,
+	logger lumber.Logger,
+	cfg *config.SynapseConfig) (core.DockerRunner, error) {
+	restConfig, err := restConfig(cfg.Kubernetes.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newRunner(client, secretsManager, logger, cfg)
+}
+
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+func newRunner(client kubernetes.Interface,
+	secretsManager core.SecretsManager,
+	logger lumber.Logger,
+	cfg *config.SynapseConfig) (*k8s, error) {
+	k := &k8s{
+		client:         client,
+		logger:         logger,
+		cfg:            cfg,
+		secretsManager: secretsManager,
+		runningJobs:    make(map[string]*core.RunnerOptions),
+	}
+	// the capacity is the allocatable resources of the nodes the jobs can be scheduled on
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(cfg.Kubernetes.NodeSelector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		allocatable := nodes.Items[i].Status.Allocatable
+		k.cpu += float32(allocatable.Cpu().MilliValue()) / 1000
+		k.ram += allocatable.Memory().Value() / mb
+	}
+	logger.Infof("available cpu: %f", k.cpu)
+	logger.Infof("available memory: %d", k.ram)
+	return k, nil
+}
+
+func (k *k8s) Create(ctx context.Context, r *core.RunnerOptions) core.ContainerStatus {
+	containerStatus := core.ContainerStatus{Done: true}
+	job, err := k.getJob(r)
+	if err != nil {
+		k.logger.Errorf("error creating job specification: %v", err)
+		containerStatus.Done = false
+		containerStatus.Error = errs.ERR_K8S_CRT(err.Error())
+		return containerStatus
+	}
+	job, err = k.client.BatchV1().Jobs(k.namespace()).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		k.logger.Errorf("error creating job: %v", err)
+		containerStatus.Done = false
+		containerStatus.Error = errs.ERR_K8S_CRT(err.Error())
+		return containerStatus
+	}
+	r.ContainerID = job.Name
+
+	// the secret is owned by the job so that it is deleted along with the job
+	secret, err := k.getSecret(r, job)
+	if err != nil {
+		k.logger.Errorf("error reading secrets of job %s: %v", job.Name, err)
+		containerStatus.Done = false
+		containerStatus.Error = errs.ERR_K8S_CRT(err.Error())
+		return containerStatus
+	}
+	if _, err := k.client.CoreV1().Secrets(k.namespace()).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		k.logger.Errorf("error creating secret of job %s: %v", job.Name, err)
+		containerStatus.Done = false
+		containerStatus.Error = errs.ERR_K8S_CRT(err.Error())
+		return containerStatus
+	}
+	k.logger.Debugf("job created with name: %s, updating status %+v", job.Name, containerStatus)
+	return containerStatus
+}
+
+func (k *k8s) Destroy(ctx context.Context, r *core.RunnerOptions) error {
+	propagation := metav1.DeletePropagationBackground
+	err := k.client.BatchV1().Jobs(k.namespace()).Delete(ctx, r.ContainerID, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		k.logger.Errorf("error deleting job %v", err)
+		return err
+	}
+	return nil
+}
+
+func (k *k8s) Run(ctx context.Context, r *core.RunnerOptions) core.ContainerStatus {
+	containerStatus := core.ContainerStatus{Done: true}
+	k.logger.Debugf("running job %s", r.ContainerID)
+	k.mu.Lock()
+	k.runningJobs[r.ContainerID] = r
+	k.mu.Unlock()
+	defer func() {
+		k.mu.Lock()
+		delete(k.runningJobs, r.ContainerID)
+		k.mu.Unlock()
+	}()
+
+	if err := k.waitForCompletion(ctx, r); err != nil {
+		k.logger.Errorf("error while waiting for the job completion: %v", err)
+		containerStatus.Done = false
+		containerStatus.Error = errs.ERR_K8S_RUN(err.Error())
+		return containerStatus
+	}
+	k.logger.Debugf("Updating status %+v", containerStatus)
+	return containerStatus
+}
+
+// waitForCompletion streams the logs of the job pod once it is started and waits until the job completes
+func (k *k8s) waitForCompletion(ctx context.Context, r *core.RunnerOptions) error {
+	k.logger.Infof("waiting for job %s completion", r.ContainerID)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	streaming := false
+	var wg sync.WaitGroup
+	// the logs are streamed until the pod terminates
+	defer wg.Wait()
+	for {
+		job, err := k.client.BatchV1().Jobs(k.namespace()).Get(ctx, r.ContainerID, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return fmt.Errorf("job %s failed: %s", r.ContainerID, condition.Message)
+			}
+		}
+		if !streaming {
+			if pod, ok := k.startedPod(ctx, r.ContainerID); ok {
+				streaming = true
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					k.streamLogs(ctx, pod)
+				}()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// startedPod returns the name of the pod of the job if its container is started
+func (k *k8s) startedPod(ctx context.Context, jobName string) (string, bool) {
+	pods, err := k.client.CoreV1().Pods(k.namespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{jobNameLabel: jobName}).String(),
+	})
+	if err != nil {
+		k.logger.Warnf("error listing pods of job %s: %v", jobName, err)
+		return "", false
+	}
+	for i := range pods.Items {
+		if phase := pods.Items[i].Status.Phase; phase != corev1.PodPending && phase != corev1.PodUnknown {
+			return pods.Items[i].Name, true
+		}
+	}
+	return "", false
+}
+
+func (k *k8s) streamLogs(ctx context.Context, pod string) {
+	stream, err := k.client.CoreV1().Pods(k.namespace()).GetLogs(pod, &corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		k.logger.Warnf("error streaming logs of pod %s: %v", pod, err)
+		return
+	}
+	defer stream.Close()
+	logWriter := lumber.NewWriter(k.logger)
+	defer logWriter.Close()
+	if _, err := io.Copy(logWriter, stream); err != nil {
+		k.logger.Warnf("error streaming logs of pod %s: %v", pod, err)
+	}
+}
+
+func (k *k8s) GetInfo(ctx context.Context) (float32, int64) {
+	return k.cpu, k.ram
+}
+
+func (k *k8s) Initiate(ctx context.Context, r *core.RunnerOptions, statusChan chan core.ContainerStatus) {
+	if status := k.Create(ctx, r); !status.Done {
+		k.logger.Errorf("error creating job: %v", status.Error)
+		k.logger.Infof("Update error status after creation")
+		statusChan <- status
+		return
+	}
+	if status := k.Run(ctx, r); !status.Done {
+		k.logger.Errorf("error running job: %v", status.Error)
+		k.logger.Infof("Update error status after running")
+		if err := k.Destroy(ctx, r); err != nil {
+			k.logger.Errorf("error deleting failed job %s: %v", r.ContainerID, err)
+		}
+		statusChan <- status
+		return
+	}
+	if err := k.Destroy(ctx, r); err != nil {
+		k.logger.Errorf("error deleting completed job %s: %v", r.ContainerID, err)
+	}
+	k.logger.Infof("job %s execution succesful", r.ContainerID)
+	statusChan <- core.ContainerStatus{Done: true}
+}
+
+// PullImage is a no-op as the images are pulled by the kubelet according to the image pull policy of the job
+func (k *k8s) PullImage(containerImageConfig *core.ContainerImageConfig) error {
+	return nil
+}
+
+// KillRunningDocker deletes the running jobs
+func (k *k8s) KillRunningDocker(ctx context.Context) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, r := range k.runningJobs {
+		if err := k.Destroy(ctx, r); err != nil {
+			k.logger.Errorf("error deleting job %s: %v", r.ContainerID, err)
+		}
+	}
+}
+
+func (k *k8s) namespace() string {
+	return k.cfg.Kubernetes.Namespace
+}
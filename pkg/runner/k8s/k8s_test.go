@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestRunner(t *testing.T, cfg *config.SynapseConfig) (*k8s, *fake.Clientset) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{"pool": "tas"}},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("3500m"),
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	}
+	other := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("16"),
+	}}}
+	client := fake.NewSimpleClientset(node, other)
+	k, err := newRunner(client, nil, logger, cfg)
+	assert.Nil(t, err)
+	return k, client
+}
+
+func TestCreate(t *testing.T) {
+	cfg := &config.SynapseConfig{Kubernetes: config.KubernetesConfig{
+		Namespace:    "tas",
+		NodeSelector: map[string]string{"pool": "tas"},
+		Limits:       config.ResourceConfig{Memory: "6Gi"},
+	}}
+	k, client := newTestRunner(t, cfg)
+	cpu, ram := k.GetInfo(context.Background())
+	assert.Equal(t, float32(3.5), cpu)
+	assert.Equal(t, int64(8192), ram)
+
+	secretsDir := t.TempDir()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(secretsDir, "oauth"), []byte("token"), 0600))
+	r := &core.RunnerOptions{
+		ContainerName:  "Build_1",
+		DockerImage:    "lambdatest/nucleus:latest",
+		Env:            []string{"REPO=org/repo"},
+		HostVolumePath: secretsDir,
+		PodType:        core.NucleusPod,
+		Tier:           core.Small,
+	}
+	status := k.Create(context.Background(), r)
+	assert.True(t, status.Done)
+	assert.Equal(t, "build-1-nucleus", r.ContainerID)
+
+	job, err := client.BatchV1().Jobs("tas").Get(context.Background(), r.ContainerID, metav1.GetOptions{})
+	assert.Nil(t, err)
+	pod := job.Spec.Template.Spec
+	assert.Equal(t, map[string]string{"pool": "tas"}, pod.NodeSelector)
+	container := pod.Containers[0]
+	assert.Equal(t, []corev1.EnvVar{{Name: "REPO", Value: "org/repo"}}, container.Env)
+	assert.Equal(t, "2", container.Resources.Requests.Cpu().String())
+	assert.Equal(t, "4000Mi", container.Resources.Requests.Memory().String())
+	assert.Equal(t, "6Gi", container.Resources.Limits.Memory().String())
+
+	secret, err := client.CoreV1().Secrets("tas").Get(context.Background(), r.ContainerID, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("token"), secret.Data["oauth"])
+	assert.Equal(t, "Job", secret.OwnerReferences[0].Kind)
+}
+
+func TestRun(t *testing.T) {
+	k, client := newTestRunner(t, &config.SynapseConfig{Kubernetes: config.KubernetesConfig{Namespace: "tas"}})
+	r := &core.RunnerOptions{ContainerName: "build", PodType: core.ParsingPod}
+	assert.True(t, k.Create(context.Background(), r).Done)
+
+	job, err := client.BatchV1().Jobs("tas").Get(context.Background(), r.ContainerID, metav1.GetOptions{})
+	assert.Nil(t, err)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	_, err = client.BatchV1().Jobs("tas").UpdateStatus(context.Background(), job, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	status := k.Run(context.Background(), r)
+	assert.False(t, status.Done)
+	assert.Equal(t, "ERR::K8S::RUN", status.Error.Code)
+
+	assert.Nil(t, k.Destroy(context.Background(), r))
+	_, err = client.BatchV1().Jobs("tas").Get(context.Background(), r.ContainerID, metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestGetResourcesInvalid(t *testing.T) {
+	_, err := getResources(core.Small, config.KubernetesConfig{Requests: config.ResourceConfig{CPU: "two"}})
+	assert.NotNil(t, err)
+}
@@ -1,37 +1,75 @@
 package secret
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/logstream"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 )
 
 type secretParser struct {
 	logger      lumber.Logger
 	secretRegex *regexp.Regexp
+	vault       *vaultProvider
+	redactor    *logstream.Redactor
 }
 
 type secretData struct {
 	SecretMap map[string]string `json:"data"`
 }
 
-// New return new secret parser
-func New(logger lumber.Logger) core.SecretParser {
-	return &secretParser{
+// New return new secret parser, all the resolved secret values are registered in the redactor
+func New(cfg *config.NucleusConfig, redactor *logstream.Redactor, logger lumber.Logger) core.SecretParser {
+	s := &secretParser{
 		logger:      logger,
 		secretRegex: regexp.MustCompile(global.SecretRegex),
+		redactor:    redactor,
 	}
+	if cfg.Vault.Address != "" {
+		s.vault = newVaultProvider(cfg.Vault, logger)
+	}
+	return s
 }
 
-// GetRepoSecret read repo secrets from given path
-func (s *secretParser) GetRepoSecret(path string) (map[string]string, error) {
+// GetRepoSecret read repo secrets from given path. If vault is configured,
+// secrets from vault are also returned, with repo secrets taking precedence.
+func (s *secretParser) GetRepoSecret(ctx context.Context, path string) (map[string]string, error) {
+	secrets, err := s.readRepoSecret(path)
+	if err != nil || s.vault == nil {
+		s.redact(secrets)
+		return secrets, err
+	}
+	vaultSecrets, err := s.vault.Secrets(ctx)
+	if err != nil {
+		s.logger.Errorf("failed to fetch secrets from vault, error %v", err)
+		return nil, err
+	}
+	for k, v := range secrets {
+		vaultSecrets[k] = v
+	}
+	s.redact(vaultSecrets)
+	return vaultSecrets, nil
+}
+
+func (s *secretParser) redact(secrets map[string]string) {
+	if s.redactor == nil {
+		return
+	}
+	for _, v := range secrets {
+		s.redactor.Add(v)
+	}
+}
+
+func (s *secretParser) readRepoSecret(path string) (map[string]string, error) {
 	var secretData secretData
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		s.logger.Debugf("failed to find user env secrets in path %s, as path does not exists", path)
@@ -67,6 +105,9 @@ func (s *secretParser) GetOauthSecret(path string) (*core.Oauth, error) {
 		s.logger.Errorf("failed to unmarshal oauth secret, error %v", err)
 		return nil, err
 	}
+	if s.redactor != nil {
+		s.redactor.Add(o.Data.AccessToken, o.Data.RefreshToken)
+	}
 
 	return o, err
 }
@@ -4,6 +4,7 @@ import (
 	"log"
 	"testing"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 )
 
@@ -13,7 +14,7 @@ func TestSubstituteSecret(t *testing.T) {
 		log.Fatalf("Could not instantiate logger %s", err.Error())
 	}
 
-	secretParser := New(logger)
+	secretParser := New(&config.NucleusConfig{}, nil, logger)
 	var expressions = []struct {
 		params    map[string]string
 		input     string
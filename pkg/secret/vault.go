@@ -0,0 +1,311 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+)
+
+const (
+	vaultAuthToken      = "token"
+	vaultAuthAppRole    = "approle"
+	vaultAuthKubernetes = "kubernetes"
+	defaultVaultTTL     = 5 * time.Minute
+	// minimum time before expiry at which the token or lease is renewed
+	minRenewWindow = 10 * time.Second
+)
+
+// vaultResponse is the common response envelope of the vault HTTP API
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *vaultAuth             `json:"auth"`
+	Errors        []string               `json:"errors"`
+}
+
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+type cachedSecret struct {
+	data      map[string]string
+	leaseID   string
+	renewable bool
+	lease     time.Duration
+	expiresAt time.Time
+}
+
+// vaultProvider resolves secrets from the configured vault paths.
+// The client token and the secrets are cached till their lease expires,
+// renewable leases are renewed before expiry instead of logging in or reading again.
+type vaultProvider struct {
+	cfg        config.Vault
+	logger     lumber.Logger
+	httpClient http.Client
+
+	mu          sync.Mutex
+	token       string
+	renewable   bool
+	tokenLease  time.Duration
+	tokenExpiry time.Time
+	cache       map[string]*cachedSecret
+}
+
+func newVaultProvider(cfg config.Vault, logger lumber.Logger) *vaultProvider {
+	if cfg.AuthMethod == "" {
+		cfg.AuthMethod = vaultAuthToken
+	}
+	return &vaultProvider{
+		cfg:        cfg,
+		logger:     logger,
+		httpClient: requestutils.NewClient(global.DefaultHTTPTimeout),
+		cache:      make(map[string]*cachedSecret),
+	}
+}
+
+// Secrets returns the keys of all the configured secret paths,
+// keys in later paths override the ones in earlier paths.
+func (v *vaultProvider) Secrets(ctx context.Context) (map[string]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]string)
+	for _, path := range strings.Split(v.cfg.SecretPaths, ",") {
+		path = strings.Trim(strings.TrimSpace(path), "/")
+		if path == "" {
+			continue
+		}
+		data, err := v.read(ctx, path)
+		if err != nil {
+			v.logger.Errorf("failed to read vault secret %s, error: %v", path, err)
+			return nil, err
+		}
+		for k, val := range data {
+			secrets[k] = val
+		}
+	}
+	return secrets, nil
+}
+
+// ensureToken logs in or renews the client token if it is about to expire
+func (v *vaultProvider) ensureToken(ctx context.Context) error {
+	if v.token != "" && (v.tokenExpiry.IsZero() || !expiresSoon(v.tokenExpiry, v.tokenLease)) {
+		return nil
+	}
+	if v.token != "" && v.renewable {
+		auth, err := v.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]interface{}{})
+		if err == nil && auth.Auth != nil {
+			v.setToken(auth.Auth)
+			return nil
+		}
+		v.logger.Warnf("failed to renew vault token, logging in again: %v", err)
+	}
+	return v.login(ctx)
+}
+
+func (v *vaultProvider) login(ctx context.Context) error {
+	var loginPath string
+	var body map[string]interface{}
+	switch v.cfg.AuthMethod {
+	case vaultAuthToken:
+		v.token = v.cfg.Token
+		resp, err := v.do(ctx, http.MethodGet, "auth/token/lookup-self", nil)
+		if err != nil {
+			return err
+		}
+		ttl, _ := resp.Data["ttl"].(float64)
+		renewable, _ := resp.Data["renewable"].(bool)
+		v.setToken(&vaultAuth{ClientToken: v.cfg.Token, LeaseDuration: int(ttl), Renewable: renewable})
+		return nil
+	case vaultAuthAppRole:
+		loginPath = v.authPath(vaultAuthAppRole)
+		body = map[string]interface{}{"role_id": v.cfg.RoleID, "secret_id": v.cfg.SecretID}
+	case vaultAuthKubernetes:
+		jwt, err := ioutil.ReadFile(v.cfg.JWTPath)
+		if err != nil {
+			v.logger.Errorf("failed to read service account token %s, error: %v", v.cfg.JWTPath, err)
+			return err
+		}
+		loginPath = v.authPath(vaultAuthKubernetes)
+		body = map[string]interface{}{"role": v.cfg.Role, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return errs.ErrUnsupportedVaultAuth
+	}
+
+	v.token = ""
+	resp, err := v.do(ctx, http.MethodPost, loginPath, body)
+	if err != nil {
+		v.logger.Errorf("failed to login to vault using %s auth, error: %v", v.cfg.AuthMethod, err)
+		return err
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return errs.ErrVaultAuth
+	}
+	v.setToken(resp.Auth)
+	return nil
+}
+
+func (v *vaultProvider) authPath(method string) string {
+	mount := method
+	if v.cfg.AuthMount != "" {
+		mount = strings.Trim(v.cfg.AuthMount, "/")
+	}
+	return fmt.Sprintf("auth/%s/login", mount)
+}
+
+func (v *vaultProvider) setToken(auth *vaultAuth) {
+	v.token = auth.ClientToken
+	v.renewable = auth.Renewable
+	v.tokenLease = time.Duration(auth.LeaseDuration) * time.Second
+	v.tokenExpiry = time.Time{}
+	// root tokens without ttl never expire
+	if v.tokenLease > 0 {
+		v.tokenExpiry = time.Now().Add(v.tokenLease)
+	}
+}
+
+// read returns the secret at path, from cache if the lease has not expired
+func (v *vaultProvider) read(ctx context.Context, path string) (map[string]string, error) {
+	if cached, ok := v.cache[path]; ok {
+		if !expiresSoon(cached.expiresAt, cached.lease) {
+			return cached.data, nil
+		}
+		if cached.renewable && cached.leaseID != "" && time.Now().Before(cached.expiresAt) {
+			resp, err := v.do(ctx, http.MethodPut, "sys/leases/renew", map[string]interface{}{"lease_id": cached.leaseID})
+			if err == nil {
+				cached.lease = time.Duration(resp.LeaseDuration) * time.Second
+				cached.expiresAt = time.Now().Add(cached.lease)
+				return cached.data, nil
+			}
+			v.logger.Warnf("failed to renew lease of vault secret %s, reading again: %v", path, err)
+		}
+	}
+
+	resp, err := v.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	secret := &cachedSecret{
+		data:      flattenSecretData(resp.Data),
+		leaseID:   resp.LeaseID,
+		renewable: resp.Renewable,
+		lease:     v.cacheTTL(),
+	}
+	if resp.LeaseDuration > 0 {
+		secret.lease = time.Duration(resp.LeaseDuration) * time.Second
+	}
+	secret.expiresAt = time.Now().Add(secret.lease)
+	v.cache[path] = secret
+	return secret.data, nil
+}
+
+func (v *vaultProvider) cacheTTL() time.Duration {
+	if v.cfg.CacheTTL > 0 {
+		return time.Duration(v.cfg.CacheTTL) * time.Second
+	}
+	return defaultVaultTTL
+}
+
+func (v *vaultProvider) do(ctx context.Context, method, path string, body interface{}) (*vaultResponse, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	endpoint := strings.TrimSuffix(v.cfg.Address, "/") + "/v1/" + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	payload := new(vaultResponse)
+	if resp.StatusCode == http.StatusNoContent {
+		return payload, nil
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// the errors of a proxy in front of vault are usually not json, their raw body is reported instead
+		if json.Unmarshal(respBody, payload) == nil && len(payload.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.Join(payload.Errors, ", "))
+		}
+		if text := strings.TrimSpace(string(respBody)); text != "" {
+			return nil, fmt.Errorf("vault returned status %d: %s", resp.StatusCode, text)
+		}
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(respBody, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// flattenSecretData converts the secret data to string values, unwrapping the kv v2 envelope
+func flattenSecretData(data map[string]interface{}) map[string]string {
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	secrets := make(map[string]string, len(data))
+	for k, val := range data {
+		switch val := val.(type) {
+		case string:
+			secrets[k] = val
+		case nil:
+			continue
+		default:
+			raw, err := json.Marshal(val)
+			if err != nil {
+				continue
+			}
+			secrets[k] = string(raw)
+		}
+	}
+	return secrets
+}
+
+// expiresSoon checks if expiry lies within the last third of the lease or the minimum window
+func expiresSoon(expiry time.Time, lease time.Duration) bool {
+	window := lease / 3
+	if window < minRenewWindow {
+		window = minRenewWindow
+	}
+	return time.Until(expiry) <= window
+}
@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultSecrets(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	if err != nil {
+		log.Fatalf("Could not instantiate logger %s", err.Error())
+	}
+
+	logins, reads := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			logins++
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "role", body["role_id"])
+			assert.Equal(t, "secret", body["secret_id"])
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"s.token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/secret/data/tas":
+			reads++
+			assert.Equal(t, "s.token", r.Header.Get("X-Vault-Token"))
+			_, _ = w.Write([]byte(`{"data":{"data":{"NPM_TOKEN":"npm","PORT":8080},"metadata":{"version":1}}}`))
+		case "/v1/kv/shared":
+			_, _ = w.Write([]byte(`{"lease_duration":3600,"data":{"NPM_TOKEN":"shared","REGISTRY":"registry"}}`))
+		case "/v1/secret/data/proxied":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html>502 Bad Gateway</html>\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["not found"]}`))
+		}
+	}))
+	defer server.Close()
+
+	v := newVaultProvider(config.Vault{
+		Address:     server.URL,
+		AuthMethod:  vaultAuthAppRole,
+		RoleID:      "role",
+		SecretID:    "secret",
+		SecretPaths: "kv/shared, /secret/data/tas",
+	}, logger)
+
+	for i := 0; i < 2; i++ {
+		secrets, err := v.Secrets(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"NPM_TOKEN": "npm", "PORT": "8080", "REGISTRY": "registry"}, secrets)
+	}
+	// token and secrets are cached
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, reads)
+
+	v.cfg.SecretPaths = "secret/data/missing"
+	_, err = v.Secrets(context.Background())
+	assert.EqualError(t, err, "vault returned status 404: not found")
+
+	// the errors which are not returned by vault are reported as is
+	v.cfg.SecretPaths = "secret/data/proxied"
+	_, err = v.Secrets(context.Background())
+	assert.EqualError(t, err, "vault returned status 502: <html>502 Bad Gateway</html>")
+}
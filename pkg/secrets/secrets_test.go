@@ -1,9 +1,9 @@
 package secrets
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/LambdaTest/synapse/pkg/global"
@@ -16,9 +16,10 @@ func TestGetLambdatestSecrets(t *testing.T) {
 }
 
 func TestWriteGitSecrets(t *testing.T) {
-	expectedFile := fmt.Sprintf("%s/%s", testdDataDir, global.GitConfigFileName)
+	dir := t.TempDir()
+	expectedFile := filepath.Join(dir, global.GitConfigFileName)
 	expectedFileContent := `{"data":{"access_token":"dummytoken","expiry":"0001-01-01T00:00:00Z","refresh_token":""}}`
-	err := secretsManager.WriteGitSecrets(testdDataDir)
+	err := secretsManager.WriteGitSecrets(dir)
 	if err != nil {
 		t.Errorf("error while writing secrets: %v", err)
 	}
@@ -13,8 +13,6 @@ import (
 var cfg *config.SynapseConfig
 var secretsManager core.SecretsManager
 
-const testdDataDir = "./testdata"
-
 func TestMain(m *testing.M) {
 	cfg = tests.MockConfig()
 	logger, err := lumber.NewLogger(cfg.LogConfig, cfg.Verbose, lumber.InstanceZapLogger)
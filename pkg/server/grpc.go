@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/api/resultspb"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"google.golang.org/grpc"
+)
+
+// ListenAndServeGRPC initializes a server to respond to gRPC requests on the gRPC port.
+func ListenAndServeGRPC(ctx context.Context, results resultspb.ResultsServer, config *config.NucleusConfig, logger lumber.Logger) error {
+	lis, err := net.Listen("tcp", ":"+config.GRPCPort)
+	if err != nil {
+		logger.Errorf("failed to listen on gRPC port %s: %v", config.GRPCPort, err)
+		return err
+	}
+	srv := grpc.NewServer()
+	resultspb.RegisterResultsServer(srv, results)
+
+	errChan := make(chan error, 1)
+	go func() {
+		logger.Infof("Starting gRPC server on port %s", config.GRPCPort)
+		if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			logger.Errorf("gRPC serve: %v", err)
+			errChan <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Infof("Caller has requested graceful shutdown. shutting down the gRPC server")
+		// watch streams only end with the context of the client, hence not waiting for them
+		srv.Stop()
+		return nil
+	case err := <-errChan:
+		return err
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"net/http"
 
 	"github.com/LambdaTest/synapse/config"
-	"github.com/LambdaTest/synapse/pkg/api"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 	"github.com/gin-gonic/gin"
 )
 
+// Router provides the routes of the API server, i.e. the API of the task or the one of the resident nucleus
+type Router interface {
+	Handler() *gin.Engine
+}
+
 // ListenAndServe initializes a server to respond to HTTP network requests.
-func ListenAndServe(ctx context.Context, router api.Router, config *config.NucleusConfig, logger lumber.Logger) error {
+func ListenAndServe(ctx context.Context, router Router, config *config.NucleusConfig, logger lumber.Logger) error {
 
 	// set gin to release mode
 	gin.SetMode(gin.ReleaseMode)
@@ -0,0 +1,293 @@
+// Package autoconfig infers the configuration of the repositories without configuration file from their
+// manifests, so that their tests run without any setup.
+package autoconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"gopkg.in/yaml.v2"
+)
+
+const configMimeType = "application/x-yaml"
+
+const configHeader = "# inferred from the manifests of the repository, review it and commit it as the configuration file\n"
+
+// manifests of the languages, in the order they are reported
+const (
+	packageJSON  = "package.json"
+	goMod        = "go.mod"
+	pomXML       = "pom.xml"
+	requirements = "requirements.txt"
+)
+
+// frameworks are the supported frameworks, in the order they are preferred if several are installed
+var frameworks = []string{"jest", "mocha", "jasmine"}
+
+// testPatterns are the default locations of the test files of the frameworks
+var testPatterns = map[string][]string{
+	"jest":    {"./**/__tests__/**/*.{js,jsx,ts,tsx}", "./**/*.{spec,test}.{js,jsx,ts,tsx}"},
+	"mocha":   {"./test/**/*.{js,ts}"},
+	"jasmine": {"./spec/**/*[sS]pec.js"},
+}
+
+// configFiles are the conventional configuration files of the frameworks
+var configFiles = map[string][]string{
+	"jest":    {"jest.config.js", "jest.config.ts", "jest.config.json"},
+	"mocha":   {".mocharc.js", ".mocharc.json", ".mocharc.yml", ".mocharc.yaml"},
+	"jasmine": {"spec/support/jasmine.json"},
+}
+
+var versionRegex = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// Config is the inferred configuration file
+type Config struct {
+	Framework  string            `yaml:"framework"`
+	Tier       core.Tier         `yaml:"tier"`
+	ConfigFile string            `yaml:"configFile,omitempty"`
+	PreRun     *Run              `yaml:"preRun"`
+	PreMerge   *Merge            `yaml:"preMerge"`
+	PostMerge  *Merge            `yaml:"postMerge"`
+	Caches     []core.NamedCache `yaml:"caches"`
+	Toolchains *Toolchains       `yaml:"toolchains,omitempty"`
+}
+
+// Run are the commands run before the tests
+type Run struct {
+	Commands []string `yaml:"command"`
+}
+
+// Merge are the patterns of the test files
+type Merge struct {
+	Patterns []string `yaml:"pattern"`
+}
+
+// Toolchains are the versions of the toolchains found in the manifests
+type Toolchains struct {
+	Node string `yaml:"node,omitempty"`
+	Go   string `yaml:"go,omitempty"`
+	Java string `yaml:"java,omitempty"`
+}
+
+type packageManifest struct {
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+	Engines         struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+}
+
+type pomManifest struct {
+	Properties struct {
+		JavaVersion     string `xml:"java.version"`
+		CompilerRelease string `xml:"maven.compiler.release"`
+		CompilerSource  string `xml:"maven.compiler.source"`
+	} `xml:"properties"`
+}
+
+// Detector infers the configuration from the manifests of the repository
+type Detector struct {
+	azureClient core.AzureClient
+	logger      lumber.Logger
+}
+
+// New returns a new instance of Detector
+func New(azureClient core.AzureClient, logger lumber.Logger) *Detector {
+	return &Detector{azureClient: azureClient, logger: logger}
+}
+
+// Detect infers the configuration of the JavaScript tests of the repository, the versions of the other
+// toolchains found in the manifests are required by the inferred configuration
+func (d *Detector) Detect(ctx context.Context, repoDir string) ([]byte, error) {
+	languages := detectLanguages(repoDir)
+	if len(languages) == 0 {
+		return nil, errors.New("no manifest found in the repository to infer the configuration from, " +
+			"add a configuration file")
+	}
+	d.logger.Infof("manifests found in the repository: %s", strings.Join(languages, ", "))
+	config, err := inferConfig(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	body, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(configHeader), body...), nil
+}
+
+// Publish logs the inferred configuration and uploads it next to the other reports of the task
+func (d *Detector) Publish(ctx context.Context, payload *core.Payload, config []byte) error {
+	d.logger.Infof("inferred configuration:\n%s", config)
+	blobPath := fmt.Sprintf("autoconfig/%s/%s/%s.yml", payload.OrgID, payload.RepoID, payload.TaskID)
+	blobURL, err := d.azureClient.Create(ctx, blobPath, bytes.NewReader(config), configMimeType)
+	if err != nil {
+		d.logger.Errorf("failed to upload the inferred configuration %v", err)
+		return err
+	}
+	d.logger.Infof("inferred configuration uploaded to %s", blobURL)
+	return nil
+}
+
+// detectLanguages returns the manifests found at the root of the repository
+func detectLanguages(repoDir string) []string {
+	found := make([]string, 0)
+	for _, manifest := range []string{packageJSON, goMod, pomXML, requirements} {
+		if fileExists(filepath.Join(repoDir, manifest)) {
+			found = append(found, manifest)
+		}
+	}
+	return found
+}
+
+// inferConfig infers the configuration from package.json, along with the toolchains of go.mod and pom.xml
+func inferConfig(repoDir string) (*Config, error) {
+	content, err := ioutil.ReadFile(filepath.Join(repoDir, packageJSON))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("only the %s frameworks are supported, %s not found in the repository",
+				strings.Join(frameworks, ", "), packageJSON)
+		}
+		return nil, err
+	}
+	manifest := new(packageManifest)
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", packageJSON, err)
+	}
+	framework := detectFramework(manifest)
+	if framework == "" {
+		return nil, fmt.Errorf("none of the %s frameworks is a dependency in %s",
+			strings.Join(frameworks, ", "), packageJSON)
+	}
+
+	install, cache := packageManager(repoDir)
+	config := &Config{
+		Framework: framework,
+		Tier:      core.Small,
+		PreRun:    &Run{Commands: []string{install}},
+		PreMerge:  &Merge{Patterns: testPatterns[framework]},
+		PostMerge: &Merge{Patterns: testPatterns[framework]},
+		Caches:    []core.NamedCache{cache},
+	}
+	for _, file := range configFiles[framework] {
+		if fileExists(filepath.Join(repoDir, file)) {
+			config.ConfigFile = file
+			break
+		}
+	}
+	toolchains := &Toolchains{
+		Node: nodeVersion(repoDir, manifest),
+		Go:   goVersion(repoDir),
+		Java: javaVersion(repoDir),
+	}
+	if *toolchains != (Toolchains{}) {
+		config.Toolchains = toolchains
+	}
+	return config, nil
+}
+
+// detectFramework returns the framework run by the test script, or the preferred installed framework
+func detectFramework(manifest *packageManifest) string {
+	installed := make([]string, 0, len(frameworks))
+	for _, framework := range frameworks {
+		_, dev := manifest.DevDependencies[framework]
+		_, dep := manifest.Dependencies[framework]
+		if dev || dep {
+			installed = append(installed, framework)
+		}
+	}
+	for _, framework := range installed {
+		if strings.Contains(manifest.Scripts["test"], framework) {
+			return framework
+		}
+	}
+	if len(installed) == 0 {
+		return ""
+	}
+	return installed[0]
+}
+
+// packageManager returns the install command and the cache of the package manager of the lockfile
+func packageManager(repoDir string) (string, core.NamedCache) {
+	switch {
+	case fileExists(filepath.Join(repoDir, "yarn.lock")):
+		return "yarn install --frozen-lockfile", lockfileCache("yarn", "yarn.lock", "~/.cache/yarn")
+	case fileExists(filepath.Join(repoDir, "pnpm-lock.yaml")):
+		return "pnpm install --frozen-lockfile", lockfileCache("pnpm", "pnpm-lock.yaml", "~/.local/share/pnpm/store")
+	case fileExists(filepath.Join(repoDir, "package-lock.json")):
+		return "npm ci", lockfileCache("npm", "package-lock.json", "~/.npm")
+	default:
+		return "npm install", lockfileCache("npm", packageJSON, "node_modules")
+	}
+}
+
+func lockfileCache(name, lockfile, path string) core.NamedCache {
+	return core.NamedCache{
+		Name:        name,
+		Key:         fmt.Sprintf("%s-{{ checksum %q }}", name, lockfile),
+		RestoreKeys: []string{name + "-"},
+		Paths:       []string{path},
+	}
+}
+
+// nodeVersion returns the version of .nvmrc, or the first version of the node engine of package.json
+func nodeVersion(repoDir string, manifest *packageManifest) string {
+	if content, err := ioutil.ReadFile(filepath.Join(repoDir, ".nvmrc")); err == nil {
+		if version := versionRegex.FindString(string(content)); version != "" {
+			return version
+		}
+	}
+	return versionRegex.FindString(manifest.Engines.Node)
+}
+
+// goVersion returns the version of the go directive of go.mod
+func goVersion(repoDir string) string {
+	content, err := ioutil.ReadFile(filepath.Join(repoDir, goMod))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "go" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// javaVersion returns the java version of the properties of pom.xml
+func javaVersion(repoDir string) string {
+	content, err := ioutil.ReadFile(filepath.Join(repoDir, pomXML))
+	if err != nil {
+		return ""
+	}
+	pom := new(pomManifest)
+	if err := xml.Unmarshal(content, pom); err != nil {
+		return ""
+	}
+	for _, version := range []string{pom.Properties.JavaVersion, pom.Properties.CompilerRelease,
+		pom.Properties.CompilerSource} {
+		if version = versionRegex.FindString(version); version != "" {
+			// 1.8 is java 8
+			return strings.TrimPrefix(version, "1.")
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
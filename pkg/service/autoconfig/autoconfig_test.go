@@ -0,0 +1,83 @@
+package autoconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	d := New(nil, logger)
+	repo := t.TempDir()
+	writeFiles(t, repo, map[string]string{
+		packageJSON: `{"scripts": {"test": "mocha"}, "devDependencies": {"jest": "^27.0.0", "mocha": "^9.0.0"},
+			"engines": {"node": ">=16.13"}}`,
+		"package-lock.json": "{}",
+		".mocharc.yml":      "spec: test",
+		goMod:               "module example.com/api\n\ngo 1.17\n",
+		pomXML: `<project><properties><maven.compiler.source>1.8</maven.compiler.source></properties>
+			</project>`,
+	})
+	body, err := d.Detect(context.Background(), repo)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(body), configHeader))
+
+	config := new(Config)
+	assert.Nil(t, yaml.Unmarshal(body, config))
+	assert.Equal(t, &Config{
+		Framework:  "mocha",
+		Tier:       core.Small,
+		ConfigFile: ".mocharc.yml",
+		PreRun:     &Run{Commands: []string{"npm ci"}},
+		PreMerge:   &Merge{Patterns: testPatterns["mocha"]},
+		PostMerge:  &Merge{Patterns: testPatterns["mocha"]},
+		Caches: []core.NamedCache{{Name: "npm", Key: `npm-{{ checksum "package-lock.json" }}`,
+			RestoreKeys: []string{"npm-"}, Paths: []string{"~/.npm"}}},
+		Toolchains: &Toolchains{Node: "16.13", Go: "1.17", Java: "8"},
+	}, config)
+}
+
+func TestDetectUnsupported(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	d := New(nil, logger)
+	repo := t.TempDir()
+	_, err = d.Detect(context.Background(), repo)
+	assert.NotNil(t, err)
+
+	writeFiles(t, repo, map[string]string{requirements: "pytest==7.0.0\n"})
+	_, err = d.Detect(context.Background(), repo)
+	assert.EqualError(t, err, "only the jest, mocha, jasmine frameworks are supported, package.json not found in "+
+		"the repository")
+
+	writeFiles(t, repo, map[string]string{packageJSON: `{"devDependencies": {"ava": "^4.0.0"}}`})
+	_, err = d.Detect(context.Background(), repo)
+	assert.EqualError(t, err, "none of the jest, mocha, jasmine frameworks is a dependency in package.json")
+}
+
+func TestPackageManager(t *testing.T) {
+	repo := t.TempDir()
+	install, cache := packageManager(repo)
+	assert.Equal(t, "npm install", install)
+	assert.Equal(t, []string{"node_modules"}, cache.Paths)
+
+	writeFiles(t, repo, map[string]string{"yarn.lock": ""})
+	install, cache = packageManager(repo)
+	assert.Equal(t, "yarn install --frozen-lockfile", install)
+	assert.Equal(t, `yarn-{{ checksum "yarn.lock" }}`, cache.Key)
+}
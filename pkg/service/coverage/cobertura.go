@@ -0,0 +1,103 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// conditionCoverageRegex matches cobertura condition-coverage attribute eg. `50% (1/2)`
+var conditionCoverageRegex = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+type coberturaReport struct {
+	Sources  []string           `xml:"sources>source"`
+	Packages []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Classes []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Filename string            `xml:"filename,attr"`
+	Methods  []coberturaMethod `xml:"methods>method"`
+	Lines    []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name      string          `xml:"name,attr"`
+	Signature string          `xml:"signature,attr"`
+	Lines     []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+// parseCobertura parses cobertura xml reports, Ref: https://github.com/cobertura/web/blob/master/htdocs/xml/coverage-04.dtd
+func parseCobertura(path string, paths *pathNormalizer) (coverageReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cr coberturaReport
+	if err := xml.NewDecoder(f).Decode(&cr); err != nil {
+		return nil, err
+	}
+
+	report := make(coverageReport)
+	for _, pkg := range cr.Packages {
+		for _, class := range pkg.Classes {
+			fc := report.file(paths.normalize(resolveCoberturaPath(class.Filename, cr.Sources)))
+			for _, line := range class.Lines {
+				fc.Lines[line.Number] += line.Hits
+				if !line.Branch {
+					continue
+				}
+				matches := conditionCoverageRegex.FindStringSubmatch(line.ConditionCoverage)
+				if len(matches) != 3 {
+					continue
+				}
+				covered, _ := strconv.Atoi(matches[1])
+				total, _ := strconv.Atoi(matches[2])
+				for i := 0; i < total; i++ {
+					id := strconv.Itoa(line.Number) + ":" + strconv.Itoa(i)
+					if i < covered {
+						fc.Branches[id]++
+					} else {
+						fc.Branches[id] += 0
+					}
+				}
+			}
+			for _, method := range class.Methods {
+				hits := 0
+				for _, line := range method.Lines {
+					hits += line.Hits
+				}
+				fc.Functions[method.Name+method.Signature] += hits
+			}
+		}
+	}
+	return report, nil
+}
+
+// resolveCoberturaPath returns the path of filename relative to the first source directory where it exists
+func resolveCoberturaPath(filename string, sources []string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	for _, source := range sources {
+		candidate := filepath.Join(source, filename)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return filename
+}
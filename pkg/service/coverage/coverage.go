@@ -18,7 +18,9 @@ import (
 
 	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/LambdaTest/synapse/pkg/fileutils"
@@ -31,6 +33,7 @@ const (
 	compressedFileName   = "coverage-files.tzst"
 	mainfestJSONFileName = "manifest.json"
 	coverageFilePath     = "/scripts/mapCoverage.js"
+	coverageEndpoint     = "/coverage"
 )
 
 type codeCoverageService struct {
@@ -38,15 +41,25 @@ type codeCoverageService struct {
 	execManager          core.ExecutionManager
 	codeCoveragParentDir string
 	azureClient          core.AzureClient
-	zstd                 core.ZstdCompressor
+	diffManager          core.DiffManager
+	annotator            core.Annotator
+	compressor           core.Compressor
+	resultStore          core.ResultStore
+	secretParser         core.SecretParser
 	httpClient           http.Client
-	endpoint             string
+	localRunner          bool
+	htmlReport           bool
+	htmlReportDir        string
 }
 
 // New returns a new instance of CoverageService
 func New(execManager core.ExecutionManager,
 	azureClient core.AzureClient,
-	zstd core.ZstdCompressor,
+	compressor core.Compressor,
+	diffManager core.DiffManager,
+	annotator core.Annotator,
+	resultStore core.ResultStore,
+	secretParser core.SecretParser,
 	cfg *config.NucleusConfig,
 	logger lumber.Logger) (core.CoverageService, error) {
 	// if coverage mode not enabled do not initialize the service
@@ -60,30 +73,29 @@ func New(execManager core.ExecutionManager,
 		logger:               logger,
 		execManager:          execManager,
 		azureClient:          azureClient,
-		zstd:                 zstd,
+		diffManager:          diffManager,
+		annotator:            annotator,
+		compressor:           compressor,
+		resultStore:          resultStore,
+		secretParser:         secretParser,
+		localRunner:          cfg.LocalRunner,
+		htmlReport:           cfg.CoverageReport,
+		htmlReportDir:        global.CoverageReportDir,
 		codeCoveragParentDir: global.CodeCoveragParentDir,
-		endpoint:             global.NeuronHost + "/coverage",
-		httpClient: http.Client{
-			Timeout: global.DefaultHTTPTimeout,
-		}}, nil
+		httpClient:           requestutils.NewResilientClient(global.DefaultHTTPTimeout),
+	}, nil
 
 }
 
-//mergeCodeCoverageFiles merge all the coverage.json into single entity
+//mergeCodeCoverageFiles merge all the coverage files into single entity
 func (c *codeCoverageService) mergeCodeCoverageFiles(ctx context.Context, commitDir, coverageManifestPath string, threshold bool) error {
 	if _, err := os.Stat(commitDir); os.IsNotExist(err) {
 		c.logger.Errorf("coverage files not found, skipping merge")
 		return nil
 	}
 
-	coverageFiles := make([]string, 0)
-	if err := filepath.WalkDir(commitDir, func(path string, d fs.DirEntry, err error) error {
-		//add all individual coverage json files
-		if d.Name() == coverageJSONFileName {
-			coverageFiles = append(coverageFiles, path)
-		}
-		return nil
-	}); err != nil {
+	coverageFiles, err := findCoverageFiles(commitDir)
+	if err != nil {
 		return err
 	}
 
@@ -91,18 +103,47 @@ func (c *codeCoverageService) mergeCodeCoverageFiles(ctx context.Context, commit
 		return errors.New("no coverage dirs found")
 	}
 
-	args := []string{"/scripts/node_modules/.bin/babel-node", coverageFilePath,
-		"--commitDir", commitDir,
-		"--coverageFiles", "'" + strings.Join(coverageFiles, " ") + "'"}
-	if threshold {
-		args = append(args, "--coverageManifest", coverageManifestPath)
+	// istanbul coverage is merged by the node script if available, as it applies the source maps
+	if istanbulFiles, ok := coverageFiles[formatIstanbul]; ok && c.isNodeMergerAvailable() {
+		args := []string{"/scripts/node_modules/.bin/babel-node", coverageFilePath,
+			"--commitDir", commitDir,
+			"--coverageFiles", "'" + strings.Join(istanbulFiles, " ") + "'"}
+		if threshold {
+			args = append(args, "--coverageManifest", coverageManifestPath)
+		}
+		if err := c.execManager.ExecuteInternalCommands(ctx, core.CoverageMerge, args, "", nil, nil); err != nil {
+			return err
+		}
+		delete(coverageFiles, formatIstanbul)
+	}
+	if len(coverageFiles) == 0 {
+		return nil
 	}
-	return c.execManager.ExecuteInternalCommands(ctx, core.CoverageMerge, args, "", nil, nil)
+
+	c.logger.Debugf("merging coverage files %+v", coverageFiles)
+	report, err := parseCoverageFiles(global.RepoDir, coverageFiles)
+	if err != nil {
+		c.logger.Errorf("failed to parse coverage files, error: %v", err)
+		return err
+	}
+	// merge with the summary written by node script, if any
+	return mergeSummaryFile(filepath.Join(commitDir, mergedcoverageJSON), report.summarize())
 }
 
-// MergeAndUpload compress the file and upload in azure blob
-func (c *codeCoverageService) MergeAndUpload(ctx context.Context, payload *core.Payload) error {
+func (c *codeCoverageService) isNodeMergerAvailable() bool {
+	_, err := os.Stat(coverageFilePath)
+	return err == nil
+}
+
+// MergeAndUpload compress the file and upload in azure blob.
+// If coverage thresholds are configured, the build target commit is gated on them
+// and a *errs.CoverageThresholdError is returned after the coverage data is sent.
+// If coverage upload is configured, the coverage of the build target commit is uploaded to codecov or coveralls.
+// If coverage comparison is configured, the changes of the coverage of the build target commit against the base
+// commit are reported along with the coverage data, in the html report and in the check of the commit.
+func (c *codeCoverageService) MergeAndUpload(ctx context.Context, payload *core.Payload, cloneToken string) error {
 	var parentCommitDir, repoDir string
+	var baseCoverage *parentCommitCoverage
 	var g errgroup.Group
 	// change variable name
 	repoDir = filepath.Join(c.codeCoveragParentDir, payload.OrgID, payload.RepoID)
@@ -118,8 +159,10 @@ func (c *codeCoverageService) MergeAndUpload(ctx context.Context, payload *core.
 			return err
 		}
 		parentCommitDir = filepath.Join(repoDir, coverage.ParentCommit)
+		baseCoverage = &coverage
 	}
 	coveragePayload := make([]coverageData, 0, len(payload.Commits))
+	var thresholdErr *errs.CoverageThresholdError
 
 	for _, commit := range payload.Commits {
 		commitDir := filepath.Join(repoDir, commit.Sha)
@@ -154,7 +197,7 @@ func (c *codeCoverageService) MergeAndUpload(ctx context.Context, payload *core.
 		c.logger.Debugf("compressed file name %v", compressedFileName)
 
 		g.Go(func() error {
-			if err := c.zstd.Compress(ctx, compressedFileName, false, repoDir, commit.Sha); err != nil {
+			if err := c.compressor.Compress(ctx, compressedFileName, false, repoDir, commit.Sha); err != nil {
 				c.logger.Errorf("failed to compress coverage files %v", err)
 				return err
 			}
@@ -187,11 +230,138 @@ func (c *codeCoverageService) MergeAndUpload(ctx context.Context, payload *core.
 			return err
 		}
 		blobURL = strings.TrimSuffix(blobURL, fmt.Sprintf("/%s", mergedcoverageJSON))
-		coveragePayload = append(coveragePayload, coverageData{BuildID: payload.BuildID, RepoID: payload.RepoID, CommitID: commit.Sha, BlobLink: blobURL, TotalCoverage: totalCoverage})
+		data := coverageData{BuildID: payload.BuildID, RepoID: payload.RepoID, CommitID: commit.Sha, BlobLink: blobURL, TotalCoverage: totalCoverage}
+		gated := c.isGatedCommit(payload, commit.Sha)
+		if manifestPayload.CoverageCompare && gated {
+			if summaries, err := readSummaryFile(filepath.Join(commitDir, mergedcoverageJSON)); err != nil {
+				c.logger.Errorf("failed to read coverage summary of commit %s, error: %v", commit.Sha, err)
+			} else {
+				data.CoverageDelta = c.compareCoverage(ctx, baseCoverage, summaries)
+			}
+		}
+		if c.htmlReport {
+			data.ReportLink = c.publishHTMLReport(ctx, payload, repoBlobPath, commitDir, commit.Sha, data.CoverageDelta)
+		}
+		if thresholdEnabled && gated {
+			if err := c.gateCoverage(ctx, payload, cloneToken, commitDir, manifestPayload.CoverageThreshold, &data); err != nil {
+				return err
+			}
+			if len(data.ThresholdViolations) > 0 {
+				thresholdErr = &errs.CoverageThresholdError{CommitID: commit.Sha, Violations: data.ThresholdViolations}
+			}
+		}
+		if (thresholdEnabled || data.CoverageDelta != nil) && gated {
+			if err := c.annotator.Publish(ctx, payload, cloneToken, coverageAnnotation(&data)); err != nil {
+				c.logger.Errorf("failed to publish the check of the coverage, error: %v", err)
+			}
+		}
+		if manifestPayload.CoverageUpload != nil && gated {
+			c.uploadCoverage(ctx, payload, cloneToken, commitDir, commit.Sha, manifestPayload.CoverageUpload)
+		}
+		c.saveCoverage(ctx, &data)
+		coveragePayload = append(coveragePayload, data)
 		//current commit dir becomes parent for next commit
 		parentCommitDir = commitDir
 	}
-	return c.sendCoverageData(coveragePayload)
+	if err := c.sendCoverageData(coveragePayload); err != nil {
+		if !c.localRunner {
+			return err
+		}
+		c.logger.Warnf("failed to send coverage data, the coverage is only kept in the local results store: %v", err)
+	}
+	if thresholdErr != nil {
+		return thresholdErr
+	}
+	return nil
+}
+
+// publishHTMLReport generates the html report of the merged coverage of the commit and uploads it, it returns
+// the URL of the report or an empty string if it failed, as the report is not required by neuron
+func (c *codeCoverageService) publishHTMLReport(ctx context.Context,
+	payload *core.Payload,
+	blobPath, commitDir, commitID string,
+	delta *coverageDelta) string {
+	summaries, err := readSummaryFile(filepath.Join(commitDir, mergedcoverageJSON))
+	if err != nil {
+		c.logger.Errorf("failed to read coverage summary of commit %s, error: %v", commitID, err)
+		return ""
+	}
+	reportDir := filepath.Join(c.htmlReportDir, payload.OrgID, payload.RepoID, commitID)
+	if err := os.RemoveAll(reportDir); err != nil {
+		c.logger.Errorf("failed to remove the previous html report of commit %s, error: %v", commitID, err)
+		return ""
+	}
+	if err := writeHTMLReport(summaries, commitID, delta, newPathNormalizer(global.RepoDir), reportDir); err != nil {
+		c.logger.Errorf("failed to generate the html report of commit %s, error: %v", commitID, err)
+		return ""
+	}
+	reportURL, err := c.uploadHTMLReport(ctx, blobPath, commitID, reportDir)
+	if err != nil {
+		c.logger.Errorf("failed to upload the html report of commit %s, error: %v", commitID, err)
+		return ""
+	}
+	c.logger.Infof("coverage report of commit %s: %s", commitID, reportURL)
+	return reportURL
+}
+
+// saveCoverage keeps the total coverage of the commit in the local results store, the failures are only logged
+func (c *codeCoverageService) saveCoverage(ctx context.Context, data *coverageData) {
+	total := coverageSummary{}
+	if err := json.Unmarshal(data.TotalCoverage, &total); err != nil {
+		c.logger.Errorf("failed to parse the total coverage of commit %s, error: %v", data.CommitID, err)
+		return
+	}
+	summary := core.CoverageSummary{
+		BuildID:    data.BuildID,
+		RepoID:     data.RepoID,
+		CommitID:   data.CommitID,
+		Lines:      total.Lines.Pct,
+		Statements: total.Statements.Pct,
+		Functions:  total.Functions.Pct,
+		Branches:   total.Branches.Pct,
+	}
+	if data.DiffCoverage != nil {
+		summary.DiffCoverage = &data.DiffCoverage.Pct
+	}
+	if err := c.resultStore.SaveCoverage(ctx, &summary); err != nil {
+		c.logger.Errorf("failed to save the coverage of commit %s to the results store, error: %v", data.CommitID, err)
+	}
+}
+
+// isGatedCommit checks if the thresholds are to be enforced for the commit
+func (c *codeCoverageService) isGatedCommit(payload *core.Payload, commitID string) bool {
+	if payload.BuildTargetCommit != "" {
+		return commitID == payload.BuildTargetCommit
+	}
+	return len(payload.Commits) > 0 && payload.Commits[len(payload.Commits)-1].Sha == commitID
+}
+
+// gateCoverage checks the merged coverage of the commit against the threshold
+func (c *codeCoverageService) gateCoverage(ctx context.Context,
+	payload *core.Payload,
+	cloneToken, commitDir string,
+	threshold *core.CoverageThreshold,
+	data *coverageData) error {
+	summaries, err := readSummaryFile(filepath.Join(commitDir, mergedcoverageJSON))
+	if err != nil {
+		c.logger.Errorf("failed to read coverage summary, error: %v", err)
+		return err
+	}
+	if threshold.Diff > 0 {
+		// coverage mode does not populate the diff range, use the build commits
+		diffPayload := *payload
+		diffPayload.BaseCommit = payload.BuildBaseCommit
+		diffPayload.TargetCommit = payload.BuildTargetCommit
+		changedLines, err := c.diffManager.GetChangedLines(ctx, &diffPayload, cloneToken)
+		if err != nil {
+			c.logger.Errorf("failed to get changed lines, error: %v", err)
+			return err
+		}
+		data.DiffCoverage = computeDiffCoverage(summaries, changedLines, newPathNormalizer(global.RepoDir))
+		c.logger.Infof("diff coverage %.2f%% (%d/%d lines)", data.DiffCoverage.Pct, data.DiffCoverage.Covered, data.DiffCoverage.Total)
+	}
+	data.ThresholdViolations = checkThresholds(summaries, threshold, data.DiffCoverage)
+	return nil
 }
 
 func (c *codeCoverageService) uploadFile(ctx context.Context, blobPath, filename, commitID string) (blobURL string, err error) {
@@ -231,21 +401,13 @@ func (c *codeCoverageService) downloadAndDecompressParentCommitDir(ctx context.C
 		return err
 	}
 	u.Path = path.Join(u.Path, compressedFileName)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.httpClient.Do(req)
+	// download through the blob store, so that local and s3 storage are supported as well
+	body, err := c.azureClient.FindUsingSASUrl(ctx, u.String())
 	if err != nil {
-		c.logger.Errorf("error while making http request %v", err)
+		c.logger.Errorf("error while downloading parent commit coverage from %s, error: %v", u.Redacted(), err)
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("non 200 status while cloning from endpoint %s, status %d ", u.String(), resp.StatusCode)
-	}
+	defer body.Close()
 
 	parentCommitFilePath := filepath.Join(repoDir, coverage.ParentCommit+".tzst")
 	c.logger.Debugf("parent commit file path %s", parentCommitFilePath)
@@ -255,17 +417,17 @@ func (c *codeCoverageService) downloadAndDecompressParentCommitDir(ctx context.C
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	if _, err := io.Copy(out, body); err != nil {
 		return err
 	}
 
-	// decompress the file in temp directory as we cannot decompress inside azure file volume
-	if err := c.zstd.Decompress(ctx, parentCommitFilePath, false, os.TempDir()); err != nil {
+	// decompress the file in the scratch directory as we cannot decompress inside azure file volume
+	if err := c.compressor.Decompress(ctx, parentCommitFilePath, false, global.ScratchDir); err != nil {
 		c.logger.Errorf("failed to decompress parent commit directory %v", err)
 		return err
 	}
 
-	srcPath := filepath.Join(os.TempDir(), coverage.ParentCommit)
+	srcPath := filepath.Join(global.ScratchDir, coverage.ParentCommit)
 	destPath := filepath.Join(repoDir, coverage.ParentCommit)
 	// copy the coverage directories to shared volume,
 	// chmod is not allowed inside azure file volume so that is skipped Ref: https://stackoverflow.com/questions/58301985/permissions-on-azure-file
@@ -314,9 +476,11 @@ func (c *codeCoverageService) copyFromParentCommitDir(parentCommitDir, commitDir
 }
 
 func (c *codeCoverageService) getParentCommitCoverageDir(repoID, commitID string) (coverage parentCommitCoverage, err error) {
-	u, err := url.Parse(c.endpoint)
+	// the endpoint is built for each request as the neuron host is changed by a config reload
+	endpoint := global.NeuronURL(coverageEndpoint)
+	u, err := url.Parse(endpoint)
 	if err != nil {
-		c.logger.Errorf("error while parsing endpoint %s, %v", c.endpoint, err)
+		c.logger.Errorf("error while parsing endpoint %s, %v", endpoint, err)
 		return coverage, err
 	}
 	q := u.Query()
@@ -362,7 +526,7 @@ func (c *codeCoverageService) sendCoverageData(payload []coverageData) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest(http.MethodPost, global.NeuronURL(coverageEndpoint), bytes.NewBuffer(reqBody))
 	if err != nil {
 		c.logger.Errorf("failed to create new request %v", err)
 		return err
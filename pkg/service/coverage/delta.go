@@ -0,0 +1,134 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// fileDelta is the change of the line coverage of a file against the base commit
+type fileDelta struct {
+	File  string  `json:"file"`
+	Base  float64 `json:"base"`
+	Head  float64 `json:"head"`
+	Delta float64 `json:"delta"`
+}
+
+func (d *fileDelta) String() string {
+	return fmt.Sprintf("`%s` %.1f%% → %.1f%% (%+.1f%%)", d.File, d.Base, d.Head, d.Delta)
+}
+
+// coverageDelta is the comparison of the line coverage of the commit with the coverage of the base commit
+type coverageDelta struct {
+	BaseCommit string  `json:"base_commit"`
+	Total      float64 `json:"total"`
+	// Improved and Regressed are sorted by the magnitude of the change, the files which are only covered
+	// in one of the commits are not compared
+	Improved  []fileDelta `json:"improved,omitempty"`
+	Regressed []fileDelta `json:"regressed,omitempty"`
+}
+
+// files returns the change of each compared file keyed by its path
+func (d *coverageDelta) files() map[string]float64 {
+	files := make(map[string]float64, len(d.Improved)+len(d.Regressed))
+	for _, fd := range append(append([]fileDelta(nil), d.Improved...), d.Regressed...) {
+		files[fd.File] = fd.Delta
+	}
+	return files
+}
+
+// downloadBaseSummary downloads the merged coverage summary stored for the base commit
+func (c *codeCoverageService) downloadBaseSummary(ctx context.Context, base *parentCommitCoverage) (map[string]*coverageSummary, error) {
+	u, err := url.Parse(base.Bloblink)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, mergedcoverageJSON)
+	body, err := c.azureClient.FindUsingSASUrl(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	summaries := make(map[string]*coverageSummary)
+	if err := json.NewDecoder(body).Decode(&summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// compareCoverage compares the merged coverage of the commit with the coverage of the base commit, it returns nil
+// if the coverage of the base commit is not available as the comparison is not required by the build
+func (c *codeCoverageService) compareCoverage(ctx context.Context, base *parentCommitCoverage, summaries map[string]*coverageSummary) *coverageDelta {
+	if base == nil {
+		c.logger.Infof("coverage of the base commit not found, skipping the comparison")
+		return nil
+	}
+	baseSummaries, err := c.downloadBaseSummary(ctx, base)
+	if err != nil {
+		c.logger.Errorf("failed to download the coverage of the base commit %s, error: %v", base.ParentCommit, err)
+		return nil
+	}
+	delta := computeCoverageDelta(baseSummaries, summaries, newPathNormalizer(global.RepoDir))
+	delta.BaseCommit = base.ParentCommit
+	c.logger.Infof("coverage changed by %+.1f%% against commit %s, %d files improved and %d regressed",
+		delta.Total, base.ParentCommit, len(delta.Improved), len(delta.Regressed))
+	return delta
+}
+
+// computeCoverageDelta returns the change of the line coverage of the files covered in both base and head
+func computeCoverageDelta(base, head map[string]*coverageSummary, paths *pathNormalizer) *coverageDelta {
+	delta := new(coverageDelta)
+	if baseTotal, ok := base[totalCoverageKey]; ok {
+		if headTotal, ok := head[totalCoverageKey]; ok {
+			delta.Total = roundPct(headTotal.Lines.Pct - baseTotal.Lines.Pct)
+		}
+	}
+	baseFiles := normalizeSummaries(base, paths)
+	for file, h := range normalizeSummaries(head, paths) {
+		b, ok := baseFiles[file]
+		if !ok || b.Lines.Total == 0 || h.Lines.Total == 0 {
+			continue
+		}
+		fd := fileDelta{File: file, Base: b.Lines.Pct, Head: h.Lines.Pct, Delta: roundPct(h.Lines.Pct - b.Lines.Pct)}
+		switch {
+		case fd.Delta > 0:
+			delta.Improved = append(delta.Improved, fd)
+		case fd.Delta < 0:
+			delta.Regressed = append(delta.Regressed, fd)
+		}
+	}
+	sortDeltas(delta.Improved)
+	sortDeltas(delta.Regressed)
+	return delta
+}
+
+// normalizeSummaries returns the summaries of the files keyed by their path relative to the repo root
+func normalizeSummaries(summaries map[string]*coverageSummary, paths *pathNormalizer) map[string]*coverageSummary {
+	normalized := make(map[string]*coverageSummary, len(summaries))
+	for file, s := range summaries {
+		if file != totalCoverageKey {
+			normalized[paths.normalize(file)] = s
+		}
+	}
+	return normalized
+}
+
+func sortDeltas(deltas []fileDelta) {
+	sort.Slice(deltas, func(i, j int) bool {
+		if di, dj := math.Abs(deltas[i].Delta), math.Abs(deltas[j].Delta); di != dj {
+			return di > dj
+		}
+		return deltas[i].File < deltas[j].File
+	})
+}
+
+// roundPct rounds the difference of two percentages to one decimal, as the percentages themselves
+func roundPct(pct float64) float64 {
+	return math.Round(pct*10) / 10
+}
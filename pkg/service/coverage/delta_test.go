@@ -0,0 +1,65 @@
+package coverage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeCoverageDelta(t *testing.T) {
+	base := map[string]*coverageSummary{
+		"/home/nucleus/repo/src/a.js": {Lines: coverageMetric{Total: 10, Covered: 8, Pct: 80}},
+		"src/b.js":                    {Lines: coverageMetric{Total: 10, Covered: 5, Pct: 50}},
+		"src/c.js":                    {Lines: coverageMetric{Total: 3, Covered: 2, Pct: 66.6}},
+		"src/same.js":                 {Lines: coverageMetric{Total: 4, Covered: 4, Pct: 100}},
+		"src/removed.js":              {Lines: coverageMetric{Total: 4, Covered: 4, Pct: 100}},
+		totalCoverageKey:              {Lines: coverageMetric{Total: 31, Covered: 23, Pct: 74.1}},
+	}
+	head := map[string]*coverageSummary{
+		"src/a.js":    {Lines: coverageMetric{Total: 10, Covered: 6, Pct: 60}},
+		"src/b.js":    {Lines: coverageMetric{Total: 10, Covered: 9, Pct: 90}},
+		"src/c.js":    {Lines: coverageMetric{Total: 3, Covered: 3, Pct: 100}},
+		"src/same.js": {Lines: coverageMetric{Total: 4, Covered: 4, Pct: 100}},
+		// the new files are not compared
+		"src/added.js":   {Lines: coverageMetric{Total: 2, Covered: 0, Pct: 0}},
+		totalCoverageKey: {Lines: coverageMetric{Total: 29, Covered: 22, Pct: 75.8}},
+	}
+	delta := computeCoverageDelta(base, head, newPathNormalizer(repoDir))
+	assert.Equal(t, 1.7, delta.Total)
+	assert.Equal(t, []fileDelta{
+		{File: "src/b.js", Base: 50, Head: 90, Delta: 40},
+		{File: "src/c.js", Base: 66.6, Head: 100, Delta: 33.4},
+	}, delta.Improved)
+	assert.Equal(t, []fileDelta{{File: "src/a.js", Base: 80, Head: 60, Delta: -20}}, delta.Regressed)
+	assert.Equal(t, map[string]float64{"src/a.js": -20, "src/b.js": 40, "src/c.js": 33.4}, delta.files())
+	assert.Equal(t, "`src/a.js` 80.0% → 60.0% (-20.0%)", delta.Regressed[0].String())
+}
+
+func TestCoverageDeltaReport(t *testing.T) {
+	delta := &coverageDelta{
+		BaseCommit: "base123",
+		Total:      -2.5,
+		Regressed:  []fileDelta{{File: "src/a.js", Base: 80, Head: 60, Delta: -20}},
+	}
+	annotation := coverageAnnotation(&coverageData{CoverageDelta: delta})
+	assert.Equal(t, -2.5, *annotation.CoverageDelta)
+	assert.Equal(t, []string{"`src/a.js` 80.0% → 60.0% (-20.0%)"}, annotation.Regressed)
+	assert.Empty(t, annotation.Improved)
+	assert.False(t, annotation.Failing())
+
+	summaries := map[string]*coverageSummary{
+		"src/a.js":       {Lines: coverageMetric{Total: 10, Covered: 6, Pct: 60}},
+		"src/new.js":     {Lines: coverageMetric{Total: 2, Covered: 2, Pct: 100}},
+		totalCoverageKey: {Lines: coverageMetric{Total: 12, Covered: 8, Pct: 66.6}},
+	}
+	out := t.TempDir()
+	assert.Nil(t, writeHTMLReport(summaries, "abc123", delta, newPathNormalizer(repoDir), out))
+	index, err := ioutil.ReadFile(filepath.Join(out, htmlReportIndex))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), "Line coverage -2.5% against base123, 0 files improved and 1 regressed")
+	assert.Contains(t, string(index), `<td class="medium">60% (6/10)</td>`)
+	assert.Contains(t, string(index), "<td>-20.0%</td>")
+	assert.Contains(t, string(index), "<th>-2.5%</th>")
+}
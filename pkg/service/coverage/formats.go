@@ -0,0 +1,125 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// coverageFormat is the format of a coverage report file
+type coverageFormat string
+
+const (
+	formatIstanbul  coverageFormat = "istanbul"
+	formatLcov      coverageFormat = "lcov"
+	formatCobertura coverageFormat = "cobertura"
+	formatGo        coverageFormat = "gocoverprofile"
+)
+
+const totalCoverageKey = "total"
+
+// coverageParser parses a coverage file into the unified report, paths are made relative to repoDir
+type coverageParser func(path string, paths *pathNormalizer) (coverageReport, error)
+
+var coverageParsers = map[coverageFormat]coverageParser{
+	formatIstanbul:  parseIstanbul,
+	formatLcov:      parseLcov,
+	formatCobertura: parseCobertura,
+	formatGo:        parseGoCoverProfile,
+}
+
+// detectFormat finds the coverage format using the file name, returns an empty format if unknown
+func detectFormat(name string) coverageFormat {
+	switch {
+	case name == coverageJSONFileName:
+		return formatIstanbul
+	case name == "lcov.info" || strings.HasSuffix(name, ".lcov"):
+		return formatLcov
+	case name == "cobertura-coverage.xml" || name == "cobertura.xml" || name == "coverage.xml":
+		return formatCobertura
+	case name == "coverage.out" || name == "cover.out" || strings.HasSuffix(name, ".coverprofile"):
+		return formatGo
+	default:
+		return ""
+	}
+}
+
+// findCoverageFiles returns all coverage files in dir grouped by format
+func findCoverageFiles(dir string) (map[coverageFormat][]string, error) {
+	files := make(map[coverageFormat][]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if format := detectFormat(d.Name()); format != "" {
+			files[format] = append(files[format], path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// parseCoverageFiles parses and merges the coverage files into a single report
+func parseCoverageFiles(repoDir string, files map[coverageFormat][]string) (coverageReport, error) {
+	paths := newPathNormalizer(repoDir)
+	report := make(coverageReport)
+	for format, formatFiles := range files {
+		parse, ok := coverageParsers[format]
+		if !ok {
+			return nil, fmt.Errorf("unsupported coverage format %s", format)
+		}
+		for _, file := range formatFiles {
+			r, err := parse(file, paths)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s coverage file %s: %w", format, file, err)
+			}
+			report.merge(r)
+		}
+	}
+	return report, nil
+}
+
+// pathNormalizer converts the source paths found in coverage reports to paths relative to the repo root
+type pathNormalizer struct {
+	repoDir    string
+	modulePath string
+}
+
+func newPathNormalizer(repoDir string) *pathNormalizer {
+	return &pathNormalizer{repoDir: repoDir, modulePath: readGoModulePath(repoDir)}
+}
+
+func (p *pathNormalizer) normalize(path string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if rel, err := filepath.Rel(p.repoDir, path); err == nil && filepath.IsAbs(path) && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	// go coverprofiles use import paths
+	if p.modulePath != "" && strings.HasPrefix(path, p.modulePath+"/") {
+		return strings.TrimPrefix(path, p.modulePath+"/")
+	}
+	return strings.TrimPrefix(path, "./")
+}
+
+// readGoModulePath returns the module path declared in go.mod of repoDir, if any
+func readGoModulePath(repoDir string) string {
+	f, err := os.Open(filepath.Join(repoDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package coverage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const repoDir = "/home/nucleus/repo"
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", name, err)
+	}
+	return path
+}
+
+func TestParseLcov(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "lcov.info", `TN:
+SF:/home/nucleus/repo/src/sum.py
+FN:1,sum
+FNDA:2,sum
+FN:5,unused
+FNDA:0,unused
+DA:1,2
+DA:2,2
+DA:5,0
+BRDA:2,0,0,1
+BRDA:2,0,1,-
+end_of_record
+`)
+	report, err := parseLcov(path, newPathNormalizer(repoDir))
+	assert.Nil(t, err)
+
+	s := report["src/sum.py"].summary()
+	assert.Equal(t, coverageMetric{Total: 3, Covered: 2, Pct: 66.6}, s.Lines)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Functions)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Branches)
+	assert.Equal(t, "5", s.UncoveredLines)
+}
+
+func TestParseGoCoverProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "go.mod", "module github.com/org/repo\n")
+	path := writeTestFile(t, dir, "coverage.out", `mode: set
+github.com/org/repo/pkg/sum.go:3.20,5.2 2 1
+github.com/org/repo/pkg/sum.go:7.20,9.2 1 0
+`)
+	report, err := parseGoCoverProfile(path, newPathNormalizer(dir))
+	assert.Nil(t, err)
+
+	s := report["pkg/sum.go"].summary()
+	assert.Equal(t, coverageMetric{Total: 6, Covered: 3, Pct: 50}, s.Lines)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Statements)
+	assert.Equal(t, "7-9", s.UncoveredLines)
+}
+
+func TestParseCobertura(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "cobertura.xml", `<?xml version="1.0" ?>
+<coverage>
+	<sources><source>/home/nucleus/repo</source></sources>
+	<packages>
+		<package name="src">
+			<classes>
+				<class name="Sum" filename="/home/nucleus/repo/src/Sum.java">
+					<methods>
+						<method name="sum" signature="(II)I">
+							<lines><line number="3" hits="4"/></lines>
+						</method>
+					</methods>
+					<lines>
+						<line number="3" hits="4"/>
+						<line number="4" hits="4" branch="true" condition-coverage="50% (1/2)"/>
+						<line number="6" hits="0"/>
+					</lines>
+				</class>
+			</classes>
+		</package>
+	</packages>
+</coverage>`)
+	report, err := parseCobertura(path, newPathNormalizer(repoDir))
+	assert.Nil(t, err)
+
+	s := report["src/Sum.java"].summary()
+	assert.Equal(t, coverageMetric{Total: 3, Covered: 2, Pct: 66.6}, s.Lines)
+	assert.Equal(t, coverageMetric{Total: 1, Covered: 1, Pct: 100}, s.Functions)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Branches)
+}
+
+func TestParseIstanbul(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), coverageJSONFileName, `{
+	"/home/nucleus/repo/src/sum.js": {
+		"path": "/home/nucleus/repo/src/sum.js",
+		"statementMap": {"0": {"start": {"line": 1}, "end": {"line": 1}}, "1": {"start": {"line": 2}, "end": {"line": 2}}},
+		"fnMap": {"0": {"name": "sum", "loc": {"start": {"line": 1}, "end": {"line": 3}}}},
+		"branchMap": {},
+		"s": {"0": 1, "1": 0},
+		"f": {"0": 1},
+		"b": {"0": [1, 0]}
+	}
+}`)
+	report, err := parseIstanbul(path, newPathNormalizer(repoDir))
+	assert.Nil(t, err)
+
+	s := report["src/sum.js"].summary()
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Lines)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Statements)
+	assert.Equal(t, coverageMetric{Total: 1, Covered: 1, Pct: 100}, s.Functions)
+	assert.Equal(t, coverageMetric{Total: 2, Covered: 1, Pct: 50}, s.Branches)
+}
+
+func TestMergeReports(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "lcov.info", "SF:src/a.py\nDA:1,1\nDA:2,0\nend_of_record\n")
+	writeTestFile(t, dir, "coverage.out", "mode: set\nsrc/b.go:1.1,2.2 1 1\n")
+	files, err := findCoverageFiles(dir)
+	assert.Nil(t, err)
+
+	report, err := parseCoverageFiles(repoDir, files)
+	assert.Nil(t, err)
+
+	summaryPath := writeTestFile(t, dir, mergedcoverageJSON,
+		`{"total": {"lines": {"total": 2, "covered": 2, "skipped": 0, "pct": 100}},
+		"src/c.js": {"lines": {"total": 2, "covered": 2, "skipped": 0, "pct": 100}, "branches": {"total": 0, "covered": 0, "skipped": 0, "pct": "Unknown"}}}`)
+	assert.Nil(t, mergeSummaryFile(summaryPath, report.summarize()))
+
+	merged := make(map[string]*coverageSummary)
+	body, err := ioutil.ReadFile(summaryPath)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(body, &merged))
+	assert.Len(t, merged, 4)
+	assert.Equal(t, coverageMetric{Total: 6, Covered: 5, Pct: 83.3}, merged[totalCoverageKey].Lines)
+}
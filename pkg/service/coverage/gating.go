@@ -0,0 +1,150 @@
+package coverage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+)
+
+// Metric names used in threshold violations
+const (
+	metricLines      = "lines"
+	metricStatements = "statements"
+	metricFunctions  = "functions"
+	metricBranches   = "branches"
+	metricDiff       = "diff"
+)
+
+// readSummaryFile reads the json-summary written after merging coverage
+func readSummaryFile(path string) (map[string]*coverageSummary, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]*coverageSummary)
+	if err := json.Unmarshal(body, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// computeDiffCoverage returns the coverage of the changed lines. Changed lines which are
+// neither covered nor uncovered (eg. comments) are not executable and hence ignored.
+func computeDiffCoverage(summaries map[string]*coverageSummary, changedLines map[string][]int, paths *pathNormalizer) *coverageMetric {
+	normalized := normalizeSummaries(summaries, paths)
+	m := new(coverageMetric)
+	for file, lines := range changedLines {
+		s, ok := normalized[file]
+		if !ok {
+			continue
+		}
+		covered := parseLineRanges(s.CoveredLines)
+		uncovered := parseLineRanges(s.UncoveredLines)
+		for _, line := range lines {
+			if covered[line] {
+				m.Total++
+				m.Covered++
+			} else if uncovered[line] {
+				m.Total++
+			}
+		}
+	}
+	m.computePct()
+	return m
+}
+
+// checkThresholds returns the metrics in summaries which are below the threshold.
+// Thresholds with zero value are considered as not configured.
+func checkThresholds(summaries map[string]*coverageSummary, threshold *core.CoverageThreshold, diffCoverage *coverageMetric) []errs.ThresholdViolation {
+	violations := make([]errs.ThresholdViolation, 0)
+	check := func(file string, s *coverageSummary) {
+		for _, metric := range []struct {
+			name      string
+			threshold float64
+			actual    coverageMetric
+		}{
+			{metricLines, threshold.Lines, s.Lines},
+			{metricStatements, threshold.Statements, s.Statements},
+			{metricFunctions, threshold.Functions, s.Functions},
+			{metricBranches, threshold.Branches, s.Branches},
+		} {
+			if metric.threshold > 0 && metric.actual.Pct < metric.threshold {
+				violations = append(violations, errs.ThresholdViolation{
+					Metric:    metric.name,
+					File:      file,
+					Threshold: metric.threshold,
+					Actual:    metric.actual.Pct,
+				})
+			}
+		}
+	}
+
+	if threshold.PerFile {
+		for file, s := range summaries {
+			if file != totalCoverageKey {
+				check(file, s)
+			}
+		}
+	} else if total, ok := summaries[totalCoverageKey]; ok {
+		check("", total)
+	}
+
+	if diffCoverage != nil && threshold.Diff > 0 && diffCoverage.Pct < threshold.Diff {
+		violations = append(violations, errs.ThresholdViolation{
+			Metric:    metricDiff,
+			Threshold: threshold.Diff,
+			Actual:    diffCoverage.Pct,
+		})
+	}
+	return violations
+}
+
+// parseLineRanges parses comma separated line ranges eg. `1-3,7`
+func parseLineRanges(ranges string) map[int]bool {
+	lines := make(map[int]bool)
+	if ranges == "" {
+		return lines
+	}
+	for _, r := range strings.Split(ranges, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(r), "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		for l := start; l <= end; l++ {
+			lines[l] = true
+		}
+	}
+	return lines
+}
+
+// coverageAnnotation returns the annotation of the coverage of the gated commit and of its changes
+func coverageAnnotation(data *coverageData) *core.Annotation {
+	annotation := &core.Annotation{Name: "coverage"}
+	if data.DiffCoverage != nil {
+		annotation.DiffCoverage = &data.DiffCoverage.Pct
+	}
+	if data.CoverageDelta != nil {
+		annotation.CoverageDelta = &data.CoverageDelta.Total
+		for i := range data.CoverageDelta.Improved {
+			annotation.Improved = append(annotation.Improved, data.CoverageDelta.Improved[i].String())
+		}
+		for i := range data.CoverageDelta.Regressed {
+			annotation.Regressed = append(annotation.Regressed, data.CoverageDelta.Regressed[i].String())
+		}
+	}
+	for _, v := range data.ThresholdViolations {
+		annotation.Violations = append(annotation.Violations, v.String())
+	}
+	return annotation
+}
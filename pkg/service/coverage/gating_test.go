@@ -0,0 +1,86 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLineRanges(t *testing.T) {
+	lines := parseLineRanges("1-3,7, 9-9,x")
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 7: true, 9: true}, lines)
+	assert.Empty(t, parseLineRanges(""))
+}
+
+func TestComputeDiffCoverage(t *testing.T) {
+	summaries := map[string]*coverageSummary{
+		"/home/nucleus/repo/src/sum.js": {CoveredLines: "1-4", UncoveredLines: "6-7"},
+		"src/other.js":                  {CoveredLines: "1"},
+		totalCoverageKey:                {},
+	}
+	changed := map[string][]int{
+		// line 5 is not executable
+		"src/sum.js":     {2, 5, 6},
+		"src/other.js":   {1},
+		"src/missing.js": {1},
+	}
+	m := computeDiffCoverage(summaries, changed, newPathNormalizer(repoDir))
+	assert.Equal(t, 3, m.Total)
+	assert.Equal(t, 2, m.Covered)
+	assert.Equal(t, 66.6, m.Pct)
+}
+
+func TestCheckThresholds(t *testing.T) {
+	summaries := map[string]*coverageSummary{
+		"src/a.js": {
+			Lines:    coverageMetric{Total: 10, Covered: 9, Pct: 90},
+			Branches: coverageMetric{Total: 4, Covered: 1, Pct: 25},
+		},
+		"src/b.js": {
+			Lines:    coverageMetric{Total: 10, Covered: 5, Pct: 50},
+			Branches: coverageMetric{Total: 4, Covered: 4, Pct: 100},
+		},
+		totalCoverageKey: {
+			Lines:    coverageMetric{Total: 20, Covered: 14, Pct: 70},
+			Branches: coverageMetric{Total: 8, Covered: 5, Pct: 62.5},
+		},
+	}
+
+	violations := checkThresholds(summaries, &core.CoverageThreshold{Lines: 80}, nil)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, metricLines, violations[0].Metric)
+	assert.Equal(t, "", violations[0].File)
+	assert.Equal(t, float64(70), violations[0].Actual)
+
+	violations = checkThresholds(summaries, &core.CoverageThreshold{Lines: 80, Branches: 50, PerFile: true}, nil)
+	assert.Len(t, violations, 2)
+	for _, v := range violations {
+		if v.File == "src/a.js" {
+			assert.Equal(t, metricBranches, v.Metric)
+		} else {
+			assert.Equal(t, "src/b.js", v.File)
+			assert.Equal(t, metricLines, v.Metric)
+		}
+	}
+
+	diff := &coverageMetric{Total: 4, Covered: 3, Pct: 75}
+	violations = checkThresholds(summaries, &core.CoverageThreshold{Diff: 80}, diff)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, metricDiff, violations[0].Metric)
+
+	assert.Empty(t, checkThresholds(summaries, &core.CoverageThreshold{Diff: 70, Lines: 60}, diff))
+}
+
+func TestCoverageAnnotation(t *testing.T) {
+	data := &coverageData{
+		DiffCoverage:        &coverageMetric{Total: 4, Covered: 3, Pct: 75},
+		ThresholdViolations: []errs.ThresholdViolation{{Metric: metricDiff, Threshold: 80, Actual: 75}},
+	}
+	annotation := coverageAnnotation(data)
+	assert.Equal(t, "coverage", annotation.Name)
+	assert.Equal(t, 75.0, *annotation.DiffCoverage)
+	assert.Equal(t, []string{"diff coverage (75.00%) does not meet threshold (80.00%)"}, annotation.Violations)
+	assert.True(t, annotation.Failing())
+}
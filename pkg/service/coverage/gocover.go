@@ -0,0 +1,67 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseGoCoverProfile parses go coverprofiles, Ref: https://pkg.go.dev/golang.org/x/tools/cover
+// each line is of the form `name.go:line.column,line.column numberOfStatements count`
+func parseGoCoverProfile(path string, paths *pathNormalizer) (coverageReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := make(coverageReport)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		i := strings.LastIndex(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid coverprofile line %q", line)
+		}
+		fileName, block := line[:i], strings.Fields(line[i+1:])
+		if len(block) != 3 {
+			return nil, fmt.Errorf("invalid coverprofile line %q", line)
+		}
+		startLine, endLine, err := parseGoBlockRange(block[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line %q: %w", line, err)
+		}
+		count, err := strconv.Atoi(block[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverprofile line %q: %w", line, err)
+		}
+
+		fc := report.file(paths.normalize(fileName))
+		// blocks are the closest thing to statements in go coverprofiles
+		fc.Statements[block[0]] += count
+		for l := startLine; l <= endLine; l++ {
+			fc.Lines[l] += count
+		}
+	}
+	return report, scanner.Err()
+}
+
+// parseGoBlockRange parses `startLine.startCol,endLine.endCol` and returns the start and end line
+func parseGoBlockRange(r string) (startLine, endLine int, err error) {
+	parts := strings.Split(r, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid block range %s", r)
+	}
+	if startLine, err = strconv.Atoi(strings.SplitN(parts[0], ".", 2)[0]); err != nil {
+		return 0, 0, err
+	}
+	if endLine, err = strconv.Atoi(strings.SplitN(parts[1], ".", 2)[0]); err != nil {
+		return 0, 0, err
+	}
+	return startLine, endLine, nil
+}
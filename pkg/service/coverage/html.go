@@ -0,0 +1,227 @@
+package coverage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+const (
+	htmlReportIndex   = "index.html"
+	htmlReportBlobDir = "report"
+)
+
+// htmlFile is a row of the index of the html report
+type htmlFile struct {
+	Path    string
+	Page    string
+	Summary *coverageSummary
+	// Delta is the change of the line coverage against the base commit, nil if the file is not compared
+	Delta *float64
+}
+
+// htmlLine is a source line of the page of a file, Class is empty for the lines which are not executable
+type htmlLine struct {
+	Number int
+	Class  string
+	Source string
+}
+
+var htmlFuncs = template.FuncMap{
+	"level": func(m coverageMetric) string {
+		switch {
+		case m.Pct >= 80:
+			return "high"
+		case m.Pct >= 50:
+			return "medium"
+		default:
+			return "low"
+		}
+	},
+	"delta": func(d float64) string {
+		return fmt.Sprintf("%+.1f%%", d)
+	},
+}
+
+const htmlStyle = `<style>
+body{font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;margin:2em;color:#24292f}
+table{border-collapse:collapse}th,td{padding:4px 10px;text-align:left;border-bottom:1px solid #d0d7de}
+.high{background:#dafbe1}.medium{background:#fff8c5}.low{background:#ffebe9}
+pre{margin:0}.source td{border:0;padding:0 8px;font-family:monospace;white-space:pre}
+.source .covered{background:#dafbe1}.source .uncovered{background:#ffebe9}.num{color:#6e7781;text-align:right}
+</style>`
+
+var htmlIndexTemplate = template.Must(template.New("index").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Coverage of {{.Commit}}</title>` + htmlStyle + `</head><body>
+<h1>Coverage of {{.Commit}}</h1>
+{{with .Delta}}<p>Line coverage {{delta .Total}} against {{.BaseCommit}}, {{len .Improved}} files improved and {{len .Regressed}} regressed</p>{{end}}
+<table>
+<tr><th>File</th><th>Lines</th><th>Statements</th><th>Functions</th><th>Branches</th>{{if .Delta}}<th>Change</th>{{end}}</tr>
+{{with .Total}}<tr><th>Total</th>
+<th class="{{level .Lines}}">{{.Lines.Pct}}% ({{.Lines.Covered}}/{{.Lines.Total}})</th>
+<th class="{{level .Statements}}">{{.Statements.Pct}}%</th>
+<th class="{{level .Functions}}">{{.Functions.Pct}}%</th>
+<th class="{{level .Branches}}">{{.Branches.Pct}}%</th>{{if $.Delta}}<th>{{delta $.Delta.Total}}</th>{{end}}</tr>{{end}}
+{{range .Files}}<tr><td><a href="{{.Page}}">{{.Path}}</a></td>
+<td class="{{level .Summary.Lines}}">{{.Summary.Lines.Pct}}% ({{.Summary.Lines.Covered}}/{{.Summary.Lines.Total}})</td>
+<td class="{{level .Summary.Statements}}">{{.Summary.Statements.Pct}}%</td>
+<td class="{{level .Summary.Functions}}">{{.Summary.Functions.Pct}}%</td>
+<td class="{{level .Summary.Branches}}">{{.Summary.Branches.Pct}}%</td>{{if $.Delta}}<td>{{with .Delta}}{{delta .}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body></html>
+`))
+
+var htmlFileTemplate = template.Must(template.New("file").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Path}}</title>` + htmlStyle + `</head><body>
+<p><a href="{{.Index}}">All files</a></p>
+<h1>{{.Path}}</h1>
+<p>Lines {{.Summary.Lines.Pct}}% ({{.Summary.Lines.Covered}}/{{.Summary.Lines.Total}}),
+statements {{.Summary.Statements.Pct}}%, functions {{.Summary.Functions.Pct}}%, branches {{.Summary.Branches.Pct}}%</p>
+{{if .Lines}}<table class="source">
+{{range .Lines}}<tr class="{{.Class}}"><td class="num">{{.Number}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+{{else}}<p>The source of the file is not available.</p>
+<p>Uncovered lines: {{.Summary.UncoveredLines}}</p>
+{{end}}</body></html>
+`))
+
+// writeHTMLReport writes the browsable html report of the merged summaries to outDir: an index of the files
+// and a page per file with its covered and uncovered lines highlighted, the sources are read from the repo.
+// The index has the changes of the coverage against the base commit if delta is not nil.
+func writeHTMLReport(summaries map[string]*coverageSummary, commitID string, delta *coverageDelta, paths *pathNormalizer, outDir string) error {
+	if err := os.MkdirAll(outDir, global.DirectoryPermissions); err != nil {
+		return err
+	}
+	var deltas map[string]float64
+	if delta != nil {
+		deltas = delta.files()
+	}
+	files := make([]htmlFile, 0, len(summaries))
+	for file, s := range summaries {
+		if file == totalCoverageKey {
+			continue
+		}
+		rel := paths.normalize(file)
+		// the pages are kept inside outDir, whatever the path of the file is
+		page := path.Join("files", strings.TrimLeft(path.Clean("/"+rel), "/")+".html")
+		f := htmlFile{Path: rel, Page: page, Summary: s}
+		if d, ok := deltas[rel]; ok {
+			f.Delta = &d
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	for i := range files {
+		f := &files[i]
+		lines, err := readSourceLines(filepath.Join(paths.repoDir, f.Path), f.Summary)
+		if err != nil {
+			return err
+		}
+		err = writeTemplate(filepath.Join(outDir, filepath.FromSlash(f.Page)), htmlFileTemplate, map[string]interface{}{
+			"Path":    f.Path,
+			"Summary": f.Summary,
+			"Lines":   lines,
+			"Index":   strings.Repeat("../", strings.Count(f.Page, "/")) + htmlReportIndex,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	total, ok := summaries[totalCoverageKey]
+	if !ok {
+		total = new(coverageSummary)
+	}
+	return writeTemplate(filepath.Join(outDir, htmlReportIndex), htmlIndexTemplate, map[string]interface{}{
+		"Commit": commitID,
+		"Total":  total,
+		"Files":  files,
+		"Delta":  delta,
+	})
+}
+
+// readSourceLines returns the lines of the source file classified by the summary, or nil if the source is not
+// available e.g. the repo is not cloned in coverage mode
+func readSourceLines(sourcePath string, s *coverageSummary) ([]htmlLine, error) {
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	covered := parseLineRanges(s.CoveredLines)
+	uncovered := parseLineRanges(s.UncoveredLines)
+	lines := make([]htmlLine, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := htmlLine{Number: n, Source: scanner.Text()}
+		if covered[n] {
+			line.Class = "covered"
+		} else if uncovered[n] {
+			line.Class = "uncovered"
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func writeTemplate(filePath string, tmpl *template.Template, data interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), global.DirectoryPermissions); err != nil {
+		return err
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// uploadHTMLReport uploads the files of the report as a static site next to the merged coverage of the commit,
+// it returns the URL of the index
+func (c *codeCoverageService) uploadHTMLReport(ctx context.Context, blobPath, commitID, reportDir string) (string, error) {
+	var indexURL string
+	err := filepath.WalkDir(reportDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(reportDir, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		mimeType := mime.TypeByExtension(filepath.Ext(filePath))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		blobURL, err := c.azureClient.Create(ctx, path.Join(blobPath, commitID, htmlReportBlobDir, filepath.ToSlash(rel)), file, mimeType)
+		if err != nil {
+			return err
+		}
+		if rel == htmlReportIndex {
+			indexURL = blobURL
+		}
+		return nil
+	})
+	return indexURL, err
+}
@@ -0,0 +1,47 @@
+package coverage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, repo, "src/sum.js", "function sum(a, b) {\n  return a + b\n}\n// <unused>\nsum(1, 2)\n")
+	summaries := map[string]*coverageSummary{
+		filepath.Join(repo, "src/sum.js"): {
+			Lines:        coverageMetric{Total: 3, Covered: 2, Pct: 66.6},
+			CoveredLines: "1,5", UncoveredLines: "2",
+		},
+		"../outside.js":  {UncoveredLines: "1-2"},
+		totalCoverageKey: {Lines: coverageMetric{Total: 5, Covered: 2, Pct: 40}},
+	}
+	out := t.TempDir()
+	assert.Nil(t, writeHTMLReport(summaries, "abc123", nil, newPathNormalizer(repo), out))
+
+	index, err := ioutil.ReadFile(filepath.Join(out, htmlReportIndex))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), `<a href="files/src/sum.js.html">src/sum.js</a>`)
+	assert.Contains(t, string(index), `<th class="low">40% (2/5)</th>`)
+	assert.Contains(t, string(index), `<td class="medium">66.6% (2/3)</td>`)
+
+	page, err := ioutil.ReadFile(filepath.Join(out, "files", "src", "sum.js.html"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(page), `<a href="../../index.html">All files</a>`)
+	assert.Contains(t, string(page), `<tr class="covered"><td class="num">1</td><td>function sum(a, b) {</td></tr>`)
+	assert.Contains(t, string(page), `<tr class="uncovered"><td class="num">2</td><td>  return a &#43; b</td></tr>`)
+	assert.Contains(t, string(page), `<tr class=""><td class="num">4</td><td>// &lt;unused&gt;</td></tr>`)
+
+	// the pages of the files outside the repo are kept in the report
+	page, err = ioutil.ReadFile(filepath.Join(out, "files", "outside.js.html"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(page), "The source of the file is not available.")
+	assert.Contains(t, string(page), "Uncovered lines: 1-2")
+}
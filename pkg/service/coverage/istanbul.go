@@ -0,0 +1,72 @@
+package coverage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+)
+
+type istanbulLocation struct {
+	Start struct {
+		Line int `json:"line"`
+	} `json:"start"`
+	End struct {
+		Line int `json:"line"`
+	} `json:"end"`
+}
+
+type istanbulFunction struct {
+	Name string           `json:"name"`
+	Loc  istanbulLocation `json:"loc"`
+}
+
+type istanbulFileCoverage struct {
+	Path         string                      `json:"path"`
+	StatementMap map[string]istanbulLocation `json:"statementMap"`
+	FnMap        map[string]istanbulFunction `json:"fnMap"`
+	S            map[string]int              `json:"s"`
+	F            map[string]int              `json:"f"`
+	B            map[string][]int            `json:"b"`
+}
+
+// parseIstanbul parses istanbul coverage-final.json, Ref: https://github.com/gotwarlost/istanbul/blob/master/coverage.json.md
+func parseIstanbul(path string, paths *pathNormalizer) (coverageReport, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var files map[string]istanbulFileCoverage
+	if err := json.Unmarshal(body, &files); err != nil {
+		return nil, err
+	}
+
+	report := make(coverageReport)
+	for key, ifc := range files {
+		if ifc.Path == "" {
+			ifc.Path = key
+		}
+		fc := report.file(paths.normalize(ifc.Path))
+		for id, hits := range ifc.S {
+			fc.Statements[id] += hits
+			// istanbul attributes the statement hits to the line it starts on
+			if loc, ok := ifc.StatementMap[id]; ok {
+				if cur, exists := fc.Lines[loc.Start.Line]; !exists || hits > cur {
+					fc.Lines[loc.Start.Line] = hits
+				}
+			}
+		}
+		for id, hits := range ifc.F {
+			name := id
+			if fn, ok := ifc.FnMap[id]; ok {
+				name = fn.Name + ":" + strconv.Itoa(fn.Loc.Start.Line)
+			}
+			fc.Functions[name] += hits
+		}
+		for id, branches := range ifc.B {
+			for i, hits := range branches {
+				fc.Branches[id+":"+strconv.Itoa(i)] += hits
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,76 @@
+package coverage
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseLcov parses lcov tracefiles, Ref: https://manpages.debian.org/stretch/lcov/geninfo.1.en.html#FILES
+func parseLcov(path string, paths *pathNormalizer) (coverageReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := make(coverageReport)
+	var fc *fileCoverage
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			key, value = line[:i], line[i+1:]
+		}
+		switch key {
+		case "SF":
+			fc = report.file(paths.normalize(value))
+		case "end_of_record":
+			fc = nil
+		}
+		if fc == nil {
+			continue
+		}
+		fields := strings.Split(value, ",")
+		switch key {
+		case "DA":
+			// DA:<line number>,<execution count>[,<checksum>]
+			if len(fields) < 2 {
+				continue
+			}
+			lineNo, err1 := strconv.Atoi(fields[0])
+			hits, err2 := strconv.Atoi(fields[1])
+			if err1 == nil && err2 == nil {
+				fc.Lines[lineNo] += hits
+			}
+		case "FN":
+			// FN:<line number of function start>,<function name>
+			if len(fields) >= 2 {
+				fc.Functions[fields[1]] += 0
+			}
+		case "FNDA":
+			// FNDA:<execution count>,<function name>
+			if len(fields) >= 2 {
+				if hits, err := strconv.Atoi(fields[0]); err == nil {
+					fc.Functions[fields[1]] += hits
+				}
+			}
+		case "BRDA":
+			// BRDA:<line number>,<block number>,<branch number>,<taken>
+			if len(fields) < 4 {
+				continue
+			}
+			id := fields[0] + ":" + fields[1] + ":" + fields[2]
+			// `-` means the branch expression was never evaluated
+			hits, err := strconv.Atoi(fields[3])
+			if err != nil {
+				hits = 0
+			}
+			fc.Branches[id] += hits
+		}
+	}
+	return report, scanner.Err()
+}
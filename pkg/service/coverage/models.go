@@ -1,6 +1,10 @@
 package coverage
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/LambdaTest/synapse/pkg/errs"
+)
 
 type parentCommitCoverage struct {
 	Bloblink     string `json:"blob_link"`
@@ -13,4 +17,10 @@ type coverageData struct {
 	CommitID      string          `json:"commit_id"`
 	BlobLink      string          `json:"blob_link"`
 	TotalCoverage json.RawMessage `json:"total_coverage"`
+	// ReportLink is the URL of the index of the html report, if generated
+	ReportLink string `json:"report_link,omitempty"`
+	// DiffCoverage, ThresholdViolations and CoverageDelta are only reported for the gated commit
+	DiffCoverage        *coverageMetric           `json:"diff_coverage,omitempty"`
+	ThresholdViolations []errs.ThresholdViolation `json:"threshold_violations,omitempty"`
+	CoverageDelta       *coverageDelta            `json:"coverage_delta,omitempty"`
 }
@@ -0,0 +1,220 @@
+package coverage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fileCoverage holds the hit counts of a single source file.
+// Statements, functions and branches are keyed by a format specific identifier
+// so that reports of the same file from multiple runs can be merged.
+type fileCoverage struct {
+	Lines      map[int]int
+	Statements map[string]int
+	Functions  map[string]int
+	Branches   map[string]int
+}
+
+func newFileCoverage() *fileCoverage {
+	return &fileCoverage{
+		Lines:      make(map[int]int),
+		Statements: make(map[string]int),
+		Functions:  make(map[string]int),
+		Branches:   make(map[string]int),
+	}
+}
+
+// coverageReport is the unified, format independent coverage of all files
+type coverageReport map[string]*fileCoverage
+
+func (r coverageReport) file(path string) *fileCoverage {
+	fc, ok := r[path]
+	if !ok {
+		fc = newFileCoverage()
+		r[path] = fc
+	}
+	return fc
+}
+
+// merge adds the hit counts of other into r
+func (r coverageReport) merge(other coverageReport) {
+	for path, ofc := range other {
+		fc := r.file(path)
+		addHits(fc.Statements, ofc.Statements)
+		addHits(fc.Functions, ofc.Functions)
+		addHits(fc.Branches, ofc.Branches)
+		for line, hits := range ofc.Lines {
+			fc.Lines[line] += hits
+		}
+	}
+}
+
+func addHits(dst, src map[string]int) {
+	for k, hits := range src {
+		dst[k] += hits
+	}
+}
+
+// coverageMetric follows the istanbul json-summary metric format
+type coverageMetric struct {
+	Total   int     `json:"total"`
+	Covered int     `json:"covered"`
+	Skipped int     `json:"skipped"`
+	Pct     float64 `json:"pct"`
+}
+
+func (m *coverageMetric) add(other coverageMetric) {
+	m.Total += other.Total
+	m.Covered += other.Covered
+	m.Skipped += other.Skipped
+	m.computePct()
+}
+
+// computePct calculates percentage rounded down to one decimal, same as istanbul
+func (m *coverageMetric) computePct() {
+	if m.Total == 0 {
+		m.Pct = 100
+		return
+	}
+	m.Pct = math.Floor(float64(1000*m.Covered)/float64(m.Total)) / 10
+}
+
+// coverageSummary follows the istanbul json-summary format written by custom-reporter.js
+type coverageSummary struct {
+	Lines          coverageMetric `json:"lines"`
+	Statements     coverageMetric `json:"statements"`
+	Functions      coverageMetric `json:"functions"`
+	Branches       coverageMetric `json:"branches"`
+	UncoveredLines string         `json:"uncovered_lines,omitempty"`
+	CoveredLines   string         `json:"covered_lines,omitempty"`
+}
+
+func (s *coverageSummary) add(other *coverageSummary) {
+	s.Lines.add(other.Lines)
+	s.Statements.add(other.Statements)
+	s.Functions.add(other.Functions)
+	s.Branches.add(other.Branches)
+}
+
+func countMetric(hits map[string]int) coverageMetric {
+	m := coverageMetric{Total: len(hits)}
+	for _, h := range hits {
+		if h > 0 {
+			m.Covered++
+		}
+	}
+	m.computePct()
+	return m
+}
+
+func (fc *fileCoverage) summary() *coverageSummary {
+	lines := coverageMetric{Total: len(fc.Lines)}
+	uncovered := make([]int, 0)
+	covered := make([]int, 0)
+	for line, hits := range fc.Lines {
+		if hits > 0 {
+			lines.Covered++
+			covered = append(covered, line)
+		} else {
+			uncovered = append(uncovered, line)
+		}
+	}
+	lines.computePct()
+
+	s := &coverageSummary{
+		Lines:          lines,
+		Statements:     countMetric(fc.Statements),
+		Functions:      countMetric(fc.Functions),
+		Branches:       countMetric(fc.Branches),
+		UncoveredLines: formatLineRanges(uncovered),
+		CoveredLines:   formatLineRanges(covered),
+	}
+	// formats without statement information report lines as statements
+	if len(fc.Statements) == 0 {
+		s.Statements = lines
+	}
+	return s
+}
+
+// summarize returns the per file and total summaries of the report, keyed by file path and `total`
+func (r coverageReport) summarize() map[string]*coverageSummary {
+	summaries := make(map[string]*coverageSummary, len(r)+1)
+	total := new(coverageSummary)
+	for path, fc := range r {
+		s := fc.summary()
+		summaries[path] = s
+		total.add(s)
+	}
+	summaries[totalCoverageKey] = total
+	return summaries
+}
+
+// formatLineRanges converts uncovered lines to comma separated ranges eg. `1-3,7`
+func formatLineRanges(lines []int) string {
+	sort.Ints(lines)
+	ranges := make([]string, 0)
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(lines[i]))
+		} else {
+			ranges = append(ranges, strconv.Itoa(lines[i])+"-"+strconv.Itoa(lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// mergeSummaryFile merges the summaries with the json-summary (if any) present at path and writes it back.
+// Files present in both are replaced by summaries, totals are recomputed.
+func mergeSummaryFile(path string, summaries map[string]*coverageSummary) error {
+	merged := make(map[string]*coverageSummary)
+	if body, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(body, &merged); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for file, s := range summaries {
+		if file != totalCoverageKey {
+			merged[file] = s
+		}
+	}
+	total := new(coverageSummary)
+	for file, s := range merged {
+		if file != totalCoverageKey {
+			total.add(s)
+		}
+	}
+	merged[totalCoverageKey] = total
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, body, 0644)
+}
+
+// UnmarshalJSON ignores pct, as istanbul reports `Unknown` for empty metrics, and recomputes it
+func (m *coverageMetric) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Total   int `json:"total"`
+		Covered int `json:"covered"`
+		Skipped int `json:"skipped"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Total, m.Covered, m.Skipped = raw.Total, raw.Covered, raw.Skipped
+	m.computePct()
+	return nil
+}
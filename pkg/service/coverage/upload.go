@@ -0,0 +1,343 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/urlmanager"
+)
+
+const (
+	codecovURL          = "https://codecov.io"
+	coverallsURL        = "https://coveralls.io"
+	coverallsService    = "tas"
+	codecovReportName   = "coverage.json"
+	maxUploadSourceSize = 10 << 20
+)
+
+// codecovReport is the codecov json format, the hits of the lines are keyed by the line number
+type codecovReport struct {
+	Coverage map[string]map[string]int `json:"coverage"`
+}
+
+type coverallsJob struct {
+	RepoToken          string                `json:"repo_token"`
+	ServiceName        string                `json:"service_name"`
+	ServiceJobID       string                `json:"service_job_id"`
+	ServicePullRequest string                `json:"service_pull_request,omitempty"`
+	CommitSHA          string                `json:"commit_sha"`
+	Git                coverallsGit          `json:"git"`
+	SourceFiles        []coverallsSourceFile `json:"source_files"`
+}
+
+type coverallsGit struct {
+	Head   coverallsHead `json:"head"`
+	Branch string        `json:"branch,omitempty"`
+}
+
+type coverallsHead struct {
+	ID string `json:"id"`
+}
+
+// coverallsSourceFile has the hits of every line of the file, null for the lines which are not executable
+type coverallsSourceFile struct {
+	Name         string `json:"name"`
+	SourceDigest string `json:"source_digest"`
+	Coverage     []*int `json:"coverage"`
+}
+
+// sourceReader returns the source of the file relative to the repo root, or nil if it is not available
+type sourceReader func(file string) ([]byte, error)
+
+// uploadCoverage uploads the merged coverage of the commit to the configured coverage services. The failures are
+// only logged, as the coverage services are not required by the build.
+func (c *codeCoverageService) uploadCoverage(ctx context.Context,
+	payload *core.Payload,
+	cloneToken, commitDir, commitID string,
+	upload *core.CoverageUpload) {
+	summaries, err := readSummaryFile(filepath.Join(commitDir, mergedcoverageJSON))
+	if err != nil {
+		c.logger.Errorf("failed to read coverage summary of commit %s, error: %v", commitID, err)
+		return
+	}
+	secrets, err := c.secretParser.GetRepoSecret(ctx, global.RepoSecretPath)
+	if err != nil {
+		c.logger.Errorf("failed to get the repo secrets for the coverage upload, error: %v", err)
+		return
+	}
+	paths := newPathNormalizer(global.RepoDir)
+	if upload.Codecov != nil {
+		if err := c.uploadCodecov(ctx, payload, commitID, upload.Codecov, secrets, summaries, paths); err != nil {
+			c.logger.Errorf("failed to upload the coverage of commit %s to codecov, error: %v", commitID, err)
+		} else {
+			c.logger.Infof("uploaded the coverage of commit %s to codecov", commitID)
+		}
+	}
+	if upload.Coveralls != nil {
+		readSource := func(file string) ([]byte, error) {
+			return c.readSource(ctx, payload, cloneToken, commitID, file)
+		}
+		if err := c.uploadCoveralls(ctx, payload, commitID, upload.Coveralls, secrets, summaries, paths, readSource); err != nil {
+			c.logger.Errorf("failed to upload the coverage of commit %s to coveralls, error: %v", commitID, err)
+		} else {
+			c.logger.Infof("uploaded the coverage of commit %s to coveralls", commitID)
+		}
+	}
+}
+
+// uploadCodecov uploads the report with the v4 upload api, which returns the url the report is put to
+func (c *codeCoverageService) uploadCodecov(ctx context.Context,
+	payload *core.Payload,
+	commitID string,
+	target *core.CoverageUploadTarget,
+	secrets map[string]string,
+	summaries map[string]*coverageSummary,
+	paths *pathNormalizer) error {
+	token, err := c.secretParser.SubstituteSecret(target.Token, secrets)
+	if err != nil {
+		return err
+	}
+	report, err := buildCodecovReport(summaries, paths)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(uploadURL(target, codecovURL) + "/upload/v4")
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("commit", commitID)
+	q.Set("branch", payload.BranchName)
+	q.Set("slug", payload.RepoSlug)
+	q.Set("build", payload.BuildID)
+	q.Set("service", "custom")
+	if payload.PullRequestNumber != 0 {
+		q.Set("pr", strconv.Itoa(payload.PullRequestNumber))
+	}
+	if len(target.Flags) > 0 {
+		q.Set("flags", strings.Join(target.Flags, ","))
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	// the token is sent in the header, so that it is not logged with the url
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", "text/plain")
+	body, err := c.doUpload(req)
+	if err != nil {
+		return err
+	}
+	// the response has the url of the report and the url the report is put to
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) < 2 {
+		return fmt.Errorf("unexpected codecov response %q", body)
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSpace(lines[1]), bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	_, err = c.doUpload(req)
+	return err
+}
+
+// uploadCoveralls posts the job of the commit to the coveralls api, the sources are required for their digest
+func (c *codeCoverageService) uploadCoveralls(ctx context.Context,
+	payload *core.Payload,
+	commitID string,
+	target *core.CoverageUploadTarget,
+	secrets map[string]string,
+	summaries map[string]*coverageSummary,
+	paths *pathNormalizer,
+	readSource sourceReader) error {
+	token, err := c.secretParser.SubstituteSecret(target.Token, secrets)
+	if err != nil {
+		return err
+	}
+	sourceFiles, err := buildCoverallsSourceFiles(summaries, paths, readSource)
+	if err != nil {
+		return err
+	}
+	job := coverallsJob{
+		RepoToken:    token,
+		ServiceName:  coverallsService,
+		ServiceJobID: payload.BuildID,
+		CommitSHA:    commitID,
+		Git:          coverallsGit{Head: coverallsHead{ID: commitID}, Branch: payload.BranchName},
+		SourceFiles:  sourceFiles,
+	}
+	if payload.PullRequestNumber != 0 {
+		job.ServicePullRequest = strconv.Itoa(payload.PullRequestNumber)
+	}
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	part, err := w.CreateFormFile("json_file", "coveralls.json")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(part).Encode(&job); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL(target, coverallsURL)+"/api/v1/jobs", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	_, err = c.doUpload(req)
+	return err
+}
+
+func (c *codeCoverageService) doUpload(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("non 2xx status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return body, nil
+}
+
+// readSource reads the source of the file from the repo if it is cloned, and downloads it from the git provider
+// otherwise as the repo is not cloned in coverage mode
+func (c *codeCoverageService) readSource(ctx context.Context, payload *core.Payload, cloneToken, commitID, file string) ([]byte, error) {
+	source, err := ioutil.ReadFile(filepath.Join(global.RepoDir, filepath.FromSlash(file)))
+	if err == nil || !os.IsNotExist(err) {
+		return source, err
+	}
+	downloadURL, err := urlmanager.GetDownloadURL(payload.GitProvider, payload.RepoSlug, commitID, file)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cloneToken != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", cloneToken))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non 200 status %d while downloading %s", resp.StatusCode, file)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxUploadSourceSize))
+}
+
+func uploadURL(target *core.CoverageUploadTarget, defaultURL string) string {
+	if target.URL != "" {
+		return strings.TrimSuffix(target.URL, "/")
+	}
+	return defaultURL
+}
+
+// buildCodecovReport converts the merged summaries to the codecov json format, in the legacy upload format
+func buildCodecovReport(summaries map[string]*coverageSummary, paths *pathNormalizer) ([]byte, error) {
+	report := codecovReport{Coverage: make(map[string]map[string]int, len(summaries))}
+	for file, s := range summaries {
+		if file == totalCoverageKey {
+			continue
+		}
+		lines := make(map[string]int)
+		for line := range parseLineRanges(s.UncoveredLines) {
+			lines[strconv.Itoa(line)] = 0
+		}
+		for line := range parseLineRanges(s.CoveredLines) {
+			lines[strconv.Itoa(line)] = 1
+		}
+		report.Coverage[paths.normalize(file)] = lines
+	}
+	rawBytes, err := json.Marshal(&report)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("# path=%s\n%s\n<<<<<< EOF\n", codecovReportName, rawBytes)), nil
+}
+
+// buildCoverallsSourceFiles converts the merged summaries to the coveralls source files, the files whose source
+// is not available are skipped
+func buildCoverallsSourceFiles(summaries map[string]*coverageSummary, paths *pathNormalizer,
+	readSource sourceReader) ([]coverallsSourceFile, error) {
+	files := make([]string, 0, len(summaries))
+	normalized := make(map[string]*coverageSummary, len(summaries))
+	for file, s := range summaries {
+		if file == totalCoverageKey {
+			continue
+		}
+		rel := paths.normalize(file)
+		files = append(files, rel)
+		normalized[rel] = s
+	}
+	sort.Strings(files)
+
+	sourceFiles := make([]coverallsSourceFile, 0, len(files))
+	for _, file := range files {
+		source, err := readSource(file)
+		if err != nil {
+			return nil, err
+		}
+		if source == nil {
+			continue
+		}
+		covered := parseLineRanges(normalized[file].CoveredLines)
+		uncovered := parseLineRanges(normalized[file].UncoveredLines)
+		coverage := make([]*int, countLines(source))
+		for i := range coverage {
+			hits := 0
+			if covered[i+1] {
+				hits = 1
+			} else if !uncovered[i+1] {
+				continue
+			}
+			coverage[i] = &hits
+		}
+		digest := md5.Sum(source) // nolint:gosec
+		sourceFiles = append(sourceFiles, coverallsSourceFile{
+			Name:         file,
+			SourceDigest: hex.EncodeToString(digest[:]),
+			Coverage:     coverage,
+		})
+	}
+	return sourceFiles, nil
+}
+
+func countLines(source []byte) int {
+	n := bytes.Count(source, []byte("\n"))
+	if len(source) > 0 && source[len(source)-1] != '\n' {
+		n++
+	}
+	return n
+}
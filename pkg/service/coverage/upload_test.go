@@ -0,0 +1,55 @@
+package coverage
+
+import (
+	"crypto/md5" // nolint:gosec
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCodecovReport(t *testing.T) {
+	repo := t.TempDir()
+	summaries := map[string]*coverageSummary{
+		filepath.Join(repo, "src/sum.js"): {CoveredLines: "1-2,5", UncoveredLines: "3"},
+		totalCoverageKey:                  {Lines: coverageMetric{Total: 4, Covered: 3, Pct: 75}},
+	}
+	report, err := buildCodecovReport(summaries, newPathNormalizer(repo))
+	assert.Nil(t, err)
+	assert.Equal(t, "# path=coverage.json\n"+
+		`{"coverage":{"src/sum.js":{"1":1,"2":1,"3":0,"5":1}}}`+
+		"\n<<<<<< EOF\n", string(report))
+}
+
+func TestBuildCoverallsSourceFiles(t *testing.T) {
+	repo := t.TempDir()
+	summaries := map[string]*coverageSummary{
+		filepath.Join(repo, "src/sum.js"): {CoveredLines: "1,5", UncoveredLines: "2"},
+		"src/missing.js":                  {UncoveredLines: "1"},
+		totalCoverageKey:                  {Lines: coverageMetric{Total: 3, Covered: 2, Pct: 66.6}},
+	}
+	source := []byte("function sum(a, b) {\n  return a + b\n}\n// unused\nsum(1, 2)")
+	readSource := func(file string) ([]byte, error) {
+		if file == "src/sum.js" {
+			return source, nil
+		}
+		return nil, nil
+	}
+	files, err := buildCoverallsSourceFiles(summaries, newPathNormalizer(repo), readSource)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	digest := md5.Sum(source) // nolint:gosec
+	assert.Equal(t, "src/sum.js", files[0].Name)
+	assert.Equal(t, hex.EncodeToString(digest[:]), files[0].SourceDigest)
+	one, zero := 1, 0
+	assert.Equal(t, []*int{&one, &zero, nil, nil, &one}, files[0].Coverage)
+}
+
+func TestCountLines(t *testing.T) {
+	assert.Equal(t, 0, countLines(nil))
+	assert.Equal(t, 1, countLines([]byte("a")))
+	assert.Equal(t, 2, countLines([]byte("a\nb\n")))
+	assert.Equal(t, 3, countLines([]byte("a\n\nb")))
+}
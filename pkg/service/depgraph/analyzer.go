@@ -0,0 +1,190 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const graphMimeType = "application/json"
+
+type analyzer struct {
+	azureClient core.AzureClient
+	logger      lumber.Logger
+	repoDir     string
+}
+
+// New returns a new ImpactAnalyzer for the repository cloned at global.RepoDir
+func New(azureClient core.AzureClient, logger lumber.Logger) core.ImpactAnalyzer {
+	return &analyzer{azureClient: azureClient, logger: logger, repoDir: global.RepoDir}
+}
+
+// ImpactedFiles updates the dependency graph stored for the repository with the files of the
+// current checkout and returns the files which transitively import the changed files.
+func (a *analyzer) ImpactedFiles(ctx context.Context, payload *core.Payload, diff map[string]int) ([]string, error) {
+	blobPath := fmt.Sprintf("depgraph/%s/%s/graph.json", payload.OrgID, payload.RepoID)
+	old, err := a.load(ctx, blobPath)
+	if err != nil {
+		a.logger.Warnf("failed to load dependency graph, building from scratch: %v", err)
+		old = newGraph()
+	}
+	files, err := listFiles(ctx, a.repoDir)
+	if err != nil {
+		return nil, err
+	}
+	g, modified := update(ctx, old, a.repoDir, files, a.logger)
+	if modified {
+		if err := a.save(ctx, blobPath, g); err != nil {
+			// the graph is rebuilt incrementally in the next run
+			a.logger.Warnf("failed to store dependency graph: %v", err)
+		}
+	}
+
+	changed := make([]string, 0, len(diff))
+	removed := make([]string, 0)
+	for file, changeType := range diff {
+		if changeType == core.FileRemoved {
+			removed = append(removed, file)
+		} else {
+			changed = append(changed, file)
+		}
+	}
+	result := impacted(g.dependents(), changed)
+	if len(removed) > 0 {
+		// the importers of the removed files are only present in the previous graph
+		for _, file := range impacted(old.dependents(), removed) {
+			if _, ok := g.Files[file]; ok {
+				result = append(result, file)
+			}
+		}
+	}
+	a.logger.Debugf("%d files are impacted by %d changed files", len(result), len(diff))
+	return result, nil
+}
+
+func (a *analyzer) load(ctx context.Context, blobPath string) (*Graph, error) {
+	reader, err := a.azureClient.Find(ctx, blobPath)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return newGraph(), nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+	g := &Graph{}
+	if err := json.NewDecoder(reader).Decode(g); err != nil {
+		return nil, err
+	}
+	if g.Version != graphVersion || g.Files == nil {
+		return newGraph(), nil
+	}
+	return g, nil
+}
+
+func (a *analyzer) save(ctx context.Context, blobPath string, g *Graph) error {
+	body, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	_, err = a.azureClient.Create(ctx, blobPath, bytes.NewReader(body), graphMimeType)
+	return err
+}
+
+// listFiles returns the git blob hash of the tracked source files keyed by their path,
+// the hashes are read from the index so that only the changed files are read.
+func listFiles(ctx context.Context, repoDir string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "--stage", "-z")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of the repository: %v", err)
+	}
+	files := make(map[string]string)
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> <object> <stage>\t<file>
+		tab := strings.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:tab])
+		file := entry[tab+1:]
+		if len(fields) == 3 && (isJSFile(file) || isGoFile(file)) {
+			files[file] = fields[1]
+		}
+	}
+	return files, nil
+}
+
+// update returns the graph of the files, only the javascript files with a different checksum
+// are parsed and the go packages are only listed if a go file changed. It returns true if the
+// graph is modified.
+func update(ctx context.Context, old *Graph, repoDir string, files map[string]string, logger lumber.Logger) (*Graph, bool) {
+	g := newGraph()
+	modified, goModified := len(old.Files) != len(files), false
+	for file, checksum := range files {
+		prev, ok := old.Files[file]
+		if ok && prev.Checksum == checksum {
+			g.Files[file] = &Node{Checksum: checksum, Specifiers: prev.Specifiers, Imports: prev.Imports}
+			continue
+		}
+		modified = true
+		node := &Node{Checksum: checksum}
+		if isJSFile(file) {
+			src, err := ioutil.ReadFile(filepath.Join(repoDir, filepath.FromSlash(file)))
+			if err != nil {
+				logger.Warnf("failed to read %s: %v", file, err)
+			}
+			node.Specifiers = parseJSImports(src)
+		} else {
+			goModified = true
+		}
+		g.Files[file] = node
+	}
+	for file := range old.Files {
+		if _, ok := files[file]; !ok && isGoFile(file) {
+			goModified = true
+		}
+	}
+
+	// imports of unchanged files resolve to different files if files are added or removed
+	for file, node := range g.Files {
+		if !isJSFile(file) {
+			continue
+		}
+		node.Imports = nil
+		for _, spec := range node.Specifiers {
+			if resolved, ok := resolveJSImport(file, spec, g.Files); ok {
+				node.Imports = append(node.Imports, resolved)
+			}
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, goModFile)); goModified && err == nil {
+		packages, err := listGoPackages(ctx, repoDir)
+		if err == nil {
+			err = setGoImports(g, repoDir, packages)
+		}
+		if err != nil {
+			logger.Warnf("failed to list go packages, go imports are not updated: %v", err)
+			// go files without checksum are considered modified in the next update
+			for file, node := range g.Files {
+				if isGoFile(file) {
+					node.Checksum = ""
+				}
+			}
+		}
+	}
+	return g, modified
+}
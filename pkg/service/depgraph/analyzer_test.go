@@ -0,0 +1,86 @@
+package depgraph
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) map[string]string {
+	checksums := make(map[string]string, len(files))
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.Nil(t, ioutil.WriteFile(p, []byte(content), 0644))
+		checksums[name] = content
+	}
+	return checksums
+}
+
+func TestParseJSImports(t *testing.T) {
+	src := []byte(`import React from 'react'
+import { a,
+  b } from "./utils"
+import type { Props } from '../types'
+export * from './reexport'
+const lazy = import('./lazy')
+const fs = require("fs"), helper = require('./helper.js')
+jest.mock('./api')
+`)
+	assert.Equal(t, []string{"./utils", "../types", "./reexport", "./lazy", "./helper.js", "./api"}, parseJSImports(src))
+}
+
+func TestUpdateJavascript(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string]string{
+		"src/utils/format.ts":           "export const format = (s: string) => s",
+		"src/utils/index.ts":            "export * from './format'",
+		"src/api.ts":                    "import { format } from './utils'",
+		"src/ui/button.tsx":             "import { format } from '../utils/format.js'",
+		"test/api.spec.ts":              "import '../src/api'",
+		"test/button.spec.tsx":          "import '../src/ui/button'",
+		"test/standalone.spec.js":       "const assert = require('assert')",
+		"src/unresolved-import-only.js": "require('./missing')",
+	})
+
+	g, modified := update(context.Background(), newGraph(), dir, files, logger)
+	assert.True(t, modified)
+	assert.Equal(t, []string{"src/utils/format.ts"}, g.Files["src/utils/index.ts"].Imports)
+	assert.Equal(t, []string{"src/utils/format.ts"}, g.Files["src/ui/button.tsx"].Imports)
+	assert.Empty(t, g.Files["src/unresolved-import-only.js"].Imports)
+
+	assert.Equal(t, []string{"src/api.ts", "src/ui/button.tsx", "src/utils/index.ts", "test/api.spec.ts", "test/button.spec.tsx"},
+		impacted(g.dependents(), []string{"src/utils/format.ts"}))
+
+	// unchanged files are not parsed again
+	assert.Nil(t, os.Remove(filepath.Join(dir, "src", "api.ts")))
+	_, modified = update(context.Background(), g, dir, files, logger)
+	assert.False(t, modified)
+}
+
+func TestUpdateGo(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string]string{
+		"go.mod":                  "module example.com/mod\n\ngo 1.17\n",
+		"util/util.go":            "package util\n\nfunc Add(a, b int) int { return a + b }\n",
+		"api/api.go":              "package api\n\nimport \"example.com/mod/util\"\n\nvar Sum = util.Add(1, 2)\n",
+		"api/api_test.go":         "package api\n\nimport \"testing\"\n\nfunc TestSum(t *testing.T) {}\n",
+		"other/other.go":          "package other\n",
+		"other/other_ext_test.go": "package other_test\n\nimport _ \"example.com/mod/api\"\n",
+	})
+
+	g, _ := update(context.Background(), newGraph(), dir, files, logger)
+	assert.Equal(t, []string{"util/util.go"}, g.Files["api/api.go"].Imports)
+	assert.Equal(t, []string{"api/api.go"}, g.Files["api/api_test.go"].Imports)
+	assert.Equal(t, []string{"api/api.go", "api/api_test.go", "other/other_ext_test.go"},
+		impacted(g.dependents(), []string{"util/util.go"}))
+}
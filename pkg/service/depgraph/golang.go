@@ -0,0 +1,132 @@
+package depgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+const goModFile = "go.mod"
+
+// goPackage contains the fields of `go list -json` used for building the graph
+type goPackage struct {
+	Dir          string
+	ImportPath   string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+func isGoFile(p string) bool {
+	return path.Ext(p) == ".go" || path.Base(p) == goModFile
+}
+
+// listGoPackages lists the packages of the module at the repository root
+func listGoPackages(ctx context.Context, repoDir string) ([]goPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json", "./...")
+	cmd.Dir = repoDir
+	// the imports of the module are parsed from the sources, dependencies are never downloaded
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %v: %s", err, stderr.String())
+	}
+
+	packages := make([]goPackage, 0)
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// setGoImports sets the imports of the go files, go files depend on all the files of
+// the imported packages of the module and the test files on the files of their own package.
+func setGoImports(g *Graph, repoDir string, packages []goPackage) error {
+	rel := func(dir string, files []string) ([]string, error) {
+		relDir, err := filepath.Rel(repoDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]string, 0, len(files))
+		for _, f := range files {
+			result = append(result, path.Join(filepath.ToSlash(relDir), f))
+		}
+		return result, nil
+	}
+
+	sources := make(map[string][]string, len(packages))
+	for _, pkg := range packages {
+		files, err := rel(pkg.Dir, append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...))
+		if err != nil {
+			return err
+		}
+		sources[pkg.ImportPath] = files
+	}
+	importedFiles := func(imports ...[]string) []string {
+		seen := make(map[string]bool)
+		result := make([]string, 0)
+		for _, list := range imports {
+			for _, imp := range list {
+				for _, f := range sources[imp] {
+					if !seen[f] {
+						seen[f] = true
+						result = append(result, f)
+					}
+				}
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	for _, pkg := range packages {
+		own := sources[pkg.ImportPath]
+		tests, err := rel(pkg.Dir, pkg.TestGoFiles)
+		if err != nil {
+			return err
+		}
+		xtests, err := rel(pkg.Dir, pkg.XTestGoFiles)
+		if err != nil {
+			return err
+		}
+		setImports(g, own, importedFiles(pkg.Imports))
+		setImports(g, tests, importedFiles(pkg.TestImports, []string{pkg.ImportPath}))
+		setImports(g, xtests, importedFiles(pkg.XTestImports))
+	}
+	return nil
+}
+
+func setImports(g *Graph, files, imports []string) {
+	for _, f := range files {
+		node, ok := g.Files[f]
+		if !ok {
+			continue
+		}
+		node.Imports = make([]string, 0, len(imports))
+		for _, imp := range imports {
+			if imp != f {
+				node.Imports = append(node.Imports, imp)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package depgraph builds the file dependency graph of the repository, which is used for
+// selecting the tests transitively impacted by the changed files.
+package depgraph
+
+import "sort"
+
+// graphVersion is incremented when the format or the parsing of the graph changes,
+// graphs with a different version are rebuilt from scratch.
+const graphVersion = 1
+
+// Graph is the file dependency graph of the repository, paths are relative to the repository root
+type Graph struct {
+	Version int              `json:"version"`
+	Files   map[string]*Node `json:"files"`
+}
+
+// Node is a source file of the repository
+type Node struct {
+	// Checksum of the content used for updating the graph incrementally
+	Checksum string `json:"checksum"`
+	// Specifiers are the relative modules imported by a javascript file, they are resolved
+	// on each update as the resolved file depends on the other files of the repository
+	Specifiers []string `json:"specifiers,omitempty"`
+	// Imports are the files of the repository imported by the file
+	Imports []string `json:"imports,omitempty"`
+}
+
+func newGraph() *Graph {
+	return &Graph{Version: graphVersion, Files: make(map[string]*Node)}
+}
+
+// dependents returns the reverse dependency map, files keyed by the files importing them
+func (g *Graph) dependents() map[string][]string {
+	reverse := make(map[string][]string)
+	for file, node := range g.Files {
+		for _, imported := range node.Imports {
+			reverse[imported] = append(reverse[imported], file)
+		}
+	}
+	return reverse
+}
+
+// impacted returns the files which transitively import any of the changed files,
+// the changed files are not included in the result.
+func impacted(reverse map[string][]string, changed []string) []string {
+	visited := make(map[string]bool, len(changed))
+	queue := make([]string, 0, len(changed))
+	for _, file := range changed {
+		if !visited[file] {
+			visited[file] = true
+			queue = append(queue, file)
+		}
+	}
+	result := make([]string, 0)
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverse[file] {
+			if visited[dependent] {
+				continue
+			}
+			visited[dependent] = true
+			queue = append(queue, dependent)
+			result = append(result, dependent)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,66 @@
+package depgraph
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// jsExtensions are tried in order when resolving an import without extension
+var jsExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}
+
+// jsImportRegex matches the module specifier of the static and dynamic imports, re-exports,
+// require calls and jest module mocks
+var jsImportRegex = regexp.MustCompile(`(?:\bimport\s*(?:[\w*{}\s,$]+\s*from\s*)?|\bexport\s*[\w*{}\s,$]+\s*from\s*|\brequire\s*\(\s*|\bimport\s*\(\s*|\bjest\.(?:mock|requireActual)\s*\(\s*)['"` + "`" + `]([^'"` + "`" + `\n]+)['"` + "`" + `]`)
+
+func isJSFile(p string) bool {
+	ext := path.Ext(p)
+	for _, e := range jsExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJSImports returns the relative module specifiers imported by the source,
+// packages are ignored as only the files of the repository are part of the graph.
+func parseJSImports(src []byte) []string {
+	seen := make(map[string]bool)
+	specifiers := make([]string, 0)
+	for _, match := range jsImportRegex.FindAllSubmatch(src, -1) {
+		spec := string(match[1])
+		if spec != "." && spec != ".." && !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+			continue
+		}
+		if !seen[spec] {
+			seen[spec] = true
+			specifiers = append(specifiers, spec)
+		}
+	}
+	return specifiers
+}
+
+// resolveJSImport resolves the specifier imported by file like node and typescript do,
+// it returns false if the specifier does not resolve to a file of the repository.
+func resolveJSImport(file, spec string, files map[string]*Node) (string, bool) {
+	base := path.Join(path.Dir(file), spec)
+	candidates := []string{base}
+	// typescript allows importing the compiled .js file of a .ts source
+	if ext := path.Ext(base); ext == ".js" || ext == ".jsx" {
+		trimmed := strings.TrimSuffix(base, ext)
+		candidates = append(candidates, trimmed+".ts", trimmed+".tsx")
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range jsExtensions {
+		candidates = append(candidates, path.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if _, ok := files[candidate]; ok && isJSFile(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+// Package dryrun reports the impacted tests of a task without executing them.
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+// reportMimeType is the content type of the uploaded report
+const reportMimeType = "application/json"
+
+// Report is the impacted test list emitted in dry run mode
+type Report struct {
+	TaskID       string            `json:"taskID"`
+	BuildID      string            `json:"buildID"`
+	OrgID        string            `json:"orgID"`
+	RepoID       string            `json:"repoID"`
+	RepoSlug     string            `json:"repoSlug"`
+	BranchName   string            `json:"branchName"`
+	BaseCommit   string            `json:"baseCommit"`
+	TargetCommit string            `json:"targetCommit"`
+	ChangedFiles map[string]string `json:"changedFiles"`
+	// Discovery contains the test lists posted by the runners as is
+	Discovery []json.RawMessage `json:"discovery"`
+}
+
+// Reporter collects the test lists posted by the runners in dry run mode
+type Reporter struct {
+	azureClient core.AzureClient
+	logger      lumber.Logger
+	out         io.Writer
+	mu          sync.Mutex
+	discovery   []json.RawMessage
+}
+
+// New returns a new Reporter which writes the report to stdout and blob storage
+func New(azureClient core.AzureClient, logger lumber.Logger) *Reporter {
+	return &Reporter{
+		azureClient: azureClient,
+		logger:      logger,
+		out:         os.Stdout,
+	}
+}
+
+// AddDiscoveryResult stores the test list posted by a runner
+func (r *Reporter) AddDiscoveryResult(raw json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discovery = append(r.discovery, raw)
+}
+
+// Report writes the impacted tests to stdout and uploads them to blob storage
+func (r *Reporter) Report(ctx context.Context, payload *core.Payload, diff map[string]int) error {
+	r.mu.Lock()
+	discovery := r.discovery
+	r.mu.Unlock()
+	if len(discovery) == 0 {
+		return errors.New("no test list received from the runners")
+	}
+
+	report := Report{
+		TaskID:       payload.TaskID,
+		BuildID:      payload.BuildID,
+		OrgID:        payload.OrgID,
+		RepoID:       payload.RepoID,
+		RepoSlug:     payload.RepoSlug,
+		BranchName:   payload.BranchName,
+		BaseCommit:   payload.BaseCommit,
+		TargetCommit: payload.TargetCommit,
+		ChangedFiles: make(map[string]string, len(diff)),
+		Discovery:    discovery,
+	}
+	for file, changeType := range diff {
+		report.ChangedFiles[file] = core.ChangeTypes[changeType]
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		r.logger.Errorf("failed to marshal dry run report %v", err)
+		return err
+	}
+	if _, err := r.out.Write(append(body, '\n')); err != nil {
+		r.logger.Errorf("failed to write dry run report %v", err)
+		return err
+	}
+
+	blobPath := fmt.Sprintf("dryrun/%s/%s/%s.json", payload.OrgID, payload.RepoID, payload.TaskID)
+	blobURL, err := r.azureClient.Create(ctx, blobPath, bytes.NewReader(body), reportMimeType)
+	if err != nil {
+		r.logger.Errorf("failed to upload dry run report %v", err)
+		return err
+	}
+	r.logger.Infof("dry run report uploaded to %s", blobURL)
+	return nil
+}
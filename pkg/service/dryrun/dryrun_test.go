@@ -0,0 +1,45 @@
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	store, err := storage.NewLocalStore(t.TempDir(), "coverage", logger)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	r := New(store, logger)
+	r.out = &out
+	payload := &core.Payload{TaskID: "task", OrgID: "org", RepoID: "repo", TargetCommit: "abc"}
+	diff := map[string]int{"src/index.js": core.FileModified}
+
+	assert.NotNil(t, r.Report(context.Background(), payload, diff))
+
+	r.AddDiscoveryResult(json.RawMessage(`{"tests":[],"impactedTests":["1"]}`))
+	assert.Nil(t, r.Report(context.Background(), payload, diff))
+
+	got := Report{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, "task", got.TaskID)
+	assert.Equal(t, map[string]string{"src/index.js": "modified"}, got.ChangedFiles)
+	assert.Len(t, got.Discovery, 1)
+
+	blob, err := store.Find(context.Background(), "dryrun/org/repo/task.json")
+	assert.Nil(t, err)
+	defer blob.Close()
+	uploaded, err := ioutil.ReadAll(blob)
+	assert.Nil(t, err)
+	assert.Equal(t, bytes.TrimSpace(out.Bytes()), uploaded)
+}
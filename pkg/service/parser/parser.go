@@ -7,11 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 )
 
 // Parser represents the code parser object
@@ -20,9 +24,11 @@ type Parser struct {
 	logger           lumber.Logger
 	TASConfigManager core.TASConfigManager
 	httpClient       http.Client
-	endpoint         string
+	zeroConfig       bool
 }
 
+const parserEndpoint = "/ymlparser"
+
 var tierEnumMapping = map[core.Tier]int{
 	core.XSmall: 1,
 	core.Small:  2,
@@ -32,16 +38,15 @@ var tierEnumMapping = map[core.Tier]int{
 }
 
 //New returns a new instance of Parser
-func New(ctx context.Context, TASConfigManager core.TASConfigManager,
+func New(ctx context.Context, cfg *config.NucleusConfig, TASConfigManager core.TASConfigManager,
 	logger lumber.Logger) (*Parser, error) {
 	return &Parser{
 		logger:           logger,
 		ctx:              ctx,
 		TASConfigManager: TASConfigManager,
-		endpoint:         global.NeuronHost + "/ymlparser",
-		httpClient: http.Client{
-			Timeout: 30 * time.Second,
-		}}, nil
+		httpClient:       requestutils.NewResilientClient(30 * time.Second),
+		zeroConfig:       cfg.ZeroConfig,
+	}, nil
 
 }
 
@@ -54,7 +59,13 @@ func (p *Parser) PerformParsing(payload *core.Payload) error {
 		Status:         core.Passed,
 	}
 
-	if tasConfig, err := p.TASConfigManager.LoadConfig(p.ctx,
+	if p.zeroConfig && !p.configExists(targetCommit+payload.TasFileName) {
+		// the tasks infer the configuration from the manifests of the repository once cloned
+		p.logger.Infof("Configuration file not found for commitID: %s, buildID: %s, it is inferred by the tasks",
+			targetCommit, payload.BuildID)
+		parserPayloadStatus.Tier = core.Small
+		parserPayloadStatus.Inferred = true
+	} else if tasConfig, err := p.TASConfigManager.LoadConfig(p.ctx,
 		targetCommit+payload.TasFileName, payload.EventType, true); err != nil {
 		p.logger.Infof("Parsing failed for commitID: %s, buildID: %s, error: %v", targetCommit, payload.BuildID, err)
 		parserPayloadStatus.Status = core.Error
@@ -62,6 +73,10 @@ func (p *Parser) PerformParsing(payload *core.Payload) error {
 	} else {
 		parserPayloadStatus.Tier = tasConfig.Tier
 		parserPayloadStatus.ContainerImage = tasConfig.ContainerImage
+		parserPayloadStatus.Clone = tasConfig.Clone
+		if tasConfig.Matrix != nil {
+			parserPayloadStatus.SubTasks = tasConfig.Matrix.SubTasks()
+		}
 		if _, err := isValidLicenseTier(tasConfig.Tier, payload.LicenseTier); err != nil {
 			p.logger.Errorf("LicenseTier validation failed error:%v", err)
 			parserPayloadStatus.Status = core.Error
@@ -84,6 +99,12 @@ func (p *Parser) PerformParsing(payload *core.Payload) error {
 	return nil
 }
 
+// configExists checks if the configuration file was cloned
+func (p *Parser) configExists(path string) bool {
+	_, err := os.Stat(filepath.Join(global.RepoDir, path))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
 func (p *Parser) sendParserResponse(payload *core.ParserResponse) error {
 
 	reqBody, err := json.Marshal(payload)
@@ -92,7 +113,7 @@ func (p *Parser) sendParserResponse(payload *core.ParserResponse) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest(http.MethodPost, global.NeuronURL(parserEndpoint), bytes.NewBuffer(reqBody))
 	if err != nil {
 		p.logger.Errorf("failed to create new request %v", err)
 		return err
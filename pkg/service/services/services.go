@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// startTimeout is the maximum time to wait for a service to be ready
+	startTimeout = 5 * time.Minute
+	// pollInterval is the interval between the readiness checks of the services
+	pollInterval = 2 * time.Second
+	// dialTimeout is the timeout of a port readiness check
+	dialTimeout   = time.Second
+	taskIDLabel   = "tas.task-id"
+	healthHealthy = "healthy"
+	healthFailed  = "unhealthy"
+)
+
+type manager struct {
+	secretParser core.SecretParser
+	logger       lumber.Logger
+	client       *client.Client
+	mu           sync.Mutex
+	containers   []string
+}
+
+// New returns a new ServiceManager which runs the services as docker containers in the network of the nucleus container
+func New(secretParser core.SecretParser, logger lumber.Logger) core.ServiceManager {
+	return &manager{secretParser: secretParser, logger: logger}
+}
+
+// Start pulls and starts the services concurrently and waits until all of them are ready
+func (m *manager) Start(ctx context.Context, payload *core.Payload, services map[string]core.Service, secretData map[string]string) (map[string]string, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+	if m.client == nil {
+		c, err := client.NewClientWithOpts(client.FromEnv)
+		if err != nil {
+			return nil, err
+		}
+		m.client = c
+	}
+	networkID, err := m.networkID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	errChan := make(chan error, len(services))
+	for name := range services {
+		go func(name string, service core.Service) {
+			errChan <- m.start(ctx, payload.TaskID, networkID, name, service, secretData)
+		}(name, services[name])
+	}
+	var startErr error
+	for range services {
+		if err := <-errChan; err != nil && startErr == nil {
+			startErr = err
+		}
+	}
+	if startErr != nil {
+		return nil, startErr
+	}
+	return Env(services), nil
+}
+
+// Stop removes the containers of the started services along with their volumes
+func (m *manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stopErr error
+	for _, id := range m.containers {
+		if err := m.client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			m.logger.Errorf("failed to remove service container %s: %v", id, err)
+			stopErr = err
+		}
+	}
+	m.containers = nil
+	return stopErr
+}
+
+// networkID returns the network of the nucleus container, the container hostname is its id
+func (m *manager) networkID(ctx context.Context) (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	info, err := m.client.ContainerInspect(ctx, hostname)
+	if err != nil {
+		m.logger.Errorf("failed to inspect nucleus container %s: %v", hostname, err)
+		return "", errors.New("services require access to the docker daemon of the nucleus container")
+	}
+	for _, endpoint := range info.NetworkSettings.Networks {
+		if endpoint != nil && endpoint.NetworkID != "" {
+			return endpoint.NetworkID, nil
+		}
+	}
+	return "", fmt.Errorf("no network found for nucleus container %s", hostname)
+}
+
+func (m *manager) start(ctx context.Context, taskID, networkID, name string, service core.Service, secretData map[string]string) error {
+	env := make([]string, 0, len(service.Env))
+	for k, v := range service.Env {
+		val, err := m.secretParser.SubstituteSecret(v, secretData)
+		if err != nil {
+			return err
+		}
+		env = append(env, fmt.Sprintf("%s=%s", k, val))
+	}
+	sort.Strings(env)
+
+	reader, err := m.client.ImagePull(ctx, service.Image, types.ImagePullOptions{})
+	if err != nil {
+		m.logger.Errorf("failed to pull image %s of service %s: %v", service.Image, name, err)
+		return fmt.Errorf("failed to pull image of service %s", name)
+	}
+	// the image is pulled while the progress is read
+	_, err = io.Copy(ioutil.Discard, reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to pull image of service %s: %v", name, err)
+	}
+
+	containerConfig := &container.Config{
+		Image:       service.Image,
+		Env:         env,
+		Cmd:         service.Command,
+		Labels:      map[string]string{taskIDLabel: taskID},
+		Healthcheck: healthConfig(service.HealthCheck),
+	}
+	networkConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{networkID: {Aliases: []string{name}}},
+	}
+	resp, err := m.client.ContainerCreate(ctx, containerConfig, &container.HostConfig{}, networkConfig, nil,
+		fmt.Sprintf("tas-%s-%s", taskID, name))
+	if err != nil {
+		m.logger.Errorf("failed to create container of service %s: %v", name, err)
+		return fmt.Errorf("failed to create container of service %s", name)
+	}
+	m.mu.Lock()
+	m.containers = append(m.containers, resp.ID)
+	m.mu.Unlock()
+
+	if err := m.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		m.logger.Errorf("failed to start container of service %s: %v", name, err)
+		return fmt.Errorf("failed to start container of service %s", name)
+	}
+	if err := m.waitReady(ctx, resp.ID, name, service); err != nil {
+		return err
+	}
+	m.logger.Infof("service %s is ready", name)
+	return nil
+}
+
+// waitReady waits until the health check of the service passes, or its port accepts connections
+func (m *manager) waitReady(ctx context.Context, id, name string, service core.Service) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := m.client.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container of service %s: %v", name, err)
+		}
+		if !info.State.Running {
+			return fmt.Errorf("service %s exited with code %d", name, info.State.ExitCode)
+		}
+		switch {
+		case service.HealthCheck != nil:
+			if info.State.Health != nil {
+				if info.State.Health.Status == healthHealthy {
+					return nil
+				}
+				if info.State.Health.Status == healthFailed {
+					return fmt.Errorf("health check of service %s failed", name)
+				}
+			}
+		case service.Port != 0:
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(name, strconv.Itoa(service.Port)), dialTimeout)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+		default:
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("service %s is not ready: %v", name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func healthConfig(check *core.ServiceHealthCheck) *container.HealthConfig {
+	if check == nil {
+		return nil
+	}
+	return &container.HealthConfig{
+		Test:     []string{"CMD-SHELL", check.Command},
+		Interval: time.Duration(check.Interval) * time.Second,
+		Timeout:  time.Duration(check.Timeout) * time.Second,
+		Retries:  check.Retries,
+	}
+}
+
+// Env returns the environment variables exposing the host and port of the services to the tests
+func Env(services map[string]core.Service) map[string]string {
+	env := make(map[string]string, 2*len(services))
+	for name, service := range services {
+		prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		env[prefix+"_HOST"] = name
+		if service.Port != 0 {
+			env[prefix+"_PORT"] = strconv.Itoa(service.Port)
+		}
+	}
+	return env
+}
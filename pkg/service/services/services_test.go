@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	env := Env(map[string]core.Service{
+		"postgres":    {Image: "postgres:14", Port: 5432},
+		"redis-cache": {Image: "redis:6"},
+	})
+	assert.Equal(t, map[string]string{
+		"POSTGRES_HOST":    "postgres",
+		"POSTGRES_PORT":    "5432",
+		"REDIS_CACHE_HOST": "redis-cache",
+	}, env)
+}
+
+func TestHealthConfig(t *testing.T) {
+	assert.Nil(t, healthConfig(nil))
+	hc := healthConfig(&core.ServiceHealthCheck{Command: "pg_isready", Interval: 5, Retries: 3})
+	assert.Equal(t, []string{"CMD-SHELL", "pg_isready"}, hc.Test)
+	assert.Equal(t, 5*time.Second, hc.Interval)
+	assert.Equal(t, 3, hc.Retries)
+}
+
+func TestStartNoServices(t *testing.T) {
+	env, err := New(nil, nil).Start(context.Background(), &core.Payload{}, nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, env)
+}
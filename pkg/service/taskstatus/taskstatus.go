@@ -0,0 +1,161 @@
+// Package taskstatus tracks the progress of the pipeline, so that the orchestrators can tell a hung nucleus
+// apart from a slow one.
+package taskstatus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// Progress is the number of tests which completed out of the tests of the task
+type Progress struct {
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+	// Total is 0 if the number of tests is not known before they complete, e.g. if the runners select them
+	Total int `json:"total,omitempty"`
+}
+
+// StageError is the last error of the pipeline
+type StageError struct {
+	Stage   string    `json:"stage"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Status is the status of the task run by the nucleus
+type Status struct {
+	TaskID string `json:"taskID,omitempty"`
+	// Stage is the running stage, or the last stage which ran
+	Stage          string     `json:"stage,omitempty"`
+	StageStartedAt *time.Time `json:"stageStartedAt,omitempty"`
+	// Ready is set once the payload is loaded, until a stage fails
+	Ready     bool        `json:"ready"`
+	Progress  Progress    `json:"progress"`
+	LastError *StageError `json:"lastError,omitempty"`
+	// LastActivity is the time of the last stage transition or test result
+	LastActivity time.Time `json:"lastActivity"`
+	// Stalled is set if there was no activity for longer than the stall timeout
+	Stalled bool `json:"stalled"`
+}
+
+// Tracker records the stages and the test results of the pipeline, it is a core.StageHook
+type Tracker struct {
+	mu           sync.RWMutex
+	status       Status
+	tests        map[string]string
+	stallTimeout time.Duration
+	now          func() time.Time
+}
+
+// New returns a new instance of Tracker
+func New(cfg *config.NucleusConfig) *Tracker {
+	return &Tracker{
+		status:       Status{LastActivity: time.Now()},
+		tests:        make(map[string]string),
+		stallTimeout: time.Duration(cfg.StallTimeout) * time.Second,
+		now:          time.Now,
+	}
+}
+
+// Watch counts the test results published by stats until ctx is done
+func (t *Tracker) Watch(ctx context.Context, stats core.TestStats) {
+	results, unsubscribe := stats.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case result := <-results:
+			t.addResult(&result)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// BeforeStage records the stage as running
+func (t *Tracker) BeforeStage(ctx context.Context, stage string, state *core.StageState) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.status.Stage = stage
+	t.status.StageStartedAt = &now
+	t.status.LastActivity = now
+	if state.Payload != nil {
+		t.status.TaskID = state.Payload.TaskID
+		if stage == core.StageExecution {
+			t.status.Progress.Total = expectedTests(state.Payload)
+		}
+	}
+	return nil
+}
+
+// AfterStage records the error of the stage, the nucleus is ready once the payload is loaded
+func (t *Tracker) AfterStage(ctx context.Context, stage string, state *core.StageState, err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.status.LastActivity = now
+	if err != nil {
+		t.status.Ready = false
+		t.status.LastError = &StageError{Stage: stage, Message: err.Error(), Time: now}
+		return nil
+	}
+	if stage == core.StagePayload {
+		t.status.Ready = true
+	}
+	return nil
+}
+
+// Status returns the status of the task
+func (t *Tracker) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	status := t.status
+	status.Stalled = t.stallTimeout > 0 && t.now().Sub(status.LastActivity) > t.stallTimeout
+	return status
+}
+
+func (t *Tracker) addResult(result *core.ExecutionResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	added := false
+	for i := range result.TestPayload {
+		test := &result.TestPayload[i]
+		if test.Status == core.TestStarted {
+			continue
+		}
+		// the partial results are published again with the complete result of the runner
+		if previous, ok := t.tests[test.TestID]; ok && previous == test.Status {
+			continue
+		}
+		t.tests[test.TestID] = test.Status
+		added = true
+	}
+	if !added {
+		return
+	}
+	t.status.LastActivity = t.now()
+	t.status.Progress.Done = len(t.tests)
+	t.status.Progress.Failed = 0
+	for _, status := range t.tests {
+		if status == core.TestFailed || status == core.TestTimedOut {
+			t.status.Progress.Failed++
+		}
+	}
+}
+
+// expectedTests returns the number of tests run by the task if the payload lists them, and 0 otherwise
+func expectedTests(payload *core.Payload) int {
+	if payload.Locators != "" && payload.LocatorAddress == "" {
+		return len(strings.Split(payload.Locators, global.TestLocatorsDelimiter))
+	}
+	if payload.Selection != nil && payload.Selection.Strategy == core.SelectionNamed {
+		return len(payload.Selection.Tests)
+	}
+	return 0
+}
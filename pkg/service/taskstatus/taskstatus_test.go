@@ -0,0 +1,51 @@
+package taskstatus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := New(&config.NucleusConfig{StallTimeout: 60})
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+	assert.False(t, tracker.Status().Ready)
+
+	state := &core.StageState{}
+	assert.Nil(t, tracker.BeforeStage(ctx, core.StagePayload, state))
+	state.Payload = &core.Payload{TaskID: "task", Locators: "a.test.js#TAS#b.test.js##suite##test"}
+	assert.Nil(t, tracker.AfterStage(ctx, core.StagePayload, state, nil))
+	assert.True(t, tracker.Status().Ready)
+
+	assert.Nil(t, tracker.BeforeStage(ctx, core.StageExecution, state))
+	tracker.addResult(&core.ExecutionResult{TestPayload: []core.TestPayload{
+		{TestID: "1", Status: core.TestStarted},
+		{TestID: "2", Status: core.TestFailed},
+	}})
+	// the complete result of the runner repeats the partial results
+	tracker.addResult(&core.ExecutionResult{TestPayload: []core.TestPayload{
+		{TestID: "1", Status: "passed"},
+		{TestID: "2", Status: core.TestFailed},
+	}})
+	status := tracker.Status()
+	assert.Equal(t, "task", status.TaskID)
+	assert.Equal(t, core.StageExecution, status.Stage)
+	assert.Equal(t, Progress{Done: 2, Failed: 1, Total: 2}, status.Progress)
+	assert.False(t, status.Stalled)
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, tracker.Status().Stalled)
+
+	assert.Nil(t, tracker.AfterStage(ctx, core.StageExecution, state, errors.New("runner exited")))
+	status = tracker.Status()
+	assert.False(t, status.Ready)
+	assert.False(t, status.Stalled)
+	assert.Equal(t, &StageError{Stage: core.StageExecution, Message: "runner exited", Time: now}, status.LastError)
+}
@@ -0,0 +1,48 @@
+package teststats
+
+import (
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// watcherBufferSize is the number of results buffered for each watcher
+const watcherBufferSize = 256
+
+// broadcaster sends the results received from the runners to the watchers
+type broadcaster struct {
+	mu       sync.Mutex
+	watchers map[chan core.ExecutionResult]struct{}
+}
+
+// Subscribe returns a channel which receives the results as they are received from the runners,
+// the returned function must be called to unsubscribe.
+func (s *ProcStats) Subscribe() (<-chan core.ExecutionResult, func()) {
+	ch := make(chan core.ExecutionResult, watcherBufferSize)
+	s.broadcaster.mu.Lock()
+	s.broadcaster.watchers[ch] = struct{}{}
+	s.broadcaster.mu.Unlock()
+	return ch, func() {
+		s.broadcaster.mu.Lock()
+		defer s.broadcaster.mu.Unlock()
+		delete(s.broadcaster.watchers, ch)
+	}
+}
+
+// Publish sends the partial or complete result of a runner to all the watchers. The runners are
+// never blocked by the watchers, results are dropped for the watchers which are not keeping up.
+func (s *ProcStats) Publish(result core.ExecutionResult) {
+	s.broadcaster.mu.Lock()
+	defer s.broadcaster.mu.Unlock()
+	dropped := 0
+	for ch := range s.broadcaster.watchers {
+		select {
+		case ch <- result:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		s.logger.Warnf("dropped result updates for %d slow watchers", dropped)
+	}
+}
@@ -0,0 +1,28 @@
+package teststats
+
+import (
+	"sync"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// hookTimings stores the durations of the lifecycle hooks
+type hookTimings struct {
+	mu      sync.Mutex
+	timings []core.HookTiming
+}
+
+// RecordHookTiming records the duration of a lifecycle hook
+func (s *ProcStats) RecordHookTiming(timing core.HookTiming) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.timings = append(s.hooks.timings, timing)
+	s.logger.Debugf("hook %s completed with status %s in %dms", timing.Hook, timing.Status, timing.Duration)
+}
+
+// HookTimings returns the durations of the hooks run so far, in the order they were run
+func (s *ProcStats) HookTimings() []core.HookTiming {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	return append([]core.HookTiming(nil), s.hooks.timings...)
+}
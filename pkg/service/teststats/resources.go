@@ -0,0 +1,120 @@
+package teststats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/procfs"
+)
+
+// summarize returns the peak and average usage of the RecordTime sorted samples, the disk io is the
+// difference between the first and last sample unless sinceStart is set.
+func summarize(processStats []*procfs.Stats, sinceStart bool) *core.ResourceUsage {
+	if len(processStats) == 0 {
+		return nil
+	}
+	usage := &core.ResourceUsage{Samples: len(processStats)}
+	var cpu float64
+	var memory uint64
+	for _, stats := range processStats {
+		cpu += stats.CPUPercentage
+		memory += stats.MemConsumed
+		if stats.CPUPercentage > usage.PeakCPU {
+			usage.PeakCPU = stats.CPUPercentage
+		}
+		if stats.MemConsumed > usage.PeakMemory {
+			usage.PeakMemory = stats.MemConsumed
+		}
+	}
+	usage.AvgCPU = cpu / float64(len(processStats))
+	usage.AvgMemory = memory / uint64(len(processStats))
+	first, last := processStats[0], processStats[len(processStats)-1]
+	usage.DiskRead, usage.DiskWrite = last.DiskRead, last.DiskWrite
+	if !sinceStart {
+		usage.DiskRead -= min(first.DiskRead, last.DiskRead)
+		usage.DiskWrite -= min(first.DiskWrite, last.DiskWrite)
+	}
+	return usage
+}
+
+// summarizeConcurrent returns the usage of processes running at the same time, the peaks are
+// summed as an upper bound as the samples of the processes are not taken at the same time.
+func summarizeConcurrent(usages []*core.ResourceUsage) *core.ResourceUsage {
+	var total *core.ResourceUsage
+	for _, usage := range usages {
+		if usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &core.ResourceUsage{}
+		}
+		total.PeakCPU += usage.PeakCPU
+		total.AvgCPU += usage.AvgCPU
+		total.PeakMemory += usage.PeakMemory
+		total.AvgMemory += usage.AvgMemory
+		total.DiskRead += usage.DiskRead
+		total.DiskWrite += usage.DiskWrite
+		total.Samples += usage.Samples
+	}
+	return total
+}
+
+type fileInterval struct {
+	file       string
+	start, end time.Time
+}
+
+// fileUsage returns the usage of each test file from the samples taken while its tests were run.
+// The samples can not be attributed to a file if the tests of other files ran at the same time,
+// e.g. by parallel workers, the usage of these files is not returned.
+func (s *ProcStats) fileUsage(testResults []core.TestPayload, processStats []*procfs.Stats) []core.FileResourceUsage {
+	byFile := make(map[string]*fileInterval)
+	for i := range testResults {
+		result := &testResults[i]
+		if result.FilePath == "" || result.StartTime.IsZero() {
+			continue
+		}
+		end := result.StartTime.Add(time.Duration(result.Duration) * time.Millisecond)
+		interval, ok := byFile[result.FilePath]
+		if !ok {
+			byFile[result.FilePath] = &fileInterval{file: result.FilePath, start: result.StartTime, end: end}
+			continue
+		}
+		if result.StartTime.Before(interval.start) {
+			interval.start = result.StartTime
+		}
+		if end.After(interval.end) {
+			interval.end = end
+		}
+	}
+	intervals := make([]*fileInterval, 0, len(byFile))
+	for _, interval := range byFile {
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+
+	usages := make([]core.FileResourceUsage, 0, len(intervals))
+	// the latest end of the intervals started before the current one
+	var prevEnd time.Time
+	for i, interval := range intervals {
+		overlaps := prevEnd.After(interval.start) || (i+1 < len(intervals) && interval.end.After(intervals[i+1].start))
+		if interval.end.After(prevEnd) {
+			prevEnd = interval.end
+		}
+		if overlaps {
+			continue
+		}
+		if usage := summarize(s.getProcsForInterval(interval.start, interval.end, processStats), false); usage != nil {
+			usages = append(usages, core.FileResourceUsage{File: interval.file, ResourceUsage: *usage})
+		}
+	}
+	return usages
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package teststats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/procfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileUsage(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	processStats := make([]*procfs.Stats, 0)
+	for i := 0; i < 10; i++ {
+		processStats = append(processStats, &procfs.Stats{
+			CPUPercentage: float64(10 * i),
+			MemConsumed:   uint64(100 * i),
+			DiskWrite:     uint64(1000 * i),
+			RecordTime:    at(i),
+		})
+	}
+	results := []core.TestPayload{
+		{FilePath: "a.spec.js", StartTime: at(0), Duration: 1500},
+		{FilePath: "a.spec.js", StartTime: at(1), Duration: 1500},
+		// b and c overlap, e.g. run by parallel workers
+		{FilePath: "b.spec.js", StartTime: at(3), Duration: 3000},
+		{FilePath: "c.spec.js", StartTime: at(4), Duration: 1000},
+		{FilePath: "d.spec.js", StartTime: at(7), Duration: 2000},
+	}
+	s := &ProcStats{}
+	usages := s.fileUsage(results, processStats)
+	assert.Equal(t, []core.FileResourceUsage{
+		{File: "a.spec.js", ResourceUsage: core.ResourceUsage{PeakCPU: 20, AvgCPU: 10, PeakMemory: 200, AvgMemory: 100, DiskWrite: 2000, Samples: 3}},
+		{File: "d.spec.js", ResourceUsage: core.ResourceUsage{PeakCPU: 80, AvgCPU: 75, PeakMemory: 800, AvgMemory: 750, DiskWrite: 1000, Samples: 2}},
+	}, usages)
+
+	run := summarize(processStats, true)
+	assert.Equal(t, &core.ResourceUsage{PeakCPU: 90, AvgCPU: 45, PeakMemory: 900, AvgMemory: 450, DiskWrite: 9000, Samples: 10}, run)
+	assert.Nil(t, summarize(nil, true))
+}
+
+func TestMergeResourceUsage(t *testing.T) {
+	a := &core.ResourceUsage{PeakCPU: 50, AvgCPU: 20, PeakMemory: 300, AvgMemory: 200, DiskRead: 10, Samples: 1}
+	b := &core.ResourceUsage{PeakCPU: 30, AvgCPU: 10, PeakMemory: 500, AvgMemory: 100, DiskRead: 5, Samples: 3}
+	var none *core.ResourceUsage
+	assert.Equal(t, a, none.Merge(a))
+	assert.Equal(t, &core.ResourceUsage{PeakCPU: 50, AvgCPU: 12.5, PeakMemory: 500, AvgMemory: 125, DiskRead: 15, Samples: 4}, a.Merge(b))
+	assert.Equal(t, &core.ResourceUsage{PeakCPU: 80, AvgCPU: 30, PeakMemory: 800, AvgMemory: 300, DiskRead: 15, Samples: 4},
+		summarizeConcurrent([]*core.ResourceUsage{a, nil, b}))
+}
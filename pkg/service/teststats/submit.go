@@ -0,0 +1,22 @@
+package teststats
+
+import (
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// Submit hands over the result of a runner process, which is waited for after the process exits. The
+// started updates are only sent to the watchers, they are dropped from the submitted result whatever
+// the transport the runner used. It returns the number of submitted test results.
+func (s *ProcStats) Submit(result core.ExecutionResult) int {
+	tests := make([]core.TestPayload, 0, len(result.TestPayload))
+	for i := range result.TestPayload {
+		if result.TestPayload[i].Status != core.TestStarted {
+			tests = append(tests, result.TestPayload[i])
+		}
+	}
+	result.TestPayload = tests
+	go func() {
+		s.ExecutionResultInputChannel <- result
+	}()
+	return len(tests)
+}
@@ -0,0 +1,29 @@
+package teststats
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmit(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	ts, err := New(nil, logger)
+	assert.Nil(t, err)
+
+	result := core.ExecutionResult{TaskID: "task", TestPayload: []core.TestPayload{
+		{TestID: "1", Status: core.TestStarted},
+		{TestID: "1", Status: "passed"},
+		{TestID: "2", Status: core.TestStarted},
+	}}
+	assert.Equal(t, 1, ts.Submit(result))
+
+	submitted := <-ts.ExecutionResultInputChannel
+	assert.Equal(t, "task", submitted.TaskID)
+	assert.Equal(t, []core.TestPayload{{TestID: "1", Status: "passed"}}, submitted.TestPayload)
+	// the result published to the watchers is not modified
+	assert.Len(t, result.TestPayload, 3)
+}
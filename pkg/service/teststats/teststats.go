@@ -11,6 +11,7 @@ import (
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 	"github.com/LambdaTest/synapse/pkg/procfs"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 )
 
 //ProcStats represents the process stats for a particular pid
@@ -20,17 +21,19 @@ type ProcStats struct {
 	ExecutionResultInputChannel  chan core.ExecutionResult
 	wg                           sync.WaitGroup
 	ExecutionResultOutputChannel chan core.ExecutionResult
+	broadcaster                  broadcaster
+	hooks                        hookTimings
 }
 
 // New returns instance of ProcStats
 func New(cfg *config.NucleusConfig, logger lumber.Logger) (*ProcStats, error) {
 	return &ProcStats{
-		logger:                      logger,
-		ExecutionResultInputChannel: make(chan core.ExecutionResult),
-		httpClient: http.Client{
-			Timeout: 45 * time.Second,
-		},
-		ExecutionResultOutputChannel: make(chan core.ExecutionResult),
+		logger:                       logger,
+		ExecutionResultInputChannel:  make(chan core.ExecutionResult),
+		httpClient:                   requestutils.NewClient(45 * time.Second),
+		// buffered so that the capturing goroutine never blocks if the runner fails before the result is received
+		ExecutionResultOutputChannel: make(chan core.ExecutionResult, 1),
+		broadcaster:                  broadcaster{watchers: make(map[chan core.ExecutionResult]struct{})},
 	}, nil
 
 }
@@ -56,6 +59,8 @@ func (s *ProcStats) CaptureTestStats(pid int32) error {
 			// https://www.freecodecamp.org/news/generics-in-golang/
 			s.appendStatsToTests(executionResult.TestPayload, processStats)
 			s.appendStatsToTestSuites(executionResult.TestSuitePayload, processStats)
+			executionResult.ResourceUsage = summarize(processStats, true)
+			executionResult.FileResourceUsage = s.fileUsage(executionResult.TestPayload, processStats)
 
 			s.ExecutionResultOutputChannel <- executionResult
 		default:
@@ -72,6 +77,71 @@ func (s *ProcStats) CaptureTestStats(pid int32) error {
 	return nil
 }
 
+// CaptureWorkerStats combines the ps stats of the worker processes running in parallel.
+// The execution results of all the workers are merged into a single result, as the tests of the
+// workers overlap in time the stats of each test include the samples of all the workers.
+func (s *ProcStats) CaptureWorkerStats(pids []int32) error {
+	procs := make([]*procfs.Proc, 0, len(pids))
+	for _, pid := range pids {
+		ps, err := procfs.New(pid, global.SamplingTime, false)
+		if err != nil {
+			s.logger.Errorf("failed to find process stats with pid %d %v", pid, err)
+			return err
+		}
+		procs = append(procs, ps)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		processStats := make([]*procfs.Stats, 0)
+		usages := make([]*core.ResourceUsage, 0, len(procs))
+		for _, ps := range procs {
+			wg.Add(1)
+			go func(ps *procfs.Proc) {
+				defer wg.Done()
+				stats := ps.GetStatsInInterval()
+				mu.Lock()
+				processStats = append(processStats, stats...)
+				usages = append(usages, summarize(stats, true))
+				mu.Unlock()
+			}(ps)
+		}
+		wg.Wait()
+		sort.Slice(processStats, func(i, j int) bool {
+			return processStats[i].RecordTime.Before(processStats[j].RecordTime)
+		})
+
+		merged := core.ExecutionResult{}
+		received := 0
+	drain:
+		for received < len(pids) {
+			select {
+			case executionResult := <-s.ExecutionResultInputChannel:
+				received++
+				merged.TaskID, merged.BuildID, merged.RepoID = executionResult.TaskID, executionResult.BuildID, executionResult.RepoID
+				merged.OrgID, merged.CommitID = executionResult.OrgID, executionResult.CommitID
+				merged.TestPayload = append(merged.TestPayload, executionResult.TestPayload...)
+				merged.TestSuitePayload = append(merged.TestSuitePayload, executionResult.TestSuitePayload...)
+			default:
+				break drain
+			}
+		}
+		if received < len(pids) {
+			s.logger.Warnf("test results found for %d of %d workers", received, len(pids))
+		}
+		s.appendStatsToTests(merged.TestPayload, processStats)
+		s.appendStatsToTestSuites(merged.TestSuitePayload, processStats)
+		// the file usage is not set as the files of the workers run at the same time
+		merged.ResourceUsage = summarizeConcurrent(usages)
+		s.ExecutionResultOutputChannel <- merged
+	}()
+
+	return nil
+}
+
 // processStats is RecordTime sorted
 func (s *ProcStats) getProcsForInterval(start, end time.Time, processStats []*procfs.Stats) []*procfs.Stats {
 	n := len(processStats)
@@ -0,0 +1,77 @@
+package testtiming
+
+import (
+	"sort"
+	"strings"
+)
+
+// locatorDelimiter separates the file, suites and test in a test locator
+const locatorDelimiter = "##"
+
+// SplitByDuration partitions the locators into at most k buckets with similar predicted duration.
+// Locators without history are predicted using the average of the known durations.
+// The buckets are filled greedily starting with the longest locator (LPT scheduling).
+func SplitByDuration(locators []string, k int, timings map[string]int) [][]string {
+	if k > len(locators) {
+		k = len(locators)
+	}
+	if k < 1 {
+		return nil
+	}
+	predicted := predict(locators, timings)
+	order := make([]int, len(locators))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return predicted[order[i]] > predicted[order[j]] })
+
+	buckets := make([][]string, k)
+	totals := make([]int, k)
+	for _, idx := range order {
+		next := 0
+		for b := 1; b < k; b++ {
+			if totals[b] < totals[next] {
+				next = b
+			}
+		}
+		buckets[next] = append(buckets[next], locators[idx])
+		totals[next] += predicted[idx]
+	}
+	return buckets
+}
+
+// predict returns the duration of each locator. Locators of a file or suite
+// are predicted as the sum of the durations of their tests.
+func predict(locators []string, timings map[string]int) []int {
+	predicted := make([]int, len(locators))
+	known, total := 0, 0
+	for i, locator := range locators {
+		d, ok := timings[locator]
+		if !ok {
+			prefix := locator + locatorDelimiter
+			for k, v := range timings {
+				if strings.HasPrefix(k, prefix) {
+					d += v
+					ok = true
+				}
+			}
+		}
+		if ok {
+			predicted[i] = d
+			known++
+			total += d
+		} else {
+			predicted[i] = -1
+		}
+	}
+	avg := 1
+	if known > 0 && total/known > 0 {
+		avg = total / known
+	}
+	for i := range predicted {
+		if predicted[i] < 0 {
+			predicted[i] = avg
+		}
+	}
+	return predicted
+}
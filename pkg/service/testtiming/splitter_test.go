@@ -0,0 +1,23 @@
+package testtiming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitByDuration(t *testing.T) {
+	timings := map[string]int{
+		"a.test.js##suite##slow": 900,
+		"a.test.js##suite##fast": 100,
+		"b.test.js":              500,
+		"c.test.js":              400,
+		"d.test.js":              100,
+	}
+	// e.test.js has no history and is predicted with the average
+	buckets := SplitByDuration([]string{"a.test.js", "b.test.js", "c.test.js", "d.test.js", "e.test.js"}, 2, timings)
+	assert.Equal(t, [][]string{{"a.test.js", "c.test.js"}, {"b.test.js", "e.test.js", "d.test.js"}}, buckets)
+
+	assert.Len(t, SplitByDuration([]string{"a.test.js"}, 4, timings), 1)
+	assert.Nil(t, SplitByDuration(nil, 4, timings))
+}
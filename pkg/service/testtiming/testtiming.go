@@ -0,0 +1,231 @@
+// Package testtiming provides the historical test durations used for splitting tests by time
+package testtiming
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+)
+
+const (
+	// weight of the latest duration in the moving average stored in the local cache
+	smoothingFactor  = 0.5
+	timingsEndpoint  = "/test-timings"
+	failuresEndpoint = "/test-failures"
+)
+
+type timingResponse struct {
+	Timings map[string]int `json:"timings"`
+}
+
+type failuresResponse struct {
+	Failures []string `json:"failures"`
+}
+
+type timingStore struct {
+	cfg        *config.NucleusConfig
+	logger     lumber.Logger
+	httpClient http.Client
+	cacheDir   string
+	mu         sync.Mutex
+}
+
+// New returns a new TestTimingStore. Timings are fetched from neuron, which records them from the
+// test reports. In local runner mode the timings are stored in a cache on local disk instead.
+func New(cfg *config.NucleusConfig, logger lumber.Logger) core.TestTimingStore {
+	return &timingStore{
+		cfg:        cfg,
+		logger:     logger,
+		cacheDir:   filepath.Join(cfg.Storage.LocalDir, "test-timings"),
+		httpClient: requestutils.NewResilientClient(15 * time.Second),
+	}
+}
+
+// GetTimings returns the durations in milliseconds keyed by test locator
+func (t *timingStore) GetTimings(ctx context.Context, repoID, branch string) (map[string]int, error) {
+	if t.cfg.LocalRunner {
+		return t.readCache(repoID)
+	}
+	return t.fetchFromNeuron(ctx, repoID, branch)
+}
+
+// GetFailures returns the locators of the tests which failed in their latest execution
+func (t *timingStore) GetFailures(ctx context.Context, repoID, branch string) ([]string, error) {
+	if t.cfg.LocalRunner {
+		failures := make(map[string]bool)
+		if err := readFile(t.failuresPath(repoID), &failures); err != nil {
+			return nil, err
+		}
+		locators := make([]string, 0, len(failures))
+		for locator := range failures {
+			locators = append(locators, locator)
+		}
+		sort.Strings(locators)
+		return locators, nil
+	}
+	return t.fetchFailuresFromNeuron(ctx, repoID, branch)
+}
+
+// StoreTimings merges the durations of the executed tests in the local cache. The failed tests are
+// stored as well, a test is removed from the failures once it passes.
+func (t *timingStore) StoreTimings(ctx context.Context, repoID string, results []core.TestPayload) error {
+	if !t.cfg.LocalRunner {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timings, err := t.readCache(repoID)
+	if err != nil {
+		return err
+	}
+	failures := make(map[string]bool)
+	if err := readFile(t.failuresPath(repoID), &failures); err != nil {
+		return err
+	}
+	for i := range results {
+		result := &results[i]
+		if result.Filelocator == "" || result.Status == core.TestSkipped || result.Blocklisted {
+			continue
+		}
+		if old, ok := timings[result.Filelocator]; ok {
+			timings[result.Filelocator] = int(smoothingFactor*float64(result.Duration) + (1-smoothingFactor)*float64(old))
+		} else {
+			timings[result.Filelocator] = result.Duration
+		}
+		if result.Status == core.TestFailed || result.Status == core.TestTimedOut {
+			failures[result.Filelocator] = true
+		} else {
+			delete(failures, result.Filelocator)
+		}
+	}
+	if err := os.MkdirAll(t.cacheDir, global.DirectoryPermissions); err != nil {
+		return err
+	}
+	if err := writeFile(t.cachePath(repoID), timings); err != nil {
+		return err
+	}
+	return writeFile(t.failuresPath(repoID), failures)
+}
+
+func (t *timingStore) fetchFromNeuron(ctx context.Context, repoID, branch string) (map[string]int, error) {
+	u, err := url.Parse(global.NeuronURL(timingsEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("repoID", repoID)
+	q.Set("branch", branch)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	tracing.InjectHeaders(ctx, req.Header)
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]int{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("non 200 status")
+	}
+	payload := timingResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.Timings == nil {
+		payload.Timings = map[string]int{}
+	}
+	return payload.Timings, nil
+}
+
+func (t *timingStore) fetchFailuresFromNeuron(ctx context.Context, repoID, branch string) ([]string, error) {
+	u, err := url.Parse(global.NeuronURL(failuresEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("repoID", repoID)
+	q.Set("branch", branch)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	tracing.InjectHeaders(ctx, req.Header)
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return []string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("non 200 status")
+	}
+	payload := failuresResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.Failures == nil {
+		payload.Failures = []string{}
+	}
+	return payload.Failures, nil
+}
+
+func (t *timingStore) readCache(repoID string) (map[string]int, error) {
+	timings := make(map[string]int)
+	if err := readFile(t.cachePath(repoID), &timings); err != nil {
+		return nil, err
+	}
+	return timings, nil
+}
+
+// readFile decodes the cache file into v, v is left unchanged if the file does not exist
+func readFile(path string, v interface{}) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func writeFile(path string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+func (t *timingStore) cachePath(repoID string) string {
+	return filepath.Join(t.cacheDir, filepath.Base(filepath.Clean("/"+repoID))+".json")
+}
+
+func (t *timingStore) failuresPath(repoID string) string {
+	return filepath.Join(t.cacheDir, filepath.Base(filepath.Clean("/"+repoID))+"-failures.json")
+}
@@ -0,0 +1,177 @@
+// Package stageplugin runs executables as custom stages of the pipeline, e.g. license scanning or custom
+// test selection, so that the pipeline can be extended without rebuilding nucleus.
+//
+// The plugins are declared in a manifest:
+//
+//	stages:
+//	  - name: license-scan
+//	    after: clone
+//	    command: /opt/plugins/license-scan
+//	    args: ["--strict"]
+//	    timeout: 300
+//
+// The plugin receives the state of the pipeline as a JSON request on stdin and may write a JSON response
+// on stdout. A non zero exit code fails the task unless allowFailure is set.
+package stageplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"gopkg.in/yaml.v2"
+)
+
+// maxRemarkLength is the maximum length of the remark of a failed plugin
+const maxRemarkLength = 255
+
+type manifest struct {
+	Stages []definition `yaml:"stages"`
+}
+
+type definition struct {
+	Name                string `yaml:"name"`
+	core.StagePlacement `yaml:",inline"`
+	Command             string   `yaml:"command"`
+	Args                []string `yaml:"args"`
+	// Timeout in seconds, the plugin is only bounded by the task timeout if 0
+	Timeout int `yaml:"timeout"`
+	// AllowFailure only logs the failure of the plugin instead of failing the task
+	AllowFailure bool `yaml:"allowFailure"`
+}
+
+// request is written to the stdin of the plugin
+type request struct {
+	Stage   string                `json:"stage"`
+	Payload *core.Payload         `json:"payload"`
+	Config  *core.TASConfig       `json:"config,omitempty"`
+	Task    *core.TaskPayload     `json:"task,omitempty"`
+	Result  *core.ExecutionResult `json:"result,omitempty"`
+	// ChangedFiles is the change type by file, 1 for added, 2 for removed and 3 for modified
+	ChangedFiles map[string]int `json:"changedFiles,omitempty"`
+}
+
+// response is read from the stdout of the plugin, all the fields are optional
+type response struct {
+	// Env is set in the environment of the following stages
+	Env map[string]string `json:"env"`
+	// ChangedFiles replaces the changed files the tests are selected by, if set
+	ChangedFiles map[string]int `json:"changedFiles"`
+	// Remark is the user facing reason of the failure of the plugin
+	Remark string `json:"remark"`
+}
+
+// Plugin is a custom stage running an executable
+type Plugin struct {
+	definition
+	logger lumber.Logger
+}
+
+// Load returns the plugins of the manifest, there are no plugins if the path is empty
+func Load(path string, logger lumber.Logger) ([]*Plugin, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := manifest{}
+	if err := yaml.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse the stage plugins manifest: %w", err)
+	}
+	plugins := make([]*Plugin, 0, len(m.Stages))
+	for _, d := range m.Stages {
+		if d.Name == "" || d.Command == "" {
+			return nil, fmt.Errorf("stage plugins require a name and a command")
+		}
+		plugins = append(plugins, &Plugin{definition: d, logger: logger})
+	}
+	return plugins, nil
+}
+
+// Placement returns where the plugin runs in the pipeline
+func (p *Plugin) Placement() core.StagePlacement {
+	return p.StagePlacement
+}
+
+// Name returns the name of the plugin
+func (p *Plugin) Name() string {
+	return p.definition.Name
+}
+
+// Run runs the executable of the plugin and applies its response to the state
+func (p *Plugin) Run(ctx context.Context, state *core.StageState) error {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.Timeout)*time.Second)
+		defer cancel()
+	}
+	input, err := json.Marshal(&request{
+		Stage:        p.definition.Name,
+		Payload:      state.Payload,
+		Config:       state.TASConfig,
+		Task:         state.Task,
+		Result:       state.Result,
+		ChangedFiles: state.ChangedFiles,
+	})
+	if err != nil {
+		return err
+	}
+	var stdout, stderr bytes.Buffer
+	// #nosec G204 the plugins are configured by the operator
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	if _, statErr := os.Stat(global.RepoDir); statErr == nil {
+		cmd.Dir = global.RepoDir
+	}
+	cmd.Env = append(os.Environ(), "TAS_STAGE_PLUGIN="+p.definition.Name)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	p.logger.Infof("Running stage plugin %s", p.definition.Name)
+	runErr := cmd.Run()
+	if stderr.Len() > 0 {
+		p.logger.Debugf("stage plugin %s stderr: %s", p.definition.Name, stderr.String())
+	}
+	resp := response{}
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil && runErr == nil {
+			runErr = fmt.Errorf("invalid response of stage plugin %s: %w", p.definition.Name, err)
+		}
+	}
+	if runErr != nil {
+		if p.AllowFailure {
+			p.logger.Warnf("stage plugin %s failed, continuing as failures are allowed: %v", p.definition.Name, runErr)
+			return nil
+		}
+		p.logger.Errorf("stage plugin %s failed: %v", p.definition.Name, runErr)
+		state.ErrRemark = fmt.Sprintf("Error occurred in %s stage", p.definition.Name)
+		if remark := strings.TrimSpace(resp.Remark); remark != "" {
+			if len(remark) > maxRemarkLength {
+				remark = remark[:maxRemarkLength-3] + "..."
+			}
+			state.ErrRemark = remark
+		}
+		return runErr
+	}
+	for name, value := range resp.Env {
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	if resp.ChangedFiles != nil {
+		p.logger.Infof("stage plugin %s selected %d changed files", p.definition.Name, len(resp.ChangedFiles))
+		state.ChangedFiles = resp.ChangedFiles
+	}
+	return nil
+}
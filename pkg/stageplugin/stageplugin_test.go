@@ -0,0 +1,71 @@
+package stageplugin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), perm))
+}
+
+func loadPlugins(t *testing.T, manifest string) []*Plugin {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "plugins.yml")
+	writeFile(t, path, manifest, 0644)
+	plugins, err := Load(path, logger)
+	assert.Nil(t, err)
+	return plugins
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "select.sh")
+	// the plugin keeps the test files of the changed files and fails without changed files
+	writeFile(t, script, `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"stage":"select"'*'"changedFiles":{'*) echo '{"env":{"SELECTED_BY":"select"},"changedFiles":{"a.test.js":3}}' ;;
+  *) echo '{"remark":"no changed files to select from"}'; exit 1 ;;
+esac
+`, 0755)
+	plugins := loadPlugins(t, "stages:\n  - name: select\n    after: diff\n    command: "+script+"\n")
+	assert.Len(t, plugins, 1)
+	plugin := plugins[0]
+	assert.Equal(t, "select", plugin.Name())
+	assert.Equal(t, core.StagePlacement{After: core.StageDiff}, plugin.Placement())
+
+	state := &core.StageState{Payload: &core.Payload{TaskID: "task"}, ChangedFiles: map[string]int{"a.js": core.FileModified}}
+	assert.Nil(t, plugin.Run(context.Background(), state))
+	assert.Equal(t, map[string]int{"a.test.js": core.FileModified}, state.ChangedFiles)
+	assert.Equal(t, "select", os.Getenv("SELECTED_BY"))
+	os.Unsetenv("SELECTED_BY")
+
+	state = &core.StageState{Payload: &core.Payload{TaskID: "task"}}
+	assert.NotNil(t, plugin.Run(context.Background(), state))
+	assert.Equal(t, "no changed files to select from", state.ErrRemark)
+
+	plugin.AllowFailure = true
+	state = &core.StageState{Payload: &core.Payload{TaskID: "task"}}
+	assert.Nil(t, plugin.Run(context.Background(), state))
+	assert.Empty(t, state.ErrRemark)
+}
+
+func TestLoad(t *testing.T) {
+	plugins, err := Load("", nil)
+	assert.Nil(t, err)
+	assert.Empty(t, plugins)
+
+	path := filepath.Join(t.TempDir(), "plugins.yml")
+	writeFile(t, path, "stages:\n  - name: scan\n    before: execution\n", 0644)
+	_, err = Load(path, nil)
+	assert.NotNil(t, err, "the command is required")
+}
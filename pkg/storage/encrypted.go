@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const (
+	// encryptionChunkSize is the size of the plaintext of the chunks sealed separately, so that the blobs are
+	// encrypted and decrypted while they are streamed
+	encryptionChunkSize = 64 << 10
+	noncePrefixSize     = 7
+	encryptedMimeType   = "application/octet-stream"
+)
+
+// encryptionMagic starts the encrypted blobs, the blobs without it are not encrypted
+var encryptionMagic = []byte("TASENC01")
+
+// EncryptedStore encrypts the blobs uploaded to the underlying store with AES-GCM and decrypts them when they are
+// downloaded. An encrypted blob is the magic, a random nonce prefix and the chunks of the plaintext, each chunk is
+// sealed with the prefix, its index and whether it is the last one as nonce, so that the chunks can't be
+// reordered and the blob can't be truncated. The path of the blob is authenticated with each chunk, so that a blob
+// can't be copied over another one. The blobs without the magic are rejected, unless plaintext blobs
+// are allowed while migrating.
+type EncryptedStore struct {
+	core.AzureClient
+	key            string
+	allowPlaintext bool
+	secretParser   core.SecretParser
+	logger         lumber.Logger
+
+	mu   sync.Mutex
+	aead cipher.AEAD
+}
+
+// NewEncryptedStore returns the store encrypting the blobs uploaded to store, or store if no key is configured.
+// The key is resolved when the first blob is transferred, as the repo secrets are only available once the
+// payload of the task is loaded.
+func NewEncryptedStore(cfg config.Encryption,
+	store core.AzureClient,
+	secretParser core.SecretParser,
+	logger lumber.Logger) core.AzureClient {
+	if cfg.Key == "" {
+		return store
+	}
+	return &EncryptedStore{
+		AzureClient:    store,
+		key:            cfg.Key,
+		allowPlaintext: cfg.AllowPlaintext,
+		secretParser:   secretParser,
+		logger:         logger,
+	}
+}
+
+// FindUsingSASUrl downloads and decrypts the blob of the sasURL
+func (s *EncryptedStore) FindUsingSASUrl(ctx context.Context, sasURL string) (io.ReadCloser, error) {
+	blobPath, err := sasBlobPath(sasURL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.AzureClient.FindUsingSASUrl(ctx, sasURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(ctx, body, pathSuffixes(blobPath)...)
+}
+
+// CreateUsingSASURL encrypts and uploads the blob of the sasURL
+func (s *EncryptedStore) CreateUsingSASURL(ctx context.Context, sasURL string, reader io.Reader, mimeType string) (string, error) {
+	blobPath, err := sasBlobPath(sasURL)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := s.encrypt(ctx, reader, blobAD(blobPath))
+	if err != nil {
+		return "", err
+	}
+	return s.AzureClient.CreateUsingSASURL(ctx, sasURL, encrypted, encryptedMimeType)
+}
+
+// Find downloads and decrypts the blob
+func (s *EncryptedStore) Find(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, err := s.AzureClient.Find(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(ctx, body, blobAD(path))
+}
+
+// Create encrypts and uploads the blob
+func (s *EncryptedStore) Create(ctx context.Context, path string, reader io.Reader, mimeType string) (string, error) {
+	encrypted, err := s.encrypt(ctx, reader, blobAD(path))
+	if err != nil {
+		return "", err
+	}
+	return s.AzureClient.Create(ctx, path, encrypted, encryptedMimeType)
+}
+
+// cipher returns the AEAD of the key, a ${{ secrets.NAME }} key is resolved from the repo secrets
+func (s *EncryptedStore) cipher(ctx context.Context) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aead != nil {
+		return s.aead, nil
+	}
+	key := s.key
+	if strings.Contains(key, "${{") {
+		secrets, err := s.secretParser.GetRepoSecret(ctx, global.RepoSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the secrets of the encryption key: %w", err)
+		}
+		if key, err = s.secretParser.SubstituteSecret(key, secrets); err != nil {
+			return nil, err
+		}
+		if strings.Contains(key, "${{") {
+			return nil, errors.New("the secret of the encryption key is not found")
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, errors.New("the encryption key is not base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return s.aead, nil
+}
+
+// blobAD returns the additional data authenticated with the chunks of the blob at path
+func blobAD(path string) []byte {
+	return []byte(strings.TrimPrefix(path, "/"))
+}
+
+// sasBlobPath returns the path of the blob of sasURL, without the query holding the token
+func sasBlobPath(sasURL string) (string, error) {
+	u, err := url.Parse(sasURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Path, nil
+}
+
+// pathSuffixes returns the additional data of path and of each of its suffixes, longest first. A blob uploaded
+// at a path of the container is read through the url of the blob, whose path is prefixed with the container.
+func pathSuffixes(path string) [][]byte {
+	path = strings.TrimPrefix(path, "/")
+	ads := [][]byte{blobAD(path)}
+	for i := strings.Index(path, "/"); i >= 0; i = strings.Index(path, "/") {
+		path = path[i+1:]
+		ads = append(ads, blobAD(path))
+	}
+	return ads
+}
+
+// encrypt returns the reader of the encrypted blob of the plaintext of reader, ad is authenticated with each chunk
+func (s *EncryptedStore) encrypt(ctx context.Context, reader io.Reader, ad []byte) (io.Reader, error) {
+	aead, err := s.cipher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	sl := &sealer{
+		aead:   aead,
+		src:    bufio.NewReaderSize(reader, encryptionChunkSize),
+		ad:     ad,
+		prefix: prefix,
+		plain:  make([]byte, encryptionChunkSize),
+	}
+	sl.out.Write(encryptionMagic)
+	sl.out.Write(prefix)
+	return sl, nil
+}
+
+// decrypt returns the plaintext of the blob, a blob which is not encrypted is only returned as is if plaintext
+// blobs are allowed. The chunks are authenticated with the first of ads opening the first chunk.
+func (s *EncryptedStore) decrypt(ctx context.Context, body io.ReadCloser, ads ...[]byte) (io.ReadCloser, error) {
+	src := bufio.NewReaderSize(body, encryptionChunkSize)
+	header, err := src.Peek(len(encryptionMagic) + noncePrefixSize)
+	if err != nil && err != io.EOF {
+		body.Close()
+		return nil, err
+	}
+	if !bytes.HasPrefix(header, encryptionMagic) {
+		if s.allowPlaintext {
+			s.logger.Warnf("blob is not encrypted, it is read as is as plaintext blobs are allowed")
+			return readCloser{Reader: src, Closer: body}, nil
+		}
+		body.Close()
+		s.logger.Errorf("blob is not encrypted, it is rejected as the encryption is enabled")
+		return nil, errs.ErrBlobDecryption
+	}
+	if len(header) < len(encryptionMagic)+noncePrefixSize {
+		body.Close()
+		return nil, errs.ErrBlobDecryption
+	}
+	aead, err := s.cipher(ctx)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	prefix := append([]byte(nil), header[len(encryptionMagic):]...)
+	if _, err := src.Discard(len(header)); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &opener{
+		aead:   aead,
+		src:    src,
+		closer: body,
+		ads:    ads,
+		prefix: prefix,
+		sealed: make([]byte, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// chunkNonce returns the nonce of the chunk at index
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	if last {
+		nonce[noncePrefixSize+4] = 1
+	}
+	return nonce
+}
+
+// readChunk reads the next chunk of src into buf, it reports whether it is the last chunk of src
+func readChunk(src *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(src, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	if _, err := src.Peek(1); err != nil {
+		if err == io.EOF {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+// sealer encrypts the plaintext of src chunk by chunk
+type sealer struct {
+	aead   cipher.AEAD
+	src    *bufio.Reader
+	ad     []byte
+	prefix []byte
+	index  uint32
+	plain  []byte
+	out    bytes.Buffer
+	done   bool
+}
+
+func (s *sealer) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.seal(); err != nil {
+			return 0, err
+		}
+	}
+	return s.out.Read(p)
+}
+
+func (s *sealer) seal() error {
+	if s.index == math.MaxUint32 {
+		return errors.New("the blob is too large to be encrypted")
+	}
+	n, last, err := readChunk(s.src, s.plain)
+	if err != nil {
+		return err
+	}
+	s.out.Write(s.aead.Seal(nil, chunkNonce(s.prefix, s.index, last), s.plain[:n], s.ad))
+	s.index++
+	s.done = last
+	return nil
+}
+
+// opener decrypts the chunks of src
+type opener struct {
+	aead   cipher.AEAD
+	src    *bufio.Reader
+	closer io.Closer
+	// ads are the candidates of the additional data, the one opening the first chunk opens the next ones
+	ads    [][]byte
+	prefix []byte
+	index  uint32
+	sealed []byte
+	out    bytes.Reader
+	done   bool
+}
+
+func (o *opener) Read(p []byte) (int, error) {
+	for o.out.Len() == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	return o.out.Read(p)
+}
+
+func (o *opener) open() error {
+	n, last, err := readChunk(o.src, o.sealed)
+	if err != nil {
+		return err
+	}
+	nonce := chunkNonce(o.prefix, o.index, last)
+	for i, ad := range o.ads {
+		var plain []byte
+		if plain, err = o.aead.Open(nil, nonce, o.sealed[:n], ad); err == nil {
+			o.ads = o.ads[i : i+1]
+			o.out.Reset(plain)
+			break
+		}
+	}
+	if err != nil || len(o.ads) == 0 {
+		return errs.ErrBlobDecryption
+	}
+	o.index++
+	o.done = last
+	return nil
+}
+
+func (o *opener) Close() error {
+	return o.closer.Close()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretParserStub struct {
+	core.SecretParser
+	secrets map[string]string
+}
+
+func (s secretParserStub) GetRepoSecret(ctx context.Context, path string) (map[string]string, error) {
+	return s.secrets, nil
+}
+
+func (s secretParserStub) SubstituteSecret(command string, secretData map[string]string) (string, error) {
+	for name, value := range secretData {
+		command = strings.ReplaceAll(command, "${{ secrets."+name+" }}", value)
+	}
+	return command, nil
+}
+
+func newEncryptedTestStore(t *testing.T, key string) (core.AzureClient, string) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	if err != nil {
+		t.Fatalf("Could not instantiate logger %s", err.Error())
+	}
+	root := t.TempDir()
+	store, err := NewLocalStore(root, defaultContainerName, logger)
+	assert.Nil(t, err)
+	parser := secretParserStub{secrets: map[string]string{"CACHE_KEY": key}}
+	return NewEncryptedStore(config.Encryption{Key: "${{ secrets.CACHE_KEY }}"}, store, parser, logger), root
+}
+
+func newKey(t *testing.T) string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEncryptedStore(t *testing.T) {
+	key := newKey(t)
+	store, root := newEncryptedTestStore(t, key)
+	ctx := context.Background()
+
+	for _, size := range []int{0, 10, encryptionChunkSize, 3*encryptionChunkSize + 5} {
+		plain := make([]byte, size)
+		if _, err := rand.Read(plain); err != nil {
+			t.Fatal(err)
+		}
+		sasURL, err := store.GetSASURL(ctx, "org/repo/cache.tzst", core.CacheContainer)
+		assert.Nil(t, err)
+		_, err = store.CreateUsingSASURL(ctx, sasURL, bytes.NewReader(plain), "application/zstd")
+		assert.Nil(t, err)
+
+		stored, err := ioutil.ReadFile(filepath.Join(root, "cache", "org", "repo", "cache.tzst"))
+		assert.Nil(t, err)
+		assert.True(t, bytes.HasPrefix(stored, encryptionMagic))
+		chunks := size/encryptionChunkSize + 1
+		if size > 0 && size%encryptionChunkSize == 0 {
+			chunks--
+		}
+		assert.Equal(t, len(encryptionMagic)+noncePrefixSize+size+16*chunks, len(stored))
+
+		r, err := store.FindUsingSASUrl(ctx, sasURL)
+		assert.Nil(t, err)
+		body, err := ioutil.ReadAll(r)
+		r.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, plain, body, "size %d", size)
+	}
+
+	// the encrypted blobs are not readable without the key
+	plain, _ := NewLocalStore(root, defaultContainerName, nil)
+	_, err := store.Create(ctx, "commit/coverage-merged.json", strings.NewReader(`{"total":{}}`), "application/json")
+	assert.Nil(t, err)
+	r, err := plain.Find(ctx, "commit/coverage-merged.json")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(r)
+	r.Close()
+	assert.NotContains(t, string(body), "total")
+}
+
+func TestEncryptedStorePlaintext(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	root := t.TempDir()
+	plain, err := NewLocalStore(root, defaultContainerName, logger)
+	assert.Nil(t, err)
+	ctx := context.Background()
+	parser := secretParserStub{secrets: map[string]string{"CACHE_KEY": newKey(t)}}
+
+	for _, content := range []string{`{"total":{}}`, "", "TASENC"} {
+		// e.g. an encrypted blob replaced by anyone able to write to the container
+		_, err = plain.Create(ctx, "commit/coverage-merged.json", strings.NewReader(content), "application/json")
+		assert.Nil(t, err)
+
+		store := NewEncryptedStore(config.Encryption{Key: "${{ secrets.CACHE_KEY }}"}, plain, parser, logger)
+		_, err = store.Find(ctx, "commit/coverage-merged.json")
+		assert.True(t, errors.Is(err, errs.ErrBlobDecryption), "content %q", content)
+
+		// the blobs uploaded before the encryption was enabled are only read while migrating
+		migrating := NewEncryptedStore(config.Encryption{Key: "${{ secrets.CACHE_KEY }}", AllowPlaintext: true}, plain, parser, logger)
+		r, err := migrating.Find(ctx, "commit/coverage-merged.json")
+		assert.Nil(t, err)
+		body, _ := ioutil.ReadAll(r)
+		r.Close()
+		assert.Equal(t, content, string(body))
+	}
+}
+
+func TestEncryptedStoreTampered(t *testing.T) {
+	store, root := newEncryptedTestStore(t, newKey(t))
+	ctx := context.Background()
+	path := filepath.Join(root, "coverage", "artifacts.tzst")
+	readAll := func() error {
+		r, err := store.Find(ctx, "artifacts.tzst")
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = ioutil.ReadAll(r)
+		return err
+	}
+
+	_, err := store.Create(ctx, "artifacts.tzst", bytes.NewReader(make([]byte, 2*encryptionChunkSize+1)), "application/zstd")
+	assert.Nil(t, err)
+	stored, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, readAll())
+
+	flipped := append([]byte(nil), stored...)
+	flipped[len(flipped)-1] ^= 1
+	assert.Nil(t, ioutil.WriteFile(path, flipped, 0644))
+	assert.True(t, errors.Is(readAll(), errs.ErrBlobDecryption))
+
+	// the blob truncated after a chunk is detected as the chunk is not the last one
+	truncated := stored[:len(encryptionMagic)+noncePrefixSize+encryptionChunkSize+16]
+	assert.Nil(t, ioutil.WriteFile(path, truncated, 0644))
+	assert.True(t, errors.Is(readAll(), errs.ErrBlobDecryption))
+
+	other, otherRoot := newEncryptedTestStore(t, newKey(t))
+	assert.Nil(t, os.MkdirAll(filepath.Join(otherRoot, "coverage"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(otherRoot, "coverage", "artifacts.tzst"), stored, 0644))
+	r, err := other.Find(ctx, "artifacts.tzst")
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(r)
+	r.Close()
+	assert.True(t, errors.Is(err, errs.ErrBlobDecryption))
+}
+
+func TestEncryptedStoreCopied(t *testing.T) {
+	store, root := newEncryptedTestStore(t, newKey(t))
+	ctx := context.Background()
+	readAll := func(r io.ReadCloser, err error) error {
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = ioutil.ReadAll(r)
+		return err
+	}
+	copyBlob := func(from, to string) {
+		stored, err := ioutil.ReadFile(from)
+		assert.Nil(t, err)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(to), 0755))
+		assert.Nil(t, ioutil.WriteFile(to, stored, 0644))
+	}
+
+	// a blob uploaded at a path of the container is read through its url
+	blobURL, err := store.Create(ctx, "org/repo/commit/coverage.tzst", strings.NewReader("coverage"), "application/zstd")
+	assert.Nil(t, err)
+	assert.Nil(t, readAll(store.FindUsingSASUrl(ctx, blobURL)))
+
+	copyBlob(filepath.Join(root, "coverage", "org", "repo", "commit", "coverage.tzst"),
+		filepath.Join(root, "coverage", "org", "repo", "other", "coverage.tzst"))
+	err = readAll(store.Find(ctx, "org/repo/other/coverage.tzst"))
+	assert.True(t, errors.Is(err, errs.ErrBlobDecryption))
+	otherURL, err := store.GetSASURL(ctx, "org/repo/other/coverage.tzst", defaultContainerName)
+	assert.Nil(t, err)
+	err = readAll(store.FindUsingSASUrl(ctx, otherURL))
+	assert.True(t, errors.Is(err, errs.ErrBlobDecryption))
+
+	// the query of the sas url is not part of the path of the blob
+	sasURL, err := store.GetSASURL(ctx, "org/repo/cache.tzst", core.CacheContainer)
+	assert.Nil(t, err)
+	_, err = store.CreateUsingSASURL(ctx, sasURL, strings.NewReader("cache"), "application/zstd")
+	assert.Nil(t, err)
+	assert.Nil(t, readAll(store.FindUsingSASUrl(ctx, sasURL+"?sig=token")))
+
+	copyBlob(filepath.Join(root, "cache", "org", "repo", "cache.tzst"), filepath.Join(root, "cache", "org", "other", "cache.tzst"))
+	otherURL, err = store.GetSASURL(ctx, "org/other/cache.tzst", core.CacheContainer)
+	assert.Nil(t, err)
+	err = readAll(store.FindUsingSASUrl(ctx, otherURL))
+	assert.True(t, errors.Is(err, errs.ErrBlobDecryption))
+}
+
+func TestEncryptionKey(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	store, err := NewLocalStore(t.TempDir(), defaultContainerName, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, store, NewEncryptedStore(config.Encryption{}, store, nil, logger))
+
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short")), "${{ secrets.MISSING }}"} {
+		encrypted := NewEncryptedStore(config.Encryption{Key: key}, store, secretParserStub{}, logger)
+		_, err := encrypted.Create(context.Background(), "blob", strings.NewReader("data"), "text/plain")
+		assert.NotNil(t, err, "key %s", key)
+	}
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+// LocalStore stores the blobs on local disk, each container is a directory inside root.
+// The SAS URLs are file:// URLs of the blob path, so they can be shared with local tools.
+type LocalStore struct {
+	root          string
+	containerName string
+	logger        lumber.Logger
+}
+
+// NewLocalStore returns a new local disk blob store.
+func NewLocalStore(root, containerName string, logger lumber.Logger) (core.AzureClient, error) {
+	if root == "" {
+		return nil, errors.New("local storage directory is not set")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, global.DirectoryPermissions); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root: root, containerName: containerName, logger: logger}, nil
+}
+
+// FindUsingSASUrl opens the blob at sasURL
+func (s *LocalStore) FindUsingSASUrl(ctx context.Context, sasURL string) (io.ReadCloser, error) {
+	path, err := s.pathFromURL(sasURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(path)
+}
+
+// CreateUsingSASURL writes the blob at sasURL
+func (s *LocalStore) CreateUsingSASURL(ctx context.Context, sasURL string, reader io.Reader, mimeType string) (string, error) {
+	path, err := s.pathFromURL(sasURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.write(path, reader); err != nil {
+		return "", err
+	}
+	return sasURL, nil
+}
+
+// Find opens the blob at path in the container
+func (s *LocalStore) Find(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.open(s.blobPath(s.containerName, path))
+}
+
+// Create writes the blob at path in the container
+func (s *LocalStore) Create(ctx context.Context, path string, reader io.Reader, mimeType string) (string, error) {
+	blobPath := s.blobPath(s.containerName, path)
+	if err := s.write(blobPath, reader); err != nil {
+		return "", err
+	}
+	return fileURL(blobPath), nil
+}
+
+// GetSASURL returns the file URL of the blob, no token is required for local disk
+func (s *LocalStore) GetSASURL(ctx context.Context, containerPath string, containerType core.ContainerType) (string, error) {
+	return fileURL(s.blobPath(string(containerType), containerPath)), nil
+}
+
+// Exists checks the blob if exists
+func (s *LocalStore) Exists(ctx context.Context, path string) (bool, error) {
+	if _, err := os.Stat(s.blobPath(s.containerName, path)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *LocalStore) blobPath(container, path string) string {
+	// cleaning the rooted path prevents escaping the container with `..`
+	return filepath.Join(s.root, container, filepath.Clean("/"+path))
+}
+
+func (s *LocalStore) pathFromURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported url scheme %s for local storage", u.Scheme)
+	}
+	path := filepath.Clean(u.Path)
+	if path != s.root && !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the storage directory", path)
+	}
+	return path, nil
+}
+
+func (s *LocalStore) open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// write writes the blob to a temp file first, so readers never see partial blobs
+func (s *LocalStore) write(path string, reader io.Reader) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, global.DirectoryPermissions); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		s.logger.Errorf("failed to write blob %s, error: %v", path, err)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func fileURL(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
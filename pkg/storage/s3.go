@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+)
+
+const defaultS3Region = "us-east-1"
+
+// S3Store stores the blobs in a S3 compatible object store eg. MinIO, using path style URLs.
+// Each container is a prefix in the bucket. The SAS URLs are the object URLs, requests
+// to them are signed using the configured credentials.
+type S3Store struct {
+	endpoint      *url.URL
+	bucket        string
+	containerName string
+	signer        *sigV4Signer
+	httpClient    http.Client
+	logger        lumber.Logger
+}
+
+// NewS3Store returns a new S3 compatible blob store.
+func NewS3Store(cfg config.Storage, containerName string, logger lumber.Logger) (core.AzureClient, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("either the storage endpoint or bucket is not set")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("either the storage access key or secret key is not set")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	region := cfg.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	return &S3Store{
+		endpoint:      endpoint,
+		bucket:        cfg.Bucket,
+		containerName: containerName,
+		signer:        &sigV4Signer{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: region, service: "s3"},
+		httpClient:    requestutils.NewResilientClient(global.DefaultHTTPTimeout),
+		logger:        logger,
+	}, nil
+}
+
+// FindUsingSASUrl downloads the object at sasURL
+func (s *S3Store) FindUsingSASUrl(ctx context.Context, sasURL string) (io.ReadCloser, error) {
+	if err := s.checkURL(sasURL); err != nil {
+		return nil, err
+	}
+	return s.get(ctx, sasURL)
+}
+
+// CreateUsingSASURL uploads the object at sasURL
+func (s *S3Store) CreateUsingSASURL(ctx context.Context, sasURL string, reader io.Reader, mimeType string) (string, error) {
+	if err := s.checkURL(sasURL); err != nil {
+		return "", err
+	}
+	return sasURL, s.put(ctx, sasURL, reader, mimeType)
+}
+
+// Find downloads the object at path in the container
+func (s *S3Store) Find(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.get(ctx, s.objectURL(s.containerName, path))
+}
+
+// Create uploads the object at path in the container
+func (s *S3Store) Create(ctx context.Context, path string, reader io.Reader, mimeType string) (string, error) {
+	objectURL := s.objectURL(s.containerName, path)
+	return objectURL, s.put(ctx, objectURL, reader, mimeType)
+}
+
+// GetSASURL returns the object URL of the blob
+func (s *S3Store) GetSASURL(ctx context.Context, containerPath string, containerType core.ContainerType) (string, error) {
+	return s.objectURL(string(containerType), containerPath), nil
+}
+
+// Exists checks the object if exists
+func (s *S3Store) Exists(ctx context.Context, path string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(s.containerName, path), nil, 0, "")
+	if err != nil {
+		return false, fmt.Errorf("check if object exists, %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("check if object exists, status %d", resp.StatusCode)
+	}
+}
+
+func (s *S3Store) objectURL(container, key string) string {
+	u := *s.endpoint
+	u.Path = path.Join(u.Path, s.bucket, container, path.Clean("/"+key))
+	return u.String()
+}
+
+// checkURL ensures credentials are only sent to the configured endpoint
+func (s *S3Store) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != s.endpoint.Scheme || u.Host != s.endpoint.Host {
+		return fmt.Errorf("url %s does not belong to the storage endpoint", u.Redacted())
+	}
+	return nil
+}
+
+func (s *S3Store) get(ctx context.Context, objectURL string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, objectURL, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errs.ErrNotFound
+	default:
+		resp.Body.Close()
+		s.logger.Errorf("error while downloading object, status code %d", resp.StatusCode)
+		return nil, errs.ErrApiStatus
+	}
+}
+
+// put uploads the object, the reader is spooled to a temp file as S3 requires the content length
+func (s *S3Store) put(ctx context.Context, objectURL string, reader io.Reader, mimeType string) error {
+	tmp, err := ioutil.TempFile("", "s3-upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, reader)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, objectURL, tmp, size, mimeType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Errorf("error while uploading object, status code %d", resp.StatusCode)
+		return errs.ErrApiStatus
+	}
+	return nil
+}
+
+func (s *S3Store) do(ctx context.Context, method, objectURL string, body io.Reader, size int64, mimeType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, objectURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if mimeType != "" {
+		req.Header.Set("Content-Type", mimeType)
+	}
+	s.signer.sign(req, unsignedPayload, time.Now())
+	return s.httpClient.Do(req)
+}
@@ -0,0 +1,45 @@
+// Package storage provides the blob store used for payloads, caches, logs and coverage artifacts
+package storage
+
+import (
+	"fmt"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/azure"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+// Storage providers
+const (
+	ProviderAzure = "azure"
+	ProviderLocal = "local"
+	ProviderS3    = "s3"
+)
+
+// defaultContainerName is used for coverage artifacts if azure container is not configured
+const defaultContainerName = "coverage"
+
+// New returns the blob store for the configured storage provider.
+// Azure is the default, the local disk and s3 stores are only used if they are configured explicitly.
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (core.AzureClient, error) {
+	provider := cfg.Storage.Provider
+	if provider == "" {
+		provider = ProviderAzure
+	}
+	containerName := cfg.Azure.ContainerName
+	if containerName == "" {
+		containerName = defaultContainerName
+	}
+
+	switch provider {
+	case ProviderAzure:
+		return azure.NewAzureBlobEnv(cfg, logger)
+	case ProviderLocal:
+		return NewLocalStore(cfg.Storage.LocalDir, containerName, logger)
+	case ProviderS3:
+		return NewS3Store(cfg.Storage, containerName, logger)
+	default:
+		return nil, fmt.Errorf("unsupported storage provider %s", provider)
+	}
+}
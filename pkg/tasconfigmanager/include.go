@@ -0,0 +1,152 @@
+package tasconfigmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"gopkg.in/yaml.v2"
+)
+
+// maxFragmentSize bounds the size of the remote fragments
+const maxFragmentSize = 1 << 20
+
+// variableRegex matches the variable references, e.g. ${{ vars.NODE_IMAGE }}
+var variableRegex = regexp.MustCompile(`\${{\s*vars\.([A-Za-z0-9_-]+)\s*}}`)
+
+// header holds the keys read before the variables are substituted
+type header struct {
+	Include   []core.Include    `yaml:"include"`
+	Variables map[string]string `yaml:"variables"`
+}
+
+// resolve returns the documents of the configuration file in the order they are unmarshalled, the included
+// fragments first so that the keys of the file override them. The variables are substituted in all documents,
+// the inherited variables are overridden by the ones of the fragments which are overridden by the ones of the
+// file. The variables are returned so that the packages inherit them.
+func (tc *TASConfigManager) resolve(ctx context.Context, repoDir, file string, content []byte,
+	inherited map[string]string) ([][]byte, map[string]string, error) {
+	h := header{}
+	if err := yaml.Unmarshal(content, &h); err != nil {
+		tc.logger.Errorf("Error while unmarshalling yaml file, path %s, error %v", file, err)
+		return nil, nil, fmt.Errorf("Invalid format of configuration file at path: %s", file)
+	}
+	vars := make(map[string]string, len(inherited)+len(h.Variables))
+	for name, value := range inherited {
+		vars[name] = value
+	}
+	docs := make([][]byte, 0, len(h.Include)+1)
+	for i := range h.Include {
+		include := &h.Include[i]
+		fragment, err := tc.fetchFragment(ctx, repoDir, file, include)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: failed to include %s: %v", file, include.Source(), err)
+		}
+		fh := header{}
+		if err := yaml.Unmarshal(fragment, &fh); err != nil {
+			return nil, nil, fmt.Errorf("Invalid format of configuration fragment %s", include.Source())
+		}
+		if len(fh.Include) > 0 {
+			return nil, nil, fmt.Errorf("%s: nested includes are not supported", include.Source())
+		}
+		for name, value := range fh.Variables {
+			vars[name] = value
+		}
+		docs = append(docs, fragment)
+	}
+	for name, value := range h.Variables {
+		vars[name] = value
+	}
+	docs = append(docs, content)
+	for i := range docs {
+		rendered, err := render(docs[i], vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", file, err)
+		}
+		docs[i] = rendered
+	}
+	return docs, vars, nil
+}
+
+// fetchFragment reads the fragment from the repository or downloads it and verifies its checksum
+func (tc *TASConfigManager) fetchFragment(ctx context.Context, repoDir, file string, include *core.Include) ([]byte, error) {
+	if err := include.Validate(); err != nil {
+		return nil, err
+	}
+	if include.Path != "" {
+		p, err := fragmentPath(file, include.Path)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadFile(filepath.Join(repoDir, filepath.FromSlash(p)))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, include.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := tc.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non 200 status %d", resp.StatusCode)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFragmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxFragmentSize {
+		return nil, fmt.Errorf("fragment is larger than %d bytes", maxFragmentSize)
+	}
+	sum := sha256.Sum256(content)
+	if checksum := hex.EncodeToString(sum[:]); checksum != strings.ToLower(include.SHA256) {
+		return nil, fmt.Errorf("checksum mismatch, the sha256 of the fragment is %s", checksum)
+	}
+	return content, nil
+}
+
+// fragmentPath returns the path of the included fragment relative to the repository root, the path of the
+// include is relative to the directory of the including file
+func fragmentPath(file, p string) (string, error) {
+	joined := path.Join(path.Dir(file), p)
+	if joined == ".." || strings.HasPrefix(joined, "../") {
+		return "", errors.New("the fragment is outside the repository")
+	}
+	return joined, nil
+}
+
+// render substitutes the variables of the document, the secrets are substituted when the commands are run
+func render(doc []byte, vars map[string]string) ([]byte, error) {
+	undefined := make(map[string]bool)
+	rendered := variableRegex.ReplaceAllFunc(doc, func(match []byte) []byte {
+		name := string(variableRegex.FindSubmatch(match)[1])
+		value, ok := vars[name]
+		if !ok {
+			undefined[name] = true
+			return match
+		}
+		return []byte(value)
+	})
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables %s", strings.Join(names, ", "))
+	}
+	return rendered, nil
+}
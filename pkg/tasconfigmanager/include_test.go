@@ -0,0 +1,103 @@
+package tasconfigmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestResolve(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tc := NewTASConfigManager(logger)
+
+	remote := []byte(`framework: jest
+tier: ${{ vars.TIER }}
+variables:
+  TIER: small
+  NODE: "16"
+`)
+	sum := sha256.Sum256(remote)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(remote) // nolint:errcheck
+	}))
+	defer srv.Close()
+
+	repoDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(repoDir, "ci"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(repoDir, "ci", "base.yml"), []byte(`preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+  env:
+    NODE_VERSION: ${{ vars.NODE }}
+tier: medium
+`), 0644))
+
+	content := []byte(fmt.Sprintf(`include:
+  - url: %s/tas/base.yml
+    sha256: %s
+  - ci/base.yml
+variables:
+  NODE: "18"
+preRun:
+  command:
+    - npm ci --token ${{ secrets.NPM_TOKEN }}
+`, srv.URL, hex.EncodeToString(sum[:])))
+	docs, vars, err := tc.resolve(context.Background(), repoDir, ".tas.yml", content, map[string]string{"TIER": "xsmall"})
+	assert.Nil(t, err)
+	assert.Len(t, docs, 3)
+	assert.Equal(t, map[string]string{"TIER": "small", "NODE": "18"}, vars)
+
+	tasConfig := &core.TASConfig{}
+	for _, doc := range docs {
+		assert.Nil(t, yaml.Unmarshal(doc, tasConfig))
+	}
+	assert.Equal(t, "jest", tasConfig.Framework)
+	// the keys of the later fragments override the ones of the previous fragments
+	assert.Equal(t, core.Medium, tasConfig.Tier)
+	assert.Equal(t, map[string]string{"NODE_VERSION": "18"}, tasConfig.Premerge.EnvMap)
+	assert.Equal(t, "npm ci --token ${{ secrets.NPM_TOKEN }}", tasConfig.Prerun.Commands[0].Run)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"checksum mismatch", fmt.Sprintf("include:\n  - url: %s/tas/base.yml\n    sha256: %064d\n", srv.URL, 0)},
+		{"missing checksum", fmt.Sprintf("include:\n  - url: %s/tas/base.yml\n", srv.URL)},
+		{"outside the repository", "include:\n  - ../base.yml\n"},
+		{"missing fragment", "include:\n  - ci/missing.yml\n"},
+		{"undefined variable", "framework: ${{ vars.FRAMEWORK }}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tc.resolve(context.Background(), repoDir, ".tas.yml", []byte(tt.content), nil)
+			assert.NotNil(t, err)
+		})
+	}
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(repoDir, "ci", "nested.yml"), []byte("include:\n  - base.yml\n"), 0644))
+	_, _, err = tc.resolve(context.Background(), repoDir, ".tas.yml", []byte("include:\n  - ci/nested.yml\n"), nil)
+	assert.EqualError(t, err, "ci/nested.yml: nested includes are not supported")
+}
+
+func TestRender(t *testing.T) {
+	rendered, err := render([]byte("image: ${{vars.IMAGE}}:${{ vars.TAG }}\ntoken: ${{ secrets.TOKEN }}"),
+		map[string]string{"IMAGE": "node", "TAG": "18"})
+	assert.Nil(t, err)
+	assert.Equal(t, "image: node:18\ntoken: ${{ secrets.TOKEN }}", string(rendered))
+
+	_, err = render([]byte("${{ vars.B }} ${{ vars.A }} ${{ vars.B }}"), nil)
+	assert.EqualError(t, err, "undefined variables A, B")
+}
@@ -0,0 +1,189 @@
+package tasconfigmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"gopkg.in/yaml.v2"
+)
+
+// blocklistSeparator separates the file name from the suites and the test name of a blocklist entry
+const blocklistSeparator = "##"
+
+// loadPackages loads the configuration files of the packages matching the package patterns of the root.
+// The package configuration has the same file name as the root configuration and inherits its values,
+// except for the pre and post run commands, the root ones are run once and the ones defined by the
+// package are run in the package directory. The patterns and the
+// config file of the packages are relative to the package directory, they are rewritten relative to
+// the repository root as the runners are run from the root. Caches, blocklist and artifacts of the
+// packages are added to the ones of the root. The packages inherit the variables of the root, their fragments
+// are included over the root configuration.
+func (tc *TASConfigManager) loadPackages(ctx context.Context, repoDir string, root *core.TASConfig, rootDocs [][]byte,
+	rootVars map[string]string, eventType core.EventType) error {
+	fileName := path.Base(root.File)
+	rootDir := path.Dir(root.File)
+	dirs := make(map[string]bool)
+	for _, pattern := range root.Packages {
+		matches, err := filepath.Glob(filepath.Join(repoDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return fmt.Errorf("invalid packages pattern %s: %v", pattern, err)
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(repoDir, match)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if info, err := os.Stat(match); err != nil || !info.IsDir() || rel == rootDir {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(match, fileName)); err == nil {
+				dirs[rel] = true
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no %s found in the directories matching the packages %v", fileName, root.Packages)
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	for _, dir := range sorted {
+		pkg, err := tc.loadPackage(ctx, repoDir, dir, fileName, rootDocs, rootVars)
+		if err != nil {
+			return err
+		}
+		if err := checkEventType(pkg, eventType); err != nil {
+			return fmt.Errorf("%s: %v", pkg.File, err)
+		}
+		if err := mergeCaches(root, pkg); err != nil {
+			return err
+		}
+		root.Blocklist = append(root.Blocklist, pkg.Blocklist...)
+		if pkg.Artifacts != nil {
+			if root.Artifacts == nil {
+				root.Artifacts = &core.Artifacts{When: pkg.Artifacts.When}
+			}
+			root.Artifacts.Paths = append(root.Artifacts.Paths, pkg.Artifacts.Paths...)
+		}
+		root.SubConfigs = append(root.SubConfigs, pkg)
+	}
+	return nil
+}
+
+func (tc *TASConfigManager) loadPackage(ctx context.Context, repoDir, dir, fileName string, rootDocs [][]byte,
+	rootVars map[string]string) (*core.TASConfig, error) {
+	file := path.Join(dir, fileName)
+	content, err := ioutil.ReadFile(filepath.Join(repoDir, filepath.FromSlash(file)))
+	if err != nil {
+		tc.logger.Errorf("Error while reading file, error %v", err)
+		return nil, fmt.Errorf("Error while reading configuration file at path: %s", file)
+	}
+	docs, _, err := tc.resolve(ctx, repoDir, file, content, rootVars)
+	if err != nil {
+		return nil, err
+	}
+
+	// unmarshalling the package configuration over the root one overrides the keys present in the package
+	pkg := &core.TASConfig{SmartRun: true, Tier: core.Small}
+	for _, doc := range rootDocs {
+		if err := yaml.Unmarshal(doc, pkg); err != nil {
+			return nil, errors.New("Invalid format of configuration file")
+		}
+	}
+	pkg.Packages, pkg.Prerun, pkg.Postrun, pkg.Include = nil, nil, nil, nil
+	pkg.Cache, pkg.Caches, pkg.Blocklist, pkg.Artifacts = nil, nil, nil, nil
+	for _, doc := range docs {
+		if err := yaml.Unmarshal(doc, pkg); err != nil {
+			tc.logger.Errorf("Error while unmarshalling yaml file, path %s, error %v", file, err)
+			return nil, fmt.Errorf("Invalid format of configuration file at path: %s", file)
+		}
+	}
+	if len(pkg.Packages) > 0 {
+		return nil, fmt.Errorf("%s: nested packages are not supported", file)
+	}
+	if err := tc.validateConfig(pkg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	pkg.Dir, pkg.File = dir, file
+	for _, merge := range []*core.Merge{pkg.Premerge, pkg.Postmerge} {
+		if merge == nil {
+			continue
+		}
+		patterns := make([]string, 0, len(merge.Patterns))
+		for _, pattern := range merge.Patterns {
+			patterns = append(patterns, packagePath(dir, pattern))
+		}
+		merge.Patterns = patterns
+	}
+	if pkg.ConfigFile != "" {
+		pkg.ConfigFile = packagePath(dir, pkg.ConfigFile)
+	}
+	if pkg.Artifacts != nil {
+		for i, p := range pkg.Artifacts.Paths {
+			pkg.Artifacts.Paths[i] = packagePath(dir, p)
+		}
+	}
+	// regular expressions and tags are matched as they are
+	for i := range pkg.Blocklist {
+		for _, entry := range []*string{&pkg.Blocklist[i].Locator, &pkg.Blocklist[i].Glob} {
+			if *entry == "" {
+				continue
+			}
+			parts := strings.SplitN(*entry, blocklistSeparator, 2)
+			parts[0] = packagePath(dir, parts[0])
+			*entry = strings.Join(parts, blocklistSeparator)
+		}
+	}
+	for _, run := range []*core.Run{pkg.Prerun, pkg.Postrun} {
+		if run != nil {
+			run.Dir = dir
+		}
+	}
+	return pkg, nil
+}
+
+// packagePath returns the path relative to the package directory as relative to the repository root
+func packagePath(dir, p string) string {
+	if strings.HasPrefix(p, "!") {
+		return "!" + packagePath(dir, p[1:])
+	}
+	if path.IsAbs(p) {
+		return p
+	}
+	return path.Join(dir, p)
+}
+
+// mergeCaches adds the named caches of the package to the root, packages can share a cache by
+// defining it with the same name and values.
+func mergeCaches(root, pkg *core.TASConfig) error {
+	for _, cache := range pkg.Caches {
+		found := false
+		for _, existing := range root.Caches {
+			if existing.Name != cache.Name {
+				continue
+			}
+			if !reflect.DeepEqual(existing, cache) {
+				return fmt.Errorf("%s: cache %s is defined differently in another configuration", pkg.File, cache.Name)
+			}
+			found = true
+		}
+		if !found {
+			root.Caches = append(root.Caches, cache)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package tasconfigmanager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestLoadPackages(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tc := NewTASConfigManager(logger)
+
+	repoDir := t.TempDir()
+	rootContent := []byte(`framework: jest
+packages:
+  - packages/*
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+  env:
+    CI: "true"
+preRun:
+  command:
+    - npm ci
+caches:
+  - name: npm
+    key: npm
+    paths:
+      - ~/.npm
+`)
+	files := map[string]string{
+		"packages/api/.tas.yml": `framework: mocha
+configFile: .mocharc.yml
+blocklist:
+  - "test/flaky.spec.ts##suite"
+  - glob: "test/**/*.e2e.ts"
+preMerge:
+  env:
+    API: "1"
+preRun:
+  command:
+    - npm run build
+`,
+		"packages/web/.tas.yml": `caches:
+  - name: cypress
+    key: cypress
+    paths:
+      - ~/.cache/Cypress
+`,
+		"packages/docs/README.md": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(repoDir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.Nil(t, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+
+	root := &core.TASConfig{File: ".tas.yml"}
+	assert.Nil(t, yaml.Unmarshal(rootContent, root))
+	assert.Nil(t, tc.loadPackages(context.Background(), repoDir, root, [][]byte{rootContent}, nil, core.EventPullRequest))
+
+	assert.Len(t, root.Targets(), 2)
+	api, web := root.SubConfigs[0], root.SubConfigs[1]
+	assert.Equal(t, "packages/api", api.Dir)
+	assert.Equal(t, "packages/api/.tas.yml", api.File)
+	assert.Equal(t, "mocha", api.Framework)
+	assert.Equal(t, "packages/api/.mocharc.yml", api.ConfigFile)
+	assert.Equal(t, []string{"packages/api/test/**/*.spec.ts"}, api.Premerge.Patterns)
+	assert.Equal(t, map[string]string{"CI": "true", "API": "1"}, api.Premerge.EnvMap)
+	assert.Equal(t, &core.Run{Commands: []core.Command{{Run: "npm run build"}}, Dir: "packages/api"}, api.Prerun)
+
+	assert.Equal(t, "jest", web.Framework)
+	assert.Nil(t, web.Prerun)
+	assert.Equal(t, []string{"packages/web/test/**/*.spec.ts"}, web.Premerge.Patterns)
+
+	assert.Equal(t, []core.BlocklistEntry{{Locator: "packages/api/test/flaky.spec.ts##suite"}, {Glob: "packages/api/test/**/*.e2e.ts"}}, root.Blocklist)
+	assert.Len(t, root.Caches, 2)
+	assert.Equal(t, "cypress", root.Caches[1].Name)
+}
+
+func TestPackagePath(t *testing.T) {
+	assert.Equal(t, "packages/a/test/**/*.js", packagePath("packages/a", "./test/**/*.js"))
+	assert.Equal(t, "!packages/a/test/e2e/**", packagePath("packages/a", "!test/e2e/**"))
+	assert.Equal(t, "/abs/path", packagePath("packages/a", "/abs/path"))
+}
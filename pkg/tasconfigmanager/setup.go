@@ -6,14 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/LambdaTest/synapse/pkg/global"
 
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 	"github.com/LambdaTest/synapse/pkg/utils"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -37,6 +40,8 @@ type TASConfigManager struct {
 	uni        *ut.UniversalTranslator
 	validate   *validator.Validate
 	translator ut.Translator
+	// httpClient downloads the remote fragments
+	httpClient http.Client
 }
 
 // NewTASConfigManager creates and returns a new TASConfigManager instance
@@ -48,7 +53,8 @@ func NewTASConfigManager(logger lumber.Logger) *TASConfigManager {
 	en_translations.RegisterDefaultTranslations(validate, trans)
 	configureValidator(validate, trans)
 
-	return &TASConfigManager{logger: logger, uni: uni, validate: validate, translator: trans}
+	return &TASConfigManager{logger: logger, uni: uni, validate: validate, translator: trans,
+		httpClient: requestutils.NewResilientClient(30 * time.Second)}
 }
 
 // LoadConfig used for loading and validating the  tas configuration values provided by user
@@ -66,31 +72,31 @@ func (tc *TASConfigManager) LoadConfig(ctx context.Context,
 		return nil, fmt.Errorf("Error while reading configuration file at path: %s", path)
 	}
 
-	tasConfig := &core.TASConfig{SmartRun: true, Tier: core.Small}
-
-	err = yaml.Unmarshal(yamlFile, tasConfig)
+	docs, vars, err := tc.resolve(ctx, global.RepoDir, path, yamlFile, nil)
 	if err != nil {
-		tc.logger.Errorf("Error while unmarshalling yaml file, path %s, error %v", path, err)
-		return nil, errors.New("Invalid format of configuration file")
+		return nil, err
 	}
 
-	validateErr := tc.validate.Struct(tasConfig)
-	if validateErr != nil {
-		// translate all error at once
-		errs := validateErr.(validator.ValidationErrors)
-
-		errMsg := "Invalid values provided for the following fields in configuration file: \n"
-		for _, e := range errs {
-			// can translate each error one at a time.
-			errMsg += fmt.Sprintf("%s: %s\n", e.Field(), e.Value())
+	tasConfig := &core.TASConfig{SmartRun: true, Tier: core.Small, File: path}
+	for _, doc := range docs {
+		if err := yaml.Unmarshal(doc, tasConfig); err != nil {
+			tc.logger.Errorf("Error while unmarshalling yaml file, path %s, error %v", path, err)
+			return nil, errors.New("Invalid format of configuration file")
 		}
+	}
 
-		tc.logger.Errorf("Error while validating yaml file, error %v", validateErr)
-		return nil, errors.New(errMsg)
+	if err := tc.validateConfig(tasConfig); err != nil {
+		return nil, err
+	}
 
+	// only the root configuration is cloned in parse mode
+	if !parseMode && len(tasConfig.Packages) > 0 {
+		if err := tc.loadPackages(ctx, global.RepoDir, tasConfig, docs, vars, eventType); err != nil {
+			return nil, err
+		}
 	}
 
-	if !parseMode && tasConfig.Cache == nil {
+	if !parseMode && tasConfig.Cache == nil && len(tasConfig.Caches) == 0 {
 		checksum, err := utils.ComputeChecksum(fmt.Sprintf("%s/%s", global.RepoDir, packageJSON))
 		if err != nil {
 			tc.logger.Errorf("Error while computing checksum, error %v", err)
@@ -106,20 +112,47 @@ func (tc *TASConfigManager) LoadConfig(ctx context.Context,
 		tasConfig.CoverageThreshold = new(core.CoverageThreshold)
 	}
 
-	switch eventType {
-	case core.EventPullRequest:
-		if tasConfig.Premerge == nil {
-			return nil, errors.New("`preMerge` is not configured in configuration file")
-		}
-	case core.EventPush:
-		if tasConfig.Postmerge == nil {
-			return nil, errors.New("`postMerge` is not configured in configuration file")
-		}
+	if len(tasConfig.SubConfigs) > 0 {
+		// the root configuration is not executed if packages are configured
+		return tasConfig, nil
+	}
+	if err := checkEventType(tasConfig, eventType); err != nil {
+		return nil, err
 	}
 	return tasConfig, nil
 
 }
 
+func (tc *TASConfigManager) validateConfig(tasConfig *core.TASConfig) error {
+	validateErr := tc.validate.Struct(tasConfig)
+	if validateErr != nil {
+		// translate all error at once
+		errs := validateErr.(validator.ValidationErrors)
+
+		errMsg := "Invalid values provided for the following fields in configuration file: \n"
+		for _, e := range errs {
+			// can translate each error one at a time.
+			errMsg += fmt.Sprintf("%s: %s\n", e.Field(), e.Value())
+		}
+
+		tc.logger.Errorf("Error while validating yaml file, error %v", validateErr)
+		return errors.New(errMsg)
+	}
+	return nil
+}
+
+// checkEventType checks if the tests are configured for the event
+func checkEventType(tasConfig *core.TASConfig, eventType core.EventType) error {
+	if eventType.IsPremerge() {
+		if tasConfig.Premerge == nil {
+			return errors.New("`preMerge` is not configured in configuration file")
+		}
+	} else if tasConfig.Postmerge == nil {
+		return errors.New("`postMerge` is not configured in configuration file")
+	}
+	return nil
+}
+
 // configureValidator configure the struct validator
 func configureValidator(validate *validator.Validate, trans ut.Translator) {
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -0,0 +1,17 @@
+package tasconfigmanager
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEventType(t *testing.T) {
+	postMergeOnly := &core.TASConfig{Postmerge: &core.Merge{Patterns: []string{"test/**"}}}
+	assert.NotNil(t, checkEventType(postMergeOnly, core.EventPullRequest))
+	for _, eventType := range []core.EventType{core.EventPush, core.EventCron, core.EventCommitRange} {
+		assert.Nil(t, checkEventType(postMergeOnly, eventType))
+		assert.NotNil(t, checkEventType(&core.TASConfig{}, eventType))
+	}
+}
@@ -0,0 +1,379 @@
+package tasconfigmanager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/go-playground/validator/v10"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+// Severity values
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is an issue found while validating the configuration file
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// serverKeys are read by the TAS server and are not part of core.TASConfig
+var serverKeys = map[string]bool{
+	"version":            true,
+	"postMerge.strategy": true,
+}
+
+// extensionKeyPrefix is the prefix of the keys ignored by nucleus, e.g. x-env: &env
+const extensionKeyPrefix = "x-"
+
+var lineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// ValidateFile validates the configuration file at filePath without running any command.
+// Secret references are resolved against secrets, they are only reported as warnings if secrets is nil.
+// Diagnostics are sorted by line, the returned error is only set if the file can not be read.
+func (tc *TASConfigManager) ValidateFile(filePath string, secrets map[string]string) ([]Diagnostic, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	v := &configValidator{secretRegex: regexp.MustCompile(global.SecretRegex)}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		v.addYAMLError(err)
+		return v.diagnostics, nil
+	}
+	if len(root.Content) == 0 {
+		v.add(SeverityError, nil, "", "configuration file is empty")
+		return v.diagnostics, nil
+	}
+	doc := root.Content[0]
+	v.checkKeys(doc, reflect.TypeOf(core.TASConfig{}), "")
+
+	// the fragments in the repository are resolved relative to the file wherever the repository is
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	docs, _, err := tc.resolve(context.Background(), "", filepath.ToSlash(filePath), content, nil)
+	if err != nil {
+		v.add(SeverityError, nil, "", err.Error())
+		return v.sorted(), nil
+	}
+	tasConfig := &core.TASConfig{SmartRun: true, Tier: core.Small}
+	for _, d := range docs {
+		if err := yamlv2.Unmarshal(d, tasConfig); err != nil {
+			v.addYAMLError(err)
+			return v.sorted(), nil
+		}
+	}
+	if validateErr := tc.validate.Struct(tasConfig); validateErr != nil {
+		if fieldErrs, ok := validateErr.(validator.ValidationErrors); ok {
+			for _, e := range fieldErrs {
+				field := fieldPath(e.Namespace())
+				v.add(SeverityError, lookup(doc, field), field, e.Translate(tc.translator))
+			}
+		} else {
+			v.add(SeverityError, nil, "", validateErr.Error())
+		}
+	}
+
+	v.checkMerge(doc, "preMerge", tasConfig.Premerge, secrets)
+	v.checkMerge(doc, "postMerge", tasConfig.Postmerge, secrets)
+	if tasConfig.Premerge == nil && tasConfig.Postmerge == nil {
+		v.add(SeverityError, doc, "", "neither `preMerge` nor `postMerge` is configured")
+	}
+	v.checkEnv(doc, "env", tasConfig.Env, secrets)
+	if tasConfig.StageEnv != nil {
+		v.checkEnv(doc, "stageEnv.install", tasConfig.StageEnv.Install, secrets)
+		v.checkEnv(doc, "stageEnv.discovery", tasConfig.StageEnv.Discovery, secrets)
+		v.checkEnv(doc, "stageEnv.execution", tasConfig.StageEnv.Execution, secrets)
+	}
+	v.checkRun(doc, "preRun", tasConfig.Prerun, secrets)
+	v.checkRun(doc, "postRun", tasConfig.Postrun, secrets)
+	hooks := tasConfig.Hooks
+	// the hooks are checked in the order they run, so that the diagnostics are stable
+	for _, hook := range []struct {
+		field string
+		run   *core.Run
+	}{
+		{"hooks.preInstall", hooks.PreInstall},
+		{"hooks.postInstall", hooks.PostInstall},
+		{"hooks.preDiscovery", hooks.PreDiscovery},
+		{"hooks.preRun", hooks.PreRun},
+		{"hooks.postRun", hooks.PostRun},
+		{"hooks.onFailure", hooks.OnFailure},
+	} {
+		v.checkRun(doc, hook.field, hook.run, secrets)
+	}
+	if tasConfig.Artifacts != nil {
+		for i, p := range tasConfig.Artifacts.Paths {
+			v.checkPattern(doc, fmt.Sprintf("artifacts.paths[%d]", i), p)
+		}
+	}
+	for i := range tasConfig.Blocklist {
+		v.checkBlocklist(doc, fmt.Sprintf("blocklist[%d]", i), &tasConfig.Blocklist[i])
+	}
+	for name, service := range tasConfig.Services {
+		v.checkEnv(doc, fmt.Sprintf("services[%s].env", name), service.Env, secrets)
+	}
+	for i := range tasConfig.Caches {
+		for j, p := range tasConfig.Caches[i].Paths {
+			v.checkPattern(doc, fmt.Sprintf("caches[%d].paths[%d]", i, j), p)
+		}
+	}
+	return v.sorted(), nil
+}
+
+type configValidator struct {
+	secretRegex *regexp.Regexp
+	diagnostics []Diagnostic
+}
+
+func (v *configValidator) add(severity Severity, node *yaml.Node, field, message string) {
+	d := Diagnostic{Severity: severity, Field: field, Message: message}
+	if node != nil {
+		d.Line, d.Column = node.Line, node.Column
+	}
+	v.diagnostics = append(v.diagnostics, d)
+}
+
+// addYAMLError adds the syntax errors, which are prefixed with the line number by the yaml parsers
+func (v *configValidator) addYAMLError(err error) {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	} else if typeErr, ok := err.(*yamlv2.TypeError); ok {
+		messages = typeErr.Errors
+	}
+	for _, msg := range messages {
+		msg = strings.TrimPrefix(strings.TrimPrefix(msg, "yaml: "), "unmarshal errors:\n")
+		d := Diagnostic{Severity: SeverityError, Message: strings.TrimSpace(msg)}
+		if m := lineRegex.FindStringSubmatch(d.Message); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		v.diagnostics = append(v.diagnostics, d)
+	}
+}
+
+// checkKeys reports the keys of the mapping nodes which do not match a yaml tag of the type
+func (v *configValidator) checkKeys(node *yaml.Node, t reflect.Type, prefix string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, item := range node.Content {
+			v.checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i))
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.checkKeys(node.Content[i+1], t.Elem(), fmt.Sprintf("%s[%s]", prefix, node.Content[i].Value))
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// the keys merged from an anchor are checked where the anchor is defined, the extension keys prefixed
+			// with x- hold the anchors shared by the configuration
+			if key.Tag == "!!merge" || strings.HasPrefix(key.Value, extensionKeyPrefix) {
+				continue
+			}
+			field := joinField(prefix, key.Value)
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				if !serverKeys[field] {
+					v.add(SeverityWarning, key, field, fmt.Sprintf("unknown key `%s`", key.Value))
+				}
+				continue
+			}
+			v.checkKeys(value, fieldType, field)
+		}
+	}
+}
+
+func (v *configValidator) checkMerge(doc *yaml.Node, field string, merge *core.Merge, secrets map[string]string) {
+	if merge == nil {
+		return
+	}
+	for i, pattern := range merge.Patterns {
+		v.checkPattern(doc, fmt.Sprintf("%s.pattern[%d]", field, i), pattern)
+	}
+	v.checkEnv(doc, field+".env", merge.EnvMap, secrets)
+}
+
+func (v *configValidator) checkRun(doc *yaml.Node, field string, run *core.Run, secrets map[string]string) {
+	if run == nil {
+		return
+	}
+	if len(run.Commands) == 0 {
+		v.add(SeverityError, lookup(doc, field), field, fmt.Sprintf("`%s` has no commands", field))
+	}
+	for i, command := range run.Commands {
+		commandField := fmt.Sprintf("%s.command[%d]", field, i)
+		v.checkEnv(doc, commandField+".env", command.Env, secrets)
+		if strings.TrimSpace(command.Run) == "" {
+			v.add(SeverityError, lookup(doc, commandField), commandField, "command is empty")
+			continue
+		}
+		v.checkSecrets(doc, commandField, command.Run, secrets)
+	}
+	v.checkEnv(doc, field+".env", run.EnvMap, secrets)
+}
+
+func (v *configValidator) checkEnv(doc *yaml.Node, field string, env map[string]string, secrets map[string]string) {
+	for name, value := range env {
+		v.checkSecrets(doc, field+"."+name, value, secrets)
+	}
+}
+
+// checkSecrets reports the secret references in value which do not resolve
+func (v *configValidator) checkSecrets(doc *yaml.Node, field, value string, secrets map[string]string) {
+	for _, match := range v.secretRegex.FindAllStringSubmatch(value, -1) {
+		name := match[1]
+		if secrets == nil {
+			v.add(SeverityWarning, lookup(doc, field), field, fmt.Sprintf("secret `%s` can not be verified, no repo secrets provided", name))
+			continue
+		}
+		if _, ok := secrets[name]; !ok {
+			v.add(SeverityError, lookup(doc, field), field, fmt.Sprintf("secret `%s` is not defined in the repo secrets", name))
+		}
+	}
+}
+
+func (v *configValidator) checkPattern(doc *yaml.Node, field, pattern string) {
+	if strings.TrimSpace(pattern) == "" {
+		v.add(SeverityError, lookup(doc, field), field, "glob pattern is empty")
+		return
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		v.add(SeverityError, lookup(doc, field), field, fmt.Sprintf("invalid glob pattern `%s`: %v", pattern, err))
+	}
+}
+
+func (v *configValidator) checkBlocklist(doc *yaml.Node, field string, entry *core.BlocklistEntry) {
+	entryType, err := entry.Type()
+	if err != nil {
+		v.add(SeverityError, lookup(doc, field), field, err.Error())
+		return
+	}
+	switch entryType {
+	case core.BlocklistGlob:
+		v.checkPattern(doc, field+".glob", entry.Glob)
+	case core.BlocklistRegex:
+		if _, err := regexp.Compile(entry.Regex); err != nil {
+			v.add(SeverityError, lookup(doc, field+".regex"), field+".regex", fmt.Sprintf("invalid regex `%s`: %v", entry.Regex, err))
+		}
+	}
+	if entry.Expired(time.Now()) {
+		v.add(SeverityWarning, lookup(doc, field+".expires"), field+".expires",
+			fmt.Sprintf("blocklist entry `%s` expired on %s and is ignored", entry.Value(), entry.Expires.Format("2006-01-02")))
+	}
+}
+
+func (v *configValidator) sorted() []Diagnostic {
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Message < b.Message
+	})
+	return v.diagnostics
+}
+
+// yamlFields returns the types of the struct fields keyed by their yaml names
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get(yamlTagName), ",", 2)[0]
+		if name == emptyTagName {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinField(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + namespaceSeparator + name
+}
+
+// fieldPath removes the struct name from the namespace of the validation errors
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, namespaceSeparator); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// lookup returns the node of the field path, e.g. preRun.command[1] or services[postgres].image,
+// or the closest parent node found
+func lookup(node *yaml.Node, field string) *yaml.Node {
+	for _, part := range strings.Split(field, namespaceSeparator) {
+		name, indexes := part, []string{}
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			indexes = strings.Split(strings.Trim(part[i:], "[]"), "][")
+		}
+		child := mappingValue(node, name)
+		if child == nil {
+			return node
+		}
+		node = child
+		for _, idx := range indexes {
+			if node.Kind == yaml.MappingNode {
+				child = mappingValue(node, idx)
+			} else if n, err := strconv.Atoi(idx); err == nil && node.Kind == yaml.SequenceNode && n < len(node.Content) {
+				child = node.Content[n]
+			} else {
+				child = nil
+			}
+			if child == nil {
+				return node
+			}
+			node = child
+		}
+	}
+	return node
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
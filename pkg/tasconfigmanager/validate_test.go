@@ -0,0 +1,211 @@
+package tasconfigmanager
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFile(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tc := NewTASConfigManager(logger)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Diagnostic
+	}{
+		{
+			name: "valid",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+preRun:
+  command:
+    - npm ci --token ${{ secrets.NPM_TOKEN }}
+`,
+			want: []Diagnostic{},
+		},
+		{
+			name: "invalid",
+			content: `framework: jest
+tier: huge
+preMerge:
+  pattern:
+    - "./test/[a-.spec.ts"
+  env:
+    AWS_KEY: ${{ secrets.AWS_KEY }}
+preRun:
+  comand:
+    - npm ci
+workers: 64
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 2, Column: 7, Field: "tier", Message: "tier must be one of [xsmall small medium large xlarge]"},
+				{Severity: SeverityError, Line: 5, Column: 7, Field: "preMerge.pattern[0]", Message: "invalid glob pattern `./test/[a-.spec.ts`: syntax error in pattern"},
+				{Severity: SeverityError, Line: 7, Column: 14, Field: "preMerge.env.AWS_KEY", Message: "secret `AWS_KEY` is not defined in the repo secrets"},
+				{Severity: SeverityError, Line: 9, Column: 3, Field: "preRun", Message: "`preRun` has no commands"},
+				{Severity: SeverityWarning, Line: 9, Column: 3, Field: "preRun.comand", Message: "unknown key `comand`"},
+				{Severity: SeverityError, Line: 11, Column: 10, Field: "workers", Message: "workers must be 32 or less"},
+			},
+		},
+		{
+			name: "services",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+services:
+  postgres:
+    imge: postgres:14
+    env:
+      POSTGRES_PASSWORD: ${{ secrets.DB_PASSWORD }}
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 7, Column: 5, Field: "services[postgres].image", Message: "services[postgres].image field is required!"},
+				{Severity: SeverityWarning, Line: 7, Column: 5, Field: "services[postgres].imge", Message: "unknown key `imge`"},
+				{Severity: SeverityError, Line: 9, Column: 26, Field: "services[postgres].env.POSTGRES_PASSWORD", Message: "secret `DB_PASSWORD` is not defined in the repo secrets"},
+			},
+		},
+		{
+			name: "hooks",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+hooks:
+  onFailure:
+    command:
+      - ./upload.sh ${{ secrets.UPLOAD_TOKEN }}
+  preInstall:
+    command: []
+  postRun:
+    command:
+      - ""
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 8, Column: 9, Field: "hooks.onFailure.command[0]", Message: "secret `UPLOAD_TOKEN` is not defined in the repo secrets"},
+				{Severity: SeverityError, Line: 10, Column: 5, Field: "hooks.preInstall", Message: "`hooks.preInstall` has no commands"},
+				{Severity: SeverityError, Line: 10, Column: 14, Field: "hooks.preInstall.command", Message: "command must contain more than 0 items"},
+				{Severity: SeverityError, Line: 13, Column: 9, Field: "hooks.postRun.command[0]", Message: "command is empty"},
+			},
+		},
+		{
+			name: "blocklist",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+blocklist:
+  - "test/api.spec.ts##suite"
+  - tag: "@slow"
+    regex: "slow"
+  - regex: "(api"
+  - glob: "test/**/*.e2e.ts"
+    expires: 2020-01-01
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 7, Column: 5, Field: "blocklist[1]", Message: "blocklist entry must set exactly one of `locator`, `glob`, `regex` or `tag`"},
+				{Severity: SeverityError, Line: 9, Column: 12, Field: "blocklist[2].regex", Message: "invalid regex `(api`: error parsing regexp: missing closing ): `(api`"},
+				{Severity: SeverityWarning, Line: 11, Column: 14, Field: "blocklist[3].expires", Message: "blocklist entry `test/**/*.e2e.ts` expired on 2020-01-01 and is ignored"},
+			},
+		},
+		{
+			name: "env",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+env:
+  NODE_ENV: test
+stageEnv:
+  install:
+    NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+  execution:
+    API_KEY: ${{ secrets.API_KEY }}
+preRun:
+  command:
+    - npm ci
+    - run: npm run build
+      env:
+        BUILD_KEY: ${{ secrets.BUILD_KEY }}
+    - run: " "
+      evn:
+        A: b
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 11, Column: 14, Field: "stageEnv.execution.API_KEY", Message: "secret `API_KEY` is not defined in the repo secrets"},
+				{Severity: SeverityError, Line: 17, Column: 20, Field: "preRun.command[1].env.BUILD_KEY", Message: "secret `BUILD_KEY` is not defined in the repo secrets"},
+				{Severity: SeverityError, Line: 18, Column: 7, Field: "preRun.command[2]", Message: "command is empty"},
+				{Severity: SeverityWarning, Line: 19, Column: 7, Field: "preRun.command[2].evn", Message: "unknown key `evn`"},
+			},
+		},
+		{
+			name: "caches",
+			content: `framework: jest
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+caches:
+  - name: ".."
+    key: npm
+    paths:
+      - node_modules
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 6, Column: 11, Field: "caches[0].name", Message: "name should not be equal to .."},
+			},
+		},
+		{
+			name: "variables",
+			content: `framework: jest
+variables:
+  TIER: huge
+tier: ${{ vars.TIER }}
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+`,
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 4, Column: 7, Field: "tier", Message: "tier must be one of [xsmall small medium large xlarge]"},
+			},
+		},
+		{
+			name: "anchors",
+			content: `framework: jest
+x-env: &env
+  CI: "true"
+preMerge:
+  pattern:
+    - "./test/**/*.spec.ts"
+  env:
+    <<: *env
+    API: "1"
+`,
+			want: []Diagnostic{},
+		},
+		{
+			name:    "syntax",
+			content: "framework: jest\npreMerge:\n  pattern: [\n",
+			want:    []Diagnostic{{Severity: SeverityError, Line: 3, Message: "did not find expected node content"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".tas.yml")
+			assert.Nil(t, ioutil.WriteFile(path, []byte(tt.content), 0644))
+			got, err := tc.ValidateFile(path, map[string]string{"NPM_TOKEN": "token"})
+			assert.Nil(t, err)
+			if len(tt.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
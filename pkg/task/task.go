@@ -13,23 +13,24 @@ import (
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
 )
 
+const taskEndpoint = "/task"
+
 // task represents each instance of nucleus spawned by neuron
 type task struct {
-	ctx      context.Context
-	client   http.Client
-	endpoint string
-	logger   lumber.Logger
+	ctx    context.Context
+	client http.Client
+	logger lumber.Logger
 }
 
 // New returns new task
 func New(ctx context.Context, cfg *config.NucleusConfig, logger lumber.Logger) (core.Task, error) {
 	return &task{
-		ctx:      ctx,
-		client:   http.Client{Timeout: 30 * time.Second},
-		logger:   logger,
-		endpoint: global.NeuronHost + "/task",
+		ctx:    ctx,
+		client: requestutils.NewResilientClient(30 * time.Second),
+		logger: logger,
 	}, nil
 }
 
@@ -42,7 +43,7 @@ func (t *task) UpdateStatus(payload *core.TaskPayload) error {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(t.ctx, http.MethodPut, t.endpoint, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPut, global.NeuronURL(taskEndpoint), bytes.NewBuffer(reqBody))
 
 	if err != nil {
 		t.logger.Errorf("error while creating http request %v", err)
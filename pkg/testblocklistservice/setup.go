@@ -5,45 +5,91 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
 )
 
 const (
-	delimiter = "##"
+	delimiter         = "##"
+	blocklistEndpoint = "/blocklist"
+	sourceYML         = "yml"
+	sourceAPI         = "api"
+	sourceRuntime     = "runtime"
+	// anyFile is the key of the entries which are not resolved to a test file, i.e. the regular
+	// expressions and the tags, which are matched by the runners against all the tests
+	anyFile = "*"
 )
 
 //blocklist represents the blocklisted test suites and test cases.
 type blocklist struct {
 	Source  string `json:"source"`
 	Locator string `json:"locator"`
+	// Type is omitted for the test locators, the locator of the other types is a pattern or a tag
+	Type core.BlocklistType `json:"type,omitempty"`
 }
 
 // fetch blocklisted test cases from neuron API
 type blocklistResponse struct {
-	Name        string `json:"name"`
-	Repo        string `json:"repo"`
-	TestLocator string `json:"test_locator"`
+	Name        string             `json:"name"`
+	Repo        string             `json:"repo"`
+	TestLocator string             `json:"test_locator"`
+	Type        core.BlocklistType `json:"type"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+}
+
+// entry returns the blocklist entry of the response, the test locator is a pattern or a tag for the other types
+func (b *blocklistResponse) entry() core.BlocklistEntry {
+	entry := core.BlocklistEntry{Expires: b.ExpiresAt}
+	switch b.Type {
+	case core.BlocklistGlob:
+		entry.Glob = b.TestLocator
+	case core.BlocklistRegex:
+		entry.Regex = b.TestLocator
+	case core.BlocklistTag:
+		entry.Tag = b.TestLocator
+	default:
+		entry.Locator = b.TestLocator
+	}
+	return entry
+}
+
+// Entry is a blocklist entry with its source, i.e. the yml, the api or the runtime entries
+type Entry struct {
+	Source string `json:"source"`
+	core.BlocklistEntry
 }
 
 // TestBlockListService represents an instance of ConfManager instance
 type TestBlockListService struct {
 	cfg                 *config.NucleusConfig
 	logger              lumber.Logger
+	repoDir             string
+	blocklistFile       string
 	httpClient          http.Client
-	endpoint            string
 	blocklistedEntities map[string][]blocklist
 	once                sync.Once
 	errChan             chan error
+	mu                  sync.Mutex
+	refreshInterval     time.Duration
+	intervalChanged     chan struct{}
+	// entries are the last fetched api entries, the yml and the runtime entries
+	entries map[string][]core.BlocklistEntry
+	changed chan struct{}
 }
 
 // NewTestBlockListService creates and returns a new TestBlockListService instance
@@ -52,26 +98,28 @@ func NewTestBlockListService(cfg *config.NucleusConfig, logger lumber.Logger) (*
 	return &TestBlockListService{
 		cfg:                 cfg,
 		logger:              logger,
-		endpoint:            global.NeuronHost + "/blocklist",
+		repoDir:             global.RepoDir,
+		blocklistFile:       global.BlocklistedFileLocation,
 		blocklistedEntities: make(map[string][]blocklist),
 		errChan:             make(chan error, 1),
-		httpClient: http.Client{
-			Timeout: 15 * time.Second,
-			Transport: &http.Transport{
-				DisableKeepAlives: true,
-			},
-		}}, nil
+		refreshInterval:     time.Duration(cfg.BlocklistRefreshInterval) * time.Second,
+		intervalChanged:     make(chan struct{}, 1),
+		entries:             make(map[string][]core.BlocklistEntry),
+		changed:             make(chan struct{}, 1),
+		httpClient:          requestutils.Resilient(requestutils.NewClientWithoutKeepAlive(15 * time.Second)),
+	}, nil
 }
 
 //fetchBlockListFromNeuron
-func (tbs *TestBlockListService) fetchBlockListFromNeuron(ctx context.Context, repoID string) error {
+func (tbs *TestBlockListService) fetchBlockListFromNeuron(ctx context.Context, repoID string) ([]core.BlocklistEntry, error) {
 
 	var inp []blocklistResponse
 
-	u, err := url.Parse(tbs.endpoint)
+	endpoint := global.NeuronURL(blocklistEndpoint)
+	u, err := url.Parse(endpoint)
 	if err != nil {
-		tbs.logger.Errorf("error while parsing endpoint %s, %v", tbs.endpoint, err)
-		return err
+		tbs.logger.Errorf("error while parsing endpoint %s, %v", endpoint, err)
+		return nil, err
 	}
 	q := u.Query()
 	q.Set("repoID", repoID)
@@ -80,98 +128,304 @@ func (tbs *TestBlockListService) fetchBlockListFromNeuron(ctx context.Context, r
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		tbs.logger.Errorf("Unable to fetch blocklist response: %+v", err)
-		return err
+		return nil, err
 	}
+	tracing.InjectHeaders(ctx, req.Header)
 
 	resp, err := tbs.httpClient.Do(req)
 	if err != nil {
 		tbs.logger.Errorf("Unable to fetch blocklist response: %v", err)
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil
+		return nil, nil
 	}
 	if resp.StatusCode != http.StatusOK {
 		err = errors.New("non 200 status")
 		tbs.logger.Errorf("Unable to fetch blocklist response: %v", err)
-		return err
+		return nil, err
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		tbs.logger.Errorf("Unable to fetch blocklist response: %v", err)
-		return err
+		return nil, err
 	}
 
 	if jsonErr := json.Unmarshal(body, &inp); jsonErr != nil {
 		tbs.logger.Errorf("Unable to fetch blocklist response: %v", jsonErr)
-		return jsonErr
+		return nil, jsonErr
 	}
-	// populate bl
-
-	locators := make([]string, 0, len(inp))
+	entries := make([]core.BlocklistEntry, 0, len(inp))
 	for i := range inp {
-		locators = append(locators, inp[i].TestLocator)
+		entries = append(entries, inp[i].entry())
 	}
-	tbs.populateBlockList("api", locators)
-	return nil
+	return entries, nil
 }
 
 // GetBlockListedTests provides list of blocklisted test cases
 func (tbs *TestBlockListService) GetBlockListedTests(ctx context.Context, tasConfig *core.TASConfig, repoID string) error {
 
 	tbs.once.Do(func() {
-		tbs.populateBlockList("yml", tasConfig.Blocklist)
-
-		if err := tbs.fetchBlockListFromNeuron(ctx, repoID); err != nil {
-			tbs.logger.Errorf("Unable to fetch remote blocklist: %v. Ignoring remote response", err)
+		if err := tbs.writeBlockList(ctx, tasConfig, repoID); err != nil {
 			tbs.errChan <- err
 			return
 		}
-		tbs.logger.Infof("Blocklisted tests: %+v", tbs.blocklistedEntities)
+		go tbs.refresh(ctx, tasConfig, repoID)
+	})
+	select {
+	case err := <-tbs.errChan:
+		return err
+	default:
+		return nil
+	}
+}
 
-		// write blocklistest tests on disk
-		marshalledBlocklist, err := json.Marshal(tbs.blocklistedEntities)
-		if err != nil {
-			tbs.logger.Errorf("Unable to json marshal blocklist: %+v", err)
-			tbs.errChan <- err
-			return
-		}
+// writeBlockList fetches the remote blocklist and writes it on disk along with the blocklist of the yml
+func (tbs *TestBlockListService) writeBlockList(ctx context.Context, tasConfig *core.TASConfig, repoID string) error {
+	remote, err := tbs.fetchBlockListFromNeuron(ctx, repoID)
+	if err != nil {
+		tbs.logger.Errorf("Unable to fetch remote blocklist: %v. Ignoring remote response", err)
+		return err
+	}
+	tbs.mu.Lock()
+	tbs.entries[sourceYML] = tasConfig.Blocklist
+	tbs.entries[sourceAPI] = remote
+	tbs.mu.Unlock()
+	return tbs.write()
+}
 
-		if err = ioutil.WriteFile(global.BlocklistedFileLocation, marshalledBlocklist, 0644); err != nil {
-			tbs.logger.Errorf("Unable to write blocklist file: %+v", err)
-			tbs.errChan <- err
+// write writes the blocklist file with the yml, the api and the runtime entries
+func (tbs *TestBlockListService) write() error {
+	tbs.blocklistedEntities = make(map[string][]blocklist)
+	defer func() { tbs.blocklistedEntities = nil }()
+	tbs.mu.Lock()
+	entries := make(map[string][]core.BlocklistEntry, len(tbs.entries))
+	for source := range tbs.entries {
+		entries[source] = append([]core.BlocklistEntry{}, tbs.entries[source]...)
+	}
+	tbs.mu.Unlock()
+	for _, source := range []string{sourceYML, sourceAPI, sourceRuntime} {
+		tbs.populateBlockList(source, entries[source])
+	}
+	tbs.logger.Infof("Blocklisted tests: %+v", tbs.blocklistedEntities)
+
+	// write blocklistest tests on disk
+	marshalledBlocklist, err := json.Marshal(tbs.blocklistedEntities)
+	if err != nil {
+		tbs.logger.Errorf("Unable to json marshal blocklist: %+v", err)
+		return err
+	}
+
+	if err = ioutil.WriteFile(tbs.blocklistFile, marshalledBlocklist, 0644); err != nil {
+		tbs.logger.Errorf("Unable to write blocklist file: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// refresh rewrites the blocklist file at the refresh interval and whenever the runtime entries change
+// until ctx is done, so that the tests blocklisted while the task is running are skipped by the runner
+// processes started afterwards. The remote blocklist is not refreshed if the interval is 0.
+func (tbs *TestBlockListService) refresh(ctx context.Context, tasConfig *core.TASConfig, repoID string) {
+	for {
+		var timer *time.Timer
+		var tick <-chan time.Time
+		if interval := tbs.getRefreshInterval(); interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+		refresh, changed := false, false
+		select {
+		case <-ctx.Done():
+		case <-tbs.intervalChanged:
+		case <-tbs.changed:
+			changed = true
+		case <-tick:
+			refresh = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
 			return
 		}
-		tbs.blocklistedEntities = nil
-	})
+		switch {
+		case refresh:
+			// the previous blocklist file is kept if the remote blocklist can not be fetched
+			if err := tbs.writeBlockList(ctx, tasConfig, repoID); err != nil {
+				tbs.logger.Warnf("Unable to refresh blocklist: %v", err)
+			}
+		case changed:
+			if err := tbs.write(); err != nil {
+				tbs.logger.Warnf("Unable to apply runtime blocklist: %v", err)
+			}
+		}
+	}
+}
+
+// SetRefreshInterval changes the interval at which the blocklist file is refreshed, 0 disables the refresh
+func (tbs *TestBlockListService) SetRefreshInterval(interval time.Duration) {
+	tbs.mu.Lock()
+	tbs.refreshInterval = interval
+	tbs.mu.Unlock()
 	select {
-	case err := <-tbs.errChan:
+	case tbs.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (tbs *TestBlockListService) getRefreshInterval() time.Duration {
+	tbs.mu.Lock()
+	defer tbs.mu.Unlock()
+	return tbs.refreshInterval
+}
+
+// Entries returns the entries of the blocklist, the api entries are the last fetched ones
+func (tbs *TestBlockListService) Entries() []Entry {
+	tbs.mu.Lock()
+	defer tbs.mu.Unlock()
+	entries := make([]Entry, 0)
+	for _, source := range []string{sourceYML, sourceAPI, sourceRuntime} {
+		for _, entry := range tbs.entries[source] {
+			entries = append(entries, Entry{Source: source, BlocklistEntry: entry})
+		}
+	}
+	return entries
+}
+
+// AddEntry adds a runtime entry, the blocklist file is rewritten so that the entry applies to the
+// runner processes started afterwards. An entry which was already added replaces the previous one.
+func (tbs *TestBlockListService) AddEntry(entry core.BlocklistEntry) error {
+	if _, err := validateEntry(&entry); err != nil {
 		return err
+	}
+	tbs.mu.Lock()
+	runtime := tbs.entries[sourceRuntime]
+	if i := indexOf(runtime, &entry); i >= 0 {
+		runtime[i] = entry
+	} else {
+		tbs.entries[sourceRuntime] = append(runtime, entry)
+	}
+	tbs.mu.Unlock()
+	tbs.notifyChanged()
+	return nil
+}
+
+// RemoveEntry removes a runtime entry matching the locator, pattern or tag of entry,
+// the entries of the yml and the api can not be removed.
+func (tbs *TestBlockListService) RemoveEntry(entry core.BlocklistEntry) error {
+	tbs.mu.Lock()
+	runtime := tbs.entries[sourceRuntime]
+	i := indexOf(runtime, &entry)
+	if i < 0 {
+		tbs.mu.Unlock()
+		return errs.ErrBlocklistEntryNotFound
+	}
+	tbs.entries[sourceRuntime] = append(runtime[:i:i], runtime[i+1:]...)
+	tbs.mu.Unlock()
+	tbs.notifyChanged()
+	return nil
+}
+
+func (tbs *TestBlockListService) notifyChanged() {
+	select {
+	case tbs.changed <- struct{}{}:
 	default:
-		return nil
 	}
 }
 
-func (tbs *TestBlockListService) populateBlockList(blocklistSource string, blocklistLocators []string) {
+// indexOf returns the index of the entry with the same locator, pattern or tag, or -1
+func indexOf(entries []core.BlocklistEntry, entry *core.BlocklistEntry) int {
+	for i := range entries {
+		if entries[i].Locator == entry.Locator && entries[i].Glob == entry.Glob &&
+			entries[i].Regex == entry.Regex && entries[i].Tag == entry.Tag {
+			return i
+		}
+	}
+	return -1
+}
 
-	i := 0
-	for _, locator := range blocklistLocators {
+// validateEntry returns the type of the entry, or an error if its pattern is invalid
+func validateEntry(entry *core.BlocklistEntry) (core.BlocklistType, error) {
+	entryType, err := entry.Type()
+	if err != nil {
+		return "", err
+	}
+	switch entryType {
+	case core.BlocklistGlob:
+		if _, err := utils.MatchGlob(entry.Glob, ""); err != nil {
+			return "", fmt.Errorf("invalid glob %s: %v", entry.Glob, err)
+		}
+	case core.BlocklistRegex:
+		if _, err := regexp.Compile(entry.Regex); err != nil {
+			return "", fmt.Errorf("invalid regex %s: %v", entry.Regex, err)
+		}
+	}
+	return entryType, nil
+}
 
-		//locators must end with delimiter
-		if !strings.HasSuffix(locator, delimiter) {
-			locator += delimiter
+// populateBlockList adds the entries which have not expired. Glob patterns are resolved to the test files
+// of the repo matching their file part, regular expressions and tags are matched by the runners.
+func (tbs *TestBlockListService) populateBlockList(blocklistSource string, entries []core.BlocklistEntry) {
+	now := time.Now()
+	for i := range entries {
+		entryType, err := validateEntry(&entries[i])
+		if err != nil {
+			tbs.logger.Warnf("Ignoring %s blocklist entry %+v: %v", blocklistSource, entries[i], err)
+			continue
 		}
-		i = strings.Index(locator, delimiter)
-		//TODO: handle duplicate entries and ignore its individual suites or testcases in blocklist if file is blocklisted
+		value := entries[i].Value()
+		if entries[i].Expired(now) {
+			tbs.logger.Infof("Ignoring %s blocklist entry %s, expired at %s", blocklistSource, value, entries[i].Expires)
+			continue
+		}
+		switch entryType {
+		case core.BlocklistLocator:
+			tbs.addLocator(blocklistSource, value)
+		case core.BlocklistGlob:
+			tbs.addGlob(blocklistSource, value)
+		case core.BlocklistRegex, core.BlocklistTag:
+			tbs.add(anyFile, blocklist{Source: blocklistSource, Locator: value, Type: entryType})
+		}
+	}
+}
+
+// addLocator adds the test locator of a file, a suite or a test
+func (tbs *TestBlockListService) addLocator(blocklistSource, locator string) {
+	//locators must end with delimiter
+	if !strings.HasSuffix(locator, delimiter) {
+		locator += delimiter
+	}
+	i := strings.Index(locator, delimiter)
+	//TODO: handle duplicate entries and ignore its individual suites or testcases in blocklist if file is blocklisted
+	tbs.add(locator[:i], blocklist{Source: blocklistSource, Locator: locator})
+}
 
-		if val, ok := tbs.blocklistedEntities[locator[:i]]; ok {
-			tbs.blocklistedEntities[locator[:i]] = append(val, blocklist{Source: blocklistSource, Locator: locator})
-		} else {
-			tbs.blocklistedEntities[locator[:i]] = append([]blocklist{}, blocklist{Source: blocklistSource, Locator: locator})
+// addGlob adds the glob pattern for each test file matching its file part. The pattern is added as
+// test locators if the suites and the test name of the pattern have no wildcards.
+func (tbs *TestBlockListService) addGlob(blocklistSource, pattern string) {
+	parts := strings.SplitN(strings.TrimSuffix(pattern, delimiter), delimiter, 2)
+	files, err := utils.Glob(tbs.repoDir, parts[0])
+	if err != nil {
+		tbs.logger.Warnf("Ignoring %s blocklist entry, invalid glob %s: %v", blocklistSource, pattern, err)
+		return
+	}
+	if len(files) == 0 {
+		tbs.logger.Debugf("%s blocklist glob %s matches no test file", blocklistSource, pattern)
+	}
+	for _, file := range files {
+		if len(parts) == 1 || !strings.ContainsAny(parts[1], "*?[\\") {
+			tbs.addLocator(blocklistSource, strings.Join(append([]string{file}, parts[1:]...), delimiter))
+			continue
 		}
+		tbs.add(file, blocklist{Source: blocklistSource, Locator: file + delimiter + parts[1], Type: core.BlocklistGlob})
 	}
 }
+
+func (tbs *TestBlockListService) add(file string, entry blocklist) {
+	tbs.blocklistedEntities[file] = append(tbs.blocklistedEntities[file], entry)
+}
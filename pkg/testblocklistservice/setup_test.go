@@ -0,0 +1,83 @@
+package testblocklistservice
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPopulateBlockList(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tbs, err := NewTestBlockListService(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	tbs.repoDir = t.TempDir()
+	for _, file := range []string{"test/a.e2e.js", "test/nested/b.e2e.js", "test/c.spec.js"} {
+		p := filepath.Join(tbs.repoDir, filepath.FromSlash(file))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.Nil(t, ioutil.WriteFile(p, []byte(file), 0644))
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	tbs.populateBlockList("yml", []core.BlocklistEntry{
+		{Locator: "test/c.spec.js##suite"},
+		{Glob: "test/**/*.e2e.js"},
+		{Glob: "test/*.spec.js##suite##*flaky*"},
+		{Regex: "^test/.*##payments##"},
+		{Regex: "(invalid"},
+		{Tag: "@slow"},
+		{Tag: "@wip", Expires: &expired},
+		{Locator: "test/c.spec.js", Tag: "@slow"},
+	})
+	assert.Equal(t, map[string][]blocklist{
+		"test/a.e2e.js":        {{Source: "yml", Locator: "test/a.e2e.js##"}},
+		"test/nested/b.e2e.js": {{Source: "yml", Locator: "test/nested/b.e2e.js##"}},
+		"test/c.spec.js": {
+			{Source: "yml", Locator: "test/c.spec.js##suite##"},
+			{Source: "yml", Locator: "test/c.spec.js##suite##*flaky*", Type: core.BlocklistGlob},
+		},
+		anyFile: {
+			{Source: "yml", Locator: "^test/.*##payments##", Type: core.BlocklistRegex},
+			{Source: "yml", Locator: "@slow", Type: core.BlocklistTag},
+		},
+	}, tbs.blocklistedEntities)
+}
+
+func TestRuntimeEntries(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tbs, err := NewTestBlockListService(&config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	tbs.repoDir = t.TempDir()
+	tbs.blocklistFile = filepath.Join(t.TempDir(), "blocklist.json")
+	tbs.entries[sourceYML] = []core.BlocklistEntry{{Locator: "test/a.spec.js"}}
+
+	assert.NotNil(t, tbs.AddEntry(core.BlocklistEntry{Regex: "(invalid"}))
+	assert.Nil(t, tbs.AddEntry(core.BlocklistEntry{Tag: "@slow"}))
+	assert.Nil(t, tbs.AddEntry(core.BlocklistEntry{Locator: "test/b.spec.js##suite"}))
+	assert.Equal(t, []Entry{
+		{Source: sourceYML, BlocklistEntry: core.BlocklistEntry{Locator: "test/a.spec.js"}},
+		{Source: sourceRuntime, BlocklistEntry: core.BlocklistEntry{Tag: "@slow"}},
+		{Source: sourceRuntime, BlocklistEntry: core.BlocklistEntry{Locator: "test/b.spec.js##suite"}},
+	}, tbs.Entries())
+
+	assert.ErrorIs(t, tbs.RemoveEntry(core.BlocklistEntry{Locator: "test/a.spec.js"}), errs.ErrBlocklistEntryNotFound)
+	assert.Nil(t, tbs.RemoveEntry(core.BlocklistEntry{Tag: "@slow"}))
+	assert.Len(t, tbs.changed, 1)
+
+	assert.Nil(t, tbs.write())
+	content, err := ioutil.ReadFile(tbs.blocklistFile)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"test/a.spec.js": [{"source": "yml", "locator": "test/a.spec.js##"}],
+		"test/b.spec.js": [{"source": "runtime", "locator": "test/b.spec.js##suite##"}]
+	}`, string(content))
+}
@@ -3,12 +3,18 @@ package testdiscoveryservice
 
 import (
 	"context"
+	"fmt"
 	"os/exec"
+	"sort"
+	"time"
 
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/logstream"
 	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type testDiscoveryService struct {
@@ -26,32 +32,32 @@ func (tds *testDiscoveryService) Discover(ctx context.Context,
 	tasConfig *core.TASConfig,
 	payload *core.Payload,
 	secretData map[string]string,
-	diff map[string]int) error {
-	var target []string
+	diff map[string]int) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "testdiscoveryservice.Discover", attribute.String("tas.framework", tasConfig.Framework))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	var envMap map[string]string
-	if payload.EventType == core.EventPullRequest {
-		target = tasConfig.Premerge.Patterns
+	if payload.EventType.IsPremerge() {
 		envMap = tasConfig.Premerge.EnvMap
 	} else {
-		target = tasConfig.Postmerge.Patterns
 		envMap = tasConfig.Postmerge.EnvMap
 	}
-	tasYmlModified := false
-	if _, ok := diff[payload.TasFileName]; ok {
-		tasYmlModified = true
+	strategy := payload.SelectionStrategy(tasConfig)
+	target, changed, err := tds.selectTests(strategy, tasConfig, payload, diff)
+	if err != nil {
+		tds.logger.Errorf("failed to select the tests with the %s strategy, error: %v", strategy, err)
+		return err
+	}
+	span.SetAttributes(attribute.String("tas.selection", string(strategy)),
+		attribute.Bool("tas.discover_all", changed == nil && (strategy == core.SelectionFull || strategy == core.SelectionImpacted)))
+	if len(target) == 0 {
+		tds.logger.Infof("No test of %s is selected by the %s strategy, skipping test discovery", tasConfig.File, strategy)
+		return nil
 	}
-
-	// discover all tests if tas.yml modified or if parent commit does not exists or smart run feature is set to false
-	discoverAll := tasYmlModified || !payload.ParentCommitCoverageExists || !tasConfig.SmartRun
 
 	args := []string{"--command", "discover"}
-	if !discoverAll {
-		for k, v := range diff {
-			// in changed files we only have added or modified files.
-			if v != core.FileRemoved {
-				args = append(args, "--diff", k)
-			}
-		}
+	for _, file := range changed {
+		args = append(args, "--diff", file)
 	}
 	if tasConfig.ConfigFile != "" {
 		args = append(args, "--config", tasConfig.ConfigFile)
@@ -64,12 +70,12 @@ func (tds *testDiscoveryService) Discover(ctx context.Context,
 
 	cmd := exec.CommandContext(ctx, global.FrameworkRunnerMap[tasConfig.Framework], args...)
 	cmd.Dir = global.RepoDir
-	envVars, err := tds.execManager.GetEnvVariables(envMap, secretData)
+	envVars, err := tds.execManager.GetEnvVariables(secretData, append(tasConfig.EnvScopes(core.EnvStageDiscovery), envMap)...)
 	if err != nil {
 		tds.logger.Errorf("failed to parsed env variables, error: %v", err)
 		return err
 	}
-	cmd.Env = envVars
+	cmd.Env = append(envVars, tracing.Environ(ctx)...)
 	logWriter := lumber.NewWriter(tds.logger)
 	defer logWriter.Close()
 	maskWriter := logstream.NewMasker(logWriter, secretData)
@@ -77,10 +83,74 @@ func (tds *testDiscoveryService) Discover(ctx context.Context,
 	cmd.Stderr = maskWriter
 
 	tds.logger.Debugf("Executing test discovery command: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
+	startTime := time.Now()
+	err = cmd.Run()
+	tds.execManager.RecordCommand(ctx, core.Discovery, cmd, startTime, secretData, err)
+	if err != nil {
 		tds.logger.Errorf("command %s of type %s failed with error: %v", cmd.String(), core.Discovery, err)
 		return err
 	}
 
 	return nil
 }
+
+// selectTests returns the test patterns and the changed files passed to the runner by the selection strategy,
+// the runner selects the tests depending on the changed files if there are any
+func (tds *testDiscoveryService) selectTests(strategy core.SelectionStrategy,
+	tasConfig *core.TASConfig,
+	payload *core.Payload,
+	diff map[string]int) (patterns, changed []string, err error) {
+	switch strategy {
+	case core.SelectionFull:
+		return tasConfig.TestPatterns(payload.EventType), nil, nil
+	case core.SelectionImpacted:
+		_, tasYmlModified := diff[payload.TasFileName]
+		// packages inherit the root configuration, hence both are checked
+		if _, ok := diff[tasConfig.File]; ok {
+			tasYmlModified = true
+		}
+		// discover all tests if tas.yml modified or if parent commit does not exists
+		if tasYmlModified || !payload.ParentCommitCoverageExists {
+			return tasConfig.TestPatterns(payload.EventType), nil, nil
+		}
+		changed = make([]string, 0, len(diff))
+		for file, changeType := range diff {
+			// in changed files we only have added or modified files.
+			if changeType != core.FileRemoved {
+				changed = append(changed, file)
+			}
+		}
+		return tasConfig.TestPatterns(payload.EventType), changed, nil
+	case core.SelectionChanged:
+		tests, err := core.ChangedTests(tasConfig, payload.EventType, diff)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Strings(tests)
+		return quoteGlobs(tests), nil, nil
+	case core.SelectionNamed:
+		tests, err := payload.Selection.NamedTests(tasConfig, payload.EventType)
+		if err != nil {
+			return nil, nil, err
+		}
+		patterns = payload.Selection.NamedPatterns(tasConfig)
+		seen := make(map[string]bool, len(tests))
+		for _, test := range tests {
+			if file := core.LocatorFile(test); !seen[file] {
+				seen[file] = true
+				patterns = append(patterns, utils.QuoteGlob(file))
+			}
+		}
+		return patterns, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown test selection strategy %s", strategy)
+	}
+}
+
+func quoteGlobs(files []string) []string {
+	patterns := make([]string, 0, len(files))
+	for _, file := range files {
+		patterns = append(patterns, utils.QuoteGlob(file))
+	}
+	return patterns
+}
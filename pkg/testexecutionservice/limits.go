@@ -0,0 +1,44 @@
+package testexecutionservice
+
+import (
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// limitTests returns the payload executing at most the maximum number of tests of the task and the locators
+// beyond it, the tests run first by the history are kept. The limit is only enforced if the tests are selected
+// by locators, as the number of tests matching the patterns is only known once they are run.
+func (tes *testExecutionService) limitTests(payload *core.Payload, locatorFile string, history *testHistory) (*core.Payload, string, []string, error) {
+	if tes.maxTests <= 0 {
+		return payload, locatorFile, nil, nil
+	}
+	if locatorFile == "" && payload.Locators == "" {
+		tes.logger.Warnf("the limit of %d tests is not enforced as the tests are not selected by locators", tes.maxTests)
+		return payload, locatorFile, nil, nil
+	}
+	locators, err := readLocators(payload, locatorFile)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if len(locators) <= tes.maxTests {
+		return payload, locatorFile, nil, nil
+	}
+	locators = history.order(locators)
+	tes.logger.Warnf("%d of the %d tests exceed the limit of %d tests of the task and are not run",
+		len(locators)-tes.maxTests, len(locators), tes.maxTests)
+	limited := *payload
+	limited.Locators = strings.Join(locators[:tes.maxTests], global.TestLocatorsDelimiter)
+	limited.LocatorAddress = ""
+	return &limited, "", locators[tes.maxTests:], nil
+}
+
+// limitExceededTests returns the results of the tests not run as they exceeded the maximum number of tests
+func limitExceededTests(locators []string) []core.TestPayload {
+	tests := make([]core.TestPayload, 0, len(locators))
+	for _, file := range groupByFile(locators) {
+		tests = append(tests, fileTests(file, core.TestLimitExceeded, 0)...)
+	}
+	return tests
+}
@@ -0,0 +1,43 @@
+package testexecutionservice
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitTests(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	tes := &testExecutionService{logger: logger}
+	payload := &core.Payload{Locators: "a.test.js#TAS#b.test.js##works#TAS#c.test.js"}
+	limited, _, excluded, err := tes.limitTests(payload, "", nil)
+	assert.Nil(t, err)
+	assert.Same(t, payload, limited)
+	assert.Empty(t, excluded)
+
+	tes.maxTests = 2
+	history := &testHistory{failed: prefixSet([]string{"c.test.js"}), known: prefixSet([]string{"a.test.js"})}
+	limited, locatorFile, excluded, err := tes.limitTests(payload, "", history)
+	assert.Nil(t, err)
+	assert.Empty(t, locatorFile)
+	// the recently failed and the new tests are kept
+	assert.Equal(t, "c.test.js#TAS#b.test.js##works", limited.Locators)
+	assert.Equal(t, []string{"a.test.js"}, excluded)
+
+	// the limit is not enforced without locators
+	limited, _, excluded, err = tes.limitTests(&core.Payload{}, "", nil)
+	assert.Nil(t, err)
+	assert.Empty(t, limited.Locators)
+	assert.Empty(t, excluded)
+}
+
+func TestLimitExceededTests(t *testing.T) {
+	tests := limitExceededTests([]string{"a.test.js##suite##works", "b.test.js"})
+	assert.Len(t, tests, 2)
+	assert.Equal(t, core.TestLimitExceeded, tests[0].Status)
+	assert.Equal(t, "works", tests[0].Name)
+	assert.Equal(t, "b.test.js", tests[1].FilePath)
+}
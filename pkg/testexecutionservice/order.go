@@ -0,0 +1,88 @@
+package testexecutionservice
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+)
+
+// testHistory is the history of the tests used for ordering the test locators
+type testHistory struct {
+	// failed contains the locators of the recently failed tests and their files and suites
+	failed map[string]bool
+	// known contains the locators of the tests having timings and their files and suites
+	known map[string]bool
+}
+
+// getHistory returns the history of the tests if the smart order is configured, else nil.
+// The tests are executed in the default order if the history can not be fetched.
+func (tes *testExecutionService) getHistory(ctx context.Context, payload *core.Payload, tasConfig *core.TASConfig) *testHistory {
+	if tasConfig.Order != core.OrderSmart {
+		return nil
+	}
+	timings, err := tes.timingStore.GetTimings(ctx, payload.RepoID, payload.BranchName)
+	if err != nil {
+		tes.logger.Warnf("failed to get test timings, tests are executed in the default order: %v", err)
+		return nil
+	}
+	failures, err := tes.timingStore.GetFailures(ctx, payload.RepoID, payload.BranchName)
+	if err != nil {
+		tes.logger.Warnf("failed to get test failures, tests are executed in the default order: %v", err)
+		return nil
+	}
+	known := make([]string, 0, len(timings))
+	for locator := range timings {
+		known = append(known, locator)
+	}
+	return &testHistory{failed: prefixSet(failures), known: prefixSet(known)}
+}
+
+// order returns the locators of the recently failed tests first, followed by the locators of the
+// newly added tests and then the remaining locators. The order is unchanged within each group.
+func (h *testHistory) order(locators []string) []string {
+	if h == nil || len(locators) < 2 {
+		return locators
+	}
+	rank := func(locator string) int {
+		switch {
+		case h.failed[locator]:
+			return 0
+		case !h.known[locator]:
+			return 1
+		default:
+			return 2
+		}
+	}
+	ordered := append([]string{}, locators...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+	return ordered
+}
+
+// orderFile rewrites the locator file with the ordered locators
+func (h *testHistory) orderFile(locatorFile string) error {
+	if h == nil {
+		return nil
+	}
+	locators, err := readLocators(&core.Payload{}, locatorFile)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(locatorFile, []byte(strings.Join(h.order(locators), global.TestLocatorsDelimiter)), 0644)
+}
+
+// prefixSet returns the set of the locators and their files and suites, so that a locator of a file
+// or a suite matches the locators of its tests
+func prefixSet(locators []string) map[string]bool {
+	set := make(map[string]bool, len(locators))
+	for _, locator := range locators {
+		parts := strings.Split(locator, locatorSeparator)
+		for i := range parts {
+			set[strings.Join(parts[:i+1], locatorSeparator)] = true
+		}
+	}
+	return set
+}
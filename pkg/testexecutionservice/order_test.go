@@ -0,0 +1,27 @@
+package testexecutionservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryOrder(t *testing.T) {
+	history := &testHistory{
+		failed: prefixSet([]string{"c.test.js##suite##broken"}),
+		known:  prefixSet([]string{"a.test.js##works", "c.test.js##suite##broken", "d.test.js##works"}),
+	}
+	ordered := history.order([]string{"a.test.js", "b.test.js", "c.test.js##suite", "d.test.js##works", "e.test.js##new"})
+	assert.Equal(t, []string{"c.test.js##suite", "b.test.js", "e.test.js##new", "a.test.js", "d.test.js##works"}, ordered)
+
+	var none *testHistory
+	assert.Equal(t, []string{"b", "a"}, none.order([]string{"b", "a"}))
+}
+
+func TestPrefixSet(t *testing.T) {
+	assert.Equal(t, map[string]bool{
+		"a.test.js":              true,
+		"a.test.js##suite":       true,
+		"a.test.js##suite##test": true,
+	}, prefixSet([]string{"a.test.js##suite##test"}))
+}
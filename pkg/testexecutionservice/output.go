@@ -0,0 +1,253 @@
+package testexecutionservice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// fileLogsDirName is the directory of the logs of the test files in the scratch directory
+	fileLogsDirName = "file-logs"
+	// maxRecordedOutput is the size of the output of a runner process recorded for the test files, the
+	// output beyond it is only in the log of the execution
+	maxRecordedOutput    = 64 << 20
+	maxConcurrentUploads = 8
+)
+
+// FileLogsDir returns the directory of the logs of the test files, the log of a file is stored at
+// <taskID>/<log name>/<file>.log
+func FileLogsDir() string {
+	return filepath.Join(global.ScratchDir, fileLogsDirName)
+}
+
+// outputLine is a line of the output of a runner process with the time it was written
+type outputLine struct {
+	time time.Time
+	text []byte
+}
+
+// outputRecorder records the lines of the output of a runner process, the lines are attributed to the
+// test files once the results of the process are received
+type outputRecorder struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines []outputLine
+	size  int
+	now   func() time.Time
+}
+
+func newOutputRecorder() *outputRecorder {
+	return &outputRecorder{now: time.Now}
+}
+
+func (r *outputRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(r.buf.Bytes(), '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		r.add(r.buf.Next(idx + 1))
+	}
+}
+
+// Close records the partial line if any
+func (r *outputRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.buf.Len() > 0 {
+		r.add(append(r.buf.Bytes(), '\n'))
+		r.buf.Reset()
+	}
+	return nil
+}
+
+func (r *outputRecorder) add(line []byte) {
+	if r.size+len(line) > maxRecordedOutput {
+		return
+	}
+	r.size += len(line)
+	r.lines = append(r.lines, outputLine{time: r.now(), text: append([]byte(nil), line...)})
+}
+
+func (r *outputRecorder) recorded() []outputLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lines
+}
+
+// fileOutputs is the output of each test file
+type fileOutputs struct {
+	mu      sync.Mutex
+	outputs map[string]*bytes.Buffer
+}
+
+func newFileOutputs() *fileOutputs {
+	return &fileOutputs{outputs: make(map[string]*bytes.Buffer)}
+}
+
+// add appends the lines to the output of file
+func (o *fileOutputs) add(file string, lines []outputLine) {
+	if file == "" || len(lines) == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	buf, ok := o.outputs[file]
+	if !ok {
+		buf = new(bytes.Buffer)
+		o.outputs[file] = buf
+	}
+	for _, line := range lines {
+		buf.Write(line.text)
+	}
+}
+
+// attribute appends the lines of a runner process to the files of its tests. A line is attributed to the file
+// whose tests were running when it was written, else to the next file which started, e.g. the output of the
+// hooks of the file, else to the last file. The lines are not attributed if the tests have no start time.
+func (o *fileOutputs) attribute(lines []outputLine, tests []core.TestPayload) {
+	intervals := fileIntervals(tests)
+	if len(intervals) == 0 {
+		return
+	}
+	byFile := make(map[string][]outputLine, len(intervals))
+	for _, line := range lines {
+		file := intervals[len(intervals)-1].file
+		for _, interval := range intervals {
+			if !line.time.After(interval.end) {
+				file = interval.file
+				break
+			}
+		}
+		byFile[file] = append(byFile[file], line)
+	}
+	for _, interval := range intervals {
+		o.add(interval.file, byFile[interval.file])
+	}
+}
+
+// fileInterval is the time during which the tests of a file were run
+type fileInterval struct {
+	file       string
+	start, end time.Time
+}
+
+// fileIntervals returns the intervals of the files of the tests sorted by their start
+func fileIntervals(tests []core.TestPayload) []*fileInterval {
+	byFile := make(map[string]*fileInterval)
+	intervals := make([]*fileInterval, 0)
+	for i := range tests {
+		test := &tests[i]
+		if test.FilePath == "" || test.StartTime.IsZero() {
+			continue
+		}
+		end := test.StartTime.Add(time.Duration(test.Duration) * time.Millisecond)
+		interval, ok := byFile[test.FilePath]
+		if !ok {
+			interval = &fileInterval{file: test.FilePath, start: test.StartTime, end: end}
+			byFile[test.FilePath] = interval
+			intervals = append(intervals, interval)
+			continue
+		}
+		if test.StartTime.Before(interval.start) {
+			interval.start = test.StartTime
+		}
+		if end.After(interval.end) {
+			interval.end = end
+		}
+	}
+	sort.SliceStable(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+	return intervals
+}
+
+// bucketTests returns the tests of the files of the locators of a bucket
+func bucketTests(tests []core.TestPayload, bucket []string) []core.TestPayload {
+	files := make(map[string]bool, len(bucket))
+	for _, locator := range bucket {
+		files[strings.SplitN(locator, locatorSeparator, 2)[0]] = true
+	}
+	selected := make([]core.TestPayload, 0)
+	for i := range tests {
+		if files[tests[i].FilePath] {
+			selected = append(selected, tests[i])
+		}
+	}
+	return selected
+}
+
+// storeFileLogs writes the output of each test file in the file logs directory and uploads it next to the
+// log of the execution. The logs which failed to be stored are not returned.
+func (tes *testExecutionService) storeFileLogs(ctx context.Context,
+	payload *core.Payload,
+	logName string,
+	outputs *fileOutputs) []core.FileLog {
+	outputs.mu.Lock()
+	defer outputs.mu.Unlock()
+	if len(outputs.outputs) == 0 {
+		return nil
+	}
+	files := make([]string, 0, len(outputs.outputs))
+	for file := range outputs.outputs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	logs := make([]core.FileLog, len(files))
+	var g errgroup.Group
+	sem := make(chan struct{}, maxConcurrentUploads)
+	for i, file := range files {
+		content := outputs.outputs[file].Bytes()
+		logPath := path.Join(payload.TaskID, logName, filepath.ToSlash(filepath.Clean(file))+".log")
+		if strings.HasPrefix(logPath, "../") || strings.Contains(logPath, "/../") {
+			tes.logger.Warnf("skipping the log of the test file %s outside of the repository", file)
+			continue
+		}
+		localPath := filepath.Join(FileLogsDir(), filepath.FromSlash(logPath))
+		if err := os.MkdirAll(filepath.Dir(localPath), global.DirectoryPermissions); err != nil {
+			tes.logger.Warnf("failed to create the log directory of the test file %s, error: %v", file, err)
+			continue
+		}
+		if err := ioutil.WriteFile(localPath, content, 0644); err != nil {
+			tes.logger.Warnf("failed to write the log of the test file %s, error: %v", file, err)
+			continue
+		}
+		logs[i] = core.FileLog{File: file, Path: logPath, Size: len(content)}
+		blobPath := fmt.Sprintf("%s/%s/%s", payload.OrgID, payload.BuildID, logPath)
+		i, file := i, file
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			if err := <-tes.execManager.StoreCommandLogs(ctx, blobPath, bytes.NewReader(content)); err != nil {
+				tes.logger.Warnf("failed to upload the log of the test file %s, error: %v", file, err)
+				return nil
+			}
+			logs[i].Blob = blobPath
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	stored := make([]core.FileLog, 0, len(logs))
+	for _, log := range logs {
+		if log.File != "" {
+			stored = append(stored, log)
+		}
+	}
+	return stored
+}
@@ -0,0 +1,60 @@
+package testexecutionservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputRecorder(t *testing.T) {
+	recorder := newOutputRecorder()
+	start := time.Now()
+	recorder.now = func() time.Time { return start }
+	_, err := recorder.Write([]byte("first\nsec"))
+	assert.Nil(t, err)
+	_, err = recorder.Write([]byte("ond\npartial"))
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Close())
+
+	lines := recorder.recorded()
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "first\n", string(lines[0].text))
+	assert.Equal(t, "second\n", string(lines[1].text))
+	assert.Equal(t, "partial\n", string(lines[2].text))
+	assert.Equal(t, start, lines[0].time)
+}
+
+func TestAttribute(t *testing.T) {
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	tests := []core.TestPayload{
+		{FilePath: "a.test.js", StartTime: at(1), Duration: 1000},
+		{FilePath: "a.test.js", StartTime: at(2), Duration: 1000},
+		{FilePath: "b.test.js", StartTime: at(5), Duration: 1000},
+		// the tests without start time are not attributed any output
+		{FilePath: "c.test.js"},
+	}
+	lines := []outputLine{
+		{time: at(0), text: []byte("loading a\n")},
+		{time: at(2), text: []byte("in a\n")},
+		{time: at(4), text: []byte("loading b\n")},
+		{time: at(5), text: []byte("in b\n")},
+		{time: at(8), text: []byte("summary\n")},
+	}
+	outputs := newFileOutputs()
+	outputs.attribute(lines, tests)
+	assert.Len(t, outputs.outputs, 2)
+	assert.Equal(t, "loading a\nin a\n", outputs.outputs["a.test.js"].String())
+	assert.Equal(t, "loading b\nin b\nsummary\n", outputs.outputs["b.test.js"].String())
+
+	outputs.add("a.test.js", []outputLine{{text: []byte("rerun\n")}})
+	assert.Equal(t, "loading a\nin a\nrerun\n", outputs.outputs["a.test.js"].String())
+}
+
+func TestBucketTests(t *testing.T) {
+	tests := []core.TestPayload{{FilePath: "a.test.js"}, {FilePath: "b.test.js"}, {FilePath: "a.test.js"}}
+	assert.Len(t, bucketTests(tests, []string{"a.test.js##suite##test"}), 2)
+	assert.Len(t, bucketTests(tests, []string{"c.test.js"}), 0)
+}
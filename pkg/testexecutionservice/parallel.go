@@ -0,0 +1,259 @@
+package testexecutionservice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/logstream"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/service/testtiming"
+	"github.com/LambdaTest/synapse/pkg/utils"
+)
+
+// getBuckets splits the test locators into at most workers buckets.
+// Locators of the locator file take precedence over the payload locators.
+// Buckets are balanced by the historical durations of the tests if available, else
+// contiguous chunks are used so that locators of the same file stay together.
+// The locators of each bucket are ordered using the history if any.
+func (tes *testExecutionService) getBuckets(ctx context.Context,
+	payload *core.Payload,
+	workers int,
+	locatorFile string,
+	history *testHistory) [][]string {
+	if workers < 2 {
+		return nil
+	}
+	list, err := readLocators(payload, locatorFile)
+	if err != nil {
+		tes.logger.Warnf("failed to read locator file %s, running tests serially: %v", locatorFile, err)
+		return nil
+	}
+	if len(list) < 2 {
+		return nil
+	}
+	timings, err := tes.timingStore.GetTimings(ctx, payload.RepoID, payload.BranchName)
+	if err != nil {
+		tes.logger.Warnf("failed to get test timings, splitting tests by count: %v", err)
+	}
+	var buckets [][]string
+	if len(timings) > 0 {
+		buckets = testtiming.SplitByDuration(list, workers, timings)
+	} else {
+		buckets = splitBuckets(list, workers)
+	}
+	for i := range buckets {
+		buckets[i] = history.order(buckets[i])
+	}
+	return buckets
+}
+
+// readLocators returns the test locators of the locator file or else of the payload
+func readLocators(payload *core.Payload, locatorFile string) ([]string, error) {
+	locators := payload.Locators
+	if locatorFile != "" {
+		raw, err := ioutil.ReadFile(locatorFile)
+		if err != nil {
+			return nil, err
+		}
+		locators = string(raw)
+	}
+	list := make([]string, 0)
+	for _, line := range strings.Split(locators, "\n") {
+		for _, locator := range strings.Split(line, global.TestLocatorsDelimiter) {
+			if locator = strings.TrimSpace(locator); locator != "" {
+				list = append(list, locator)
+			}
+		}
+	}
+	return list, nil
+}
+
+func splitBuckets(list []string, k int) [][]string {
+	if k > len(list) {
+		k = len(list)
+	}
+	buckets := make([][]string, 0, k)
+	for i := 0; i < k; i++ {
+		start, end := i*len(list)/k, (i+1)*len(list)/k
+		buckets = append(buckets, list[start:end])
+	}
+	return buckets
+}
+
+// runParallel executes each bucket in a separate runner process with its own locator file
+// and temp directory. The results of all the workers are merged by teststats.
+func (tes *testExecutionService) runParallel(ctx context.Context,
+	tasConfig *core.TASConfig,
+	args, envVars []string,
+	buckets [][]string,
+	collectCoverage bool,
+	azureWriter io.Writer,
+	secretData map[string]string,
+	outputs *fileOutputs) (core.ExecutionResult, error) {
+	tes.logger.Infof("executing %d test locators using %d workers", countLocators(buckets), len(buckets))
+	workDir, err := ioutil.TempDir(global.ScratchDir, "tas-workers-")
+	if err != nil {
+		return core.ExecutionResult{}, err
+	}
+	defer os.RemoveAll(workDir)
+	// started workers are killed if any worker fails to start
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// azure log stream is shared, lines of the workers are written atomically
+	var mu sync.Mutex
+	// the workers are independent, a failed worker does not stop the others so that the results of all
+	// the buckets are reported
+	var wg sync.WaitGroup
+	workerErrs := make([]error, len(buckets))
+	pids := make([]int32, 0, len(buckets))
+	recorders := make([]*outputRecorder, 0, len(buckets))
+	for i, bucket := range buckets {
+		workerDir := filepath.Join(workDir, fmt.Sprintf("worker-%d", i))
+		if err := os.MkdirAll(workerDir, global.DirectoryPermissions); err != nil {
+			return core.ExecutionResult{}, err
+		}
+		locatorPath := filepath.Join(workerDir, locatorFile)
+		if err := ioutil.WriteFile(locatorPath, []byte(strings.Join(bucket, global.TestLocatorsDelimiter)), 0644); err != nil {
+			return core.ExecutionResult{}, err
+		}
+
+		workerEnv := append(append([]string{}, envVars...),
+			fmt.Sprintf("TAS_WORKER_ID=%d", i),
+			fmt.Sprintf("TAS_WORKER_COUNT=%d", len(buckets)),
+			"TMPDIR="+workerDir)
+		cmd := tes.buildCommand(ctx, tasConfig, append(append([]string{}, args...), "--locator-file", locatorPath), workerEnv, collectCoverage)
+
+		logWriter := lumber.NewWriter(tes.logger)
+		maskWriter := logstream.NewMasker(io.MultiWriter(logWriter, azureWriter), secretData)
+		workerWriter := newLineWriter(&mu, maskWriter, fmt.Sprintf("[worker %d] ", i))
+		// the output of the worker is recorded without the prefix
+		recorder := newOutputRecorder()
+		recorders = append(recorders, recorder)
+		outputWriter := io.MultiWriter(workerWriter, logstream.NewMasker(recorder, secretData))
+		cmd.Stdout = outputWriter
+		cmd.Stderr = outputWriter
+
+		tes.logger.Debugf("Executing test execution command for worker %d: %s", i, cmd.String())
+		startTime := time.Now()
+		if err := cmd.Start(); err != nil {
+			tes.execManager.RecordCommand(ctx, core.Execution, cmd, startTime, secretData, err)
+			tes.logger.Errorf("failed to execute test %s %v", cmd.String(), err)
+			cancel()
+			wg.Wait()
+			return core.ExecutionResult{}, err
+		}
+		pids = append(pids, int32(cmd.Process.Pid))
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			defer logWriter.Close()
+			defer workerWriter.Close()
+			defer recorder.Close()
+			err := utils.WaitProcessGroup(ctx, cmd)
+			tes.execManager.RecordCommand(ctx, core.Execution, cmd, startTime, secretData, err)
+			if err != nil {
+				tes.logger.Errorf("Error in executing worker %d: %+v", worker, err)
+				workerErrs[worker] = err
+			}
+		}(i)
+	}
+
+	if err := tes.ts.CaptureWorkerStats(pids); err != nil {
+		tes.logger.Errorf("failed to capture stats for workers %v, error: %v", pids, err)
+		wg.Wait()
+		return core.ExecutionResult{}, err
+	}
+	wg.Wait()
+	// the result is always received, else it is returned by the next run
+	result := <-tes.ts.ExecutionResultOutputChannel
+	if err := workersError(workerErrs); err != nil {
+		return core.ExecutionResult{}, err
+	}
+	// the output of each worker is attributed to the files of its bucket
+	for i, recorder := range recorders {
+		outputs.attribute(recorder.recorded(), bucketTests(result.TestPayload, buckets[i]))
+	}
+	return result, nil
+}
+
+// workersError returns the error of the failed workers if any, the first error is wrapped
+func workersError(workerErrs []error) error {
+	var first error
+	failed := make([]string, 0)
+	for i, err := range workerErrs {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		failed = append(failed, strconv.Itoa(i))
+	}
+	if first == nil {
+		return nil
+	}
+	return fmt.Errorf("workers %s of %d failed: %w", strings.Join(failed, ", "), len(workerErrs), first)
+}
+
+func countLocators(buckets [][]string) int {
+	n := 0
+	for _, bucket := range buckets {
+		n += len(bucket)
+	}
+	return n
+}
+
+// lineWriter prefixes each line and writes it to the shared writer while holding the lock,
+// so that lines of concurrent processes are not interleaved.
+type lineWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newLineWriter(mu *sync.Mutex, w io.Writer, prefix string) *lineWriter {
+	return &lineWriter{mu: mu, w: w, prefix: prefix}
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(lw.buf.Bytes(), '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		if err := lw.writeLine(lw.buf.Next(idx + 1)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Close flushes the partial line if any
+func (lw *lineWriter) Close() error {
+	if lw.buf.Len() == 0 {
+		return nil
+	}
+	line := append(lw.buf.Bytes(), '\n')
+	lw.buf.Reset()
+	return lw.writeLine(line)
+}
+
+func (lw *lineWriter) writeLine(line []byte) error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	_, err := lw.w.Write(append([]byte(lw.prefix), line...))
+	return err
+}
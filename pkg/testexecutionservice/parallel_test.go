@@ -0,0 +1,36 @@
+package testexecutionservice
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBuckets(t *testing.T) {
+	list := []string{"a", "b", "c", "d", "e"}
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d", "e"}}, splitBuckets(list, 2))
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, splitBuckets(list, 8))
+	assert.Empty(t, splitBuckets(nil, 4))
+}
+
+func TestWorkersError(t *testing.T) {
+	assert.Nil(t, workersError(make([]error, 3)))
+	errExit := errors.New("exit status 1")
+	err := workersError([]error{nil, errExit, errors.New("exit status 2")})
+	assert.EqualError(t, err, "workers 1, 2 of 3 failed: exit status 1")
+	assert.True(t, errors.Is(err, errExit))
+}
+
+func TestLineWriter(t *testing.T) {
+	var mu sync.Mutex
+	buf := &bytes.Buffer{}
+	w := newLineWriter(&mu, buf, "[worker 1] ")
+	_, _ = w.Write([]byte("PASS a.test"))
+	_, _ = w.Write([]byte(".js\nFAIL b"))
+	assert.Equal(t, "[worker 1] PASS a.test.js\n", buf.String())
+	assert.Nil(t, w.Close())
+	assert.Equal(t, "[worker 1] PASS a.test.js\n[worker 1] FAIL b\n", buf.String())
+}
@@ -0,0 +1,56 @@
+package testexecutionservice
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedTests(t *testing.T) {
+	pkg := &core.TASConfig{
+		Dir:       "packages/api",
+		Premerge:  &core.Merge{Patterns: []string{"packages/api/**/*.test.js"}},
+		Postmerge: &core.Merge{Patterns: []string{"packages/api/**/*.spec.js"}},
+	}
+	payload := &core.Payload{EventType: core.EventPullRequest, Selection: &core.TestSelection{
+		Strategy: core.SelectionNamed,
+		Tests:    []string{"packages/api/src/a.test.js##suite##fails", "packages/web/b.test.js##renders"},
+	}}
+
+	named, patterns, selected, err := namedTests(payload, pkg)
+	assert.NoError(t, err)
+	assert.True(t, selected)
+	assert.Equal(t, "packages/api/src/a.test.js##suite##fails", named.Locators)
+	assert.Equal(t, []string{"packages/api/**/*.test.js"}, patterns)
+	assert.Empty(t, payload.Locators, "the payload of the other packages is unchanged")
+
+	// the tests of the other packages are not run
+	payload.EventType = core.EventPush
+	_, _, selected, err = namedTests(payload, pkg)
+	assert.NoError(t, err)
+	assert.False(t, selected)
+
+	payload.Selection = &core.TestSelection{Strategy: core.SelectionNamed, Patterns: []string{"packages/api/src/**/*.spec.js", "packages/web/**/*.spec.js"}}
+	named, patterns, selected, err = namedTests(payload, pkg)
+	assert.NoError(t, err)
+	assert.True(t, selected)
+	assert.Empty(t, named.Locators)
+	assert.Equal(t, []string{"packages/api/src/**/*.spec.js"}, patterns)
+}
+
+func TestSelectionStrategy(t *testing.T) {
+	tasConfig := &core.TASConfig{SmartRun: true}
+	payload := &core.Payload{EventType: core.EventPush}
+	assert.Equal(t, core.SelectionImpacted, payload.SelectionStrategy(tasConfig))
+	payload.EventType = core.EventCron
+	assert.Equal(t, core.SelectionFull, payload.SelectionStrategy(tasConfig))
+	payload.Selection = &core.TestSelection{Strategy: core.SelectionChanged}
+	assert.Equal(t, core.SelectionChanged, payload.SelectionStrategy(tasConfig))
+
+	assert.NoError(t, payload.Selection.Validate())
+	assert.Error(t, (&core.TestSelection{Strategy: "flaky"}).Validate())
+	assert.Error(t, (&core.TestSelection{Strategy: core.SelectionNamed}).Validate())
+	assert.Error(t, (&core.TestSelection{Strategy: core.SelectionNamed, Tests: []string{"a.test.js"}, Patterns: []string{"*.test.js"}}).Validate())
+	assert.Error(t, (&core.TestSelection{Strategy: core.SelectionNamed, Patterns: []string{"src/[a.test.js"}}).Validate())
+}
@@ -3,19 +3,26 @@ package testexecutionservice
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/LambdaTest/synapse/config"
 	"github.com/LambdaTest/synapse/pkg/core"
 	"github.com/LambdaTest/synapse/pkg/global"
 	"github.com/LambdaTest/synapse/pkg/logstream"
 	"github.com/LambdaTest/synapse/pkg/lumber"
 	"github.com/LambdaTest/synapse/pkg/service/teststats"
+	"github.com/LambdaTest/synapse/pkg/tracing"
+	"github.com/LambdaTest/synapse/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const locatorFile = "locators"
@@ -25,42 +32,66 @@ type testExecutionService struct {
 	azureClient core.AzureClient
 	ts          *teststats.ProcStats
 	execManager core.ExecutionManager
+	timingStore core.TestTimingStore
+	// maxTests is the maximum number of tests executed by a task, unlimited if 0
+	maxTests int
 }
 
 // NewTestExecutionService creates and returns a new TestExecutionService instance
-func NewTestExecutionService(execManager core.ExecutionManager,
+func NewTestExecutionService(cfg *config.NucleusConfig,
+	execManager core.ExecutionManager,
 	azureClient core.AzureClient,
 	ts *teststats.ProcStats,
+	timingStore core.TestTimingStore,
 	logger lumber.Logger) core.TestExecutionService {
 	return &testExecutionService{execManager: execManager,
 		azureClient: azureClient,
 		ts:          ts,
+		timingStore: timingStore,
+		maxTests:    cfg.Limits.MaxTests,
 		logger:      logger}
 }
 
-// Run executes the test files
+// Run executes the test files. If parallelism is configured, the test locators are
+// split into buckets which are executed concurrently in separate runner processes.
+// With the smart order the recently failed and the newly added tests are executed first.
 func (tes *testExecutionService) Run(ctx context.Context,
 	tasConfig *core.TASConfig,
 	payload *core.Payload,
 	coverageDir string,
-	secretData map[string]string) (*core.ExecutionResult, error) {
+	secretData map[string]string) (result *core.ExecutionResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "testexecutionservice.Run", attribute.String("tas.framework", tasConfig.Framework))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	strategy := payload.SelectionStrategy(tasConfig)
+	span.SetAttributes(attribute.String("tas.selection", string(strategy)))
+	target := tasConfig.TestPatterns(payload.EventType)
+	// the other strategies execute the locators of the tests selected by the discovery
+	if strategy == core.SelectionNamed && payload.Locators == "" && payload.LocatorAddress == "" {
+		var selected bool
+		if payload, target, selected, err = namedTests(payload, tasConfig); err != nil {
+			tes.logger.Errorf("failed to select the named tests, error: %v", err)
+			return nil, err
+		}
+		if !selected {
+			tes.logger.Infof("No named test of %s, skipping test execution", tasConfig.File)
+			return emptyResult(payload), nil
+		}
+	}
 
 	azureReader, azureWriter := io.Pipe()
 	defer azureWriter.Close()
-	blobPath := fmt.Sprintf("%s/%s/%s/%s.log", payload.OrgID, payload.BuildID, payload.TaskID, core.Execution)
+	logName := core.LogName(core.Execution, tasConfig.Dir)
+	if len(payload.Matrix) > 0 {
+		logName += "-" + payload.Matrix.Name()
+	}
+	blobPath := fmt.Sprintf("%s/%s/%s/%s.log", payload.OrgID, payload.BuildID, payload.TaskID, logName)
 	errChan := tes.execManager.StoreCommandLogs(ctx, blobPath, azureReader)
-	logWriter := lumber.NewWriter(tes.logger)
-	defer logWriter.Close()
-	multiWriter := io.MultiWriter(logWriter, azureWriter)
-	maskWriter := logstream.NewMasker(multiWriter, secretData)
 
-	var target []string
 	var envMap map[string]string
-	if payload.EventType == core.EventPullRequest {
-		target = tasConfig.Premerge.Patterns
+	if payload.EventType.IsPremerge() {
 		envMap = tasConfig.Premerge.EnvMap
 	} else {
-		target = tasConfig.Postmerge.Patterns
 		envMap = tasConfig.Postmerge.EnvMap
 	}
 	var args []string
@@ -72,120 +103,254 @@ func (tes *testExecutionService) Run(ctx context.Context,
 		args = append(args, "--pattern", pattern)
 	}
 
+	var locatorFile string
 	if payload.LocatorAddress != "" {
-		locatorFile, err := tes.GetLocatorsFile(ctx, payload.LocatorAddress)
+		locatorFile, err = tes.GetLocatorsFile(ctx, payload.LocatorAddress)
 		if err != nil {
 			tes.logger.Errorf("failed to get locator file, error: %v", err)
 			return nil, err
 		}
-		args = append(args, "--locator-file", locatorFile)
-	}
-	// use locators only if there is no locator address
-	if payload.Locators != "" && payload.LocatorAddress == "" {
-		locators := strings.Split(payload.Locators, global.TestLocatorsDelimiter)
-		for _, locator := range locators {
-			if locator != "" {
-				args = append(args, "--locator", locator)
-			}
-		}
 	}
 	collectCoverage := payload.CollectCoverage
-	testResults := make([]core.TestPayload, 0)
-	testSuiteResults := make([]core.TestSuitePayload, 0)
 
-	commandArgs := args
-	envVars, err := tes.execManager.GetEnvVariables(envMap, secretData)
+	// the env of the matrix combination overrides the env of the configuration
+	envMaps := append(tasConfig.EnvScopes(core.EnvStageExecution), envMap, payload.Matrix.Env())
+	envVars, err := tes.execManager.GetEnvVariables(secretData, envMaps...)
 	if err != nil {
 		tes.logger.Errorf("failed to parsed env variables, error: %v", err)
 		return nil, err
 	}
-	var cmd *exec.Cmd
-	if tasConfig.Framework == "jasmine" || tasConfig.Framework == "mocha" {
-		if collectCoverage {
-			cmd = exec.CommandContext(ctx, "nyc", commandArgs...)
-		} else {
-			cmd = exec.CommandContext(ctx, commandArgs[0], commandArgs[1:]...)
-		}
+	if collectCoverage && tasConfig.Framework != "jasmine" && tasConfig.Framework != "mocha" {
+		envVars = append(envVars, "TAS_COLLECT_COVERAGE=true")
+	}
+	envVars = append(envVars, tracing.Environ(ctx)...)
+	if tasConfig.Timeouts.Test > 0 {
+		envVars = append(envVars, fmt.Sprintf("TAS_TEST_TIMEOUT=%d", tasConfig.Timeouts.Test*1000))
+	}
+	if tasConfig.FailFast > 0 {
+		envVars = append(envVars, fmt.Sprintf("TAS_FAIL_FAST=%d", tasConfig.FailFast))
+	}
+
+	history := tes.getHistory(ctx, payload, tasConfig)
+	var excluded []string
+	if payload, locatorFile, excluded, err = tes.limitTests(payload, locatorFile, history); err != nil {
+		tes.logger.Errorf("failed to limit the number of tests, error: %v", err)
+		return nil, err
+	}
+	outputs := newFileOutputs()
+	var execResultsWithStats core.ExecutionResult
+	if files := tes.getFiles(payload, tasConfig, locatorFile, history); len(files) > 0 {
+		execResultsWithStats, err = tes.runFiles(ctx, tasConfig, args, envVars, files, collectCoverage, azureWriter, secretData, outputs)
+	} else if buckets := tes.getBuckets(ctx, payload, tasConfig.Workers, locatorFile, history); len(buckets) > 1 {
+		execResultsWithStats, err = tes.runParallel(ctx, tasConfig, args, envVars, buckets, collectCoverage, azureWriter, secretData, outputs)
 	} else {
-		cmd = exec.CommandContext(ctx, commandArgs[0], commandArgs[1:]...)
-		if collectCoverage {
-			envVars = append(envVars, "TAS_COLLECT_COVERAGE=true")
+		if locatorFile != "" {
+			if err := history.orderFile(locatorFile); err != nil {
+				tes.logger.Warnf("failed to order the locator file %s, tests are executed in the default order: %v", locatorFile, err)
+			}
+			args = append(args, "--locator-file", locatorFile)
 		}
+		// use locators only if there is no locator address
+		if payload.Locators != "" && payload.LocatorAddress == "" {
+			args = append(args, locatorArgs(history.order(strings.Split(payload.Locators, global.TestLocatorsDelimiter)))...)
+		}
+		recorder := newOutputRecorder()
+		execResultsWithStats, err = tes.runSerial(ctx, tasConfig, args, envVars, collectCoverage, azureWriter, secretData, recorder)
+		outputs.attribute(recorder.recorded(), execResultsWithStats.TestPayload)
 	}
-	cmd.Dir = global.RepoDir
-	cmd.Env = envVars
+	if err != nil {
+		return nil, err
+	}
+	testResults := execResultsWithStats.TestPayload
+	markTimedOut(testResults, tasConfig.Timeouts.Test)
+	var limitsExceeded []string
+	if len(excluded) > 0 {
+		testResults = append(testResults, limitExceededTests(excluded)...)
+		limitsExceeded = append(limitsExceeded, core.LimitMaxTests)
+	}
+	testSuiteResults := execResultsWithStats.TestSuitePayload
+	if testResults == nil {
+		testResults = make([]core.TestPayload, 0)
+	}
+	if testSuiteResults == nil {
+		testSuiteResults = make([]core.TestSuitePayload, 0)
+	}
+	tagMatrix(payload.Matrix, testResults, testSuiteResults)
+	span.SetAttributes(attribute.Int("tas.test_count", len(testResults)))
+	if err := tes.timingStore.StoreTimings(ctx, payload.RepoID, testResults); err != nil {
+		tes.logger.Warnf("failed to store test timings, error: %v", err)
+	}
+	fileLogs := tes.storeFileLogs(ctx, payload, logName, outputs)
+
+	if collectCoverage {
+		if err := tes.writeCoverageManifest(tasConfig, coverageDir); err != nil {
+			tes.logger.Errorf("failed to write coverage threshold and upload in manifest file %v", err)
+			return nil, err
+		}
+	}
+	azureWriter.Close()
+	if uploadErr := <-errChan; uploadErr != nil {
+		tes.logger.Errorf("failed to upload logs for test execution, error: %v", uploadErr)
+		return nil, uploadErr
+	}
+	return &core.ExecutionResult{
+		OrgID:             payload.OrgID,
+		RepoID:            payload.RepoID,
+		BuildID:           payload.BuildID,
+		TaskID:            payload.TaskID,
+		CommitID:          payload.TargetCommit,
+		TestPayload:       testResults,
+		TestSuitePayload:  testSuiteResults,
+		ResourceUsage:     execResultsWithStats.ResourceUsage,
+		FileResourceUsage: execResultsWithStats.FileResourceUsage,
+		FileLogs:          fileLogs,
+		LimitsExceeded:    limitsExceeded,
+	}, nil
+}
+
+// namedTests returns the payload executing the named tests of the configuration as locators and the test patterns,
+// which are the named patterns if the payload names patterns. It returns false if no test of the configuration
+// is named.
+func namedTests(payload *core.Payload, tasConfig *core.TASConfig) (*core.Payload, []string, bool, error) {
+	if patterns := payload.Selection.NamedPatterns(tasConfig); len(patterns) > 0 {
+		return payload, patterns, true, nil
+	}
+	tests, err := payload.Selection.NamedTests(tasConfig, payload.EventType)
+	if err != nil || len(tests) == 0 {
+		return payload, nil, false, err
+	}
+	named := *payload
+	named.Locators = strings.Join(tests, global.TestLocatorsDelimiter)
+	return &named, tasConfig.TestPatterns(payload.EventType), true, nil
+}
+
+func emptyResult(payload *core.Payload) *core.ExecutionResult {
+	return &core.ExecutionResult{
+		OrgID:            payload.OrgID,
+		RepoID:           payload.RepoID,
+		BuildID:          payload.BuildID,
+		TaskID:           payload.TaskID,
+		CommitID:         payload.TargetCommit,
+		TestPayload:      make([]core.TestPayload, 0),
+		TestSuitePayload: make([]core.TestSuitePayload, 0),
+	}
+}
+
+// tagMatrix sets the matrix combination of the results, so that the results of each combination can be told apart
+func tagMatrix(matrix core.MatrixCombination, tests []core.TestPayload, suites []core.TestSuitePayload) {
+	if len(matrix) == 0 {
+		return
+	}
+	for i := range tests {
+		tests[i].Matrix = matrix
+	}
+	for i := range suites {
+		suites[i].Matrix = matrix
+	}
+}
+
+// runSerial executes all the tests in a single runner process, the output of the process is recorded by recorder
+func (tes *testExecutionService) runSerial(ctx context.Context,
+	tasConfig *core.TASConfig,
+	args, envVars []string,
+	collectCoverage bool,
+	azureWriter io.Writer,
+	secretData map[string]string,
+	recorder *outputRecorder) (core.ExecutionResult, error) {
+	logWriter := lumber.NewWriter(tes.logger)
+	defer logWriter.Close()
+	defer recorder.Close()
+	multiWriter := io.MultiWriter(logWriter, azureWriter, recorder)
+	maskWriter := logstream.NewMasker(multiWriter, secretData)
+
+	cmd := tes.buildCommand(ctx, tasConfig, args, envVars, collectCoverage)
 	cmd.Stdout = maskWriter
 	cmd.Stderr = maskWriter
 
 	tes.logger.Debugf("Executing test execution command: %s", cmd.String())
+	startTime := time.Now()
 	if err := cmd.Start(); err != nil {
+		tes.execManager.RecordCommand(ctx, core.Execution, cmd, startTime, secretData, err)
 		tes.logger.Errorf("failed to execute test %s %v", cmd.String(), err)
-		return nil, err
+		return core.ExecutionResult{}, err
 	}
 	pid := int32(cmd.Process.Pid)
 	tes.logger.Debugf("execution command started with pid %d", pid)
 
 	if err := tes.ts.CaptureTestStats(pid); err != nil {
 		tes.logger.Errorf("failed to find process for command %s with pid %d %v", cmd.String(), pid, err)
-		return nil, err
+		return core.ExecutionResult{}, err
 	}
-	if err := cmd.Wait(); err != nil {
+	// the result is always received, else it is returned by the next run
+	err := utils.WaitProcessGroup(ctx, cmd)
+	tes.execManager.RecordCommand(ctx, core.Execution, cmd, startTime, secretData, err)
+	result := <-tes.ts.ExecutionResultOutputChannel
+	if err != nil {
 		tes.logger.Errorf("Error in executing []: %+v\n", err)
-		return nil, err
+		return core.ExecutionResult{}, err
 	}
-	execResultsWithStats := <-tes.ts.ExecutionResultOutputChannel
-	testResults = append(testResults, execResultsWithStats.TestPayload...)
-	testSuiteResults = append(testSuiteResults, execResultsWithStats.TestSuitePayload...)
-
-	// FIXME:  commenting this out as we will need to rework on coverage logic after test parallelization
-	// if collectCoverage {
-	// 	if err := tes.createCoverageManifest(tasConfig, coverageDir, removedfiles, executeAll); err != nil {
-	// 		tes.logger.Errorf("failed to create manifest file %v", err)
-	// 		return nil, err
-	// 	}
-	// }
-	azureWriter.Close()
-	if uploadErr := <-errChan; uploadErr != nil {
-		tes.logger.Errorf("failed to upload logs for test execution, error: %v", uploadErr)
-		return nil, uploadErr
+	return result, nil
+}
+
+func (tes *testExecutionService) buildCommand(ctx context.Context,
+	tasConfig *core.TASConfig,
+	args, envVars []string,
+	collectCoverage bool) *exec.Cmd {
+	var cmd *exec.Cmd
+	if (tasConfig.Framework == "jasmine" || tasConfig.Framework == "mocha") && collectCoverage {
+		cmd = exec.CommandContext(ctx, "nyc", args...)
+	} else {
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
 	}
-	return &core.ExecutionResult{
-		OrgID:            payload.OrgID,
-		RepoID:           payload.RepoID,
-		BuildID:          payload.BuildID,
-		TaskID:           payload.TaskID,
-		CommitID:         payload.TargetCommit,
-		TestPayload:      testResults,
-		TestSuitePayload: testSuiteResults,
-	}, nil
+	cmd.Dir = global.RepoDir
+	cmd.Env = envVars
+	utils.SetProcessGroup(cmd)
+	return cmd
 }
 
-// func (tes *testExecutionService) createCoverageManifest(tasConfig *core.TASConfig, coverageDirectory string, removedFiles []string, executeAll bool) error {
-// 	manifestFile := core.CoverageMainfest{
-// 		Removedfiles:     removedFiles,
-// 		AllFilesExecuted: executeAll,
-// 	}
-
-// 	coverageThreshold := core.CoverageThreshold{
-// 		Branches:   tasConfig.CoverageThreshold.Branches,
-// 		Lines:      tasConfig.CoverageThreshold.Lines,
-// 		Functions:  tasConfig.CoverageThreshold.Functions,
-// 		Statements: tasConfig.CoverageThreshold.Statements,
-// 		PerFile:    tasConfig.CoverageThreshold.PerFile,
-// 	}
-
-// 	if coverageThreshold != (core.CoverageThreshold{}) {
-// 		manifestFile.CoverageThreshold = &coverageThreshold
-// 	}
-
-// 	manifestPath := filepath.Join(coverageDirectory, global.CoverageManifestFileName)
-
-// 	rawBytes, err := json.Marshal(manifestFile)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	return ioutil.WriteFile(manifestPath, rawBytes, 0644)
-// }
+func locatorArgs(locators []string) []string {
+	args := make([]string, 0, 2*len(locators))
+	for _, locator := range locators {
+		if locator != "" {
+			args = append(args, "--locator", locator)
+		}
+	}
+	return args
+}
+
+// writeCoverageManifest adds the coverage threshold, upload and comparison in the manifest file of the coverage
+// directory, which are used for gating, uploading and comparing the coverage in coverage mode.
+func (tes *testExecutionService) writeCoverageManifest(tasConfig *core.TASConfig, coverageDirectory string) error {
+	threshold := tasConfig.CoverageThreshold != nil && *tasConfig.CoverageThreshold != (core.CoverageThreshold{})
+	if coverageDirectory == "" || (!threshold && tasConfig.CoverageUpload == nil && !tasConfig.CoverageCompare) {
+		return nil
+	}
+	manifestPath := filepath.Join(coverageDirectory, global.CoverageManifestFileName)
+	manifestFile := core.CoverageMainfest{}
+	// retain removed files and execution info if manifest already exists
+	if rawBytes, err := ioutil.ReadFile(manifestPath); err == nil {
+		if err := json.Unmarshal(rawBytes, &manifestFile); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if threshold {
+		coverageThreshold := *tasConfig.CoverageThreshold
+		manifestFile.CoverageThreshold = &coverageThreshold
+	}
+	manifestFile.CoverageUpload = tasConfig.CoverageUpload
+	manifestFile.CoverageCompare = tasConfig.CoverageCompare
+
+	rawBytes, err := json.Marshal(manifestFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(coverageDirectory, global.DirectoryPermissions); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestPath, rawBytes, 0644)
+}
 
 func (tes *testExecutionService) GetLocatorsFile(ctx context.Context, locatorAddress string) (string, error) {
 	u, err := url.Parse(locatorAddress)
@@ -206,7 +371,7 @@ func (tes *testExecutionService) GetLocatorsFile(ctx context.Context, locatorAdd
 	}
 	defer resp.Close()
 
-	locatorFilePath := filepath.Join(os.TempDir(), locatorFile)
+	locatorFilePath := filepath.Join(global.ScratchDir, locatorFile)
 	out, err := os.Create(locatorFilePath)
 	if err != nil {
 		return "", err
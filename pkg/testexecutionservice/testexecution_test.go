@@ -0,0 +1,34 @@
+package testexecutionservice
+
+import (
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagMatrix(t *testing.T) {
+	combination := core.MatrixCombination{core.MatrixNodeVersion: "16.13.0", "TZ": "UTC"}
+	tests := []struct {
+		name   string
+		matrix core.MatrixCombination
+		want   core.MatrixCombination
+	}{
+		{"without matrix", nil, nil},
+		{"empty combination", core.MatrixCombination{}, nil},
+		{"combination", combination, combination},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testResults := []core.TestPayload{{TestID: "1"}, {TestID: "2"}}
+			suiteResults := []core.TestSuitePayload{{SuiteID: "1"}}
+			tagMatrix(tt.matrix, testResults, suiteResults)
+			for _, test := range testResults {
+				assert.Equal(t, tt.want, test.Matrix)
+			}
+			assert.Equal(t, tt.want, suiteResults[0].Matrix)
+		})
+	}
+	// no results to tag
+	tagMatrix(combination, nil, nil)
+}
@@ -0,0 +1,160 @@
+package testexecutionservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// locatorSeparator separates the file of a test locator from its suites and test name
+const locatorSeparator = "##"
+
+// testFile is a test file with the locators of its tests which are run
+type testFile struct {
+	path     string
+	locators []string
+}
+
+// getFiles returns the test files of the locators if the file timeout is configured, the files are
+// executed one after another so that only the runner process of a timed out file is killed.
+// The files are executed in the order of their first locator after ordering using the history.
+func (tes *testExecutionService) getFiles(payload *core.Payload, tasConfig *core.TASConfig, locatorFile string, history *testHistory) []testFile {
+	if tasConfig.Timeouts.File <= 0 {
+		return nil
+	}
+	locators, err := readLocators(payload, locatorFile)
+	if err != nil {
+		tes.logger.Warnf("failed to read locator file %s, file timeout is not enforced: %v", locatorFile, err)
+		return nil
+	}
+	if len(locators) == 0 {
+		tes.logger.Warnf("no test locators found, file timeout is not enforced")
+		return nil
+	}
+	if tasConfig.Workers > 1 {
+		tes.logger.Warnf("workers are ignored as the test files are executed separately with the file timeout")
+	}
+	return groupByFile(history.order(locators))
+}
+
+// groupByFile groups the locators by their file, keeping the order of the files
+func groupByFile(locators []string) []testFile {
+	files := make([]testFile, 0)
+	index := make(map[string]int)
+	for _, locator := range locators {
+		path := strings.SplitN(locator, locatorSeparator, 2)[0]
+		i, ok := index[path]
+		if !ok {
+			i = len(files)
+			index[path] = i
+			files = append(files, testFile{path: path})
+		}
+		files[i].locators = append(files[i].locators, locator)
+	}
+	return files
+}
+
+// runFiles executes each test file in its own runner process which is killed once the file timeout
+// is exceeded. The tests of a killed file are reported as timed out and the remaining files are executed.
+// Once the fail fast limit of failed tests is reached, the tests of the remaining files are reported as skipped.
+func (tes *testExecutionService) runFiles(ctx context.Context,
+	tasConfig *core.TASConfig,
+	args, envVars []string,
+	files []testFile,
+	collectCoverage bool,
+	azureWriter io.Writer,
+	secretData map[string]string,
+	outputs *fileOutputs) (core.ExecutionResult, error) {
+	timeout := time.Duration(tasConfig.Timeouts.File) * time.Second
+	tes.logger.Infof("executing %d test files with a timeout of %s", len(files), timeout)
+
+	merged := core.ExecutionResult{}
+	failed := 0
+	for i, file := range files {
+		if tasConfig.FailFast > 0 && failed >= tasConfig.FailFast {
+			tes.logger.Warnf("fail fast limit of %d failed tests reached, skipping %d remaining test files", tasConfig.FailFast, len(files)-i)
+			for _, skipped := range files[i:] {
+				merged.TestPayload = append(merged.TestPayload, fileTests(skipped, core.TestSkipped, 0)...)
+			}
+			break
+		}
+		fileArgs := append(append([]string{}, args...), locatorArgs(file.locators)...)
+		fileCtx, cancel := context.WithTimeout(ctx, timeout)
+		recorder := newOutputRecorder()
+		result, err := tes.runSerial(fileCtx, tasConfig, fileArgs, envVars, collectCoverage, azureWriter, secretData, recorder)
+		cancel()
+		// the whole output of the runner process belongs to the file
+		outputs.add(file.path, recorder.recorded())
+		if err != nil {
+			if ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded) {
+				return core.ExecutionResult{}, err
+			}
+			tes.logger.Warnf("test file %s timed out after %s", file.path, timeout)
+			merged.TestPayload = append(merged.TestPayload, timedOutTests(file, timeout)...)
+			failed += len(file.locators)
+			continue
+		}
+		failed += countFailed(result.TestPayload)
+		merged.TaskID, merged.BuildID, merged.RepoID = result.TaskID, result.BuildID, result.RepoID
+		merged.OrgID, merged.CommitID = result.OrgID, result.CommitID
+		merged.TestPayload = append(merged.TestPayload, result.TestPayload...)
+		merged.TestSuitePayload = append(merged.TestSuitePayload, result.TestSuitePayload...)
+		merged.ResourceUsage = merged.ResourceUsage.Merge(result.ResourceUsage)
+		merged.FileResourceUsage = append(merged.FileResourceUsage, result.FileResourceUsage...)
+	}
+	return merged, nil
+}
+
+// timedOutTests returns the results of the tests of a killed file, the runner reports the results
+// once all the tests of the file are run, so none of them are known.
+func timedOutTests(file testFile, timeout time.Duration) []core.TestPayload {
+	return fileTests(file, core.TestTimedOut, int(timeout/time.Millisecond))
+}
+
+// fileTests returns the results with the given status of the tests of a file which was not run to completion
+func fileTests(file testFile, status string, duration int) []core.TestPayload {
+	tests := make([]core.TestPayload, 0, len(file.locators))
+	for _, locator := range file.locators {
+		name := file.path
+		if parts := strings.Split(locator, locatorSeparator); len(parts) > 1 {
+			name = parts[len(parts)-1]
+		}
+		tests = append(tests, core.TestPayload{
+			Title:       name,
+			Name:        name,
+			FullTitle:   name,
+			FilePath:    file.path,
+			Filelocator: locator,
+			Status:      status,
+			Duration:    duration,
+		})
+	}
+	return tests
+}
+
+func countFailed(tests []core.TestPayload) int {
+	n := 0
+	for i := range tests {
+		if tests[i].Status == core.TestFailed || tests[i].Status == core.TestTimedOut {
+			n++
+		}
+	}
+	return n
+}
+
+// markTimedOut sets the status of the failed tests which ran for the test timeout in seconds as
+// timed out, the runners fail the tests which exceed TAS_TEST_TIMEOUT.
+func markTimedOut(tests []core.TestPayload, timeout int) {
+	if timeout <= 0 {
+		return
+	}
+	for i := range tests {
+		if tests[i].Status == core.TestFailed && tests[i].Duration >= timeout*1000 {
+			tests[i].Status = core.TestTimedOut
+		}
+	}
+}
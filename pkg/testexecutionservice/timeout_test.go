@@ -0,0 +1,38 @@
+package testexecutionservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByFile(t *testing.T) {
+	files := groupByFile([]string{"b.test.js##suite##first", "a.test.js", "b.test.js##second"})
+	assert.Equal(t, []testFile{
+		{path: "b.test.js", locators: []string{"b.test.js##suite##first", "b.test.js##second"}},
+		{path: "a.test.js", locators: []string{"a.test.js"}},
+	}, files)
+}
+
+func TestTimedOutTests(t *testing.T) {
+	tests := timedOutTests(testFile{path: "a.test.js", locators: []string{"a.test.js##suite##works"}}, 2*time.Second)
+	assert.Len(t, tests, 1)
+	assert.Equal(t, "works", tests[0].Name)
+	assert.Equal(t, "a.test.js##suite##works", tests[0].Filelocator)
+	assert.Equal(t, core.TestTimedOut, tests[0].Status)
+	assert.Equal(t, 2000, tests[0].Duration)
+}
+
+func TestMarkTimedOut(t *testing.T) {
+	tests := []core.TestPayload{
+		{Status: core.TestFailed, Duration: 5000},
+		{Status: core.TestFailed, Duration: 10},
+		{Status: "passed", Duration: 6000},
+	}
+	markTimedOut(tests, 5)
+	assert.Equal(t, core.TestTimedOut, tests[0].Status)
+	assert.Equal(t, core.TestFailed, tests[1].Status)
+	assert.Equal(t, "passed", tests[2].Status)
+}
@@ -0,0 +1,149 @@
+package toolchainmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+const nvmScript = "/home/nucleus/.nvm/nvm.sh"
+
+var (
+	requiredVersionRegex = regexp.MustCompile(`^v?\d+(\.\d+){0,2}$`)
+	versionRegex         = regexp.MustCompile(`\d+(\.\d+)*`)
+)
+
+// toolchain describes how the version of a toolchain is determined
+type toolchain struct {
+	name string
+	// binaries are the names of the toolchain binary, the first one found in the PATH is used
+	binaries []string
+	// args print the version of the toolchain
+	args []string
+}
+
+var (
+	node   = toolchain{name: "node", binaries: []string{"node"}, args: []string{"--version"}}
+	python = toolchain{name: "python", binaries: []string{"python3", "python"}, args: []string{"--version"}}
+	golang = toolchain{name: "go", binaries: []string{"go"}, args: []string{"version"}}
+	java   = toolchain{name: "java", binaries: []string{"java"}, args: []string{"-version"}}
+)
+
+type manager struct {
+	logger lumber.Logger
+}
+
+// New returns a new ToolchainManager installing node with nvm and verifying the other toolchains
+func New(logger lumber.Logger) core.ToolchainManager {
+	return &manager{logger: logger}
+}
+
+// Setup installs the node version with nvm if it is available in the container image and verifies
+// the versions of all the required toolchains, so that the task fails before running any command
+func (m *manager) Setup(ctx context.Context, toolchains *core.Toolchains) error {
+	required := []struct {
+		toolchain
+		version string
+	}{{node, toolchains.Node}, {python, toolchains.Python}, {golang, toolchains.Go}, {java, toolchains.Java}}
+	for _, r := range required {
+		if r.version == "" {
+			continue
+		}
+		if !requiredVersionRegex.MatchString(r.version) {
+			return &errs.ToolchainError{Toolchain: r.name, Required: r.version, Reason: "invalid version, expected e.g. 3, 3.9 or 3.9.7"}
+		}
+		version := strings.TrimPrefix(r.version, "v")
+		if r.name == node.name {
+			if err := m.installNode(ctx, version); err != nil {
+				return err
+			}
+		}
+		if err := m.verify(ctx, r.toolchain, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installNode installs the node version with nvm and prepends its binaries to the PATH, the version
+// in the container image is verified if nvm is not installed
+func (m *manager) installNode(ctx context.Context, version string) error {
+	if _, err := os.Stat(nvmScript); err != nil {
+		m.logger.Infof("nvm not found, verifying node %s of the container image", version)
+		return nil
+	}
+	m.logger.Infof("Installing node %s with nvm", version)
+	// the logs of nvm install are written to stderr so that stdout only contains the path of the binary
+	script := fmt.Sprintf("source %s && nvm install %s 1>&2 && nvm which %s", nvmScript, version, version)
+	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", script)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		m.logger.Errorf("failed to install node %s, error %v, output: %s", version, err, stderr.String())
+		return &errs.ToolchainError{Toolchain: node.name, Required: version, Reason: "nvm failed to install the version"}
+	}
+	binDir := filepath.Dir(strings.TrimSpace(string(out)))
+	return os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// verify checks that the version of the toolchain in the PATH matches the required version
+func (m *manager) verify(ctx context.Context, t toolchain, version string) error {
+	binary := ""
+	for _, name := range t.binaries {
+		if _, err := exec.LookPath(name); err == nil {
+			binary = name
+			break
+		}
+	}
+	if binary == "" {
+		return &errs.ToolchainError{Toolchain: t.name, Required: version}
+	}
+	out, err := exec.CommandContext(ctx, binary, t.args...).CombinedOutput()
+	if err != nil {
+		m.logger.Errorf("failed to get the version of %s, error %v, output: %s", binary, err, out)
+		return &errs.ToolchainError{Toolchain: t.name, Required: version, Reason: fmt.Sprintf("%s %s failed", binary, strings.Join(t.args, " "))}
+	}
+	installed := parseVersion(t, string(out))
+	if installed == "" {
+		return &errs.ToolchainError{Toolchain: t.name, Required: version, Reason: fmt.Sprintf("unable to determine the version of %s", binary)}
+	}
+	if !matchVersion(version, installed) {
+		return &errs.ToolchainError{Toolchain: t.name, Required: version, Installed: installed}
+	}
+	m.logger.Infof("Using %s %s", t.name, installed)
+	return nil
+}
+
+// parseVersion returns the first version in the output of the version command, the java versions
+// before 9 are reported as 1.x and returned as x
+func parseVersion(t toolchain, out string) string {
+	version := versionRegex.FindString(out)
+	if t.name == java.name && strings.HasPrefix(version, "1.") {
+		version = strings.TrimPrefix(version, "1.")
+	}
+	return version
+}
+
+// matchVersion reports whether the components of the required version are a prefix of the installed version
+func matchVersion(required, installed string) bool {
+	requiredParts := strings.Split(required, ".")
+	installedParts := strings.Split(installed, ".")
+	if len(requiredParts) > len(installedParts) {
+		return false
+	}
+	for i, part := range requiredParts {
+		if part != installedParts[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package toolchainmanager
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/errs"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		toolchain toolchain
+		out       string
+		want      string
+	}{
+		{node, "v16.13.0\n", "16.13.0"},
+		{python, "Python 3.9.7\n", "3.9.7"},
+		{golang, "go version go1.17.2 linux/amd64\n", "1.17.2"},
+		{java, "openjdk version \"11.0.12\" 2021-07-20\n", "11.0.12"},
+		{java, "openjdk version \"1.8.0_292\"\n", "8.0"},
+		{python, "command not found", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseVersion(tt.toolchain, tt.out), tt.out)
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	assert.True(t, matchVersion("3", "3.9.7"))
+	assert.True(t, matchVersion("3.9", "3.9.7"))
+	assert.True(t, matchVersion("3.9.7", "3.9.7"))
+	assert.False(t, matchVersion("3.1", "3.10.2"))
+	assert.False(t, matchVersion("3.9.7", "3.9"))
+	assert.False(t, matchVersion("2", "3.9.7"))
+}
+
+func TestSetup(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	m := New(logger)
+	goVersion := strings.TrimPrefix(runtime.Version(), "go")
+
+	assert.Nil(t, m.Setup(context.Background(), &core.Toolchains{Go: goVersion}))
+
+	err = m.Setup(context.Background(), &core.Toolchains{Go: "1.0"})
+	assert.Equal(t, &errs.ToolchainError{Toolchain: "go", Required: "1.0", Installed: goVersion}, err)
+
+	err = m.Setup(context.Background(), &core.Toolchains{Go: "latest"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid version")
+}
@@ -0,0 +1,108 @@
+// Package tracing configures OpenTelemetry tracing for nucleus
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/LambdaTest/synapse"
+
+// ShutdownFunc flushes and stops the trace exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup registers the global tracer provider and propagator.
+// If tracing is disabled in config, a no-op tracer is used.
+func Setup(ctx context.Context, cfg *config.NucleusConfig, logger lumber.Logger) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Tracing.Enabled {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{}
+	if cfg.Tracing.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint))
+	}
+	if cfg.Tracing.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(cfg.Tracing.URLPath))
+	}
+	if cfg.Tracing.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		logger.Errorf("failed to create otlp trace exporter, error: %v", err)
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithHost(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(cfg.Tracing.ServiceName),
+			semconv.ServiceVersionKey.String(global.NUCLEUS_BINARY_VERSION),
+			attribute.String("tas.task_id", cfg.TaskID),
+			attribute.String("tas.build_id", cfg.BuildID),
+		))
+	if err != nil {
+		logger.Errorf("failed to create trace resource, error: %v", err)
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	logger.Infof("OpenTelemetry tracing enabled, exporting to %s", cfg.Tracing.Endpoint)
+	return tp.Shutdown, nil
+}
+
+// StartSpan starts a new span with the given name as a child of the span in ctx
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err on span, if any, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InjectHeaders propagates the trace context in ctx on the outgoing request headers
+func InjectHeaders(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Environ returns the trace context in ctx as environment variables,
+// so that child processes (test runners) can continue the trace
+func Environ(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	envs := make([]string, 0, len(carrier))
+	for _, k := range carrier.Keys() {
+		envs = append(envs, toEnvKey(k)+"="+carrier.Get(k))
+	}
+	return envs
+}
+
+// toEnvKey converts the propagation key (eg. traceparent) to an env var name (eg. TRACEPARENT)
+func toEnvKey(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setupTest registers the propagators and a tracer provider recording the ended spans in memory
+func setupTest(t *testing.T) *tracetest.InMemoryExporter {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	shutdown, err := Setup(context.Background(), &config.NucleusConfig{}, logger)
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestEndSpan(t *testing.T) {
+	exporter := setupTest(t)
+
+	ctx, parent := StartSpan(context.Background(), "pipeline.Start")
+	_, child := StartSpan(ctx, "pipeline.clone", attribute.String("repo", "org/repo"))
+	EndSpan(child, errors.New("clone failed"))
+	EndSpan(parent, nil)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	failed, ok := spans[0], spans[1]
+	assert.Equal(t, "pipeline.clone", failed.Name)
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, "clone failed", failed.Status.Description)
+	assert.Len(t, failed.Events, 1)
+	assert.Equal(t, "exception", failed.Events[0].Name)
+	assert.Contains(t, failed.Attributes, attribute.String("repo", "org/repo"))
+	assert.Equal(t, ok.SpanContext.SpanID(), failed.Parent.SpanID())
+
+	assert.Equal(t, "pipeline.Start", ok.Name)
+	assert.Equal(t, codes.Unset, ok.Status.Code)
+	assert.Empty(t, ok.Events)
+}
+
+func TestPropagation(t *testing.T) {
+	setupTest(t)
+
+	// nothing is propagated outside of a span
+	assert.Empty(t, Environ(context.Background()))
+	header := http.Header{}
+	InjectHeaders(context.Background(), header)
+	assert.Empty(t, header.Get("traceparent"))
+
+	ctx, span := StartSpan(context.Background(), "command.ExecuteUserCommands")
+	defer EndSpan(span, nil)
+	sc := span.SpanContext()
+	traceparent := fmt.Sprintf("00-%s-%s-01", sc.TraceID(), sc.SpanID())
+
+	InjectHeaders(ctx, header)
+	assert.Equal(t, traceparent, header.Get("traceparent"))
+	assert.Equal(t, []string{"TRACEPARENT=" + traceparent}, Environ(ctx))
+}
+
+func TestToEnvKey(t *testing.T) {
+	assert.Equal(t, "TRACEPARENT", toEnvKey("traceparent"))
+	assert.Equal(t, "OT_BAGGAGE_KEY", toEnvKey("ot-baggage-key"))
+}
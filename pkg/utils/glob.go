@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const globStar = "**"
+
+// Glob returns the files relative to root matching the pattern, `**` matches any number of directories.
+// The walk starts from the directory prefix of the pattern without wildcards.
+func Glob(root, pattern string) ([]string, error) {
+	pattern = path.Clean(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+	segments := strings.Split(pattern, "/")
+	start := filepath.Join(root, filepath.FromSlash(GlobBase(pattern)))
+	if _, err := os.Stat(start); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var matches []string
+	err := filepath.Walk(start, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		ok, err := match(segments, strings.Split(rel, "/"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// GlobBase returns the directory prefix of the pattern without wildcards, "." if there is none
+func GlobBase(pattern string) string {
+	segments := strings.Split(path.Clean(strings.TrimPrefix(filepath.ToSlash(pattern), "./")), "/")
+	base := make([]string, 0, len(segments))
+	for _, segment := range segments[:len(segments)-1] {
+		if strings.ContainsAny(segment, "*?[\\") {
+			break
+		}
+		base = append(base, segment)
+	}
+	return path.Join(append([]string{"."}, base...)...)
+}
+
+// ValidateGlob checks the syntax of the pattern
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuoteGlob escapes the wildcards of the name, so that the pattern only matches the name
+func QuoteGlob(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// MatchGlob reports whether the slash separated name matches the pattern, `**` matches any number of directories
+func MatchGlob(pattern, name string) (bool, error) {
+	pattern = path.Clean(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+	return match(strings.Split(pattern, "/"), strings.Split(path.Clean(name), "/"))
+}
+
+// match reports whether the path segments match the pattern segments
+func match(pattern, name []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(name) == 0, nil
+	}
+	if pattern[0] == globStar {
+		for i := 0; i <= len(name); i++ {
+			if ok, err := match(pattern[1:], name[i:]); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	if len(name) == 0 {
+		return false, nil
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if !ok || err != nil {
+		return false, err
+	}
+	return match(pattern[1:], name[1:])
+}
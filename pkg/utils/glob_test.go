@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlob(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"cypress/screenshots/a.spec.js/a.png", "cypress/videos/a.spec.js.mp4", "logs/out.log", "logs/nested/err.log"} {
+		p := filepath.Join(root, filepath.FromSlash(file))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.Nil(t, ioutil.WriteFile(p, []byte(file), 0644))
+	}
+	matches, err := Glob(root, "./cypress/**")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cypress/screenshots/a.spec.js/a.png", "cypress/videos/a.spec.js.mp4"}, matches)
+	matches, err = Glob(root, "logs/**/*.log")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"logs/nested/err.log", "logs/out.log"}, matches)
+	matches, err = Glob(root, "missing/*.png")
+	assert.Nil(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestMatchGlob(t *testing.T) {
+	ok, err := MatchGlob("src/**/*.spec.js", "src/a/b/c.spec.js")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = MatchGlob("./src/*.spec.js", "src/a/c.spec.js")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, err = MatchGlob("src/[a.js", "src/a.js")
+	assert.NotNil(t, err)
+}
+
+func TestGlobBase(t *testing.T) {
+	assert.Equal(t, "test/unit", GlobBase("./test/unit/**/*.spec.js"))
+	assert.Equal(t, "packages", GlobBase("packages/*"))
+	assert.Equal(t, ".", GlobBase("**/*.test.js"))
+	assert.Equal(t, ".", GlobBase("jest.config.js"))
+}
+
+func TestValidateGlob(t *testing.T) {
+	assert.Nil(t, ValidateGlob("src/**/*.spec.js"))
+	assert.NotNil(t, ValidateGlob("src/[a/*.js"))
+}
+
+func TestQuoteGlob(t *testing.T) {
+	pattern := QuoteGlob("pages/[id]/index*.test.js")
+	assert.Equal(t, `pages/\[id\]/index\*.test.js`, pattern)
+	ok, err := MatchGlob(pattern, "pages/[id]/index*.test.js")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = MatchGlob(pattern, "pages/i/index.test.js")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
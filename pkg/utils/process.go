@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"context"
+	"os/exec"
+)
+
+// WaitProcessGroup waits for the command started after SetProcessGroup. The process group is killed
+// when ctx is done, otherwise the children of the command keep running after a timeout and Wait hangs
+// until they close the output pipes of the command. The context error is returned in that case.
+func WaitProcessGroup(ctx context.Context, cmd *exec.Cmd) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
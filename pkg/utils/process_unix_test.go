@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	// the background child keeps the output pipe open after the shell is killed
+	cmd := exec.Command("sh", "-c", "sleep 10 & sleep 10")
+	cmd.Stdout = &bytes.Buffer{}
+	SetProcessGroup(cmd)
+	assert.Nil(t, cmd.Start())
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, WaitProcessGroup(ctx, cmd))
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	cmd = exec.Command("sh", "-c", "exit 3")
+	SetProcessGroup(cmd)
+	assert.Nil(t, cmd.Start())
+	assert.NotNil(t, WaitProcessGroup(context.Background(), cmd))
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import "os/exec"
+
+// SetProcessGroup is a no-op on windows, only the command process is killed
+func SetProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LambdaTest/synapse/pkg/core"
+)
+
+// maxListedTests is the maximum number of tests listed in the chat messages
+const maxListedTests = 10
+
+var themeColors = map[core.Status]string{
+	core.Passed:  "2EB67D",
+	core.Failed:  "E01E5A",
+	core.Error:   "E01E5A",
+	core.Aborted: "ECB22E",
+	core.Running: "36C5F0",
+}
+
+// summary returns the one line description of the event
+func summary(event *core.NotificationEvent) string {
+	task := event.Task
+	commit := task.CommitID
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	subject := fmt.Sprintf("TAS %s task for %s@%s", task.Type, task.RepoSlug, commit)
+	switch event.Type {
+	case core.EventTaskStarted:
+		return subject + " started"
+	case core.EventTestFailed:
+		return fmt.Sprintf("%s has %d failed tests", subject, len(event.Tests))
+	case core.EventBlocklistHit:
+		return fmt.Sprintf("%s skipped %d blocklisted tests", subject, len(event.Tests))
+	case core.EventTaskCompleted:
+		text := fmt.Sprintf("%s completed with status %s", subject, task.Status)
+		if task.Remark != "" {
+			text += ": " + task.Remark
+		}
+		return text
+	case core.EventStageFinished:
+		return fmt.Sprintf("%s finished the %s stage", subject, event.Stage)
+	case core.EventError:
+		return fmt.Sprintf("%s failed in the %s stage", subject, event.Stage)
+	default:
+		return fmt.Sprintf("%s: %s", subject, event.Type)
+	}
+}
+
+// testList returns the names of the tests, one per line
+func testList(tests []core.NotificationTest) string {
+	lines := make([]string, 0, maxListedTests+1)
+	for i, test := range tests {
+		if i == maxListedTests {
+			lines = append(lines, fmt.Sprintf("and %d more", len(tests)-maxListedTests))
+			break
+		}
+		lines = append(lines, "• "+test.Name)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// slackMessage returns the payload of a slack incoming webhook
+func slackMessage(event *core.NotificationEvent) map[string]interface{} {
+	text := summary(event)
+	if len(event.Tests) > 0 {
+		text += "\n" + testList(event.Tests)
+	}
+	return map[string]interface{}{"text": text}
+}
+
+// teamsMessage returns the payload of a microsoft teams incoming webhook
+func teamsMessage(event *core.NotificationEvent) map[string]interface{} {
+	message := map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  summary(event),
+		"title":    summary(event),
+	}
+	if color, ok := themeColors[event.Task.Status]; ok {
+		message["themeColor"] = color
+	}
+	if len(event.Tests) > 0 {
+		// teams renders markdown, line breaks need two trailing spaces
+		message["text"] = strings.ReplaceAll(testList(event.Tests), "\n", "  \n")
+	}
+	return message
+}
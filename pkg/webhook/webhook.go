@@ -0,0 +1,267 @@
+// Package webhook notifies the configured webhooks of the task lifecycle events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/LambdaTest/synapse/pkg/requestutils"
+)
+
+// headers sent with each delivery
+const (
+	SignatureHeader = "X-TAS-Signature-256"
+	EventHeader     = "X-TAS-Event"
+)
+
+const (
+	formatJSON  = "json"
+	formatSlack = "slack"
+	formatTeams = "teams"
+	maxAttempts = 3
+	retryDelay  = time.Second
+	// queueSize is the number of events waiting to be delivered, the non-terminal events are dropped beyond it
+	queueSize = 64
+)
+
+type target struct {
+	url    string
+	format string
+}
+
+// Notifier delivers the events of the task to the webhooks in the background, in the order they are emitted
+type Notifier struct {
+	targets    []target
+	events     map[core.NotificationEventType]bool
+	secret     []byte
+	httpClient http.Client
+	logger     lumber.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *core.NotificationEvent
+	done   chan struct{}
+	// ctx of the deliveries, it is canceled if the queue is not drained before the deadline of Close
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// defaultEvents are the events sent if no event is configured
+var defaultEvents = []core.NotificationEventType{
+	core.EventTaskStarted,
+	core.EventTestFailed,
+	core.EventBlocklistHit,
+	core.EventTaskCompleted,
+}
+
+// New returns the subscriber notifying the webhooks of the events of the task, events are discarded
+// if no webhook is configured. The queued events are delivered until Close returns.
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (*Notifier, error) {
+	n := &Notifier{
+		secret:     []byte(cfg.Webhook.Secret),
+		events:     make(map[core.NotificationEventType]bool),
+		httpClient: requestutils.NewClient(time.Duration(cfg.Webhook.Timeout) * time.Second),
+		logger:     logger,
+		queue:      make(chan *core.NotificationEvent, queueSize),
+		done:       make(chan struct{}),
+	}
+	for _, raw := range strings.Split(cfg.Webhook.URLs, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		t := target{url: raw, format: formatJSON}
+		for _, format := range []string{formatSlack, formatTeams} {
+			if strings.HasPrefix(raw, format+":") {
+				t = target{url: strings.TrimPrefix(raw, format+":"), format: format}
+			}
+		}
+		if !strings.HasPrefix(t.url, "http://") && !strings.HasPrefix(t.url, "https://") {
+			return nil, fmt.Errorf("invalid webhook url %s", t.url)
+		}
+		n.targets = append(n.targets, t)
+	}
+	for _, event := range strings.Split(cfg.Webhook.Events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			n.events[core.NotificationEventType(event)] = true
+		}
+	}
+	if len(n.events) == 0 {
+		for _, event := range defaultEvents {
+			n.events[event] = true
+		}
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	go n.run()
+	return n, nil
+}
+
+// run delivers the queued events until the queue is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+	defer n.cancel()
+	for event := range n.queue {
+		n.Notify(n.ctx, event)
+	}
+}
+
+// HandleEvent queues the events of the task to be delivered to the webhooks without blocking the event bus,
+// the events emitted before the task is loaded are not sent. The non-terminal events are dropped if the queue
+// is full, the completion of the task waits for room in the queue.
+func (n *Notifier) HandleEvent(ctx context.Context, event *core.Event) {
+	if event.Task == nil || len(n.targets) == 0 || !n.events[event.Type] {
+		return
+	}
+	notification := &core.NotificationEvent{
+		Type:      event.Type,
+		Timestamp: event.Time,
+		Task:      *event.Task,
+		Stage:     event.Stage,
+		Tests:     event.Tests,
+	}
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = time.Now()
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		n.logger.Warnf("webhook notifier is closed, dropping %s event", event.Type)
+		return
+	}
+	if event.Type == core.EventTaskCompleted {
+		select {
+		case n.queue <- notification:
+		case <-ctx.Done():
+			n.logger.Errorf("failed to queue %s event for the webhooks, error: %v", event.Type, ctx.Err())
+		}
+		return
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		n.logger.Warnf("webhook queue is full, dropping %s event", event.Type)
+	}
+}
+
+// Close stops queuing the events and waits for the queued ones to be delivered, the pending deliveries are
+// canceled once ctx is done
+func (n *Notifier) Close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-n.done
+		return errors.New("webhook deliveries canceled before the queued events were delivered")
+	}
+}
+
+// Notify delivers the event to all the webhooks concurrently and waits for the deliveries
+func (n *Notifier) Notify(ctx context.Context, event *core.NotificationEvent) {
+	if len(n.targets) == 0 || !n.events[event.Type] {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	var wg sync.WaitGroup
+	for _, t := range n.targets {
+		wg.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			if err := n.deliver(ctx, t, event); err != nil {
+				n.logger.Errorf("failed to deliver %s event to webhook %s, error: %v", event.Type, redactURL(t.url), err)
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (n *Notifier) deliver(ctx context.Context, t target, event *core.NotificationEvent) error {
+	var body interface{} = event
+	switch t.format {
+	case formatSlack:
+		body = slackMessage(event)
+	case formatTeams:
+		body = teamsMessage(event)
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		retry, err := n.post(ctx, t.url, event.Type, reqBody)
+		if err == nil || !retry {
+			return err
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * retryDelay):
+		}
+	}
+	return lastErr
+}
+
+// post sends the request, it returns true if the delivery can be retried
+func (n *Notifier) post(ctx context.Context, url string, eventType core.NotificationEventType, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(eventType))
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
+
+// Sign returns the signature of the body sent in the X-TAS-Signature-256 header,
+// receivers verify it by computing the HMAC-SHA256 of the request body using the shared secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// redactURL removes the path of the URL, which contains the token of slack and teams webhooks
+func redactURL(url string) string {
+	parts := strings.SplitN(url, "/", 4)
+	if len(parts) < 4 {
+		return url
+	}
+	return strings.Join(parts[:3], "/") + "/..."
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/core"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	bodies := make(map[string][]byte)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/flaky" {
+			attempts++
+			if attempts == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		}
+		if r.URL.Path == "/json" {
+			assert.Equal(t, Sign([]byte("secret"), body), r.Header.Get(SignatureHeader))
+			assert.Equal(t, string(core.EventTestFailed), r.Header.Get(EventHeader))
+		}
+		bodies[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	cfg := &config.NucleusConfig{Webhook: config.Webhook{
+		URLs:    server.URL + "/json, slack:" + server.URL + "/slack,teams:" + server.URL + "/flaky",
+		Secret:  "secret",
+		Events:  "test.failed,task.completed",
+		Timeout: 5,
+	}}
+	n, err := New(cfg, logger)
+	assert.Nil(t, err)
+	events := core.NewEventBus()
+	events.Subscribe(n)
+
+	task := &core.TaskPayload{TaskID: "task", RepoSlug: "org/repo", CommitID: "0123456789", Type: core.ExecutionTask}
+	events.Emit(context.Background(), &core.Event{
+		Type:  core.EventTestFailed,
+		Task:  task,
+		Tests: []core.NotificationTest{{TestID: "1", Name: "api returns 200", Status: "failed"}},
+	})
+	// filtered events are not delivered
+	events.Emit(context.Background(), &core.Event{Type: core.EventTaskStarted, Task: task})
+	assert.Nil(t, n.Close(context.Background()))
+
+	got := core.NotificationEvent{}
+	assert.Nil(t, json.Unmarshal(bodies["/json"], &got))
+	assert.Equal(t, core.EventTestFailed, got.Type)
+	assert.Equal(t, "task", got.Task.TaskID)
+	assert.Len(t, got.Tests, 1)
+
+	slack := map[string]string{}
+	assert.Nil(t, json.Unmarshal(bodies["/slack"], &slack))
+	assert.Equal(t, "TAS execute task for org/repo@0123456 has 1 failed tests\n• api returns 200", slack["text"])
+
+	teams := map[string]string{}
+	assert.Nil(t, json.Unmarshal(bodies["/flaky"], &teams))
+	assert.Equal(t, "MessageCard", teams["@type"])
+	assert.Equal(t, 2, attempts)
+}
+
+func TestNotifyDefaultEvents(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	var mu sync.Mutex
+	received := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get(EventHeader))
+	}))
+	defer server.Close()
+
+	n, err := New(&config.NucleusConfig{Webhook: config.Webhook{URLs: server.URL, Timeout: 5}}, logger)
+	assert.Nil(t, err)
+	events := core.NewEventBus()
+	events.Subscribe(n)
+
+	task := &core.TaskPayload{TaskID: "task", Status: core.Passed}
+	// the events emitted before the task is loaded and the pipeline events are not sent by default
+	events.Emit(context.Background(), &core.Event{Type: core.EventTaskStarted})
+	events.Emit(context.Background(), &core.Event{Type: core.EventStageFinished, Task: task, Stage: core.StageClone})
+	events.Emit(context.Background(), &core.Event{Type: core.EventTaskCompleted, Task: task})
+	assert.Nil(t, n.Close(context.Background()))
+	assert.Equal(t, []string{string(core.EventTaskCompleted)}, received)
+}
+
+func TestNotifyAsync(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	received := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get(EventHeader))
+	}))
+	defer server.Close()
+
+	n, err := New(&config.NucleusConfig{Webhook: config.Webhook{
+		URLs:    server.URL,
+		Events:  "test.failed,task.completed",
+		Timeout: 5,
+	}}, logger)
+	assert.Nil(t, err)
+	events := core.NewEventBus()
+	events.Subscribe(n)
+
+	// the event bus is not blocked by the webhook, the non-terminal events beyond the queue are dropped
+	task := &core.TaskPayload{TaskID: "task"}
+	for i := 0; i < 2*queueSize; i++ {
+		events.Emit(context.Background(), &core.Event{Type: core.EventTestFailed, Task: task})
+	}
+	completed := make(chan struct{})
+	go func() {
+		defer close(completed)
+		events.Emit(context.Background(), &core.Event{Type: core.EventTaskCompleted, Task: task})
+	}()
+	close(release)
+	<-completed
+
+	// the queued events are delivered in order when the notifier is closed
+	assert.Nil(t, n.Close(context.Background()))
+	assert.Greater(t, len(received), queueSize)
+	assert.Less(t, len(received), 2*queueSize+1)
+	assert.Equal(t, string(core.EventTaskCompleted), received[len(received)-1])
+	// the events emitted after the notifier is closed are dropped
+	events.Emit(context.Background(), &core.Event{Type: core.EventTestFailed, Task: task})
+	assert.Nil(t, n.Close(context.Background()))
+}
+
+func TestCloseTimeout(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	n, err := New(&config.NucleusConfig{Webhook: config.Webhook{URLs: server.URL, Timeout: 60}}, logger)
+	assert.Nil(t, err)
+	n.HandleEvent(context.Background(), &core.Event{Type: core.EventTaskCompleted, Task: &core.TaskPayload{}})
+	// the pending delivery is canceled once the deadline of the shutdown is exceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, n.Close(ctx))
+}
+
+func TestNewInvalidURL(t *testing.T) {
+	_, err := New(&config.NucleusConfig{Webhook: config.Webhook{URLs: "slack:hooks.slack.com/x"}}, nil)
+	assert.NotNil(t, err)
+}
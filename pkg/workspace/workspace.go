@@ -0,0 +1,173 @@
+// Package workspace lays out the directories of the tasks: the clone of the repository, the downloaded cache
+// archives, the scratch files and the workspaces of the tasks run by the batch and the resident nucleus. The
+// directories are cleaned up between the tasks of a reused container, within their quotas.
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+)
+
+// Cleanup policies between the tasks
+const (
+	// CleanupTask removes the scratch files of each task
+	CleanupTask = "task"
+	// CleanupQuota keeps the scratch files within the scratch quota
+	CleanupQuota = "quota"
+)
+
+// ScratchDirName is the scratch directory of the tasks run by the batch and the resident nucleus, inside the
+// workspace of the task
+const ScratchDirName = "scratch"
+
+const mib = 1 << 20
+
+// Manager lays out and cleans up the directories of the tasks
+type Manager struct {
+	cfg     config.Workspace
+	repoDir string
+	logger  lumber.Logger
+}
+
+// New returns a new instance of Manager
+func New(cfg *config.NucleusConfig, logger lumber.Logger) (*Manager, error) {
+	ws := cfg.Workspace
+	switch ws.Cleanup {
+	case "":
+		ws.Cleanup = CleanupTask
+	case CleanupTask, CleanupQuota:
+	default:
+		return nil, fmt.Errorf("unknown workspace cleanup policy %s", ws.Cleanup)
+	}
+	if ws.CacheQuota < 0 || ws.ScratchQuota < 0 || ws.KeepTasks < 0 {
+		return nil, fmt.Errorf("the workspace quotas and the number of kept tasks can't be negative")
+	}
+	return &Manager{cfg: ws, repoDir: cfg.RepoDir, logger: logger}, nil
+}
+
+// Setup sets the paths of the directories in global and creates the cache and scratch directories
+func (m *Manager) Setup() error {
+	if root := m.cfg.Root; root != "" {
+		global.RepoDir = filepath.Join(root, "repo")
+		global.CacheDir = filepath.Join(root, "cache")
+		global.ScratchDir = filepath.Join(root, ScratchDirName)
+		global.BatchDir = filepath.Join(root, "batch")
+		global.DaemonDir = filepath.Join(root, "daemon")
+	}
+	if m.repoDir != "" {
+		global.RepoDir = m.repoDir
+	}
+	if m.cfg.CacheDir != "" {
+		global.CacheDir = m.cfg.CacheDir
+	}
+	if m.cfg.ScratchDir != "" {
+		global.ScratchDir = m.cfg.ScratchDir
+	}
+	if m.cfg.BlocklistFile != "" {
+		global.BlocklistedFileLocation = m.cfg.BlocklistFile
+	}
+	dirs := []string{global.CacheDir, global.ScratchDir}
+	if m.cfg.CoverageDir != "" {
+		global.CodeCoveragParentDir = m.cfg.CoverageDir
+		dirs = append(dirs, global.CodeCoveragParentDir)
+	}
+	if m.cfg.CoverageReportDir != "" {
+		global.CoverageReportDir = m.cfg.CoverageReportDir
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, global.DirectoryPermissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanTask cleans up after the task run in the workspace, the scratch files of the task are removed or kept
+// within the scratch quota depending on the policy, and the cache archives are kept within the cache quota
+func (m *Manager) CleanTask(workspace string) error {
+	scratchDir := filepath.Join(workspace, ScratchDirName)
+	if m.cfg.Cleanup == CleanupTask {
+		if err := os.RemoveAll(scratchDir); err != nil {
+			return err
+		}
+	} else if err := trim(scratchDir, m.cfg.ScratchQuota*mib); err != nil {
+		return err
+	}
+	return trim(global.CacheDir, m.cfg.CacheQuota*mib)
+}
+
+// PruneTasks removes the oldest task workspaces of dir beyond the number of kept tasks, it must not be called
+// while tasks are running in dir
+func (m *Manager) PruneTasks(dir string) error {
+	if m.cfg.KeepTasks == 0 {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	tasks := make([]fs.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			tasks = append(tasks, entry)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ModTime().After(tasks[j].ModTime()) })
+	for i := m.cfg.KeepTasks; i < len(tasks); i++ {
+		m.logger.Debugf("removing the workspace of task %s", tasks[i].Name())
+		if err := os.RemoveAll(filepath.Join(dir, tasks[i].Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type file struct {
+	path string
+	info fs.FileInfo
+}
+
+// trim removes the least recently modified files of dir until their size is within quota, the files are kept if
+// quota is 0
+func trim(dir string, quota int64) error {
+	if quota == 0 {
+		return nil
+	}
+	files := make([]file, 0)
+	var size int64
+	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, file{path: path, info: info})
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].info.ModTime().Before(files[j].info.ModTime()) })
+	for i := 0; i < len(files) && size > quota; i++ {
+		if err := os.Remove(files[i].path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= files[i].info.Size()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LambdaTest/synapse/config"
+	"github.com/LambdaTest/synapse/pkg/global"
+	"github.com/LambdaTest/synapse/pkg/lumber"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestManager(t *testing.T, ws config.Workspace) *Manager {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	m, err := New(&config.NucleusConfig{Workspace: ws}, logger)
+	assert.Nil(t, err)
+	return m
+}
+
+// restoreGlobals restores the paths of global once the test completed
+func restoreGlobals(t *testing.T) {
+	repoDir, cacheDir, scratchDir, batchDir, daemonDir := global.RepoDir, global.CacheDir, global.ScratchDir,
+		global.BatchDir, global.DaemonDir
+	blocklistFile, coverageDir, coverageReportDir := global.BlocklistedFileLocation, global.CodeCoveragParentDir,
+		global.CoverageReportDir
+	t.Cleanup(func() {
+		global.RepoDir, global.CacheDir, global.ScratchDir, global.BatchDir, global.DaemonDir = repoDir, cacheDir,
+			scratchDir, batchDir, daemonDir
+		global.BlocklistedFileLocation, global.CodeCoveragParentDir, global.CoverageReportDir = blocklistFile,
+			coverageDir, coverageReportDir
+	})
+}
+
+func writeFile(t *testing.T, path string, size int, modTime time.Time) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, make([]byte, size), 0644))
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestNew(t *testing.T) {
+	logger, err := lumber.NewLogger(lumber.LoggingConfig{EnableConsole: true}, true, lumber.InstanceZapLogger)
+	assert.Nil(t, err)
+	_, err = New(&config.NucleusConfig{Workspace: config.Workspace{Cleanup: "always"}}, logger)
+	assert.EqualError(t, err, "unknown workspace cleanup policy always")
+	_, err = New(&config.NucleusConfig{Workspace: config.Workspace{CacheQuota: -1}}, logger)
+	assert.NotNil(t, err)
+	m := newTestManager(t, config.Workspace{})
+	assert.Equal(t, CleanupTask, m.cfg.Cleanup)
+}
+
+func TestSetup(t *testing.T) {
+	restoreGlobals(t)
+	root := t.TempDir()
+	scratchDir := filepath.Join(t.TempDir(), "scratch")
+	m := newTestManager(t, config.Workspace{Root: root, ScratchDir: scratchDir})
+	assert.Nil(t, m.Setup())
+	assert.Equal(t, filepath.Join(root, "repo"), global.RepoDir)
+	assert.Equal(t, filepath.Join(root, "cache"), global.CacheDir)
+	assert.Equal(t, scratchDir, global.ScratchDir)
+	assert.Equal(t, filepath.Join(root, "batch"), global.BatchDir)
+	assert.DirExists(t, global.CacheDir)
+	assert.DirExists(t, global.ScratchDir)
+
+	// the blocklist and coverage paths of a sub-task of a batch are in its workspace
+	taskDir := t.TempDir()
+	m = newTestManager(t, config.Workspace{
+		BlocklistFile:     filepath.Join(taskDir, "blocklist.json"),
+		CoverageDir:       filepath.Join(taskDir, "coverage"),
+		CoverageReportDir: filepath.Join(taskDir, "coverage-report"),
+	})
+	assert.Nil(t, m.Setup())
+	assert.Equal(t, filepath.Join(taskDir, "blocklist.json"), global.BlocklistedFileLocation)
+	assert.Equal(t, filepath.Join(taskDir, "coverage"), global.CodeCoveragParentDir)
+	assert.Equal(t, filepath.Join(taskDir, "coverage-report"), global.CoverageReportDir)
+	assert.DirExists(t, global.CodeCoveragParentDir)
+}
+
+func TestCleanTask(t *testing.T) {
+	restoreGlobals(t)
+	global.CacheDir = t.TempDir()
+	now := time.Now()
+	writeFile(t, filepath.Join(global.CacheDir, "old.tzst"), 2*mib, now.Add(-time.Hour))
+	writeFile(t, filepath.Join(global.CacheDir, "cache-npm.tzst"), mib, now)
+
+	workspace := t.TempDir()
+	writeFile(t, filepath.Join(workspace, ScratchDirName, "artifacts.tzst"), 10, now)
+	m := newTestManager(t, config.Workspace{CacheQuota: 2})
+	assert.Nil(t, m.CleanTask(workspace))
+	assert.NoDirExists(t, filepath.Join(workspace, ScratchDirName))
+	// the least recently modified archive is removed
+	assert.NoFileExists(t, filepath.Join(global.CacheDir, "old.tzst"))
+	assert.FileExists(t, filepath.Join(global.CacheDir, "cache-npm.tzst"))
+
+	writeFile(t, filepath.Join(workspace, ScratchDirName, "old"), mib, now.Add(-time.Hour))
+	writeFile(t, filepath.Join(workspace, ScratchDirName, "new"), mib, now)
+	m = newTestManager(t, config.Workspace{Cleanup: CleanupQuota, ScratchQuota: 1})
+	assert.Nil(t, m.CleanTask(workspace))
+	assert.NoFileExists(t, filepath.Join(workspace, ScratchDirName, "old"))
+	assert.FileExists(t, filepath.Join(workspace, ScratchDirName, "new"))
+}
+
+func TestPruneTasks(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"task-1", "task-2", "task-3"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, name), 0755))
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		assert.Nil(t, os.Chtimes(filepath.Join(dir, name), modTime, modTime))
+	}
+	assert.Nil(t, newTestManager(t, config.Workspace{}).PruneTasks(dir))
+	assert.DirExists(t, filepath.Join(dir, "task-1"))
+
+	assert.Nil(t, newTestManager(t, config.Workspace{KeepTasks: 2}).PruneTasks(dir))
+	assert.NoDirExists(t, filepath.Join(dir, "task-1"))
+	assert.DirExists(t, filepath.Join(dir, "task-2"))
+	assert.DirExists(t, filepath.Join(dir, "task-3"))
+	assert.Nil(t, newTestManager(t, config.Workspace{KeepTasks: 1}).PruneTasks(filepath.Join(dir, "missing")))
+}
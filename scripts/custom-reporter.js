@@ -41,6 +41,22 @@ function nodeMissing(metrics, fileCoverage) {
   return [].concat(...ranges).join(",");
 }
 
+function nodeCovered(fileCoverage) {
+  const coveredLines = Object.entries(fileCoverage.getLineCoverage())
+    .filter(([, hits]) => hits > 0)
+    .map(([line]) => parseInt(line));
+
+  const ranges = [];
+  coveredLines.forEach((line) => {
+    const last = ranges[ranges.length - 1];
+    if (last && last[1] === line - 1) last[1] = line;
+    else ranges.push([line, line]);
+  });
+  return ranges
+    .map(([start, end]) => (start === end ? `${start}` : `${start}-${end}`))
+    .join(",");
+}
+
 class JsonSummaryReport extends ReportBase {
   constructor(opts) {
     super();
@@ -58,7 +74,7 @@ class JsonSummaryReport extends ReportBase {
     this.contentWriter.write("{");
   }
 
-  writeSummary(filePath, sc, uncovered) {
+  writeSummary(filePath, sc, uncovered, covered) {
     const cw = this.contentWriter;
     if (this.first) {
       this.first = false;
@@ -68,6 +84,9 @@ class JsonSummaryReport extends ReportBase {
     if (uncovered) {
       sc.data.uncovered_lines = uncovered;
     }
+    if (covered) {
+      sc.data.covered_lines = covered;
+    }
     cw.write(JSON.stringify(filePath));
     cw.write(": ");
     cw.write(JSON.stringify(sc));
@@ -85,10 +104,12 @@ class JsonSummaryReport extends ReportBase {
     const metrics = node.getCoverageSummary();
     const fileCoverage = node.getFileCoverage();
     let missingLines;
+    let coveredLines;
     if (!node.isSummary()) {
       missingLines = nodeMissing(metrics, fileCoverage);
+      coveredLines = nodeCovered(fileCoverage);
     }
-    this.writeSummary(fileCoverage.path, metrics, missingLines);
+    this.writeSummary(fileCoverage.path, metrics, missingLines, coveredLines);
   }
 
   onEnd() {